@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// migrate imports the DNS records and certificates of the Ingresses of an
+// existing cluster into the controller. It prints the ManagedZones,
+// DNSRecords, TLS secrets and Certificates adopting them, or creates them in
+// the control plane with --apply. The certificates keep their secrets and
+// cert-manager issuers
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	certmanv1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	corev1 "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+
+	kuadrantiov1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/migrate"
+)
+
+var (
+	setupLog = ctrl.Log.WithName("migrate")
+	scheme   = clientgoscheme.Scheme
+)
+
+func init() {
+	utilruntime.Must(kuadrantiov1.AddToScheme(scheme))
+	utilruntime.Must(certmanv1.AddToScheme(scheme))
+}
+
+func main() {
+	var cluster string
+	var namespace string
+	var dnsProviderName string
+	var apply bool
+	flag.StringVar(&cluster, "cluster", "", "The name of the cluster secret of the cluster to import, in the controller namespace.")
+	flag.StringVar(&namespace, "namespace", "argocd", "The controller namespace.")
+	flag.StringVar(&dnsProviderName, "dns-provider", "aws", "The DNS provider hosting the zones of the hosts.")
+	flag.BoolVar(&apply, "apply", false, "Create the resources in the control plane instead of printing them.")
+
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts), zap.WriteTo(os.Stderr)))
+
+	if cluster == "" {
+		setupLog.Error(fmt.Errorf("--cluster is required"), "invalid flags")
+		os.Exit(1)
+	}
+
+	if err := run(context.Background(), cluster, namespace, dnsProviderName, apply); err != nil {
+		setupLog.Error(err, "migration failed")
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cluster, namespace, dnsProviderName string, apply bool) error {
+	controlClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{}
+	if err := controlClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: cluster}, secret); err != nil {
+		return fmt.Errorf("failed to get cluster secret: %v", err)
+	}
+	workloadClient, err := clusterSecret.ClientFromSecret(controlClient, secret, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	provider, err := dns.DNSProvider(dnsProviderName)
+	if err != nil {
+		return err
+	}
+	lister, ok := provider.(dns.Lister)
+	if !ok {
+		return fmt.Errorf("DNS provider %s can't list its zones", dnsProviderName)
+	}
+
+	importer := &migrate.Importer{
+		WorkloadClient: workloadClient,
+		Provider:       lister,
+		Cluster:        cluster,
+		Namespace:      namespace,
+	}
+	objects, err := importer.Plan(ctx)
+	if err != nil {
+		return err
+	}
+
+	if apply {
+		return migrate.Apply(ctx, controlClient, scheme, objects)
+	}
+	for _, obj := range objects {
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("---\n%s", out)
+	}
+	return nil
+}
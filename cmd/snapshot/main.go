@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// snapshot exports the desired state of the controller, its ManagedZones,
+// DNSRecords, ManagedHosts and traffic objects, as a versioned snapshot
+// printed as YAML, or restores a snapshot on a new control plane cluster
+// with --restore. The cluster secrets and DNS provider credentials aren't
+// part of the snapshot and are restored beforehand. Once restored, the
+// zones and records of the previous control plane are handed over to the
+// new one with --handover, so both can run side by side until the previous
+// one is removed
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+
+	kuadrantiov1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/snapshot"
+)
+
+var (
+	setupLog = ctrl.Log.WithName("snapshot")
+	scheme   = clientgoscheme.Scheme
+)
+
+func init() {
+	utilruntime.Must(kuadrantiov1.AddToScheme(scheme))
+}
+
+func main() {
+	var namespace string
+	var restore string
+	var handover string
+	flag.StringVar(&namespace, "namespace", "argocd", "The controller namespace.")
+	flag.StringVar(&restore, "restore", "", "The snapshot file to restore in the control plane, instead of taking a snapshot.")
+	flag.StringVar(&handover, "handover", "", "The name of the control plane to hand the ManagedZones and DNSRecords of the namespace over to, instead of taking a snapshot.")
+
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts), zap.WriteTo(os.Stderr)))
+	ctx := log.IntoContext(context.Background(), setupLog)
+
+	if restore != "" && handover != "" {
+		setupLog.Error(fmt.Errorf("--restore and --handover are exclusive"), "invalid flags")
+		os.Exit(1)
+	}
+
+	if err := run(ctx, namespace, restore, handover); err != nil {
+		setupLog.Error(err, "snapshot failed")
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, namespace, restore, handover string) error {
+	controlClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	if handover != "" {
+		return snapshot.Handover(ctx, controlClient, namespace, handover)
+	}
+	if restore != "" {
+		data, err := os.ReadFile(restore)
+		if err != nil {
+			return err
+		}
+		s := &snapshot.Snapshot{}
+		if err := yaml.Unmarshal(data, s); err != nil {
+			return fmt.Errorf("invalid snapshot %s: %w", restore, err)
+		}
+		return snapshot.Restore(ctx, controlClient, s)
+	}
+
+	s, err := snapshot.Take(ctx, controlClient, namespace)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
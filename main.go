@@ -18,26 +18,74 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	internalctrl "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/controller"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/admission"
+	managedzonewebhook "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/admission/managedzone"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	kuadrantiov1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/applicationset"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/companion"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/challenge"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/cluster"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/clusterevacuation"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/controllerconfig"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/dnsrecord"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/hostclaim"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/hostpool"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/managedhost"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/managedzone"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/trafficrollout"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dataplane"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/debug"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/export"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/fault"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/fleet"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/hostapi"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/localdns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/notify"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/placement"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/policy"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/rbac"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/standby"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls/acme"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/zonereport"
+	acmev1 "github.com/jetstack/cert-manager/pkg/apis/acme/v1"
 	certmanv1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
 
 	//+kubebuilder:scaffold:imports
@@ -54,6 +102,7 @@ func init() {
 
 	utilruntime.Must(kuadrantiov1.AddToScheme(scheme.Scheme))
 	utilruntime.Must(certmanv1.AddToScheme(scheme.Scheme))
+	utilruntime.Must(acmev1.AddToScheme(scheme.Scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -68,60 +117,505 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var WebhookPortNumber int
+	var webhookCertDir string
+	var enableClusterIdentity bool
+	var clusterTrustDomain string
+	var clusterTokenExpiration time.Duration
+	var auditPermissions bool
+	var zoneCredentialsNamespaces string
+	var privateZone string
+	var tenantRootZone string
+	var tenantMaxHosts int
+	var hostGenerationStrategy string
+	var hostGenerationTemplate string
+	var fleetCIDRs string
+	var dnsRecordWorkers int
+	var zoneConcurrency int
+	var zoneQPS float64
+	var zoneBurst int
+	var dnsVerifyInterval time.Duration
+	var clusterHostnames bool
+	var hostCollision string
+	var admissionHostCollision string
+	var hostReuseCooldown time.Duration
+	var httpsRedirect bool
+	var certificateAuthorities string
+	var acmeSolverImage string
+	var acmeDirectory string
+	var acmeEmail string
+	var controllerConfigName string
+	var exportDir string
+	var exportMaxManifests int
+	var applicationSetGeneratorPort int
+	var applicationSetGeneratorTokenFile string
+	var fleetSummaryPort int
+	var debugPort int
+	var dataPlaneMetricsInterval time.Duration
+	var dataPlaneMetricsService string
+	var zoneReportInterval time.Duration
+	var zoneReportRepair bool
+	var localDNSInterval time.Duration
+	var localDNSNamespace string
+	var egressDetectionInterval time.Duration
+	var hostAPIPort int
+	var hostAPITokenFile string
+	var fleetSummaryTokenFile string
+	var notificationsFile string
+	var hubName string
+	var heartbeatInterval time.Duration
+	var heartbeatLeaseDuration time.Duration
+	var standbyOf string
+	var standbyForceTakeover bool
+	faults := fault.Faults{}
+	featureGates := features.Gates{}
+	logLevels := logging.Levels{}
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.IntVar(&WebhookPortNumber, "webhooks-port", 8082, "The port of the webhooks server. Set to 0 disables the webhooks server")
+	flag.StringVar(&webhookCertDir, "webhooks-cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"),
+		"The directory of the tls.crt and tls.key the webhooks server is served with. The certificate is reloaded when it changes.")
+	flag.BoolVar(&enableClusterIdentity, "cluster-identity", false,
+		"Access workload clusters with short lived client certificates minted by the hub CA "+
+			"instead of the credentials stored in the cluster secrets.")
+	flag.StringVar(&clusterTrustDomain, "cluster-identity-trust-domain", "kuadrant.io", "The trust domain of the SPIFFE ID set on cluster identities.")
+	flag.DurationVar(&clusterTokenExpiration, "cluster-token-expiration", time.Hour,
+		"The lifetime of the service account tokens requested for cluster secrets annotated with kuadrant.io/token-service-account.")
+
+	flag.BoolVar(&auditPermissions, "rbac-audit", false,
+		"Audit the permissions granted to the controller in the control plane and workload clusters. "+
+			"Missing and excess permissions are logged and exported as metrics, and the controller doesn't "+
+			"become ready while mandatory permissions are missing.")
+
+	flag.StringVar(&zoneCredentialsNamespaces, "zone-credentials-namespaces", "",
+		"Comma separated list of namespaces whose ManagedZones can reference their own DNS provider credentials.")
+
+	flag.StringVar(&privateZone, "private-zone", "",
+		"The name of the private ManagedZone, in the controller namespace, the managed hosts of traffic objects annotated with kuadrant.io/visibility=private are assigned from.")
+	flag.StringVar(&fleetCIDRs, "fleet-cidrs", "",
+		"Comma separated list of the source CIDRs of the fleet the backend services of private traffic objects only accept traffic from. When empty, their backends aren't restricted.")
+
+	flag.StringVar(&tenantRootZone, "tenant-root-zone", "",
+		"The name of the public ManagedZone, in the controller namespace, giving the traffic objects of each namespace the <namespace>.<root> subdomain. "+
+			"Hosts outside of the subdomain of their namespace are rejected. The namespace-as-tenant mode is disabled when empty.")
+	flag.IntVar(&tenantMaxHosts, "tenant-max-hosts", 0,
+		"The number of hosts the traffic objects and HostClaims of each namespace can be assigned in the namespace-as-tenant mode. Set to 0 means no limit.")
+
+	flag.StringVar(&hostGenerationStrategy, "host-generation-strategy", string(kuadrantiov1.HostGenerationShortUUID),
+		"How the managed hosts are generated in the ManagedZones that don't configure their own: ShortUUID, Hash, Words, Sequential or Template.")
+	flag.StringVar(&hostGenerationTemplate, "host-generation-template", "",
+		"The template of the managed hosts of the Template strategy, expanding {name}, {ns}, {kind} and {zone}, e.g. {name}-{ns}.{zone}.")
+
+	flag.IntVar(&dnsRecordWorkers, "dns-record-workers", 10, "The number of DNSRecords reconciled at a time across all zones.")
+	flag.IntVar(&zoneConcurrency, "zone-concurrency", 2, "The number of DNSRecords changed at a time in each zone. Set to 0 for no limit.")
+	flag.Float64Var(&zoneQPS, "zone-qps", 5, "The rate of DNSRecord changes per second in each zone. Set to 0 for no limit.")
+	flag.IntVar(&zoneBurst, "zone-burst", 10, "The burst of DNSRecord changes allowed in each zone.")
+
+	flag.DurationVar(&dnsVerifyInterval, "dns-verify-interval", 15*time.Minute,
+		"How often published DNS records are verified against the DNS provider and repaired when changed out of band. Set to 0 to disable.")
+
+	flag.BoolVar(&clusterHostnames, "cluster-hostnames", false,
+		"Publish a <cluster>.<host> hostname resolving to the addresses of a single cluster alongside each managed host, "+
+			"to target a specific cluster when troubleshooting.")
+
+	flag.StringVar(&hostCollision, "host-collision", string(kuadrantiov1.HostCollisionMerge),
+		"How a host already managed for another traffic object is handled: Merge publishes the endpoints of both traffic objects, "+
+			"Reject leaves the host to the traffic object it was first managed for.")
+	flag.StringVar(&admissionHostCollision, "admission-host-collision", string(kuadrantiov1.AdmissionHostCollisionAllow),
+		"How the admission webhook handles a traffic object requesting a host already managed for another traffic object or "+
+			"claimed by a HostClaim it doesn't reference: Allow, Warn or Deny.")
+	flag.DurationVar(&hostReuseCooldown, "host-reuse-cooldown", 0,
+		"How long a released host can't be assigned to the traffic objects or HostClaims of another namespace than the one it was released by. Set to 0 to disable.")
+
+	flag.BoolVar(&httpsRedirect, "https-redirect", false, "Redirect plain HTTP requests to HTTPS for the managed hosts TLS is provisioned for.")
+
+	flag.StringVar(&certificateAuthorities, "certificate-authorities", "",
+		"Comma separated list of the CAA issuer domains of the certificate authority issuing the certificates of managed hosts, "+
+			"e.g. letsencrypt.org. When set, a CAA record allowing them is published for each managed host.")
+
+	flag.StringVar(&acmeSolverImage, "acme-solver-image", "quay.io/jetstack/cert-manager-acmesolver:v1.7.1",
+		"The cert-manager acmesolver image answering HTTP-01 challenges in the workload clusters when the HTTP01Challenges feature is enabled.")
+
+	flag.StringVar(&acmeDirectory, "acme-directory", "",
+		"The directory URL of the ACME server the built-in ACME client issues the certificates of managed hosts with instead of cert-manager, "+
+			"e.g. https://acme-v02.api.letsencrypt.org/directory. cert-manager isn't required when set.")
+	flag.StringVar(&acmeEmail, "acme-email", "", "The contact email of the account of the built-in ACME client.")
+
+	flag.StringVar(&controllerConfigName, "controller-config", "mctc",
+		"The name of the ControllerConfig in the controller namespace overriding the configuration set by the flags. "+
+			"Changes to the ControllerConfig take effect without restarting the controller.")
+
+	flag.StringVar(&exportDir, "export-dir", "",
+		"Write the manifests of the DNSRecords, Certificates and synced objects the controller would apply to the directory "+
+			"instead of applying them, for review or debugging. The writes of every controller are exported.")
+	flag.IntVar(&exportMaxManifests, "export-max-manifests", 1000,
+		"The maximum number of manifests written to the export directory. As the objects exported aren't created, "+
+			"controllers may keep exporting new ones, e.g. generating hosts. 0 doesn't limit them.")
+
+	flag.IntVar(&applicationSetGeneratorPort, "applicationset-generator-port", 0,
+		"The port of the ArgoCD ApplicationSet plugin generator generating the clusters traffic objects are placed on. Set to 0 disables the generator")
+	flag.StringVar(&applicationSetGeneratorTokenFile, "applicationset-generator-token-file", "",
+		"The file holding the bearer token ArgoCD authenticates to the ApplicationSet plugin generator with.")
+	flag.IntVar(&fleetSummaryPort, "fleet-summary-port", 0,
+		"The port of the read-only fleet summary served for dashboards at "+fleet.SummaryPath+", and of the egress addresses of the fleet at "+
+			fleet.EgressPath+". Set to 0 disables the summary")
+	flag.StringVar(&fleetSummaryTokenFile, "fleet-summary-token-file", "",
+		"The file holding the bearer token clients of the fleet summary authenticate with. Without it the summary is only served on localhost.")
+	flag.DurationVar(&egressDetectionInterval, "egress-detection-interval", 0,
+		"How often the egress addresses of each workload cluster are detected from the external addresses of its nodes, "+
+			"for clusters not declaring them with the "+clusterSecret.AnnotationEgressIPs+" annotation. Set to 0 disables the detection")
+	flag.IntVar(&hostAPIPort, "host-api-port", 0,
+		"The port of the read-only HTTP API looking up the clusters, targets and certificate of the managed hosts at "+hostapi.HostsPath+
+			"/<host>, for tooling outside the clusters. Set to 0 disables the API")
+	flag.StringVar(&hostAPITokenFile, "host-api-token-file", "",
+		"The file holding the bearer token clients of the host API authenticate with.")
+	flag.IntVar(&debugPort, "debug-port", 0,
+		"The localhost port serving pprof profiles at /debug/pprof/, a dump of the object cache at "+debug.CachePath+
+			" and the depth of the workqueues at "+debug.WorkqueuesPath+". Set to 0 disables the debug server")
+
+	flag.DurationVar(&dataPlaneMetricsInterval, "dataplane-metrics-interval", 0,
+		"How often the request metrics of the data plane of each workload cluster are scraped, to federate their rates per host "+
+			"and gate TrafficRollouts on their error rate. Set to 0 disables the scraping")
+	flag.StringVar(&dataPlaneMetricsService, "dataplane-metrics-service", "ingress-nginx/mctc-ingress-nginx-controller-metrics:10254",
+		"The <namespace>/<name>:<port> of the service exposing the metrics of the data plane in each workload cluster.")
+
+	flag.DurationVar(&zoneReportInterval, "zone-report-interval", 0,
+		"How often the records published in the hosted zones of each ManagedZone are compared against its DNSRecords, "+
+			"reporting the drift in the ZoneReport of the zone. Set to 0 disables the reports")
+	flag.BoolVar(&zoneReportRepair, "zone-report-repair", false,
+		"Publish the DNSRecords of the records the zone reports find missing or changed again. Records published out of band are never removed.")
+
+	flag.DurationVar(&localDNSInterval, "local-dns-interval", 0,
+		"How often the addresses of the managed hosts are synced to a ConfigMap of each workload cluster, for the cluster DNS "+
+			"to serve them with the CoreDNS hosts plugin. Set to 0 disables the sync")
+	flag.StringVar(&localDNSNamespace, "local-dns-namespace", "kube-system",
+		"The namespace of the ConfigMap holding the addresses of the managed hosts in each workload cluster.")
+
+	flag.StringVar(&notificationsFile, "notifications-file", "",
+		"The YAML file listing the webhook and Slack endpoints notified of the hosts becoming unserved, clusters evacuated, "+
+			"certificate issuance failures and DNS drift, each with its url, type (webhook or slack) and events. Notifications are disabled when empty.")
+
+	flag.StringVar(&hubName, "hub-name", "",
+		"The name of this control plane, holding the heartbeat lease and the zones and records handed over to it.")
+	flag.DurationVar(&heartbeatInterval, "heartbeat-interval", 0,
+		"How often the heartbeat lease watched by the standby control plane is renewed. Set to 0 disables the heartbeat")
+	flag.DurationVar(&heartbeatLeaseDuration, "heartbeat-lease-duration", time.Minute,
+		"How long the standby control plane waits for the heartbeat lease to be renewed before taking over.")
+	flag.StringVar(&standbyOf, "standby-of", "",
+		"The kubeconfig file of the active control plane this control plane is the standby of. The standby doesn't start its controllers, "+
+			"which write the records of its zones, and takes over once the heartbeat lease of the active control plane expires. Requires --heartbeat-interval")
+	flag.BoolVar(&standbyForceTakeover, "standby-force-takeover", false,
+		"Let the standby control plane take over when the zones and records of the active control plane can't be handed over to it. "+
+			"Only set once the active control plane is known to be down, as both control planes would write the records.")
+
+	flag.Float64Var(&faults.ErrorRate, "fault-error-rate", 0,
+		"The fraction of calls to the DNS provider and the certificate service failing while the FaultInjection feature is enabled.")
+	flag.DurationVar(&faults.Latency, "fault-latency", 0,
+		"The maximum delay added to the calls to the DNS provider and the certificate service while the FaultInjection feature is enabled.")
+	flag.Float64Var(&faults.PartialFailureRate, "fault-partial-failure-rate", 0,
+		"The fraction of DNS record updates publishing only some of their endpoints before failing while the FaultInjection feature is enabled.")
+
+	flag.Var(featureGates, "feature-gates",
+		"A set of key=value pairs that describe feature gates for experimental features. Options are: "+features.Usage())
+	flag.Var(logLevels, "log-levels",
+		"A set of subsystem=verbosity pairs setting the log verbosity of subsystems of the controller, overriding the zap log level. Subsystems are: "+logging.Usage())
 
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
+	// structured JSON logs, unless --zap-encoder=console is set
+	if err := flag.CommandLine.Set("zap-encoder", "json"); err != nil {
+		panic(err)
+	}
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	// the verbosity of each subsystem is filtered by the log filter, so it
+	// can be raised at runtime past the zap log level
+	logFilter := logging.NewFilter(logging.Verbosity(opts.Level, opts.Development), logLevels)
+	opts.Level = zapcore.Level(-logging.MaxVerbosity)
+	ctrl.SetLogger(logFilter.Logger(zap.New(zap.UseFlagOptions(&opts))))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	restConfig := ctrl.GetConfigOrDie()
+	options := ctrl.Options{
 		Scheme:                 scheme.Scheme,
 		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
+		Port:                   WebhookPortNumber,
+		CertDir:                webhookCertDir,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "fb80029c.kuadrant.io",
-	})
+	}
+	// a standby control plane starts fenced: it holds the leader election
+	// lock of the manager until it takes over, so none of its controllers
+	// write to the control plane, the workload clusters or the DNS providers
+	var standbyRunnable *standby.Standby
+	if standbyOf != "" {
+		standbyRunnable = &standby.Standby{ForceTakeover: standbyForceTakeover}
+		lock, err := standbyLock(restConfig, defaultCtrlNS, options.LeaderElectionID)
+		if err != nil {
+			setupLog.Error(err, "unable to create the leader election lock of the standby")
+			os.Exit(1)
+		}
+		options.LeaderElection = true
+		options.LeaderElectionResourceLockInterface = &standby.Lock{Interface: lock, Standby: standbyRunnable}
+	}
+	// in export mode the client of every controller writes the manifests of
+	// the objects to the export directory instead of applying them
+	var exporter *export.Writer
+	if exportDir != "" {
+		setupLog.Info("exporting the manifests of the objects the controller would apply", "dir", exportDir, "maxManifests", exportMaxManifests)
+		exporter = export.NewWriter(exportDir, options.Scheme, exportMaxManifests)
+		options.NewClient = exporter.ForControlPlane().NewClient
+	}
+	mgr, err := ctrl.NewManager(restConfig, options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	var allowedCredentialsNamespaces []string
+	if zoneCredentialsNamespaces != "" {
+		allowedCredentialsNamespaces = strings.Split(zoneCredentialsNamespaces, ",")
+	}
+	var fleetSourceCIDRs []string
+	if fleetCIDRs != "" {
+		fleetSourceCIDRs = strings.Split(fleetCIDRs, ",")
+		for _, cidr := range fleetSourceCIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				setupLog.Error(err, "invalid fleet CIDR")
+				os.Exit(1)
+			}
+		}
+	}
+	switch kuadrantiov1.HostCollisionPolicy(hostCollision) {
+	case kuadrantiov1.HostCollisionMerge, kuadrantiov1.HostCollisionReject:
+	default:
+		setupLog.Error(fmt.Errorf("unknown host collision policy %s", hostCollision), "invalid host collision policy")
+		os.Exit(1)
+	}
+	switch kuadrantiov1.AdmissionHostCollisionPolicy(admissionHostCollision) {
+	case kuadrantiov1.AdmissionHostCollisionAllow, kuadrantiov1.AdmissionHostCollisionWarn, kuadrantiov1.AdmissionHostCollisionDeny:
+	default:
+		setupLog.Error(fmt.Errorf("unknown admission host collision policy %s", admissionHostCollision), "invalid admission host collision policy")
+		os.Exit(1)
+	}
+	hostGeneration := kuadrantiov1.HostGeneration{Strategy: kuadrantiov1.HostGenerationStrategy(hostGenerationStrategy), Template: hostGenerationTemplate}
+	if err := dns.ValidateHostGeneration(hostGeneration); err != nil {
+		setupLog.Error(err, "invalid host generation")
+		os.Exit(1)
+	}
+	var dataPlane trafficrollout.DataPlane
+	if dataPlaneMetricsInterval != 0 {
+		service, err := dataplane.ParseMetricsService(dataPlaneMetricsService)
+		if err != nil {
+			setupLog.Error(err, "invalid data plane metrics service")
+			os.Exit(1)
+		}
+		scraper := &dataplane.Scraper{
+			Client:    mgr.GetClient(),
+			Namespace: defaultCtrlNS,
+			Service:   service,
+			Interval:  dataPlaneMetricsInterval,
+		}
+		if err := mgr.Add(scraper); err != nil {
+			setupLog.Error(err, "unable to set up data plane metrics scraper")
+			os.Exit(1)
+		}
+		dataPlane = scraper
+	}
+	if localDNSInterval != 0 {
+		if err := mgr.Add(&localdns.Syncer{
+			Client:          mgr.GetClient(),
+			Namespace:       defaultCtrlNS,
+			TargetNamespace: localDNSNamespace,
+			Interval:        localDNSInterval,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up local DNS syncer")
+			os.Exit(1)
+		}
+	}
+	var caaAuthorities []string
+	if certificateAuthorities != "" {
+		caaAuthorities = strings.Split(certificateAuthorities, ",")
+	}
+	zoneID, zoneIDSet := os.LookupEnv("AWS_DNS_PUBLIC_ZONE_ID")
+	if zoneIDSet {
+		setupLog.Info("Using AWS DNS zone", "id", zoneID)
+	} else {
+		setupLog.Info("No AWS DNS zone id set (AWS_DNS_PUBLIC_ZONE_ID), no DNS records will be created unless a ControllerConfig sets a default zone")
+	}
+	configStore := config.NewStore(config.Config{
+		DefaultZoneID:             zoneID,
+		DefaultZoneRootDomain:     os.Getenv("ZONE_ROOT_DOMAIN"),
+		CertificateIssuer:         defaultCertProvider,
+		CertificateAuthorities:    caaAuthorities,
+		DNSVerifyInterval:         dnsVerifyInterval,
+		HTTPSRedirect:             httpsRedirect,
+		ClusterHostnames:          clusterHostnames,
+		HostCollision:             kuadrantiov1.HostCollisionPolicy(hostCollision),
+		AdmissionHostCollision:    kuadrantiov1.AdmissionHostCollisionPolicy(admissionHostCollision),
+		HostReuseCooldown:         hostReuseCooldown,
+		ZoneCredentialsNamespaces: allowedCredentialsNamespaces,
+		PrivateZone:               privateZone,
+		FleetCIDRs:                fleetSourceCIDRs,
+		TenantRootZone:            tenantRootZone,
+		TenantMaxHosts:            tenantMaxHosts,
+		HostGeneration:            hostGeneration,
+		FeatureGates:              featureGates,
+		LogLevels:                 logLevels,
+	})
+	if err = (&controllerconfig.ControllerConfigReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Config:    configStore,
+		Name:      types.NamespacedName{Namespace: defaultCtrlNS, Name: controllerConfigName},
+		LogFilter: logFilter,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ControllerConfig")
+		os.Exit(1)
+	}
+
+	if err := faults.Validate(); err != nil {
+		setupLog.Error(err, "invalid fault injection flags")
+		os.Exit(1)
+	}
+	// faults are only injected while the FaultInjection feature is enabled
+	faultInjector := fault.NewInjector(faults, configStore, time.Now().UnixNano())
 	dnsProvider, err := dns.DNSProvider("aws")
 	if err != nil {
 		setupLog.Error(err, "unable to create dns provider client")
 		os.Exit(1)
 	}
+	dnsProvider = faultInjector.DNSProvider(dnsProvider)
+	zoneProviders := dns.NewZoneProviders(mgr.GetClient(), defaultCtrlNS, "aws", dnsProvider, configStore)
+	zoneProviders.Wrap = faultInjector.DNSProvider
 	if err = (&dnsrecord.DNSRecordReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 		ReconcilerConfig: dnsrecord.DNSRecordReconcilerConfig{
-			DNSProvider: "aws",
+			DNSProvider:             "aws",
+			MaxConcurrentReconciles: dnsRecordWorkers,
+			ZoneConcurrency:         zoneConcurrency,
+			ZoneQPS:                 zoneQPS,
+			ZoneBurst:               zoneBurst,
 		},
-		DNSProvider: dnsProvider,
+		DNSProvider:   dnsProvider,
+		Config:        configStore,
+		ZoneProviders: zoneProviders,
+		Recorder:      mgr.GetEventRecorderFor("dnsrecord-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DNSRecord")
 		os.Exit(1)
 	}
-	dnsService := dns.NewService(mgr.GetClient(), dns.NewSafeHostResolver(dns.NewDefaultHostResolver()), defaultCtrlNS)
-	certService := tls.NewService(mgr.GetClient(), defaultCtrlNS, defaultCertProvider)
+	if zoneReportInterval != 0 {
+		if err := mgr.Add(&zonereport.Reporter{
+			Client:        mgr.GetClient(),
+			Scheme:        mgr.GetScheme(),
+			ZoneProviders: zoneProviders,
+			Interval:      zoneReportInterval,
+			Repair:        zoneReportRepair,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up zone reporter")
+			os.Exit(1)
+		}
+	}
+	if err = (&managedzone.ManagedZoneReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		ZoneProviders: zoneProviders,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ManagedZone")
+		os.Exit(1)
+	}
+	if err = (&managedhost.ManagedHostReconciler{
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Config:      configStore,
+		CAAResolver: dns.NewDefaultHostResolver(),
+		BuiltinACME: acmeDirectory != "",
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ManagedHost")
+		os.Exit(1)
+	}
+	if err = (&trafficrollout.TrafficRolloutReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		HealthChecker: trafficrollout.NewHTTPHealthChecker(),
+		DataPlane:     dataPlane,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TrafficRollout")
+		os.Exit(1)
+	}
+	if err = (&clusterevacuation.ClusterEvacuationReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Namespace: defaultCtrlNS,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterEvacuation")
+		os.Exit(1)
+	}
+	if acmeDirectory == "" {
+		if err = (&challenge.ChallengeReconciler{
+			Client:           mgr.GetClient(),
+			Scheme:           mgr.GetScheme(),
+			Config:           configStore,
+			ClusterNamespace: defaultCtrlNS,
+			SolverImage:      acmeSolverImage,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Challenge")
+			os.Exit(1)
+		}
+	}
+	dnsService := dns.NewService(mgr.GetClient(), dns.NewSafeHostResolver(dns.NewDefaultHostResolver()), defaultCtrlNS, configStore)
+	var certService traffic.CertificateService = tls.NewService(mgr.GetClient(), defaultCtrlNS, configStore)
+	if acmeDirectory != "" {
+		setupLog.Info("issuing certificates with the built-in ACME client", "directory", acmeDirectory)
+		acmeService := acme.NewService(mgr.GetClient(), defaultCtrlNS, acmeDirectory, acmeEmail)
+		if err := mgr.Add(acmeService); err != nil {
+			setupLog.Error(err, "unable to set up the built-in ACME client")
+			os.Exit(1)
+		}
+		certService = acmeService
+	}
+	// the faults are injected in every consumer of the certificates, the
+	// traffic controller, the admission webhook and the host pools
+	certService = faultInjector.CertificateService(certService)
+	if err = (&hostclaim.HostClaimReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Hosts:  dnsService,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HostClaim")
+		os.Exit(1)
+	}
+	if err = (&hostpool.HostPoolReconciler{
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		Hosts:        dnsService,
+		Certificates: certService,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HostPool")
+		os.Exit(1)
+	}
+
+	policies := policy.NewRuleEvaluator(mgr.GetClient(), defaultCtrlNS)
 
-	trafficHandler := multiClusterWatch.NewTrafficHandlerFactory(dnsService, certService)
-	if err = (&secret.SecretReconciler{
+	trafficHandler := multiClusterWatch.NewTrafficHandlerFactory(dnsService, certService, configStore, policies, companion.NewRenderer(mgr.GetClient(), defaultCtrlNS), exporter)
+	secretReconciler := &secret.SecretReconciler{
 		Client:            mgr.GetClient(),
 		Scheme:            mgr.GetScheme(),
-		MCWatch:           &multiClusterWatch.WatchController{Manager: mgr, HandlerFactory: trafficHandler},
+		MCWatch:           &multiClusterWatch.WatchController{Manager: mgr, HandlerFactory: trafficHandler, Exporter: exporter, Config: configStore},
 		ClusterReconciler: cluster.NewAdmissionReconciler(mgr.GetClient()),
-	}).SetupWithManager(mgr); err != nil {
+		TokenExpiration:   clusterTokenExpiration,
+		AuditPermissions:  auditPermissions,
+	}
+	if enableClusterIdentity {
+		secretReconciler.ClusterIdentity = tls.NewIdentityService(mgr.GetClient(), defaultCtrlNS, defaultCertProvider, clusterTrustDomain)
+	}
+	if err = secretReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Secret")
 		os.Exit(1)
 	}
@@ -135,18 +629,215 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if auditPermissions {
+		auditor := rbac.NewAuditor(mgr.GetConfig(), defaultCtrlNS, rbac.ControllerPermissions)
+		if err := mgr.Add(auditor); err != nil {
+			setupLog.Error(err, "unable to set up permissions audit")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("rbac", auditor.Checker); err != nil {
+			setupLog.Error(err, "unable to set up permissions ready check")
+			os.Exit(1)
+		}
+	}
 
-	if WebhookPortNumber != 0 {
-		setupLog.Info("starting webhook server")
-		if err := mgr.Add(admission.NewWebhookServer(dnsService, certService, WebhookPortNumber)); err != nil {
-			setupLog.Error(err, "unable to set up webhook server")
+	if applicationSetGeneratorPort != 0 {
+		token, err := os.ReadFile(applicationSetGeneratorTokenFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read ApplicationSet generator token")
+			os.Exit(1)
+		}
+		setupLog.Info("starting ApplicationSet generator")
+		if err := mgr.Add(&applicationset.Generator{
+			Client:    mgr.GetClient(),
+			Namespace: defaultCtrlNS,
+			Port:      applicationSetGeneratorPort,
+			Token:     strings.TrimSpace(string(token)),
+			Scorer:    placement.CostZoneScorer{},
+		}); err != nil {
+			setupLog.Error(err, "unable to set up ApplicationSet generator")
 			os.Exit(1)
 		}
 	}
 
+	if notificationsFile != "" {
+		endpoints, err := notify.LoadEndpoints(notificationsFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load notification endpoints")
+			os.Exit(1)
+		}
+		if err := mgr.Add(&notify.Notifier{
+			Cache:     mgr.GetCache(),
+			Endpoints: endpoints,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up notifier")
+			os.Exit(1)
+		}
+	}
+
+	if heartbeatInterval != 0 {
+		if hubName == "" {
+			setupLog.Error(fmt.Errorf("--hub-name is required"), "invalid heartbeat flags")
+			os.Exit(1)
+		}
+		heartbeat := &standby.Heartbeat{
+			Client:        mgr.GetClient(),
+			Namespace:     defaultCtrlNS,
+			Identity:      hubName,
+			Interval:      heartbeatInterval,
+			LeaseDuration: heartbeatLeaseDuration,
+		}
+		var runnable manager.Runnable = heartbeat
+		if standbyOf != "" {
+			activeConfig, err := clientcmd.BuildConfigFromFlags("", standbyOf)
+			if err != nil {
+				setupLog.Error(err, "unable to load the kubeconfig of the active control plane")
+				os.Exit(1)
+			}
+			activeClient, err := client.New(activeConfig, client.Options{Scheme: mgr.GetScheme()})
+			if err != nil {
+				setupLog.Error(err, "unable to create the client of the active control plane")
+				os.Exit(1)
+			}
+			standbyRunnable.Client = mgr.GetClient()
+			standbyRunnable.ActiveClient = activeClient
+			standbyRunnable.Namespace = defaultCtrlNS
+			standbyRunnable.Identity = hubName
+			standbyRunnable.Interval = heartbeatInterval
+			standbyRunnable.ZoneProviders = zoneProviders
+			standbyRunnable.Heartbeat = heartbeat
+			if err := mgr.AddReadyzCheck("standby", standbyRunnable.Checker); err != nil {
+				setupLog.Error(err, "unable to set up standby ready check")
+				os.Exit(1)
+			}
+			runnable = standbyRunnable
+		}
+		if err := mgr.Add(runnable); err != nil {
+			setupLog.Error(err, "unable to set up heartbeat")
+			os.Exit(1)
+		}
+	} else if standbyOf != "" {
+		setupLog.Error(fmt.Errorf("--standby-of requires --heartbeat-interval"), "invalid standby flags")
+		os.Exit(1)
+	}
+
+	if hostAPIPort != 0 {
+		token, err := os.ReadFile(hostAPITokenFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read host API token")
+			os.Exit(1)
+		}
+		setupLog.Info("starting host API")
+		if err := mgr.Add(&hostapi.Server{
+			Client:    mgr.GetClient(),
+			Namespace: defaultCtrlNS,
+			Port:      hostAPIPort,
+			Token:     strings.TrimSpace(string(token)),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up host API")
+			os.Exit(1)
+		}
+	}
+
+	if debugPort != 0 {
+		setupLog.Info("starting debug server")
+		if err := mgr.Add(&debug.Server{
+			Port:   debugPort,
+			Cache:  mgr.GetCache(),
+			Scheme: mgr.GetScheme(),
+			Lists: []client.ObjectList{
+				&kuadrantiov1.ManagedHostList{},
+				&kuadrantiov1.DNSRecordList{},
+				&kuadrantiov1.ManagedZoneList{},
+				&kuadrantiov1.ClusterEvacuationList{},
+				&kuadrantiov1.TrafficRolloutList{},
+				&kuadrantiov1.TrafficPolicyList{},
+				&kuadrantiov1.ControllerConfigList{},
+				&certmanv1.CertificateList{},
+				&corev1.SecretList{},
+			},
+			Gatherer: metrics.Registry,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up debug server")
+			os.Exit(1)
+		}
+	}
+
+	if fleetSummaryPort != 0 {
+		var token []byte
+		if fleetSummaryTokenFile != "" {
+			if token, err = os.ReadFile(fleetSummaryTokenFile); err != nil {
+				setupLog.Error(err, "unable to read fleet summary token")
+				os.Exit(1)
+			}
+		}
+		setupLog.Info("starting fleet summary server")
+		if err := mgr.Add(&fleet.Server{
+			Client:    mgr.GetClient(),
+			Namespace: defaultCtrlNS,
+			Port:      fleetSummaryPort,
+			Token:     strings.TrimSpace(string(token)),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up fleet summary server")
+			os.Exit(1)
+		}
+	}
+
+	if egressDetectionInterval != 0 {
+		if err := mgr.Add(&fleet.EgressDetector{
+			Client:    mgr.GetClient(),
+			Namespace: defaultCtrlNS,
+			Interval:  egressDetectionInterval,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up egress address detector")
+			os.Exit(1)
+		}
+	}
+
+	if WebhookPortNumber != 0 {
+		if _, err := os.Stat(filepath.Join(webhookCertDir, "tls.crt")); err != nil && internalctrl.IsRunningLocally() {
+			setupLog.Info("no webhook serving certificate, not starting the webhook server", "dir", webhookCertDir)
+		} else {
+			setupLog.Info("starting webhook server")
+			webhookServer := mgr.GetWebhookServer()
+			var active func() bool
+			if standbyRunnable != nil {
+				active = standbyRunnable.Active
+			}
+			if err := admission.Register(webhookServer, dnsService, certService, policies, active); err != nil {
+				setupLog.Error(err, "unable to set up webhook server")
+				os.Exit(1)
+			}
+			if err := (&managedzonewebhook.Validator{Client: mgr.GetClient(), Credentials: zoneProviders}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to set up ManagedZone webhook")
+				os.Exit(1)
+			}
+			if err := mgr.AddReadyzCheck("webhooks", webhookServer.StartedChecker()); err != nil {
+				setupLog.Error(err, "unable to set up webhook ready check")
+				os.Exit(1)
+			}
+		}
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// standbyLock returns the Lease leader election lock of the manager, as
+// created by the manager when leader election is enabled
+func standbyLock(config *rest.Config, namespace, id string) (resourcelock.Interface, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return resourcelock.New(resourcelock.LeasesResourceLock, namespace, id,
+		clientset.CoreV1(), clientset.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: hostname + "_" + string(uuid.NewUUID())})
+}
@@ -0,0 +1,182 @@
+package conditions
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// Reasons set on the conditions of the resources managed by the controllers
+const (
+	// ReasonPausedByAnnotation means reconciliation is paused by the
+	// kuadrant.io/paused annotation
+	ReasonPausedByAnnotation = "PausedByAnnotation"
+	// ReasonHandedOverByAnnotation means the object is handed over to
+	// another control plane by the kuadrant.io/handed-over-to annotation
+	ReasonHandedOverByAnnotation = "HandedOverByAnnotation"
+
+	// ReasonProviderConfigured means the DNS provider is configured
+	ReasonProviderConfigured = "ProviderConfigured"
+	// ReasonProviderSuccess means the DNS provider accepted the change
+	ReasonProviderSuccess = "ProviderSuccess"
+	// ReasonProviderError means the DNS provider failed or rejected the change
+	ReasonProviderError = "ProviderError"
+	// ReasonCredentialsNotAllowed means the referenced provider credentials
+	// are not allowed in the namespace
+	ReasonCredentialsNotAllowed = "CredentialsNotAllowed"
+	// ReasonCredentialsNotFound means the referenced provider credentials
+	// don't exist
+	ReasonCredentialsNotFound = "CredentialsNotFound"
+
+	// ReasonRecordsExist means DNSRecords still reference the zone
+	ReasonRecordsExist = "RecordsExist"
+	// ReasonNotSigned means the zone isn't signed with DNSSEC, or has no
+	// active key signing key
+	ReasonNotSigned = "NotSigned"
+	// ReasonChangeFreeze means the changes to the zone are frozen
+	ReasonChangeFreeze = "ChangeFreeze"
+	// ReasonOutsideHost means records are outside of the host of the
+	// resource
+	ReasonOutsideHost = "OutsideHost"
+	// ReasonOutsideChangeWindow means none of the change windows of the zone
+	// is open
+	ReasonOutsideChangeWindow = "OutsideChangeWindow"
+
+	// ReasonHostReady means the host is published and its certificate is
+	// issued
+	ReasonHostReady = "HostReady"
+	// ReasonHostNotReady means the host is not published or its certificate
+	// is not issued yet
+	ReasonHostNotReady = "HostNotReady"
+	// ReasonNoEndpoints means no cluster serves the host yet
+	ReasonNoEndpoints = "NoEndpoints"
+	// ReasonNoHealthyClusters means every cluster serving the host is
+	// drained or evacuated, or none serves it
+	ReasonNoHealthyClusters = "NoHealthyClusters"
+	// ReasonClustersHealthy means a healthy cluster serves the host
+	ReasonClustersHealthy = "ClustersHealthy"
+	// ReasonSynced means the resources are synced to the clusters
+	ReasonSynced = "Synced"
+	// ReasonCertificateIssued means the certificate is issued
+	ReasonCertificateIssued = "CertificateIssued"
+	// ReasonIssuanceFailed means cert-manager failed to issue the
+	// certificate
+	ReasonIssuanceFailed = "IssuanceFailed"
+	// ReasonCAAAllowed means the CAA records allow the certificate
+	// authorities to issue certificates
+	ReasonCAAAllowed = "CAAAllowed"
+	// ReasonCAABlocked means the CAA records don't allow any of the
+	// certificate authorities to issue certificates
+	ReasonCAABlocked = "CAABlocked"
+	// ReasonLookupFailed means a DNS lookup failed
+	ReasonLookupFailed = "LookupFailed"
+	// ReasonPending means the change is not applied yet
+	ReasonPending = "Pending"
+	// ReasonNotFound means a resource the condition depends on doesn't exist
+	ReasonNotFound = "NotFound"
+
+	// ReasonHealthCheckPassed means the health check succeeded
+	ReasonHealthCheckPassed = "HealthCheckPassed"
+	// ReasonHealthCheckFailed means the health check failed
+	ReasonHealthCheckFailed = "HealthCheckFailed"
+	// ReasonRolledBack means the change was reverted
+	ReasonRolledBack = "RolledBack"
+
+	// ReasonMinimumVersionNotMet means clusters are excluded from the
+	// placement for running older versions than its minimum versions
+	ReasonMinimumVersionNotMet = "MinimumVersionNotMet"
+	// ReasonMinimumVersionMet means no cluster is excluded from the
+	// placement by its minimum versions
+	ReasonMinimumVersionMet = "MinimumVersionMet"
+
+	// ReasonEvacuated means no DNS endpoint points at the cluster
+	ReasonEvacuated = "Evacuated"
+	// ReasonLastCluster means the endpoints of the cluster are kept for the
+	// hosts no other cluster serves
+	ReasonLastCluster = "LastCluster"
+
+	// ReasonClaimed means the host is reserved for the claim
+	ReasonClaimed = "Claimed"
+	// ReasonHostTaken means the host is claimed by another claim or managed
+	// for a traffic object
+	ReasonHostTaken = "HostTaken"
+	// ReasonNoZone means no zone of the controller contains the host
+	ReasonNoZone = "NoZone"
+	// ReasonReservedHost means the host is reserved in its zone
+	ReasonReservedHost = "ReservedHost"
+	// ReasonHostCoolingDown means the host was released by another namespace
+	// within the host reuse cooldown
+	ReasonHostCoolingDown = "HostCoolingDown"
+	// ReasonOutsideTenantDomain means the host is outside of the subdomain
+	// of the namespace in the namespace-as-tenant mode
+	ReasonOutsideTenantDomain = "OutsideTenantDomain"
+	// ReasonTenantQuotaExceeded means the namespace has as many hosts as it
+	// can be assigned in the namespace-as-tenant mode
+	ReasonTenantQuotaExceeded = "TenantQuotaExceeded"
+	// ReasonHostsAvailable means the hosts of the pool are provisioned and
+	// their certificates issued
+	ReasonHostsAvailable = "HostsAvailable"
+	// ReasonInvalidNamespace means the resource isn't in the namespace it
+	// must be created in
+	ReasonInvalidNamespace = "InvalidNamespace"
+
+	// ReasonConfigApplied means the configuration is in use
+	ReasonConfigApplied = "ConfigApplied"
+	// ReasonUnknownFeatureGate means the configuration sets a feature gate
+	// that doesn't exist
+	ReasonUnknownFeatureGate = "UnknownFeatureGate"
+	// ReasonInvalidLogLevels means the configuration sets the log verbosity
+	// of an unknown subsystem, or an invalid verbosity
+	ReasonInvalidLogLevels = "InvalidLogLevels"
+	// ReasonInvalidDefaultZone means the configuration sets a default zone
+	// without an identifier or with an invalid root domain
+	ReasonInvalidDefaultZone = "InvalidDefaultZone"
+	// ReasonInvalidFleetCIDRs means the configuration sets fleet CIDRs of
+	// private traffic that aren't CIDRs
+	ReasonInvalidFleetCIDRs = "InvalidFleetCIDRs"
+	// ReasonInvalidHostGeneration means the configuration sets an unknown
+	// host generation strategy or an invalid template
+	ReasonInvalidHostGeneration = "InvalidHostGeneration"
+)
+
+// Set sets the condition, stamping the generation it was observed at. The
+// transition time only changes when the status changes
+func Set(conditions *[]metav1.Condition, generation int64, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+// Remove removes the condition of the given type
+func Remove(conditions *[]metav1.Condition, conditionType string) {
+	meta.RemoveStatusCondition(conditions, conditionType)
+}
+
+// NewZoneCondition returns the condition of a record within a zone
+func NewZoneCondition(conditionType string, status metav1.ConditionStatus, reason, message string) v1.DNSZoneCondition {
+	return v1.DNSZoneCondition{
+		Type:               conditionType,
+		Status:             string(status),
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// UpdateStatus updates the status of the object, unless it's unchanged from
+// the previous status
+func UpdateStatus(ctx context.Context, c client.Client, obj client.Object, previous, current interface{}) error {
+	if equality.Semantic.DeepEqual(previous, current) {
+		return nil
+	}
+	return c.Status().Update(ctx, obj)
+}
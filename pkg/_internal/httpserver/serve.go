@@ -0,0 +1,31 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Serve runs the server until the context is done, then shuts it down. It
+// returns the error of the server when it stops on its own, and nil when
+// the context was cancelled, as the manager expects of its runnables
+func Serve(ctx context.Context, server *http.Server) error {
+	httpErr := make(chan error)
+	go func() {
+		httpErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-httpErr:
+		return err
+	case <-ctx.Done():
+		if err := server.Shutdown(context.Background()); err != nil {
+			return err
+		}
+		ctxErr := ctx.Err()
+		if errors.Is(ctxErr, context.Canceled) {
+			return nil
+		}
+		return ctxErr
+	}
+}
@@ -6,6 +6,19 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// AnnotationPaused set to "true" freezes the reconciliation of an object.
+	// Its deletion is still reconciled
+	AnnotationPaused = "kuadrant.io/paused"
+	// AnnotationForceDelete set to "true" lets an object be deleted even if
+	// other objects still depend on it
+	AnnotationForceDelete = "kuadrant.io/force-delete"
+	// AnnotationHandedOverTo names the control plane a ManagedZone or
+	// DNSRecord is handed over to. The controller stops writing the records
+	// of the object, and releases it on deletion without deleting them
+	AnnotationHandedOverTo = "kuadrant.io/handed-over-to"
+)
+
 func GetAnnotation(obj metav1.Object, key string) string {
 	if !HasAnnotation(obj, key) {
 		return ""
@@ -107,3 +120,18 @@ func CopyAnnotationsPredicate(fromObj, toObj metav1.Object, predicate Annotation
 
 	return copied
 }
+
+// IsPaused returns true when the reconciliation of the object is paused
+func IsPaused(obj metav1.Object) bool {
+	return GetAnnotation(obj, AnnotationPaused) == "true"
+}
+
+func IsForceDelete(obj metav1.Object) bool {
+	return GetAnnotation(obj, AnnotationForceDelete) == "true"
+}
+
+// HandedOverTo returns the control plane the object is handed over to, or
+// an empty string when it isn't handed over
+func HandedOverTo(obj metav1.Object) string {
+	return strings.TrimSpace(GetAnnotation(obj, AnnotationHandedOverTo))
+}
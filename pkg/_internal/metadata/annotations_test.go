@@ -289,3 +289,42 @@ func Test_hasAnnotation(t *testing.T) {
 		})
 	}
 }
+
+func Test_isPaused(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expect      bool
+	}{
+		{
+			name:        "paused",
+			annotations: map[string]string{AnnotationPaused: "true"},
+			expect:      true,
+		},
+		{
+			name:        "explicitly not paused",
+			annotations: map[string]string{AnnotationPaused: "false"},
+			expect:      false,
+		},
+		{
+			name:        "no annotations",
+			annotations: nil,
+			expect:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-object",
+					Annotations: tt.annotations,
+				},
+			}
+			got := IsPaused(obj)
+			if got != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -7,19 +7,22 @@ import (
 
 	trafficadmission "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/admission/traffic"
 	controllertraffic "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/policy"
 )
 
 type Handler struct {
 	*trafficadmission.TrafficWebhookHandler[*networkingv1.Ingress]
 }
 
-func CreateHandler(hostService controllertraffic.HostService, certService controllertraffic.CertificateService) (admission.Handler, error) {
+func CreateHandler(hostService controllertraffic.HostService, certService controllertraffic.CertificateService, policies policy.Evaluator, active func() bool) (admission.Handler, error) {
 	trafficHandler, err := trafficadmission.NewTrafficWebhookHandler(
 		networkingv1.AddToScheme,
 		func() *networkingv1.Ingress { return &networkingv1.Ingress{} },
 		traffic.NewIngress,
 		hostService,
 		certService,
+		policies,
+		active,
 	)
 	if err != nil {
 		return nil, err
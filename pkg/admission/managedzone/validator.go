@@ -0,0 +1,245 @@
+package managedzone
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+//+kubebuilder:webhook:path=/validate-kuadrant-io-v1-managedzone,mutating=false,failurePolicy=fail,sideEffects=None,groups=kuadrant.io,resources=managedzones,verbs=create;update,versions=v1,name=vmanagedzone.kuadrant.io,admissionReviewVersions=v1
+
+// CredentialsPolicy tells whether zones in a namespace can reference their
+// own provider credentials, and whether they can use the ones of the
+// controller
+type CredentialsPolicy interface {
+	CredentialsAllowed(namespace string) bool
+	DefaultCredentialsAllowed(namespace string) bool
+}
+
+// Validator rejects ManagedZones the controller could never make Ready: an
+// invalid domain, provider credentials that aren't allowed or don't exist,
+// or a hosted zone already managed for another domain. Zones marked as the
+// default zone of namespaces another zone is already the default of are
+// rejected too
+type Validator struct {
+	Client      client.Client
+	Credentials CredentialsPolicy
+}
+
+var _ admission.CustomValidator = &Validator{}
+
+// SetupWithManager serves the validator from the webhook server of the
+// manager
+func (v *Validator) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1.ManagedZone{}).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *Validator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	zone, ok := obj.(*v1.ManagedZone)
+	if !ok {
+		return fmt.Errorf("expected a ManagedZone but got %T", obj)
+	}
+	return v.validate(ctx, zone)
+}
+
+// ValidateUpdate only validates changes to the spec and default zone
+// annotation, so the controller can
+// still update the finalizer and status of zones invalidated by changes
+// elsewhere, such as the deletion of their credentials
+func (v *Validator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	previous, ok := oldObj.(*v1.ManagedZone)
+	if !ok {
+		return fmt.Errorf("expected a ManagedZone but got %T", oldObj)
+	}
+	zone, ok := newObj.(*v1.ManagedZone)
+	if !ok {
+		return fmt.Errorf("expected a ManagedZone but got %T", newObj)
+	}
+	if equality.Semantic.DeepEqual(previous.Spec, zone.Spec) &&
+		metadata.GetAnnotation(previous, dns.AnnotationDefaultZone) == metadata.GetAnnotation(zone, dns.AnnotationDefaultZone) {
+		return nil
+	}
+	return v.validate(ctx, zone)
+}
+
+func (v *Validator) ValidateDelete(_ context.Context, _ runtime.Object) error {
+	return nil
+}
+
+func (v *Validator) validate(ctx context.Context, zone *v1.ManagedZone) error {
+	spec := field.NewPath("spec")
+	errs := field.ErrorList{}
+	if zone.Spec.ID == "" {
+		errs = append(errs, field.Required(spec.Child("id"), "the provider identifier of the hosted zone is required"))
+	}
+	errs = append(errs, ValidateDomain(zone.Spec.DomainName, spec.Child("domainName"))...)
+	if zone.Spec.HostGeneration != nil {
+		if err := dns.ValidateHostGeneration(*zone.Spec.HostGeneration); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("hostGeneration"), *zone.Spec.HostGeneration, err.Error()))
+		}
+	}
+	for i, pattern := range zone.Spec.ReservedHosts {
+		if err := dns.ValidateReservedHostPattern(pattern); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("reservedHosts").Index(i), pattern, err.Error()))
+		}
+	}
+
+	credentialsErrs, err := v.validateCredentials(ctx, zone, zone.Spec.ProviderCredentialsRef, spec.Child("providerCredentialsRef"))
+	if err != nil {
+		return err
+	}
+	errs = append(errs, credentialsErrs...)
+
+	secondaryErrs, err := v.validateSecondaryZones(ctx, zone, spec.Child("secondaryZones"))
+	if err != nil {
+		return err
+	}
+	errs = append(errs, secondaryErrs...)
+
+	zonesErrs, err := v.validateZones(ctx, zone, spec)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, zonesErrs...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return k8serrors.NewInvalid(v1.GroupVersion.WithKind("ManagedZone").GroupKind(), zone.Name, errs)
+}
+
+// ValidateDomain returns the errors of a domain that can't be the root
+// domain of a zone: domains that aren't lower case RFC 1123 subdomains, and
+// public suffixes, which can't be registered
+func ValidateDomain(domain string, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Subdomain(domain) {
+		errs = append(errs, field.Invalid(path, domain, msg))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if suffix, icann := publicsuffix.PublicSuffix(domain); icann && suffix == domain {
+		errs = append(errs, field.Invalid(path, domain, "is a public suffix"))
+	}
+	return errs
+}
+
+// validateCredentials returns the errors of a reference to provider
+// credentials the zone isn't allowed to use or that don't exist, or of a
+// missing reference when the zone isn't allowed the default credentials
+func (v *Validator) validateCredentials(ctx context.Context, zone *v1.ManagedZone, ref *v1.ProviderCredentialsReference, path *field.Path) (field.ErrorList, error) {
+	if ref == nil {
+		if !v.Credentials.DefaultCredentialsAllowed(zone.Namespace) {
+			return field.ErrorList{field.Required(path, fmt.Sprintf("the provider credentials of the controller are not allowed in namespace %s", zone.Namespace))}, nil
+		}
+		return nil, nil
+	}
+	if !v.Credentials.CredentialsAllowed(zone.Namespace) {
+		return field.ErrorList{field.Forbidden(path, fmt.Sprintf("provider credentials are not allowed in namespace %s", zone.Namespace))}, nil
+	}
+	secret := &corev1.Secret{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Namespace: zone.Namespace, Name: ref.Name}, secret); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return field.ErrorList{field.NotFound(path.Child("name"), ref.Name)}, nil
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// validateSecondaryZones returns the errors of the secondary zones of the
+// zone: hosted zones listed more than once, including the hosted zone of the
+// zone itself, and references to credentials as for the zone
+func (v *Validator) validateSecondaryZones(ctx context.Context, zone *v1.ManagedZone, path *field.Path) (field.ErrorList, error) {
+	errs := field.ErrorList{}
+	ids := map[string]bool{zone.Spec.ID: true}
+	for i, secondary := range zone.Spec.SecondaryZones {
+		if ids[secondary.ID] {
+			errs = append(errs, field.Duplicate(path.Index(i).Child("id"), secondary.ID))
+		}
+		ids[secondary.ID] = true
+		credentialsErrs, err := v.validateCredentials(ctx, zone, secondary.ProviderCredentialsRef, path.Index(i).Child("providerCredentialsRef"))
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, credentialsErrs...)
+	}
+	return errs, nil
+}
+
+// validateZones returns the errors of a zone inconsistent with the other
+// zones of its namespace: a hosted zone is managed for a single domain, so
+// a zone can't share its ID with a zone of another domain, including its
+// parent zone, a domain has a single zone of each visibility, and the
+// traffic objects of a namespace have a single default zone
+func (v *Validator) validateZones(ctx context.Context, zone *v1.ManagedZone, path *field.Path) (field.ErrorList, error) {
+	zones := &v1.ManagedZoneList{}
+	if err := v.Client.List(ctx, zones, client.InNamespace(zone.Namespace)); err != nil {
+		return nil, err
+	}
+	errs := field.ErrorList{}
+	for _, other := range zones.Items {
+		if other.Name == zone.Name {
+			continue
+		}
+		if overlap := defaultOverlap(zone, &other); overlap != "" {
+			annotation := field.NewPath("metadata", "annotations").Key(dns.AnnotationDefaultZone)
+			errs = append(errs, field.Invalid(annotation, metadata.GetAnnotation(zone, dns.AnnotationDefaultZone),
+				fmt.Sprintf("zone %s is already the default zone of %s", other.Name, overlap)))
+		}
+		switch {
+		case other.Spec.ID == zone.Spec.ID && strings.HasSuffix(zone.Spec.DomainName, "."+other.Spec.DomainName):
+			errs = append(errs, field.Invalid(path.Child("id"), zone.Spec.ID,
+				fmt.Sprintf("is the hosted zone of the parent zone %s, a subdomain zone must be its own hosted zone", other.Name)))
+		case other.Spec.ID == zone.Spec.ID && other.Spec.DomainName != zone.Spec.DomainName:
+			errs = append(errs, field.Invalid(path.Child("id"), zone.Spec.ID,
+				fmt.Sprintf("is the hosted zone of zone %s, managed for domain %s", other.Name, other.Spec.DomainName)))
+		case other.Spec.DomainName == zone.Spec.DomainName && visibility(&other) == visibility(zone):
+			errs = append(errs, field.Duplicate(path.Child("domainName"), zone.Spec.DomainName))
+		}
+	}
+	return errs, nil
+}
+
+// defaultOverlap returns what the zones are both marked as the default zone
+// of: every namespace, or the first namespace they share
+func defaultOverlap(zone, other *v1.ManagedZone) string {
+	namespaces, all := dns.DefaultNamespaces(zone)
+	otherNamespaces, otherAll := dns.DefaultNamespaces(other)
+	if all && otherAll {
+		return "every namespace"
+	}
+	for _, namespace := range namespaces {
+		if slice.ContainsString(otherNamespaces, namespace) {
+			return "namespace " + namespace
+		}
+	}
+	return ""
+}
+
+func visibility(zone *v1.ManagedZone) v1.ZoneVisibility {
+	if zone.Spec.Visibility == "" {
+		return v1.ZoneVisibilityPublic
+	}
+	return zone.Spec.Visibility
+}
@@ -0,0 +1,186 @@
+package managedzone
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+type allowedNamespaces []string
+
+func (a allowedNamespaces) CredentialsAllowed(namespace string) bool {
+	for _, ns := range a {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (a allowedNamespaces) DefaultCredentialsAllowed(namespace string) bool {
+	return namespace == "argocd"
+}
+
+func TestValidator_ValidateCreate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+
+	zone := func(namespace, name, id, domain string) *v1.ManagedZone {
+		return &v1.ManagedZone{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       v1.ManagedZoneSpec{ID: id, DomainName: domain},
+		}
+	}
+	withCredentials := func(z *v1.ManagedZone, secret string) *v1.ManagedZone {
+		z.Spec.ProviderCredentialsRef = &v1.ProviderCredentialsReference{Name: secret}
+		return z
+	}
+	defaultFor := func(z *v1.ManagedZone, namespaces string) *v1.ManagedZone {
+		z.Annotations = map[string]string{dns.AnnotationDefaultZone: namespaces}
+		return z
+	}
+	withSecondary := func(z *v1.ManagedZone, id, secret string) *v1.ManagedZone {
+		secondary := v1.SecondaryZone{ID: id}
+		if secret != "" {
+			secondary.ProviderCredentialsRef = &v1.ProviderCredentialsReference{Name: secret}
+		}
+		z.Spec.SecondaryZones = append(z.Spec.SecondaryZones, secondary)
+		return z
+	}
+	private := func(z *v1.ManagedZone) *v1.ManagedZone {
+		z.Spec.Visibility = v1.ZoneVisibilityPrivate
+		return z
+	}
+
+	existing := []*v1.ManagedZone{
+		defaultFor(zone("argocd", "example", "Z1", "example.com"), "team-a"),
+		defaultFor(zone("argocd", "shared", "Z3", "shared.com"), "true"),
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "aws-credentials", Namespace: "tenant-a"}}
+
+	cases := []struct {
+		name     string
+		zone     *v1.ManagedZone
+		expected string
+	}{
+		{
+			name: "valid zone",
+			zone: zone("argocd", "other", "Z2", "other.com"),
+		},
+		{
+			name: "subdomain zone with its own hosted zone",
+			zone: zone("argocd", "apps", "Z2", "apps.example.com"),
+		},
+		{
+			name: "private zone of a public domain",
+			zone: private(zone("argocd", "example-private", "Z2", "example.com")),
+		},
+		{
+			name:     "missing id",
+			zone:     zone("argocd", "other", "", "other.com"),
+			expected: "spec.id: Required value",
+		},
+		{
+			name:     "invalid domain",
+			zone:     zone("argocd", "other", "Z2", "Other_.com"),
+			expected: "spec.domainName: Invalid value",
+		},
+		{
+			name:     "public suffix",
+			zone:     zone("argocd", "other", "Z2", "co.uk"),
+			expected: "is a public suffix",
+		},
+		{
+			name:     "credentials not allowed",
+			zone:     withCredentials(zone("argocd", "other", "Z2", "other.com"), "aws-credentials"),
+			expected: "spec.providerCredentialsRef: Forbidden",
+		},
+		{
+			name:     "credentials not found",
+			zone:     withCredentials(zone("tenant-a", "other", "Z2", "other.com"), "missing"),
+			expected: "spec.providerCredentialsRef.name: Not found",
+		},
+		{
+			name:     "default credentials not allowed",
+			zone:     zone("tenant-a", "other", "Z2", "other.com"),
+			expected: "spec.providerCredentialsRef: Required value",
+		},
+		{
+			name: "credentials found",
+			zone: withCredentials(zone("tenant-a", "other", "Z2", "other.com"), "aws-credentials"),
+		},
+		{
+			name:     "subdomain zone sharing the hosted zone of its parent",
+			zone:     zone("argocd", "apps", "Z1", "apps.example.com"),
+			expected: "parent zone example",
+		},
+		{
+			name:     "hosted zone of another domain",
+			zone:     zone("argocd", "other", "Z1", "other.com"),
+			expected: "managed for domain example.com",
+		},
+		{
+			name: "default zone of another namespace",
+			zone: defaultFor(zone("argocd", "other", "Z2", "other.com"), "team-b"),
+		},
+		{
+			name:     "default zone of a namespace with a default zone",
+			zone:     defaultFor(zone("argocd", "other", "Z2", "other.com"), "team-b,team-a"),
+			expected: "already the default zone of namespace team-a",
+		},
+		{
+			name:     "second default zone of every namespace",
+			zone:     defaultFor(zone("argocd", "other", "Z2", "other.com"), "true"),
+			expected: "already the default zone of every namespace",
+		},
+		{
+			name: "secondary zone with credentials",
+			zone: withSecondary(withCredentials(zone("tenant-a", "other", "Z2", "other.com"), "aws-credentials"), "Z4", "aws-credentials"),
+		},
+		{
+			name:     "secondary zone of the hosted zone",
+			zone:     withSecondary(zone("argocd", "other", "Z2", "other.com"), "Z2", ""),
+			expected: "spec.secondaryZones[0].id: Duplicate value",
+		},
+		{
+			name:     "secondary zone credentials not found",
+			zone:     withSecondary(withCredentials(zone("tenant-a", "other", "Z2", "other.com"), "aws-credentials"), "Z4", "missing"),
+			expected: "spec.secondaryZones[0].providerCredentialsRef.name: Not found",
+		},
+		{
+			name:     "duplicate domain",
+			zone:     zone("argocd", "example-2", "Z2", "example.com"),
+			expected: "spec.domainName: Duplicate value",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret)
+			for _, z := range existing {
+				builder = builder.WithObjects(z.DeepCopy())
+			}
+			validator := &Validator{Client: builder.Build(), Credentials: allowedNamespaces{"tenant-a"}}
+			err := validator.ValidateCreate(context.Background(), tc.zone)
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected '%v' got '%v'", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package admission
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	decisionAllowed = "allowed"
+	decisionDenied  = "denied"
+	decisionErrored = "errored"
+)
+
+var (
+	// admissionRequestTotal is a prometheus counter metric which holds the
+	// total number of admission requests by decision.
+	admissionRequestTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mctc_admission_request_total",
+			Help: "MCTC total number of admission requests",
+		},
+		[]string{"webhook", "operation", "decision"},
+	)
+
+	// admissionRequestDuration is a prometheus metric which records the
+	// duration of the admission requests.
+	admissionRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mctc_admission_request_duration_seconds",
+			Help:    "MCTC admission request duration",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		[]string{"webhook", "operation"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(admissionRequestTotal, admissionRequestDuration)
+}
+
+// instrumentedHandler records the decision and duration of the requests
+// handled by the admission handler of a webhook
+type instrumentedHandler struct {
+	webhook string
+	handler admission.Handler
+}
+
+func (h *instrumentedHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	start := time.Now()
+	resp := h.handler.Handle(ctx, req)
+	operation := string(req.Operation)
+	admissionRequestDuration.WithLabelValues(h.webhook, operation).Observe(time.Since(start).Seconds())
+	admissionRequestTotal.WithLabelValues(h.webhook, operation, decision(resp)).Inc()
+	return resp
+}
+
+// decision returns whether the response allows or denies the request, or
+// reports an error handling it
+func decision(resp admission.Response) string {
+	if resp.Allowed {
+		return decisionAllowed
+	}
+	if resp.Result != nil && resp.Result.Code == http.StatusForbidden {
+		return decisionDenied
+	}
+	return decisionErrored
+}
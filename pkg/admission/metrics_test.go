@@ -0,0 +1,49 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestInstrumentedHandler(t *testing.T) {
+	cases := []struct {
+		name     string
+		resp     admission.Response
+		decision string
+	}{
+		{
+			name:     "allowed",
+			resp:     admission.Allowed(""),
+			decision: decisionAllowed,
+		},
+		{
+			name:     "denied",
+			resp:     admission.Denied("host taken"),
+			decision: decisionDenied,
+		},
+		{
+			name:     "errored",
+			resp:     admission.Errored(-1, errors.New("unreachable")),
+			decision: decisionErrored,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := &instrumentedHandler{
+				webhook: "test",
+				handler: admission.HandlerFunc(func(context.Context, admission.Request) admission.Response { return tc.resp }),
+			}
+			counter := admissionRequestTotal.WithLabelValues("test", string(admissionv1.Create), tc.decision)
+			before := testutil.ToFloat64(counter)
+			handler.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Create}})
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("expected '%v' got '%v'", 1, got)
+			}
+		})
+	}
+}
@@ -3,12 +3,19 @@ package traffic
 import (
 	"bytes"
 	"context"
+	"errors"
+	"strings"
 
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
 	trafficctrl "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/policy"
 	trafficapi "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -18,6 +25,15 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+const (
+	// AuditAnnotationHosts is the audit annotation of the admission response
+	// listing the managed hosts assigned to the traffic object
+	AuditAnnotationHosts = "assigned-hosts"
+	// AuditAnnotationZones is the audit annotation of the admission response
+	// listing the ManagedZones of the managed hosts
+	AuditAnnotationZones = "managed-zones"
+)
+
 // TrafficWebhookHandler implements the admission Handler interface with the
 // generic logic to handle requests for an object that can be wrapped around
 // the traffic interface
@@ -29,6 +45,13 @@ type TrafficWebhookHandler[T runtime.Object] struct {
 
 	HostService trafficctrl.HostService
 	CertService trafficctrl.CertificateService
+	// Policies are evaluated against the traffic object, denying it or
+	// returning warnings when it violates them. Optional
+	Policies policy.Evaluator
+	// Active returns false while the control plane is the standby of
+	// another one, which assigns the managed hosts, so they aren't assigned
+	// twice. Optional
+	Active func() bool
 
 	decoder    *admission.Decoder
 	serializer *json.Serializer
@@ -41,6 +64,8 @@ func NewTrafficWebhookHandler[T runtime.Object](
 
 	hostService trafficctrl.HostService,
 	certService trafficctrl.CertificateService,
+	policies policy.Evaluator,
+	active func() bool,
 ) (*TrafficWebhookHandler[T], error) {
 	scheme := runtime.NewScheme()
 	if err := addToScheme(scheme); err != nil {
@@ -64,6 +89,8 @@ func NewTrafficWebhookHandler[T runtime.Object](
 
 		HostService: hostService,
 		CertService: certService,
+		Policies:    policies,
+		Active:      active,
 
 		serializer: serializer,
 		decoder:    decoder,
@@ -80,9 +107,59 @@ func (h *TrafficWebhookHandler[T]) Handle(ctx context.Context, req admission.Req
 		return admission.Errored(-1, err)
 	}
 
+	old, err := h.oldAccessor(req)
+	if err != nil {
+		return admission.Errored(-1, err)
+	}
+	if err := dns.ValidateHosts(addedHosts(h.NewAccessor(obj), old)); err != nil {
+		return admission.Denied(err.Error())
+	}
+	// the zones and owners of the hosts are only validated when the hosts
+	// or annotations change, not on updates of the status or removal of
+	// the finalizers
+	var warnings []string
+	if t := h.NewAccessor(obj); t.GetDeletionTimestamp() == nil && hostsChanged(t, old) {
+		if err := h.HostService.ValidateManagedZone(ctx, t); err != nil {
+			var selectionErr *dns.ZoneSelectionError
+			var tenantErr *dns.TenantDomainError
+			if errors.As(err, &selectionErr) || errors.As(err, &tenantErr) {
+				return admission.Denied(err.Error())
+			}
+			return admission.Errored(-1, err)
+		}
+		if err := h.HostService.ValidateReservedHosts(ctx, t); err != nil {
+			var reservedErr *dns.ReservedHostError
+			if errors.As(err, &reservedErr) {
+				return admission.Denied(err.Error())
+			}
+			return admission.Errored(-1, err)
+		}
+		warnings, err = h.HostService.ValidateHostCollisions(ctx, t)
+		if err != nil {
+			var collisionErr *dns.HostCollisionError
+			if errors.As(err, &collisionErr) {
+				return admission.Denied(err.Error())
+			}
+			return admission.Errored(-1, err)
+		}
+	}
+
+	violations, denied, err := h.evaluatePolicies(ctx, h.NewAccessor(obj), old)
+	if err != nil {
+		return admission.Errored(-1, err)
+	}
+	if len(denied) > 0 {
+		return admission.Denied(strings.Join(policy.Messages(denied), "; "))
+	}
+	warnings = append(warnings, policy.Messages(violations)...)
+
+	if h.Active != nil && !h.Active() {
+		return admission.Allowed("managed hosts not assigned by a standby control plane").WithWarnings(warnings...)
+	}
+
 	original := obj.DeepCopyObject().(T)
 
-	allowed, err := h.handle(ctx, obj)
+	allowed, records, err := h.handle(ctx, obj)
 	if err != nil {
 		return admission.Errored(-1, err)
 	}
@@ -90,6 +167,7 @@ func (h *TrafficWebhookHandler[T]) Handle(ctx context.Context, req admission.Req
 	if !allowed {
 		return admission.Denied("")
 	}
+	auditAnnotations := auditAnnotations(records)
 
 	if !equality.Semantic.DeepEqual(original, obj) {
 		var originalSerialised bytes.Buffer
@@ -105,53 +183,140 @@ func (h *TrafficWebhookHandler[T]) Handle(ctx context.Context, req admission.Req
 			return admission.Errored(-1, err)
 		}
 
-		return admission.PatchResponseFromRaw(
+		resp := admission.PatchResponseFromRaw(
 			originalSerialised.Bytes(),
 			currentSerialised.Bytes(),
-		)
+		).WithWarnings(warnings...)
+		resp.AuditAnnotations = auditAnnotations
+		return resp
+	}
+
+	resp := admission.Allowed("").WithWarnings(warnings...)
+	resp.AuditAnnotations = auditAnnotations
+	return resp
+}
+
+// oldAccessor returns the traffic accessor of the object being updated, or
+// nil when the object is created
+func (h *TrafficWebhookHandler[T]) oldAccessor(req admission.Request) (trafficapi.Interface, error) {
+	if req.Operation != admissionv1.Update || len(req.OldObject.Raw) == 0 {
+		return nil, nil
+	}
+	old := h.NewObj()
+	if err := h.decoder.DecodeRaw(req.OldObject, old); err != nil {
+		return nil, err
+	}
+	return h.NewAccessor(old), nil
+}
+
+// addedHosts returns the hosts of the traffic object the object being
+// updated didn't have, so hosts already admitted aren't validated again
+func addedHosts(t, old trafficapi.Interface) []string {
+	if old == nil {
+		return t.GetHosts()
+	}
+	hosts := []string{}
+	for _, host := range t.GetHosts() {
+		if !slice.ContainsString(old.GetHosts(), host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// hostsChanged returns true when the traffic object is created, or when its
+// hosts or annotations differ from the object being updated
+func hostsChanged(t, old trafficapi.Interface) bool {
+	return old == nil ||
+		!equality.Semantic.DeepEqual(t.GetHosts(), old.GetHosts()) ||
+		!equality.Semantic.DeepEqual(t.GetAnnotations(), old.GetAnnotations())
+}
+
+// auditAnnotations returns the audit annotations of the admission response
+// tracing the managed hosts assigned to the traffic object and their zones
+func auditAnnotations(records []*v1.DNSRecord) map[string]string {
+	if len(records) == 0 {
+		return nil
+	}
+	hosts := []string{}
+	zones := []string{}
+	for _, record := range records {
+		hosts = append(hosts, record.Name)
+		if record.Spec.ManagedZoneRef != nil && !slice.ContainsString(zones, record.Spec.ManagedZoneRef.Name) {
+			zones = append(zones, record.Spec.ManagedZoneRef.Name)
+		}
+	}
+	annotations := map[string]string{AuditAnnotationHosts: strings.Join(hosts, ",")}
+	if len(zones) > 0 {
+		annotations[AuditAnnotationZones] = strings.Join(zones, ",")
 	}
+	return annotations
+}
 
-	return admission.Allowed("")
+// evaluatePolicies returns the policy violations of the traffic object, and
+// those denying it. Only the violations the change introduces deny it, so
+// objects already admitted can still be updated, and deleted, once rules
+// they violate are added
+func (h *TrafficWebhookHandler[T]) evaluatePolicies(ctx context.Context, t, old trafficapi.Interface) ([]policy.Violation, []policy.Violation, error) {
+	if h.Policies == nil || t.GetDeletionTimestamp() != nil {
+		return nil, nil, nil
+	}
+	violations, err := h.Policies.Evaluate(ctx, policy.Input{Object: t})
+	if err != nil {
+		return nil, nil, err
+	}
+	denied := policy.Denied(violations)
+	if old != nil {
+		previous, err := h.Policies.Evaluate(ctx, policy.Input{Object: old})
+		if err != nil {
+			return nil, nil, err
+		}
+		denied = policy.Introduced(denied, previous)
+	}
+	return violations, denied, nil
 }
 
-func (h *TrafficWebhookHandler[T]) handle(ctx context.Context, obj T) (bool, error) {
+// handle assigns the managed hosts to the traffic object, returning their
+// records. Their certificates are created and their TLS configured unless
+// the traffic object opted out of TLS management
+func (h *TrafficWebhookHandler[T]) handle(ctx context.Context, obj T) (bool, []*v1.DNSRecord, error) {
 	trafficAccessor := h.NewAccessor(obj)
+	if metadata.IsPaused(trafficAccessor) {
+		return true, nil, nil
+	}
 
 	// verify host is correct
 	// no managed host assigned assign one
 	// create empty DNSRecord with assigned host
 	_, managedHostRecords, err := h.HostService.EnsureManagedHost(ctx, trafficAccessor)
 	if err != nil && err != dns.AlreadyAssignedErr {
-		return false, err
+		return false, nil, err
 	}
 
 	for _, managedHostRecord := range managedHostRecords {
 		if err := trafficAccessor.AddManagedHost(managedHostRecord.Name); err != nil {
-			return false, err
+			return false, nil, err
 		}
-		// create certificate resource for assigned host
-		if err := h.CertService.EnsureCertificate(ctx, managedHostRecord.Name, managedHostRecord); err != nil && !k8serrors.IsAlreadyExists(err) {
-			return false, err
+		if trafficapi.TLSDisabled(trafficAccessor) {
+			continue
 		}
-		// when certificate ready copy secret (need to add event handler for certs)
-		// only once certificate is ready update DNS based status of ingress
-		secret, err := h.CertService.GetCertificateSecret(ctx, managedHostRecord.Name)
-		if err != nil && !k8serrors.IsNotFound(err) {
-			return false, err
+		// the hosts of wildcard zones are served by the wildcard certificate
+		// of the zone, owned by its wildcard record
+		certificateHost, certificateRecord, err := h.HostService.CertificateHost(ctx, managedHostRecord)
+		if err != nil {
+			return false, nil, err
 		}
-
-		// If the secret was not found, `GetCertificateSecret` returns `nil`
-		// we still set the TLS expecting the name to match the host name
-		if secret == nil {
-			secret = &corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: managedHostRecord.Name,
-				},
-			}
+		if err := h.CertService.EnsureCertificate(ctx, certificateHost, certificateRecord); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return false, nil, err
 		}
-
-		trafficAccessor.AddTLS(managedHostRecord.Name, secret)
+		// the controller copies the secret of the certificate to the
+		// namespace of the traffic object named after the managed host
+		trafficAccessor.AddTLS(managedHostRecord.Name, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: managedHostRecord.Name,
+			},
+		})
 	}
 
-	return true, nil
+	return true, managedHostRecords, nil
 }
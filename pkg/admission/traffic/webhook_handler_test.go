@@ -0,0 +1,262 @@
+package traffic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/policy"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/test"
+	trafficapi "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func TestAddedHosts(t *testing.T) {
+	ingress := func(hosts ...string) trafficapi.Interface {
+		i := &networkingv1.Ingress{}
+		for _, host := range hosts {
+			i.Spec.Rules = append(i.Spec.Rules, networkingv1.IngressRule{Host: host})
+		}
+		return trafficapi.NewIngress(i)
+	}
+
+	cases := []struct {
+		name     string
+		obj      trafficapi.Interface
+		old      trafficapi.Interface
+		expected []string
+	}{
+		{
+			name:     "created",
+			obj:      ingress("a.example.com", "b.example.com"),
+			expected: []string{"a.example.com", "b.example.com"},
+		},
+		{
+			name:     "host added",
+			obj:      ingress("a.example.com", "b.example.com"),
+			old:      ingress("a.example.com"),
+			expected: []string{"b.example.com"},
+		},
+		{
+			name:     "hosts unchanged",
+			obj:      ingress("a.example.com"),
+			old:      ingress("a.example.com"),
+			expected: []string{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if hosts := addedHosts(tc.obj, tc.old); !reflect.DeepEqual(hosts, tc.expected) {
+				t.Errorf("expected '%v' got '%v'", tc.expected, hosts)
+			}
+		})
+	}
+}
+
+func TestHostsChanged(t *testing.T) {
+	ingress := func(annotations map[string]string, hosts ...string) trafficapi.Interface {
+		i := &networkingv1.Ingress{}
+		i.Annotations = annotations
+		for _, host := range hosts {
+			i.Spec.Rules = append(i.Spec.Rules, networkingv1.IngressRule{Host: host})
+		}
+		return trafficapi.NewIngress(i)
+	}
+	zone := map[string]string{"kuadrant.io/managed-zone": "apps"}
+
+	cases := []struct {
+		name    string
+		obj     trafficapi.Interface
+		old     trafficapi.Interface
+		changed bool
+	}{
+		{
+			name:    "created",
+			obj:     ingress(nil, "a.example.com"),
+			changed: true,
+		},
+		{
+			name:    "host added",
+			obj:     ingress(nil, "a.example.com", "b.example.com"),
+			old:     ingress(nil, "a.example.com"),
+			changed: true,
+		},
+		{
+			name:    "annotation added",
+			obj:     ingress(zone, "a.example.com"),
+			old:     ingress(nil, "a.example.com"),
+			changed: true,
+		},
+		{
+			name: "status or finalizers updated",
+			obj:  ingress(zone, "a.example.com"),
+			old:  ingress(zone, "a.example.com"),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if changed := hostsChanged(tc.obj, tc.old); changed != tc.changed {
+				t.Errorf("expected '%v' got '%v'", tc.changed, changed)
+			}
+		})
+	}
+}
+
+func TestEvaluatePolicies(t *testing.T) {
+	ingress := func(hosts ...string) trafficapi.Interface {
+		i := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		for _, host := range hosts {
+			i.Spec.Rules = append(i.Spec.Rules, networkingv1.IngressRule{Host: host})
+		}
+		return trafficapi.NewIngress(i)
+	}
+	deleting := ingress("a.example.com")
+	deleting.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(v1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&v1.TrafficPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "corp", Namespace: "argocd"},
+		Spec:       v1.TrafficPolicySpec{Rules: []v1.PolicyRule{{Name: "corp-hosts", HostSuffixes: []string{".corp.example.com"}}}},
+	}).Build()
+	h := &TrafficWebhookHandler[*networkingv1.Ingress]{Policies: policy.NewRuleEvaluator(c, "argocd")}
+
+	cases := []struct {
+		name             string
+		obj              trafficapi.Interface
+		old              trafficapi.Interface
+		expectViolations int
+		expectDenied     int
+	}{
+		{
+			name:             "created violating",
+			obj:              ingress("a.example.com"),
+			expectViolations: 1,
+			expectDenied:     1,
+		},
+		{
+			name:             "object admitted before the rule it violates updated",
+			obj:              ingress("a.example.com"),
+			old:              ingress("a.example.com"),
+			expectViolations: 1,
+		},
+		{
+			name:             "violating host added",
+			obj:              ingress("a.example.com", "b.example.com"),
+			old:              ingress("a.example.com"),
+			expectViolations: 2,
+			expectDenied:     1,
+		},
+		{
+			name: "violating object deleted",
+			obj:  deleting,
+			old:  ingress("a.example.com"),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations, denied, err := h.evaluatePolicies(context.TODO(), tc.obj, tc.old)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(violations) != tc.expectViolations {
+				t.Errorf("expected '%v' got '%v'", tc.expectViolations, violations)
+			}
+			if len(denied) != tc.expectDenied {
+				t.Errorf("expected '%v' got '%v'", tc.expectDenied, denied)
+			}
+		})
+	}
+}
+
+func TestTrafficWebhookHandler_handle(t *testing.T) {
+	ingress := func(annotations map[string]string) *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: annotations},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "test.example.com"}}},
+		}
+	}
+
+	cases := []struct {
+		name    string
+		ingress *networkingv1.Ingress
+		// wildcard serves the hosts of the zone with a wildcard certificate
+		wildcard          bool
+		expectCertificate bool
+	}{
+		{
+			name:              "TLS managed",
+			ingress:           ingress(nil),
+			expectCertificate: true,
+		},
+		{
+			name:              "wildcard certificate of the zone",
+			ingress:           ingress(nil),
+			wildcard:          true,
+			expectCertificate: true,
+		},
+		{
+			name:    "TLS disabled",
+			ingress: ingress(map[string]string{trafficapi.AnnotationTLS: trafficapi.AnnotationValueDisabled}),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			certificates := test.NewCertificateService("argocd")
+			hosts := test.NewHostService(test.Scheme(), "mctc.example.com", "argocd", config.NewStore(config.Config{}))
+			if tc.wildcard {
+				zone := &v1.ManagedZone{}
+				if err := hosts.Client.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "default"}, zone); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				zone.Spec.Wildcard = true
+				if err := hosts.Client.Update(ctx, zone); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			h := &TrafficWebhookHandler[*networkingv1.Ingress]{
+				NewAccessor: func(i *networkingv1.Ingress) trafficapi.Interface { return trafficapi.NewIngress(i) },
+				HostService: hosts,
+				CertService: certificates,
+			}
+			allowed, records, err := h.handle(ctx, tc.ingress)
+			if err != nil || !allowed {
+				t.Fatalf("expected allowed got '%v' '%v'", allowed, err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("expected a managed host got '%v'", records)
+			}
+			host := records[0].Name
+			if hosts := trafficapi.NewIngress(tc.ingress).GetHosts(); len(hosts) != 2 || hosts[1] != host {
+				t.Errorf("expected the managed host '%v' added got '%v'", host, hosts)
+			}
+			certificateHost := host
+			if tc.wildcard {
+				certificateHost = "*.mctc.example.com"
+				if certificates.Ensured(host) {
+					t.Errorf("expected no certificate for '%v' in a wildcard zone", host)
+				}
+			}
+			if ensured := certificates.Ensured(certificateHost); ensured != tc.expectCertificate {
+				t.Errorf("expected certificate '%v' got '%v'", tc.expectCertificate, ensured)
+			}
+			expectTLS := []networkingv1.IngressTLS{}
+			if tc.expectCertificate {
+				expectTLS = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: host}}
+			}
+			if tls := append([]networkingv1.IngressTLS{}, tc.ingress.Spec.TLS...); !reflect.DeepEqual(tls, expectTLS) {
+				t.Errorf("expected '%v' got '%v'", expectTLS, tls)
+			}
+		})
+	}
+}
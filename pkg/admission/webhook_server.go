@@ -1,72 +1,34 @@
 package admission
 
 import (
-	"context"
-	"errors"
-	"fmt"
-
 	admissioningress "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/admission/ingress"
 	controllertraffic "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
-	"github.com/go-logr/logr"
-	"github.com/go-logr/zapr"
-	log "github.com/sirupsen/logrus"
-	"go.uber.org/zap"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/policy"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
-
-	"net/http"
 )
 
-type WebhookServer struct {
-	Port int
-
-	Hosts        controllertraffic.HostService
-	Certificates controllertraffic.CertificateService
-}
-
-func NewWebhookServer(hostService controllertraffic.HostService, certsService controllertraffic.CertificateService, port int) *WebhookServer {
-	return &WebhookServer{
-		Port: port,
-
-		Hosts:        hostService,
-		Certificates: certsService,
-	}
-}
-
-func (s *WebhookServer) Start(ctx context.Context) error {
-	logger := logr.New(zapr.NewLogger(zap.L()).GetSink())
-	logger.Info(fmt.Sprintf("Starting webhook server at :%d", s.Port))
-
-	mux := http.NewServeMux()
-
-	handler, err := admissioningress.CreateHandler(s.Hosts, s.Certificates)
+// IngressPath is the path the Ingress admission webhook is served at
+const IngressPath = "/ingress"
+
+// Register serves the admission webhooks from the webhook server of the
+// manager, which serves them over TLS with the certificate of its
+// certificate directory, reloaded on rotation, and shuts them down with the
+// manager. The decision and duration of the admission requests are recorded
+// as metrics. The managed hosts are only assigned while active returns true,
+// when set
+func Register(server *webhook.Server, hostService controllertraffic.HostService, certsService controllertraffic.CertificateService, policies policy.Evaluator, active func() bool) error {
+	handler, err := admissioningress.CreateHandler(hostService, certsService, policies, active)
 	if err != nil {
-		log.Error("Error creating handler", err)
 		return err
 	}
-	webhook := &webhook.Admission{
-		Handler: handler,
+	ingressWebhook := &webhook.Admission{
+		Handler: &instrumentedHandler{webhook: "ingress", handler: handler},
 	}
-
-	err = webhook.InjectLogger(logger)
-	if err != nil {
+	if err := ingressWebhook.InjectLogger(log.Log.WithName("webhook-server")); err != nil {
 		return err
 	}
 
-	mux.Handle("/ingress", webhook)
-	httpErr := make(chan error)
-	go func() {
-		httpErr <- http.ListenAndServe(fmt.Sprintf(":%d", s.Port), mux)
-	}()
-
-	select {
-	case err := <-httpErr:
-		return err
-	case <-ctx.Done():
-		ctxErr := ctx.Err()
-		if errors.Is(ctxErr, context.Canceled) {
-			return nil
-		}
-
-		return ctxErr
-	}
+	server.Register(IngressPath, ingressWebhook)
+	return nil
 }
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterEvacuationSpec defines the desired state of ClusterEvacuation
+type ClusterEvacuationSpec struct {
+	// cluster is the name of the cluster secret of the cluster evacuated
+	Cluster string `json:"cluster"`
+}
+
+// ClusterEvacuationStatus defines the observed state of ClusterEvacuation
+type ClusterEvacuationStatus struct {
+	// observedGeneration is the most recently observed generation of the
+	// ClusterEvacuation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// records are the DNSRecords the endpoints of the cluster were withdrawn
+	// from
+	// +optional
+	Records []string `json:"records,omitempty"`
+
+	// pendingRecords are the DNSRecords not published to all their zones
+	// since the endpoints of the cluster were withdrawn
+	// +optional
+	PendingRecords []string `json:"pendingRecords,omitempty"`
+
+	// retainedRecords are the DNSRecords the endpoints of the cluster are
+	// kept on, as no other cluster serves their host
+	// +optional
+	RetainedRecords []string `json:"retainedRecords,omitempty"`
+
+	// conditions are any conditions associated with the evacuation.
+	//
+	// The "Evacuated" condition is set to true once the endpoints of the
+	// cluster are withdrawn from every DNSRecord and the records are
+	// published to all their zones. It stays false while a DNSRecord has no
+	// endpoints of another cluster, or when the ClusterEvacuation isn't in
+	// the namespace of the controller.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	ClusterEvacuationEvacuatedConditionType = "Evacuated"
+)
+
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.cluster"
+//+kubebuilder:printcolumn:name="Evacuated",type="string",JSONPath=".status.conditions[?(@.type==\"Evacuated\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ClusterEvacuation is the Schema for the clusterevacuations API. While it
+// exists, no DNS endpoint points at the cluster: the endpoints of the
+// cluster are withdrawn from every DNSRecord and no new ones are published.
+// The endpoints of the hosts only the cluster serves are kept, rather than
+// the hosts no longer resolving. It must be created in the namespace of the
+// controller.
+// Deleting it lets the traffic objects of the cluster publish their
+// endpoints again
+type ClusterEvacuation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterEvacuationSpec   `json:"spec,omitempty"`
+	Status ClusterEvacuationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterEvacuationList contains a list of ClusterEvacuation
+type ClusterEvacuationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterEvacuation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterEvacuation{}, &ClusterEvacuationList{})
+}
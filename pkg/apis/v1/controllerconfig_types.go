@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ControllerConfigSpec defines the configuration of the controller. Fields
+// that aren't set keep the value configured by the controller flags
+type ControllerConfigSpec struct {
+	// defaultZone is the zone managed hosts are assigned from, and that
+	// records not referencing a ManagedZone are published to
+	// +optional
+	DefaultZone *DefaultZone `json:"defaultZone,omitempty"`
+	// certificateIssuer is the name of the ClusterIssuer issuing the
+	// certificates of managed hosts
+	// +optional
+	CertificateIssuer string `json:"certificateIssuer,omitempty"`
+	// certificateAuthorities are the CAA issuer domains of the certificate
+	// authority behind the certificateIssuer, e.g. letsencrypt.org. When
+	// set, a CAA record allowing them is published for each managed host,
+	// and the ManagedHost reports whether the CAA records of the host allow
+	// its certificate to be issued
+	// +optional
+	CertificateAuthorities []string `json:"certificateAuthorities,omitempty"`
+	// sync configures how traffic objects and DNS records are synchronised
+	// +optional
+	Sync *SyncOptions `json:"sync,omitempty"`
+	// provider configures the DNS provider
+	// +optional
+	Provider *ProviderOptions `json:"provider,omitempty"`
+	// privateTraffic configures the traffic objects exposed within the
+	// fleet only
+	// +optional
+	PrivateTraffic *PrivateTrafficOptions `json:"privateTraffic,omitempty"`
+	// tenancy gives the traffic objects of each namespace their own
+	// subdomain of a root zone
+	// +optional
+	Tenancy *TenancyOptions `json:"tenancy,omitempty"`
+	// hostGeneration configures how the managed hosts are generated in the
+	// zones that don't configure their own
+	// +optional
+	HostGeneration *HostGeneration `json:"hostGeneration,omitempty"`
+	// featureGates enables or disables features by name
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// logLevels sets the log verbosity of subsystems of the controller by
+	// name: dns, tls, syncer or placement. Subsystems that aren't set log
+	// with the verbosity of the controller flags
+	// +optional
+	LogLevels map[string]int `json:"logLevels,omitempty"`
+}
+
+// DefaultZone is the zone used when no ManagedZone is referenced
+type DefaultZone struct {
+	// id is the provider identifier of the hosted zone. The records
+	// published to the previous default zone are moved to the new one
+	// when it changes
+	// +kubebuilder:validation:MinLength=1
+	ID string `json:"id"`
+	// rootDomain is the domain managed hosts are generated under
+	// +kubebuilder:validation:MinLength=1
+	RootDomain string `json:"rootDomain"`
+}
+
+// SyncOptions configures how traffic objects and DNS records are
+// synchronised
+type SyncOptions struct {
+	// dnsVerifyInterval is how often published DNS records are verified
+	// against the DNS provider. Zero disables verification
+	// +optional
+	DNSVerifyInterval *metav1.Duration `json:"dnsVerifyInterval,omitempty"`
+	// httpsRedirect redirects plain HTTP requests to HTTPS for the managed
+	// hosts TLS is provisioned for
+	// +optional
+	HTTPSRedirect *bool `json:"httpsRedirect,omitempty"`
+	// clusterHostnames publishes a <cluster>.<host> hostname for each cluster
+	// alongside each managed host
+	// +optional
+	ClusterHostnames *bool `json:"clusterHostnames,omitempty"`
+	// hostCollision is how a host already managed for another traffic
+	// object is handled. Merge publishes the endpoints of both traffic
+	// objects for the host, Reject leaves the host to the traffic object it
+	// was first managed for
+	// +optional
+	HostCollision HostCollisionPolicy `json:"hostCollision,omitempty"`
+	// admissionHostCollision is how the admission webhook handles a traffic
+	// object requesting a host already managed for another traffic object
+	// or claimed by a HostClaim it doesn't reference. Allow admits it, Warn
+	// admits it with a warning, Deny rejects it
+	// +optional
+	AdmissionHostCollision AdmissionHostCollisionPolicy `json:"admissionHostCollision,omitempty"`
+	// hostReuseCooldown is how long a released host can't be assigned to the
+	// traffic objects or HostClaims of another namespace than the one it was
+	// released by, so resolvers and clients caching its records or
+	// certificate don't send the traffic of the previous owner to the new
+	// one. Zero disables the cooldown
+	// +optional
+	HostReuseCooldown *metav1.Duration `json:"hostReuseCooldown,omitempty"`
+}
+
+// HostCollisionPolicy is how a host managed for more than one traffic
+// object is handled
+// +kubebuilder:validation:Enum=Merge;Reject
+type HostCollisionPolicy string
+
+const (
+	HostCollisionMerge  HostCollisionPolicy = "Merge"
+	HostCollisionReject HostCollisionPolicy = "Reject"
+)
+
+// AdmissionHostCollisionPolicy is how the admission webhook handles a
+// traffic object requesting a host owned by another object
+// +kubebuilder:validation:Enum=Allow;Warn;Deny
+type AdmissionHostCollisionPolicy string
+
+const (
+	AdmissionHostCollisionAllow AdmissionHostCollisionPolicy = "Allow"
+	AdmissionHostCollisionWarn  AdmissionHostCollisionPolicy = "Warn"
+	AdmissionHostCollisionDeny  AdmissionHostCollisionPolicy = "Deny"
+)
+
+// ProviderOptions configures the DNS provider
+type ProviderOptions struct {
+	// zoneCredentialsNamespaces are the namespaces whose ManagedZones can
+	// reference their own DNS provider credentials
+	// +optional
+	ZoneCredentialsNamespaces []string `json:"zoneCredentialsNamespaces,omitempty"`
+}
+
+// PrivateTrafficOptions configures the traffic objects annotated with
+// kuadrant.io/visibility: private
+type PrivateTrafficOptions struct {
+	// zone is the name of the Private ManagedZone, in the controller
+	// namespace, the managed hosts of private traffic objects are assigned
+	// from
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// fleetCIDRs are the source ranges of the fleet, the pod CIDRs of the
+	// clusters or their global CIDRs when connected by Submariner. The
+	// backends of private traffic objects only accept traffic from them
+	// +optional
+	FleetCIDRs []string `json:"fleetCIDRs,omitempty"`
+}
+
+// TenancyOptions configures the namespace-as-tenant mode, where the traffic
+// objects of each namespace are assigned hosts of the `<namespace>.<root>`
+// subdomain of the root zone, and can't be assigned hosts outside of it
+type TenancyOptions struct {
+	// rootZone is the name of the public ManagedZone, in the controller
+	// namespace, the subdomains of the namespaces are published to. The
+	// tenant mode is disabled when empty
+	// +optional
+	RootZone string `json:"rootZone,omitempty"`
+	// maxHosts is the number of hosts the traffic objects and HostClaims of
+	// each namespace can be assigned. Zero means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxHosts *int `json:"maxHosts,omitempty"`
+}
+
+// ControllerConfigStatus defines the observed state of ControllerConfig
+type ControllerConfigStatus struct {
+	// observedGeneration is the most recently observed generation of the
+	// ControllerConfig.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// conditions are any conditions associated with the configuration.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ControllerConfigAppliedConditionType is set to true once the
+	// configuration is in use by the controller
+	ControllerConfigAppliedConditionType = "Applied"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ControllerConfig is the Schema for the controllerconfigs API
+type ControllerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ControllerConfigSpec   `json:"spec,omitempty"`
+	Status ControllerConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ControllerConfigList contains a list of ControllerConfig
+type ControllerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ControllerConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ControllerConfig{}, &ControllerConfigList{})
+}
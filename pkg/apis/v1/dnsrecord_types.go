@@ -18,6 +18,7 @@ package v1
 
 import (
 	"fmt"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -52,15 +53,34 @@ type ProviderSpecific []ProviderSpecificProperty
 // Endpoint is a high-level way of a connection between a service and an IP
 type Endpoint struct {
 	// The hostname of the DNS record
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^(\*\.)?([a-zA-Z0-9_]([-a-zA-Z0-9_]*[a-zA-Z0-9_])?\.)*[a-zA-Z0-9_]([-a-zA-Z0-9_]*[a-zA-Z0-9_])?\.?$`
 	DNSName string `json:"dnsName,omitempty"`
 	// The targets the DNS record points to
+	// +kubebuilder:validation:MinItems=1
 	Targets Targets `json:"targets,omitempty"`
-	// RecordType type of record, e.g. CNAME, A, SRV, TXT etc
+	// RecordType type of record, e.g. CNAME, A, TXT etc
+	// +kubebuilder:validation:Enum=CNAME;A;AAAA;NS;TXT;MX;CAA;SRV;HTTPS;SVCB;DS
 	RecordType string `json:"recordType,omitempty"`
 	// Identifier to distinguish multiple records with the same name and type (e.g. Route53 records with routing policies other than 'simple')
 	SetIdentifier string `json:"setIdentifier,omitempty"`
-	// TTL for the record
+	// TTL for the record. When not set, the record inherits the TTL of the
+	// DNSRecord
+	// +kubebuilder:validation:Minimum=0
 	RecordTTL TTL `json:"recordTTL,omitempty"`
+	// weight is the relative weight of the record among the records with
+	// the same name and type, answered in proportion to their weights
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	Weight *int64 `json:"weight,omitempty"`
+	// geoLocation routes the queries from the location to the record
+	// +optional
+	GeoLocation *GeoLocation `json:"geoLocation,omitempty"`
+	// healthCheckID is the identifier of the provider health check the
+	// record is answered by while healthy
+	// +optional
+	HealthCheckID string `json:"healthCheckID,omitempty"`
 	// Labels stores labels defined for the Endpoint
 	// +optional
 	Labels Labels `json:"labels,omitempty"`
@@ -69,6 +89,25 @@ type Endpoint struct {
 	ProviderSpecific ProviderSpecific `json:"providerSpecific,omitempty"`
 }
 
+// GeoLocation is the location of the queries answered by a record. Either
+// the continent or the country, optionally with its subdivision, is set
+type GeoLocation struct {
+	// continentCode is the two letter code of the continent, e.g. EU
+	// +kubebuilder:validation:Enum=AF;AN;AS;EU;NA;OC;SA
+	// +optional
+	ContinentCode string `json:"continentCode,omitempty"`
+	// countryCode is the ISO 3166-1 alpha-2 code of the country, e.g. IE,
+	// or * for the queries from any other location
+	// +kubebuilder:validation:Pattern=`^([A-Z]{2}|\*)$`
+	// +optional
+	CountryCode string `json:"countryCode,omitempty"`
+	// subdivisionCode is the code of the subdivision of the country, e.g.
+	// the state of the United States
+	// +kubebuilder:validation:Pattern=`^[A-Z0-9]{1,3}$`
+	// +optional
+	SubdivisionCode string `json:"subdivisionCode,omitempty"`
+}
+
 // WithSetIdentifier applies the given set identifier to the endpoint.
 func (e *Endpoint) WithSetIdentifier(setIdentifier string) *Endpoint {
 	e.SetIdentifier = setIdentifier
@@ -105,9 +144,92 @@ func (e *Endpoint) String() string {
 
 // DNSRecordSpec defines the desired state of DNSRecord
 type DNSRecordSpec struct {
+	// managedZone is the ManagedZone the record is published to. When not
+	// set, the record is published to the zones configured on the controller
+	// +optional
+	ManagedZoneRef *ManagedZoneReference `json:"managedZone,omitempty"`
+	// ttl is the TTL of the endpoints and raw records that don't set their
+	// own recordTTL
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTL TTL `json:"ttl,omitempty"`
 	// +kubebuilder:validation:MinItems=1
 	// +optional
 	Endpoints []*Endpoint `json:"endpoints"`
+	// rawRecords are extra records published alongside the endpoints, such
+	// as TXT records for domain verification, MX or CAA records. They are
+	// managed by the user and left untouched by the controllers generating
+	// the endpoints. A raw record without a dnsName is published for the
+	// name of the DNSRecord. The dnsName of a raw record must be the name of
+	// the DNSRecord, or a name under it whose extra labels start with an
+	// underscore, such as _acme-challenge or _dmarc, so a DNSRecord can't
+	// publish records for the hosts of other DNSRecords. The raw records
+	// with other names aren't published
+	// +optional
+	RawRecords []*Endpoint `json:"rawRecords,omitempty"`
+}
+
+// PublishedEndpoints returns the endpoints and raw records of the record,
+// which are the records published to its zones. Records without a TTL
+// inherit the TTL of the DNSRecord, and the raw records outside of the host
+// of the record are left out
+func (r *DNSRecord) PublishedEndpoints() []*Endpoint {
+	endpoints := make([]*Endpoint, 0, len(r.Spec.Endpoints)+len(r.Spec.RawRecords))
+	for _, endpoint := range r.Spec.Endpoints {
+		endpoints = append(endpoints, r.inheritTTL(endpoint))
+	}
+	for _, raw := range r.Spec.RawRecords {
+		if raw.DNSName == "" {
+			raw = raw.DeepCopy()
+			raw.DNSName = r.Name
+		}
+		if !r.InScope(raw.DNSName) {
+			continue
+		}
+		endpoints = append(endpoints, r.inheritTTL(raw))
+	}
+	return endpoints
+}
+
+// InScope returns true when a raw record can be published for the name: the
+// name of the record, or a name under it whose extra labels all start with
+// an underscore
+func (r *DNSRecord) InScope(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	host := strings.ToLower(r.Name)
+	if name == host {
+		return true
+	}
+	if !strings.HasSuffix(name, "."+host) {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."+host), ".") {
+		if !strings.HasPrefix(label, "_") {
+			return false
+		}
+	}
+	return true
+}
+
+// RejectedRawRecords returns the names of the raw records of the record that
+// aren't published, as they are outside of its host
+func (r *DNSRecord) RejectedRawRecords() []string {
+	rejected := []string{}
+	for _, raw := range r.Spec.RawRecords {
+		if raw.DNSName != "" && !r.InScope(raw.DNSName) {
+			rejected = append(rejected, raw.DNSName)
+		}
+	}
+	return rejected
+}
+
+func (r *DNSRecord) inheritTTL(endpoint *Endpoint) *Endpoint {
+	if endpoint.RecordTTL != 0 || r.Spec.TTL == 0 {
+		return endpoint
+	}
+	endpoint = endpoint.DeepCopy()
+	endpoint.RecordTTL = r.Spec.TTL
+	return endpoint
 }
 
 // DNSRecordStatus defines the observed state of DNSRecord
@@ -123,8 +245,28 @@ type DNSRecordStatus struct {
 	// needs to retry the update for that specific zone.
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// conditions are any conditions associated with the record as a whole.
+	//
+	// While reconciliation of the record is paused, the "Paused" condition is
+	// set to true. While the provider fails to delete a deleted record, the
+	// "DeletionFailed" condition is set to true. While the changes to the
+	// record are held by the change freeze or the change windows of its
+	// zone, the "ChangesQueued" condition is set to true. The "Consistent"
+	// condition of records published to more than one zone is set to true
+	// while the record is published to each of them. While the record is
+	// handed over to another control plane, the "HandedOver" condition is
+	// set to true. While raw records are outside of the host of the record,
+	// the "RawRecordsRejected" condition is set to true.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+//+kubebuilder:printcolumn:name="Zone",type="string",JSONPath=".spec.managedZone.name"
+//+kubebuilder:printcolumn:name="Paused",type="string",JSONPath=".status.conditions[?(@.type==\"Paused\")].status"
+//+kubebuilder:printcolumn:name="Queued",type="string",JSONPath=".status.conditions[?(@.type==\"ChangesQueued\")].status",priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:resource:shortName=dnsr
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 
@@ -147,7 +289,7 @@ type DNSRecordList struct {
 }
 
 // DNSRecordType is a DNS resource record type.
-// +kubebuilder:validation:Enum=CNAME;A
+// +kubebuilder:validation:Enum=CNAME;A;AAAA;NS;TXT;MX;CAA;SRV;HTTPS;SVCB;DS
 type DNSRecordType string
 
 const (
@@ -156,6 +298,33 @@ const (
 
 	// ARecordType is an RFC 1035 A record.
 	ARecordType DNSRecordType = "A"
+
+	// AAAARecordType is an RFC 3596 AAAA record.
+	AAAARecordType DNSRecordType = "AAAA"
+
+	// NSRecordType is an RFC 1035 NS record.
+	NSRecordType DNSRecordType = "NS"
+
+	// TXTRecordType is an RFC 1035 TXT record.
+	TXTRecordType DNSRecordType = "TXT"
+
+	// MXRecordType is an RFC 1035 MX record.
+	MXRecordType DNSRecordType = "MX"
+
+	// CAARecordType is an RFC 8659 CAA record.
+	CAARecordType DNSRecordType = "CAA"
+
+	// SRVRecordType is an RFC 2782 SRV record.
+	SRVRecordType DNSRecordType = "SRV"
+
+	// HTTPSRecordType is an RFC 9460 HTTPS record.
+	HTTPSRecordType DNSRecordType = "HTTPS"
+
+	// SVCBRecordType is an RFC 9460 SVCB record.
+	SVCBRecordType DNSRecordType = "SVCB"
+
+	// DSRecordType is an RFC 4034 DS record.
+	DSRecordType DNSRecordType = "DS"
 )
 
 // DNSZone is used to define a DNS hosted zone.
@@ -194,7 +363,8 @@ type DNSZoneStatus struct {
 	// endpoints are the last endpoints that were successfully published to the provider
 	//
 	// Provides a simple mechanism to store the current provider records in order to
-	// delete any that are no longer present in DNSRecordSpec.Endpoints
+	// delete any that are no longer present in DNSRecordSpec.Endpoints or
+	// DNSRecordSpec.RawRecords
 	//
 	// Note: This will not be required if/when we switch to using external-dns since when
 	// running with a "sync" policy it will clean up unused records automatically.
@@ -204,6 +374,22 @@ type DNSZoneStatus struct {
 var (
 	// Failed means the record is not available within a zone.
 	DNSRecordFailedConditionType = "Failed"
+	// Paused means reconciliation of the record is paused.
+	DNSRecordPausedConditionType = "Paused"
+	// HandedOver means the record is handed over to another control plane,
+	// and is no longer written to the provider.
+	DNSRecordHandedOverConditionType = "HandedOver"
+	// DeletionFailed means the provider failed to delete the record, which
+	// is kept until deletion succeeds.
+	DNSRecordDeletionFailedConditionType = "DeletionFailed"
+	// ChangesQueued means the changes to the record aren't written to the
+	// provider while its zone is frozen or outside of its change windows.
+	DNSRecordChangesQueuedConditionType = "ChangesQueued"
+	// Consistent means the record is published to each of its zones.
+	DNSRecordConsistentConditionType = "Consistent"
+	// RawRecordsRejected means raw records of the record are outside of its
+	// host, and aren't published.
+	DNSRecordRawRecordsRejectedConditionType = "RawRecordsRejected"
 )
 
 // DNSZoneCondition is just the standard condition fields.
@@ -230,6 +416,9 @@ type Target struct {
 	Cluster    string
 	TargetType string
 	Value      string
+	// Weight is the relative weight of the target among the targets of the
+	// same traffic object
+	Weight int
 }
 
 func (endpoint *Endpoint) GetAddress() (string, bool) {
@@ -247,9 +436,9 @@ func (endpoint *Endpoint) SetProviderSpecific(name, value string) {
 		endpoint.ProviderSpecific = ProviderSpecific{}
 	}
 
-	for _, pair := range endpoint.ProviderSpecific {
-		if pair.Name == name {
-			property = &pair
+	for i := range endpoint.ProviderSpecific {
+		if endpoint.ProviderSpecific[i].Name == name {
+			property = &endpoint.ProviderSpecific[i]
 		}
 	}
 
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"testing"
+)
+
+func TestDNSRecord_PublishedEndpoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   DNSRecordSpec
+		expect []TTL
+	}{
+		{
+			name: "endpoints set their own TTL",
+			spec: DNSRecordSpec{
+				TTL:       300,
+				Endpoints: []*Endpoint{{DNSName: "test.example.com", RecordTTL: 60}},
+			},
+			expect: []TTL{60},
+		},
+		{
+			name: "endpoints inherit the TTL of the record",
+			spec: DNSRecordSpec{
+				TTL:        300,
+				Endpoints:  []*Endpoint{{DNSName: "test.example.com"}, {DNSName: "test.example.com", RecordTTL: 60}},
+				RawRecords: []*Endpoint{{RecordType: "TXT"}},
+			},
+			expect: []TTL{300, 60, 300},
+		},
+		{
+			name: "raw records outside of the host are left out",
+			spec: DNSRecordSpec{
+				TTL:       300,
+				Endpoints: []*Endpoint{{DNSName: "test.example.com"}},
+				RawRecords: []*Endpoint{
+					{DNSName: "_acme-challenge.test.example.com", RecordType: "TXT"},
+					{DNSName: "other.example.com", RecordType: "TXT"},
+				},
+			},
+			expect: []TTL{300, 300},
+		},
+		{
+			name: "record without a TTL",
+			spec: DNSRecordSpec{
+				Endpoints: []*Endpoint{{DNSName: "test.example.com"}},
+			},
+			expect: []TTL{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &DNSRecord{Spec: tt.spec}
+			record.Name = "test.example.com"
+			endpoints := record.PublishedEndpoints()
+			if len(endpoints) != len(tt.expect) {
+				t.Fatalf("expected '%v' endpoints got '%v'", len(tt.expect), len(endpoints))
+			}
+			for i, endpoint := range endpoints {
+				if endpoint.RecordTTL != tt.expect[i] {
+					t.Errorf("expected '%v' got '%v'", tt.expect[i], endpoint.RecordTTL)
+				}
+			}
+			if record.Spec.Endpoints[0].RecordTTL == tt.spec.TTL && tt.spec.TTL != 0 {
+				t.Errorf("expected the endpoints of the record to be left untouched")
+			}
+		})
+	}
+}
+
+func TestDNSRecord_InScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect bool
+	}{
+		{name: "test.example.com", expect: true},
+		{name: "Test.Example.com.", expect: true},
+		{name: "_acme-challenge.test.example.com", expect: true},
+		{name: "_sip._tcp.test.example.com", expect: true},
+		{name: "www.test.example.com", expect: false},
+		{name: "_dmarc.www.test.example.com", expect: false},
+		{name: "other.example.com", expect: false},
+		{name: "_acme-challenge.example.com", expect: false},
+		{name: "fooest.example.com", expect: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &DNSRecord{}
+			record.Name = "test.example.com"
+			if got := record.InScope(tt.name); got != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HostClaimSpec defines the desired state of HostClaim
+type HostClaimSpec struct {
+	// host is the hostname claimed. It must be a subdomain of the domain of
+	// a public ManagedZone of the controller
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([a-z0-9]([-a-z0-9]*[a-z0-9])?\.)+[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Host string `json:"host"`
+
+	// expiresAfter is the time after the creation of the claim it's deleted,
+	// releasing the host and deleting its DNS records and certificate. Meant
+	// for short-lived hosts, such as the preview environments of pull
+	// requests. The claim doesn't expire when unset
+	// +optional
+	ExpiresAfter *metav1.Duration `json:"expiresAfter,omitempty"`
+}
+
+// HostClaimStatus defines the observed state of HostClaim
+type HostClaimStatus struct {
+	// observedGeneration is the most recently observed generation of the
+	// HostClaim.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// managedZone is the name of the ManagedZone the host is published to
+	// +optional
+	ManagedZone string `json:"managedZone,omitempty"`
+
+	// expiresAt is the time the claim is deleted at, when it expires
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// conditions are any conditions associated with the claim.
+	//
+	// The "Claimed" condition is set to true once the host is reserved for
+	// the claim, and to false while the host is claimed by another
+	// HostClaim, managed for a traffic object, or outside the zones of the
+	// controller.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	HostClaimClaimedConditionType = "Claimed"
+)
+
+//+kubebuilder:printcolumn:name="Host",type="string",JSONPath=".spec.host"
+//+kubebuilder:printcolumn:name="Zone",type="string",JSONPath=".status.managedZone"
+//+kubebuilder:printcolumn:name="Expires",type="date",JSONPath=".status.expiresAt",priority=1
+//+kubebuilder:printcolumn:name="Claimed",type="string",JSONPath=".status.conditions[?(@.type==\"Claimed\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:resource:shortName=hc
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// HostClaim is the Schema for the hostclaims API. It reserves a hostname
+// for the traffic objects of the namespace it's created in, which reference
+// it by name with the kuadrant.io/host-claims annotation. Teams can claim
+// hosts in the zones owned by the platform without access to the zones or
+// to the DNSRecords of the controller
+type HostClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HostClaimSpec   `json:"spec,omitempty"`
+	Status HostClaimStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HostClaimList contains a list of HostClaim
+type HostClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HostClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HostClaim{}, &HostClaimList{})
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HostPoolSpec defines the desired state of HostPool
+type HostPoolSpec struct {
+	// managedZoneRef is the zone the hosts of the pool are provisioned in
+	ManagedZoneRef ManagedZoneReference `json:"managedZoneRef"`
+
+	// size is the number of hosts kept provisioned, ready to be assigned
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Size int `json:"size"`
+}
+
+// HostPoolStatus defines the observed state of HostPool
+type HostPoolStatus struct {
+	// observedGeneration is the most recently observed generation of the
+	// HostPool.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// provisioned is the number of hosts of the pool not assigned yet
+	// +optional
+	Provisioned int `json:"provisioned,omitempty"`
+
+	// available is the number of hosts of the pool with their certificate
+	// issued, assigned to the traffic objects first
+	// +optional
+	Available int `json:"available,omitempty"`
+
+	// conditions are any conditions associated with the pool.
+	//
+	// The "Ready" condition is set to true once all the hosts of the pool
+	// are available.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	HostPoolReadyConditionType = "Ready"
+)
+
+//+kubebuilder:printcolumn:name="Zone",type="string",JSONPath=".spec.managedZoneRef.name"
+//+kubebuilder:printcolumn:name="Size",type="integer",JSONPath=".spec.size"
+//+kubebuilder:printcolumn:name="Available",type="integer",JSONPath=".status.available"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:resource:shortName=hp
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// HostPool is the Schema for the hostpools API. It keeps hosts of a zone
+// provisioned ahead of time, with their DNSRecord and certificate, so that
+// the traffic objects the zone is selected for are assigned a host with a
+// certificate issued already instead of waiting for issuance. HostPools are
+// created in the controller namespace
+type HostPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HostPoolSpec   `json:"spec,omitempty"`
+	Status HostPoolStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HostPoolList contains a list of HostPool
+type HostPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HostPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HostPool{}, &HostPoolList{})
+}
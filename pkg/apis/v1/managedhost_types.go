@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ManagedHostSpec defines the desired state of ManagedHost
+type ManagedHostSpec struct {
+	// host is the managed hostname
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+	// trafficRef is the traffic object the host is assigned to
+	TrafficRef TrafficReference `json:"trafficRef"`
+}
+
+// TrafficReference identifies a traffic object in the workload clusters
+type TrafficReference struct {
+	// kind of the traffic object, e.g. Ingress
+	Kind string `json:"kind"`
+	// namespace of the traffic object
+	Namespace string `json:"namespace"`
+	// name of the traffic object
+	Name string `json:"name"`
+}
+
+// ManagedHostStatus defines the observed state of ManagedHost
+type ManagedHostStatus struct {
+	// observedGeneration is the most recently observed generation of the
+	// ManagedHost.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// dnsRecord is the name of the DNSRecord publishing the host
+	// +optional
+	DNSRecord string `json:"dnsRecord,omitempty"`
+
+	// certificate is the name of the Certificate issued for the host
+	// +optional
+	Certificate string `json:"certificate,omitempty"`
+
+	// issuanceFailures is the number of failed attempts to issue the
+	// certificate of the host since it was last issued
+	// +optional
+	IssuanceFailures int `json:"issuanceFailures,omitempty"`
+
+	// lastIssuanceFailureTime is the time of the last failed attempt to
+	// issue the certificate of the host
+	// +optional
+	LastIssuanceFailureTime *metav1.Time `json:"lastIssuanceFailureTime,omitempty"`
+
+	// clusters are the clusters the traffic of the host is served from
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// placement are the clusters the ApplicationSet generator last placed
+	// the traffic object of the host on. Unlike clusters, they're kept
+	// while the endpoints of unhealthy clusters are withdrawn
+	// +optional
+	Placement []string `json:"placement,omitempty"`
+
+	// conditions are any conditions associated with the host.
+	//
+	// The "DNSPublished" condition is set to true once the DNSRecord of the
+	// host is published to all its zones, and the "CertificateReady"
+	// condition once the certificate of the host is issued. The "Ready"
+	// condition is set to true when both are. While cert-manager fails to
+	// issue the certificate the "CertificateReady" condition carries the
+	// reason of the failure.
+	//
+	// The "ResourcesSynced" condition is set to true once the TLS secret of
+	// the host is synced to every cluster serving it, and the
+	// "PlacementSatisfied" condition while a healthy cluster serves it. The
+	// "PlacementBlocked" condition is set to true while clusters are
+	// excluded from the placement of its traffic object for not meeting
+	// its minimum Kubernetes or Gateway API versions.
+	//
+	// When certificate authorities are configured, the "CAAAllowed"
+	// condition is set to false while the CAA records of the host would
+	// block the issuance of its certificate.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	ManagedHostDNSPublishedConditionType       = "DNSPublished"
+	ManagedHostCertificateReadyConditionType   = "CertificateReady"
+	ManagedHostReadyConditionType              = "Ready"
+	ManagedHostCAAAllowedConditionType         = "CAAAllowed"
+	ManagedHostResourcesSyncedConditionType    = "ResourcesSynced"
+	ManagedHostPlacementSatisfiedConditionType = "PlacementSatisfied"
+	ManagedHostPlacementBlockedConditionType   = "PlacementBlocked"
+)
+
+//+kubebuilder:printcolumn:name="Host",type="string",JSONPath=".spec.host"
+//+kubebuilder:printcolumn:name="Traffic",type="string",JSONPath=".spec.trafficRef.name"
+//+kubebuilder:printcolumn:name="Clusters",type="string",JSONPath=".status.clusters"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:resource:shortName=mhost
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ManagedHost is the Schema for the managedhosts API. It shows the
+// lifecycle state of a managed hostname: the traffic object it's assigned
+// to, its DNSRecord and certificate, and the clusters serving it
+type ManagedHost struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ManagedHostSpec   `json:"spec,omitempty"`
+	Status ManagedHostStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ManagedHostList contains a list of ManagedHost
+type ManagedHostList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ManagedHost `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ManagedHost{}, &ManagedHostList{})
+}
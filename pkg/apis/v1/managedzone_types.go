@@ -0,0 +1,312 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ManagedZoneSpec defines the desired state of ManagedZone
+type ManagedZoneSpec struct {
+	// id is the provider identifier of the hosted zone
+	// +kubebuilder:validation:MinLength=1
+	ID string `json:"id"`
+	// domainName is the root domain of the hosted zone
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([a-z0-9]([-a-z0-9]*[a-z0-9])?\.)*[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	DomainName string `json:"domainName"`
+	// description of the zone
+	// +optional
+	Description string `json:"description,omitempty"`
+	// providerCredentialsRef references a secret in the namespace of the zone
+	// holding the credentials used to manage the records of the zone. When
+	// not set, the credentials of the controller are used, which only
+	// zones in the namespace of the controller can.
+	//
+	// Only zones in namespaces allowed by the controller configuration can
+	// reference their own credentials.
+	// +optional
+	ProviderCredentialsRef *ProviderCredentialsReference `json:"providerCredentialsRef,omitempty"`
+	// visibility is where the records of the zone resolve. The records of
+	// Private zones, resolved within clusters connected by Submariner or
+	// Cilium ClusterMesh, get only the private addresses of the load
+	// balancers or ingresses of traffic objects published, e.g. the ones of
+	// internal load balancers.
+	// +kubebuilder:default=Public
+	// +optional
+	Visibility ZoneVisibility `json:"visibility,omitempty"`
+	// wildcard serves the managed hosts of the zone with a single wildcard
+	// DNS record and certificate, *.domainName, instead of a record and a
+	// certificate for each host. The load balancer addresses of the traffic
+	// objects are published in the wildcard record for their cluster, so
+	// every traffic object of the zone must be served by the load balancers
+	// of its cluster. The addresses are removed when the cluster is
+	// evacuated, and aren't weighted or withdrawn per traffic object.
+	// +optional
+	Wildcard bool `json:"wildcard,omitempty"`
+	// manageDSRecord publishes the DS records of the zone in the ManagedZone
+	// of its parent domain in the same namespace while the zone is signed
+	// with DNSSEC, so resolvers can validate the chain of trust to the
+	// zone. The DS records are removed once the zone stops signing, or when
+	// this field or the zone is removed, which must be done before signing
+	// is disabled in the provider.
+	// +optional
+	ManageDSRecord bool `json:"manageDSRecord,omitempty"`
+	// changeFreeze stops the changes to the records of the zone from being
+	// written to the provider, including the deletion of records and the
+	// repair of records changed out of band. The changes are queued,
+	// reported by the ChangesQueued condition of the DNSRecords, and written
+	// once the freeze is lifted.
+	// +optional
+	ChangeFreeze bool `json:"changeFreeze,omitempty"`
+	// changeWindows restrict the changes written to the provider to the
+	// windows. The changes made outside of the windows are queued until the
+	// next window opens. When empty, changes are written at any time.
+	// +optional
+	ChangeWindows []ChangeWindow `json:"changeWindows,omitempty"`
+	// secondaryZones are hosted zones of the domain in other DNS providers or
+	// accounts the records of the zone are published to as well, so the
+	// domain keeps resolving when a provider fails. The domain must be
+	// delegated to the name servers of each of the hosted zones. The records
+	// are repaired in each zone they drift in, and the DNSRecords report the
+	// zones they aren't published to with their Consistent condition.
+	// Records aren't removed from a hosted zone removed from the secondary
+	// zones.
+	// +optional
+	SecondaryZones []SecondaryZone `json:"secondaryZones,omitempty"`
+	// hostGeneration configures how the managed hosts of the zone are
+	// generated. When not set, the host generation of the controller is
+	// used
+	// +optional
+	HostGeneration *HostGeneration `json:"hostGeneration,omitempty"`
+	// reservedHosts are the names, relative to the domain of the zone, that
+	// aren't generated for traffic objects, claimed by HostClaims or
+	// admitted as hosts of traffic objects. A name can be a pattern where *
+	// matches any characters, e.g. *.internal
+	// +optional
+	ReservedHosts []string `json:"reservedHosts,omitempty"`
+	// allowedNamespaces are the namespaces of the traffic objects that can
+	// select the zone with the kuadrant.io/managed-zone annotation, or * for
+	// every namespace. When empty, only the namespaces the zone is the
+	// default zone of can select it
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+// HostGeneration configures how the managed hosts of traffic objects are
+// generated
+type HostGeneration struct {
+	// strategy generates the managed hosts. ShortUUID generates a short UUID
+	// of the namespace and name of the traffic object, Hash a hash of them,
+	// Words human readable words derived from them, Sequential the next
+	// number of the zone, host-<n>, and Template expands the template
+	// +kubebuilder:default=ShortUUID
+	// +optional
+	Strategy HostGenerationStrategy `json:"strategy,omitempty"`
+	// template of the managed hosts of the Template strategy, expanding
+	// {name}, {ns} and {kind} of the traffic object and {zone}, the domain
+	// of the zone, e.g. {name}-{ns}.{zone}. The template must expand {ns},
+	// and dots of {name} and {ns} are replaced by dashes. The domain of the
+	// zone is appended when the template doesn't end with {zone}
+	// +optional
+	Template string `json:"template,omitempty"`
+}
+
+// HostGenerationStrategy is how the managed hosts are generated
+// +kubebuilder:validation:Enum=ShortUUID;Hash;Words;Sequential;Template
+type HostGenerationStrategy string
+
+const (
+	HostGenerationShortUUID  HostGenerationStrategy = "ShortUUID"
+	HostGenerationHash       HostGenerationStrategy = "Hash"
+	HostGenerationWords      HostGenerationStrategy = "Words"
+	HostGenerationSequential HostGenerationStrategy = "Sequential"
+	HostGenerationTemplate   HostGenerationStrategy = "Template"
+)
+
+// SecondaryZone is a hosted zone of the domain of a ManagedZone in another
+// DNS provider or account
+type SecondaryZone struct {
+	// provider of the hosted zone. When not set, the provider of the
+	// controller
+	// +kubebuilder:validation:Enum=aws
+	// +optional
+	Provider string `json:"provider,omitempty"`
+	// id is the provider identifier of the hosted zone
+	// +kubebuilder:validation:MinLength=1
+	ID string `json:"id"`
+	// providerCredentialsRef references a secret in the namespace of the zone
+	// holding the credentials used to manage the records of the hosted zone.
+	// When not set, the credentials of the controller are used, which
+	// requires the hosted zone to be of the provider of the controller and
+	// the zone to be in the namespace of the controller.
+	// +optional
+	ProviderCredentialsRef *ProviderCredentialsReference `json:"providerCredentialsRef,omitempty"`
+}
+
+// ChangeWindow is a daily window of time, in UTC, during which the changes
+// to the records of a zone are written to the provider. A window ending
+// before it starts ends on the next day
+type ChangeWindow struct {
+	// days of the week the window opens on. When empty, the window opens
+	// every day
+	// +optional
+	Days []Weekday `json:"days,omitempty"`
+	// start is the time the window opens, as HH:MM in UTC
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// end is the time the window closes, as HH:MM in UTC
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+// Weekday is a day of the week
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
+// ZoneVisibility is where the records of a zone resolve
+// +kubebuilder:validation:Enum=Public;Private
+type ZoneVisibility string
+
+const (
+	ZoneVisibilityPublic  ZoneVisibility = "Public"
+	ZoneVisibilityPrivate ZoneVisibility = "Private"
+)
+
+// ProviderCredentialsReference references the secret holding DNS provider
+// credentials.
+//
+// For AWS the secret is expected to contain the AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY keys, and optionally AWS_REGION.
+type ProviderCredentialsReference struct {
+	// name of the secret
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ManagedZoneStatus defines the observed state of ManagedZone
+type ManagedZoneStatus struct {
+	// observedGeneration is the most recently observed generation of the
+	// ManagedZone.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// conditions are any conditions associated with the zone.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// dnssec is the DNSSEC signing state of the zone, when the provider can
+	// report it.
+	// +optional
+	DNSSEC *ZoneDNSSECStatus `json:"dnssec,omitempty"`
+}
+
+// ZoneDNSSECStatus is the DNSSEC signing state of a zone
+type ZoneDNSSECStatus struct {
+	// state of the signing of the zone by the provider
+	State DNSSECState `json:"state"`
+	// dsRecords are the DS records of the active key signing keys of the
+	// zone, in presentation format, to publish in the parent zone
+	// +optional
+	DSRecords []string `json:"dsRecords,omitempty"`
+	// message reported by the provider about the signing of the zone, e.g.
+	// the action needed when its state is ActionNeeded
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DNSSECState is the state of the DNSSEC signing of a zone
+// +kubebuilder:validation:Enum=Signing;NotSigning;Deleting;ActionNeeded;Failed
+type DNSSECState string
+
+const (
+	DNSSECStateSigning      DNSSECState = "Signing"
+	DNSSECStateNotSigning   DNSSECState = "NotSigning"
+	DNSSECStateDeleting     DNSSECState = "Deleting"
+	DNSSECStateActionNeeded DNSSECState = "ActionNeeded"
+	DNSSECStateFailed       DNSSECState = "Failed"
+)
+
+const (
+	// ManagedZoneReadyConditionType is set to true when the records of the
+	// zone can be managed
+	ManagedZoneReadyConditionType = "Ready"
+	// ManagedZoneDeletionBlockedConditionType is set to true while the
+	// deletion of the zone is blocked by the DNSRecords referencing it
+	ManagedZoneDeletionBlockedConditionType = "DeletionBlocked"
+	// ManagedZoneDSRecordPublishedConditionType is set to true when the DS
+	// records of a zone managing them are published in its parent zone
+	ManagedZoneDSRecordPublishedConditionType = "DSRecordPublished"
+	// ManagedZoneHandedOverConditionType is set to true when the zone is
+	// handed over to another control plane, which manages its records
+	ManagedZoneHandedOverConditionType = "HandedOver"
+)
+
+//+kubebuilder:printcolumn:name="Domain",type="string",JSONPath=".spec.domainName"
+//+kubebuilder:printcolumn:name="ID",type="string",JSONPath=".spec.id",priority=1
+//+kubebuilder:printcolumn:name="Visibility",type="string",JSONPath=".spec.visibility"
+//+kubebuilder:printcolumn:name="Frozen",type="boolean",JSONPath=".spec.changeFreeze",priority=1
+//+kubebuilder:printcolumn:name="DNSSEC",type="string",JSONPath=".status.dnssec.state",priority=1
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:resource:shortName=mz
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ManagedZone is the Schema for the managedzones API
+type ManagedZone struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ManagedZoneSpec   `json:"spec,omitempty"`
+	Status ManagedZoneStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ManagedZoneList contains a list of ManagedZone
+type ManagedZoneList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ManagedZone `json:"items"`
+}
+
+// ManagedZoneReference references a ManagedZone in the same namespace
+type ManagedZoneReference struct {
+	// name of the ManagedZone
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// DNSZone returns the zone as used by the DNS providers
+func (z *ManagedZone) DNSZone() DNSZone {
+	return DNSZone{ID: z.Spec.ID}
+}
+
+// DNSZones returns the zone and its secondary zones as used by the DNS
+// providers
+func (z *ManagedZone) DNSZones() []DNSZone {
+	zones := []DNSZone{z.DNSZone()}
+	for _, secondary := range z.Spec.SecondaryZones {
+		zones = append(zones, DNSZone{ID: secondary.ID})
+	}
+	return zones
+}
+
+func init() {
+	SchemeBuilder.Register(&ManagedZone{}, &ManagedZoneList{})
+}
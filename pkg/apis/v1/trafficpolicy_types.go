@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyAction is what happens to a traffic object violating a rule
+// +kubebuilder:validation:Enum=Deny;Warn
+type PolicyAction string
+
+const (
+	// PolicyActionDeny rejects the traffic object at admission, and doesn't
+	// publish its DNS endpoints
+	PolicyActionDeny PolicyAction = "Deny"
+	// PolicyActionWarn returns a warning at admission, and flags the traffic
+	// object
+	PolicyActionWarn PolicyAction = "Warn"
+)
+
+// TrafficPolicySpec defines the desired state of TrafficPolicy
+type TrafficPolicySpec struct {
+	// rules are the constraints on traffic objects
+	// +kubebuilder:validation:MinItems=1
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule is a set of constraints on traffic objects. A traffic object
+// violates the rule when it doesn't meet any of the constraints set
+type PolicyRule struct {
+	// name identifies the rule in violation messages
+	Name string `json:"name"`
+	// action is what happens to traffic objects violating the rule
+	// +kubebuilder:default=Deny
+	// +optional
+	Action PolicyAction `json:"action,omitempty"`
+	// namespaces restricts the rule to the traffic objects in the
+	// namespaces. The rule applies to all namespaces when empty
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// hostSuffixes are the domains the hosts of traffic objects must be
+	// within, matching whole labels: .corp.example.com requires a subdomain
+	// of corp.example.com, corp.example.com also allows the domain itself.
+	// Managed hosts are not constrained
+	// +optional
+	HostSuffixes []string `json:"hostSuffixes,omitempty"`
+	// maxHosts is the maximum number of hosts of a traffic object, managed
+	// hosts excluded
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxHosts *int `json:"maxHosts,omitempty"`
+	// maxClusters is the maximum number of clusters serving each host of a
+	// traffic object. It's only checked at reconcile time, as the cluster
+	// of a traffic object isn't known at admission
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxClusters *int `json:"maxClusters,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TrafficPolicy is the Schema for the trafficpolicies API. The rules of the
+// TrafficPolicies in the controller namespace are evaluated against every
+// traffic object at admission and reconcile time
+type TrafficPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TrafficPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TrafficPolicyList contains a list of TrafficPolicy
+type TrafficPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TrafficPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TrafficPolicy{}, &TrafficPolicyList{})
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TrafficRolloutSpec defines the desired state of TrafficRollout
+type TrafficRolloutSpec struct {
+	// host is the managed host whose traffic is shifted. Its DNSRecord must
+	// be in the namespace of the rollout
+	Host string `json:"host"`
+	// from is the cluster traffic is shifted away from
+	From string `json:"from"`
+	// to is the cluster traffic is shifted to
+	To string `json:"to"`
+	// steps are the weights the traffic is shifted through, in order
+	// +kubebuilder:validation:MinItems=1
+	Steps []RolloutStep `json:"steps"`
+	// healthCheck gates each step on the health of the cluster traffic is
+	// shifted to. The rollout is rolled back when the check fails
+	// +optional
+	HealthCheck *RolloutHealthCheck `json:"healthCheck,omitempty"`
+}
+
+// RolloutStep is a step of a rollout
+type RolloutStep struct {
+	// weight is the percentage of the traffic of the host sent to the
+	// cluster traffic is shifted to during the step
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int `json:"weight"`
+	// pause is how long the step lasts before moving to the next one
+	// +optional
+	Pause metav1.Duration `json:"pause,omitempty"`
+}
+
+// RolloutHealthCheck is an HTTP check of the addresses published for the
+// cluster traffic is shifted to
+type RolloutHealthCheck struct {
+	// path requested on each address
+	// +kubebuilder:default=/
+	// +optional
+	Path string `json:"path,omitempty"`
+	// port requested on each address
+	// +kubebuilder:default=80
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// scheme of the request, HTTP or HTTPS
+	// +kubebuilder:validation:Enum=HTTP;HTTPS
+	// +kubebuilder:default=HTTP
+	// +optional
+	Scheme string `json:"scheme,omitempty"`
+	// interval between checks
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// failureThreshold is the number of consecutive failed checks after
+	// which the rollout is rolled back
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+	// maxErrorPercent is the maximum percentage of the requests for the
+	// host answered with a 5xx by the data plane of the cluster, when its
+	// request metrics are scraped
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	MaxErrorPercent *int `json:"maxErrorPercent,omitempty"`
+}
+
+// RolloutPhase is the phase of a rollout
+type RolloutPhase string
+
+const (
+	RolloutPhaseProgressing RolloutPhase = "Progressing"
+	RolloutPhaseCompleted   RolloutPhase = "Completed"
+	RolloutPhaseRolledBack  RolloutPhase = "RolledBack"
+)
+
+// TrafficRolloutStatus defines the observed state of TrafficRollout
+type TrafficRolloutStatus struct {
+	// observedGeneration is the most recently observed generation of the
+	// TrafficRollout.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// phase of the rollout: Progressing, Completed or RolledBack
+	// +optional
+	Phase RolloutPhase `json:"phase,omitempty"`
+
+	// currentStep is the index of the step in progress
+	// +optional
+	CurrentStep int `json:"currentStep,omitempty"`
+
+	// weight is the percentage of the traffic of the host currently sent to
+	// the cluster traffic is shifted to
+	// +optional
+	Weight int `json:"weight,omitempty"`
+
+	// stepStartTime is when the current step started
+	// +optional
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+
+	// failedChecks is the number of consecutive failed health checks
+	// +optional
+	FailedChecks int `json:"failedChecks,omitempty"`
+
+	// conditions are any conditions associated with the rollout.
+	//
+	// The "Healthy" condition reports the last health check of the cluster
+	// traffic is shifted to.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	TrafficRolloutHealthyConditionType = "Healthy"
+)
+
+//+kubebuilder:printcolumn:name="Host",type="string",JSONPath=".spec.host"
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="Weight",type="integer",JSONPath=".status.weight"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// TrafficRollout is the Schema for the trafficrollouts API. It gradually
+// shifts the DNS traffic of a managed host from one cluster to another,
+// rolling back when the cluster traffic is shifted to fails its health check
+type TrafficRollout struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TrafficRolloutSpec   `json:"spec,omitempty"`
+	Status TrafficRolloutStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TrafficRolloutList contains a list of TrafficRollout
+type TrafficRolloutList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TrafficRollout `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TrafficRollout{}, &TrafficRolloutList{})
+}
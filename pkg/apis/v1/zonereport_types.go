@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ZoneReportSpec defines the desired state of ZoneReport
+type ZoneReportSpec struct {
+	// managedZone is the ManagedZone reported on
+	ManagedZoneRef ManagedZoneReference `json:"managedZone"`
+}
+
+// ZoneReportStatus defines the observed state of ZoneReport
+type ZoneReportStatus struct {
+	// generatedAt is when the records of the zone were last compared
+	// +optional
+	GeneratedAt *metav1.Time `json:"generatedAt,omitempty"`
+
+	// extra, missing and mismatched are the number of records of each kind
+	// of drift, across the hosted zones of the zone
+	// +optional
+	Extra int `json:"extra"`
+	// +optional
+	Missing int `json:"missing"`
+	// +optional
+	Mismatched int `json:"mismatched"`
+
+	// zones are the reports of the hosted zone of the ManagedZone and of its
+	// secondary zones
+	// +optional
+	Zones []HostedZoneReport `json:"zones,omitempty"`
+}
+
+// HostedZoneReport compares the records published in a hosted zone against
+// the DNSRecords of the zone. Each list holds up to 100 records
+type HostedZoneReport struct {
+	// id is the provider identifier of the hosted zone
+	ID string `json:"id"`
+	// error is why the records of the hosted zone couldn't be compared
+	// +optional
+	Error string `json:"error,omitempty"`
+	// extra are the records published in the hosted zone that no DNSRecord
+	// of the zone publishes
+	// +optional
+	Extra []RecordDrift `json:"extra,omitempty"`
+	// missing are the records of the DNSRecords of the zone that aren't
+	// published in the hosted zone
+	// +optional
+	Missing []RecordDrift `json:"missing,omitempty"`
+	// mismatched are the records of the DNSRecords of the zone published in
+	// the hosted zone with other targets or TTL
+	// +optional
+	Mismatched []RecordDrift `json:"mismatched,omitempty"`
+	// repaired are the DNSRecords published again to the hosted zone to
+	// repair their missing and mismatched records
+	// +optional
+	Repaired []string `json:"repaired,omitempty"`
+}
+
+// RecordDrift is a record that differs between a hosted zone and the
+// DNSRecords of the zone
+type RecordDrift struct {
+	DNSName       string `json:"dnsName"`
+	RecordType    string `json:"recordType"`
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+	// dnsRecord is the name of the DNSRecord publishing the record
+	// +optional
+	DNSRecord string `json:"dnsRecord,omitempty"`
+	// expected are the values of the record in the DNSRecord
+	// +optional
+	Expected *RecordValues `json:"expected,omitempty"`
+	// published are the values of the record in the hosted zone
+	// +optional
+	Published *RecordValues `json:"published,omitempty"`
+}
+
+// RecordValues are the targets and TTL of a record
+type RecordValues struct {
+	Targets Targets `json:"targets,omitempty"`
+	// +optional
+	TTL TTL `json:"ttl,omitempty"`
+}
+
+//+kubebuilder:printcolumn:name="Zone",type="string",JSONPath=".spec.managedZone.name"
+//+kubebuilder:printcolumn:name="Extra",type="integer",JSONPath=".status.extra"
+//+kubebuilder:printcolumn:name="Missing",type="integer",JSONPath=".status.missing"
+//+kubebuilder:printcolumn:name="Mismatched",type="integer",JSONPath=".status.mismatched"
+//+kubebuilder:printcolumn:name="Generated",type="date",JSONPath=".status.generatedAt"
+//+kubebuilder:resource:shortName=zr
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ZoneReport is the Schema for the zonereports API. The controller
+// periodically compares the records published in the hosted zones of each
+// ManagedZone against its DNSRecords, and reports the drift in the
+// ZoneReport of the zone, of the same name
+type ZoneReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ZoneReportSpec   `json:"spec,omitempty"`
+	Status ZoneReportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ZoneReportList contains a list of ZoneReport
+type ZoneReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ZoneReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ZoneReport{}, &ZoneReportList{})
+}
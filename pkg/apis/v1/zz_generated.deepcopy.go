@@ -22,9 +22,286 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeWindow) DeepCopyInto(out *ChangeWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeWindow.
+func (in *ChangeWindow) DeepCopy() *ChangeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEvacuation) DeepCopyInto(out *ClusterEvacuation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEvacuation.
+func (in *ClusterEvacuation) DeepCopy() *ClusterEvacuation {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEvacuation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterEvacuation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEvacuationList) DeepCopyInto(out *ClusterEvacuationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterEvacuation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEvacuationList.
+func (in *ClusterEvacuationList) DeepCopy() *ClusterEvacuationList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEvacuationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterEvacuationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEvacuationSpec) DeepCopyInto(out *ClusterEvacuationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEvacuationSpec.
+func (in *ClusterEvacuationSpec) DeepCopy() *ClusterEvacuationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEvacuationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEvacuationStatus) DeepCopyInto(out *ClusterEvacuationStatus) {
+	*out = *in
+	if in.Records != nil {
+		in, out := &in.Records, &out.Records
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingRecords != nil {
+		in, out := &in.PendingRecords, &out.PendingRecords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetainedRecords != nil {
+		in, out := &in.RetainedRecords, &out.RetainedRecords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEvacuationStatus.
+func (in *ClusterEvacuationStatus) DeepCopy() *ClusterEvacuationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEvacuationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerConfig) DeepCopyInto(out *ControllerConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerConfig.
+func (in *ControllerConfig) DeepCopy() *ControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControllerConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerConfigList) DeepCopyInto(out *ControllerConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ControllerConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerConfigList.
+func (in *ControllerConfigList) DeepCopy() *ControllerConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControllerConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerConfigSpec) DeepCopyInto(out *ControllerConfigSpec) {
+	*out = *in
+	if in.DefaultZone != nil {
+		in, out := &in.DefaultZone, &out.DefaultZone
+		*out = new(DefaultZone)
+		**out = **in
+	}
+	if in.CertificateAuthorities != nil {
+		in, out := &in.CertificateAuthorities, &out.CertificateAuthorities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sync != nil {
+		in, out := &in.Sync, &out.Sync
+		*out = new(SyncOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Provider != nil {
+		in, out := &in.Provider, &out.Provider
+		*out = new(ProviderOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrivateTraffic != nil {
+		in, out := &in.PrivateTraffic, &out.PrivateTraffic
+		*out = new(PrivateTrafficOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tenancy != nil {
+		in, out := &in.Tenancy, &out.Tenancy
+		*out = new(TenancyOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostGeneration != nil {
+		in, out := &in.HostGeneration, &out.HostGeneration
+		*out = new(HostGeneration)
+		**out = **in
+	}
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LogLevels != nil {
+		in, out := &in.LogLevels, &out.LogLevels
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerConfigSpec.
+func (in *ControllerConfigSpec) DeepCopy() *ControllerConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerConfigStatus) DeepCopyInto(out *ControllerConfigStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerConfigStatus.
+func (in *ControllerConfigStatus) DeepCopy() *ControllerConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSRecord) DeepCopyInto(out *DNSRecord) {
 	*out = *in
@@ -87,6 +364,11 @@ func (in *DNSRecordList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSRecordSpec) DeepCopyInto(out *DNSRecordSpec) {
 	*out = *in
+	if in.ManagedZoneRef != nil {
+		in, out := &in.ManagedZoneRef, &out.ManagedZoneRef
+		*out = new(ManagedZoneReference)
+		**out = **in
+	}
 	if in.Endpoints != nil {
 		in, out := &in.Endpoints, &out.Endpoints
 		*out = make([]*Endpoint, len(*in))
@@ -98,6 +380,17 @@ func (in *DNSRecordSpec) DeepCopyInto(out *DNSRecordSpec) {
 			}
 		}
 	}
+	if in.RawRecords != nil {
+		in, out := &in.RawRecords, &out.RawRecords
+		*out = make([]*Endpoint, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(Endpoint)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecordSpec.
@@ -120,6 +413,13 @@ func (in *DNSRecordStatus) DeepCopyInto(out *DNSRecordStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecordStatus.
@@ -204,6 +504,21 @@ func (in *DNSZoneStatus) DeepCopy() *DNSZoneStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefaultZone) DeepCopyInto(out *DefaultZone) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultZone.
+func (in *DefaultZone) DeepCopy() *DefaultZone {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Endpoint) DeepCopyInto(out *Endpoint) {
 	*out = *in
@@ -212,6 +527,16 @@ func (in *Endpoint) DeepCopyInto(out *Endpoint) {
 		*out = make(Targets, len(*in))
 		copy(*out, *in)
 	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int64)
+		**out = **in
+	}
+	if in.GeoLocation != nil {
+		in, out := &in.GeoLocation, &out.GeoLocation
+		*out = new(GeoLocation)
+		**out = **in
+	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
 		*out = make(Labels, len(*in))
@@ -236,6 +561,47 @@ func (in *Endpoint) DeepCopy() *Endpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostedZoneReport) DeepCopyInto(out *HostedZoneReport) {
+	*out = *in
+	if in.Extra != nil {
+		in, out := &in.Extra, &out.Extra
+		*out = make([]RecordDrift, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Missing != nil {
+		in, out := &in.Missing, &out.Missing
+		*out = make([]RecordDrift, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Mismatched != nil {
+		in, out := &in.Mismatched, &out.Mismatched
+		*out = make([]RecordDrift, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Repaired != nil {
+		in, out := &in.Repaired, &out.Repaired
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostedZoneReport.
+func (in *HostedZoneReport) DeepCopy() *HostedZoneReport {
+	if in == nil {
+		return nil
+	}
+	out := new(HostedZoneReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in Labels) DeepCopyInto(out *Labels) {
 	{
@@ -258,35 +624,759 @@ func (in Labels) DeepCopy() Labels {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in ProviderSpecific) DeepCopyInto(out *ProviderSpecific) {
-	{
-		in := &in
-		*out = make(ProviderSpecific, len(*in))
-		copy(*out, *in)
-	}
+func (in *GeoLocation) DeepCopyInto(out *GeoLocation) {
+	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSpecific.
-func (in ProviderSpecific) DeepCopy() ProviderSpecific {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeoLocation.
+func (in *GeoLocation) DeepCopy() *GeoLocation {
 	if in == nil {
 		return nil
 	}
-	out := new(ProviderSpecific)
+	out := new(GeoLocation)
 	in.DeepCopyInto(out)
-	return *out
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProviderSpecificProperty) DeepCopyInto(out *ProviderSpecificProperty) {
+func (in *HostClaim) DeepCopyInto(out *HostClaim) {
 	*out = *in
-}
-
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostClaim.
+func (in *HostClaim) DeepCopy() *HostClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(HostClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostClaimList) DeepCopyInto(out *HostClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HostClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostClaimList.
+func (in *HostClaimList) DeepCopy() *HostClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(HostClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostClaimSpec) DeepCopyInto(out *HostClaimSpec) {
+	*out = *in
+	if in.ExpiresAfter != nil {
+		in, out := &in.ExpiresAfter, &out.ExpiresAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostClaimSpec.
+func (in *HostClaimSpec) DeepCopy() *HostClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HostClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostClaimStatus) DeepCopyInto(out *HostClaimStatus) {
+	*out = *in
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostClaimStatus.
+func (in *HostClaimStatus) DeepCopy() *HostClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HostClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostGeneration) DeepCopyInto(out *HostGeneration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostGeneration.
+func (in *HostGeneration) DeepCopy() *HostGeneration {
+	if in == nil {
+		return nil
+	}
+	out := new(HostGeneration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPool) DeepCopyInto(out *HostPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPool.
+func (in *HostPool) DeepCopy() *HostPool {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPoolList) DeepCopyInto(out *HostPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HostPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPoolList.
+func (in *HostPoolList) DeepCopy() *HostPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPoolSpec) DeepCopyInto(out *HostPoolSpec) {
+	*out = *in
+	out.ManagedZoneRef = in.ManagedZoneRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPoolSpec.
+func (in *HostPoolSpec) DeepCopy() *HostPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPoolStatus) DeepCopyInto(out *HostPoolStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPoolStatus.
+func (in *HostPoolStatus) DeepCopy() *HostPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedHost) DeepCopyInto(out *ManagedHost) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedHost.
+func (in *ManagedHost) DeepCopy() *ManagedHost {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedHost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ManagedHost) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedHostList) DeepCopyInto(out *ManagedHostList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ManagedHost, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedHostList.
+func (in *ManagedHostList) DeepCopy() *ManagedHostList {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedHostList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ManagedHostList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedHostSpec) DeepCopyInto(out *ManagedHostSpec) {
+	*out = *in
+	out.TrafficRef = in.TrafficRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedHostSpec.
+func (in *ManagedHostSpec) DeepCopy() *ManagedHostSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedHostSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedHostStatus) DeepCopyInto(out *ManagedHostStatus) {
+	*out = *in
+	if in.LastIssuanceFailureTime != nil {
+		in, out := &in.LastIssuanceFailureTime, &out.LastIssuanceFailureTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedHostStatus.
+func (in *ManagedHostStatus) DeepCopy() *ManagedHostStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedHostStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedZone) DeepCopyInto(out *ManagedZone) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedZone.
+func (in *ManagedZone) DeepCopy() *ManagedZone {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ManagedZone) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedZoneList) DeepCopyInto(out *ManagedZoneList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ManagedZone, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedZoneList.
+func (in *ManagedZoneList) DeepCopy() *ManagedZoneList {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedZoneList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ManagedZoneList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedZoneReference) DeepCopyInto(out *ManagedZoneReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedZoneReference.
+func (in *ManagedZoneReference) DeepCopy() *ManagedZoneReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedZoneReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedZoneSpec) DeepCopyInto(out *ManagedZoneSpec) {
+	*out = *in
+	if in.ProviderCredentialsRef != nil {
+		in, out := &in.ProviderCredentialsRef, &out.ProviderCredentialsRef
+		*out = new(ProviderCredentialsReference)
+		**out = **in
+	}
+	if in.ChangeWindows != nil {
+		in, out := &in.ChangeWindows, &out.ChangeWindows
+		*out = make([]ChangeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecondaryZones != nil {
+		in, out := &in.SecondaryZones, &out.SecondaryZones
+		*out = make([]SecondaryZone, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HostGeneration != nil {
+		in, out := &in.HostGeneration, &out.HostGeneration
+		*out = new(HostGeneration)
+		**out = **in
+	}
+	if in.ReservedHosts != nil {
+		in, out := &in.ReservedHosts, &out.ReservedHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedZoneSpec.
+func (in *ManagedZoneSpec) DeepCopy() *ManagedZoneSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedZoneSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedZoneStatus) DeepCopyInto(out *ManagedZoneStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DNSSEC != nil {
+		in, out := &in.DNSSEC, &out.DNSSEC
+		*out = new(ZoneDNSSECStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedZoneStatus.
+func (in *ManagedZoneStatus) DeepCopy() *ManagedZoneStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedZoneStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRule) DeepCopyInto(out *PolicyRule) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostSuffixes != nil {
+		in, out := &in.HostSuffixes, &out.HostSuffixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxHosts != nil {
+		in, out := &in.MaxHosts, &out.MaxHosts
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxClusters != nil {
+		in, out := &in.MaxClusters, &out.MaxClusters
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRule.
+func (in *PolicyRule) DeepCopy() *PolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderCredentialsReference) DeepCopyInto(out *ProviderCredentialsReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderCredentialsReference.
+func (in *ProviderCredentialsReference) DeepCopy() *ProviderCredentialsReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderCredentialsReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrivateTrafficOptions) DeepCopyInto(out *PrivateTrafficOptions) {
+	*out = *in
+	if in.FleetCIDRs != nil {
+		in, out := &in.FleetCIDRs, &out.FleetCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrivateTrafficOptions.
+func (in *PrivateTrafficOptions) DeepCopy() *PrivateTrafficOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(PrivateTrafficOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderOptions) DeepCopyInto(out *ProviderOptions) {
+	*out = *in
+	if in.ZoneCredentialsNamespaces != nil {
+		in, out := &in.ZoneCredentialsNamespaces, &out.ZoneCredentialsNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderOptions.
+func (in *ProviderOptions) DeepCopy() *ProviderOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in ProviderSpecific) DeepCopyInto(out *ProviderSpecific) {
+	{
+		in := &in
+		*out = make(ProviderSpecific, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSpecific.
+func (in ProviderSpecific) DeepCopy() ProviderSpecific {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSpecific)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSpecificProperty) DeepCopyInto(out *ProviderSpecificProperty) {
+	*out = *in
+}
+
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSpecificProperty.
 func (in *ProviderSpecificProperty) DeepCopy() *ProviderSpecificProperty {
 	if in == nil {
 		return nil
 	}
-	out := new(ProviderSpecificProperty)
+	out := new(ProviderSpecificProperty)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecordDrift) DeepCopyInto(out *RecordDrift) {
+	*out = *in
+	if in.Expected != nil {
+		in, out := &in.Expected, &out.Expected
+		*out = new(RecordValues)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Published != nil {
+		in, out := &in.Published, &out.Published
+		*out = new(RecordValues)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecordDrift.
+func (in *RecordDrift) DeepCopy() *RecordDrift {
+	if in == nil {
+		return nil
+	}
+	out := new(RecordDrift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecordValues) DeepCopyInto(out *RecordValues) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make(Targets, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecordValues.
+func (in *RecordValues) DeepCopy() *RecordValues {
+	if in == nil {
+		return nil
+	}
+	out := new(RecordValues)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutHealthCheck) DeepCopyInto(out *RolloutHealthCheck) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxErrorPercent != nil {
+		in, out := &in.MaxErrorPercent, &out.MaxErrorPercent
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutHealthCheck.
+func (in *RolloutHealthCheck) DeepCopy() *RolloutHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStep) DeepCopyInto(out *RolloutStep) {
+	*out = *in
+	out.Pause = in.Pause
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStep.
+func (in *RolloutStep) DeepCopy() *RolloutStep {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecondaryZone) DeepCopyInto(out *SecondaryZone) {
+	*out = *in
+	if in.ProviderCredentialsRef != nil {
+		in, out := &in.ProviderCredentialsRef, &out.ProviderCredentialsRef
+		*out = new(ProviderCredentialsReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecondaryZone.
+func (in *SecondaryZone) DeepCopy() *SecondaryZone {
+	if in == nil {
+		return nil
+	}
+	out := new(SecondaryZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncOptions) DeepCopyInto(out *SyncOptions) {
+	*out = *in
+	if in.DNSVerifyInterval != nil {
+		in, out := &in.DNSVerifyInterval, &out.DNSVerifyInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HTTPSRedirect != nil {
+		in, out := &in.HTTPSRedirect, &out.HTTPSRedirect
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ClusterHostnames != nil {
+		in, out := &in.ClusterHostnames, &out.ClusterHostnames
+		*out = new(bool)
+		**out = **in
+	}
+	if in.HostReuseCooldown != nil {
+		in, out := &in.HostReuseCooldown, &out.HostReuseCooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncOptions.
+func (in *SyncOptions) DeepCopy() *SyncOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncOptions)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -324,3 +1414,349 @@ func (in Targets) DeepCopy() Targets {
 	in.DeepCopyInto(out)
 	return *out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TenancyOptions) DeepCopyInto(out *TenancyOptions) {
+	*out = *in
+	if in.MaxHosts != nil {
+		in, out := &in.MaxHosts, &out.MaxHosts
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenancyOptions.
+func (in *TenancyOptions) DeepCopy() *TenancyOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(TenancyOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficPolicy) DeepCopyInto(out *TrafficPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficPolicy.
+func (in *TrafficPolicy) DeepCopy() *TrafficPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TrafficPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficPolicyList) DeepCopyInto(out *TrafficPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TrafficPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficPolicyList.
+func (in *TrafficPolicyList) DeepCopy() *TrafficPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TrafficPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficPolicySpec) DeepCopyInto(out *TrafficPolicySpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficPolicySpec.
+func (in *TrafficPolicySpec) DeepCopy() *TrafficPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficReference) DeepCopyInto(out *TrafficReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficReference.
+func (in *TrafficReference) DeepCopy() *TrafficReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficRollout) DeepCopyInto(out *TrafficRollout) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficRollout.
+func (in *TrafficRollout) DeepCopy() *TrafficRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TrafficRollout) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficRolloutList) DeepCopyInto(out *TrafficRolloutList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TrafficRollout, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficRolloutList.
+func (in *TrafficRolloutList) DeepCopy() *TrafficRolloutList {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficRolloutList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TrafficRolloutList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficRolloutSpec) DeepCopyInto(out *TrafficRolloutSpec) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]RolloutStep, len(*in))
+		copy(*out, *in)
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(RolloutHealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficRolloutSpec.
+func (in *TrafficRolloutSpec) DeepCopy() *TrafficRolloutSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficRolloutSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficRolloutStatus) DeepCopyInto(out *TrafficRolloutStatus) {
+	*out = *in
+	if in.StepStartTime != nil {
+		in, out := &in.StepStartTime, &out.StepStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficRolloutStatus.
+func (in *TrafficRolloutStatus) DeepCopy() *TrafficRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneDNSSECStatus) DeepCopyInto(out *ZoneDNSSECStatus) {
+	*out = *in
+	if in.DSRecords != nil {
+		in, out := &in.DSRecords, &out.DSRecords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneDNSSECStatus.
+func (in *ZoneDNSSECStatus) DeepCopy() *ZoneDNSSECStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneDNSSECStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneReport) DeepCopyInto(out *ZoneReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneReport.
+func (in *ZoneReport) DeepCopy() *ZoneReport {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZoneReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneReportList) DeepCopyInto(out *ZoneReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ZoneReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneReportList.
+func (in *ZoneReportList) DeepCopy() *ZoneReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZoneReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneReportSpec) DeepCopyInto(out *ZoneReportSpec) {
+	*out = *in
+	out.ManagedZoneRef = in.ManagedZoneRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneReportSpec.
+func (in *ZoneReportSpec) DeepCopy() *ZoneReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneReportStatus) DeepCopyInto(out *ZoneReportStatus) {
+	*out = *in
+	if in.GeneratedAt != nil {
+		in, out := &in.GeneratedAt, &out.GeneratedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]HostedZoneReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneReportStatus.
+func (in *ZoneReportStatus) DeepCopy() *ZoneReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
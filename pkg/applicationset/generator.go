@@ -0,0 +1,376 @@
+package applicationset
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/httpserver"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/placement"
+)
+
+// GetParamsPath is the path ArgoCD plugin generators post requests to
+const GetParamsPath = "/api/v1/getparams.execute"
+
+const (
+	// RebalanceUnhealthy moves a placement off its clusters while they're
+	// evacuated or drained for maintenance, onto the next best clusters
+	RebalanceUnhealthy = "Unhealthy"
+	// RebalanceNever keeps a placement on its clusters while they're
+	// unhealthy. Clusters removed from the fleet are still replaced
+	RebalanceNever = "Never"
+)
+
+// Generator is an ArgoCD ApplicationSet plugin generator generating a set
+// of parameters for each cluster a traffic object is placed on. The
+// placement is made from the clusters of the fleet, never from the clusters
+// its managed hosts resolve to, as those only serve it once it's deployed.
+// Applications generated from them are deployed to exactly these clusters
+type Generator struct {
+	Client client.Client
+	// Namespace is the controller namespace, holding the ManagedHosts and
+	// the cluster secrets
+	Namespace string
+	Port      int
+	// Token is the bearer token ArgoCD authenticates with
+	Token string
+	// Scorer ranks the candidate clusters of placements
+	Scorer placement.Scorer
+}
+
+// Input selects the traffic object whose clusters are generated, either by
+// one of its managed hosts or by reference. The clusters are placed by the
+// scorer among the clusters matching the cluster selector, every one of
+// them unless MaxClusters is set. The clusters the traffic object selected is
+// already placed on are kept while they remain compliant, so placements only
+// move when their clusters are removed, or become unhealthy according to
+// the rebalance policy
+type Input struct {
+	Host      string `json:"host,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// MinClusters is the minimum number of clusters to place on. The
+	// placement fails while fewer clusters are compliant
+	MinClusters int `json:"minClusters,omitempty"`
+	// MaxClusters is the maximum number of clusters to place on. Every
+	// compliant cluster when not set
+	MaxClusters int `json:"maxClusters,omitempty"`
+	// ClusterSelector is a label selector of the cluster secrets of the
+	// clusters compliant with the placement. Every cluster when empty
+	ClusterSelector string `json:"clusterSelector,omitempty"`
+	// Tolerations lists the cluster taints the placement tolerates, as
+	// comma separated key, key=value, key:effect or key=value:effect entries.
+	// Clusters with other taints, whatever their effect, are excluded
+	Tolerations string `json:"tolerations,omitempty"`
+	// Rebalance is the policy moving the placement off unhealthy clusters,
+	// RebalanceUnhealthy (default) or RebalanceNever
+	Rebalance string `json:"rebalance,omitempty"`
+	// MinKubernetesVersion is the minimum Kubernetes version of the
+	// clusters to place on, e.g. v1.25. Clusters running older versions,
+	// or whose version wasn't detected, are excluded
+	MinKubernetesVersion string `json:"minKubernetesVersion,omitempty"`
+	// MinGatewayAPIVersion is the minimum Gateway API version installed in
+	// the clusters to place on, e.g. v0.6.0. Clusters with older versions,
+	// or without the Gateway API, are excluded
+	MinGatewayAPIVersion string `json:"minGatewayAPIVersion,omitempty"`
+}
+
+type request struct {
+	ApplicationSetName string `json:"applicationSetName"`
+	Input              struct {
+		Parameters Input `json:"parameters"`
+	} `json:"input"`
+}
+
+type response struct {
+	Output struct {
+		Parameters []map[string]string `json:"parameters"`
+	} `json:"output"`
+}
+
+func (g *Generator) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info(fmt.Sprintf("Starting ApplicationSet generator at :%d", g.Port))
+	mux := http.NewServeMux()
+	mux.Handle(GetParamsPath, g)
+	server := &http.Server{Addr: fmt.Sprintf(":%d", g.Port), Handler: mux}
+
+	return httpserver.Serve(ctx, server)
+}
+
+func (g *Generator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if g.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(g.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req := &request{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parameters, err := g.Parameters(r.Context(), req.Input.Parameters)
+	if err != nil {
+		log.Log.Error(err, "failed to generate ApplicationSet parameters", "applicationSet", req.ApplicationSetName)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := &response{}
+	resp.Output.Parameters = parameters
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Log.Error(err, "failed to write ApplicationSet parameters", "applicationSet", req.ApplicationSetName)
+	}
+}
+
+// Parameters returns the parameters of each cluster the traffic object
+// selected by the input is placed on, see clusterParameters. Without a
+// minimum or maximum, it's placed on every compliant cluster
+func (g *Generator) Parameters(ctx context.Context, input Input) ([]map[string]string, error) {
+	if input.MinClusters == 0 && input.MaxClusters == 0 && input.Host == "" && input.Name == "" {
+		return nil, fmt.Errorf("either host or name of the traffic object is required")
+	}
+	return g.placementParameters(ctx, input)
+}
+
+// placementParameters returns the parameters of the clusters the scorer
+// ranks first among the clusters matching the cluster selector of the input,
+// up to its maximum. Fails when fewer clusters than its minimum are
+// compliant, so ArgoCD reports it and keeps the current applications. The
+// clusters excluded for not meeting the minimum versions of the input are
+// reported by the PlacementBlocked condition of the ManagedHosts of the
+// traffic object it selects
+func (g *Generator) placementParameters(ctx context.Context, input Input) ([]map[string]string, error) {
+	if g.Scorer == nil {
+		return nil, fmt.Errorf("no placement scorer configured")
+	}
+	if input.MaxClusters > 0 && input.MinClusters > input.MaxClusters {
+		return nil, fmt.Errorf("minClusters %d exceeds maxClusters %d", input.MinClusters, input.MaxClusters)
+	}
+	selector, err := labels.Parse(input.ClusterSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster selector: %w", err)
+	}
+	tolerations, err := clusterSecret.ParseTolerations(input.Tolerations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tolerations: %w", err)
+	}
+	if input.Rebalance != "" && input.Rebalance != RebalanceUnhealthy && input.Rebalance != RebalanceNever {
+		return nil, fmt.Errorf("invalid rebalance policy %s, expected %s or %s", input.Rebalance, RebalanceUnhealthy, RebalanceNever)
+	}
+	if err := clusterSecret.ValidateMinimumVersions(input.MinKubernetesVersion, input.MinGatewayAPIVersion); err != nil {
+		return nil, err
+	}
+	placed := []string{}
+	managedHosts := &v1.ManagedHostList{}
+	if input.Host != "" || input.Name != "" {
+		if err := g.Client.List(ctx, managedHosts, client.InNamespace(g.Namespace)); err != nil {
+			return nil, err
+		}
+		placed = placementDecision(managedHosts.Items, input)
+	}
+	secrets := &corev1.SecretList{}
+	if err := g.Client.List(ctx, secrets, client.InNamespace(g.Namespace), client.MatchingLabels{secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE}); err != nil {
+		return nil, err
+	}
+	unhealthy, err := g.unhealthyClusters(ctx, secrets.Items)
+	if err != nil {
+		return nil, err
+	}
+	candidates := []placement.Cluster{}
+	blocked := []string{}
+	byName := map[string]*corev1.Secret{}
+	for i := range secrets.Items {
+		s := &secrets.Items[i]
+		if !selector.Matches(labels.Set(s.Labels)) {
+			continue
+		}
+		taints, err := clusterSecret.Taints(s)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "excluding cluster with invalid taints from placement", "cluster", s.Name)
+			continue
+		}
+		if !clusterSecret.Tolerates(tolerations, taints) {
+			continue
+		}
+		if err := clusterSecret.CheckMinimumVersions(s, input.MinKubernetesVersion, input.MinGatewayAPIVersion); err != nil {
+			blocked = append(blocked, err.Error())
+			continue
+		}
+		kept := input.Rebalance == RebalanceNever && slice.ContainsString(placed, s.Name)
+		if _, ok := unhealthy[s.Name]; ok && !kept {
+			log.FromContext(ctx).V(3).Info("excluding unhealthy cluster from placement", "cluster", s.Name)
+			continue
+		}
+		candidates = append(candidates, placement.Cluster{Name: s.Name, Labels: s.Labels})
+		byName[s.Name] = s
+	}
+	if err := g.reportBlocked(ctx, managedHosts.Items, input, blocked); err != nil {
+		return nil, err
+	}
+	if len(candidates) < input.MinClusters {
+		return nil, fmt.Errorf("%d clusters are compliant with the placement, fewer than the minimum of %d", len(candidates), input.MinClusters)
+	}
+	parameters := []map[string]string{}
+	decision := []string{}
+	for _, cluster := range placement.Select(placement.Keep(g.Scorer, placed), candidates, input.MaxClusters) {
+		clusterParams, err := clusterParameters(byName[cluster.Name])
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, clusterParams)
+		decision = append(decision, cluster.Name)
+	}
+	if err := g.recordPlacement(ctx, managedHosts.Items, input, decision); err != nil {
+		return nil, err
+	}
+	return parameters, nil
+}
+
+// recordPlacement records the clusters the traffic object selected by the
+// input is placed on in the status of its ManagedHosts, so the next
+// placement keeps them. The clusters serving its hosts can't be relied on,
+// as the endpoints of unhealthy clusters are withdrawn while they're placed
+func (g *Generator) recordPlacement(ctx context.Context, managedHosts []v1.ManagedHost, input Input, decision []string) error {
+	sort.Strings(decision)
+	for i := range managedHosts {
+		managedHost := &managedHosts[i]
+		if !selects(input, *managedHost) {
+			continue
+		}
+		previous := managedHost.Status.DeepCopy()
+		managedHost.Status.Placement = decision
+		if err := conditions.UpdateStatus(ctx, g.Client, managedHost, previous, &managedHost.Status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportBlocked sets the PlacementBlocked condition of the ManagedHosts of
+// the traffic object selected by the input, listing why the clusters were
+// blocked from its placement
+func (g *Generator) reportBlocked(ctx context.Context, managedHosts []v1.ManagedHost, input Input, blocked []string) error {
+	for i := range managedHosts {
+		managedHost := &managedHosts[i]
+		if !selects(input, *managedHost) {
+			continue
+		}
+		previous := managedHost.Status.DeepCopy()
+		if len(blocked) > 0 {
+			conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostPlacementBlockedConditionType, metav1.ConditionTrue,
+				conditions.ReasonMinimumVersionNotMet, strings.Join(blocked, "; "))
+		} else {
+			conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostPlacementBlockedConditionType, metav1.ConditionFalse,
+				conditions.ReasonMinimumVersionMet, "every cluster meets the minimum versions of the placement")
+		}
+		if err := conditions.UpdateStatus(ctx, g.Client, managedHost, previous, &managedHost.Status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unhealthyClusters returns the clusters of the cluster secrets evacuated
+// or drained for one of their maintenance windows
+func (g *Generator) unhealthyClusters(ctx context.Context, secrets []corev1.Secret) (map[string]struct{}, error) {
+	evacuations := &v1.ClusterEvacuationList{}
+	if err := g.Client.List(ctx, evacuations, client.InNamespace(g.Namespace)); err != nil {
+		return nil, err
+	}
+	unhealthy := map[string]struct{}{}
+	for _, evacuation := range evacuations.Items {
+		unhealthy[evacuation.Spec.Cluster] = struct{}{}
+	}
+	now := time.Now()
+	for i := range secrets {
+		windows, err := clusterSecret.MaintenanceWindows(&secrets[i])
+		if err != nil {
+			continue
+		}
+		if drained, _ := clusterSecret.InMaintenance(windows, now, dns.MaintenanceLeadTime); drained {
+			unhealthy[secrets[i].Name] = struct{}{}
+		}
+	}
+	return unhealthy, nil
+}
+
+// clusterParameters returns the ArgoCD name and server of the cluster, the
+// name of its cluster secret, and the infrastructure labels and annotations
+// it declares as JSON objects, for the applications to stamp onto the
+// traffic objects they sync to the cluster. Fails on invalid declarations,
+// so ArgoCD reports it rather than syncing without them
+func clusterParameters(secret *corev1.Secret) (map[string]string, error) {
+	labels, err := clusterSecret.InfrastructureLabels(secret)
+	if err != nil {
+		return nil, fmt.Errorf("cluster %s: %w", secret.Name, err)
+	}
+	annotations, err := clusterSecret.InfrastructureAnnotations(secret)
+	if err != nil {
+		return nil, fmt.Errorf("cluster %s: %w", secret.Name, err)
+	}
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return nil, err
+	}
+	annotationsJSON, err := json.Marshal(annotations)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"name":                      string(secret.Data["name"]),
+		"server":                    string(secret.Data["server"]),
+		"clusterSecret":             secret.Name,
+		"infrastructureLabels":      string(labelsJSON),
+		"infrastructureAnnotations": string(annotationsJSON),
+	}, nil
+}
+
+// placementDecision returns the clusters the traffic object selected by the
+// input was last placed on by the generator
+func placementDecision(managedHosts []v1.ManagedHost, input Input) []string {
+	clusters := []string{}
+	for _, managedHost := range managedHosts {
+		if !selects(input, managedHost) {
+			continue
+		}
+		for _, cluster := range managedHost.Status.Placement {
+			if !slice.ContainsString(clusters, cluster) {
+				clusters = append(clusters, cluster)
+			}
+		}
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+func selects(input Input, managedHost v1.ManagedHost) bool {
+	if input.Host != "" && input.Host != managedHost.Spec.Host {
+		return false
+	}
+	ref := managedHost.Spec.TrafficRef
+	return (input.Kind == "" || input.Kind == ref.Kind) &&
+		(input.Namespace == "" || input.Namespace == ref.Namespace) &&
+		(input.Name == "" || input.Name == ref.Name)
+}
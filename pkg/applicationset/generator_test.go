@@ -0,0 +1,235 @@
+package applicationset
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/placement"
+)
+
+func testManagedHost(host, name string, clusters ...string) v1.ManagedHost {
+	return v1.ManagedHost{
+		ObjectMeta: metav1.ObjectMeta{Name: host},
+		Spec: v1.ManagedHostSpec{
+			Host:       host,
+			TrafficRef: v1.TrafficReference{Kind: "Ingress", Namespace: "default", Name: name},
+		},
+		Status: v1.ManagedHostStatus{Clusters: clusters},
+	}
+}
+
+func Test_placementDecision(t *testing.T) {
+	placed := func(host, name string, clusters ...string) v1.ManagedHost {
+		managedHost := testManagedHost(host, name)
+		managedHost.Status.Placement = clusters
+		return managedHost
+	}
+	managedHosts := []v1.ManagedHost{
+		placed("a.example.com", "app", "cluster-2", "cluster-1"),
+		placed("b.example.com", "app", "cluster-3", "cluster-1"),
+		placed("c.example.com", "other", "cluster-4"),
+		// the clusters serving the host aren't the placement
+		testManagedHost("d.example.com", "served", "cluster-5"),
+	}
+	tests := []struct {
+		name   string
+		input  Input
+		expect []string
+	}{
+		{
+			name:   "by host",
+			input:  Input{Host: "a.example.com"},
+			expect: []string{"cluster-1", "cluster-2"},
+		},
+		{
+			name:   "by traffic object",
+			input:  Input{Kind: "Ingress", Namespace: "default", Name: "app"},
+			expect: []string{"cluster-1", "cluster-2", "cluster-3"},
+		},
+		{
+			name:   "not placed yet",
+			input:  Input{Name: "served"},
+			expect: []string{},
+		},
+		{
+			name:   "unknown traffic object",
+			input:  Input{Namespace: "prod", Name: "app"},
+			expect: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := placementDecision(managedHosts, tt.input)
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestGenerator_placementParameters(t *testing.T) {
+	newClusterSecret := func(name, tier, zone, taints, kubernetesVersion string) client.Object {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "argocd",
+				Labels: map[string]string{
+					secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE,
+					placement.LabelCostTier:      tier,
+					placement.LabelZone:          zone,
+				},
+				Annotations: map[string]string{
+					clusterSecret.AnnotationTaints:            taints,
+					clusterSecret.AnnotationKubernetesVersion: kubernetesVersion,
+				},
+			},
+			Data: map[string][]byte{"name": []byte(name), "server": []byte("https://" + name)},
+		}
+	}
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+	// cluster-4 is evacuated, so its endpoints are withdrawn and the host is
+	// only served by cluster-3 while it's still placed on both
+	placed := testManagedHost("app.example.com", "app", "cluster-3")
+	placed.Namespace = "argocd"
+	placed.Status.Placement = []string{"cluster-3", "cluster-4"}
+
+	tests := []struct {
+		name            string
+		input           Input
+		expect          []string
+		err             bool
+		blocked         metav1.ConditionStatus
+		expectPlacement []string
+	}{
+		{
+			name:   "cheapest untainted clusters up to the maximum",
+			input:  Input{MaxClusters: 1},
+			expect: []string{"cluster-1"},
+		},
+		{
+			name:   "tolerated taint",
+			input:  Input{MaxClusters: 2, Tolerations: "maintenance"},
+			expect: []string{"cluster-1", "cluster-2"},
+		},
+		{
+			name:            "every compliant cluster without limits, not only the clusters serving the host",
+			input:           Input{Name: "app"},
+			expect:          []string{"cluster-3", "cluster-1"},
+			expectPlacement: []string{"cluster-1", "cluster-3"},
+		},
+		{
+			name:  "traffic object required without limits",
+			input: Input{},
+			err:   true,
+		},
+		{
+			name:   "every compliant cluster without a maximum",
+			input:  Input{MinClusters: 2},
+			expect: []string{"cluster-1", "cluster-3"},
+		},
+		{
+			name:            "placed clusters kept",
+			input:           Input{Name: "app", MaxClusters: 1},
+			expect:          []string{"cluster-3"},
+			expectPlacement: []string{"cluster-3"},
+		},
+		{
+			name:            "unhealthy placed cluster replaced by the next best",
+			input:           Input{Name: "app", MaxClusters: 2},
+			expect:          []string{"cluster-3", "cluster-1"},
+			expectPlacement: []string{"cluster-1", "cluster-3"},
+		},
+		{
+			name:            "unhealthy placed cluster kept without rebalancing",
+			input:           Input{Name: "app", MaxClusters: 2, Rebalance: RebalanceNever},
+			expect:          []string{"cluster-4", "cluster-3"},
+			expectPlacement: []string{"cluster-3", "cluster-4"},
+		},
+		{
+			name:    "clusters older than the minimum version blocked",
+			input:   Input{Name: "app", MaxClusters: 2, MinKubernetesVersion: "v1.25"},
+			expect:  []string{"cluster-3"},
+			blocked: metav1.ConditionTrue,
+		},
+		{
+			name:    "every cluster meeting the minimum version",
+			input:   Input{Name: "app", MaxClusters: 2, MinKubernetesVersion: "v1.24"},
+			expect:  []string{"cluster-3", "cluster-1"},
+			blocked: metav1.ConditionFalse,
+		},
+		{
+			name:  "invalid minimum version",
+			input: Input{MaxClusters: 1, MinKubernetesVersion: "latest"},
+			err:   true,
+		},
+		{
+			name:  "fewer compliant clusters than the minimum",
+			input: Input{MinClusters: 3},
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				newClusterSecret("cluster-1", "1", "z1", "", "v1.24.3"),
+				newClusterSecret("cluster-2", "1", "z2", "maintenance", "v1.26.1"),
+				newClusterSecret("cluster-3", "2", "z1", "", "v1.26.1"),
+				newClusterSecret("cluster-4", "1", "z3", "", "v1.26.1"),
+				&v1.ClusterEvacuation{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster-4", Namespace: "argocd"},
+					Spec:       v1.ClusterEvacuationSpec{Cluster: "cluster-4"},
+				},
+				placed.DeepCopy(),
+			).Build()
+			g := &Generator{Client: c, Namespace: "argocd", Scorer: placement.CostZoneScorer{}}
+			parameters, err := g.Parameters(context.Background(), tt.input)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error got '%v'", parameters)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+			got := []string{}
+			for _, p := range parameters {
+				got = append(got, p["clusterSecret"])
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+			managedHost := &v1.ManagedHost{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(&placed), managedHost); err != nil {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+			if tt.expectPlacement != nil && !reflect.DeepEqual(managedHost.Status.Placement, tt.expectPlacement) {
+				t.Errorf("expected '%v' got '%v'", tt.expectPlacement, managedHost.Status.Placement)
+			}
+			if tt.blocked == "" {
+				return
+			}
+			condition := meta.FindStatusCondition(managedHost.Status.Conditions, v1.ManagedHostPlacementBlockedConditionType)
+			if condition == nil || condition.Status != tt.blocked {
+				t.Errorf("expected '%v' got '%v'", tt.blocked, condition)
+			}
+		})
+	}
+}
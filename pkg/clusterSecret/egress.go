@@ -0,0 +1,74 @@
+package clusterSecret
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+)
+
+const (
+	// AnnotationEgressIPs declares the addresses the traffic of the cluster
+	// egresses from, as a comma separated list of IPs or CIDRs, e.g. the
+	// addresses of its NAT gateways. It overrides the detected addresses
+	AnnotationEgressIPs = "kuadrant.io/egress-ips"
+	// AnnotationDetectedEgressIPs is set by the controller on the cluster
+	// secrets with the external addresses of the nodes of the cluster, which
+	// its traffic egresses from unless it's NATed
+	AnnotationDetectedEgressIPs = "kuadrant.io/detected-egress-ips"
+)
+
+// EgressIPs returns the addresses the traffic of the cluster egresses from,
+// the declared ones or, when none are declared, the detected ones
+func EgressIPs(secret *corev1.Secret) ([]string, error) {
+	if declared := metadata.GetAnnotation(secret, AnnotationEgressIPs); declared != "" {
+		ips, err := ParseEgressIPs(declared)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationEgressIPs, err)
+		}
+		return ips, nil
+	}
+	ips, err := ParseEgressIPs(metadata.GetAnnotation(secret, AnnotationDetectedEgressIPs))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationDetectedEgressIPs, err)
+	}
+	return ips, nil
+}
+
+// ParseEgressIPs parses a comma separated list of IPs or CIDRs
+func ParseEgressIPs(value string) ([]string, error) {
+	ips := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return nil, fmt.Errorf("%s is not an IP or CIDR", entry)
+		}
+		ips = append(ips, entry)
+	}
+	return ips, nil
+}
+
+// NodeEgressIPs returns the sorted external addresses of the nodes
+func NodeEgressIPs(nodes []corev1.Node) []string {
+	unique := map[string]struct{}{}
+	for _, node := range nodes {
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeExternalIP && address.Address != "" {
+				unique[address.Address] = struct{}{}
+			}
+		}
+	}
+	ips := make([]string, 0, len(unique))
+	for ip := range unique {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
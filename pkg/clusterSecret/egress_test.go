@@ -0,0 +1,51 @@
+package clusterSecret
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEgressIPs(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Annotations map[string]string
+		Expected    []string
+		ExpectErr   bool
+	}{
+		{
+			Name:     "no egress addresses",
+			Expected: []string{},
+		},
+		{
+			Name:        "detected addresses",
+			Annotations: map[string]string{AnnotationDetectedEgressIPs: "1.1.1.1,2.2.2.2"},
+			Expected:    []string{"1.1.1.1", "2.2.2.2"},
+		},
+		{
+			Name:        "declared addresses override the detected ones",
+			Annotations: map[string]string{AnnotationEgressIPs: " 10.0.0.0/24, 3.3.3.3", AnnotationDetectedEgressIPs: "1.1.1.1"},
+			Expected:    []string{"10.0.0.0/24", "3.3.3.3"},
+		},
+		{
+			Name:        "invalid address",
+			Annotations: map[string]string{AnnotationEgressIPs: "nat.example.com"},
+			ExpectErr:   true,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: testCase.Annotations}}
+			ips, err := EgressIPs(secret)
+			if (err != nil) != testCase.ExpectErr {
+				t.Fatalf("expected error '%v' got '%v'", testCase.ExpectErr, err)
+			}
+			if !testCase.ExpectErr && !reflect.DeepEqual(ips, testCase.Expected) {
+				t.Errorf("expected '%v' got '%v'", testCase.Expected, ips)
+			}
+		})
+	}
+}
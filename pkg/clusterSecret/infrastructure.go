@@ -0,0 +1,67 @@
+package clusterSecret
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+)
+
+const (
+	// AnnotationInfrastructureLabels declares the labels stamped onto the
+	// traffic objects synced to the cluster, as a JSON object, e.g.
+	// {"network":"public"}
+	AnnotationInfrastructureLabels = "kuadrant.io/infrastructure-labels"
+	// AnnotationInfrastructureAnnotations declares the annotations stamped
+	// onto the traffic objects synced to the cluster, as a JSON object, e.g.
+	// {"service.beta.kubernetes.io/aws-load-balancer-type":"nlb"}, as the
+	// load balancers are provisioned differently by each provider
+	AnnotationInfrastructureAnnotations = "kuadrant.io/infrastructure-annotations"
+)
+
+// InfrastructureLabels parses the infrastructure labels of the cluster
+// secret
+func InfrastructureLabels(secret *corev1.Secret) (map[string]string, error) {
+	labels, err := parseMetadata(metadata.GetAnnotation(secret, AnnotationInfrastructureLabels))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationInfrastructureLabels, err)
+	}
+	for key, value := range labels {
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s annotation: label %s: %s", AnnotationInfrastructureLabels, key, strings.Join(errs, ", "))
+		}
+	}
+	return labels, nil
+}
+
+// InfrastructureAnnotations parses the infrastructure annotations of the
+// cluster secret
+func InfrastructureAnnotations(secret *corev1.Secret) (map[string]string, error) {
+	annotations, err := parseMetadata(metadata.GetAnnotation(secret, AnnotationInfrastructureAnnotations))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationInfrastructureAnnotations, err)
+	}
+	return annotations, nil
+}
+
+// parseMetadata parses a JSON object of labels or annotations, validating
+// their keys
+func parseMetadata(value string) (map[string]string, error) {
+	entries := map[string]string{}
+	if strings.TrimSpace(value) == "" {
+		return entries, nil
+	}
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return nil, err
+	}
+	for key := range entries {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("key %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	return entries, nil
+}
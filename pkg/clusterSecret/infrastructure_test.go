@@ -0,0 +1,58 @@
+package clusterSecret
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInfrastructureLabels(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Value    string
+		Expected map[string]string
+		Err      bool
+	}{
+		{
+			Name:     "no labels declared",
+			Expected: map[string]string{},
+		},
+		{
+			Name:     "labels declared",
+			Value:    `{"network":"public","kuadrant.io/lb":"nlb"}`,
+			Expected: map[string]string{"network": "public", "kuadrant.io/lb": "nlb"},
+		},
+		{
+			Name:  "invalid JSON",
+			Value: "network=public",
+			Err:   true,
+		},
+		{
+			Name:  "invalid key",
+			Value: `{"not a key":"public"}`,
+			Err:   true,
+		},
+		{
+			Name:  "invalid label value",
+			Value: `{"network":"not a value"}`,
+			Err:   true,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{AnnotationInfrastructureLabels: testCase.Value},
+			}}
+			got, err := InfrastructureLabels(secret)
+			if (err != nil) != testCase.Err {
+				t.Fatalf("expected error '%v' got '%v'", testCase.Err, err)
+			}
+			if !testCase.Err && !reflect.DeepEqual(got, testCase.Expected) {
+				t.Errorf("expected '%v' got '%v'", testCase.Expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package clusterSecret
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+)
+
+// AnnotationMaintenanceWindows declares the maintenance windows of the
+// cluster, as a comma separated list of start/end intervals in RFC3339
+// format, e.g. 2023-01-10T02:00:00Z/2023-01-10T04:00:00Z
+const AnnotationMaintenanceWindows = "kuadrant.io/maintenance-windows"
+
+// MaintenanceWindow is a period during which the cluster doesn't receive
+// traffic
+type MaintenanceWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MaintenanceWindows parses the maintenance windows of the cluster secret
+func MaintenanceWindows(secret *corev1.Secret) ([]MaintenanceWindow, error) {
+	value := metadata.GetAnnotation(secret, AnnotationMaintenanceWindows)
+	if value == "" {
+		return nil, nil
+	}
+	var windows []MaintenanceWindow
+	for _, interval := range strings.Split(value, ",") {
+		start, end, found := strings.Cut(strings.TrimSpace(interval), "/")
+		if !found {
+			return nil, fmt.Errorf("invalid %s annotation entry %q, expected start/end", AnnotationMaintenanceWindows, interval)
+		}
+		window := MaintenanceWindow{}
+		var err error
+		if window.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return nil, fmt.Errorf("invalid start of maintenance window %q: %v", interval, err)
+		}
+		if window.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return nil, fmt.Errorf("invalid end of maintenance window %q: %v", interval, err)
+		}
+		if !window.End.After(window.Start) {
+			return nil, fmt.Errorf("maintenance window %q ends before it starts", interval)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// InMaintenance returns whether the cluster is drained at the given time,
+// which is from lead before the start of a window until its end, so
+// resolvers stop sending clients to the cluster by the time the window
+// starts. Also returns when that changes next, or the zero time when it
+// doesn't
+func InMaintenance(windows []MaintenanceWindow, now time.Time, lead time.Duration) (bool, time.Time) {
+	drained := false
+	var next time.Time
+	setNext := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	for _, window := range windows {
+		drainAt := window.Start.Add(-lead)
+		if !now.Before(drainAt) && now.Before(window.End) {
+			drained = true
+		}
+		setNext(drainAt)
+		setNext(window.End)
+	}
+	return drained, next
+}
@@ -0,0 +1,58 @@
+package clusterSecret
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInMaintenance(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	windows := []MaintenanceWindow{{Start: at("2023-01-10T02:00:00Z"), End: at("2023-01-10T04:00:00Z")}}
+
+	cases := []struct {
+		Name            string
+		Now             time.Time
+		ExpectedDrained bool
+		ExpectedNext    time.Time
+	}{
+		{
+			Name:         "before the lead time",
+			Now:          at("2023-01-10T01:00:00Z"),
+			ExpectedNext: at("2023-01-10T01:55:00Z"),
+		},
+		{
+			Name:            "within the lead time",
+			Now:             at("2023-01-10T01:58:00Z"),
+			ExpectedDrained: true,
+			ExpectedNext:    at("2023-01-10T04:00:00Z"),
+		},
+		{
+			Name:            "within the window",
+			Now:             at("2023-01-10T03:00:00Z"),
+			ExpectedDrained: true,
+			ExpectedNext:    at("2023-01-10T04:00:00Z"),
+		},
+		{
+			Name: "after the window",
+			Now:  at("2023-01-10T04:00:00Z"),
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			drained, next := InMaintenance(windows, testCase.Now, 5*time.Minute)
+			if drained != testCase.ExpectedDrained {
+				t.Errorf("expected drained '%v' got '%v'", testCase.ExpectedDrained, drained)
+			}
+			if !next.Equal(testCase.ExpectedNext) {
+				t.Errorf("expected next change at '%v' got '%v'", testCase.ExpectedNext, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package clusterSecret
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+)
+
+// AnnotationPortMappings declares the ports the cluster exposes the
+// canonical ports of the traffic objects on, when it can't expose them
+// directly, e.g. behind a shared load balancer or a NodePort, as a comma
+// separated list of canonical=exposed entries, e.g. 443=30443,80=30080
+const AnnotationPortMappings = "kuadrant.io/port-mappings"
+
+// HTTPSPort is the canonical port of the traffic objects served with TLS
+const HTTPSPort int32 = 443
+
+// PortMappings parses the port mappings of the cluster secret, keyed by
+// canonical port
+func PortMappings(secret *corev1.Secret) (map[int32]int32, error) {
+	pairs, err := parsePairs(metadata.GetAnnotation(secret, AnnotationPortMappings))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationPortMappings, err)
+	}
+	mappings := map[int32]int32{}
+	for _, pair := range pairs {
+		canonical, err := parsePort(pair[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationPortMappings, err)
+		}
+		exposed, err := parsePort(pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationPortMappings, err)
+		}
+		mappings[canonical] = exposed
+	}
+	return mappings, nil
+}
+
+// MappedPort returns the port the canonical port is exposed on according to
+// the mappings
+func MappedPort(mappings map[int32]int32, canonical int32) int32 {
+	if exposed, ok := mappings[canonical]; ok {
+		return exposed
+	}
+	return canonical
+}
+
+func parsePort(value string) (int32, error) {
+	port, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", value)
+	}
+	return int32(port), nil
+}
@@ -0,0 +1,185 @@
+package clusterSecret
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+)
+
+const (
+	// AnnotationTokenServiceAccount opts a cluster secret into scoped
+	// credentials. The value is the `namespace/name` of the service account in
+	// the workload cluster that bound tokens are requested for
+	AnnotationTokenServiceAccount = "kuadrant.io/token-service-account"
+	// AnnotationTokenExpiry records when the bearer token currently stored in
+	// the cluster secret expires, in RFC3339 format
+	AnnotationTokenExpiry = "kuadrant.io/token-expiry"
+	// ScopedTokenKey is the key of the cluster secret holding the scoped
+	// token requested by the hub. It's kept apart from the credentials of
+	// the config, which the tokens are requested with
+	ScopedTokenKey = "scopedToken"
+	// AnnotationRegion is the AWS region closest to the workload cluster.
+	// Resolvers are answered with the clusters of the region with the lowest
+	// latency to them for the traffic objects using latency routing
+	AnnotationRegion = "kuadrant.io/region"
+
+	// tokenReloadPeriod is how long a token read from a cluster secret is used
+	// before it's read again
+	tokenReloadPeriod = time.Minute
+)
+
+type TLSClientConfig struct {
+	Insecure bool   `json:"insecure"`
+	CaData   []byte `json:"caData,omitempty"`
+	CertData []byte `json:"certData,omitempty"`
+	KeyData  []byte `json:"keyData,omitempty"`
+}
+
+type ProviderConfig struct {
+	Command    string   `json:"command,omitempty"`
+	Args       []string `json:"args,omitempty"`
+	APIVersion string   `json:"apiVersion,omitempty"`
+}
+
+type ArgoClusterConfig struct {
+	BearerToken        string          `json:"bearerToken,omitempty"`
+	Username           string          `json:"username,omitempty"`
+	Password           string          `json:"password,omitempty"`
+	TlsClientConfig    TLSClientConfig `json:"tlsClientConfig,omitempty"`
+	ExecProviderConfig ProviderConfig  `json:"execProviderConfig,omitempty"`
+}
+
+// ConfigFromSecret reads the cluster connection config stored in the secret
+func ConfigFromSecret(secret *corev1.Secret) (*ArgoClusterConfig, error) {
+	clusterClientConfig := &ArgoClusterConfig{}
+	if err := json.Unmarshal(secret.Data["config"], clusterClientConfig); err != nil {
+		return nil, err
+	}
+	return clusterClientConfig, nil
+}
+
+// SetConfig writes the cluster connection config into the secret
+func SetConfig(secret *corev1.Secret, config *ArgoClusterConfig) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data["config"] = data
+	return nil
+}
+
+// HasScopedToken returns true when the cluster secret holds a bound service
+// account token refreshed by the hub
+func HasScopedToken(secret *corev1.Secret) bool {
+	return metadata.HasAnnotation(secret, AnnotationTokenServiceAccount) && len(secret.Data[ScopedTokenKey]) > 0
+}
+
+// Region returns the AWS region closest to the cluster, or an empty string
+// when it isn't declared
+func Region(secret *corev1.Secret) string {
+	return metadata.GetAnnotation(secret, AnnotationRegion)
+}
+
+// RestConfigFromSecret builds the rest config to access the cluster described
+// by the secret. When the secret holds a scoped token, the cluster is
+// accessed with the token instead of the credentials of the config. The
+// token is re-read from the secret through the reader periodically, so
+// tokens refreshed by the hub are used without rebuilding the config
+func RestConfigFromSecret(reader client.Reader, secret *corev1.Secret) (*rest.Config, error) {
+	restConfig, err := ConfiguredRestConfigFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if HasScopedToken(secret) {
+		restConfig.BearerToken = ""
+		restConfig.Username = ""
+		restConfig.Password = ""
+		restConfig.TLSClientConfig.CertData = nil
+		restConfig.TLSClientConfig.KeyData = nil
+		restConfig.WrapTransport = transport.TokenSourceWrapTransport(transport.NewCachedTokenSource(&secretTokenSource{
+			reader: reader,
+			key:    client.ObjectKeyFromObject(secret),
+		}))
+	}
+
+	return restConfig, nil
+}
+
+// ConfiguredRestConfigFromSecret builds the rest config to access the
+// cluster described by the secret with the credentials of its config, the
+// scoped token of the secret left out
+func ConfiguredRestConfigFromSecret(secret *corev1.Secret) (*rest.Config, error) {
+	clusterClientConfig, err := ConfigFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	hostUrl, err := url.Parse(string(secret.Data["server"]))
+	if err != nil {
+		return nil, err
+	}
+
+	return &rest.Config{
+		Host:        hostUrl.Host,
+		Username:    clusterClientConfig.Username,
+		Password:    clusterClientConfig.Password,
+		BearerToken: clusterClientConfig.BearerToken,
+		TLSClientConfig: rest.TLSClientConfig{
+			ServerName: strings.SplitN(hostUrl.Host, ":", 2)[0],
+			CertData:   clusterClientConfig.TlsClientConfig.CertData,
+			KeyData:    clusterClientConfig.TlsClientConfig.KeyData,
+			CAData:     clusterClientConfig.TlsClientConfig.CaData,
+		},
+	}, nil
+}
+
+// ClientFromSecret returns a client for the cluster described by the secret.
+// See RestConfigFromSecret
+func ClientFromSecret(reader client.Reader, secret *corev1.Secret, options client.Options) (client.Client, error) {
+	restConfig, err := RestConfigFromSecret(reader, secret)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(restConfig, options)
+}
+
+// secretTokenSource reads the bearer token from the current version of a
+// cluster secret
+type secretTokenSource struct {
+	reader client.Reader
+	key    types.NamespacedName
+}
+
+var _ oauth2.TokenSource = &secretTokenSource{}
+
+func (ts *secretTokenSource) Token() (*oauth2.Token, error) {
+	secret := &corev1.Secret{}
+	if err := ts.reader.Get(context.Background(), ts.key, secret); err != nil {
+		return nil, fmt.Errorf("failed to read token from cluster secret %s: %v", ts.key, err)
+	}
+	token := string(secret.Data[ScopedTokenKey])
+	if token == "" {
+		return nil, fmt.Errorf("cluster secret %s has no scoped token", ts.key)
+	}
+
+	return &oauth2.Token{
+		AccessToken: token,
+		Expiry:      time.Now().Add(tokenReloadPeriod),
+	}, nil
+}
@@ -0,0 +1,156 @@
+package clusterSecret
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+)
+
+// AnnotationTaints declares the taints of the cluster, as a comma separated
+// list of key, key=value, key:effect or key=value:effect entries, e.g.
+// maintenance:NoExecute,gpu-only=true. Tainted clusters are excluded from the
+// placements that don't tolerate every one of their taints, and the traffic
+// objects that don't tolerate their NoExecute taints have their endpoints
+// withdrawn
+const AnnotationTaints = "kuadrant.io/taints"
+
+// TaintEffect is what a taint does to what doesn't tolerate it
+type TaintEffect string
+
+const (
+	// TaintEffectNoSchedule excludes the cluster from placements. It's the
+	// effect of taints without one
+	TaintEffectNoSchedule TaintEffect = "NoSchedule"
+	// TaintEffectNoExecute excludes the cluster from placements, and
+	// withdraws the endpoints the traffic objects of the cluster already
+	// published
+	TaintEffectNoExecute TaintEffect = "NoExecute"
+)
+
+// Taint excludes the cluster from placements that don't tolerate it
+type Taint struct {
+	Key    string
+	Value  string
+	Effect TaintEffect
+}
+
+// Toleration tolerates the taints with its key and, when set, its value and
+// its effect
+type Toleration struct {
+	Key    string
+	Value  string
+	Effect TaintEffect
+}
+
+// Taints parses the taints of the cluster secret
+func Taints(secret *corev1.Secret) ([]Taint, error) {
+	entries, err := parseEntries(metadata.GetAnnotation(secret, AnnotationTaints))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationTaints, err)
+	}
+	taints := make([]Taint, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Effect == "" {
+			entry.Effect = TaintEffectNoSchedule
+		}
+		taints = append(taints, Taint(entry))
+	}
+	return taints, nil
+}
+
+// ParseTolerations parses a comma separated list of key, key=value,
+// key:effect or key=value:effect tolerations
+func ParseTolerations(value string) ([]Toleration, error) {
+	entries, err := parseEntries(value)
+	if err != nil {
+		return nil, err
+	}
+	tolerations := make([]Toleration, 0, len(entries))
+	for _, entry := range entries {
+		tolerations = append(tolerations, Toleration(entry))
+	}
+	return tolerations, nil
+}
+
+// NoExecute returns the taints with the NoExecute effect
+func NoExecute(taints []Taint) []Taint {
+	noExecute := []Taint{}
+	for _, taint := range taints {
+		if taint.Effect == TaintEffectNoExecute {
+			noExecute = append(noExecute, taint)
+		}
+	}
+	return noExecute
+}
+
+// Tolerates returns true when every taint is tolerated by a toleration
+func Tolerates(tolerations []Toleration, taints []Taint) bool {
+	for _, taint := range taints {
+		tolerated := false
+		for _, toleration := range tolerations {
+			if toleration.Key == taint.Key && (toleration.Value == "" || toleration.Value == taint.Value) &&
+				(toleration.Effect == "" || toleration.Effect == taint.Effect) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// entry is a parsed taint or toleration
+type entry struct {
+	Key    string
+	Value  string
+	Effect TaintEffect
+}
+
+// parseEntries parses key=value pairs whose value, or key when there's no
+// value, may end with an :effect
+func parseEntries(value string) ([]entry, error) {
+	pairs, err := parsePairs(value)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]entry, 0, len(pairs))
+	for _, pair := range pairs {
+		e := entry{Key: pair[0], Value: pair[1]}
+		effect := ""
+		if pair[1] != "" {
+			e.Value, effect, _ = strings.Cut(pair[1], ":")
+		} else {
+			e.Key, effect, _ = strings.Cut(pair[0], ":")
+		}
+		if effect != "" && TaintEffect(effect) != TaintEffectNoSchedule && TaintEffect(effect) != TaintEffectNoExecute {
+			return nil, fmt.Errorf("entry %q has an unknown effect %q, expected %s or %s", e.Key, effect, TaintEffectNoSchedule, TaintEffectNoExecute)
+		}
+		if e.Key == "" {
+			return nil, fmt.Errorf("entry of effect %q has no key", effect)
+		}
+		e.Effect = TaintEffect(effect)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func parsePairs(value string) ([][2]string, error) {
+	var pairs [][2]string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, v, _ := strings.Cut(entry, "=")
+		if key == "" {
+			return nil, fmt.Errorf("entry %q has no key", entry)
+		}
+		pairs = append(pairs, [2]string{key, v})
+	}
+	return pairs, nil
+}
@@ -0,0 +1,120 @@
+package clusterSecret
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTolerates(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Tolerations string
+		Taints      []Taint
+		Expected    bool
+	}{
+		{
+			Name:     "untainted cluster",
+			Expected: true,
+		},
+		{
+			Name:     "taint not tolerated",
+			Taints:   []Taint{{Key: "maintenance"}},
+			Expected: false,
+		},
+		{
+			Name:        "toleration without a value tolerates any value",
+			Tolerations: "gpu-only",
+			Taints:      []Taint{{Key: "gpu-only", Value: "true"}},
+			Expected:    true,
+		},
+		{
+			Name:        "toleration of another value",
+			Tolerations: "gpu-only=false",
+			Taints:      []Taint{{Key: "gpu-only", Value: "true"}},
+			Expected:    false,
+		},
+		{
+			Name:        "toleration of another effect",
+			Tolerations: "maintenance:NoSchedule",
+			Taints:      []Taint{{Key: "maintenance", Effect: TaintEffectNoExecute}},
+			Expected:    false,
+		},
+		{
+			Name:        "toleration of the effect",
+			Tolerations: "maintenance=true:NoExecute",
+			Taints:      []Taint{{Key: "maintenance", Value: "true", Effect: TaintEffectNoExecute}},
+			Expected:    true,
+		},
+		{
+			Name:     "only NoExecute taints withdraw endpoints",
+			Taints:   NoExecute([]Taint{{Key: "gpu-only", Effect: TaintEffectNoSchedule}}),
+			Expected: true,
+		},
+		{
+			Name:        "every taint must be tolerated",
+			Tolerations: "gpu-only",
+			Taints:      []Taint{{Key: "gpu-only"}, {Key: "maintenance"}},
+			Expected:    false,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			tolerations, err := ParseTolerations(testCase.Tolerations)
+			if err != nil {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+			if got := Tolerates(tolerations, testCase.Taints); got != testCase.Expected {
+				t.Errorf("expected '%v' got '%v'", testCase.Expected, got)
+			}
+		})
+	}
+}
+
+func TestTaints(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Annotation  string
+		Expected    []Taint
+		ExpectedErr bool
+	}{
+		{
+			Name:       "taint without an effect",
+			Annotation: "gpu-only=true",
+			Expected:   []Taint{{Key: "gpu-only", Value: "true", Effect: TaintEffectNoSchedule}},
+		},
+		{
+			Name:       "taints with effects",
+			Annotation: "maintenance:NoExecute, gpu-only=true:NoSchedule",
+			Expected: []Taint{
+				{Key: "maintenance", Effect: TaintEffectNoExecute},
+				{Key: "gpu-only", Value: "true", Effect: TaintEffectNoSchedule},
+			},
+		},
+		{
+			Name:        "unknown effect",
+			Annotation:  "maintenance:PreferNoSchedule",
+			ExpectedErr: true,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{AnnotationTaints: testCase.Annotation}}}
+			taints, err := Taints(secret)
+			if (err != nil) != testCase.ExpectedErr {
+				t.Fatalf("expected error '%v' got '%v'", testCase.ExpectedErr, err)
+			}
+			if len(taints) != len(testCase.Expected) {
+				t.Fatalf("expected '%v' got '%v'", testCase.Expected, taints)
+			}
+			for i := range taints {
+				if taints[i] != testCase.Expected[i] {
+					t.Errorf("expected '%v' got '%v'", testCase.Expected[i], taints[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package clusterSecret
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+)
+
+const (
+	// AnnotationKubernetesVersion is set on cluster secrets to the
+	// Kubernetes version detected in the cluster, e.g. v1.26.1
+	AnnotationKubernetesVersion = "kuadrant.io/kubernetes-version"
+	// AnnotationGatewayAPIVersion is set on cluster secrets to the bundle
+	// version of the Gateway API CRDs detected in the cluster, e.g. v0.6.1.
+	// Not set when the Gateway API isn't installed
+	AnnotationGatewayAPIVersion = "kuadrant.io/gateway-api-version"
+
+	// gatewayCRD is the CRD of the Gateways of the Gateway API
+	gatewayCRD = "gateways.gateway.networking.k8s.io"
+	// annotationGatewayBundleVersion is set on the Gateway API CRDs to the
+	// version of the Gateway API release they were installed from
+	annotationGatewayBundleVersion = "gateway.networking.k8s.io/bundle-version"
+)
+
+// Versions are the Kubernetes and Gateway API versions of a cluster
+type Versions struct {
+	Kubernetes string
+	GatewayAPI string
+}
+
+// DetectVersions returns the Kubernetes version of the cluster, and the
+// bundle version of its Gateway API CRDs when installed
+func DetectVersions(ctx context.Context, restConfig *rest.Config) (Versions, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return Versions{}, err
+	}
+	info, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return Versions{}, fmt.Errorf("failed to get the Kubernetes version: %w", err)
+	}
+	versions := Versions{Kubernetes: info.GitVersion}
+
+	workloadClient, err := client.New(restConfig, client.Options{})
+	if err != nil {
+		return Versions{}, err
+	}
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+	if err := workloadClient.Get(ctx, client.ObjectKey{Name: gatewayCRD}, crd); err != nil {
+		if err := client.IgnoreNotFound(err); err != nil {
+			return Versions{}, fmt.Errorf("failed to get the Gateway API version: %w", err)
+		}
+		return versions, nil
+	}
+	versions.GatewayAPI = metadata.GetAnnotation(crd, annotationGatewayBundleVersion)
+	return versions, nil
+}
+
+// SetVersions records the versions on the cluster secret, returning true
+// when they changed
+func SetVersions(secret *corev1.Secret, versions Versions) bool {
+	changed := false
+	for annotation, value := range map[string]string{
+		AnnotationKubernetesVersion: versions.Kubernetes,
+		AnnotationGatewayAPIVersion: versions.GatewayAPI,
+	} {
+		if metadata.GetAnnotation(secret, annotation) == value {
+			continue
+		}
+		changed = true
+		if value == "" {
+			metadata.RemoveAnnotation(secret, annotation)
+		} else {
+			metadata.AddAnnotation(secret, annotation, value)
+		}
+	}
+	return changed
+}
+
+// ValidateMinimumVersions returns an error when a minimum Kubernetes or
+// Gateway API version is set but isn't a version
+func ValidateMinimumVersions(minKubernetes, minGatewayAPI string) error {
+	for _, minimum := range []string{minKubernetes, minGatewayAPI} {
+		if minimum == "" {
+			continue
+		}
+		if _, err := version.ParseGeneric(minimum); err != nil {
+			return fmt.Errorf("invalid minimum version %s: %w", minimum, err)
+		}
+	}
+	return nil
+}
+
+// CheckMinimumVersions returns an error explaining why the cluster of the
+// secret doesn't meet the minimum Kubernetes and Gateway API versions, when
+// set. Clusters whose versions weren't detected don't meet them
+func CheckMinimumVersions(secret *corev1.Secret, minKubernetes, minGatewayAPI string) error {
+	for _, requirement := range []struct {
+		name       string
+		annotation string
+		minimum    string
+	}{
+		{name: "Kubernetes", annotation: AnnotationKubernetesVersion, minimum: minKubernetes},
+		{name: "Gateway API", annotation: AnnotationGatewayAPIVersion, minimum: minGatewayAPI},
+	} {
+		if requirement.minimum == "" {
+			continue
+		}
+		minimum, err := version.ParseGeneric(requirement.minimum)
+		if err != nil {
+			return fmt.Errorf("invalid minimum %s version %s: %w", requirement.name, requirement.minimum, err)
+		}
+		detected := metadata.GetAnnotation(secret, requirement.annotation)
+		if detected == "" {
+			return fmt.Errorf("%s version of cluster %s not detected, %s required", requirement.name, secret.Name, requirement.minimum)
+		}
+		current, err := version.ParseGeneric(detected)
+		if err != nil {
+			return fmt.Errorf("invalid %s version %s of cluster %s: %w", requirement.name, detected, secret.Name, err)
+		}
+		if current.LessThan(minimum) {
+			return fmt.Errorf("%s version %s of cluster %s is older than %s", requirement.name, detected, secret.Name, requirement.minimum)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package clusterSecret
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckMinimumVersions(t *testing.T) {
+	secret := func(kubernetes, gatewayAPI string) *corev1.Secret {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+		SetVersions(s, Versions{Kubernetes: kubernetes, GatewayAPI: gatewayAPI})
+		return s
+	}
+
+	cases := []struct {
+		Name          string
+		Secret        *corev1.Secret
+		MinKubernetes string
+		MinGatewayAPI string
+		Expected      bool
+	}{
+		{
+			Name:     "no minimum versions",
+			Secret:   secret("", ""),
+			Expected: true,
+		},
+		{
+			Name:          "newer versions",
+			Secret:        secret("v1.26.1+k3s1", "v0.6.1"),
+			MinKubernetes: "v1.25",
+			MinGatewayAPI: "v0.6.0",
+			Expected:      true,
+		},
+		{
+			Name:          "older Kubernetes version",
+			Secret:        secret("v1.24.9", "v0.6.1"),
+			MinKubernetes: "v1.25",
+			Expected:      false,
+		},
+		{
+			Name:          "Gateway API not installed",
+			Secret:        secret("v1.26.1", ""),
+			MinGatewayAPI: "v0.6.0",
+			Expected:      false,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			err := CheckMinimumVersions(testCase.Secret, testCase.MinKubernetes, testCase.MinGatewayAPI)
+			if met := err == nil; met != testCase.Expected {
+				t.Errorf("expected '%v' got '%v'", testCase.Expected, err)
+			}
+		})
+	}
+}
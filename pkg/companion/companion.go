@@ -0,0 +1,169 @@
+package companion
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// LabelIngressClass set on a ConfigMap of the controller namespace declares
+// its data as the templates of the companion objects of the traffic objects
+// of the ingress class, such as the Service, Deployment and
+// HorizontalPodAutoscaler of a self-hosted data plane. Each key holds the
+// manifest of one object as a Go template, rendered with Values for each
+// traffic object in each cluster. The port function of the templates
+// returns the port the cluster exposes a canonical port on, according to the
+// port mappings of its cluster secret, e.g. {{ port 443 }}
+const LabelIngressClass = "kuadrant.io/ingress-class"
+
+// Values are the values the companion object templates are rendered with
+type Values struct {
+	// Name and Namespace are the name and namespace of the traffic object
+	Name      string
+	Namespace string
+	// Class is the ingress class of the traffic object
+	Class string
+	// Hosts are the hosts of the traffic object, including its managed hosts
+	Hosts []string
+	// Cluster is the name of the cluster secret of the cluster the objects
+	// are rendered for, and ClusterLabels its labels
+	Cluster       string
+	ClusterLabels map[string]string
+}
+
+// Renderer renders the companion objects of the traffic objects from the
+// templates of the control plane
+type Renderer struct {
+	client client.Client
+	// namespace is the controller namespace holding the templates and the
+	// cluster secrets
+	namespace string
+}
+
+func NewRenderer(c client.Client, namespace string) *Renderer {
+	return &Renderer{client: c, namespace: namespace}
+}
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// Render returns the companion objects of the traffic object in the
+// cluster, rendered from the templates of its ingress class. The objects
+// are namespaced objects of the namespace of the traffic object
+func (r *Renderer) Render(ctx context.Context, t traffic.Interface, cluster string) ([]*unstructured.Unstructured, error) {
+	class := t.GetClassName()
+	if class == "" {
+		return nil, nil
+	}
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.client.List(ctx, configMaps, client.InNamespace(r.namespace), client.MatchingLabels{LabelIngressClass: class}); err != nil {
+		return nil, err
+	}
+	if len(configMaps.Items) == 0 {
+		return nil, nil
+	}
+	values := Values{
+		Name:      t.GetName(),
+		Namespace: t.GetNamespace(),
+		Class:     class,
+		Hosts:     t.GetHosts(),
+		Cluster:   cluster,
+	}
+	secret := &corev1.Secret{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: r.namespace, Name: cluster}, secret); client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	values.ClusterLabels = secret.Labels
+	mappings, err := clusterSecret.PortMappings(secret)
+	if err != nil {
+		return nil, err
+	}
+	funcs := template.FuncMap{
+		"port": func(canonical int) int {
+			return int(clusterSecret.MappedPort(mappings, int32(canonical)))
+		},
+	}
+
+	sort.Slice(configMaps.Items, func(i, j int) bool {
+		return configMaps.Items[i].Name < configMaps.Items[j].Name
+	})
+	objects := []*unstructured.Unstructured{}
+	for _, configMap := range configMaps.Items {
+		keys := make([]string, 0, len(configMap.Data))
+		for key := range configMap.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			object, err := render(configMap.Data[key], values, funcs)
+			if err != nil {
+				return nil, fmt.Errorf("companion template %s/%s: %w", configMap.Name, key, err)
+			}
+			if object == nil {
+				continue
+			}
+			object.SetNamespace(t.GetNamespace())
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// render renders the template of an object. It returns nil when the
+// template renders to nothing, so templates can be conditional
+func render(text string, values Values, funcs template.FuncMap) (*unstructured.Unstructured, error) {
+	tmpl, err := template.New("companion").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, values); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(out.String()) == "" {
+		return nil, nil
+	}
+	data, err := yaml.YAMLToJSON(out.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	if object.GetAPIVersion() == "" || object.GetKind() == "" || object.GetName() == "" {
+		return nil, fmt.Errorf("rendered object must have an apiVersion, kind and name")
+	}
+	return object, nil
+}
+
+// Reference identifies a companion object applied for a traffic object, as
+// apiVersion/kind/name
+func Reference(object *unstructured.Unstructured) string {
+	return object.GetAPIVersion() + "/" + object.GetKind() + "/" + object.GetName()
+}
+
+// ParseReference returns an object of the apiVersion, kind and name of the
+// reference, in the namespace
+func ParseReference(reference, namespace string) (*unstructured.Unstructured, error) {
+	parts := strings.Split(reference, "/")
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid companion object reference %q", reference)
+	}
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(strings.Join(parts[:len(parts)-2], "/"))
+	object.SetKind(parts[len(parts)-2])
+	object.SetName(parts[len(parts)-1])
+	object.SetNamespace(namespace)
+	return object, nil
+}
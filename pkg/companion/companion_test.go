@@ -0,0 +1,91 @@
+package companion
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	class := "envoy"
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "envoy", Namespace: "argocd", Labels: map[string]string{LabelIngressClass: class}},
+			Data: map[string]string{
+				"service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Name }}-lb
+  namespace: other
+  labels:
+    region: {{ index .ClusterLabels "region" }}
+spec:
+  type: LoadBalancer
+  ports:
+  - port: {{ port 443 }}`,
+				"hpa.yaml": `{{ if eq (index .ClusterLabels "env") "prod" }}apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: {{ .Name }}
+{{ end }}`,
+			},
+		},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: "argocd", Labels: map[string]string{"region": "eu", "env": "dev"},
+			Annotations: map[string]string{clusterSecret.AnnotationPortMappings: "443=30443"}}},
+	).Build()
+	r := NewRenderer(c, "argocd")
+
+	cases := []struct {
+		Name     string
+		Class    *string
+		Expected []string
+	}{
+		{
+			Name:     "test templates of the class rendered",
+			Class:    &class,
+			Expected: []string{"v1/Service/echo-lb"},
+		},
+		{
+			Name:     "test no templates without a class",
+			Expected: []string{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "echo", Namespace: "default"},
+				Spec:       networkingv1.IngressSpec{IngressClassName: tc.Class},
+			}
+			objects, err := r.Render(context.Background(), traffic.NewIngress(ingress), "cluster-1")
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			references := []string{}
+			for _, object := range objects {
+				references = append(references, Reference(object))
+				if object.GetNamespace() != "default" {
+					t.Errorf("expected '%v' got '%v'", "default", object.GetNamespace())
+				}
+				if object.GetLabels()["region"] != "eu" {
+					t.Errorf("expected '%v' got '%v'", "eu", object.GetLabels()["region"])
+				}
+				ports, _, _ := unstructured.NestedSlice(object.Object, "spec", "ports")
+				if len(ports) != 1 || ports[0].(map[string]interface{})["port"] != int64(30443) {
+					t.Errorf("expected '%v' got '%v'", 30443, ports)
+				}
+			}
+			if !reflect.DeepEqual(references, tc.Expected) {
+				t.Errorf("expected '%v' got '%v'", tc.Expected, references)
+			}
+		})
+	}
+}
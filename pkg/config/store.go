@@ -0,0 +1,167 @@
+package config
+
+import (
+	"sync"
+	"time"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+)
+
+// Config is the configuration in effect in the controller
+type Config struct {
+	DefaultZoneID         string
+	DefaultZoneRootDomain string
+	CertificateIssuer     string
+	// CertificateAuthorities are the CAA issuer domains of the certificate
+	// authority behind the CertificateIssuer
+	CertificateAuthorities []string
+
+	DNSVerifyInterval time.Duration
+	HTTPSRedirect     bool
+	ClusterHostnames  bool
+	// HostCollision is how a host already managed for another traffic
+	// object is handled
+	HostCollision v1.HostCollisionPolicy
+	// AdmissionHostCollision is how the admission webhook handles a traffic
+	// object requesting a host owned by another object
+	AdmissionHostCollision v1.AdmissionHostCollisionPolicy
+	// HostReuseCooldown is how long a released host can't be assigned to
+	// another namespace
+	HostReuseCooldown time.Duration
+
+	ZoneCredentialsNamespaces []string
+
+	// PrivateZone is the name of the private ManagedZone the managed hosts
+	// of private traffic objects are assigned from
+	PrivateZone string
+	// FleetCIDRs are the source ranges of the fleet the backends of private
+	// traffic objects accept traffic from
+	FleetCIDRs []string
+
+	// TenantRootZone is the name of the public ManagedZone the subdomains of
+	// the namespaces are published to in the namespace-as-tenant mode,
+	// disabled when empty
+	TenantRootZone string
+	// TenantMaxHosts is the number of hosts of each namespace in the
+	// namespace-as-tenant mode. Zero means no limit
+	TenantMaxHosts int
+
+	// HostGeneration is how the managed hosts are generated in the zones
+	// that don't configure their own
+	HostGeneration v1.HostGeneration
+
+	FeatureGates map[string]bool
+	// LogLevels are the log verbosity of each subsystem
+	LogLevels map[string]int
+}
+
+// Enabled returns whether the feature is enabled by the feature gates
+func (c Config) Enabled(feature features.Feature) bool {
+	return features.Gates(c.FeatureGates).Enabled(feature)
+}
+
+// Store holds the configuration in effect. The defaults, configured by the
+// controller flags, are overridden by the fields set in the ControllerConfig
+// applied to the store, so configuration changes take effect without
+// restarting the controller
+type Store struct {
+	mu       sync.RWMutex
+	defaults Config
+	current  Config
+}
+
+func NewStore(defaults Config) *Store {
+	return &Store{defaults: defaults, current: defaults}
+}
+
+// Get returns the configuration in effect
+func (s *Store) Get() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Apply overrides the defaults with the fields set in the spec. A nil spec
+// restores the defaults
+func (s *Store) Apply(spec *v1.ControllerConfigSpec) {
+	config := s.defaults
+	if spec != nil {
+		if spec.DefaultZone != nil {
+			config.DefaultZoneID = spec.DefaultZone.ID
+			config.DefaultZoneRootDomain = spec.DefaultZone.RootDomain
+		}
+		if spec.CertificateIssuer != "" {
+			config.CertificateIssuer = spec.CertificateIssuer
+		}
+		if spec.CertificateAuthorities != nil {
+			config.CertificateAuthorities = spec.CertificateAuthorities
+		}
+		if spec.Sync != nil {
+			if spec.Sync.DNSVerifyInterval != nil {
+				config.DNSVerifyInterval = spec.Sync.DNSVerifyInterval.Duration
+			}
+			if spec.Sync.HTTPSRedirect != nil {
+				config.HTTPSRedirect = *spec.Sync.HTTPSRedirect
+			}
+			if spec.Sync.ClusterHostnames != nil {
+				config.ClusterHostnames = *spec.Sync.ClusterHostnames
+			}
+			if spec.Sync.HostCollision != "" {
+				config.HostCollision = spec.Sync.HostCollision
+			}
+			if spec.Sync.AdmissionHostCollision != "" {
+				config.AdmissionHostCollision = spec.Sync.AdmissionHostCollision
+			}
+			if spec.Sync.HostReuseCooldown != nil {
+				config.HostReuseCooldown = spec.Sync.HostReuseCooldown.Duration
+			}
+		}
+		if spec.Provider != nil && spec.Provider.ZoneCredentialsNamespaces != nil {
+			config.ZoneCredentialsNamespaces = spec.Provider.ZoneCredentialsNamespaces
+		}
+		if spec.PrivateTraffic != nil {
+			if spec.PrivateTraffic.Zone != "" {
+				config.PrivateZone = spec.PrivateTraffic.Zone
+			}
+			if spec.PrivateTraffic.FleetCIDRs != nil {
+				config.FleetCIDRs = spec.PrivateTraffic.FleetCIDRs
+			}
+		}
+		if spec.Tenancy != nil {
+			if spec.Tenancy.RootZone != "" {
+				config.TenantRootZone = spec.Tenancy.RootZone
+			}
+			if spec.Tenancy.MaxHosts != nil {
+				config.TenantMaxHosts = *spec.Tenancy.MaxHosts
+			}
+		}
+		if spec.HostGeneration != nil {
+			config.HostGeneration = *spec.HostGeneration
+		}
+		if len(spec.FeatureGates) > 0 {
+			gates := map[string]bool{}
+			for name, enabled := range s.defaults.FeatureGates {
+				gates[name] = enabled
+			}
+			for name, enabled := range spec.FeatureGates {
+				gates[name] = enabled
+			}
+			config.FeatureGates = gates
+		}
+		if len(spec.LogLevels) > 0 {
+			levels := map[string]int{}
+			for name, verbosity := range s.defaults.LogLevels {
+				levels[name] = verbosity
+			}
+			for name, verbosity := range spec.LogLevels {
+				levels[name] = verbosity
+			}
+			config.LogLevels = levels
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = config
+}
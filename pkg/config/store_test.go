@@ -0,0 +1,89 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestStore_Apply(t *testing.T) {
+	defaults := func() Config {
+		return Config{
+			DefaultZoneID:         "Z1",
+			DefaultZoneRootDomain: "apps.example.com",
+			CertificateIssuer:     "letsencrypt",
+			DNSVerifyInterval:     time.Minute,
+			HTTPSRedirect:         true,
+			FleetCIDRs:            []string{"10.244.0.0/16"},
+			FeatureGates:          map[string]bool{"LatencyDNS": true, "BackendPlacement": false},
+			LogLevels:             map[string]int{"dns": 1},
+		}
+	}
+	redirect := false
+
+	tests := []struct {
+		name     string
+		spec     *v1.ControllerConfigSpec
+		expected func(c Config) Config
+	}{
+		{
+			name:     "no ControllerConfig uses the flags",
+			expected: func(c Config) Config { return c },
+		},
+		{
+			name:     "empty ControllerConfig uses the flags",
+			spec:     &v1.ControllerConfigSpec{},
+			expected: func(c Config) Config { return c },
+		},
+		{
+			name: "fields set override the flags",
+			spec: &v1.ControllerConfigSpec{
+				DefaultZone:    &v1.DefaultZone{ID: "Z2", RootDomain: "other.example.com"},
+				Sync:           &v1.SyncOptions{DNSVerifyInterval: &metav1.Duration{Duration: time.Second}, HTTPSRedirect: &redirect},
+				PrivateTraffic: &v1.PrivateTrafficOptions{Zone: "private", FleetCIDRs: []string{"10.0.0.0/8"}},
+			},
+			expected: func(c Config) Config {
+				c.DefaultZoneID = "Z2"
+				c.DefaultZoneRootDomain = "other.example.com"
+				c.DNSVerifyInterval = time.Second
+				c.HTTPSRedirect = false
+				c.PrivateZone = "private"
+				c.FleetCIDRs = []string{"10.0.0.0/8"}
+				return c
+			},
+		},
+		{
+			name: "feature gates and log levels merged over the flags",
+			spec: &v1.ControllerConfigSpec{
+				FeatureGates: map[string]bool{"BackendPlacement": true},
+				LogLevels:    map[string]int{"tls": 2},
+			},
+			expected: func(c Config) Config {
+				c.FeatureGates = map[string]bool{"LatencyDNS": true, "BackendPlacement": true}
+				c.LogLevels = map[string]int{"dns": 1, "tls": 2}
+				return c
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(defaults())
+			store.Apply(tt.spec)
+			if got, expected := store.Get(), tt.expected(defaults()); !reflect.DeepEqual(got, expected) {
+				t.Errorf("expected '%+v' got '%+v'", expected, got)
+			}
+
+			// the flags aren't changed by the ControllerConfig, so they're
+			// restored once it's deleted
+			store.Apply(nil)
+			if got := store.Get(); !reflect.DeepEqual(got, defaults()) {
+				t.Errorf("expected '%+v' got '%+v'", defaults(), got)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package challenge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	acmev1 "github.com/jetstack/cert-manager/pkg/apis/acme/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+)
+
+const (
+	challengeFinalizer = "kuadrant.io/http01-solver-cleanup"
+	// annotationSolvers records the `cluster/namespace` pairs the solver of
+	// the challenge was synced to, so it's removed from all of them
+	annotationSolvers = "kuadrant.io/http01-solvers"
+	// labelChallenge is set on the solver resources with the UID of their
+	// challenge
+	labelChallenge = "kuadrant.io/acme-challenge"
+
+	annotationIngressClass = "kubernetes.io/ingress.class"
+
+	solverPort = 8089
+)
+
+// clientFromSecret is to enable unit testing
+var clientFromSecret = clusterSecret.ClientFromSecret
+
+// ChallengeReconciler answers the ACME HTTP-01 challenges of managed hosts
+// from every cluster the host resolves to. The acmesolver cert-manager runs
+// for the challenge in the control plane isn't reachable through the host,
+// so a solver pod, service and ingress are synced to the namespace of each
+// traffic object the host has endpoints for, and removed once cert-manager
+// deletes the challenge
+type ChallengeReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Config holds whether HTTP-01 challenges are answered from the clusters
+	Config *config.Store
+	// ClusterNamespace is the namespace of the cluster secrets
+	ClusterNamespace string
+	// SolverImage is the cert-manager acmesolver image run in the clusters
+	SolverImage string
+}
+
+//+kubebuilder:rbac:groups=acme.cert-manager.io,resources=challenges,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=acme.cert-manager.io,resources=challenges/finalizers,verbs=update
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch
+
+func (r *ChallengeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = logging.IntoContext(ctx, logging.TLS)
+	previous := &acmev1.Challenge{}
+	if err := r.Client.Get(ctx, req.NamespacedName, previous); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	challenge := previous.DeepCopy()
+
+	if challenge.DeletionTimestamp != nil && !challenge.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(challenge, challengeFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		for _, target := range solverTargets(challenge) {
+			if err := r.removeSolver(ctx, challenge, target); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		controllerutil.RemoveFinalizer(challenge, challengeFinalizer)
+		return ctrl.Result{}, r.Client.Update(ctx, challenge)
+	}
+
+	if challenge.Spec.Type != acmev1.ACMEChallengeTypeHTTP01 || !r.Config.Get().Enabled(features.HTTP01Challenges) {
+		return ctrl.Result{}, nil
+	}
+
+	record := &v1.DNSRecord{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: challenge.Namespace, Name: challenge.Spec.DNSName}, record); err != nil {
+		if k8serrors.IsNotFound(err) {
+			log.FromContext(ctx).Info("no DNSRecord for challenge host, not a managed host", "challenge", challenge.Name, "host", challenge.Spec.DNSName)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	owners := map[string]dns.EndpointOwner{}
+	desired := []string{}
+	for _, owner := range dns.EndpointOwners(record) {
+		target := owner.Cluster + "/" + owner.Namespace
+		if _, ok := owners[target]; !ok {
+			owners[target] = owner
+			desired = append(desired, target)
+		}
+	}
+	recorded := solverTargets(challenge)
+
+	// the finalizer and the targets are recorded before the solvers are
+	// synced, so none is left behind
+	controllerutil.AddFinalizer(challenge, challengeFinalizer)
+	setSolverTargets(challenge, append(append([]string{}, recorded...), desired...))
+	if !equality.Semantic.DeepEqual(previous.ObjectMeta, challenge.ObjectMeta) {
+		if err := r.Client.Update(ctx, challenge); err != nil {
+			return ctrl.Result{}, err
+		}
+		previous = challenge.DeepCopy()
+	}
+
+	for _, target := range desired {
+		if err := r.ensureSolver(ctx, challenge, owners[target]); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	for _, target := range recorded {
+		if slice.ContainsString(desired, target) {
+			continue
+		}
+		if err := r.removeSolver(ctx, challenge, target); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	setSolverTargets(challenge, desired)
+	if !equality.Semantic.DeepEqual(previous.ObjectMeta, challenge.ObjectMeta) {
+		return ctrl.Result{}, r.Client.Update(ctx, challenge)
+	}
+	return ctrl.Result{}, nil
+}
+
+// solverTargets returns the `cluster/namespace` pairs the solver of the
+// challenge was synced to
+func solverTargets(challenge *acmev1.Challenge) []string {
+	value := metadata.GetAnnotation(challenge, annotationSolvers)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// setSolverTargets records the `cluster/namespace` pairs the solver of the
+// challenge is synced to
+func setSolverTargets(challenge *acmev1.Challenge, targets []string) {
+	unique := []string{}
+	for _, target := range targets {
+		if !slice.ContainsString(unique, target) {
+			unique = append(unique, target)
+		}
+	}
+	sort.Strings(unique)
+	metadata.AddAnnotation(challenge, annotationSolvers, strings.Join(unique, ","))
+}
+
+// workloadClient returns a client for the cluster
+func (r *ChallengeReconciler) workloadClient(ctx context.Context, cluster string) (client.Client, error) {
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: r.ClusterNamespace, Name: cluster}, secret); err != nil {
+		return nil, err
+	}
+	return clientFromSecret(r.Client, secret, client.Options{})
+}
+
+// ensureSolver creates the solver of the challenge in the namespace of the
+// traffic object, using the ingress class of the traffic object so the
+// challenge is served by the same ingress controller
+func (r *ChallengeReconciler) ensureSolver(ctx context.Context, challenge *acmev1.Challenge, owner dns.EndpointOwner) error {
+	workloadClient, err := r.workloadClient(ctx, owner.Cluster)
+	if err != nil {
+		return err
+	}
+
+	trafficIngress := &networkingv1.Ingress{}
+	if err := workloadClient.Get(ctx, client.ObjectKey{Namespace: owner.Namespace, Name: owner.Name}, trafficIngress); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	for _, obj := range r.solverObjects(challenge, owner.Namespace, trafficIngress) {
+		if err := workloadClient.Create(ctx, obj); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create HTTP-01 solver %s/%s in cluster %s: %v", obj.GetNamespace(), obj.GetName(), owner.Cluster, err)
+		}
+	}
+	log.FromContext(ctx).Info("HTTP-01 solver synced", "challenge", challenge.Name, "host", challenge.Spec.DNSName, "cluster", owner.Cluster, "namespace", owner.Namespace)
+	return nil
+}
+
+// removeSolver deletes the solver of the challenge from the target
+func (r *ChallengeReconciler) removeSolver(ctx context.Context, challenge *acmev1.Challenge, target string) error {
+	cluster, namespace, found := strings.Cut(target, "/")
+	if !found {
+		return nil
+	}
+	workloadClient, err := r.workloadClient(ctx, cluster)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// the cluster was removed, and the solver with it
+			return nil
+		}
+		return err
+	}
+	for _, obj := range r.solverObjects(challenge, namespace, &networkingv1.Ingress{}) {
+		if err := workloadClient.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete HTTP-01 solver %s/%s in cluster %s: %v", obj.GetNamespace(), obj.GetName(), cluster, err)
+		}
+	}
+	log.FromContext(ctx).Info("HTTP-01 solver removed", "challenge", challenge.Name, "host", challenge.Spec.DNSName, "cluster", cluster, "namespace", namespace)
+	return nil
+}
+
+// solverObjects returns the pod serving the key of the challenge, and the
+// service and ingress routing the challenge path of the host to it
+func (r *ChallengeReconciler) solverObjects(challenge *acmev1.Challenge, namespace string, trafficIngress *networkingv1.Ingress) []client.Object {
+	name := "acme-http01-" + string(challenge.UID)
+	labels := map[string]string{labelChallenge: string(challenge.UID)}
+	meta := func() metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: meta(),
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyOnFailure,
+			Containers: []corev1.Container{{
+				Name:  "acmesolver",
+				Image: r.SolverImage,
+				Args: []string{
+					fmt.Sprintf("--listen-port=%d", solverPort),
+					"--domain=" + challenge.Spec.DNSName,
+					"--token=" + challenge.Spec.Token,
+					"--key=" + challenge.Spec.Key,
+				},
+				Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: solverPort}},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("10m"),
+						corev1.ResourceMemory: resource.MustParse("64Mi"),
+					},
+				},
+			}},
+		},
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: meta(),
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       solverPort,
+				TargetPort: intstr.FromInt(solverPort),
+			}},
+		},
+	}
+
+	pathType := networkingv1.PathTypeExact
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: meta(),
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: trafficIngress.Spec.IngressClassName,
+			Rules: []networkingv1.IngressRule{{
+				Host: challenge.Spec.DNSName,
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/.well-known/acme-challenge/" + challenge.Spec.Token,
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: name,
+							Port: networkingv1.ServiceBackendPort{Number: solverPort},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+	// the solver ingress is left alone by the traffic controller and the
+	// admission webhook, which would otherwise manage the host of the
+	// ingress as a traffic object
+	metadata.AddAnnotation(ingress, metadata.AnnotationPaused, "true")
+	if class := metadata.GetAnnotation(trafficIngress, annotationIngressClass); class != "" {
+		metadata.AddAnnotation(ingress, annotationIngressClass, class)
+	}
+
+	return []client.Object{pod, service, ingress}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ChallengeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&acmev1.Challenge{}).
+		Watches(&source.Kind{Type: &v1.DNSRecord{}}, handler.EnqueueRequestsFromMapFunc(r.recordToChallenges)).
+		Complete(r)
+}
+
+// recordToChallenges maps a DNSRecord to the challenges of its host, so the
+// solvers follow the clusters the host resolves to
+func (r *ChallengeReconciler) recordToChallenges(o client.Object) []reconcile.Request {
+	challenges := &acmev1.ChallengeList{}
+	if err := r.Client.List(context.Background(), challenges, client.InNamespace(o.GetNamespace())); err != nil {
+		log.Log.Error(err, "Failed to list challenges for record", "record", o.GetName(), "namespace", o.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, challenge := range challenges.Items {
+		if challenge.Spec.DNSName == o.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&challenge)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,170 @@
+package challenge
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	acmev1 "github.com/jetstack/cert-manager/pkg/apis/acme/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+)
+
+func TestChallengeReconciler_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+	utilruntime.Must(acmev1.AddToScheme(scheme))
+	defer func(previous func(client.Reader, *corev1.Secret, client.Options) (client.Client, error)) {
+		clientFromSecret = previous
+	}(clientFromSecret)
+
+	challenge := func(challengeType acmev1.ACMEChallengeType, solvers string, deleting bool) *acmev1.Challenge {
+		challenge := &acmev1.Challenge{
+			ObjectMeta: metav1.ObjectMeta{Name: "challenge", Namespace: "argocd", UID: "uid"},
+			Spec:       acmev1.ChallengeSpec{Type: challengeType, DNSName: "shop.example.com", Token: "token", Key: "key"},
+		}
+		if solvers != "" {
+			metadata.AddAnnotation(challenge, annotationSolvers, solvers)
+			controllerutil.AddFinalizer(challenge, challengeFinalizer)
+		}
+		if deleting {
+			challenge.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+		}
+		return challenge
+	}
+	record := func(owners ...string) *v1.DNSRecord {
+		record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "shop.example.com", Namespace: "argocd"}}
+		for _, owner := range owners {
+			record.Spec.Endpoints = append(record.Spec.Endpoints, &v1.Endpoint{
+				DNSName:    "shop.example.com",
+				Targets:    []string{"1.1.1.1"},
+				RecordType: "A",
+				Labels:     map[string]string{"kuadrant.io/owner": owner},
+			})
+		}
+		return record
+	}
+	solverIngress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "acme-http01-uid", Namespace: "team-a"}}
+
+	tests := []struct {
+		name      string
+		challenge *acmev1.Challenge
+		record    *v1.DNSRecord
+		// solvers are the clusters with a solver before the reconcile
+		solvers       []string
+		expectSolvers []string
+		// expectTargets is the annotation of the challenge, nil once the
+		// challenge is released
+		expectTargets *string
+	}{
+		{
+			name:          "solvers synced to every cluster the host resolves to",
+			challenge:     challenge(acmev1.ACMEChallengeTypeHTTP01, "", false),
+			record:        record("cluster-a/team-a/shop", "cluster-b/team-a/shop"),
+			expectSolvers: []string{"cluster-a", "cluster-b"},
+			expectTargets: stringPtr("cluster-a/team-a,cluster-b/team-a"),
+		},
+		{
+			name:          "solver removed from the cluster the host no longer resolves to",
+			challenge:     challenge(acmev1.ACMEChallengeTypeHTTP01, "cluster-a/team-a,cluster-b/team-a", false),
+			record:        record("cluster-a/team-a/shop"),
+			solvers:       []string{"cluster-a", "cluster-b"},
+			expectSolvers: []string{"cluster-a"},
+			expectTargets: stringPtr("cluster-a/team-a"),
+		},
+		{
+			name:          "solvers removed with the challenge",
+			challenge:     challenge(acmev1.ACMEChallengeTypeHTTP01, "cluster-a/team-a,cluster-b/team-a", true),
+			record:        record("cluster-a/team-a/shop"),
+			solvers:       []string{"cluster-a", "cluster-b"},
+			expectSolvers: []string{},
+		},
+		{
+			name:          "DNS-01 challenges are left to cert-manager",
+			challenge:     challenge(acmev1.ACMEChallengeTypeDNS01, "", false),
+			record:        record("cluster-a/team-a/shop"),
+			expectSolvers: []string{},
+			expectTargets: stringPtr(""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workloadClients := map[string]client.Client{}
+			for _, cluster := range []string{"cluster-a", "cluster-b"} {
+				builder := fake.NewClientBuilder().WithScheme(scheme)
+				for _, solver := range tt.solvers {
+					if solver == cluster {
+						builder = builder.WithObjects(solverIngress.DeepCopy())
+					}
+				}
+				workloadClients[cluster] = builder.Build()
+			}
+			clientFromSecret = func(_ client.Reader, secret *corev1.Secret, _ client.Options) (client.Client, error) {
+				return workloadClients[secret.Name], nil
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.challenge, tt.record,
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", Namespace: "argocd"}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-b", Namespace: "argocd"}},
+			).Build()
+			r := &ChallengeReconciler{
+				Client:           c,
+				Scheme:           scheme,
+				Config:           config.NewStore(config.Config{FeatureGates: map[string]bool{string(features.HTTP01Challenges): true}}),
+				ClusterNamespace: "argocd",
+				SolverImage:      "acmesolver",
+			}
+
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tt.challenge)}); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			got := []string{}
+			for _, cluster := range []string{"cluster-a", "cluster-b"} {
+				err := workloadClients[cluster].Get(ctx, client.ObjectKeyFromObject(solverIngress), &networkingv1.Ingress{})
+				if err == nil {
+					got = append(got, cluster)
+				} else if !k8serrors.IsNotFound(err) {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expectSolvers) {
+				t.Errorf("expected '%v' got '%v'", tt.expectSolvers, got)
+			}
+			// the challenge is gone once its finalizer is removed
+			updated := &acmev1.Challenge{}
+			err := c.Get(ctx, client.ObjectKeyFromObject(tt.challenge), updated)
+			if tt.expectTargets == nil {
+				if !k8serrors.IsNotFound(err) {
+					t.Errorf("expected '%v' got '%v'", "not found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got := metadata.GetAnnotation(updated, annotationSolvers); got != *tt.expectTargets {
+				t.Errorf("expected '%v' got '%v'", *tt.expectTargets, got)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
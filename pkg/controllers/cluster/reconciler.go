@@ -67,9 +67,9 @@ func (r *AdmissionReconciler) Reconcile(ctx context.Context, obj Object) (ctrl.R
 		return ctrl.Result{}, err
 	}
 
-	log.Log.Info("getting webhook configurations")
+	log.FromContext(ctx).Info("getting webhook configurations")
 	validatingWebhooks, mutatingWebhooks := webhookAccessor.GetWebhookConfigurations(managedHost, bundleCA(tlsSecret))
-	log.Log.Info("create/update validating webhooks")
+	log.FromContext(ctx).Info("create/update validating webhooks")
 	for _, webhook := range validatingWebhooks {
 		g := &admissionv1.ValidatingWebhookConfiguration{
 			ObjectMeta: webhook.ObjectMeta,
@@ -83,7 +83,7 @@ func (r *AdmissionReconciler) Reconcile(ctx context.Context, obj Object) (ctrl.R
 			return ctrl.Result{}, err
 		}
 	}
-	log.Log.Info("create/update mutating webhooks")
+	log.FromContext(ctx).Info("create/update mutating webhooks")
 	for _, webhook := range mutatingWebhooks {
 		g := &admissionv1.MutatingWebhookConfiguration{
 			ObjectMeta: webhook.ObjectMeta,
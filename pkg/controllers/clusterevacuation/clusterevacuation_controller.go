@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterevacuation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+)
+
+// ClusterEvacuationReconciler withdraws the endpoints of an evacuated
+// cluster from every DNSRecord in the namespace of the controller, and
+// reports the progress of the evacuation until the records are published to
+// all their zones. Only the ClusterEvacuations in the namespace of the
+// controller are honoured, as the DNS service only looks them up there
+type ClusterEvacuationReconciler struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Namespace string
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=clusterevacuations,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=clusterevacuations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch;update;patch
+
+func (r *ClusterEvacuationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = logging.IntoContext(ctx, logging.Placement)
+	previous := &v1.ClusterEvacuation{}
+	if err := r.Client.Get(ctx, req.NamespacedName, previous); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	evacuation := previous.DeepCopy()
+	cluster := evacuation.Spec.Cluster
+	evacuation.Status.ObservedGeneration = evacuation.Generation
+
+	if evacuation.Namespace != r.Namespace {
+		conditions.Set(&evacuation.Status.Conditions, evacuation.Generation, v1.ClusterEvacuationEvacuatedConditionType, metav1.ConditionFalse,
+			conditions.ReasonInvalidNamespace, fmt.Sprintf("ClusterEvacuations must be created in namespace %s", r.Namespace))
+		return ctrl.Result{}, conditions.UpdateStatus(ctx, r.Client, evacuation, previous.Status, evacuation.Status)
+	}
+
+	records := &v1.DNSRecordList{}
+	if err := r.Client.List(ctx, records, client.InNamespace(r.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	pending := []string{}
+	retained := []string{}
+	for i := range records.Items {
+		record := &records.Items[i]
+		served := slice.ContainsString(dns.EndpointClusters(record), cluster)
+		if served && !dns.RemoveClusterEndpoints(record, cluster) {
+			// the host would stop resolving without the endpoints of the
+			// cluster, they're withdrawn once another cluster serves it
+			retained = append(retained, record.Name)
+			continue
+		}
+		if served {
+			log.FromContext(ctx).Info("Withdrawing endpoints of evacuated cluster", "cluster", cluster, "record", record.Name, "namespace", record.Namespace)
+			if err := r.Client.Update(ctx, record); err != nil {
+				return ctrl.Result{}, err
+			}
+			if !slice.ContainsString(evacuation.Status.Records, record.Name) {
+				evacuation.Status.Records = append(evacuation.Status.Records, record.Name)
+			}
+			pending = append(pending, record.Name)
+			continue
+		}
+		if slice.ContainsString(evacuation.Status.Records, record.Name) && !published(record) {
+			pending = append(pending, record.Name)
+		}
+	}
+	sort.Strings(evacuation.Status.Records)
+	sort.Strings(pending)
+	sort.Strings(retained)
+	evacuation.Status.PendingRecords = pending
+	evacuation.Status.RetainedRecords = retained
+
+	switch {
+	case len(pending) > 0:
+		conditions.Set(&evacuation.Status.Conditions, evacuation.Generation, v1.ClusterEvacuationEvacuatedConditionType, metav1.ConditionFalse,
+			conditions.ReasonPending, fmt.Sprintf("The endpoints of cluster %s are withdrawn, %d of %d DNSRecords are not published yet", cluster, len(pending), len(evacuation.Status.Records)))
+	case len(retained) > 0:
+		conditions.Set(&evacuation.Status.Conditions, evacuation.Generation, v1.ClusterEvacuationEvacuatedConditionType, metav1.ConditionFalse,
+			conditions.ReasonLastCluster, fmt.Sprintf("The endpoints of cluster %s are kept on %d DNSRecords no other cluster serves", cluster, len(retained)))
+	default:
+		conditions.Set(&evacuation.Status.Conditions, evacuation.Generation, v1.ClusterEvacuationEvacuatedConditionType, metav1.ConditionTrue,
+			conditions.ReasonEvacuated, fmt.Sprintf("No DNS endpoint points at cluster %s", cluster))
+	}
+	if err := conditions.UpdateStatus(ctx, r.Client, evacuation, previous.Status, evacuation.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// published returns true when the current generation of the record is
+// published to all its zones
+func published(record *v1.DNSRecord) bool {
+	if record.Status.ObservedGeneration != record.Generation {
+		return false
+	}
+	for _, zone := range record.Status.Zones {
+		for _, condition := range zone.Conditions {
+			if condition.Type == v1.DNSRecordFailedConditionType && condition.Status != string(metav1.ConditionFalse) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterEvacuationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ClusterEvacuation{}).
+		Watches(&source.Kind{Type: &v1.DNSRecord{}}, handler.EnqueueRequestsFromMapFunc(r.recordToEvacuations)).
+		Complete(r)
+}
+
+// recordToEvacuations maps a DNSRecord to the evacuations, so endpoints
+// published again are withdrawn, endpoints retained are withdrawn once
+// another cluster serves the host, and the progress is updated as records
+// are published
+func (r *ClusterEvacuationReconciler) recordToEvacuations(o client.Object) []reconcile.Request {
+	if o.GetNamespace() != r.Namespace {
+		return nil
+	}
+	evacuations := &v1.ClusterEvacuationList{}
+	if err := r.Client.List(context.Background(), evacuations, client.InNamespace(r.Namespace)); err != nil {
+		log.Log.Error(err, "Failed to list evacuations for record", "record", o.GetName(), "namespace", o.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, evacuation := range evacuations.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&evacuation)})
+	}
+	return requests
+}
@@ -0,0 +1,117 @@
+package clusterevacuation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+func TestClusterEvacuationReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+
+	record := func(clusters ...string) *v1.DNSRecord {
+		record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"}}
+		for _, cluster := range clusters {
+			record.Spec.Endpoints = append(record.Spec.Endpoints, &v1.Endpoint{
+				DNSName:       "app.example.com",
+				Targets:       []string{cluster + ".lb.example.com"},
+				RecordType:    "CNAME",
+				SetIdentifier: cluster,
+				Labels:        map[string]string{"kuadrant.io/owner": cluster + "/team-a/ingress"},
+			})
+		}
+		return record
+	}
+	evacuation := func(namespace string, records ...string) *v1.ClusterEvacuation {
+		return &v1.ClusterEvacuation{
+			ObjectMeta: metav1.ObjectMeta{Name: "evacuation", Namespace: namespace},
+			Spec:       v1.ClusterEvacuationSpec{Cluster: "cluster-a"},
+			Status:     v1.ClusterEvacuationStatus{Records: records},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		objects        []client.Object
+		namespace      string
+		expectClusters []string
+		expectRetained []string
+		expectReason   string
+	}{
+		{
+			name:           "endpoints of the cluster are withdrawn",
+			objects:        []client.Object{evacuation("argocd"), record("cluster-a", "cluster-b")},
+			namespace:      "argocd",
+			expectClusters: []string{"cluster-b"},
+			expectReason:   conditions.ReasonPending,
+		},
+		{
+			name:           "endpoints of the only cluster serving the host are kept",
+			objects:        []client.Object{evacuation("argocd"), record("cluster-a")},
+			namespace:      "argocd",
+			expectClusters: []string{"cluster-a"},
+			expectRetained: []string{"app.example.com"},
+			expectReason:   conditions.ReasonLastCluster,
+		},
+		{
+			name:           "evacuation outside the controller namespace is rejected",
+			objects:        []client.Object{evacuation("team-a"), record("cluster-a", "cluster-b")},
+			namespace:      "team-a",
+			expectClusters: []string{"cluster-a", "cluster-b"},
+			expectReason:   conditions.ReasonInvalidNamespace,
+		},
+		{
+			name:           "evacuated once the records are published",
+			objects:        []client.Object{evacuation("argocd", "app.example.com"), record("cluster-b")},
+			namespace:      "argocd",
+			expectClusters: []string{"cluster-b"},
+			expectReason:   conditions.ReasonEvacuated,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build()
+			r := &ClusterEvacuationReconciler{Client: c, Scheme: scheme, Namespace: "argocd"}
+			key := client.ObjectKey{Namespace: tc.namespace, Name: "evacuation"}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			currentRecord := &v1.DNSRecord{}
+			if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "app.example.com"}, currentRecord); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if clusters := dns.EndpointClusters(currentRecord); !reflect.DeepEqual(clusters, tc.expectClusters) {
+				t.Errorf("expected '%v' got '%v'", tc.expectClusters, clusters)
+			}
+
+			current := &v1.ClusterEvacuation{}
+			if err := c.Get(ctx, key, current); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(current.Status.RetainedRecords, tc.expectRetained) {
+				t.Errorf("expected '%v' got '%v'", tc.expectRetained, current.Status.RetainedRecords)
+			}
+			condition := meta.FindStatusCondition(current.Status.Conditions, v1.ClusterEvacuationEvacuatedConditionType)
+			if condition == nil || condition.Reason != tc.expectReason {
+				t.Errorf("expected '%v' got '%v'", tc.expectReason, condition)
+			}
+		})
+	}
+}
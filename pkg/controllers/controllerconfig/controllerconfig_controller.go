@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllerconfig
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+)
+
+// ControllerConfigReconciler applies the configuration of the controller
+// from a ControllerConfig, so changes take effect without restarting the
+// controller. When the ControllerConfig doesn't exist, the configuration
+// from the controller flags is used
+type ControllerConfigReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config *config.Store
+	// Name is the ControllerConfig the configuration is read from
+	Name types.NamespacedName
+	// LogFilter is set to the log verbosity of the subsystems configured
+	LogFilter *logging.Filter
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=controllerconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=controllerconfigs/status,verbs=get;update;patch
+
+func (r *ControllerConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	previous := &v1.ControllerConfig{}
+	if err := r.Client.Get(ctx, req.NamespacedName, previous); err != nil {
+		if err := client.IgnoreNotFound(err); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.FromContext(ctx).Info("ControllerConfig not found, using the controller flags", "name", req.Name, "namespace", req.Namespace)
+		r.Config.Apply(nil)
+		r.applyLogLevels()
+		return ctrl.Result{}, nil
+	}
+	controllerConfig := previous.DeepCopy()
+
+	status, reason, message := metav1.ConditionTrue, conditions.ReasonConfigApplied, "The configuration is in use by the controller"
+	if unknown := unknownFeatureGates(controllerConfig.Spec.FeatureGates); len(unknown) > 0 {
+		// keep the configuration in use until the feature gates are fixed
+		status, reason = metav1.ConditionFalse, conditions.ReasonUnknownFeatureGate
+		message = fmt.Sprintf("Unknown feature gates %s, known feature gates are %s", strings.Join(unknown, ", "), features.Usage())
+	} else if invalid := invalidLogLevels(controllerConfig.Spec.LogLevels); len(invalid) > 0 {
+		status, reason = metav1.ConditionFalse, conditions.ReasonInvalidLogLevels
+		message = fmt.Sprintf("Invalid log levels %s, known subsystems are %s with a verbosity of 0 to %d", strings.Join(invalid, ", "), logging.Usage(), logging.MaxVerbosity)
+	} else if invalid := invalidDefaultZone(controllerConfig.Spec.DefaultZone); len(invalid) > 0 {
+		status, reason = metav1.ConditionFalse, conditions.ReasonInvalidDefaultZone
+		message = fmt.Sprintf("Invalid default zone: %s", strings.Join(invalid, ", "))
+	} else if invalid := invalidFleetCIDRs(controllerConfig.Spec.PrivateTraffic); len(invalid) > 0 {
+		status, reason = metav1.ConditionFalse, conditions.ReasonInvalidFleetCIDRs
+		message = fmt.Sprintf("Invalid fleet CIDRs %s", strings.Join(invalid, ", "))
+	} else if err := invalidHostGeneration(controllerConfig.Spec.HostGeneration); err != nil {
+		status, reason = metav1.ConditionFalse, conditions.ReasonInvalidHostGeneration
+		message = fmt.Sprintf("Invalid host generation: %s", err)
+	} else {
+		r.Config.Apply(&controllerConfig.Spec)
+		r.applyLogLevels()
+		log.FromContext(ctx).Info("Applied ControllerConfig", "name", controllerConfig.Name, "generation", controllerConfig.Generation)
+	}
+	conditions.Set(&controllerConfig.Status.Conditions, controllerConfig.Generation, v1.ControllerConfigAppliedConditionType, status, reason, message)
+	controllerConfig.Status.ObservedGeneration = controllerConfig.Generation
+	if err := conditions.UpdateStatus(ctx, r.Client, controllerConfig, previous.Status, controllerConfig.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// unknownFeatureGates returns the names of the gates that aren't known
+func unknownFeatureGates(gates map[string]bool) []string {
+	var unknown []string
+	for name := range gates {
+		if _, ok := features.Known[features.Feature(name)]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// invalidLogLevels returns the log levels of unknown subsystems or with an
+// invalid verbosity
+func invalidLogLevels(levels map[string]int) []string {
+	var invalid []string
+	for name, verbosity := range levels {
+		if !logging.IsKnown(name) || verbosity < 0 || verbosity > logging.MaxVerbosity {
+			invalid = append(invalid, fmt.Sprintf("%s=%d", name, verbosity))
+		}
+	}
+	sort.Strings(invalid)
+	return invalid
+}
+
+// invalidDefaultZone returns why the default zone is invalid: a zone
+// without an identifier, or a root domain that isn't a DNS subdomain
+func invalidDefaultZone(zone *v1.DefaultZone) []string {
+	if zone == nil {
+		return nil
+	}
+	var invalid []string
+	if strings.TrimSpace(zone.ID) == "" {
+		invalid = append(invalid, "id is empty")
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(zone.RootDomain) {
+		invalid = append(invalid, fmt.Sprintf("rootDomain %q %s", zone.RootDomain, msg))
+	}
+	return invalid
+}
+
+// invalidFleetCIDRs returns the fleet CIDRs of private traffic that aren't
+// CIDRs, validated as the controller flag is
+func invalidFleetCIDRs(private *v1.PrivateTrafficOptions) []string {
+	if private == nil {
+		return nil
+	}
+	var invalid []string
+	for _, cidr := range private.FleetCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			invalid = append(invalid, cidr)
+		}
+	}
+	return invalid
+}
+
+// invalidHostGeneration returns why the host generation is invalid,
+// validated as the controller flags are
+func invalidHostGeneration(generation *v1.HostGeneration) error {
+	if generation == nil {
+		return nil
+	}
+	return dns.ValidateHostGeneration(*generation)
+}
+
+func (r *ControllerConfigReconciler) applyLogLevels() {
+	if r.LogFilter != nil {
+		r.LogFilter.Set(r.Config.Get().LogLevels)
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ControllerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ControllerConfig{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return o.GetNamespace() == r.Name.Namespace && o.GetName() == r.Name.Name
+		}))).
+		Complete(r)
+}
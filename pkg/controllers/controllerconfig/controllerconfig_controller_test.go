@@ -0,0 +1,108 @@
+package controllerconfig
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func Test_invalidDefaultZone(t *testing.T) {
+	tests := []struct {
+		name          string
+		zone          *v1.DefaultZone
+		expectInvalid bool
+	}{
+		{
+			name: "no default zone",
+		},
+		{
+			name: "valid default zone",
+			zone: &v1.DefaultZone{ID: "Z123", RootDomain: "apps.example.com"},
+		},
+		{
+			name:          "zone without identifier",
+			zone:          &v1.DefaultZone{ID: " ", RootDomain: "apps.example.com"},
+			expectInvalid: true,
+		},
+		{
+			name:          "invalid root domain",
+			zone:          &v1.DefaultZone{ID: "Z123", RootDomain: "Apps_example.com."},
+			expectInvalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := invalidDefaultZone(tt.zone)
+			if (len(got) > 0) != tt.expectInvalid {
+				t.Errorf("expected '%v' got '%v'", tt.expectInvalid, got)
+			}
+		})
+	}
+}
+
+func Test_invalidFleetCIDRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		private  *v1.PrivateTrafficOptions
+		expected []string
+	}{
+		{
+			name: "no private traffic",
+		},
+		{
+			name:    "valid CIDRs",
+			private: &v1.PrivateTrafficOptions{FleetCIDRs: []string{"10.244.0.0/16", "fd00::/8"}},
+		},
+		{
+			name:     "address without prefix length",
+			private:  &v1.PrivateTrafficOptions{FleetCIDRs: []string{"10.244.0.0/16", "10.245.0.1"}},
+			expected: []string{"10.245.0.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := invalidFleetCIDRs(tt.private)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected '%v' got '%v'", tt.expected, got)
+			}
+		})
+	}
+}
+
+func Test_invalidHostGeneration(t *testing.T) {
+	tests := []struct {
+		name          string
+		generation    *v1.HostGeneration
+		expectInvalid bool
+	}{
+		{
+			name: "no host generation",
+		},
+		{
+			name:       "valid template",
+			generation: &v1.HostGeneration{Strategy: v1.HostGenerationTemplate, Template: "{name}-{ns}"},
+		},
+		{
+			name:          "template without namespace",
+			generation:    &v1.HostGeneration{Strategy: v1.HostGenerationTemplate, Template: "{name}"},
+			expectInvalid: true,
+		},
+		{
+			name:          "unknown strategy",
+			generation:    &v1.HostGeneration{Strategy: "random"},
+			expectInvalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := invalidHostGeneration(tt.generation)
+			if (err != nil) != tt.expectInvalid {
+				t.Errorf("expected '%v' got '%v'", tt.expectInvalid, err)
+			}
+		})
+	}
+}
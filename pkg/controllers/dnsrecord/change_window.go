@@ -0,0 +1,71 @@
+package dnsrecord
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+// changesHeld returns the reason the changes to the record can't be written
+// to the provider now, by the change freeze or the change windows of its
+// zone, with the end of a message saying until when they are held and how
+// long until the next change window opens. The reason is empty when the
+// changes can be written, and the duration zero while the zone is frozen
+func (r *DNSRecordReconciler) changesHeld(ctx context.Context, record *v1.DNSRecord) (string, string, time.Duration, error) {
+	if record.Spec.ManagedZoneRef == nil {
+		return "", "", 0, nil
+	}
+	zone := &v1.ManagedZone{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: record.Namespace, Name: record.Spec.ManagedZoneRef.Name}, zone); err != nil {
+		return "", "", 0, client.IgnoreNotFound(err)
+	}
+	now := clock.Now()
+	allowed, opens := dns.ChangesAllowed(zone, now)
+	switch {
+	case allowed:
+		return "", "", 0, nil
+	case opens.IsZero():
+		return conditions.ReasonChangeFreeze, fmt.Sprintf("while the changes to zone %s are frozen", zone.Name), 0, nil
+	default:
+		return conditions.ReasonOutsideChangeWindow, fmt.Sprintf("until the next change window of zone %s opens at %s", zone.Name, opens.Format(time.RFC3339)), opens.Sub(now), nil
+	}
+}
+
+// queueChanges reports the changes to the record as queued, and requeues
+// the record for when the next change window of its zone opens
+func (r *DNSRecordReconciler) queueChanges(ctx context.Context, previous, record *v1.DNSRecord, reason, message string, opens time.Duration) (ctrl.Result, error) {
+	log.FromContext(ctx).Info("Queuing changes to DNSRecord", "record", record.Name, "namespace", record.Namespace, "reason", reason)
+	conditions.Set(&record.Status.Conditions, record.Generation, v1.DNSRecordChangesQueuedConditionType, metav1.ConditionTrue, reason, message)
+	if err := conditions.UpdateStatus(ctx, r.Client, record, previous.Status, record.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: opens}, nil
+}
+
+// zoneToRecords maps a ManagedZone to the records published to it, so the
+// changes queued are written once its change freeze is lifted or its
+// change windows change
+func (r *DNSRecordReconciler) zoneToRecords(o client.Object) []reconcile.Request {
+	records := &v1.DNSRecordList{}
+	if err := r.Client.List(context.Background(), records, client.InNamespace(o.GetNamespace())); err != nil {
+		log.Log.Error(err, "Failed to list records for zone", "zone", o.GetName(), "namespace", o.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, record := range records.Items {
+		if record.Spec.ManagedZoneRef != nil && record.Spec.ManagedZoneRef.Name == o.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&record)})
+		}
+	}
+	return requests
+}
@@ -22,34 +22,55 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 	utilclock "k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
 	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
 )
 
-type ConditionStatus string
-
 const (
 	DNSRecordFinalizer = "kuadrant.io/dns-record"
-
-	ConditionTrue    ConditionStatus = "True"
-	ConditionFalse   ConditionStatus = "False"
-	ConditionUnknown ConditionStatus = "Unknown"
 )
 
 type DNSRecordReconcilerConfig struct {
 	DNSProvider string
+	// MaxConcurrentReconciles is the number of records reconciled at a time
+	// across all zones
+	MaxConcurrentReconciles int
+	// ZoneConcurrency is the number of records changed at a time in each
+	// zone. Zero means no limit
+	ZoneConcurrency int
+	// ZoneQPS and ZoneBurst limit the rate of changes in each zone. A zero
+	// ZoneQPS means no limit
+	ZoneQPS   float64
+	ZoneBurst int
 }
 
 // DNSRecordReconciler reconciles a DNSRecord object
@@ -58,20 +79,36 @@ type DNSRecordReconciler struct {
 	Scheme           *runtime.Scheme
 	ReconcilerConfig DNSRecordReconcilerConfig
 	DNSProvider      dns.Provider
-	DNSZones         []v1.DNSZone
+	// Config holds the default zone records are published to, and how often
+	// published records are compared against the records in the provider,
+	// and repaired when they were changed out of band
+	Config *config.Store
+	// ZoneProviders resolves the provider of records published to a
+	// ManagedZone. When nil, records referencing a ManagedZone are
+	// published with DNSProvider
+	ZoneProviders *dns.ZoneProviders
+	Recorder      record.EventRecorder
+
+	zoneLimiter *zoneLimiter
+	// lastVerified holds when each record was last verified
+	lastVerified sync.Map
 }
 
 //+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords/finalizers,verbs=update
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedzones,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
 
 func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	ctx = logging.IntoContext(ctx, logging.DNS)
 
 	previous := &v1.DNSRecord{}
 	err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, previous)
 	if err != nil {
 		if err := client.IgnoreNotFound(err); err == nil {
+			r.lastVerified.Delete(req.NamespacedName)
 			return ctrl.Result{}, nil
 		} else {
 			return ctrl.Result{}, err
@@ -79,9 +116,70 @@ func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 	dnsRecord := previous.DeepCopy()
 
-	if dnsRecord.DeletionTimestamp != nil && !dnsRecord.DeletionTimestamp.IsZero() {
-		if err := r.deleteRecord(dnsRecord); err != nil && !strings.Contains(err.Error(), "was not found") {
-			log.Log.Error(err, "Failed to delete DNSRecord", "record", dnsRecord)
+	// deletion isn't paused, so paused records can still be deleted
+	deleting := dnsRecord.DeletionTimestamp != nil && !dnsRecord.DeletionTimestamp.IsZero()
+	if metadata.IsPaused(dnsRecord) && !deleting {
+		log.FromContext(ctx).Info("Reconciliation of DNSRecord is paused", "record", dnsRecord.Name, "namespace", dnsRecord.Namespace)
+		return ctrl.Result{}, r.setPaused(ctx, previous, dnsRecord, true)
+	}
+	if err := r.setPaused(ctx, previous, dnsRecord, false); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if hub := metadata.HandedOverTo(dnsRecord); hub != "" {
+		return ctrl.Result{}, r.reconcileHandedOver(ctx, previous, dnsRecord, hub)
+	}
+	conditions.Remove(&dnsRecord.Status.Conditions, v1.DNSRecordHandedOverConditionType)
+
+	if deleting && metadata.IsForceDelete(dnsRecord) && controllerutil.ContainsFinalizer(dnsRecord, DNSRecordFinalizer) {
+		log.FromContext(ctx).Info("Forcing deletion of DNSRecord, keeping its records in the provider", "record", dnsRecord.Name, "namespace", dnsRecord.Namespace)
+		controllerutil.RemoveFinalizer(dnsRecord, DNSRecordFinalizer)
+		return ctrl.Result{}, r.Update(ctx, dnsRecord)
+	}
+
+	zones, provider, err := r.zonesAndProvider(ctx, dnsRecord)
+	if k8serrors.IsNotFound(err) && deleting {
+		// the records can't be deleted from the provider without the zone
+		// or its credentials, the finalizer is kept until they're back or
+		// the deletion is forced
+		log.FromContext(ctx).Info("Deletion of DNSRecord blocked, its zone or credentials were not found", "record", dnsRecord.Name, "namespace", dnsRecord.Namespace, "error", err.Error())
+		conditions.Set(&dnsRecord.Status.Conditions, dnsRecord.Generation, v1.DNSRecordDeletionFailedConditionType, metav1.ConditionTrue,
+			conditions.ReasonNotFound, fmt.Sprintf("The record can't be deleted from the DNS provider: %v. Restore it, or set the %s annotation to \"true\" to delete the record anyway", err, metadata.AnnotationForceDelete))
+		return ctrl.Result{}, conditions.UpdateStatus(ctx, r.Client, dnsRecord, previous.Status, dnsRecord.Status)
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to get DNS provider for DNSRecord", "record", dnsRecord.Name, "namespace", dnsRecord.Namespace)
+		return ctrl.Result{}, err
+	}
+
+	heldReason, heldUntil, opens, err := r.changesHeld(ctx, dnsRecord)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if deleting {
+		if heldReason != "" {
+			return r.queueChanges(ctx, previous, dnsRecord, heldReason, "The deletion of the record is queued "+heldUntil, opens)
+		}
+		release, retryAfter := r.zoneLimiter.acquire(publishedZones(dnsRecord))
+		if release == nil {
+			return ctrl.Result{RequeueAfter: retryAfter}, nil
+		}
+		defer release()
+
+		if err := r.deleteRecord(ctx, dnsRecord, provider); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to delete DNSRecord", "record", dnsRecord)
+			// keep the finalizer and retry with backoff, reporting the
+			// deletion as stuck until the provider accepts it
+			r.recordEvent(dnsRecord, corev1.EventTypeWarning, "DeletionFailed", fmt.Sprintf("The DNS provider failed to delete the record: %v", err))
+			conditions.Set(&dnsRecord.Status.Conditions, dnsRecord.Generation, v1.DNSRecordDeletionFailedConditionType, metav1.ConditionTrue,
+				conditions.ReasonProviderError, fmt.Sprintf("The DNS provider failed to delete the record: %v", err))
+			if statusErr := conditions.UpdateStatus(ctx, r.Client, dnsRecord, previous.Status, dnsRecord.Status); statusErr != nil {
+				log.FromContext(ctx).Error(statusErr, "Failed to update DNSRecord status", "record", dnsRecord.Name, "namespace", dnsRecord.Namespace)
+			}
+			return ctrl.Result{}, err
+		}
+		if err := dns.RecordHostRelease(ctx, r.Client, dnsRecord, r.Config.Get().HostReuseCooldown); err != nil {
 			return ctrl.Result{}, err
 		}
 		controllerutil.RemoveFinalizer(dnsRecord, DNSRecordFinalizer)
@@ -101,36 +199,58 @@ func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
-	statuses := r.publishRecordToZones(r.DNSZones, dnsRecord)
+	if usesGeolocation(dnsRecord) && !r.Config.Get().Enabled(features.GeoDNS) {
+		log.FromContext(ctx).Info("Not publishing DNSRecord with geolocation routing, the feature is disabled", "record", dnsRecord.Name, "namespace", dnsRecord.Namespace, "feature", features.GeoDNS)
+		r.recordEvent(dnsRecord, corev1.EventTypeWarning, "FeatureDisabled", fmt.Sprintf("Geolocation routing requires the %s feature gate", features.GeoDNS))
+		return ctrl.Result{}, nil
+	}
+	if usesLatency(dnsRecord) && !r.Config.Get().Enabled(features.LatencyDNS) {
+		log.FromContext(ctx).Info("Not publishing DNSRecord with latency routing, the feature is disabled", "record", dnsRecord.Name, "namespace", dnsRecord.Namespace, "feature", features.LatencyDNS)
+		r.recordEvent(dnsRecord, corev1.EventTypeWarning, "FeatureDisabled", fmt.Sprintf("Latency routing requires the %s feature gate", features.LatencyDNS))
+		return ctrl.Result{}, nil
+	}
+
+	publishZones := zonesToPublish(zones, dnsRecord)
+	if heldReason != "" {
+		if len(publishZones) > 0 {
+			return r.queueChanges(ctx, previous, dnsRecord, heldReason, fmt.Sprintf("The changes of generation %d of the record are queued %s", dnsRecord.Generation, heldUntil), opens)
+		}
+		// nothing to publish, and records changed out of band aren't
+		// repaired until the changes can be written
+		conditions.Remove(&dnsRecord.Status.Conditions, v1.DNSRecordChangesQueuedConditionType)
+		return ctrl.Result{RequeueAfter: opens}, conditions.UpdateStatus(ctx, r.Client, dnsRecord, previous.Status, dnsRecord.Status)
+	}
+	conditions.Remove(&dnsRecord.Status.Conditions, v1.DNSRecordChangesQueuedConditionType)
+
+	verifyZones, verifyAfter := r.zonesToVerify(req, zones, publishZones, dnsRecord)
+	stale := staleZones(zones, dnsRecord)
+	release, retryAfter := r.zoneLimiter.acquire(append(append(publishZones, verifyZones...), stale...))
+	if release == nil {
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+	defer release()
+
+	r.verifyRecord(ctx, req, verifyZones, dnsRecord, provider)
+
+	statuses := r.publishRecordToZones(ctx, zones, dnsRecord, provider)
 	if !dnsZoneStatusSlicesEqual(statuses, dnsRecord.Status.Zones) || dnsRecord.Status.ObservedGeneration != dnsRecord.Generation {
 		dnsRecord.Status.Zones = statuses
 		dnsRecord.Status.ObservedGeneration = dnsRecord.Generation
 	}
+	r.removeFromStaleZones(ctx, zones, stale, dnsRecord, provider)
+	setConsistent(dnsRecord, zones)
+	setRawRecordsRejected(dnsRecord)
 
 	err = r.Status().Update(ctx, dnsRecord)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: verifyAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DNSRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
-
-	var dnsZones []v1.DNSZone
-	zoneID, zoneIDSet := os.LookupEnv("AWS_DNS_PUBLIC_ZONE_ID")
-	if zoneIDSet {
-		dnsZone := &v1.DNSZone{
-			ID: zoneID,
-		}
-		dnsZones = append(dnsZones, *dnsZone)
-		log.Log.Info("Using AWS DNS zone", "id", zoneID)
-	} else {
-		log.Log.Info("No AWS DNS zone id set (AWS_DNS_PUBLIC_ZONE_ID), no DNS records will be created!")
-	}
-	r.DNSZones = dnsZones
-
 	//Logging state of AWS credentials
 	awsIdKey := os.Getenv("AWS_ACCESS_KEY_ID")
 	if awsIdKey != "" {
@@ -146,12 +266,218 @@ func (r *DNSRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		log.Log.Info("AWS Secret Key is NOT set")
 	}
 
+	r.zoneLimiter = newZoneLimiter(r.ReconcilerConfig.ZoneConcurrency, r.ReconcilerConfig.ZoneQPS, r.ReconcilerConfig.ZoneBurst)
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1.DNSRecord{}).
+		Watches(&source.Kind{Type: &v1.ManagedZone{}}, handler.EnqueueRequestsFromMapFunc(r.zoneToRecords),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &v1.ControllerConfig{}}, handler.EnqueueRequestsFromMapFunc(r.configToRecords)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.ReconcilerConfig.MaxConcurrentReconciles}).
 		Complete(r)
 }
 
-func (r *DNSRecordReconciler) publishRecordToZones(zones []v1.DNSZone, record *v1.DNSRecord) []v1.DNSZoneStatus {
+// setPaused updates the Paused condition of the record
+func (r *DNSRecordReconciler) setPaused(ctx context.Context, previous, record *v1.DNSRecord, paused bool) error {
+	if paused {
+		conditions.Set(&record.Status.Conditions, record.Generation, v1.DNSRecordPausedConditionType, metav1.ConditionTrue,
+			conditions.ReasonPausedByAnnotation, fmt.Sprintf("Reconciliation is paused by the %s annotation", metadata.AnnotationPaused))
+	} else {
+		conditions.Remove(&record.Status.Conditions, v1.DNSRecordPausedConditionType)
+	}
+	return conditions.UpdateStatus(ctx, r.Client, record, previous.Status.Conditions, record.Status.Conditions)
+}
+
+// reconcileHandedOver stops writing the records of a record handed over to
+// another control plane, which adopts them. On deletion the record is
+// released without deleting its records, which keep being served
+func (r *DNSRecordReconciler) reconcileHandedOver(ctx context.Context, previous, record *v1.DNSRecord, hub string) error {
+	if record.DeletionTimestamp != nil && !record.DeletionTimestamp.IsZero() {
+		log.FromContext(ctx).Info("Releasing DNSRecord handed over, keeping its records", "record", record.Name, "namespace", record.Namespace, "handedOverTo", hub)
+		controllerutil.RemoveFinalizer(record, DNSRecordFinalizer)
+		return r.Update(ctx, record)
+	}
+	conditions.Set(&record.Status.Conditions, record.Generation, v1.DNSRecordHandedOverConditionType, metav1.ConditionTrue,
+		conditions.ReasonHandedOverByAnnotation, fmt.Sprintf("The record is handed over to %s by the %s annotation", hub, metadata.AnnotationHandedOverTo))
+	return conditions.UpdateStatus(ctx, r.Client, record, previous.Status.Conditions, record.Status.Conditions)
+}
+
+// defaultZones returns the zones records not referencing a ManagedZone are
+// published to
+func (r *DNSRecordReconciler) defaultZones() []v1.DNSZone {
+	if zoneID := r.Config.Get().DefaultZoneID; zoneID != "" {
+		return []v1.DNSZone{{ID: zoneID}}
+	}
+	return nil
+}
+
+// configToRecords maps a ControllerConfig to the records published to the
+// default zone, so they're migrated when the default zone changes. Its
+// status changes are mapped too, as the configuration is only in use once
+// it's applied
+func (r *DNSRecordReconciler) configToRecords(o client.Object) []reconcile.Request {
+	records := &v1.DNSRecordList{}
+	if err := r.Client.List(context.Background(), records); err != nil {
+		log.Log.Error(err, "Failed to list records for controller config", "config", o.GetName(), "namespace", o.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, record := range records.Items {
+		if record.Spec.ManagedZoneRef == nil {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&record)})
+		}
+	}
+	return requests
+}
+
+// zonesAndProvider returns the zones the record is published to, including
+// the secondary zones of its ManagedZone, and the provider managing them.
+// Records without a ManagedZone are published to the default zone, with the
+// default provider, only in the controller namespace
+func (r *DNSRecordReconciler) zonesAndProvider(ctx context.Context, record *v1.DNSRecord) ([]v1.DNSZone, dns.Provider, error) {
+	if record.Spec.ManagedZoneRef == nil {
+		if r.ZoneProviders != nil && !r.ZoneProviders.DefaultCredentialsAllowed(record.Namespace) {
+			return nil, nil, dns.DefaultCredentialsNotAllowedErr
+		}
+		return r.defaultZones(), r.DNSProvider, nil
+	}
+
+	managedZone := &v1.ManagedZone{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: record.Namespace, Name: record.Spec.ManagedZoneRef.Name}, managedZone); err != nil {
+		return nil, nil, err
+	}
+	if r.ZoneProviders == nil {
+		return []v1.DNSZone{managedZone.DNSZone()}, r.DNSProvider, nil
+	}
+	return r.ZoneProviders.ProvidersFor(ctx, managedZone)
+}
+
+// zonesToPublish returns the zones the record needs to be published to,
+// see publishRecordToZones
+func zonesToPublish(zones []v1.DNSZone, record *v1.DNSRecord) []v1.DNSZone {
+	var result []v1.DNSZone
+	for i := range zones {
+		if record.Generation == record.Status.ObservedGeneration && recordIsAlreadyPublishedToZone(record, &zones[i]) {
+			continue
+		}
+		result = append(result, zones[i])
+	}
+	return result
+}
+
+// zonesToVerify returns the published zones that are due to be verified, and
+// how long until the record should be verified again
+func (r *DNSRecordReconciler) zonesToVerify(req ctrl.Request, zones, publishZones []v1.DNSZone, record *v1.DNSRecord) ([]v1.DNSZone, time.Duration) {
+	interval := r.Config.Get().DNSVerifyInterval
+	if interval <= 0 {
+		return nil, 0
+	}
+	if last, ok := r.lastVerified.Load(req.NamespacedName); ok {
+		if elapsed := clock.Since(last.(time.Time)); elapsed < interval {
+			return nil, interval - elapsed
+		}
+	}
+
+	var result []v1.DNSZone
+	for i := range zones {
+		zone := zones[i]
+		if !recordIsAlreadyPublishedToZone(record, &zone) {
+			continue
+		}
+		publishing := false
+		for _, z := range publishZones {
+			if reflect.DeepEqual(z, zone) {
+				publishing = true
+			}
+		}
+		if !publishing {
+			result = append(result, zone)
+		}
+	}
+	return result, interval
+}
+
+// verifyRecord compares the record published in each of the zones against
+// the provider, and publishes it again when it drifted
+func (r *DNSRecordReconciler) verifyRecord(ctx context.Context, req ctrl.Request, zones []v1.DNSZone, record *v1.DNSRecord, provider dns.Provider) {
+	verifier, ok := provider.(dns.Verifier)
+	if !ok || len(zones) == 0 {
+		return
+	}
+
+	verified := true
+	for _, zone := range zones {
+		drifted, err := verifier.Verify(ctx, record, zone)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed to verify DNS record in zone", "record", record.Name, "zone", zone)
+			verified = false
+			continue
+		}
+		if len(drifted) == 0 {
+			continue
+		}
+
+		log.FromContext(ctx).Info("DNS record drifted in zone, repairing", "record", record.Name, "zone", zone, "endpoints", len(drifted))
+		if err := provider.Ensure(ctx, record, zone); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to repair DNS record in zone", "record", record.Name, "zone", zone)
+			r.recordEvent(record, corev1.EventTypeWarning, "DriftRepairFailed", fmt.Sprintf("Failed to repair %d endpoints changed out of band in zone %s: %v", len(drifted), zone.ID, err))
+			verified = false
+			continue
+		}
+		r.recordEvent(record, corev1.EventTypeNormal, "DriftCorrected", fmt.Sprintf("Repaired %d endpoints changed out of band in zone %s", len(drifted), zone.ID))
+	}
+	if verified {
+		r.lastVerified.Store(req.NamespacedName, clock.Now())
+	}
+}
+
+func (r *DNSRecordReconciler) recordEvent(record *v1.DNSRecord, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(record, eventType, reason, message)
+}
+
+// usesGeolocation returns true when any endpoint of the record is routed by
+// geolocation
+func usesGeolocation(record *v1.DNSRecord) bool {
+	for _, endpoint := range record.Spec.Endpoints {
+		if endpoint.GeoLocation != nil {
+			return true
+		}
+		for _, property := range endpoint.ProviderSpecific {
+			switch property.Name {
+			case aws.ProviderSpecificGeolocationContinentCode, aws.ProviderSpecificGeolocationCountryCode, aws.ProviderSpecificGeolocationSubdivisionCode:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesLatency returns true when any endpoint of the record is routed by
+// latency
+func usesLatency(record *v1.DNSRecord) bool {
+	for _, endpoint := range record.Spec.Endpoints {
+		if _, ok := endpoint.GetProviderSpecificProperty(aws.ProviderSpecificRegion); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// publishedZones returns the zones the record is currently published to
+func publishedZones(record *v1.DNSRecord) []v1.DNSZone {
+	var result []v1.DNSZone
+	for i := range record.Status.Zones {
+		if recordIsAlreadyPublishedToZone(record, &record.Status.Zones[i].DNSZone) {
+			result = append(result, record.Status.Zones[i].DNSZone)
+		}
+	}
+	return result
+}
+
+func (r *DNSRecordReconciler) publishRecordToZones(ctx context.Context, zones []v1.DNSZone, record *v1.DNSRecord, provider dns.Provider) []v1.DNSZoneStatus {
 	var statuses []v1.DNSZoneStatus
 	for i := range zones {
 		zone := zones[i]
@@ -160,53 +486,44 @@ func (r *DNSRecordReconciler) publishRecordToZones(zones []v1.DNSZone, record *v
 		// (which would mean the target could have changed) or its
 		// status does not indicate that it has already been published.
 		if record.Generation == record.Status.ObservedGeneration && recordIsAlreadyPublishedToZone(record, &zone) {
-			log.Log.Info("Skipping zone to which the DNS record is already published", "record", record, "zone", zone)
+			log.FromContext(ctx).Info("Skipping zone to which the DNS record is already published", "record", record, "zone", zone)
 			continue
 		}
 
-		condition := v1.DNSZoneCondition{
-			Status:             string(ConditionUnknown),
-			Type:               v1.DNSRecordFailedConditionType,
-			LastTransitionTime: metav1.Now(),
-		}
-
+		var condition v1.DNSZoneCondition
 		if recordIsAlreadyPublishedToZone(record, &zone) {
-			log.Log.Info("replacing DNS record", "record", record, "zone", zone)
+			log.FromContext(ctx).Info("replacing DNS record", "record", record, "zone", zone)
 
-			if err := r.DNSProvider.Ensure(record, zone); err != nil {
-				log.Log.Error(err, "Failed to replace DNS record in zone", "record", record.Spec, "zone", zone)
-				condition.Status = string(ConditionTrue)
-				condition.Reason = "ProviderError"
-				condition.Message = fmt.Sprintf("The DNS provider failed to replace the record: %v", err)
+			if err := provider.Ensure(ctx, record, zone); err != nil {
+				log.FromContext(ctx).Error(err, "Failed to replace DNS record in zone", "record", record.Spec, "zone", zone)
+				condition = conditions.NewZoneCondition(v1.DNSRecordFailedConditionType, metav1.ConditionTrue,
+					conditions.ReasonProviderError, fmt.Sprintf("The DNS provider failed to replace the record: %v", err))
 			} else {
-				log.Log.Info("Replaced DNS record in zone", "record", record.Spec, "zone", zone)
-				condition.Status = string(ConditionFalse)
-				condition.Reason = "ProviderSuccess"
-				condition.Message = "The DNS provider succeeded in replacing the record"
+				log.FromContext(ctx).Info("Replaced DNS record in zone", "record", record.Spec, "zone", zone)
+				condition = conditions.NewZoneCondition(v1.DNSRecordFailedConditionType, metav1.ConditionFalse,
+					conditions.ReasonProviderSuccess, "The DNS provider succeeded in replacing the record")
 			}
 		} else {
-			if err := r.DNSProvider.Ensure(record, zone); err != nil {
-				log.Log.Error(err, "Failed to publish DNS record to zone", "record", record.Spec, "zone", zone)
-				condition.Status = string(ConditionTrue)
-				condition.Reason = "ProviderError"
-				condition.Message = fmt.Sprintf("The DNS provider failed to ensure the record: %v", err)
+			if err := provider.Ensure(ctx, record, zone); err != nil {
+				log.FromContext(ctx).Error(err, "Failed to publish DNS record to zone", "record", record.Spec, "zone", zone)
+				condition = conditions.NewZoneCondition(v1.DNSRecordFailedConditionType, metav1.ConditionTrue,
+					conditions.ReasonProviderError, fmt.Sprintf("The DNS provider failed to ensure the record: %v", err))
 			} else {
-				log.Log.Info("Published DNS record to zone", "record", record.Spec, "zone", zone)
-				condition.Status = string(ConditionFalse)
-				condition.Reason = "ProviderSuccess"
-				condition.Message = "The DNS provider succeeded in ensuring the record"
+				log.FromContext(ctx).Info("Published DNS record to zone", "record", record.Spec, "zone", zone)
+				condition = conditions.NewZoneCondition(v1.DNSRecordFailedConditionType, metav1.ConditionFalse,
+					conditions.ReasonProviderSuccess, "The DNS provider succeeded in ensuring the record")
 			}
 		}
 		statuses = append(statuses, v1.DNSZoneStatus{
 			DNSZone:    zone,
 			Conditions: []v1.DNSZoneCondition{condition},
-			Endpoints:  record.Spec.Endpoints,
+			Endpoints:  record.PublishedEndpoints(),
 		})
 	}
 	return mergeStatuses(zones, record.Status.DeepCopy().Zones, statuses)
 }
 
-func (r *DNSRecordReconciler) deleteRecord(record *v1.DNSRecord) error {
+func (r *DNSRecordReconciler) deleteRecord(ctx context.Context, record *v1.DNSRecord, provider dns.Provider) error {
 	var errs []error
 	for i := range record.Status.Zones {
 		zone := record.Status.Zones[i].DNSZone
@@ -215,11 +532,11 @@ func (r *DNSRecordReconciler) deleteRecord(record *v1.DNSRecord) error {
 		if !recordIsAlreadyPublishedToZone(record, &zone) {
 			continue
 		}
-		err := r.DNSProvider.Delete(record, zone)
+		err := provider.Delete(ctx, record, zone)
 		if err != nil {
 			errs = append(errs, err)
 		} else {
-			log.Log.Info("Deleted DNSRecord from DNS provider", "record", record.Spec, "zone", zone)
+			log.FromContext(ctx).Info("Deleted DNSRecord from DNS provider", "record", record.Spec, "zone", zone)
 		}
 	}
 	if len(errs) == 0 {
@@ -228,6 +545,95 @@ func (r *DNSRecordReconciler) deleteRecord(record *v1.DNSRecord) error {
 	return utilerrors.NewAggregate(errs)
 }
 
+// staleZones returns the zones the record is published to that it no longer
+// belongs to, e.g. the previous default zone once the default zone changed
+func staleZones(zones []v1.DNSZone, record *v1.DNSRecord) []v1.DNSZone {
+	var stale []v1.DNSZone
+	for _, zone := range publishedZones(record) {
+		current := false
+		for i := range zones {
+			if reflect.DeepEqual(zones[i], zone) {
+				current = true
+				break
+			}
+		}
+		if !current {
+			stale = append(stale, zone)
+		}
+	}
+	return stale
+}
+
+// removeFromStaleZones migrates the record out of the stale zones once it's
+// published to each of its zones, so the host keeps resolving while it
+// moves. The records are kept in the stale zones while the record has no
+// zone, and the status of a stale zone is kept while the provider fails to
+// delete the record from it, so its deletion is retried
+func (r *DNSRecordReconciler) removeFromStaleZones(ctx context.Context, zones, stale []v1.DNSZone, record *v1.DNSRecord, provider dns.Provider) {
+	if len(stale) == 0 || len(zones) == 0 {
+		return
+	}
+	for i := range zones {
+		if !recordIsAlreadyPublishedToZone(record, &zones[i]) {
+			return
+		}
+	}
+	removed := map[int]bool{}
+	for _, zone := range stale {
+		if err := provider.Delete(ctx, record, zone); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to delete DNS record from the zone it no longer belongs to", "record", record.Name, "zone", zone)
+			continue
+		}
+		log.FromContext(ctx).Info("Deleted DNS record from the zone it no longer belongs to", "record", record.Name, "zone", zone)
+		for i := range record.Status.Zones {
+			if reflect.DeepEqual(record.Status.Zones[i].DNSZone, zone) {
+				removed[i] = true
+			}
+		}
+	}
+	statuses := []v1.DNSZoneStatus{}
+	for i := range record.Status.Zones {
+		if !removed[i] {
+			statuses = append(statuses, record.Status.Zones[i])
+		}
+	}
+	record.Status.Zones = statuses
+}
+
+// setConsistent updates the Consistent condition of records published to
+// more than one zone, with the zones the record isn't published to
+func setConsistent(record *v1.DNSRecord, zones []v1.DNSZone) {
+	if len(zones) < 2 {
+		conditions.Remove(&record.Status.Conditions, v1.DNSRecordConsistentConditionType)
+		return
+	}
+	var unpublished []string
+	for i := range zones {
+		if !recordIsAlreadyPublishedToZone(record, &zones[i]) {
+			unpublished = append(unpublished, zones[i].ID)
+		}
+	}
+	if len(unpublished) > 0 {
+		conditions.Set(&record.Status.Conditions, record.Generation, v1.DNSRecordConsistentConditionType, metav1.ConditionFalse,
+			conditions.ReasonProviderError, fmt.Sprintf("The record is not published to zones %s", strings.Join(unpublished, ", ")))
+		return
+	}
+	conditions.Set(&record.Status.Conditions, record.Generation, v1.DNSRecordConsistentConditionType, metav1.ConditionTrue,
+		conditions.ReasonProviderSuccess, fmt.Sprintf("The record is published to each of its %d zones", len(zones)))
+}
+
+// setRawRecordsRejected updates the RawRecordsRejected condition with the
+// raw records left unpublished for being outside of the host of the record
+func setRawRecordsRejected(record *v1.DNSRecord) {
+	rejected := record.RejectedRawRecords()
+	if len(rejected) == 0 {
+		conditions.Remove(&record.Status.Conditions, v1.DNSRecordRawRecordsRejectedConditionType)
+		return
+	}
+	conditions.Set(&record.Status.Conditions, record.Generation, v1.DNSRecordRawRecordsRejectedConditionType, metav1.ConditionTrue,
+		conditions.ReasonOutsideHost, fmt.Sprintf("The raw records for %s are outside of host %s and not published", strings.Join(rejected, ", "), record.Name))
+}
+
 // recordIsAlreadyPublishedToZone returns a Boolean value indicating whether the
 // given DNSRecord is already published to the given zone, as determined from
 // the DNSRecord's status conditions.
@@ -239,7 +645,7 @@ func recordIsAlreadyPublishedToZone(record *v1.DNSRecord, zoneToPublish *v1.DNSZ
 
 		for _, condition := range zoneInStatus.Conditions {
 			if condition.Type == v1.DNSRecordFailedConditionType {
-				return condition.Status == string(ConditionFalse)
+				return condition.Status == string(metav1.ConditionFalse)
 			}
 		}
 	}
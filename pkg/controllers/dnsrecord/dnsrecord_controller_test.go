@@ -0,0 +1,107 @@
+package dnsrecord
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// deleteProvider records the zones records are deleted from, failing for
+// the zones in failing
+type deleteProvider struct {
+	deleted []string
+	failing map[string]bool
+}
+
+func (p *deleteProvider) Ensure(_ context.Context, _ *v1.DNSRecord, _ v1.DNSZone) error {
+	return nil
+}
+
+func (p *deleteProvider) Delete(_ context.Context, _ *v1.DNSRecord, zone v1.DNSZone) error {
+	if p.failing[zone.ID] {
+		return errors.New("provider error")
+	}
+	p.deleted = append(p.deleted, zone.ID)
+	return nil
+}
+
+func TestDNSRecordReconciler_removeFromStaleZones(t *testing.T) {
+	status := func(id string, published bool) v1.DNSZoneStatus {
+		failed := metav1.ConditionTrue
+		if published {
+			failed = metav1.ConditionFalse
+		}
+		return v1.DNSZoneStatus{
+			DNSZone:    v1.DNSZone{ID: id},
+			Conditions: []v1.DNSZoneCondition{conditions.NewZoneCondition(v1.DNSRecordFailedConditionType, failed, "", "")},
+		}
+	}
+	zones := func(ids ...string) []v1.DNSZone {
+		var zones []v1.DNSZone
+		for _, id := range ids {
+			zones = append(zones, v1.DNSZone{ID: id})
+		}
+		return zones
+	}
+
+	tests := []struct {
+		name          string
+		zones         []v1.DNSZone
+		statuses      []v1.DNSZoneStatus
+		failing       map[string]bool
+		expectDeleted []string
+		expectZones   []string
+	}{
+		{
+			name:          "record moved to the new default zone",
+			zones:         zones("new"),
+			statuses:      []v1.DNSZoneStatus{status("old", true), status("new", true)},
+			expectDeleted: []string{"old"},
+			expectZones:   []string{"new"},
+		},
+		{
+			name:        "record kept in the old zone until published to the new one",
+			zones:       zones("new"),
+			statuses:    []v1.DNSZoneStatus{status("old", true), status("new", false)},
+			expectZones: []string{"old", "new"},
+		},
+		{
+			name:        "record kept when the default zone is unset",
+			statuses:    []v1.DNSZoneStatus{status("old", true)},
+			expectZones: []string{"old"},
+		},
+		{
+			name:        "stale zone kept while the provider fails to delete from it",
+			zones:       zones("new"),
+			statuses:    []v1.DNSZoneStatus{status("old", true), status("new", true)},
+			failing:     map[string]bool{"old": true},
+			expectZones: []string{"old", "new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &v1.DNSRecord{Status: v1.DNSRecordStatus{Zones: tt.statuses}}
+			provider := &deleteProvider{failing: tt.failing}
+			r := &DNSRecordReconciler{}
+
+			r.removeFromStaleZones(context.Background(), tt.zones, staleZones(tt.zones, record), record, provider)
+			if !reflect.DeepEqual(provider.deleted, tt.expectDeleted) {
+				t.Errorf("expected '%v' got '%v'", tt.expectDeleted, provider.deleted)
+			}
+			var got []string
+			for _, zone := range record.Status.Zones {
+				got = append(got, zone.DNSZone.ID)
+			}
+			if !reflect.DeepEqual(got, tt.expectZones) {
+				t.Errorf("expected '%v' got '%v'", tt.expectZones, got)
+			}
+		})
+	}
+}
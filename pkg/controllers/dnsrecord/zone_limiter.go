@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsrecord
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// zoneBusyRetry is how long a record waits before retrying when all the
+// workers of its zone are busy
+const zoneBusyRetry = time.Second
+
+// zoneLimiter limits the number of records concurrently changed in each
+// zone and the rate at which they are changed. Records of a busy zone are
+// requeued instead of blocking a worker, so a burst of changes in one zone
+// doesn't starve the updates of other zones
+type zoneLimiter struct {
+	concurrency int
+	qps         rate.Limit
+	burst       int
+
+	mu    sync.Mutex
+	zones map[string]*zoneWorkers
+}
+
+type zoneWorkers struct {
+	slots   chan struct{}
+	limiter *rate.Limiter
+}
+
+// newZoneLimiter returns a limiter allowing concurrency changes at a time
+// and qps changes per second in each zone. Zero values disable the
+// respective limit
+func newZoneLimiter(concurrency int, qps float64, burst int) *zoneLimiter {
+	limit := rate.Inf
+	if qps > 0 {
+		limit = rate.Limit(qps)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &zoneLimiter{
+		concurrency: concurrency,
+		qps:         limit,
+		burst:       burst,
+		zones:       map[string]*zoneWorkers{},
+	}
+}
+
+func (l *zoneLimiter) workers(zone v1.DNSZone) *zoneWorkers {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	workers, ok := l.zones[zone.ID]
+	if !ok {
+		workers = &zoneWorkers{limiter: rate.NewLimiter(l.qps, l.burst)}
+		if l.concurrency > 0 {
+			workers.slots = make(chan struct{}, l.concurrency)
+		}
+		l.zones[zone.ID] = workers
+	}
+	return workers
+}
+
+// acquire takes a worker slot in each of the zones. When any of the zones is
+// busy or rate limited no slot is taken, and the delay after which to retry
+// is returned. Otherwise the returned func releases the slots
+func (l *zoneLimiter) acquire(zones []v1.DNSZone) (func(), time.Duration) {
+	var acquired []*zoneWorkers
+	release := func() {
+		for _, workers := range acquired {
+			if workers.slots != nil {
+				<-workers.slots
+			}
+		}
+	}
+
+	now := time.Now()
+	var reservations []*rate.Reservation
+	cancel := func() {
+		release()
+		for _, reservation := range reservations {
+			reservation.CancelAt(now)
+		}
+	}
+
+	for _, zone := range zones {
+		workers := l.workers(zone)
+		if workers.slots != nil {
+			select {
+			case workers.slots <- struct{}{}:
+			default:
+				cancel()
+				return nil, zoneBusyRetry
+			}
+		}
+		acquired = append(acquired, workers)
+
+		reservation := workers.limiter.ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			cancel()
+			return nil, delay
+		}
+		reservations = append(reservations, reservation)
+	}
+	return release, 0
+}
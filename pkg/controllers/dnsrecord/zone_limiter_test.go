@@ -0,0 +1,147 @@
+package dnsrecord
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+const (
+	acquired    = "acquired"
+	busy        = "busy"
+	rateLimited = "rateLimited"
+)
+
+// acquireStep acquires the zones, keeping the slots unless release is set.
+// releaseHeld releases the slots kept by the previous steps first
+type acquireStep struct {
+	zones       []string
+	release     bool
+	releaseHeld bool
+	expect      string
+}
+
+func TestZoneLimiter_acquire(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Concurrency int
+		QPS         float64
+		Burst       int
+		Steps       []acquireStep
+	}{
+		{
+			Name: "test zero values don't limit the zones",
+			Steps: []acquireStep{
+				{zones: []string{"a"}, expect: acquired},
+				{zones: []string{"a"}, expect: acquired},
+				{zones: []string{"a", "b"}, expect: acquired},
+				{zones: []string{"a", "b"}, expect: acquired},
+			},
+		},
+		{
+			Name:        "test concurrency is capped per zone",
+			Concurrency: 2,
+			Steps: []acquireStep{
+				{zones: []string{"a"}, expect: acquired},
+				{zones: []string{"a"}, expect: acquired},
+				{zones: []string{"a"}, expect: busy},
+				{zones: []string{"b"}, expect: acquired},
+				{zones: []string{"a"}, releaseHeld: true, expect: acquired},
+			},
+		},
+		{
+			Name:        "test released slots are reused",
+			Concurrency: 1,
+			Steps: []acquireStep{
+				{zones: []string{"a"}, release: true, expect: acquired},
+				{zones: []string{"a"}, release: true, expect: acquired},
+				{zones: []string{"a"}, expect: acquired},
+				{zones: []string{"a"}, expect: busy},
+			},
+		},
+		{
+			Name:        "test slots are released when a later zone is busy",
+			Concurrency: 1,
+			Steps: []acquireStep{
+				{zones: []string{"b"}, expect: acquired},
+				{zones: []string{"a", "b"}, expect: busy},
+				{zones: []string{"a"}, expect: acquired},
+			},
+		},
+		{
+			Name:  "test changes are rate limited per zone",
+			QPS:   1,
+			Burst: 1,
+			Steps: []acquireStep{
+				{zones: []string{"a"}, release: true, expect: acquired},
+				{zones: []string{"a"}, release: true, expect: rateLimited},
+				{zones: []string{"b"}, release: true, expect: acquired},
+			},
+		},
+		{
+			Name:  "test burst is allowed before rate limiting",
+			QPS:   1,
+			Burst: 2,
+			Steps: []acquireStep{
+				{zones: []string{"a"}, release: true, expect: acquired},
+				{zones: []string{"a"}, release: true, expect: acquired},
+				{zones: []string{"a"}, release: true, expect: rateLimited},
+			},
+		},
+		{
+			Name:        "test reservations are cancelled when a later zone is rate limited",
+			Concurrency: 1,
+			QPS:         1,
+			Burst:       1,
+			Steps: []acquireStep{
+				{zones: []string{"b"}, release: true, expect: acquired},
+				{zones: []string{"a", "b"}, expect: rateLimited},
+				{zones: []string{"a"}, expect: acquired},
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			limiter := newZoneLimiter(testCase.Concurrency, testCase.QPS, testCase.Burst)
+
+			var held []func()
+			for i, step := range testCase.Steps {
+				if step.releaseHeld {
+					for _, release := range held {
+						release()
+					}
+					held = nil
+				}
+
+				zones := []v1.DNSZone{}
+				for _, id := range step.zones {
+					zones = append(zones, v1.DNSZone{ID: id})
+				}
+				release, delay := limiter.acquire(zones)
+
+				got := acquired
+				switch {
+				case release == nil && delay == zoneBusyRetry:
+					got = busy
+				case release == nil && delay > 0 && delay <= time.Second:
+					got = rateLimited
+				case release == nil || delay != 0:
+					t.Fatalf("step %d: unexpected delay '%v'", i, delay)
+				}
+				if got != step.expect {
+					t.Errorf("step %d: expected '%v' got '%v'", i, step.expect, got)
+				}
+
+				if release != nil {
+					if step.release {
+						release()
+					} else {
+						held = append(held, release)
+					}
+				}
+			}
+		})
+	}
+}
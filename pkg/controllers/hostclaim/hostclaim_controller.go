@@ -0,0 +1,223 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostclaim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+)
+
+const hostClaimFinalizer = "kuadrant.io/host-claim"
+
+type HostService interface {
+	ZoneForHost(ctx context.Context, host string) (*v1.ManagedZone, error)
+	ClaimHost(ctx context.Context, claim *v1.HostClaim, zone *v1.ManagedZone) error
+	ReleaseHosts(ctx context.Context, claim *v1.HostClaim, keep string) error
+	TenantZone(ctx context.Context) (*v1.ManagedZone, error)
+	CheckTenantQuota(ctx context.Context, namespace, host string) error
+}
+
+// HostClaimReconciler reserves the hosts of HostClaims by creating their
+// DNSRecords in the controller namespace, and releases them once the claims
+// are deleted. Claims with an expiry are deleted once expired. In the
+// namespace-as-tenant mode, only the hosts of the subdomain of the namespace
+// of a claim are reserved, within the host quota of the namespace
+type HostClaimReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Hosts  HostService
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=hostclaims,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=kuadrant.io,resources=hostclaims/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=hostclaims/finalizers,verbs=update
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedzones,verbs=get;list;watch
+
+func (r *HostClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = logging.IntoContext(ctx, logging.DNS)
+	previous := &v1.HostClaim{}
+	if err := r.Client.Get(ctx, req.NamespacedName, previous); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	claim := previous.DeepCopy()
+
+	if claim.DeletionTimestamp != nil && !claim.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(claim, hostClaimFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.Hosts.ReleaseHosts(ctx, claim, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(claim, hostClaimFinalizer)
+		return ctrl.Result{}, r.Client.Update(ctx, claim)
+	}
+	if controllerutil.AddFinalizer(claim, hostClaimFinalizer) {
+		return ctrl.Result{}, r.Client.Update(ctx, claim)
+	}
+
+	if claim.Spec.ExpiresAfter != nil {
+		expiresAt := metav1.NewTime(claim.CreationTimestamp.Add(claim.Spec.ExpiresAfter.Duration))
+		if !time.Now().Before(expiresAt.Time) {
+			log.FromContext(ctx).Info("host claim expired, deleting", "host", claim.Spec.Host, "claim", dns.ClaimKey(claim), "expiresAt", expiresAt)
+			return ctrl.Result{}, client.IgnoreNotFound(r.Client.Delete(ctx, claim))
+		}
+		claim.Status.ExpiresAt = &expiresAt
+	} else {
+		claim.Status.ExpiresAt = nil
+	}
+
+	// requeueAfter is how long until the claim is reconciled again, to claim
+	// a host cooling down or to delete the claim once expired
+	var requeueAfter time.Duration
+	host := claim.Spec.Host
+	zone, err := r.Hosts.ZoneForHost(ctx, host)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	rejectReason, rejectMessage, err := r.tenantRejection(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if zone == nil {
+		if err := r.Hosts.ReleaseHosts(ctx, claim, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		claim.Status.ManagedZone = ""
+		conditions.Set(&claim.Status.Conditions, claim.Generation, v1.HostClaimClaimedConditionType, metav1.ConditionFalse,
+			conditions.ReasonNoZone, fmt.Sprintf("No public ManagedZone of the controller contains host %s", host))
+	} else if rejectReason != "" {
+		log.FromContext(ctx).Info("host of claim rejected", "host", host, "claim", dns.ClaimKey(claim), "reason", rejectReason)
+		if err := r.Hosts.ReleaseHosts(ctx, claim, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		claim.Status.ManagedZone = ""
+		conditions.Set(&claim.Status.Conditions, claim.Generation, v1.HostClaimClaimedConditionType, metav1.ConditionFalse,
+			rejectReason, rejectMessage)
+	} else {
+		claim.Status.ManagedZone = zone.Name
+		err := r.Hosts.ClaimHost(ctx, claim, zone)
+		var reservedErr *dns.ReservedHostError
+		var coolingDownErr *dns.HostCoolingDownError
+		switch {
+		case err == nil:
+			conditions.Set(&claim.Status.Conditions, claim.Generation, v1.HostClaimClaimedConditionType, metav1.ConditionTrue,
+				conditions.ReasonClaimed, fmt.Sprintf("Host %s is reserved in zone %s", host, zone.Spec.DomainName))
+		case err == dns.HostTakenErr:
+			log.FromContext(ctx).Info("host of claim taken", "host", host, "claim", dns.ClaimKey(claim))
+			conditions.Set(&claim.Status.Conditions, claim.Generation, v1.HostClaimClaimedConditionType, metav1.ConditionFalse,
+				conditions.ReasonHostTaken, fmt.Sprintf("Host %s is claimed by another HostClaim or managed for a traffic object", host))
+		case errors.As(err, &reservedErr):
+			log.FromContext(ctx).Info("host of claim reserved", "host", host, "claim", dns.ClaimKey(claim), "pattern", reservedErr.Pattern)
+			conditions.Set(&claim.Status.Conditions, claim.Generation, v1.HostClaimClaimedConditionType, metav1.ConditionFalse,
+				conditions.ReasonReservedHost, fmt.Sprintf("Host %s is reserved in zone %s", host, zone.Spec.DomainName))
+		case errors.As(err, &coolingDownErr):
+			log.FromContext(ctx).Info("host of claim cooling down", "host", host, "claim", dns.ClaimKey(claim), "until", coolingDownErr.Until)
+			conditions.Set(&claim.Status.Conditions, claim.Generation, v1.HostClaimClaimedConditionType, metav1.ConditionFalse,
+				conditions.ReasonHostCoolingDown, fmt.Sprintf("Host %s was released by another namespace and can be claimed from %s", host, coolingDownErr.Until.Format(time.RFC3339)))
+			requeueAfter = time.Until(coolingDownErr.Until)
+		default:
+			return ctrl.Result{}, err
+		}
+	}
+	claim.Status.ObservedGeneration = claim.Generation
+	if err := conditions.UpdateStatus(ctx, r.Client, claim, previous.Status, claim.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	if claim.Status.ExpiresAt != nil {
+		if untilExpiry := time.Until(claim.Status.ExpiresAt.Time); requeueAfter == 0 || untilExpiry < requeueAfter {
+			requeueAfter = untilExpiry
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// tenantRejection returns the reason and message the host of the claim is
+// rejected for in the namespace-as-tenant mode, or an empty reason when it's
+// not rejected
+func (r *HostClaimReconciler) tenantRejection(ctx context.Context, claim *v1.HostClaim) (string, string, error) {
+	tenantZone, err := r.Hosts.TenantZone(ctx)
+	if err != nil || tenantZone == nil {
+		return "", "", err
+	}
+	domain := dns.TenantDomain(claim.Namespace, tenantZone.Spec.DomainName)
+	if !dns.InTenantDomain(claim.Spec.Host, domain) {
+		return conditions.ReasonOutsideTenantDomain, fmt.Sprintf("Host %s is outside of the domain %s of namespace %s", claim.Spec.Host, domain, claim.Namespace), nil
+	}
+	err = r.Hosts.CheckTenantQuota(ctx, claim.Namespace, claim.Spec.Host)
+	if err == dns.TenantQuotaExceededErr {
+		return conditions.ReasonTenantQuotaExceeded, fmt.Sprintf("Namespace %s has as many hosts as it can be assigned", claim.Namespace), nil
+	}
+	return "", "", err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HostClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.HostClaim{}).
+		Watches(&source.Kind{Type: &v1.DNSRecord{}}, handler.EnqueueRequestsFromMapFunc(recordToClaim)).
+		Watches(&source.Kind{Type: &v1.ManagedZone{}}, handler.EnqueueRequestsFromMapFunc(r.zoneToClaims)).
+		Complete(r)
+}
+
+// recordToClaim maps the DNSRecord of a claimed host to its claim, so a
+// deleted record is claimed again
+func recordToClaim(o client.Object) []reconcile.Request {
+	record, ok := o.(*v1.DNSRecord)
+	if !ok {
+		return nil
+	}
+	namespace, name, found := strings.Cut(dns.ClaimOf(record), "/")
+	if !found {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}}
+}
+
+// zoneToClaims maps a ManagedZone to every claim, as the zone of their host
+// may have changed
+func (r *HostClaimReconciler) zoneToClaims(o client.Object) []reconcile.Request {
+	claims := &v1.HostClaimList{}
+	if err := r.Client.List(context.Background(), claims); err != nil {
+		log.Log.Error(err, "Failed to list claims for zone", "zone", o.GetName(), "namespace", o.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, claim := range claims.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&claim)})
+	}
+	return requests
+}
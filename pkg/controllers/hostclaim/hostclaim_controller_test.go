@@ -0,0 +1,136 @@
+package hostclaim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+func TestHostClaimReconciler_expiry(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+	utilruntime.Must(certman.AddToScheme(scheme))
+
+	claim := func(age time.Duration, expiresAfter *metav1.Duration) *v1.HostClaim {
+		return &v1.HostClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "preview",
+				Namespace:         "team-a",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+				Finalizers:        []string{hostClaimFinalizer},
+			},
+			Spec: v1.HostClaimSpec{Host: "preview.example.com", ExpiresAfter: expiresAfter},
+		}
+	}
+	// the record of the claimed host, and the certificate issued for it,
+	// which is garbage collected with the record
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "preview.example.com",
+			Namespace:   "argocd",
+			UID:         "record",
+			Annotations: map[string]string{dns.AnnotationHostClaim: "team-a/preview"},
+		},
+	}
+	certificate := &certman.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "preview.example.com",
+			Namespace:       "argocd",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "kuadrant.io/v1", Kind: "DNSRecord", Name: record.Name, UID: record.UID}},
+		},
+	}
+	zone := &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{Name: "zone", Namespace: "argocd"},
+		Spec:       v1.ManagedZoneSpec{ID: "zone", DomainName: "example.com"},
+	}
+
+	tests := []struct {
+		name  string
+		claim *v1.HostClaim
+		// expectReleased is whether the claim and the record of its host
+		// are gone after the reconciles
+		expectReleased bool
+		// expectRequeue is the lower bound of the requeue, which is at
+		// most a minute later, none when zero
+		expectRequeue time.Duration
+	}{
+		{
+			name:           "expired claim deleted and its host released",
+			claim:          claim(2*time.Hour, &metav1.Duration{Duration: time.Hour}),
+			expectReleased: true,
+		},
+		{
+			name:          "claim requeued at its expiry",
+			claim:         claim(30*time.Minute, &metav1.Duration{Duration: time.Hour}),
+			expectRequeue: 29 * time.Minute,
+		},
+		{
+			name:  "claim without expiry not requeued",
+			claim: claim(2*time.Hour, nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.claim, record.DeepCopy(), certificate.DeepCopy(), zone).Build()
+			r := &HostClaimReconciler{
+				Client: c,
+				Scheme: scheme,
+				Hosts:  dns.NewService(c, nil, "argocd", config.NewStore(config.Config{})),
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tt.claim)}
+
+			// the expired claim is deleted by the first reconcile, and its
+			// host released by the next one
+			result, err := r.Reconcile(ctx, req)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.expectReleased {
+				if _, err := r.Reconcile(ctx, req); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+
+			err = c.Get(ctx, req.NamespacedName, &v1.HostClaim{})
+			if released := k8serrors.IsNotFound(err); released != tt.expectReleased {
+				t.Errorf("expected claim released '%v' got '%v'", tt.expectReleased, err)
+			}
+			err = c.Get(ctx, client.ObjectKeyFromObject(record), &v1.DNSRecord{})
+			if released := k8serrors.IsNotFound(err); released != tt.expectReleased {
+				t.Errorf("expected record released '%v' got '%v'", tt.expectReleased, err)
+			}
+			if tt.expectReleased {
+				// the certificate goes with the record it's owned by
+				got := &certman.Certificate{}
+				if err := c.Get(ctx, client.ObjectKeyFromObject(certificate), got); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].UID != record.UID {
+					t.Errorf("expected certificate owned by '%v' got '%v'", record.UID, got.OwnerReferences)
+				}
+				return
+			}
+			if tt.expectRequeue == 0 && result.RequeueAfter != 0 ||
+				result.RequeueAfter < tt.expectRequeue || result.RequeueAfter > tt.expectRequeue+time.Minute {
+				t.Errorf("expected requeue after '%v' got '%v'", tt.expectRequeue, result.RequeueAfter)
+			}
+		})
+	}
+}
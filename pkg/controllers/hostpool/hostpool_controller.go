@@ -0,0 +1,190 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostpool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+)
+
+// poolRecheckInterval is how often a pool is reconciled while the
+// certificates of its hosts are being issued
+const poolRecheckInterval = time.Minute
+
+type HostService interface {
+	PooledHosts(ctx context.Context, pool *v1.HostPool) ([]*v1.DNSRecord, error)
+	ProvisionHost(ctx context.Context, pool *v1.HostPool, zone *v1.ManagedZone) (*v1.DNSRecord, error)
+	EnsureCAA(ctx context.Context, record *v1.DNSRecord) error
+	CertificateHost(ctx context.Context, record *v1.DNSRecord) (string, *v1.DNSRecord, error)
+}
+
+type CertificateService interface {
+	EnsureCertificate(ctx context.Context, host string, owner metav1.Object) error
+	GetCertificateSecret(ctx context.Context, host string) (*corev1.Secret, error)
+}
+
+// HostPoolReconciler keeps the hosts of HostPools provisioned: each host gets
+// a DNSRecord owned by the pool and a certificate, and is marked available
+// once the certificate is issued. The hosts assigned to traffic objects leave
+// the pool and are replaced
+type HostPoolReconciler struct {
+	client.Client
+	Scheme       *runtime.Scheme
+	Hosts        HostService
+	Certificates CertificateService
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=hostpools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=hostpools/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedzones,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create
+
+func (r *HostPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = logging.IntoContext(ctx, logging.DNS)
+	previous := &v1.HostPool{}
+	if err := r.Client.Get(ctx, req.NamespacedName, previous); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	pool := previous.DeepCopy()
+	if pool.DeletionTimestamp != nil && !pool.DeletionTimestamp.IsZero() {
+		// the hosts not assigned are garbage collected with the pool
+		return ctrl.Result{}, nil
+	}
+	pool.Status.ObservedGeneration = pool.Generation
+
+	zone := &v1.ManagedZone{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: pool.Namespace, Name: pool.Spec.ManagedZoneRef.Name}, zone); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		conditions.Set(&pool.Status.Conditions, pool.Generation, v1.HostPoolReadyConditionType, metav1.ConditionFalse,
+			conditions.ReasonNotFound, fmt.Sprintf("ManagedZone %s not found", pool.Spec.ManagedZoneRef.Name))
+		return ctrl.Result{}, conditions.UpdateStatus(ctx, r.Client, pool, previous.Status, pool.Status)
+	}
+
+	pooled, err := r.Hosts.PooledHosts(ctx, pool)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// the hosts not available are removed first when the pool shrinks
+	sort.SliceStable(pooled, func(i, j int) bool {
+		return !available(pooled[i]) && available(pooled[j])
+	})
+	for len(pooled) > pool.Spec.Size {
+		log.FromContext(ctx).Info("removing surplus pooled host", "host", pooled[0].Name, "pool", pool.Name)
+		if err := r.Client.Delete(ctx, pooled[0]); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		pooled = pooled[1:]
+	}
+	for len(pooled) < pool.Spec.Size {
+		record, err := r.Hosts.ProvisionHost(ctx, pool, zone)
+		if err == dns.PoolNamespaceErr {
+			conditions.Set(&pool.Status.Conditions, pool.Generation, v1.HostPoolReadyConditionType, metav1.ConditionFalse,
+				conditions.ReasonInvalidNamespace, err.Error())
+			return ctrl.Result{}, conditions.UpdateStatus(ctx, r.Client, pool, previous.Status, pool.Status)
+		}
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		pooled = append(pooled, record)
+	}
+
+	availableHosts := 0
+	for _, record := range pooled {
+		ok, err := r.ensureCertificate(ctx, record)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if ok {
+			availableHosts++
+		}
+	}
+	pool.Status.Provisioned = len(pooled)
+	pool.Status.Available = availableHosts
+
+	result := ctrl.Result{}
+	if availableHosts < pool.Spec.Size {
+		conditions.Set(&pool.Status.Conditions, pool.Generation, v1.HostPoolReadyConditionType, metav1.ConditionFalse,
+			conditions.ReasonPending, fmt.Sprintf("%d of %d hosts available, waiting for their certificates", availableHosts, pool.Spec.Size))
+		result = ctrl.Result{RequeueAfter: poolRecheckInterval}
+	} else {
+		conditions.Set(&pool.Status.Conditions, pool.Generation, v1.HostPoolReadyConditionType, metav1.ConditionTrue,
+			conditions.ReasonHostsAvailable, fmt.Sprintf("%d hosts available", availableHosts))
+	}
+	if err := conditions.UpdateStatus(ctx, r.Client, pool, previous.Status, pool.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return result, nil
+}
+
+// ensureCertificate publishes the CAA record of the pooled host and creates
+// its certificate, marking the host available once it's issued. Returns
+// whether the host is available
+func (r *HostPoolReconciler) ensureCertificate(ctx context.Context, record *v1.DNSRecord) (bool, error) {
+	if available(record) {
+		return true, nil
+	}
+	certificateHost, certificateRecord, err := r.Hosts.CertificateHost(ctx, record)
+	if err != nil {
+		return false, err
+	}
+	if certificateRecord == record {
+		if err := r.Hosts.EnsureCAA(ctx, record); err != nil {
+			return false, err
+		}
+	}
+	if err := r.Certificates.EnsureCertificate(ctx, certificateHost, certificateRecord); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return false, err
+	}
+	if _, err := r.Certificates.GetCertificateSecret(ctx, certificateHost); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	record.Labels[dns.LabelHostPoolAvailable] = "true"
+	if err := r.Client.Update(ctx, record); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return true, nil
+}
+
+func available(record *v1.DNSRecord) bool {
+	return record.Labels[dns.LabelHostPoolAvailable] == "true"
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HostPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.HostPool{}).
+		Owns(&v1.DNSRecord{}).
+		Complete(r)
+}
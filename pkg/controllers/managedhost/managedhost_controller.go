@@ -0,0 +1,401 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package managedhost
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls/acme"
+)
+
+// caaRecheckInterval is how often the CAA records of a host are checked
+// again while they block issuance, as DNS isn't watched
+const caaRecheckInterval = 10 * time.Minute
+
+// ManagedHostReconciler reports the lifecycle state of a managed host from
+// its DNSRecord and certificate, which are named after the host, or the
+// wildcard ones of its zone
+type ManagedHostReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Config holds the certificate authorities the CAA records of the host
+	// are checked against
+	Config      *config.Store
+	CAAResolver dns.CAAResolver
+	// BuiltinACME reports the certificates of the hosts from their TLS
+	// secrets, issued by the built-in ACME client instead of cert-manager
+	BuiltinACME bool
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedhosts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedhosts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedzones,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+func (r *ManagedHostReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	previous := &v1.ManagedHost{}
+	if err := r.Client.Get(ctx, req.NamespacedName, previous); err != nil {
+		if k8serrors.IsNotFound(err) {
+			hostReadiness.forget(req.String())
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	managedHost := previous.DeepCopy()
+
+	dnsPublished, healthy, err := r.dnsStatus(ctx, managedHost)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	certificateReady, err := r.certificateStatus(ctx, managedHost)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	caaAllowed, err := r.caaStatus(ctx, managedHost)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.syncStatus(ctx, managedHost); err != nil {
+		return ctrl.Result{}, err
+	}
+	placementStatus(managedHost, healthy)
+
+	if dnsPublished && certificateReady {
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostReadyConditionType, metav1.ConditionTrue,
+			conditions.ReasonHostReady, "The host is published and its certificate is issued")
+	} else {
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostReadyConditionType, metav1.ConditionFalse,
+			conditions.ReasonHostNotReady, "The host is not published or its certificate is not issued yet")
+	}
+	managedHost.Status.ObservedGeneration = managedHost.Generation
+	r.observeReadiness(req.String(), previous, dnsPublished && certificateReady && healthy)
+	if err := conditions.UpdateStatus(ctx, r.Client, managedHost, previous.Status, managedHost.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !caaAllowed {
+		return ctrl.Result{RequeueAfter: caaRecheckInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// observeReadiness records the end to end readiness of the host in its
+// metrics. Hosts unready when the controller starts are unready since the
+// last transition of their Ready condition
+func (r *ManagedHostReconciler) observeReadiness(key string, previous *v1.ManagedHost, ready bool) {
+	since := time.Time{}
+	if condition := meta.FindStatusCondition(previous.Status.Conditions, v1.ManagedHostReadyConditionType); condition != nil && condition.Status != metav1.ConditionTrue {
+		since = condition.LastTransitionTime.Time
+	}
+	hostReadiness.observe(key, previous.Namespace, previous.Spec.Host, ready, since)
+}
+
+// dnsStatus updates the DNS status of the host, returning whether its
+// DNSRecord is published to all its zones and whether any of its clusters
+// is healthy, with endpoints that aren't drained
+func (r *ManagedHostReconciler) dnsStatus(ctx context.Context, managedHost *v1.ManagedHost) (bool, bool, error) {
+	name, err := r.servingName(ctx, managedHost)
+	if err != nil {
+		return false, false, err
+	}
+	record := &v1.DNSRecord{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: managedHost.Namespace, Name: name}, record); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, false, err
+		}
+		managedHost.Status.DNSRecord = ""
+		managedHost.Status.Clusters = nil
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostDNSPublishedConditionType, metav1.ConditionFalse,
+			conditions.ReasonNotFound, fmt.Sprintf("The DNSRecord %s was not found", name))
+		return false, false, nil
+	}
+	managedHost.Status.DNSRecord = record.Name
+	managedHost.Status.Clusters = dns.EndpointClusters(record)
+
+	status, reason, message := metav1.ConditionTrue, conditions.ReasonProviderSuccess, "The DNSRecord is published to all its zones"
+	switch {
+	case len(record.Spec.Endpoints) == 0:
+		status, reason, message = metav1.ConditionFalse, conditions.ReasonNoEndpoints, "The host has no endpoints yet"
+	case len(record.Status.Zones) == 0 || record.Status.ObservedGeneration != record.Generation:
+		status, reason, message = metav1.ConditionFalse, conditions.ReasonPending, "The DNSRecord is not published yet"
+	default:
+		for _, zone := range record.Status.Zones {
+			for _, condition := range zone.Conditions {
+				if condition.Type == v1.DNSRecordFailedConditionType && condition.Status != string(metav1.ConditionFalse) {
+					status, reason = metav1.ConditionFalse, conditions.ReasonProviderError
+					message = fmt.Sprintf("The DNSRecord failed to publish to zone %s: %s", zone.DNSZone.ID, condition.Message)
+				}
+			}
+		}
+	}
+	conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostDNSPublishedConditionType, status, reason, message)
+	return status == metav1.ConditionTrue, len(dns.HealthyClusters(record)) > 0, nil
+}
+
+// servingName returns the name of the DNSRecord and of the certificate
+// serving the host: the wildcard ones of its zone when it's a wildcard zone,
+// or the ones of the host
+func (r *ManagedHostReconciler) servingName(ctx context.Context, managedHost *v1.ManagedHost) (string, error) {
+	record := &v1.DNSRecord{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: managedHost.Namespace, Name: managedHost.Spec.Host}, record); err != nil {
+		return managedHost.Spec.Host, client.IgnoreNotFound(err)
+	}
+	zone, err := dns.WildcardZone(ctx, r.Client, record)
+	if err != nil || zone == nil {
+		return managedHost.Spec.Host, err
+	}
+	return dns.WildcardRecordName(zone), nil
+}
+
+// certificateStatus updates the certificate status of the host, returning
+// whether its certificate is issued
+func (r *ManagedHostReconciler) certificateStatus(ctx context.Context, managedHost *v1.ManagedHost) (bool, error) {
+	name, err := r.servingName(ctx, managedHost)
+	if err != nil {
+		return false, err
+	}
+	if r.BuiltinACME {
+		return r.secretStatus(ctx, managedHost, name)
+	}
+	certificate := &certman.Certificate{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: managedHost.Namespace, Name: name}, certificate); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+		managedHost.Status.Certificate = ""
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostCertificateReadyConditionType, metav1.ConditionFalse,
+			conditions.ReasonNotFound, fmt.Sprintf("The Certificate %s was not found", name))
+		return false, nil
+	}
+	managedHost.Status.Certificate = certificate.Name
+
+	status, reason, message := metav1.ConditionFalse, conditions.ReasonPending, "The certificate is not issued yet"
+	failure := ""
+	for _, condition := range certificate.Status.Conditions {
+		switch condition.Type {
+		case certman.CertificateConditionReady:
+			if condition.Status == cmmeta.ConditionTrue {
+				status, reason, message = metav1.ConditionTrue, conditions.ReasonCertificateIssued, "The certificate is issued"
+			} else if condition.Message != "" {
+				message = condition.Message
+			}
+		case certman.CertificateConditionIssuing:
+			if condition.Status == cmmeta.ConditionFalse && condition.Message != "" {
+				failure = condition.Message
+			}
+		}
+	}
+	if status == metav1.ConditionTrue {
+		managedHost.Status.IssuanceFailures = 0
+		managedHost.Status.LastIssuanceFailureTime = nil
+	} else if observeIssuanceFailure(managedHost, certificate) {
+		if failure == "" {
+			failure = message
+		}
+		reason = conditions.ReasonIssuanceFailed
+		message = fmt.Sprintf("cert-manager failed to issue the certificate %d times, retrying every %s: %s",
+			managedHost.Status.IssuanceFailures, tls.IssuanceRetryInterval, failure)
+	}
+	conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostCertificateReadyConditionType, status, reason, message)
+	return status == metav1.ConditionTrue, nil
+}
+
+// secretStatus updates the certificate status of the host from the TLS
+// secret the built-in ACME client stores its certificate in
+func (r *ManagedHostReconciler) secretStatus(ctx context.Context, managedHost *v1.ManagedHost, name string) (bool, error) {
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: managedHost.Namespace, Name: name}, secret); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+		managedHost.Status.Certificate = ""
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostCertificateReadyConditionType, metav1.ConditionFalse,
+			conditions.ReasonPending, "The certificate is not issued yet")
+		return false, nil
+	}
+	managedHost.Status.Certificate = secret.Name
+	managedHost.Status.IssuanceFailures = 0
+	managedHost.Status.LastIssuanceFailureTime = nil
+	conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostCertificateReadyConditionType, metav1.ConditionTrue,
+		conditions.ReasonCertificateIssued, "The certificate is issued")
+	return true, nil
+}
+
+// observeIssuanceFailure counts the failed issuance recorded on the
+// certificate since the last one observed. Returns true when the last
+// issuance of the certificate failed
+func observeIssuanceFailure(managedHost *v1.ManagedHost, certificate *certman.Certificate) bool {
+	failed := certificate.Status.LastFailureTime
+	if failed == nil {
+		return false
+	}
+	last := managedHost.Status.LastIssuanceFailureTime
+	if last == nil || last.Before(failed) {
+		managedHost.Status.IssuanceFailures++
+		managedHost.Status.LastIssuanceFailureTime = failed.DeepCopy()
+	}
+	return true
+}
+
+// syncStatus updates whether the TLS secret serving the host, the wildcard
+// one of its zone in wildcard zones, is synced to every cluster serving it,
+// as recorded on the ManagedHost by the traffic controllers of the clusters
+func (r *ManagedHostReconciler) syncStatus(ctx context.Context, managedHost *v1.ManagedHost) error {
+	name, err := r.servingName(ctx, managedHost)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: managedHost.Namespace, Name: name}, secret); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostResourcesSyncedConditionType, metav1.ConditionFalse,
+			conditions.ReasonNotFound, fmt.Sprintf("The TLS secret %s was not found", name))
+		return nil
+	}
+	if len(managedHost.Status.Clusters) == 0 {
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostResourcesSyncedConditionType, metav1.ConditionFalse,
+			conditions.ReasonNoEndpoints, "No cluster serves the host yet")
+		return nil
+	}
+	synced := dns.SyncedSecrets(managedHost)
+	var pending []string
+	for _, cluster := range managedHost.Status.Clusters {
+		if synced[cluster] != secret.ResourceVersion {
+			pending = append(pending, cluster)
+		}
+	}
+	if len(pending) > 0 {
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostResourcesSyncedConditionType, metav1.ConditionFalse,
+			conditions.ReasonPending, fmt.Sprintf("The TLS secret is not synced to clusters %s yet", strings.Join(pending, ", ")))
+		return nil
+	}
+	conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostResourcesSyncedConditionType, metav1.ConditionTrue,
+		conditions.ReasonSynced, "The TLS secret is synced to every cluster serving the host")
+	return nil
+}
+
+// placementStatus updates whether a healthy cluster serves the host
+func placementStatus(managedHost *v1.ManagedHost, healthy bool) {
+	if !healthy {
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostPlacementSatisfiedConditionType, metav1.ConditionFalse,
+			conditions.ReasonNoHealthyClusters, "No healthy cluster serves the host")
+		return
+	}
+	conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostPlacementSatisfiedConditionType, metav1.ConditionTrue,
+		conditions.ReasonClustersHealthy, "A healthy cluster serves the host")
+}
+
+// caaStatus updates whether the CAA records of the host allow the
+// certificate authorities to issue its certificate, returning false while
+// they don't or can't be looked up. The CAA records of the DNSRecord of the
+// host take precedence over the ones published in DNS for its domains
+func (r *ManagedHostReconciler) caaStatus(ctx context.Context, managedHost *v1.ManagedHost) (bool, error) {
+	authorities := r.Config.Get().CertificateAuthorities
+	if len(authorities) == 0 {
+		conditions.Remove(&managedHost.Status.Conditions, v1.ManagedHostCAAAllowedConditionType)
+		return true, nil
+	}
+
+	record := &v1.DNSRecord{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: managedHost.Namespace, Name: managedHost.Spec.Host}, record); client.IgnoreNotFound(err) != nil {
+		return false, err
+	}
+	values, source := dns.RecordCAA(record), "DNSRecord "+record.Name
+	if len(values) == 0 && r.CAAResolver != nil {
+		var domain string
+		var err error
+		values, domain, err = dns.RelevantCAA(ctx, r.CAAResolver, managedHost.Spec.Host)
+		if err != nil {
+			conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostCAAAllowedConditionType, metav1.ConditionUnknown,
+				conditions.ReasonLookupFailed, fmt.Sprintf("The CAA records of the host could not be looked up: %v", err))
+			return false, nil
+		}
+		source = "domain " + domain
+	}
+
+	if !dns.CAAAllows(values, authorities) {
+		conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostCAAAllowedConditionType, metav1.ConditionFalse,
+			conditions.ReasonCAABlocked, fmt.Sprintf("The CAA records of %s don't allow %s to issue certificates: %s", source, strings.Join(authorities, ", "), strings.Join(values, ", ")))
+		return false, nil
+	}
+	conditions.Set(&managedHost.Status.Conditions, managedHost.Generation, v1.ManagedHostCAAAllowedConditionType, metav1.ConditionTrue,
+		conditions.ReasonCAAAllowed, fmt.Sprintf("The CAA records of the host allow %s to issue certificates", strings.Join(authorities, ", ")))
+	return true, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ManagedHostReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ManagedHost{}).
+		Watches(&source.Kind{Type: &v1.DNSRecord{}}, handler.EnqueueRequestsFromMapFunc(r.hostsOf))
+	if r.BuiltinACME {
+		builder = builder.Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.hostsOf),
+			ctrlbuilder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+				return o.GetLabels()[acme.LabelIssuedBy] == acme.IssuerName
+			})))
+	} else {
+		builder = builder.Watches(&source.Kind{Type: &certman.Certificate{}}, handler.EnqueueRequestsFromMapFunc(r.hostsOf))
+	}
+	return builder.Complete(r)
+}
+
+// hostsOf maps the DNSRecord, Certificate or TLS secret of a host to its ManagedHost,
+// which shares their name. The wildcard DNSRecord or Certificate of a zone
+// maps to the ManagedHosts of the domain of the zone
+func (r *ManagedHostReconciler) hostsOf(o client.Object) []reconcile.Request {
+	if !strings.HasPrefix(o.GetName(), "wildcard.") {
+		return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(o)}}
+	}
+	hosts := &v1.ManagedHostList{}
+	if err := r.Client.List(context.Background(), hosts, client.InNamespace(o.GetNamespace())); err != nil {
+		log.Log.Error(err, "Failed to list managed hosts of wildcard", "name", o.GetName(), "namespace", o.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, host := range hosts.Items {
+		if host.Name == o.GetName() || strings.HasSuffix(host.Spec.Host, "."+strings.TrimPrefix(o.GetName(), "wildcard.")) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&host)})
+		}
+	}
+	return requests
+}
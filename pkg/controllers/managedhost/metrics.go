@@ -0,0 +1,90 @@
+package managedhost
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	hostReadyDesc = prometheus.NewDesc(
+		"mctc_managed_host_ready",
+		"MCTC managed host ready end to end: its certificate is issued, its DNS published and it's served by a healthy cluster",
+		[]string{"namespace", "host"}, nil,
+	)
+	hostUnreadyDesc = prometheus.NewDesc(
+		"mctc_managed_host_unready_seconds",
+		"MCTC seconds since the managed host became unready, 0 while ready",
+		[]string{"namespace", "host"}, nil,
+	)
+
+	// hostReadiness holds the end to end readiness of each managed host,
+	// computing the time since unready hosts became unready when scraped
+	hostReadiness = &readinessCollector{hosts: map[string]readiness{}, now: time.Now}
+)
+
+func init() {
+	metrics.Registry.MustRegister(hostReadiness)
+}
+
+type readiness struct {
+	namespace string
+	host      string
+	ready     bool
+	// since is when the host became unready
+	since time.Time
+}
+
+type readinessCollector struct {
+	mu    sync.Mutex
+	hosts map[string]readiness
+	now   func() time.Time
+}
+
+// observe records the readiness of the managed host. Hosts first observed
+// unready are unready since the given time, which is when their readiness
+// last changed as far as their status records
+func (c *readinessCollector) observe(key, namespace, host string, ready bool, since time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, ok := c.hosts[key]
+	switch {
+	case ready:
+		since = time.Time{}
+	case ok && !previous.ready:
+		since = previous.since
+	case ok || since.IsZero():
+		since = c.now()
+	}
+	c.hosts[key] = readiness{namespace: namespace, host: host, ready: ready, since: since}
+}
+
+// forget removes the metrics of a deleted managed host
+func (c *readinessCollector) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.hosts, key)
+}
+
+func (c *readinessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hostReadyDesc
+	ch <- hostUnreadyDesc
+}
+
+func (c *readinessCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for _, r := range c.hosts {
+		ready, unready := 0.0, 0.0
+		if r.ready {
+			ready = 1
+		} else {
+			unready = now.Sub(r.since).Seconds()
+		}
+		ch <- prometheus.MustNewConstMetric(hostReadyDesc, prometheus.GaugeValue, ready, r.namespace, r.host)
+		ch <- prometheus.MustNewConstMetric(hostUnreadyDesc, prometheus.GaugeValue, unready, r.namespace, r.host)
+	}
+}
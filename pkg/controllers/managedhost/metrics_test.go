@@ -0,0 +1,50 @@
+package managedhost
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReadinessCollector(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name          string
+		ready         bool
+		since         time.Time
+		expectReady   int
+		expectUnready int
+	}{
+		{
+			name:        "ready host",
+			ready:       true,
+			since:       now.Add(-time.Hour),
+			expectReady: 1,
+		},
+		{
+			name:          "unready host",
+			since:         now.Add(-time.Minute),
+			expectUnready: 60,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			collector := &readinessCollector{hosts: map[string]readiness{}, now: func() time.Time { return now }}
+			collector.observe("team-a/app", "team-a", "app.example.com", tc.ready, tc.since)
+			expect := fmt.Sprintf(`
+# HELP mctc_managed_host_ready MCTC managed host ready end to end: its certificate is issued, its DNS published and it's served by a healthy cluster
+# TYPE mctc_managed_host_ready gauge
+mctc_managed_host_ready{host="app.example.com",namespace="team-a"} %d
+# HELP mctc_managed_host_unready_seconds MCTC seconds since the managed host became unready, 0 while ready
+# TYPE mctc_managed_host_unready_seconds gauge
+mctc_managed_host_unready_seconds{host="app.example.com",namespace="team-a"} %d
+`, tc.expectReady, tc.expectUnready)
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(expect)); err != nil {
+				t.Errorf("unexpected metrics %v", err)
+			}
+		})
+	}
+}
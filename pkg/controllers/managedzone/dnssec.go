@@ -0,0 +1,102 @@
+package managedzone
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+// dnssecResyncInterval is how often the DNSSEC state of the zones is read
+// from the providers, as it's changed out of band
+const dnssecResyncInterval = 5 * time.Minute
+
+// reconcileDNSSEC updates the DNSSEC state of the zone when its provider
+// can report it, returning whether it should be read again later. The
+// previous state is kept when it can't be read, so a transient failure of
+// the provider doesn't withdraw the DS records of the zone
+func (r *ManagedZoneReconciler) reconcileDNSSEC(ctx context.Context, managedZone *v1.ManagedZone, provider dns.Provider) bool {
+	reporter, ok := provider.(dns.DNSSECReporter)
+	if !ok {
+		managedZone.Status.DNSSEC = nil
+		return false
+	}
+	status, err := reporter.DNSSEC(ctx, managedZone.DNSZone())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to get DNSSEC state of zone", "zone", managedZone.Name, "namespace", managedZone.Namespace)
+		return true
+	}
+	managedZone.Status.DNSSEC = status
+	return true
+}
+
+// reconcileDSRecord publishes the DS records of the zone in its parent zone
+// while the zone is signing and manages them. Otherwise the DNSRecord of
+// the DS records is removed, as DS records that don't match the keys of the
+// zone break its resolution by validating resolvers
+func (r *ManagedZoneReconciler) reconcileDSRecord(ctx context.Context, managedZone *v1.ManagedZone) error {
+	if !managedZone.Spec.ManageDSRecord {
+		conditions.Remove(&managedZone.Status.Conditions, v1.ManagedZoneDSRecordPublishedConditionType)
+		return r.deleteDSRecord(ctx, managedZone)
+	}
+
+	endpoint := dns.DSEndpoint(managedZone)
+	if endpoint == nil {
+		conditions.Set(&managedZone.Status.Conditions, managedZone.Generation, v1.ManagedZoneDSRecordPublishedConditionType, metav1.ConditionFalse,
+			conditions.ReasonNotSigned, "The zone is not signed with DNSSEC by its provider")
+		return r.deleteDSRecord(ctx, managedZone)
+	}
+
+	zones := &v1.ManagedZoneList{}
+	if err := r.Client.List(ctx, zones, client.InNamespace(managedZone.Namespace)); err != nil {
+		return err
+	}
+	parent := dns.ParentZone(managedZone, zones.Items)
+	if parent == nil {
+		conditions.Set(&managedZone.Status.Conditions, managedZone.Generation, v1.ManagedZoneDSRecordPublishedConditionType, metav1.ConditionFalse,
+			conditions.ReasonNoZone, fmt.Sprintf("No ManagedZone of namespace %s is a parent of %s", managedZone.Namespace, managedZone.Spec.DomainName))
+		return r.deleteDSRecord(ctx, managedZone)
+	}
+
+	record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Namespace: managedZone.Namespace, Name: dns.DSRecordName(managedZone)}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, record, func() error {
+		record.Spec.ManagedZoneRef = &v1.ManagedZoneReference{Name: parent.Name}
+		record.Spec.Endpoints = []*v1.Endpoint{endpoint}
+		return controllerutil.SetControllerReference(managedZone, record, r.Scheme)
+	}); err != nil {
+		return err
+	}
+
+	status, reason, message := metav1.ConditionTrue, conditions.ReasonProviderSuccess, fmt.Sprintf("The DS records are published in zone %s", parent.Name)
+	if len(record.Status.Zones) == 0 || record.Status.ObservedGeneration != record.Generation {
+		status, reason, message = metav1.ConditionFalse, conditions.ReasonPending, fmt.Sprintf("The DS records are not published in zone %s yet", parent.Name)
+	}
+	conditions.Set(&managedZone.Status.Conditions, managedZone.Generation, v1.ManagedZoneDSRecordPublishedConditionType, status, reason, message)
+	return nil
+}
+
+// deleteDSRecord deletes the DNSRecord of the DS records of the zone, when
+// the zone owns it
+func (r *ManagedZoneReconciler) deleteDSRecord(ctx context.Context, managedZone *v1.ManagedZone) error {
+	record := &v1.DNSRecord{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: managedZone.Namespace, Name: dns.DSRecordName(managedZone)}, record); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(record, managedZone) || record.DeletionTimestamp != nil {
+		return nil
+	}
+	log.FromContext(ctx).Info("Removing DS records of zone", "zone", managedZone.Name, "namespace", managedZone.Namespace)
+	if err := r.Client.Delete(ctx, record); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
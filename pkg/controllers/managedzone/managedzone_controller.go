@@ -0,0 +1,256 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package managedzone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+)
+
+const ManagedZoneFinalizer = "kuadrant.io/managed-zone"
+
+// ManagedZoneReconciler reports whether the records of a ManagedZone can be
+// managed with the credentials it references and the DNSSEC state of the
+// zone, publishes the DS records of signed zones managing them in their
+// parent zone, and blocks the deletion of zones that still have DNSRecords
+type ManagedZoneReconciler struct {
+	client.Client
+	Scheme        *runtime.Scheme
+	ZoneProviders *dns.ZoneProviders
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedzones,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedzones/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=managedzones/finalizers,verbs=update
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+
+func (r *ManagedZoneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = logging.IntoContext(ctx, logging.DNS)
+	previous := &v1.ManagedZone{}
+	if err := r.Client.Get(ctx, req.NamespacedName, previous); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	managedZone := previous.DeepCopy()
+
+	if hub := metadata.HandedOverTo(managedZone); hub != "" {
+		return ctrl.Result{}, r.reconcileHandedOver(ctx, previous, managedZone, hub)
+	}
+	conditions.Remove(&managedZone.Status.Conditions, v1.ManagedZoneHandedOverConditionType)
+
+	if managedZone.DeletionTimestamp != nil && !managedZone.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.reconcileDeletion(ctx, previous, managedZone)
+	}
+	if !controllerutil.ContainsFinalizer(managedZone, ManagedZoneFinalizer) {
+		controllerutil.AddFinalizer(managedZone, ManagedZoneFinalizer)
+		if err := r.Update(ctx, managedZone); err != nil {
+			return ctrl.Result{}, err
+		}
+		previous = managedZone.DeepCopy()
+	}
+
+	status, reason, message := metav1.ConditionTrue, conditions.ReasonProviderConfigured, "The DNS provider of the zone is configured"
+	provider, err := r.ZoneProviders.ProviderFor(ctx, managedZone)
+	if err != nil {
+		status = metav1.ConditionFalse
+		reason, message = providerError(ctx, managedZone, "", managedZone.Spec.ProviderCredentialsRef, err)
+	}
+	for _, secondary := range managedZone.Spec.SecondaryZones {
+		if status != metav1.ConditionTrue {
+			break
+		}
+		if _, secondaryErr := r.ZoneProviders.SecondaryProviderFor(ctx, managedZone, secondary); secondaryErr != nil {
+			status = metav1.ConditionFalse
+			reason, message = providerError(ctx, managedZone, secondary.ID, secondary.ProviderCredentialsRef, secondaryErr)
+		}
+	}
+
+	conditions.Set(&managedZone.Status.Conditions, managedZone.Generation, v1.ManagedZoneReadyConditionType, status, reason, message)
+
+	result := ctrl.Result{}
+	if err == nil && r.reconcileDNSSEC(ctx, managedZone, provider) {
+		result.RequeueAfter = dnssecResyncInterval
+	}
+	if err := r.reconcileDSRecord(ctx, managedZone); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	managedZone.Status.ObservedGeneration = managedZone.Generation
+	if err := conditions.UpdateStatus(ctx, r.Client, managedZone, previous.Status, managedZone.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return result, nil
+}
+
+// providerError returns the reason and message of the Ready condition of a
+// zone the provider of which, or of its secondary zone, can't be configured
+func providerError(ctx context.Context, managedZone *v1.ManagedZone, secondaryID string, ref *v1.ProviderCredentialsReference, err error) (string, string) {
+	reason, message := conditions.ReasonProviderError, fmt.Sprintf("The DNS provider could not be configured: %v", err)
+	switch {
+	case errors.Is(err, dns.CredentialsNotAllowedErr):
+		reason, message = conditions.ReasonCredentialsNotAllowed, fmt.Sprintf("Provider credentials are not allowed in namespace %s", managedZone.Namespace)
+	case errors.Is(err, dns.DefaultCredentialsNotAllowedErr):
+		reason, message = conditions.ReasonCredentialsNotAllowed, fmt.Sprintf("The provider credentials of the controller are not allowed in namespace %s, reference provider credentials", managedZone.Namespace)
+	case k8serrors.IsNotFound(err) && ref != nil:
+		reason, message = conditions.ReasonCredentialsNotFound, fmt.Sprintf("The provider credentials secret %s was not found", ref.Name)
+	default:
+		log.FromContext(ctx).Error(err, "Failed to configure DNS provider for zone", "zone", managedZone.Name, "namespace", managedZone.Namespace, "secondaryZone", secondaryID)
+	}
+	if secondaryID != "" {
+		message = fmt.Sprintf("%s, for secondary zone %s", message, secondaryID)
+	}
+	return reason, message
+}
+
+// reconcileDeletion removes the finalizer of the zone once no DNSRecord
+// references it, or when the deletion is forced with the
+// kuadrant.io/force-delete annotation. While deletion is blocked, the
+// DeletionBlocked condition lists the records referencing the zone
+func (r *ManagedZoneReconciler) reconcileDeletion(ctx context.Context, previous, managedZone *v1.ManagedZone) error {
+	if !controllerutil.ContainsFinalizer(managedZone, ManagedZoneFinalizer) {
+		return nil
+	}
+
+	if !metadata.IsForceDelete(managedZone) {
+		records, err := r.zoneRecords(ctx, managedZone)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			log.FromContext(ctx).Info("Deletion of ManagedZone blocked by DNSRecords", "zone", managedZone.Name, "namespace", managedZone.Namespace, "records", records)
+			conditions.Set(&managedZone.Status.Conditions, managedZone.Generation, v1.ManagedZoneDeletionBlockedConditionType, metav1.ConditionTrue,
+				conditions.ReasonRecordsExist, fmt.Sprintf("The zone still has DNSRecords: %s. Delete them, or set the %s annotation to \"true\" to delete the zone anyway", strings.Join(records, ", "), metadata.AnnotationForceDelete))
+			return conditions.UpdateStatus(ctx, r.Client, managedZone, previous.Status, managedZone.Status)
+		}
+	} else {
+		log.FromContext(ctx).Info("Forcing deletion of ManagedZone", "zone", managedZone.Name, "namespace", managedZone.Namespace)
+	}
+
+	// the DS records of the zone are removed from its parent zone before
+	// the zone, which can't be resolved by validating resolvers otherwise
+	if err := r.deleteDSRecord(ctx, managedZone); err != nil {
+		return err
+	}
+	controllerutil.RemoveFinalizer(managedZone, ManagedZoneFinalizer)
+	return r.Update(ctx, managedZone)
+}
+
+// reconcileHandedOver leaves the DNSSEC signing and the DS records of a zone
+// handed over to another control plane to that control plane. On deletion
+// the zone is released without waiting for its DNSRecords, nor removing its
+// DS records from its parent zone, as the zone keeps being served
+func (r *ManagedZoneReconciler) reconcileHandedOver(ctx context.Context, previous, managedZone *v1.ManagedZone, hub string) error {
+	if managedZone.DeletionTimestamp != nil && !managedZone.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(managedZone, ManagedZoneFinalizer) {
+			return nil
+		}
+		log.FromContext(ctx).Info("Releasing ManagedZone handed over, keeping its records", "zone", managedZone.Name, "namespace", managedZone.Namespace, "handedOverTo", hub)
+		controllerutil.RemoveFinalizer(managedZone, ManagedZoneFinalizer)
+		return r.Update(ctx, managedZone)
+	}
+	conditions.Set(&managedZone.Status.Conditions, managedZone.Generation, v1.ManagedZoneHandedOverConditionType, metav1.ConditionTrue,
+		conditions.ReasonHandedOverByAnnotation, fmt.Sprintf("The zone is handed over to %s by the %s annotation", hub, metadata.AnnotationHandedOverTo))
+	return conditions.UpdateStatus(ctx, r.Client, managedZone, previous.Status, managedZone.Status)
+}
+
+// zoneRecords returns the names of the DNSRecords referencing the zone
+func (r *ManagedZoneReconciler) zoneRecords(ctx context.Context, managedZone *v1.ManagedZone) ([]string, error) {
+	records := &v1.DNSRecordList{}
+	if err := r.Client.List(ctx, records, client.InNamespace(managedZone.Namespace)); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, record := range records.Items {
+		if record.Spec.ManagedZoneRef != nil && record.Spec.ManagedZoneRef.Name == managedZone.Name {
+			names = append(names, record.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ManagedZoneReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ManagedZone{}).
+		Owns(&v1.DNSRecord{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.secretToZones)).
+		Watches(&source.Kind{Type: &v1.DNSRecord{}}, handler.EnqueueRequestsFromMapFunc(recordToZone)).
+		Complete(r)
+}
+
+// recordToZone maps a DNSRecord to the zone it references, so deletion of
+// the zone resumes once its records are removed
+func recordToZone(o client.Object) []reconcile.Request {
+	record, ok := o.(*v1.DNSRecord)
+	if !ok || record.Spec.ManagedZoneRef == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: record.Namespace, Name: record.Spec.ManagedZoneRef.Name}}}
+}
+
+// secretToZones maps a secret to the zones referencing it as the provider
+// credentials of their hosted zone or of one of their secondary zones
+func (r *ManagedZoneReconciler) secretToZones(o client.Object) []reconcile.Request {
+	zones := &v1.ManagedZoneList{}
+	if err := r.Client.List(context.Background(), zones, client.InNamespace(o.GetNamespace())); err != nil {
+		log.Log.Error(err, "Failed to list zones for secret", "secret", o.GetName(), "namespace", o.GetNamespace())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, zone := range zones.Items {
+		if referencesCredentials(&zone, o.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&zone)})
+		}
+	}
+	return requests
+}
+
+// referencesCredentials returns true when the zone or one of its secondary
+// zones references the secret as its provider credentials
+func referencesCredentials(zone *v1.ManagedZone, secret string) bool {
+	if zone.Spec.ProviderCredentialsRef != nil && zone.Spec.ProviderCredentialsRef.Name == secret {
+		return true
+	}
+	for _, secondary := range zone.Spec.SecondaryZones {
+		if secondary.ProviderCredentialsRef != nil && secondary.ProviderCredentialsRef.Name == secret {
+			return true
+		}
+	}
+	return false
+}
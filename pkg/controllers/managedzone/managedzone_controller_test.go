@@ -0,0 +1,145 @@
+package managedzone
+
+import (
+	"context"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func testScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+	return scheme
+}
+
+func deletedZone(mutate func(zone *v1.ManagedZone)) *v1.ManagedZone {
+	now := metav1.Now()
+	zone := &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "zone",
+			Namespace:         "argocd",
+			Finalizers:        []string{ManagedZoneFinalizer},
+			DeletionTimestamp: &now,
+		},
+		Spec: v1.ManagedZoneSpec{ID: "Z1", DomainName: "example.com"},
+	}
+	if mutate != nil {
+		mutate(zone)
+	}
+	return zone
+}
+
+func zoneRecord(name, zone string) *v1.DNSRecord {
+	return &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "argocd"},
+		Spec:       v1.DNSRecordSpec{ManagedZoneRef: &v1.ManagedZoneReference{Name: zone}},
+	}
+}
+
+func reconcileZone(ctx context.Context, t *testing.T, c client.Client) *v1.ManagedZone {
+	r := &ManagedZoneReconciler{Client: c, Scheme: c.Scheme()}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "argocd", Name: "zone"}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	zone := &v1.ManagedZone{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "zone"}, zone); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return nil
+	}
+	return zone
+}
+
+func TestManagedZoneReconciler_reconcileDeletion(t *testing.T) {
+	cases := []struct {
+		name          string
+		objects       []client.Object
+		expectZone    bool
+		expectMessage string
+	}{
+		{
+			name:          "deletion is blocked by the records of the zone",
+			objects:       []client.Object{deletedZone(nil), zoneRecord("b.example.com", "zone"), zoneRecord("a.example.com", "zone"), zoneRecord("other.example.com", "other")},
+			expectZone:    true,
+			expectMessage: `The zone still has DNSRecords: a.example.com, b.example.com. Delete them, or set the kuadrant.io/force-delete annotation to "true" to delete the zone anyway`,
+		},
+		{
+			name:    "zone without records is released",
+			objects: []client.Object{deletedZone(nil), zoneRecord("other.example.com", "other")},
+		},
+		{
+			name: "forced deletion releases the zone with records",
+			objects: []client.Object{deletedZone(func(zone *v1.ManagedZone) {
+				metadata.AddAnnotation(zone, metadata.AnnotationForceDelete, "true")
+			}), zoneRecord("a.example.com", "zone")},
+		},
+		{
+			name: "deletion is not forced by other annotation values",
+			objects: []client.Object{deletedZone(func(zone *v1.ManagedZone) {
+				metadata.AddAnnotation(zone, metadata.AnnotationForceDelete, "false")
+			}), zoneRecord("a.example.com", "zone")},
+			expectZone:    true,
+			expectMessage: `The zone still has DNSRecords: a.example.com. Delete them, or set the kuadrant.io/force-delete annotation to "true" to delete the zone anyway`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(tc.objects...).Build()
+
+			zone := reconcileZone(ctx, t, c)
+			if exists := zone != nil; exists != tc.expectZone {
+				t.Fatalf("expected zone '%v' got '%v'", tc.expectZone, exists)
+			}
+			if zone == nil {
+				return
+			}
+			condition := meta.FindStatusCondition(zone.Status.Conditions, v1.ManagedZoneDeletionBlockedConditionType)
+			if condition == nil {
+				t.Fatalf("expected condition '%v'", v1.ManagedZoneDeletionBlockedConditionType)
+			}
+			if condition.Reason != conditions.ReasonRecordsExist {
+				t.Errorf("expected '%v' got '%v'", conditions.ReasonRecordsExist, condition.Reason)
+			}
+			if condition.Message != tc.expectMessage {
+				t.Errorf("expected '%v' got '%v'", tc.expectMessage, condition.Message)
+			}
+		})
+	}
+}
+
+func TestManagedZoneReconciler_reconcileDeletionResumes(t *testing.T) {
+	ctx := context.Background()
+	record := zoneRecord("a.example.com", "zone")
+	c := fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(deletedZone(nil), record).Build()
+
+	zone := reconcileZone(ctx, t, c)
+	if zone == nil {
+		t.Fatalf("expected zone blocked by its records")
+	}
+	if len(zone.Finalizers) != 1 || zone.Finalizers[0] != ManagedZoneFinalizer {
+		t.Errorf("expected '%v' got '%v'", []string{ManagedZoneFinalizer}, zone.Finalizers)
+	}
+
+	if err := c.Delete(ctx, record); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if zone := reconcileZone(ctx, t, c); zone != nil {
+		t.Errorf("expected zone released got finalizers '%v'", zone.Finalizers)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	tlsservice "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+)
+
+type ClusterIdentityService interface {
+	EnsureClusterIdentity(ctx context.Context, cluster string, owner metav1.Object) error
+	GetClusterIdentitySecret(ctx context.Context, cluster string) (*corev1.Secret, error)
+}
+
+// rotatingCertificate holds the current client certificate of a cluster
+// identity. Transports built from it pick up renewed certificates on the next
+// TLS handshake, so long running cluster watchers don't need to be recreated
+// when cert-manager rotates the identity
+type rotatingCertificate struct {
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (c *rotatingCertificate) set(secret *corev1.Secret) error {
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return fmt.Errorf("invalid cluster identity in secret %s: %v", secret.Name, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	return nil
+}
+
+func (c *rotatingCertificate) get(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return c.cert, nil
+}
+
+// withClusterIdentity replaces the long lived credentials of the rest config
+// with a transport presenting the cluster identity certificate
+func withClusterIdentity(restConfig *rest.Config, cert *rotatingCertificate) (*rest.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		ServerName:           restConfig.TLSClientConfig.ServerName,
+		InsecureSkipVerify:   restConfig.TLSClientConfig.Insecure,
+		GetClientCertificate: cert.get,
+	}
+	if len(restConfig.TLSClientConfig.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(restConfig.TLSClientConfig.CAData) {
+			return nil, fmt.Errorf("unable to load CA data for cluster %s", restConfig.Host)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	identityConfig := rest.CopyConfig(restConfig)
+	identityConfig.BearerToken = ""
+	identityConfig.Username = ""
+	identityConfig.Password = ""
+	// TLS options can't be combined with a custom transport
+	identityConfig.TLSClientConfig = rest.TLSClientConfig{}
+	identityConfig.Transport = utilnet.SetTransportDefaults(&http.Transport{
+		TLSClientConfig: tlsConfig,
+	})
+	return identityConfig, nil
+}
+
+// identityRestConfig ensures the identity of the cluster has been issued and
+// returns a rest config using it. Returns nil if the identity secret doesn't
+// exist yet
+func (r *SecretReconciler) identityRestConfig(ctx context.Context, clusterSecret *corev1.Secret, restConfig *rest.Config) (*rest.Config, error) {
+	if err := r.ClusterIdentity.EnsureClusterIdentity(ctx, clusterSecret.Name, clusterSecret); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	identitySecret, err := r.ClusterIdentity.GetClusterIdentitySecret(ctx, clusterSecret.Name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	value, _ := r.identities.LoadOrStore(clusterSecret.Name, &rotatingCertificate{})
+	cert := value.(*rotatingCertificate)
+	if err := cert.set(identitySecret); err != nil {
+		return nil, err
+	}
+	return withClusterIdentity(restConfig, cert)
+}
+
+// identitySecretToCluster maps a cluster identity secret to the cluster secret
+// it was minted for, so rotated certificates are loaded as soon as they are
+// issued
+func identitySecretToCluster(obj client.Object) []reconcile.Request {
+	cluster, ok := obj.GetLabels()[tlsservice.LabelClusterIdentity]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: cluster}}}
+}
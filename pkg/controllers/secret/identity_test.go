@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// testCA signs identities the way the CA issuer of the hub does
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kuadrant.io cluster identity CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// identity returns the secret of an identity of the cluster, issued with a
+// new key as cert-manager does on every renewal
+func (ca *testCA) identity(t *testing.T, cluster string, serial int64) *corev1.Secret {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cluster},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-identity-" + cluster},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+}
+
+func TestWithClusterIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	other := newTestCA(t)
+
+	tests := []struct {
+		name string
+		// identities are loaded in turn, each followed by a request to the
+		// API server of the cluster
+		identities   []*corev1.Secret
+		expectSerial []int64
+	}{
+		{
+			name:         "identity not loaded yet rejected",
+			identities:   []*corev1.Secret{nil},
+			expectSerial: []int64{0},
+		},
+		{
+			name:         "identity issued by the hub CA accepted",
+			identities:   []*corev1.Secret{ca.identity(t, "cluster-1", 2)},
+			expectSerial: []int64{2},
+		},
+		{
+			name:         "renewed identity presented without recreating the config",
+			identities:   []*corev1.Secret{ca.identity(t, "cluster-1", 2), ca.identity(t, "cluster-1", 3)},
+			expectSerial: []int64{2, 3},
+		},
+		{
+			name:         "identity issued by another CA rejected",
+			identities:   []*corev1.Secret{other.identity(t, "cluster-1", 2)},
+			expectSerial: []int64{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Serial", req.TLS.PeerCertificates[0].SerialNumber.String())
+			}))
+			pool := x509.NewCertPool()
+			pool.AddCert(ca.cert)
+			server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+			// every request handshakes again, as the connections of a
+			// long running watch are eventually reestablished
+			server.Config.SetKeepAlivesEnabled(false)
+			server.StartTLS()
+			defer server.Close()
+
+			cert := &rotatingCertificate{}
+			restConfig, err := withClusterIdentity(&rest.Config{
+				Host:            server.URL,
+				BearerToken:     "static",
+				TLSClientConfig: rest.TLSClientConfig{CAData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})},
+			}, cert)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if restConfig.BearerToken != "" {
+				t.Errorf("expected static credentials removed got '%v'", restConfig.BearerToken)
+			}
+			httpClient := &http.Client{Transport: restConfig.Transport}
+
+			for i, identity := range tt.identities {
+				if identity != nil {
+					if err := cert.set(identity); err != nil {
+						t.Fatalf("unexpected error %v", err)
+					}
+				}
+				serial := "0"
+				if resp, err := httpClient.Get(server.URL); err == nil {
+					resp.Body.Close()
+					serial = resp.Header.Get("Serial")
+				}
+				if serial != strconv.FormatInt(tt.expectSerial[i], 10) {
+					t.Errorf("expected '%v' got '%v'", tt.expectSerial[i], serial)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+// reconcileMaintenance drains the endpoints of the cluster in the DNSRecords
+// ahead of its maintenance windows, and restores them once the windows end.
+// Returns how long until the cluster enters or leaves maintenance
+func (r *SecretReconciler) reconcileMaintenance(ctx context.Context, secret *corev1.Secret) (time.Duration, error) {
+	windows, err := clusterSecret.MaintenanceWindows(secret)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "ignoring invalid maintenance windows", "cluster", secret.Name)
+	}
+	drained, next := clusterSecret.InMaintenance(windows, time.Now(), dns.MaintenanceLeadTime)
+
+	records := &v1.DNSRecordList{}
+	if err := r.Client.List(ctx, records, client.InNamespace(secret.Namespace)); err != nil {
+		return 0, err
+	}
+	for i := range records.Items {
+		record := &records.Items[i]
+		if !dns.DrainClusterEndpoints(record, secret.Name, drained) {
+			continue
+		}
+		log.FromContext(ctx).Info("updating endpoints for cluster maintenance", "cluster", secret.Name, "record", record.Name, "drained", drained)
+		if err := r.Client.Update(ctx, record); err != nil {
+			return 0, err
+		}
+	}
+
+	if next.IsZero() {
+		return 0, nil
+	}
+	return time.Until(next), nil
+}
@@ -18,22 +18,26 @@ package secret
 
 import (
 	"context"
-	"net/url"
-	"strings"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/json"
-	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/cluster"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/rbac"
 )
 
 // SecretReconciler reconciles a Secret object
@@ -43,27 +47,25 @@ type SecretReconciler struct {
 	MCWatch multiClusterWatch.Interface
 
 	ClusterReconciler cluster.Reconciler
-}
 
-type TLSClientConfig struct {
-	Insecure bool   `json:"insecure"`
-	CaData   []byte `json:"caData,omitempty"`
-	CertData []byte `json:"certData,omitempty"`
-	KeyData  []byte `json:"keyData,omitempty"`
-}
+	// ClusterIdentity is optional. When set, workload clusters are accessed
+	// with a client certificate minted by the hub CA instead of the
+	// credentials stored in the cluster secret
+	ClusterIdentity ClusterIdentityService
 
-type ProviderConfig struct {
-	Command    string   `json:"command,omitempty"`
-	Args       []string `json:"args,omitempty"`
-	APIVersion string   `json:"apiVersion,omitempty"`
-}
+	// TokenExpiration is the lifetime of the scoped tokens requested for
+	// cluster secrets annotated with the service account to bind them to
+	TokenExpiration time.Duration
+
+	// AuditPermissions enables auditing the permissions granted in each
+	// workload cluster when it's added
+	AuditPermissions bool
 
-type ArgoClusterConfig struct {
-	BearerToken        string          `json:"bearerToken,omitempty"`
-	Username           string          `json:"username,omitempty"`
-	Password           string          `json:"password,omitempty"`
-	TlsClientConfig    TLSClientConfig `json:"tlsClientConfig,omitempty"`
-	ExecProviderConfig ProviderConfig  `json:"execProviderConfig,omitempty"`
+	// cluster name -> *rotatingCertificate
+	identities sync.Map
+	// cluster name -> labels of the cluster secret the traffic objects of
+	// the cluster were last reconciled with
+	clusterLabels sync.Map
 }
 
 const (
@@ -76,6 +78,7 @@ const (
 //+kubebuilder:rbac:groups="",resources=secrets/finalizers,verbs=update
 
 //+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=cert-manager.io,resources=issuers,verbs=get;list;watch;create
 
 //+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations,verbs=get;list;watch;create;update;patch;delete
 
@@ -98,45 +101,74 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 	secret := previous.DeepCopy()
-	log.Log.Info("new cluster added ", "name", secret.Name)
-	clusterClientConfig := &ArgoClusterConfig{}
-	err = json.Unmarshal(secret.Data["config"], clusterClientConfig)
+	log.FromContext(ctx).Info("new cluster added ", "name", secret.Name)
+
+	// maintenance doesn't depend on the cluster being reachable, as it
+	// might not be during the maintenance
+	maintenanceAfter, err := r.reconcileMaintenance(ctx, secret)
 	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to reconcile cluster maintenance", "cluster", secret.Name)
 		return ctrl.Result{}, err
 	}
 
-	hostUrl, err := url.Parse(string(secret.Data["server"]))
+	var refreshAfter time.Duration
+	if metadata.HasAnnotation(secret, clusterSecret.AnnotationTokenServiceAccount) {
+		refreshAfter, err = r.refreshScopedToken(ctx, secret)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to refresh cluster token", "cluster", secret.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	restConfig, err := clusterSecret.RestConfigFromSecret(r.Client, secret)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	restConfig := &rest.Config{
-		Host:        hostUrl.Host,
-		Username:    clusterClientConfig.Username,
-		Password:    clusterClientConfig.Password,
-		BearerToken: clusterClientConfig.BearerToken,
-		TLSClientConfig: rest.TLSClientConfig{
-			ServerName: strings.SplitN(hostUrl.Host, ":", 2)[0],
-			CertData:   clusterClientConfig.TlsClientConfig.CertData,
-			KeyData:    clusterClientConfig.TlsClientConfig.KeyData,
-			CAData:     clusterClientConfig.TlsClientConfig.CaData,
-		},
+	if r.ClusterIdentity != nil {
+		restConfig, err = r.identityRestConfig(ctx, secret, restConfig)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if restConfig == nil {
+			log.FromContext(ctx).Info("cluster identity not issued yet, requeue", "cluster", secret.Name)
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, nil
+		}
 	}
 
-	_, err = r.MCWatch.WatchCluster(restConfig)
+	watcher, err := r.MCWatch.WatchCluster(secret.Name, restConfig)
 	if err != nil {
-		log.Log.Info("error occurred", "error", err)
+		log.FromContext(ctx).Info("error occurred", "error", err)
 		return ctrl.Result{}, err
 	}
+	if r.labelsChanged(secret) {
+		log.FromContext(ctx).Info("cluster labels changed, reconciling traffic objects", "cluster", secret.Name)
+		watcher.Resync()
+	}
+
+	if r.AuditPermissions {
+		if _, err := rbac.AuditCluster(ctx, restConfig, secret.Name); err != nil {
+			log.FromContext(ctx).Error(err, "failed to audit cluster permissions", "cluster", secret.Name)
+		}
+	}
+
+	if err := r.reconcileVersions(ctx, secret, restConfig); err != nil {
+		log.FromContext(ctx).Error(err, "failed to detect cluster versions", "cluster", secret.Name)
+	}
 
 	result, err := r.ClusterReconciler.Reconcile(ctx, cluster.Object{
 		Name:       secret.Name,
 		RestConfig: restConfig,
 	})
 	if err != nil {
-		log.Log.Error(err, "failed to reconcile cluster")
+		log.FromContext(ctx).Error(err, "failed to reconcile cluster")
 		return ctrl.Result{}, err
 	}
+	for _, after := range []time.Duration{refreshAfter, maintenanceAfter} {
+		if after > 0 && (!result.Requeue || after < result.RequeueAfter) {
+			result = ctrl.Result{Requeue: true, RequeueAfter: after}
+		}
+	}
 	if result.Requeue {
 		return result, nil
 	}
@@ -144,11 +176,19 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	return ctrl.Result{}, nil
 }
 
+// labelsChanged records the labels of the cluster secret, returning true
+// when they changed since the secret was last reconciled, so placement
+// decisions based on them are updated without waiting for a resync
+func (r *SecretReconciler) labelsChanged(secret *corev1.Secret) bool {
+	previous, loaded := r.clusterLabels.Load(secret.Name)
+	r.clusterLabels.Store(secret.Name, secret.GetLabels())
+	return loaded && !equality.Semantic.DeepEqual(previous, secret.GetLabels())
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		WithEventFilter(predicate.Funcs{
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Secret{}, builder.WithPredicates(predicate.Funcs{
 			CreateFunc: func(e event.CreateEvent) bool {
 				return metadata.HasLabel(e.Object, CLUSTER__SECRET_LABEL) && e.Object.GetLabels()[CLUSTER__SECRET_LABEL] == ARGO_CLUSTER_LABEL_VALUE
 			},
@@ -158,6 +198,9 @@ func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			UpdateFunc: func(e event.UpdateEvent) bool {
 				return metadata.HasLabel(e.ObjectNew, CLUSTER__SECRET_LABEL) && e.ObjectNew.GetLabels()[CLUSTER__SECRET_LABEL] == ARGO_CLUSTER_LABEL_VALUE
 			},
-		}).
-		Complete(r)
+		}))
+	if r.ClusterIdentity != nil {
+		b = b.Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(identitySecretToCluster))
+	}
+	return b.Complete(r)
 }
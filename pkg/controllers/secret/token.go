@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+)
+
+const defaultTokenExpiration = time.Hour
+
+// refreshScopedToken requests a new bound token for the service account
+// referenced by the cluster secret when the current one is close to expiring.
+// The token is requested with the credentials of the config of the secret,
+// and stored apart from them: only the token is replaced on refresh, and the
+// cluster is accessed with it instead of the credentials of the config.
+// Returns how long until the token has to be refreshed again
+func (r *SecretReconciler) refreshScopedToken(ctx context.Context, secret *corev1.Secret) (time.Duration, error) {
+	expiration := r.TokenExpiration
+	if expiration == 0 {
+		expiration = defaultTokenExpiration
+	}
+	// refresh once two thirds of the lifetime have passed, leaving time for
+	// clients to reload the token before the previous one is rejected
+	refreshBefore := expiration / 3
+
+	if expiry, err := time.Parse(time.RFC3339, metadata.GetAnnotation(secret, clusterSecret.AnnotationTokenExpiry)); err == nil && clusterSecret.HasScopedToken(secret) {
+		if remaining := time.Until(expiry); remaining > refreshBefore {
+			return remaining - refreshBefore, nil
+		}
+	}
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(metadata.GetAnnotation(secret, clusterSecret.AnnotationTokenServiceAccount))
+	if err != nil || namespace == "" || name == "" {
+		return 0, fmt.Errorf("invalid service account %q, expected namespace/name", metadata.GetAnnotation(secret, clusterSecret.AnnotationTokenServiceAccount))
+	}
+
+	restConfig, err := clusterSecret.ConfiguredRestConfigFromSecret(secret)
+	if err != nil {
+		return 0, err
+	}
+	workloadClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return 0, err
+	}
+	expirationSeconds := int64(expiration.Seconds())
+	tokenRequest, err := workloadClient.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to request token for service account %s/%s: %v", namespace, name, err)
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[clusterSecret.ScopedTokenKey] = []byte(tokenRequest.Status.Token)
+	expiry := tokenRequest.Status.ExpirationTimestamp.Time
+	metadata.AddAnnotation(secret, clusterSecret.AnnotationTokenExpiry, expiry.UTC().Format(time.RFC3339))
+	if err := r.Update(ctx, secret); err != nil {
+		return 0, err
+	}
+	log.FromContext(ctx).Info("refreshed cluster token", "cluster", secret.Name, "expiry", expiry)
+
+	return time.Until(expiry) - refreshBefore, nil
+}
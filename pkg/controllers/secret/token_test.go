@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+)
+
+func TestRefreshScopedToken(t *testing.T) {
+	staticConfig := []byte(`{"bearerToken":"static"}`)
+
+	tests := []struct {
+		name          string
+		scopedToken   string
+		expiry        time.Duration
+		expectRequest bool
+		expectToken   string
+	}{
+		{
+			name:          "token requested with the configured credentials",
+			expectRequest: true,
+			expectToken:   "requested",
+		},
+		{
+			name:        "token not close to expiring kept",
+			scopedToken: "current",
+			expiry:      time.Hour,
+			expectToken: "current",
+		},
+		{
+			name:          "token close to expiring refreshed with the configured credentials",
+			scopedToken:   "current",
+			expiry:        time.Minute,
+			expectRequest: true,
+			expectToken:   "requested",
+		},
+		{
+			name: "token stored in the config by previous versions requested",
+			// the expiry was recorded but the token overwrote the config
+			expiry:        time.Hour,
+			expectRequest: true,
+			expectToken:   "requested",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				requested = true
+				if req.URL.Path != "/api/v1/namespaces/kuadrant/serviceaccounts/mctc/token" || req.Header.Get("Authorization") != "Bearer static" {
+					t.Errorf("unexpected token request %s with '%s'", req.URL.Path, req.Header.Get("Authorization"))
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(&authenticationv1.TokenRequest{
+					TypeMeta: metav1.TypeMeta{APIVersion: "authentication.k8s.io/v1", Kind: "TokenRequest"},
+					Status: authenticationv1.TokenRequestStatus{
+						Token:               "requested",
+						ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+					},
+				})
+			}))
+			defer server.Close()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster-1",
+					Namespace:   "argocd",
+					Annotations: map[string]string{clusterSecret.AnnotationTokenServiceAccount: "kuadrant/mctc"},
+				},
+				Data: map[string][]byte{"server": []byte(server.URL), "config": staticConfig},
+			}
+			if tt.scopedToken != "" {
+				secret.Data[clusterSecret.ScopedTokenKey] = []byte(tt.scopedToken)
+			}
+			if tt.expiry != 0 {
+				secret.Annotations[clusterSecret.AnnotationTokenExpiry] = time.Now().Add(tt.expiry).UTC().Format(time.RFC3339)
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+			r := &SecretReconciler{Client: c}
+
+			refreshAfter, err := r.refreshScopedToken(context.TODO(), secret)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if requested != tt.expectRequest {
+				t.Errorf("expected request '%v' got '%v'", tt.expectRequest, requested)
+			}
+			if refreshAfter <= 0 || refreshAfter > time.Hour {
+				t.Errorf("expected refresh within the lifetime of the token got '%v'", refreshAfter)
+			}
+
+			if err := c.Get(context.TODO(), client.ObjectKeyFromObject(secret), secret); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if string(secret.Data["config"]) != string(staticConfig) {
+				t.Errorf("expected configured credentials kept got '%s'", secret.Data["config"])
+			}
+			if string(secret.Data[clusterSecret.ScopedTokenKey]) != tt.expectToken {
+				t.Errorf("expected '%v' got '%s'", tt.expectToken, secret.Data[clusterSecret.ScopedTokenKey])
+			}
+			restConfig, err := clusterSecret.RestConfigFromSecret(c, secret)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if restConfig.BearerToken != "" || restConfig.Username != "" || restConfig.Password != "" || restConfig.WrapTransport == nil {
+				t.Errorf("expected cluster accessed with the scoped token only got '%v'", restConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+)
+
+// reconcileVersions records the Kubernetes and Gateway API versions
+// detected in the cluster on its secret, for placements to be gated on
+func (r *SecretReconciler) reconcileVersions(ctx context.Context, secret *corev1.Secret, restConfig *rest.Config) error {
+	versions, err := clusterSecret.DetectVersions(ctx, restConfig)
+	if err != nil {
+		return err
+	}
+	if !clusterSecret.SetVersions(secret, versions) {
+		return nil
+	}
+	log.FromContext(ctx).Info("cluster versions changed", "cluster", secret.Name, "kubernetes", versions.Kubernetes, "gatewayAPI", versions.GatewayAPI)
+	return r.Update(ctx, secret)
+}
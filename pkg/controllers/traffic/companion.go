@@ -0,0 +1,107 @@
+package traffic
+
+import (
+	"context"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/companion"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// annotationCompanions lists on a traffic object the companion objects
+// applied for it, as comma separated references
+const annotationCompanions = "kuadrant.io/companions"
+
+// CompanionRenderer renders the companion objects of a traffic object in
+// a cluster
+type CompanionRenderer interface {
+	Render(ctx context.Context, t traffic.Interface, cluster string) ([]*unstructured.Unstructured, error)
+}
+
+// ensureCompanions applies the companion objects rendered for the traffic
+// object in the namespace of the traffic object, and removes the ones not
+// rendered anymore. The objects applied are left as they are while the
+// templates fail to render
+func (r *Reconciler) ensureCompanions(ctx context.Context, trafficAccessor traffic.Interface) error {
+	if r.Companions == nil {
+		return nil
+	}
+	objects, err := r.Companions.Render(ctx, trafficAccessor, r.Cluster)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to render companion objects, keeping the applied ones", "class", trafficAccessor.GetClassName())
+		return nil
+	}
+	applied := []string{}
+	for _, object := range objects {
+		metadata.AddAnnotation(object, annotationTrafficOwner, trafficOwner(trafficAccessor))
+		log.FromContext(ctx).V(3).Info("applying companion object", "object", companion.Reference(object))
+		if err := r.WorkloadClient.Patch(ctx, object, client.Apply, client.FieldOwner(traffic.FieldManager), client.ForceOwnership); err != nil {
+			return err
+		}
+		applied = append(applied, companion.Reference(object))
+	}
+	for _, reference := range companionReferences(trafficAccessor) {
+		if slice.ContainsString(applied, reference) {
+			continue
+		}
+		if err := r.removeCompanion(ctx, trafficAccessor, reference); err != nil {
+			return err
+		}
+	}
+	if len(applied) == 0 {
+		metadata.RemoveAnnotation(trafficAccessor, annotationCompanions)
+	} else {
+		metadata.AddAnnotation(trafficAccessor, annotationCompanions, strings.Join(applied, ","))
+	}
+	return nil
+}
+
+// removeCompanions removes the companion objects applied for the traffic
+// object
+func (r *Reconciler) removeCompanions(ctx context.Context, trafficAccessor traffic.Interface) error {
+	for _, reference := range companionReferences(trafficAccessor) {
+		if err := r.removeCompanion(ctx, trafficAccessor, reference); err != nil {
+			return err
+		}
+	}
+	metadata.RemoveAnnotation(trafficAccessor, annotationCompanions)
+	return nil
+}
+
+// removeCompanion removes the referenced companion object, unless it was
+// applied for another traffic object since
+func (r *Reconciler) removeCompanion(ctx context.Context, trafficAccessor traffic.Interface, reference string) error {
+	object, err := companion.ParseReference(reference, trafficAccessor.GetNamespace())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "ignoring invalid companion object reference")
+		return nil
+	}
+	if err := r.WorkloadClient.Get(ctx, client.ObjectKeyFromObject(object), object); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if metadata.GetAnnotation(object, annotationTrafficOwner) != trafficOwner(trafficAccessor) {
+		return nil
+	}
+	log.FromContext(ctx).Info("removing companion object", "object", reference)
+	return client.IgnoreNotFound(r.WorkloadClient.Delete(ctx, object))
+}
+
+func companionReferences(trafficAccessor traffic.Interface) []string {
+	references := []string{}
+	for _, reference := range strings.Split(metadata.GetAnnotation(trafficAccessor, annotationCompanions), ",") {
+		if reference = strings.TrimSpace(reference); reference != "" {
+			references = append(references, reference)
+		}
+	}
+	return references
+}
@@ -0,0 +1,122 @@
+package traffic
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kuadrantv1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// The Multi-Cluster Services API kinds, used unstructured as the API is
+// only served in clusters with an MCS implementation installed
+var (
+	serviceExportGVK = schema.GroupVersionKind{Group: "multicluster.x-k8s.io", Version: "v1alpha1", Kind: "ServiceExport"}
+	serviceImportGVK = schema.GroupVersionKind{Group: "multicluster.x-k8s.io", Version: "v1alpha1", Kind: "ServiceImport"}
+)
+
+// serviceImport returns the ServiceImport of the service when it's exported
+// with a ServiceExport, creating it when the MCS implementation hasn't yet.
+// Returns nil when the service isn't exported or the cluster doesn't serve
+// the MCS API
+func (r *Reconciler) serviceImport(ctx context.Context, service *v1.Service) (*unstructured.Unstructured, error) {
+	key := client.ObjectKeyFromObject(service)
+	export := &unstructured.Unstructured{}
+	export.SetGroupVersionKind(serviceExportGVK)
+	if err := r.WorkloadClient.Get(ctx, key, export); err != nil {
+		if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	serviceImport := &unstructured.Unstructured{}
+	serviceImport.SetGroupVersionKind(serviceImportGVK)
+	err := r.WorkloadClient.Get(ctx, key, serviceImport)
+	if err == nil {
+		return serviceImport, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	serviceImport = newServiceImport(service)
+	log.FromContext(ctx).Info("creating ServiceImport for exported backend service", "service", key)
+	if err := r.WorkloadClient.Create(ctx, serviceImport); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	return serviceImport, nil
+}
+
+// newServiceImport returns the ServiceImport exposing the ports of the
+// service across the cluster set
+func newServiceImport(service *v1.Service) *unstructured.Unstructured {
+	importType := "ClusterSetIP"
+	if service.Spec.ClusterIP == v1.ClusterIPNone {
+		importType = "Headless"
+	}
+	ports := []interface{}{}
+	for _, port := range service.Spec.Ports {
+		ports = append(ports, map[string]interface{}{
+			"name":     port.Name,
+			"protocol": string(port.Protocol),
+			"port":     int64(port.Port),
+		})
+	}
+	serviceImport := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"type":  importType,
+			"ports": ports,
+		},
+	}}
+	serviceImport.SetGroupVersionKind(serviceImportGVK)
+	serviceImport.SetNamespace(service.Namespace)
+	serviceImport.SetName(service.Name)
+	return serviceImport
+}
+
+// serviceImportIPs returns the cluster set IPs of the ServiceImport
+func serviceImportIPs(serviceImport *unstructured.Unstructured) []string {
+	ips, _, _ := unstructured.NestedStringSlice(serviceImport.Object, "spec", "ips")
+	return ips
+}
+
+// meshTargets returns the cluster set IPs of the ServiceImports of the
+// backend services of the traffic object exported with a ServiceExport,
+// preferred in private zones over the private addresses of its load
+// balancers or ingresses as they're reachable from the fleet through the
+// MCS implementation, e.g. Submariner or Cilium ClusterMesh
+func (r *Reconciler) meshTargets(ctx context.Context, trafficAccessor traffic.Interface) ([]kuadrantv1.Target, error) {
+	targets := []kuadrantv1.Target{}
+	for _, name := range trafficAccessor.GetBackendServices() {
+		service := &v1.Service{}
+		if err := r.WorkloadClient.Get(ctx, client.ObjectKey{Namespace: trafficAccessor.GetNamespace(), Name: name}, service); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		serviceImport, err := r.serviceImport(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+		if serviceImport == nil {
+			continue
+		}
+		for _, ip := range serviceImportIPs(serviceImport) {
+			targets = append(targets, kuadrantv1.Target{
+				TargetType: kuadrantv1.TargetTypeIP,
+				Value:      ip,
+				Weight:     1,
+			})
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,213 @@
+package traffic
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// fleetPolicyPrefix prefixes the name of the NetworkPolicy restricting
+	// a backend service of a private traffic object to the fleet
+	fleetPolicyPrefix = "kuadrant-fleet-"
+	// annotationTrafficOwner identifies the traffic object a companion
+	// object was applied for
+	annotationTrafficOwner = "kuadrant.io/traffic"
+	// annotationTrafficOwners lists on a fleet NetworkPolicy the private
+	// traffic objects it was applied for, comma separated
+	annotationTrafficOwners = "kuadrant.io/traffic-owners"
+)
+
+// ensureFleetPolicies restricts the backend services of private traffic
+// objects to traffic from the fleet CIDRs, see syncFleetPolicy
+func (r *Reconciler) ensureFleetPolicies(ctx context.Context, trafficAccessor traffic.Interface) error {
+	for _, name := range trafficAccessor.GetBackendServices() {
+		if err := r.syncFleetPolicy(ctx, trafficAccessor.GetNamespace(), name, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeFleetPolicies syncs the fleet NetworkPolicies of the backend
+// services of the traffic object without it, so the restrictions applied
+// for the other private traffic objects sharing the services are kept
+func (r *Reconciler) removeFleetPolicies(ctx context.Context, trafficAccessor traffic.Interface) error {
+	for _, name := range trafficAccessor.GetBackendServices() {
+		if err := r.syncFleetPolicy(ctx, trafficAccessor.GetNamespace(), name, trafficOwner(trafficAccessor)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncFleetPolicy applies the NetworkPolicy of the service from the Ingresses
+// routing to it, the excluded one left out. Only the target ports the
+// private Ingresses route to are restricted to the fleet CIDRs, the other
+// ports of the service stay open to any source. A port a public Ingress
+// routes to isn't restricted, as the traffic of the public Ingress would be
+// denied too. The policy lists the private Ingresses it was applied for,
+// and is removed once no port is restricted
+func (r *Reconciler) syncFleetPolicy(ctx context.Context, namespace, name, excluded string) error {
+	key := client.ObjectKey{Namespace: namespace, Name: fleetPolicyPrefix + name}
+	service := &v1.Service{}
+	if err := r.WorkloadClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, service); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return r.removeFleetPolicy(ctx, key)
+		}
+		return err
+	}
+	cidrs := r.Config.Get().FleetCIDRs
+	if len(service.Spec.Selector) == 0 || len(cidrs) == 0 {
+		return r.removeFleetPolicy(ctx, key)
+	}
+
+	ingresses := &networkingv1.IngressList{}
+	if err := r.WorkloadClient.List(ctx, ingresses, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	owners := []string{}
+	private := []v1.ServicePort{}
+	public := []v1.ServicePort{}
+	for i := range ingresses.Items {
+		ingress := traffic.NewIngress(&ingresses.Items[i])
+		if ingress.GetDeletionTimestamp() != nil || trafficOwner(ingress) == excluded {
+			continue
+		}
+		ports := backendPorts(&ingresses.Items[i], service)
+		if len(ports) == 0 {
+			continue
+		}
+		if !traffic.Private(ingress) {
+			public = append(public, ports...)
+			continue
+		}
+		owners = append(owners, trafficOwner(ingress))
+		private = append(private, ports...)
+	}
+
+	restricted := []v1.ServicePort{}
+	open := []v1.ServicePort{}
+	for _, port := range service.Spec.Ports {
+		if containsPort(private, port) && !containsPort(public, port) {
+			restricted = append(restricted, port)
+		} else {
+			open = append(open, port)
+		}
+	}
+	if len(restricted) == 0 {
+		return r.removeFleetPolicy(ctx, key)
+	}
+	sort.Strings(owners)
+	policy := fleetPolicy(service, cidrs, restricted, open, owners)
+	log.FromContext(ctx).V(3).Info("restricting backend service of private traffic objects to the fleet", "service", name, "cidrs", cidrs, "owners", owners)
+	return r.WorkloadClient.Patch(ctx, policy, client.Apply, client.FieldOwner(traffic.FieldManager), client.ForceOwnership)
+}
+
+func (r *Reconciler) removeFleetPolicy(ctx context.Context, key client.ObjectKey) error {
+	policy := &networkingv1.NetworkPolicy{}
+	if err := r.WorkloadClient.Get(ctx, key, policy); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).Info("removing fleet restriction of backend service", "policy", key.Name)
+	return client.IgnoreNotFound(r.WorkloadClient.Delete(ctx, policy))
+}
+
+func trafficOwner(trafficAccessor traffic.Interface) string {
+	return trafficAccessor.GetKind() + "/" + trafficAccessor.GetCacheKey()
+}
+
+// backendPorts returns the ports of the service the Ingress routes to
+func backendPorts(ingress *networkingv1.Ingress, service *v1.Service) []v1.ServicePort {
+	ports := []v1.ServicePort{}
+	addBackend := func(backend *networkingv1.IngressBackend) {
+		if backend == nil || backend.Service == nil || backend.Service.Name != service.Name {
+			return
+		}
+		for _, port := range service.Spec.Ports {
+			if (backend.Service.Port.Name != "" && backend.Service.Port.Name == port.Name) ||
+				(backend.Service.Port.Number != 0 && backend.Service.Port.Number == port.Port) {
+				ports = append(ports, port)
+			}
+		}
+	}
+	addBackend(ingress.Spec.DefaultBackend)
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			addBackend(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+	return ports
+}
+
+func containsPort(ports []v1.ServicePort, port v1.ServicePort) bool {
+	for _, p := range ports {
+		if p.Port == port.Port && p.Protocol == port.Protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// fleetPolicy returns the NetworkPolicy only admitting traffic from the
+// fleet CIDRs to the restricted ports of the pods of the service, and
+// traffic from any source to its open ports
+func fleetPolicy(service *v1.Service, cidrs []string, restricted, open []v1.ServicePort, owners []string) *networkingv1.NetworkPolicy {
+	peers := make([]networkingv1.NetworkPolicyPeer, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+	}
+	rules := []networkingv1.NetworkPolicyIngressRule{{From: peers, Ports: policyPorts(restricted)}}
+	if len(open) > 0 {
+		rules = append(rules, networkingv1.NetworkPolicyIngressRule{Ports: policyPorts(open)})
+	}
+	return &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fleetPolicyPrefix + service.Name,
+			Namespace:   service.Namespace,
+			Annotations: map[string]string{annotationTrafficOwners: strings.Join(owners, ",")},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: service.Spec.Selector},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     rules,
+		},
+	}
+}
+
+// policyPorts returns the ports of the pods the service ports target
+func policyPorts(ports []v1.ServicePort) []networkingv1.NetworkPolicyPort {
+	policyPorts := []networkingv1.NetworkPolicyPort{}
+	targets := []string{}
+	for _, port := range ports {
+		target := port.TargetPort
+		if target.IntVal == 0 && target.StrVal == "" {
+			target = intstr.FromInt(int(port.Port))
+		}
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = v1.ProtocolTCP
+		}
+		if slice.ContainsString(targets, string(protocol)+"/"+target.String()) {
+			continue
+		}
+		targets = append(targets, string(protocol)+"/"+target.String())
+		policyPorts = append(policyPorts, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &target})
+	}
+	return policyPorts
+}
@@ -18,6 +18,7 @@ package traffic
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -28,8 +29,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
 	kuadrantv1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/policy"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,6 +44,16 @@ import (
 
 const (
 	trafficFinalizer = "kuadrant.io/traffic-management"
+	// backendsRecheckInterval is how often a cluster without backends is
+	// checked again, as services are not watched
+	backendsRecheckInterval = time.Minute
+	// evacuationRecheckInterval is how often the traffic objects of an
+	// evacuated or tainted cluster are checked again, so their endpoints are
+	// published once the evacuation ends or the taint is removed
+	evacuationRecheckInterval = time.Minute
+	// policyRecheckInterval is how often a traffic object denied by a policy
+	// is checked again, as TrafficPolicies are not watched
+	policyRecheckInterval = time.Minute
 )
 
 // Reconciler reconciles a traffic object
@@ -44,33 +61,65 @@ type Reconciler struct {
 	WorkloadClient client.Client
 	Hosts          HostService
 	Certificates   CertificateService
+	// Config holds whether plain HTTP requests are redirected to HTTPS once
+	// TLS is provisioned for a managed host
+	Config *config.Store
+	// Policies are evaluated against the traffic object once its managed
+	// hosts are assigned. Optional
+	Policies policy.Evaluator
+	// Companions renders the companion objects applied with the traffic
+	// objects of the cluster. Optional
+	Companions CompanionRenderer
+	// Cluster is the name of the cluster secret of the workload cluster
+	Cluster string
 }
 
 type HostService interface {
 	EnsureManagedHost(ctx context.Context, t traffic.Interface) ([]string, []*kuadrantv1.DNSRecord, error)
-	AddEndPoints(ctx context.Context, t traffic.Interface) error
+	AddEndPoints(ctx context.Context, t traffic.Interface, meshTargets []kuadrantv1.Target) error
 	RemoveEndpoints(ctx context.Context, t traffic.Interface) error
+	WithdrawEndpoints(ctx context.Context, t traffic.Interface) error
+	EnsureCAA(ctx context.Context, record *kuadrantv1.DNSRecord) error
+	CertificateHost(ctx context.Context, record *kuadrantv1.DNSRecord) (string, *kuadrantv1.DNSRecord, error)
+	RecordSynced(ctx context.Context, t traffic.Interface, host, version string) error
+	ValidateManagedZone(ctx context.Context, t traffic.Interface) error
+	ValidateHostCollisions(ctx context.Context, t traffic.Interface) ([]string, error)
+	ValidateReservedHosts(ctx context.Context, t traffic.Interface) error
 }
 
 type CertificateService interface {
 	EnsureCertificate(ctx context.Context, host string, owner metav1.Object) error
 	GetCertificateSecret(ctx context.Context, host string) (*v1.Secret, error)
+	IssuanceFailed(ctx context.Context, host string) (bool, error)
+	// RecordContentHash records the content hash of the TLS secret on the
+	// secret, returning it
+	RecordContentHash(ctx context.Context, secret *v1.Secret) (string, error)
 }
 
 func (r *Reconciler) Handle(ctx context.Context, o runtime.Object) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 	trafficAccessor := o.(traffic.Interface)
-	log.Log.Info("got traffic object", "kind", trafficAccessor.GetKind(), "name", trafficAccessor.GetName(), "namespace", trafficAccessor.GetNamespace())
-	controllerutil.AddFinalizer(trafficAccessor, trafficFinalizer)
-	// TODO add in deletion logic
+	log.FromContext(ctx).Info("got traffic object", "kind", trafficAccessor.GetKind(), "name", trafficAccessor.GetName(), "namespace", trafficAccessor.GetNamespace())
+	// deletion isn't paused, so paused objects can still be deleted
 	if trafficAccessor.GetDeletionTimestamp() != nil && !trafficAccessor.GetDeletionTimestamp().IsZero() {
 		// targets, err := trafficAccessor.GetDNSTargets()
 		if err := r.Hosts.RemoveEndpoints(ctx, trafficAccessor); err != nil {
 			return ctrl.Result{}, err
 		}
+		if err := r.removeFleetPolicies(ctx, trafficAccessor); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.removeCompanions(ctx, trafficAccessor); err != nil {
+			return ctrl.Result{}, err
+		}
 		controllerutil.RemoveFinalizer(trafficAccessor, trafficFinalizer)
 		return ctrl.Result{}, nil
 	}
+	if metadata.IsPaused(trafficAccessor) {
+		log.FromContext(ctx).Info("reconciliation of traffic object is paused", "kind", trafficAccessor.GetKind(), "name", trafficAccessor.GetName(), "namespace", trafficAccessor.GetNamespace())
+		return ctrl.Result{}, nil
+	}
+	controllerutil.AddFinalizer(trafficAccessor, trafficFinalizer)
 
 	// verify host is correct
 	// no managed host assigned assign one
@@ -79,65 +128,265 @@ func (r *Reconciler) Handle(ctx context.Context, o runtime.Object) (ctrl.Result,
 	if err != nil && err != dns.AlreadyAssignedErr {
 		return ctrl.Result{}, err
 	}
-	for i, managedHost := range managedHosts {
-		record := records[i]
-		log.Log.Info("managed record ", "record", managedHost)
+	for _, managedHost := range managedHosts {
 		if err := trafficAccessor.AddManagedHost(managedHost); err != nil {
 			return ctrl.Result{}, err
 		}
-		// create certificate resource for assigned host
-		log.Log.Info("host assigned ensuring certificate in place")
-		if err := r.Certificates.EnsureCertificate(ctx, managedHost, record); err != nil && !k8serrors.IsAlreadyExists(err) {
+	}
+	denied, err := r.evaluatePolicies(ctx, trafficAccessor, records)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if denied {
+		log.FromContext(ctx).Info("traffic object denied by policy, withdrawing dns endpoints", "violations", metadata.GetAnnotation(trafficAccessor, policy.AnnotationViolations))
+		if err := r.Hosts.WithdrawEndpoints(ctx, trafficAccessor); err != nil {
 			return ctrl.Result{}, err
 		}
-		// when certificate ready copy secret (need to add event handler for certs)
-		// only once certificate is ready update DNS based status of ingress
-		secret, err := r.Certificates.GetCertificateSecret(ctx, managedHost)
-		if err != nil && !k8serrors.IsNotFound(err) {
+		return ctrl.Result{Requeue: true, RequeueAfter: policyRecheckInterval}, nil
+	}
+	if err := r.ensureFleetPolicies(ctx, trafficAccessor); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.ensureCompanions(ctx, trafficAccessor); err != nil {
+		return ctrl.Result{}, err
+	}
+	if traffic.DNSDisabled(trafficAccessor) {
+		// the traffic object brings its own DNS, the endpoints published
+		// while DNS was managed are withdrawn
+		if err := r.Hosts.WithdrawEndpoints(ctx, trafficAccessor); err != nil {
 			return ctrl.Result{}, err
 		}
-		// if err is not exists return and wait
-		if err != nil {
-			log.Log.Info("tls secret does not exist yet for host " + managedHost + " requeue")
-			return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, nil
+	}
+	tlsPending := false
+	// the records in private zones are left without endpoints when the
+	// traffic object has no address for them, which is flagged on it
+	unaddressed := false
+	for i, managedHost := range managedHosts {
+		record := records[i]
+		log.FromContext(ctx).Info("managed record ", "record", managedHost)
+		if traffic.TLSDisabled(trafficAccessor) {
+			log.FromContext(ctx).Info("TLS management disabled, skipping certificate", "host", managedHost)
+		} else {
+			certificateHost, ready, err := r.ensureTLS(ctx, trafficAccessor, managedHost, record)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			// only once certificate is ready update DNS based status of ingress,
+			// unless the host must resolve to the clusters for HTTP-01
+			// challenges to be answered
+			if !ready {
+				failed, err := r.Certificates.IssuanceFailed(ctx, certificateHost)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if failed {
+					log.FromContext(ctx).Info("certificate issuance failed for host, retrying later", "host", managedHost, "certificateHost", certificateHost, "after", tls.IssuanceRetryInterval)
+					return ctrl.Result{Requeue: true, RequeueAfter: tls.IssuanceRetryInterval}, nil
+				}
+				if !r.Config.Get().Enabled(features.HTTP01Challenges) {
+					log.FromContext(ctx).Info("tls secret does not exist yet for host " + managedHost + " requeue")
+					return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, nil
+				}
+				log.FromContext(ctx).Info("tls secret does not exist yet for host, adding dns endpoints for HTTP-01 challenges", "host", managedHost)
+				tlsPending = true
+			}
+		}
+
+		if traffic.DNSDisabled(trafficAccessor) {
+			log.FromContext(ctx).Info("DNS management disabled, skipping dns endpoints", "host", managedHost)
+			continue
 		}
-		log.Log.Info("certificate exists for host", "host", managedHost)
 
-		//copy secret
-		if secret != nil {
-			if err := r.copySecretToWorkloadCluster(ctx, trafficAccessor, secret, managedHost); err != nil {
+		if r.Config.Get().Enabled(features.BackendPlacement) {
+			hasBackends, err := r.hasBackends(ctx, trafficAccessor)
+			if err != nil {
 				return ctrl.Result{}, err
 			}
-			trafficAccessor.AddTLS(managedHost, secret)
+			if !hasBackends {
+				log.FromContext(ctx).Info("no backend services in the cluster, withdrawing dns endpoints", "host", managedHost, "backends", trafficAccessor.GetBackendServices())
+				if err := r.Hosts.WithdrawEndpoints(ctx, trafficAccessor); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{Requeue: true, RequeueAfter: backendsRecheckInterval}, nil
+			}
 		}
 
-		log.Log.Info("certificate secret in place for  host adding dns endpoints", "host", managedHost)
-		if err := r.Hosts.AddEndPoints(ctx, trafficAccessor); err != nil {
+		meshTargets, err := r.meshTargets(ctx, trafficAccessor)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		log.FromContext(ctx).Info("certificate secret in place for  host adding dns endpoints", "host", managedHost)
+		err = r.Hosts.AddEndPoints(ctx, trafficAccessor, meshTargets)
+		if err == dns.NoPrivateAddressesErr {
+			log.FromContext(ctx).Info("no addresses for the private zones, flagging traffic object", "host", managedHost)
+			unaddressed = true
+			continue
+		}
+		if err != nil {
+			if err == dns.ClusterEvacuatedErr {
+				log.FromContext(ctx).Info("cluster evacuated, dns endpoints withdrawn", "host", managedHost)
+				return ctrl.Result{Requeue: true, RequeueAfter: evacuationRecheckInterval}, nil
+			}
+			if err == dns.ClusterTaintedErr {
+				log.FromContext(ctx).Info("cluster taint not tolerated, dns endpoints withdrawn", "host", managedHost)
+				return ctrl.Result{Requeue: true, RequeueAfter: evacuationRecheckInterval}, nil
+			}
+			var conflictErr *dns.RoutingConflictError
+			if err == dns.LatencyDisabledErr || errors.As(err, &conflictErr) {
+				log.FromContext(ctx).Info("dns endpoints not published", "host", managedHost, "reason", err.Error())
+				return ctrl.Result{Requeue: true, RequeueAfter: evacuationRecheckInterval}, nil
+			}
 			return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 5}, err
 		}
 
 	}
 
+	if unaddressed {
+		metadata.AddAnnotation(trafficAccessor, traffic.AnnotationNoPrivateAddresses, dns.NoPrivateAddressesErr.Error())
+		return ctrl.Result{Requeue: true, RequeueAfter: backendsRecheckInterval}, nil
+	}
+	metadata.RemoveAnnotation(trafficAccessor, traffic.AnnotationNoPrivateAddresses)
+	if tlsPending {
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Second * 10}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
-func (r *Reconciler) copySecretToWorkloadCluster(ctx context.Context, trafficAccessor traffic.Interface, tls *v1.Secret, host string) error {
-	log.Log.Info(fmt.Sprintf("tls secret ready for host %s. copying secret", host))
-	copySecret := tls.DeepCopy()
-	copySecret.ObjectMeta = metav1.ObjectMeta{
-		Name:      host,
-		Namespace: trafficAccessor.GetNamespace(),
-	}
-	if err := r.WorkloadClient.Create(ctx, copySecret, &client.CreateOptions{}); err != nil {
-		if k8serrors.IsAlreadyExists(err) {
-			if err := r.WorkloadClient.Get(ctx, client.ObjectKeyFromObject(copySecret), copySecret); err != nil {
-				return err
-			}
-			copySecret.Data = tls.Data
-			if err := r.WorkloadClient.Update(ctx, copySecret, &client.UpdateOptions{}); err != nil {
-				return err
-			}
+// evaluatePolicies evaluates the policies against the traffic object and
+// flags it with the violations. Returns true when a violated rule denies it
+func (r *Reconciler) evaluatePolicies(ctx context.Context, trafficAccessor traffic.Interface, records []*kuadrantv1.DNSRecord) (bool, error) {
+	if r.Policies == nil {
+		return false, nil
+	}
+	targets, err := trafficAccessor.GetDNSTargets()
+	if err != nil {
+		return false, err
+	}
+	clusters := map[string][]string{}
+	for _, record := range records {
+		clusters[record.Name] = dns.EndpointClusters(record)
+		if len(targets) > 0 && !slice.ContainsString(clusters[record.Name], targets[0].Cluster) {
+			clusters[record.Name] = append(clusters[record.Name], targets[0].Cluster)
+		}
+	}
+	violations, err := r.Policies.Evaluate(ctx, policy.Input{Object: trafficAccessor, Clusters: clusters})
+	if err != nil {
+		return false, err
+	}
+	policy.Flag(trafficAccessor, violations)
+	return len(policy.Denied(violations)) > 0, nil
+}
+
+// hasBackends returns true when any of the backend services of the traffic
+// object exists in its cluster. Traffic objects without backend services are
+// assumed to be served
+func (r *Reconciler) hasBackends(ctx context.Context, trafficAccessor traffic.Interface) (bool, error) {
+	backends := trafficAccessor.GetBackendServices()
+	if len(backends) == 0 {
+		return true, nil
+	}
+	for _, name := range backends {
+		service := &v1.Service{}
+		err := r.WorkloadClient.Get(ctx, client.ObjectKey{Namespace: trafficAccessor.GetNamespace(), Name: name}, service)
+		if err == nil {
+			return true, nil
+		}
+		if !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// ensureTLS publishes the CAA record of the managed host and creates its
+// certificate and, once issued, copies its secret to the workload cluster
+// and configures the traffic object to use it. Returns the host the
+// certificate is issued for, and false while it isn't issued
+func (r *Reconciler) ensureTLS(ctx context.Context, trafficAccessor traffic.Interface, managedHost string, record *kuadrantv1.DNSRecord) (string, bool, error) {
+	// the hosts of wildcard zones are served by the wildcard certificate of
+	// the zone, owned by its wildcard record. The CAA records of wildcard
+	// certificates are the ones of the domain of the zone, left to its owner
+	certificateHost, certificateRecord, err := r.Hosts.CertificateHost(ctx, record)
+	if err != nil {
+		return "", false, err
+	}
+	if certificateRecord == record {
+		if err := r.Hosts.EnsureCAA(ctx, record); err != nil {
+			return "", false, err
 		}
 	}
-	return nil
+	// create certificate resource for assigned host
+	log.FromContext(ctx).Info("host assigned ensuring certificate in place", "certificateHost", certificateHost)
+	if err := r.Certificates.EnsureCertificate(ctx, certificateHost, certificateRecord); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return "", false, err
+	}
+	// when certificate ready copy secret (need to add event handler for certs)
+	secret, err := r.Certificates.GetCertificateSecret(ctx, certificateHost)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return "", false, err
+	}
+	// if err is not exists return and wait
+	if err != nil {
+		return certificateHost, false, nil
+	}
+	log.FromContext(ctx).Info("certificate exists for host", "host", managedHost)
+
+	//copy secret
+	if secret != nil {
+		hash, err := r.Certificates.RecordContentHash(ctx, secret)
+		if err != nil {
+			return "", false, err
+		}
+		if err := r.copySecretToWorkloadCluster(ctx, trafficAccessor, secret, managedHost, hash); err != nil {
+			return "", false, err
+		}
+		if err := r.Hosts.RecordSynced(ctx, trafficAccessor, managedHost, secret.ResourceVersion); err != nil {
+			return "", false, err
+		}
+		// the copy is named after the managed host, not after the host of
+		// the certificate
+		trafficAccessor.AddTLS(managedHost, &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: managedHost}})
+		if r.Config.Get().HTTPSRedirect {
+			trafficAccessor.AddHTTPSRedirect()
+		}
+	}
+	return certificateHost, true, nil
+}
+
+// copySecretToWorkloadCluster applies the TLS secret of the host to the
+// namespace of the traffic object, annotated with the content hash recorded
+// on the secret. The secret is server-side applied, so fields added to it by
+// others in the workload cluster are kept. A copy already holding the
+// content of the hash isn't written again. The copy served by the workload
+// cluster is read back and verified against the hash, so a faulty copy
+// fails the reconcile rather than being served unnoticed
+func (r *Reconciler) copySecretToWorkloadCluster(ctx context.Context, trafficAccessor traffic.Interface, secret *v1.Secret, host, hash string) error {
+	key := client.ObjectKey{Namespace: trafficAccessor.GetNamespace(), Name: host}
+	existing := &v1.Secret{}
+	err := r.WorkloadClient.Get(ctx, key, existing)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && metadata.GetAnnotation(existing, tls.AnnotationContentHash) == hash && tls.VerifyContentHash(existing, hash) == nil {
+		return nil
+	}
+	log.FromContext(ctx).Info(fmt.Sprintf("tls secret ready for host %s. copying secret", host), "hash", hash)
+	copySecret := &v1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        key.Name,
+			Namespace:   key.Namespace,
+			Annotations: map[string]string{tls.AnnotationContentHash: hash},
+		},
+		Type: secret.Type,
+		Data: secret.Data,
+	}
+	if err := r.WorkloadClient.Patch(ctx, copySecret, client.Apply, client.FieldOwner(traffic.FieldManager), client.ForceOwnership); err != nil {
+		return err
+	}
+	served := &v1.Secret{}
+	if err := r.WorkloadClient.Get(ctx, key, served); err != nil {
+		return err
+	}
+	return tls.VerifyContentHash(served, hash)
 }
@@ -0,0 +1,71 @@
+package trafficrollout
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+const healthCheckTimeout = 5 * time.Second
+
+// HealthChecker checks the health of an address serving a host
+type HealthChecker interface {
+	Check(ctx context.Context, host, address string, check v1.RolloutHealthCheck) error
+}
+
+// HTTPHealthChecker requests the health check path on the address with the
+// host set as the Host header and TLS server name. Responses with a status
+// below 400 are healthy
+type HTTPHealthChecker struct {
+	Timeout time.Duration
+}
+
+func NewHTTPHealthChecker() *HTTPHealthChecker {
+	return &HTTPHealthChecker{Timeout: healthCheckTimeout}
+}
+
+func (c *HTTPHealthChecker) Check(ctx context.Context, host, address string, check v1.RolloutHealthCheck) error {
+	scheme, port, path := "http", 80, "/"
+	if strings.EqualFold(check.Scheme, "HTTPS") {
+		scheme, port = "https", 443
+	}
+	if check.Port != 0 {
+		port = int(check.Port)
+	}
+	if check.Path != "" {
+		path = check.Path
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(address, strconv.Itoa(port)), path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Host = host
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{ServerName: host}},
+		// redirects are followed to the host, not the address, so the
+		// redirect response is the result of the check
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+	}
+	return nil
+}
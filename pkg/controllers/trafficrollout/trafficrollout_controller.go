@@ -0,0 +1,276 @@
+/*
+Copyright 2022 The MultiCluster Traffic Controller Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trafficrollout
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilclock "k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/conditions"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dataplane"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+)
+
+const (
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultFailureThreshold    = 3
+	// recordRecheckInterval is how often a rollout whose DNSRecord doesn't
+	// exist is checked again
+	recordRecheckInterval = time.Minute
+	// trafficRolloutFinalizer restores the weights of the clusters of the
+	// rollout once it's deleted
+	trafficRolloutFinalizer = "kuadrant.io/traffic-rollout"
+)
+
+var clock utilclock.Clock = utilclock.RealClock{}
+
+// TrafficRolloutReconciler shifts the traffic of a managed host from one
+// cluster to another through the steps of a TrafficRollout, by setting the
+// cluster weights of the DNSRecord of the host. A step only moves on once
+// its pause elapsed and the cluster traffic is shifted to is healthy, and
+// the traffic is shifted back when its health check keeps failing. Deleting
+// a rollout removes the weights it set, whatever its phase, so the clusters
+// get back their default share of the traffic
+type TrafficRolloutReconciler struct {
+	client.Client
+	Scheme        *runtime.Scheme
+	HealthChecker HealthChecker
+	// DataPlane provides the rates of the requests served by the data plane
+	// of each cluster. Optional, without it the error rate isn't checked
+	DataPlane DataPlane
+}
+
+// DataPlane provides the rates of the requests served for a host by the data
+// plane of a cluster
+type DataPlane interface {
+	Rates(cluster, host string) (dataplane.Rates, bool)
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=trafficrollouts,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=trafficrollouts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=kuadrant.io,resources=trafficrollouts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=kuadrant.io,resources=dnsrecords,verbs=get;list;watch;update;patch
+
+func (r *TrafficRolloutReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx = logging.IntoContext(ctx, logging.Placement)
+	previous := &v1.TrafficRollout{}
+	if err := r.Client.Get(ctx, req.NamespacedName, previous); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	rollout := previous.DeepCopy()
+
+	if rollout.DeletionTimestamp != nil && !rollout.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(rollout, trafficRolloutFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.restoreWeights(ctx, rollout); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(rollout, trafficRolloutFinalizer)
+		return ctrl.Result{}, r.Client.Update(ctx, rollout)
+	}
+	if controllerutil.AddFinalizer(rollout, trafficRolloutFinalizer) {
+		return ctrl.Result{}, r.Client.Update(ctx, rollout)
+	}
+
+	if metadata.IsPaused(rollout) {
+		log.FromContext(ctx).Info("Reconciliation of TrafficRollout is paused", "rollout", rollout.Name, "namespace", rollout.Namespace)
+		return ctrl.Result{}, nil
+	}
+
+	record := &v1.DNSRecord{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: rollout.Namespace, Name: rollout.Spec.Host}, record); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		conditions.Set(&rollout.Status.Conditions, rollout.Generation, v1.TrafficRolloutHealthyConditionType, metav1.ConditionUnknown,
+			conditions.ReasonNotFound, fmt.Sprintf("The DNSRecord %s was not found", rollout.Spec.Host))
+		return ctrl.Result{RequeueAfter: recordRecheckInterval}, conditions.UpdateStatus(ctx, r.Client, rollout, previous.Status, rollout.Status)
+	}
+
+	requeueAfter := time.Duration(0)
+	if rollout.Status.Phase == "" && len(rollout.Spec.Steps) > 0 {
+		log.FromContext(ctx).Info("Starting TrafficRollout", "rollout", rollout.Name, "namespace", rollout.Namespace, "host", rollout.Spec.Host)
+		r.startStep(rollout, 0)
+	}
+	if rollout.Status.Phase == v1.RolloutPhaseProgressing {
+		requeueAfter = r.progress(ctx, rollout, record)
+	}
+
+	weights := map[string]int{
+		rollout.Spec.From: 100 - rollout.Status.Weight,
+		rollout.Spec.To:   rollout.Status.Weight,
+	}
+	if dns.SetClusterWeights(record, weights) {
+		log.FromContext(ctx).Info("Shifting traffic between clusters", "host", record.Name, "weights", weights)
+		if err := r.Client.Update(ctx, record); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	rollout.Status.ObservedGeneration = rollout.Generation
+	if err := conditions.UpdateStatus(ctx, r.Client, rollout, previous.Status, rollout.Status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// restoreWeights removes the weights of the clusters of the rollout from the
+// DNSRecord of its host
+func (r *TrafficRolloutReconciler) restoreWeights(ctx context.Context, rollout *v1.TrafficRollout) error {
+	record := &v1.DNSRecord{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: rollout.Namespace, Name: rollout.Spec.Host}, record); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !dns.RemoveClusterWeights(record, rollout.Spec.From, rollout.Spec.To) {
+		return nil
+	}
+	log.FromContext(ctx).Info("Restoring the cluster weights of deleted TrafficRollout", "rollout", rollout.Name, "namespace", rollout.Namespace, "host", record.Name)
+	return r.Client.Update(ctx, record)
+}
+
+// progress checks the health of the cluster traffic is shifted to, rolling
+// back when it failed too many times, and moves to the next step once the
+// pause of the current step elapsed. Returns when to check the rollout again
+func (r *TrafficRolloutReconciler) progress(ctx context.Context, rollout *v1.TrafficRollout, record *v1.DNSRecord) time.Duration {
+	healthy := true
+	interval := time.Duration(0)
+	if check := rollout.Spec.HealthCheck; check != nil {
+		interval = defaultHealthCheckInterval
+		if check.Interval != nil && check.Interval.Duration > 0 {
+			interval = check.Interval.Duration
+		}
+		threshold := check.FailureThreshold
+		if threshold <= 0 {
+			threshold = defaultFailureThreshold
+		}
+
+		if err := r.checkHealth(ctx, rollout, record); err != nil {
+			healthy = false
+			rollout.Status.FailedChecks++
+			log.FromContext(ctx).Info("TrafficRollout health check failed", "rollout", rollout.Name, "namespace", rollout.Namespace, "cluster", rollout.Spec.To, "failures", rollout.Status.FailedChecks, "error", err.Error())
+			conditions.Set(&rollout.Status.Conditions, rollout.Generation, v1.TrafficRolloutHealthyConditionType, metav1.ConditionFalse,
+				conditions.ReasonHealthCheckFailed, fmt.Sprintf("The health check of cluster %s failed %d times: %v", rollout.Spec.To, rollout.Status.FailedChecks, err))
+			if rollout.Status.FailedChecks >= threshold {
+				log.FromContext(ctx).Info("Rolling back TrafficRollout", "rollout", rollout.Name, "namespace", rollout.Namespace, "host", rollout.Spec.Host)
+				rollout.Status.Phase = v1.RolloutPhaseRolledBack
+				rollout.Status.Weight = 0
+				conditions.Set(&rollout.Status.Conditions, rollout.Generation, v1.TrafficRolloutHealthyConditionType, metav1.ConditionFalse,
+					conditions.ReasonRolledBack, fmt.Sprintf("The traffic was shifted back to cluster %s after the health check of cluster %s failed %d times: %v", rollout.Spec.From, rollout.Spec.To, rollout.Status.FailedChecks, err))
+				return 0
+			}
+		} else {
+			rollout.Status.FailedChecks = 0
+			conditions.Set(&rollout.Status.Conditions, rollout.Generation, v1.TrafficRolloutHealthyConditionType, metav1.ConditionTrue,
+				conditions.ReasonHealthCheckPassed, fmt.Sprintf("The health check of cluster %s passed", rollout.Spec.To))
+		}
+	}
+
+	if rollout.Status.CurrentStep >= len(rollout.Spec.Steps) {
+		// the steps were removed from the spec while in progress
+		rollout.Status.Phase = v1.RolloutPhaseCompleted
+		return 0
+	}
+	if rollout.Status.StepStartTime == nil {
+		r.startStep(rollout, rollout.Status.CurrentStep)
+	}
+	step := rollout.Spec.Steps[rollout.Status.CurrentStep]
+	remaining := step.Pause.Duration - clock.Since(rollout.Status.StepStartTime.Time)
+	if remaining <= 0 && healthy {
+		if rollout.Status.CurrentStep+1 >= len(rollout.Spec.Steps) {
+			log.FromContext(ctx).Info("TrafficRollout completed", "rollout", rollout.Name, "namespace", rollout.Namespace, "host", rollout.Spec.Host)
+			rollout.Status.Phase = v1.RolloutPhaseCompleted
+			return 0
+		}
+		r.startStep(rollout, rollout.Status.CurrentStep+1)
+		remaining = rollout.Spec.Steps[rollout.Status.CurrentStep].Pause.Duration
+	}
+	if remaining <= 0 || (interval > 0 && interval < remaining) {
+		return interval
+	}
+	return remaining
+}
+
+// startStep shifts the traffic to the weight of the step
+func (r *TrafficRolloutReconciler) startStep(rollout *v1.TrafficRollout, step int) {
+	now := metav1.NewTime(clock.Now())
+	rollout.Status.Phase = v1.RolloutPhaseProgressing
+	rollout.Status.CurrentStep = step
+	rollout.Status.Weight = rollout.Spec.Steps[step].Weight
+	rollout.Status.StepStartTime = &now
+}
+
+// checkHealth checks every address published for the cluster traffic is
+// shifted to
+func (r *TrafficRolloutReconciler) checkHealth(ctx context.Context, rollout *v1.TrafficRollout, record *v1.DNSRecord) error {
+	addresses := dns.ClusterAddresses(record, rollout.Spec.To)
+	if len(addresses) == 0 {
+		return fmt.Errorf("no addresses are published for cluster %s", rollout.Spec.To)
+	}
+	var failed []string
+	for _, address := range addresses {
+		if err := r.HealthChecker.Check(ctx, rollout.Spec.Host, address, *rollout.Spec.HealthCheck); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if err := r.checkErrorRate(rollout); err != nil {
+		failed = append(failed, err.Error())
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// checkErrorRate checks the fraction of the requests for the host answered
+// with a 5xx by the data plane of the cluster traffic is shifted to, once
+// its rates are known
+func (r *TrafficRolloutReconciler) checkErrorRate(rollout *v1.TrafficRollout) error {
+	maxErrorPercent := rollout.Spec.HealthCheck.MaxErrorPercent
+	if r.DataPlane == nil || maxErrorPercent == nil {
+		return nil
+	}
+	rates, ok := r.DataPlane.Rates(rollout.Spec.To, rollout.Spec.Host)
+	if !ok || rates.RequestsPerSecond == 0 {
+		return nil
+	}
+	if errorPercent := rates.ErrorRatio * 100; errorPercent > float64(*maxErrorPercent) {
+		return fmt.Errorf("%.1f%% of the requests failed, exceeding the maximum of %d%%", errorPercent, *maxErrorPercent)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TrafficRolloutReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.TrafficRollout{}).
+		Complete(r)
+}
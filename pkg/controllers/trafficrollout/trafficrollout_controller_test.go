@@ -0,0 +1,137 @@
+package trafficrollout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+func TestTrafficRolloutReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+
+	record := func(weights string) *v1.DNSRecord {
+		record := &v1.DNSRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"},
+			Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+				{DNSName: "app.example.com", Targets: []string{"1.1.1.1"}, RecordType: "A", SetIdentifier: "1.1.1.1", Labels: map[string]string{"kuadrant.io/owner": "cluster-a/ingress"}},
+				{DNSName: "app.example.com", Targets: []string{"2.2.2.2"}, RecordType: "A", SetIdentifier: "2.2.2.2", Labels: map[string]string{"kuadrant.io/owner": "cluster-b/ingress"}},
+			}},
+		}
+		if weights != "" {
+			metadata.AddAnnotation(record, dns.AnnotationClusterWeights, weights)
+		}
+		return record
+	}
+	rollout := func(mutate func(rollout *v1.TrafficRollout)) *v1.TrafficRollout {
+		rollout := &v1.TrafficRollout{
+			ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: "argocd", Finalizers: []string{trafficRolloutFinalizer}},
+			Spec: v1.TrafficRolloutSpec{
+				Host:  "app.example.com",
+				From:  "cluster-a",
+				To:    "cluster-b",
+				Steps: []v1.RolloutStep{{Weight: 20, Pause: metav1.Duration{Duration: time.Hour}}},
+			},
+		}
+		if mutate != nil {
+			mutate(rollout)
+		}
+		return rollout
+	}
+	deleted := func(rollout *v1.TrafficRollout) {
+		now := metav1.Now()
+		rollout.DeletionTimestamp = &now
+	}
+
+	cases := []struct {
+		name            string
+		objects         []client.Object
+		expectWeights   string
+		expectRollout   bool
+		expectFinalizer bool
+	}{
+		{
+			name:            "new rollout is finalized",
+			objects:         []client.Object{rollout(func(r *v1.TrafficRollout) { r.Finalizers = nil }), record("")},
+			expectRollout:   true,
+			expectFinalizer: true,
+		},
+		{
+			name:            "rollout shifts the weights",
+			objects:         []client.Object{rollout(nil), record("")},
+			expectWeights:   "cluster-a=80,cluster-b=20",
+			expectRollout:   true,
+			expectFinalizer: true,
+		},
+		{
+			name:          "deleted rollout restores the weights",
+			objects:       []client.Object{rollout(deleted), record("cluster-a=80,cluster-b=20")},
+			expectWeights: "",
+		},
+		{
+			name:          "deleted rollout keeps the weights of other clusters",
+			objects:       []client.Object{rollout(deleted), record("cluster-a=80,cluster-b=20,cluster-c=50")},
+			expectWeights: "cluster-c=50",
+		},
+		{
+			name: "deleted paused rollout restores the weights",
+			objects: []client.Object{rollout(func(r *v1.TrafficRollout) {
+				deleted(r)
+				metadata.AddAnnotation(r, metadata.AnnotationPaused, "true")
+			}), record("cluster-a=80,cluster-b=20")},
+			expectWeights: "",
+		},
+		{
+			name:    "deleted rollout without a record",
+			objects: []client.Object{rollout(deleted)},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build()
+			r := &TrafficRolloutReconciler{Client: c, Scheme: scheme}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "argocd", Name: "rollout"}}); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			current := &v1.TrafficRollout{}
+			err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "rollout"}, current)
+			if err != nil && !k8serrors.IsNotFound(err) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if exists := err == nil; exists != tc.expectRollout {
+				t.Errorf("expected rollout '%v' got '%v'", tc.expectRollout, exists)
+			}
+			if finalized := controllerutil.ContainsFinalizer(current, trafficRolloutFinalizer); finalized != tc.expectFinalizer {
+				t.Errorf("expected finalizer '%v' got '%v'", tc.expectFinalizer, finalized)
+			}
+
+			currentRecord := &v1.DNSRecord{}
+			if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "app.example.com"}, currentRecord); err != nil {
+				if !k8serrors.IsNotFound(err) {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if weights := metadata.GetAnnotation(currentRecord, dns.AnnotationClusterWeights); weights != tc.expectWeights {
+				t.Errorf("expected '%v' got '%v'", tc.expectWeights, weights)
+			}
+		})
+	}
+}
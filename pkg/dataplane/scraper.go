@@ -0,0 +1,306 @@
+package dataplane
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+)
+
+// RequestsMetric is the ingress-nginx counter of the requests served, by
+// host and status
+const RequestsMetric = "nginx_ingress_controller_requests"
+
+var (
+	requestsPerSecond = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_dataplane_requests_per_second",
+			Help: "MCTC rate of the requests served for a host by the data plane of a cluster",
+		},
+		[]string{"cluster", "host"},
+	)
+	errorRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_dataplane_error_ratio",
+			Help: "MCTC fraction of the requests for a host answered with a 5xx by the data plane of a cluster",
+		},
+		[]string{"cluster", "host"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestsPerSecond, errorRatio)
+}
+
+// MetricsService is the service exposing the metrics of the data plane in
+// each workload cluster
+type MetricsService struct {
+	Namespace string
+	Name      string
+	Port      int
+}
+
+// ParseMetricsService parses a <namespace>/<name>:<port> service reference
+func ParseMetricsService(value string) (MetricsService, error) {
+	namespace, rest, ok := strings.Cut(value, "/")
+	if !ok || namespace == "" {
+		return MetricsService{}, fmt.Errorf("metrics service %s is not <namespace>/<name>:<port>", value)
+	}
+	name, port, ok := strings.Cut(rest, ":")
+	if !ok || name == "" {
+		return MetricsService{}, fmt.Errorf("metrics service %s is not <namespace>/<name>:<port>", value)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return MetricsService{}, fmt.Errorf("invalid port of metrics service %s: %w", value, err)
+	}
+	return MetricsService{Namespace: namespace, Name: name, Port: p}, nil
+}
+
+// Rates are the rates of the requests served for a host by the data plane
+// of a cluster, between its last two scrapes
+type Rates struct {
+	RequestsPerSecond float64
+	// ErrorRatio is the fraction of the requests answered with a 5xx
+	ErrorRatio float64
+}
+
+// Counters are the requests served for a host, and the ones answered with
+// a 5xx
+type Counters struct {
+	Requests float64
+	Errors   float64
+}
+
+// maxScrapeTimeout is how long the metrics of a cluster are waited for
+const maxScrapeTimeout = 10 * time.Second
+
+type scrape struct {
+	at       time.Time
+	counters map[string]Counters
+}
+
+type cachedClient struct {
+	resourceVersion string
+	clientset       kubernetes.Interface
+}
+
+// Scraper periodically scrapes the request metrics of the data plane of
+// each workload cluster, through the API server proxy of the cluster, and
+// federates their rates per host and cluster as metrics of the controller.
+// The rates are also kept to gate rollouts on the traffic actually served
+type Scraper struct {
+	Client client.Client
+	// Namespace is the controller namespace holding the cluster secrets
+	Namespace string
+	Service   MetricsService
+	Interval  time.Duration
+
+	mu       sync.RWMutex
+	previous map[string]scrape
+	rates    map[string]map[string]Rates
+	clients  map[string]cachedClient
+}
+
+func (s *Scraper) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info("Starting data plane metrics scraper", "interval", s.Interval, "service", s.Service)
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.scrapeAll(ctx); err != nil {
+				log.FromContext(ctx).Error(err, "failed to scrape data plane metrics")
+			}
+		}
+	}
+}
+
+// Rates returns the rates of the requests served for the host by the data
+// plane of the cluster. Returns false until the cluster was scraped twice
+// with requests for the host
+func (s *Scraper) Rates(cluster, host string) (Rates, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rates, ok := s.rates[cluster][host]
+	return rates, ok
+}
+
+func (s *Scraper) scrapeAll(ctx context.Context) error {
+	secrets := &corev1.SecretList{}
+	if err := s.Client.List(ctx, secrets, client.InNamespace(s.Namespace), client.MatchingLabels{secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE}); err != nil {
+		return err
+	}
+	clusters := map[string]struct{}{}
+	for i := range secrets.Items {
+		cluster := &secrets.Items[i]
+		clusters[cluster.Name] = struct{}{}
+		body, err := s.fetch(ctx, cluster)
+		if err != nil {
+			log.FromContext(ctx).V(3).Info("failed to scrape data plane metrics of cluster", "cluster", cluster.Name, "error", err.Error())
+			continue
+		}
+		counters, err := ParseCounters(body)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "invalid data plane metrics", "cluster", cluster.Name)
+			continue
+		}
+		s.Observe(cluster.Name, time.Now(), counters)
+	}
+	s.forgetExcept(clusters)
+	return nil
+}
+
+// fetch requests the metrics service of the cluster through the API server
+// proxy, giving up after the scrape timeout so an unreachable cluster
+// doesn't hold up the scrapes of the others
+func (s *Scraper) fetch(ctx context.Context, cluster *corev1.Secret) ([]byte, error) {
+	clientset, err := s.clientset(cluster)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+	return clientset.CoreV1().Services(s.Service.Namespace).
+		ProxyGet("http", s.Service.Name, strconv.Itoa(s.Service.Port), "metrics", nil).
+		DoRaw(ctx)
+}
+
+// timeout returns how long the metrics of a cluster are waited for, at most
+// the scrape interval
+func (s *Scraper) timeout() time.Duration {
+	if s.Interval > 0 && s.Interval < maxScrapeTimeout {
+		return s.Interval
+	}
+	return maxScrapeTimeout
+}
+
+func (s *Scraper) clientset(cluster *corev1.Secret) (kubernetes.Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.clients[cluster.Name]; ok && cached.resourceVersion == cluster.ResourceVersion {
+		return cached.clientset, nil
+	}
+	config, err := clusterSecret.RestConfigFromSecret(s.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if s.clients == nil {
+		s.clients = map[string]cachedClient{}
+	}
+	s.clients[cluster.Name] = cachedClient{resourceVersion: cluster.ResourceVersion, clientset: clientset}
+	return clientset, nil
+}
+
+// Observe records the counters of each host scraped from the cluster, and
+// updates their rates since the previous scrape. Hosts whose counters were
+// reset, by a restart of the data plane, are only updated from the next
+// scrape
+func (s *Scraper) Observe(cluster string, at time.Time, counters map[string]Counters) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.previous == nil {
+		s.previous = map[string]scrape{}
+		s.rates = map[string]map[string]Rates{}
+	}
+	previous, ok := s.previous[cluster]
+	s.previous[cluster] = scrape{at: at, counters: counters}
+	if !ok {
+		return
+	}
+	seconds := at.Sub(previous.at).Seconds()
+	if seconds <= 0 {
+		return
+	}
+	rates := map[string]Rates{}
+	for host, current := range counters {
+		last, ok := previous.counters[host]
+		if !ok || current.Requests < last.Requests || current.Errors < last.Errors {
+			continue
+		}
+		requests := current.Requests - last.Requests
+		r := Rates{RequestsPerSecond: requests / seconds}
+		if requests > 0 {
+			r.ErrorRatio = (current.Errors - last.Errors) / requests
+		}
+		rates[host] = r
+	}
+	s.rates[cluster] = rates
+	requestsPerSecond.DeletePartialMatch(prometheus.Labels{"cluster": cluster})
+	errorRatio.DeletePartialMatch(prometheus.Labels{"cluster": cluster})
+	for host, r := range rates {
+		requestsPerSecond.WithLabelValues(cluster, host).Set(r.RequestsPerSecond)
+		errorRatio.WithLabelValues(cluster, host).Set(r.ErrorRatio)
+	}
+}
+
+// forgetExcept forgets the clusters whose secrets were removed
+func (s *Scraper) forgetExcept(clusters map[string]struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for cluster := range s.previous {
+		if _, ok := clusters[cluster]; ok {
+			continue
+		}
+		delete(s.previous, cluster)
+		delete(s.rates, cluster)
+		delete(s.clients, cluster)
+		requestsPerSecond.DeletePartialMatch(prometheus.Labels{"cluster": cluster})
+		errorRatio.DeletePartialMatch(prometheus.Labels{"cluster": cluster})
+	}
+}
+
+// ParseCounters sums the RequestsMetric counters of the metrics by host
+func ParseCounters(body []byte) (map[string]Counters, error) {
+	parser := expfmt.TextParser{}
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	counters := map[string]Counters{}
+	family, ok := families[RequestsMetric]
+	if !ok {
+		return counters, nil
+	}
+	for _, metric := range family.GetMetric() {
+		host, status := "", ""
+		for _, label := range metric.GetLabel() {
+			switch label.GetName() {
+			case "host":
+				host = label.GetValue()
+			case "status":
+				status = label.GetValue()
+			}
+		}
+		if host == "" || metric.GetCounter() == nil {
+			continue
+		}
+		c := counters[host]
+		c.Requests += metric.GetCounter().GetValue()
+		if strings.HasPrefix(status, "5") {
+			c.Errors += metric.GetCounter().GetValue()
+		}
+		counters[host] = c
+	}
+	return counters, nil
+}
@@ -0,0 +1,116 @@
+package dataplane
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const body = `# HELP nginx_ingress_controller_requests The total number of client requests
+# TYPE nginx_ingress_controller_requests counter
+nginx_ingress_controller_requests{host="a.example.com",status="200"} 90
+nginx_ingress_controller_requests{host="a.example.com",status="503"} 10
+nginx_ingress_controller_requests{host="b.example.com",status="200"} 40
+nginx_ingress_controller_requests{host="",status="404"} 5
+`
+
+func TestParseCounters(t *testing.T) {
+	counters, err := ParseCounters([]byte(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]Counters{
+		"a.example.com": {Requests: 100, Errors: 10},
+		"b.example.com": {Requests: 40},
+	}
+	if len(counters) != len(expected) {
+		t.Errorf("expected '%v' got '%v'", expected, counters)
+	}
+	for host, c := range expected {
+		if counters[host] != c {
+			t.Errorf("expected '%v' got '%v'", c, counters[host])
+		}
+	}
+}
+
+func TestScraper_Observe(t *testing.T) {
+	start := time.Now()
+	cases := []struct {
+		Name     string
+		Previous Counters
+		Current  Counters
+		Rates    Rates
+		Found    bool
+	}{
+		{
+			Name:     "rates since the previous scrape",
+			Previous: Counters{Requests: 100, Errors: 10},
+			Current:  Counters{Requests: 200, Errors: 35},
+			Rates:    Rates{RequestsPerSecond: 10, ErrorRatio: 0.25},
+			Found:    true,
+		},
+		{
+			Name:     "no requests since the previous scrape",
+			Previous: Counters{Requests: 100, Errors: 10},
+			Current:  Counters{Requests: 100, Errors: 10},
+			Rates:    Rates{},
+			Found:    true,
+		},
+		{
+			Name:     "counters reset",
+			Previous: Counters{Requests: 100, Errors: 10},
+			Current:  Counters{Requests: 20},
+			Found:    false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			s := &Scraper{}
+			s.Observe("cluster", start, map[string]Counters{"a.example.com": tc.Previous})
+			if _, found := s.Rates("cluster", "a.example.com"); found {
+				t.Errorf("expected no rates after a single scrape")
+			}
+			s.Observe("cluster", start.Add(10*time.Second), map[string]Counters{"a.example.com": tc.Current})
+			rates, found := s.Rates("cluster", "a.example.com")
+			if found != tc.Found {
+				t.Errorf("expected '%v' got '%v'", tc.Found, found)
+			}
+			if rates != tc.Rates {
+				t.Errorf("expected '%v' got '%v'", tc.Rates, rates)
+			}
+		})
+	}
+}
+
+func TestScraper_fetchTimeout(t *testing.T) {
+	// the API server of the cluster never answers
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cluster := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", ResourceVersion: "1"}}
+	s := &Scraper{
+		Service:  MetricsService{Namespace: "ingress-nginx", Name: "metrics", Port: 10254},
+		Interval: 100 * time.Millisecond,
+		clients:  map[string]cachedClient{"cluster-a": {resourceVersion: "1", clientset: clientset}},
+	}
+
+	start := time.Now()
+	if _, err := s.fetch(context.Background(), cluster); err == nil {
+		t.Errorf("expected error got '%v'", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected fetch to give up after '%v' got '%v'", s.Interval, elapsed)
+	}
+}
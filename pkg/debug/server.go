@@ -0,0 +1,149 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/httpserver"
+)
+
+const (
+	CachePath      = "/debug/cache"
+	WorkqueuesPath = "/debug/workqueues"
+)
+
+// Server serves pprof profiles, a dump of the object cache and the depth of
+// the workqueue of each controller, to diagnose reconcile storms and memory
+// growth. It listens on localhost only, so it's reached with a port-forward
+type Server struct {
+	Port int
+	// Cache is the object cache of the manager
+	Cache  client.Reader
+	Scheme *runtime.Scheme
+	// Lists are the kinds of the cached objects dumped. Only kinds the
+	// controllers already watch are listed, as listing other kinds would
+	// start caching them
+	Lists []client.ObjectList
+	// Gatherer gathers the workqueue metrics of the controllers
+	Gatherer prometheus.Gatherer
+}
+
+// CachedKind is the objects of a kind in the cache
+type CachedKind struct {
+	Count int      `json:"count"`
+	Keys  []string `json:"keys"`
+}
+
+// NeedLeaderElection returns false so every replica can be diagnosed, not
+// only the leader
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	log.Log.Info(fmt.Sprintf("Starting debug server at localhost:%d", s.Port))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc(CachePath, s.serveCache)
+	mux.HandleFunc(WorkqueuesPath, s.serveWorkqueues)
+	server := &http.Server{Addr: fmt.Sprintf("127.0.0.1:%d", s.Port), Handler: mux}
+
+	return httpserver.Serve(ctx, server)
+}
+
+func (s *Server) serveCache(w http.ResponseWriter, r *http.Request) {
+	dump, err := s.CacheDump(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, dump)
+}
+
+func (s *Server) serveWorkqueues(w http.ResponseWriter, _ *http.Request) {
+	depths, err := WorkqueueDepths(s.Gatherer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, depths)
+}
+
+// CacheDump returns the keys of the cached objects of each kind. Only the
+// keys are dumped, so the content of cached secrets isn't exposed
+func (s *Server) CacheDump(ctx context.Context) (map[string]CachedKind, error) {
+	dump := map[string]CachedKind{}
+	for _, list := range s.Lists {
+		list = list.DeepCopyObject().(client.ObjectList)
+		if err := s.Cache.List(ctx, list); err != nil {
+			return nil, err
+		}
+		gvk, err := apiutil.GVKForObject(list, s.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(items))
+		for _, item := range items {
+			if obj, ok := item.(client.Object); ok {
+				keys = append(keys, client.ObjectKeyFromObject(obj).String())
+			}
+		}
+		sort.Strings(keys)
+		kind := strings.TrimSuffix(gvk.Kind, "List")
+		if gvk.Group != "" {
+			kind = kind + "." + gvk.Group
+		}
+		dump[kind] = CachedKind{Count: len(keys), Keys: keys}
+	}
+	return dump, nil
+}
+
+// WorkqueueDepths returns the depth of each workqueue gathered, by the
+// name of the workqueue, which is the name of its controller
+func WorkqueueDepths(gatherer prometheus.Gatherer) (map[string]float64, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	depths := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" {
+					depths[label.GetValue()] = metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return depths, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Log.Error(err, "failed to write debug response")
+	}
+}
@@ -0,0 +1,61 @@
+package debug
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWorkqueueDepths(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_depth"}, []string{"name"})
+	adds := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "workqueue_adds_total"}, []string{"name"})
+	registry.MustRegister(depth, adds)
+	depth.WithLabelValues("dnsrecord").Set(3)
+	depth.WithLabelValues("cluster-1/ingress").Set(0)
+	adds.WithLabelValues("dnsrecord").Add(10)
+
+	got, err := WorkqueueDepths(registry)
+	if err != nil {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+	expected := map[string]float64{"dnsrecord": 3, "cluster-1/ingress": 0}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected '%v' got '%v'", expected, got)
+	}
+}
+
+func TestServer_Start(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// the port is still held by the listener
+	if err := (&Server{Port: port}).Start(context.Background()); err == nil {
+		t.Errorf("expected an error listening on a port in use")
+	}
+
+	if err := listener.Close(); err != nil {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() {
+		stopped <- (&Server{Port: port}).Start(ctx)
+	}()
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("expected no error once cancelled got '%v'", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the server to stop once cancelled")
+	}
+}
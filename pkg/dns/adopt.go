@@ -0,0 +1,52 @@
+package dns
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+var _ Lister = &dnsAWS.Provider{}
+
+// Lister is implemented by providers that can list the zones they host and
+// the records published in them, so existing records can be adopted
+type Lister interface {
+	// Zones returns the zones of the provider, keyed by domain name
+	Zones() (map[string]v1.DNSZone, error)
+	// Records returns the records published in the zone for the name
+	Records(zone v1.DNSZone, name string) ([]*v1.Endpoint, error)
+}
+
+// AdoptedRecord returns the DNSRecord of the host of the traffic object that
+// adopts the records already published for the host in the zone, so they're
+// kept until replaced. The A and CNAME records are labelled as published for
+// the traffic object in its cluster, so its endpoints replace them once it's
+// reconciled. The other records are kept as raw records
+func AdoptedRecord(host, namespace string, zone *v1.ManagedZone, published []*v1.Endpoint, cluster string, t traffic.Interface) *v1.DNSRecord {
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      host,
+			Namespace: namespace,
+		},
+		Spec: v1.DNSRecordSpec{
+			ManagedZoneRef: &v1.ManagedZoneReference{Name: zone.Name},
+			Endpoints:      []*v1.Endpoint{},
+		},
+	}
+	owner := endpointOwner(cluster, t)
+	for _, endpoint := range published {
+		switch v1.DNSRecordType(endpoint.RecordType) {
+		case v1.ARecordType, v1.CNAMERecordType:
+			if endpoint.Labels == nil {
+				endpoint.Labels = v1.Labels{}
+			}
+			endpoint.Labels[endpointLabelOwner] = owner
+			record.Spec.Endpoints = append(record.Spec.Endpoints, endpoint)
+		default:
+			record.Spec.RawRecords = append(record.Spec.RawRecords, endpoint)
+		}
+	}
+	return record
+}
@@ -69,6 +69,14 @@ func (c *InstrumentedRoute53) ChangeResourceRecordSets(input *route53.ChangeReso
 	return
 }
 
+func (c *InstrumentedRoute53) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (output *route53.ListResourceRecordSetsOutput, err error) {
+	observe("ListResourceRecordSets", func() error {
+		output, err = c.route53.ListResourceRecordSets(input)
+		return err
+	})
+	return
+}
+
 func (c *InstrumentedRoute53) CreateHealthCheck(input *route53.CreateHealthCheckInput) (output *route53.CreateHealthCheckOutput, err error) {
 	observe("CreateHealthCheck", func() error {
 		output, err = c.route53.CreateHealthCheck(input)
@@ -108,3 +116,35 @@ func (c *InstrumentedRoute53) ChangeTagsForResourceWithContext(ctx aws.Context,
 	})
 	return
 }
+
+func (c *InstrumentedRoute53) GetDNSSEC(input *route53.GetDNSSECInput) (output *route53.GetDNSSECOutput, err error) {
+	observe("GetDNSSEC", func() error {
+		output, err = c.route53.GetDNSSEC(input)
+		return err
+	})
+	return
+}
+
+func (c *InstrumentedRoute53) ListCidrCollections(input *route53.ListCidrCollectionsInput) (output *route53.ListCidrCollectionsOutput, err error) {
+	observe("ListCidrCollections", func() error {
+		output, err = c.route53.ListCidrCollections(input)
+		return err
+	})
+	return
+}
+
+func (c *InstrumentedRoute53) CreateCidrCollection(input *route53.CreateCidrCollectionInput) (output *route53.CreateCidrCollectionOutput, err error) {
+	observe("CreateCidrCollection", func() error {
+		output, err = c.route53.CreateCidrCollection(input)
+		return err
+	})
+	return
+}
+
+func (c *InstrumentedRoute53) ChangeCidrCollection(input *route53.ChangeCidrCollectionInput) (output *route53.ChangeCidrCollectionOutput, err error) {
+	observe("ChangeCidrCollection", func() error {
+		output, err = c.route53.ChangeCidrCollection(input)
+		return err
+	})
+	return
+}
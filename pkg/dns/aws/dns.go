@@ -17,12 +17,18 @@ limitations under the License.
 package aws
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/route53"
@@ -47,6 +53,10 @@ const (
 	ProviderSpecificGeolocationSubdivisionCode = "aws/geolocation-subdivision-code"
 	ProviderSpecificMultiValueAnswer           = "aws/multi-value-answer"
 	ProviderSpecificHealthCheckID              = "aws/health-check-id"
+	// ProviderSpecificCIDRLocations lists the resolver locations a sticky
+	// endpoint answers, see StickyLocations. A sticky endpoint is published
+	// as a record set routed by CIDR for each of its locations
+	ProviderSpecificCIDRLocations = "aws/cidr-locations"
 )
 
 // Inspired by https://github.com/openshift/cluster-ingress-operator/blob/master/pkg/dns/aws/dns.go
@@ -55,12 +65,21 @@ type Provider struct {
 	//healthCheckReconciler *Route53HealthCheckReconciler
 	config Config
 	logger logr.Logger
+	// region is the region of the Route53 API, logged with every call
+	region string
+
+	stickyMu           sync.Mutex
+	stickyCollectionID string
 }
 
 // Config is the necessary input to configure the manager.
 type Config struct {
 	// Region is the AWS region ELBs are created in.
 	Region string
+	// AccessKeyID and SecretAccessKey are static credentials used instead of
+	// the default credential chain of the controller when set.
+	AccessKeyID     string
+	SecretAccessKey string
 }
 
 func NewProvider(config Config) (*Provider, error) {
@@ -69,7 +88,12 @@ func NewProvider(config Config) (*Provider, error) {
 		region = config.Region
 	}
 
-	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	sessConfig := &aws.Config{Region: aws.String(region)}
+	if config.AccessKeyID != "" && config.SecretAccessKey != "" {
+		sessConfig.Credentials = credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, "")
+	}
+
+	sess, err := session.NewSession(sessConfig)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create AWS client session: %v", err)
 	}
@@ -100,6 +124,7 @@ func NewProvider(config Config) (*Provider, error) {
 		route53: &InstrumentedRoute53{route53.New(sess, r53Config)},
 		config:  config,
 		logger:  log.Log.WithName("aws-route53").WithValues("region", r53Config.Region),
+		region:  aws.StringValue(r53Config.Region),
 	}
 	if err := validateServiceEndpoints(p); err != nil {
 		return nil, fmt.Errorf("failed to validate AWS provider service endpoints: %v", err)
@@ -129,12 +154,134 @@ const (
 	deleteAction action = "DELETE"
 )
 
-func (p *Provider) Ensure(record *v1.DNSRecord, zone v1.DNSZone) error {
-	return p.change(record, zone, upsertAction)
+// loggerFor returns the logger of the provider carrying the values of the
+// logger of the context, so the changes published are logged with the
+// reconcileID of the reconcile requesting them
+func (p *Provider) loggerFor(ctx context.Context) logr.Logger {
+	return log.FromContext(ctx).WithName("aws-route53").WithValues("region", p.region)
+}
+
+func (p *Provider) Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return p.change(ctx, record, zone, upsertAction)
 }
 
-func (p *Provider) Delete(record *v1.DNSRecord, zone v1.DNSZone) error {
-	return p.change(record, zone, deleteAction)
+// Delete removes the record sets of the record from the zone. Record sets
+// that no longer exist are skipped, and record sets changed out of band are
+// deleted as they are currently published, so deletion doesn't get stuck on
+// records that drifted
+func (p *Provider) Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	lastPublishedEndpoints, err := p.endpointsFromZoneStatus(record, zone.ID)
+	if err != nil {
+		return err
+	}
+
+	var changes []*route53.Change
+	deleted := map[string]struct{}{}
+	for _, endpoint := range expandLocations(append(record.PublishedEndpoints(), lastPublishedEndpoints...)) {
+		change, err := p.changeForEndpoint(endpoint, string(deleteAction))
+		if err != nil {
+			return err
+		}
+		published, err := p.publishedRecordSet(change.ResourceRecordSet, zone.ID)
+		if err != nil {
+			return err
+		}
+		if published == nil {
+			continue
+		}
+		key := recordSetKey(published)
+		if _, ok := deleted[key]; ok {
+			continue
+		}
+		deleted[key] = struct{}{}
+		change.ResourceRecordSet = published
+		changes = append(changes, change)
+	}
+
+	if len(changes) == 0 {
+		p.loggerFor(ctx).Info("DNS record already deleted", "record", record.Spec, "zone", zone)
+		return nil
+	}
+	input := &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zone.ID),
+		ChangeBatch:  &route53.ChangeBatch{Changes: changes},
+	}
+	if _, err := p.route53.ChangeResourceRecordSets(input); err != nil {
+		return fmt.Errorf("failed to delete record %s in zone %s: %v", record.Name, zone.ID, err)
+	}
+	p.loggerFor(ctx).Info("Deleted DNS record", "record", record.Spec, "zone", zone)
+	return nil
+}
+
+// Verify compares the record sets published in the zone against the
+// endpoints of the record, returning the endpoints that drifted
+func (p *Provider) Verify(_ context.Context, record *v1.DNSRecord, zone v1.DNSZone) ([]*v1.Endpoint, error) {
+	var drifted []*v1.Endpoint
+	for _, endpoint := range expandLocations(record.PublishedEndpoints()) {
+		change, err := p.changeForEndpoint(endpoint, string(upsertAction))
+		if err != nil {
+			return nil, err
+		}
+		expected := change.ResourceRecordSet
+
+		published, err := p.publishedRecordSet(expected, zone.ID)
+		if err != nil {
+			return nil, err
+		}
+		if published == nil || !recordSetMatches(expected, published) {
+			drifted = append(drifted, endpoint)
+		}
+	}
+	return drifted, nil
+}
+
+// publishedRecordSet returns the record set published in the zone with the
+// name, type and set identifier of the expected record set, or nil when
+// there's none
+func (p *Provider) publishedRecordSet(expected *route53.ResourceRecordSet, zoneID string) (*route53.ResourceRecordSet, error) {
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:          aws.String(zoneID),
+		StartRecordName:       expected.Name,
+		StartRecordType:       expected.Type,
+		StartRecordIdentifier: expected.SetIdentifier,
+		MaxItems:              aws.String("1"),
+	}
+	output, err := p.route53.ListResourceRecordSets(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list record sets in zone %s: %v", zoneID, err)
+	}
+	// the listing starts at the expected record set, returning the next one
+	// when it doesn't exist
+	if len(output.ResourceRecordSets) == 0 || recordSetKey(output.ResourceRecordSets[0]) != recordSetKey(expected) {
+		return nil, nil
+	}
+	return output.ResourceRecordSets[0], nil
+}
+
+// recordSetKey identifies a record set within a zone
+func recordSetKey(recordSet *route53.ResourceRecordSet) string {
+	name := strings.TrimSuffix(strings.ToLower(aws.StringValue(recordSet.Name)), ".")
+	return fmt.Sprintf("%s/%s/%s", name, aws.StringValue(recordSet.Type), aws.StringValue(recordSet.SetIdentifier))
+}
+
+// recordSetMatches returns true when the published record set has the
+// values of the expected record set
+func recordSetMatches(expected, published *route53.ResourceRecordSet) bool {
+	if recordSetKey(expected) != recordSetKey(published) ||
+		aws.Int64Value(expected.TTL) != aws.Int64Value(published.TTL) ||
+		aws.Int64Value(expected.Weight) != aws.Int64Value(published.Weight) {
+		return false
+	}
+
+	values := func(records []*route53.ResourceRecord) []string {
+		result := make([]string, 0, len(records))
+		for _, r := range records {
+			result = append(result, aws.StringValue(r.Value))
+		}
+		sort.Strings(result)
+		return result
+	}
+	return reflect.DeepEqual(values(expected.ResourceRecords), values(published.ResourceRecords))
 }
 
 //func (p *Provider) ReconcileHealthCheck(ctx context.Context, hc v1.HealthCheck, endpoint *v1.Endpoint) error {
@@ -147,28 +294,28 @@ func (p *Provider) Delete(record *v1.DNSRecord, zone v1.DNSZone) error {
 //}
 
 // change will perform an action on a record.
-func (p *Provider) change(record *v1.DNSRecord, zone v1.DNSZone, action action) error {
+func (p *Provider) change(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone, action action) error {
 	// Configure records.
-	err := p.updateRecord(record, zone.ID, string(action))
+	err := p.updateRecord(ctx, record, zone.ID, string(action))
 	if err != nil {
 		return fmt.Errorf("failed to update record in zone %s: %v", zone.ID, err)
 	}
 	switch action {
 	case upsertAction:
-		p.logger.Info("Upserted DNS record", "record", record.Spec, "zone", zone)
+		p.loggerFor(ctx).Info("Upserted DNS record", "record", record.Spec, "zone", zone)
 	case deleteAction:
-		p.logger.Info("Deleted DNS record", "record", record.Spec, "zone", zone)
+		p.loggerFor(ctx).Info("Deleted DNS record", "record", record.Spec, "zone", zone)
 	}
 	return nil
 }
 
-func (p *Provider) updateRecord(record *v1.DNSRecord, zoneID, action string) error {
+func (p *Provider) updateRecord(ctx context.Context, record *v1.DNSRecord, zoneID, action string) error {
 	input := route53.ChangeResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)}
 
 	expectedEndpointsMap := make(map[string]struct{})
 	var changes []*route53.Change
-	for _, endpoint := range record.Spec.Endpoints {
-		expectedEndpointsMap[endpoint.SetID()] = struct{}{}
+	for _, endpoint := range expandLocations(record.PublishedEndpoints()) {
+		expectedEndpointsMap[endpointKey(endpoint)] = struct{}{}
 		change, err := p.changeForEndpoint(endpoint, action)
 		if err != nil {
 			return err
@@ -176,21 +323,26 @@ func (p *Provider) updateRecord(record *v1.DNSRecord, zoneID, action string) err
 		changes = append(changes, change)
 	}
 
-	// Delete any previously published records that are no longer present in record.Spec.Endpoints
+	// Delete any previously published records that are no longer present in
+	// the record. Deletions go first, so a record set can be replaced by
+	// record sets with the same name and type in the same batch, e.g. an
+	// adopted simple record by weighted records
 	if action != string(deleteAction) {
 		lastPublishedEndpoints, err := p.endpointsFromZoneStatus(record, zoneID)
 		if err != nil {
 			return err
 		}
-		for _, endpoint := range lastPublishedEndpoints {
-			if _, found := expectedEndpointsMap[endpoint.SetID()]; !found {
+		var deletions []*route53.Change
+		for _, endpoint := range expandLocations(lastPublishedEndpoints) {
+			if _, found := expectedEndpointsMap[endpointKey(endpoint)]; !found {
 				change, err := p.changeForEndpoint(endpoint, string(deleteAction))
 				if err != nil {
 					return err
 				}
-				changes = append(changes, change)
+				deletions = append(deletions, change)
 			}
 		}
+		changes = append(deletions, changes...)
 	}
 
 	if len(changes) == 0 {
@@ -203,12 +355,30 @@ func (p *Provider) updateRecord(record *v1.DNSRecord, zoneID, action string) err
 	if err != nil {
 		return fmt.Errorf("couldn't update DNS record %s in zone %s: %v", record.Name, zoneID, err)
 	}
-	p.logger.Info("Updated DNS record", "record", record, "zone", zoneID, "response", resp)
+	p.loggerFor(ctx).Info("Updated DNS record", "record", record, "zone", zoneID, "response", resp)
 	return nil
 }
 
+// endpointKey identifies an endpoint among the endpoints of a record, which
+// may publish records of different types for the same name
+func endpointKey(endpoint *v1.Endpoint) string {
+	return endpoint.RecordType + "/" + endpoint.SetID()
+}
+
+// recordValue returns the value of a target as expected by Route53, which
+// requires TXT values to be quoted
+func recordValue(recordType, target string) string {
+	if recordType == string(v1.TXTRecordType) && !strings.HasPrefix(target, `"`) {
+		return `"` + strings.ReplaceAll(target, `"`, `\"`) + `"`
+	}
+	return target
+}
+
 func (p *Provider) changeForEndpoint(endpoint *v1.Endpoint, action string) (*route53.Change, error) {
-	if endpoint.RecordType != string(v1.ARecordType) && endpoint.RecordType != string(v1.CNAMERecordType) {
+	switch v1.DNSRecordType(endpoint.RecordType) {
+	case v1.ARecordType, v1.AAAARecordType, v1.NSRecordType, v1.CNAMERecordType, v1.TXTRecordType, v1.MXRecordType,
+		v1.CAARecordType, v1.SRVRecordType, v1.HTTPSRecordType, v1.SVCBRecordType, v1.DSRecordType:
+	default:
 		return nil, fmt.Errorf("unsupported record type %s", endpoint.RecordType)
 	}
 	domain, targets := endpoint.DNSName, endpoint.Targets
@@ -221,7 +391,7 @@ func (p *Provider) changeForEndpoint(endpoint *v1.Endpoint, action string) (*rou
 
 	var resourceRecords []*route53.ResourceRecord
 	for _, target := range endpoint.Targets {
-		resourceRecords = append(resourceRecords, &route53.ResourceRecord{Value: aws.String(target)})
+		resourceRecords = append(resourceRecords, &route53.ResourceRecord{Value: aws.String(recordValue(endpoint.RecordType, target))})
 	}
 
 	resourceRecordSet := &route53.ResourceRecordSet{
@@ -234,7 +404,9 @@ func (p *Provider) changeForEndpoint(endpoint *v1.Endpoint, action string) (*rou
 	if endpoint.SetIdentifier != "" {
 		resourceRecordSet.SetIdentifier = aws.String(endpoint.SetIdentifier)
 	}
-	if prop, ok := endpoint.GetProviderSpecificProperty(ProviderSpecificWeight); ok {
+	if endpoint.Weight != nil {
+		resourceRecordSet.Weight = aws.Int64(*endpoint.Weight)
+	} else if prop, ok := endpoint.GetProviderSpecificProperty(ProviderSpecificWeight); ok {
 		weight, err := strconv.ParseInt(prop.Value, 10, 64)
 		if err != nil {
 			p.logger.Error(err, "Failed parsing value, using weight of 0", "weight", ProviderSpecificWeight, "value", prop.Value)
@@ -251,10 +423,31 @@ func (p *Provider) changeForEndpoint(endpoint *v1.Endpoint, action string) (*rou
 	if _, ok := endpoint.GetProviderSpecificProperty(ProviderSpecificMultiValueAnswer); ok {
 		resourceRecordSet.MultiValueAnswer = aws.Bool(true)
 	}
+	if prop, ok := endpoint.GetProviderSpecificProperty(providerSpecificCIDRLocation); ok {
+		collection, err := p.stickyCollection()
+		if err != nil {
+			return nil, err
+		}
+		resourceRecordSet.CidrRoutingConfig = &route53.CidrRoutingConfig{
+			CollectionId: aws.String(collection),
+			LocationName: aws.String(prop.Value),
+		}
+	}
 
 	var geolocation = &route53.GeoLocation{}
 	useGeolocation := false
-	if prop, ok := endpoint.GetProviderSpecificProperty(ProviderSpecificGeolocationContinentCode); ok {
+	if endpoint.GeoLocation != nil {
+		if endpoint.GeoLocation.ContinentCode != "" {
+			geolocation.ContinentCode = aws.String(endpoint.GeoLocation.ContinentCode)
+		}
+		if endpoint.GeoLocation.CountryCode != "" {
+			geolocation.CountryCode = aws.String(endpoint.GeoLocation.CountryCode)
+		}
+		if endpoint.GeoLocation.SubdivisionCode != "" {
+			geolocation.SubdivisionCode = aws.String(endpoint.GeoLocation.SubdivisionCode)
+		}
+		useGeolocation = true
+	} else if prop, ok := endpoint.GetProviderSpecificProperty(ProviderSpecificGeolocationContinentCode); ok {
 		geolocation.ContinentCode = aws.String(prop.Value)
 		useGeolocation = true
 	} else {
@@ -271,7 +464,9 @@ func (p *Provider) changeForEndpoint(endpoint *v1.Endpoint, action string) (*rou
 		resourceRecordSet.GeoLocation = geolocation
 	}
 
-	if prop, ok := endpoint.GetProviderSpecificProperty(ProviderSpecificHealthCheckID); ok {
+	if endpoint.HealthCheckID != "" {
+		resourceRecordSet.HealthCheckId = aws.String(endpoint.HealthCheckID)
+	} else if prop, ok := endpoint.GetProviderSpecificProperty(ProviderSpecificHealthCheckID); ok {
 		resourceRecordSet.HealthCheckId = aws.String(prop.Value)
 	}
 
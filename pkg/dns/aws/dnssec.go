@@ -0,0 +1,49 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// dnssecStates maps the signing states of Route53 to the DNSSEC states of
+// the zones
+var dnssecStates = map[string]v1.DNSSECState{
+	"SIGNING":          v1.DNSSECStateSigning,
+	"NOT_SIGNING":      v1.DNSSECStateNotSigning,
+	"DELETING":         v1.DNSSECStateDeleting,
+	"ACTION_NEEDED":    v1.DNSSECStateActionNeeded,
+	"INTERNAL_FAILURE": v1.DNSSECStateFailed,
+}
+
+// DNSSEC returns the signing state of the hosted zone, with the DS records
+// of its active key signing keys. Route53 requires the DS records to be
+// removed from the parent zone before signing is disabled, so the zone
+// keeps resolving while the resolvers cache them
+func (p *Provider) DNSSEC(_ context.Context, zone v1.DNSZone) (*v1.ZoneDNSSECStatus, error) {
+	output, err := p.route53.GetDNSSEC(&route53.GetDNSSECInput{HostedZoneId: aws.String(zone.ID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DNSSEC of zone %s: %v", zone.ID, err)
+	}
+	return dnssecStatus(output), nil
+}
+
+func dnssecStatus(output *route53.GetDNSSECOutput) *v1.ZoneDNSSECStatus {
+	status := &v1.ZoneDNSSECStatus{State: v1.DNSSECStateFailed}
+	if output.Status != nil {
+		if state, ok := dnssecStates[aws.StringValue(output.Status.ServeSignature)]; ok {
+			status.State = state
+		}
+		status.Message = aws.StringValue(output.Status.StatusMessage)
+	}
+	for _, key := range output.KeySigningKeys {
+		if aws.StringValue(key.Status) == "ACTIVE" && aws.StringValue(key.DSRecord) != "" {
+			status.DSRecords = append(status.DSRecords, aws.StringValue(key.DSRecord))
+		}
+	}
+	return status
+}
@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+const (
+	// stickyCollectionName is the name of the CIDR collection splitting the
+	// resolvers of the sticky strategy into locations
+	stickyCollectionName = "mctc-sticky"
+	// stickyLocationBits is the prefix length of the IPv4 blocks of the
+	// locations, 2^stickyLocationBits locations
+	stickyLocationBits = 5
+	// defaultCIDRLocation is the location answering the resolvers outside of
+	// every block of the collection, such as IPv6 resolvers
+	defaultCIDRLocation = "*"
+	// providerSpecificCIDRLocation is the location of an endpoint routed by
+	// CIDR, expanded from the locations of a sticky endpoint
+	providerSpecificCIDRLocation = "aws/cidr-location"
+)
+
+// StickyLocations returns the resolver locations of the sticky strategy: a
+// location for each IPv4 block of the collection, and the default location
+func StickyLocations() []string {
+	locations := []string{defaultCIDRLocation}
+	for i := 0; i < 1<<stickyLocationBits; i++ {
+		locations = append(locations, stickyLocation(i))
+	}
+	return locations
+}
+
+func stickyLocation(i int) string {
+	return fmt.Sprintf("sticky-%d", i)
+}
+
+// stickyBlock returns the IPv4 block of the ith location
+func stickyBlock(i int) string {
+	return fmt.Sprintf("%d.0.0.0/%d", i<<(8-stickyLocationBits), stickyLocationBits)
+}
+
+// expandLocations returns the endpoints to publish for the endpoints, with
+// an endpoint routed by CIDR for each location a sticky endpoint is pinned
+// to, in place of the sticky endpoint. Sticky endpoints pinned to no
+// location aren't published
+func expandLocations(endpoints []*v1.Endpoint) []*v1.Endpoint {
+	expanded := make([]*v1.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		value, ok := endpoint.GetProviderSpecific(ProviderSpecificCIDRLocations)
+		if !ok {
+			expanded = append(expanded, endpoint)
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		for _, location := range strings.Split(value, ",") {
+			e := endpoint.DeepCopy()
+			e.DeleteProviderSpecific(ProviderSpecificCIDRLocations)
+			e.DeleteProviderSpecific(ProviderSpecificWeight)
+			e.Weight = nil
+			e.SetProviderSpecific(providerSpecificCIDRLocation, location)
+			suffix := location
+			if location == defaultCIDRLocation {
+				suffix = "default"
+			}
+			e.SetIdentifier = e.SetID() + "-" + suffix
+			expanded = append(expanded, e)
+		}
+	}
+	return expanded
+}
+
+// stickyCollection returns the ID of the CIDR collection of the sticky
+// strategy, creating it with its locations the first time it's needed
+func (p *Provider) stickyCollection() (string, error) {
+	p.stickyMu.Lock()
+	defer p.stickyMu.Unlock()
+	if p.stickyCollectionID != "" {
+		return p.stickyCollectionID, nil
+	}
+
+	id := ""
+	input := &route53.ListCidrCollectionsInput{}
+	for id == "" {
+		output, err := p.route53.ListCidrCollections(input)
+		if err != nil {
+			return "", fmt.Errorf("failed to list CIDR collections: %v", err)
+		}
+		for _, collection := range output.CidrCollections {
+			if aws.StringValue(collection.Name) == stickyCollectionName {
+				id = aws.StringValue(collection.Id)
+			}
+		}
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	if id == "" {
+		output, err := p.route53.CreateCidrCollection(&route53.CreateCidrCollectionInput{
+			Name:            aws.String(stickyCollectionName),
+			CallerReference: aws.String(stickyCollectionName),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to create CIDR collection %s: %v", stickyCollectionName, err)
+		}
+		id = aws.StringValue(output.Collection.Id)
+	}
+
+	changes := []*route53.CidrCollectionChange{}
+	for i := 0; i < 1<<stickyLocationBits; i++ {
+		changes = append(changes, &route53.CidrCollectionChange{
+			Action:       aws.String(route53.CidrCollectionChangeActionPut),
+			LocationName: aws.String(stickyLocation(i)),
+			CidrList:     []*string{aws.String(stickyBlock(i))},
+		})
+	}
+	if _, err := p.route53.ChangeCidrCollection(&route53.ChangeCidrCollectionInput{Id: aws.String(id), Changes: changes}); err != nil {
+		return "", fmt.Errorf("failed to put the locations of CIDR collection %s: %v", stickyCollectionName, err)
+	}
+	p.stickyCollectionID = id
+	return id, nil
+}
@@ -0,0 +1,123 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// Zones returns the hosted zones of the account, keyed by domain name
+func (p *Provider) Zones() (map[string]v1.DNSZone, error) {
+	zones := map[string]v1.DNSZone{}
+	input := &route53.ListHostedZonesInput{}
+	for {
+		output, err := p.route53.ListHostedZones(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list route53 hosted zones: %v", err)
+		}
+		for _, zone := range output.HostedZones {
+			domain := strings.TrimSuffix(strings.ToLower(aws.StringValue(zone.Name)), ".")
+			id := strings.TrimPrefix(aws.StringValue(zone.Id), "/hostedzone/")
+			zones[domain] = v1.DNSZone{ID: id}
+		}
+		if !aws.BoolValue(output.IsTruncated) {
+			return zones, nil
+		}
+		input.Marker = output.NextMarker
+	}
+}
+
+// Records returns the record sets published in the zone for the name as
+// endpoints. Alias record sets and record sets of types DNSRecords can't
+// publish are skipped
+func (p *Provider) Records(zone v1.DNSZone, name string) ([]*v1.Endpoint, error) {
+	var endpoints []*v1.Endpoint
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zone.ID),
+		StartRecordName: aws.String(name),
+	}
+	for {
+		output, err := p.route53.ListResourceRecordSets(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list record sets in zone %s: %v", zone.ID, err)
+		}
+		for _, recordSet := range output.ResourceRecordSets {
+			if strings.TrimSuffix(strings.ToLower(aws.StringValue(recordSet.Name)), ".") != strings.ToLower(name) {
+				return endpoints, nil
+			}
+			if endpoint := endpointForRecordSet(name, recordSet); endpoint != nil {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		if !aws.BoolValue(output.IsTruncated) {
+			return endpoints, nil
+		}
+		input.StartRecordName = output.NextRecordName
+		input.StartRecordType = output.NextRecordType
+		input.StartRecordIdentifier = output.NextRecordIdentifier
+	}
+}
+
+// ZoneRecords returns all the record sets published in the zone as
+// endpoints, skipping the same record sets as Records
+func (p *Provider) ZoneRecords(_ context.Context, zone v1.DNSZone) ([]*v1.Endpoint, error) {
+	var endpoints []*v1.Endpoint
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(zone.ID),
+	}
+	for {
+		output, err := p.route53.ListResourceRecordSets(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list record sets in zone %s: %v", zone.ID, err)
+		}
+		for _, recordSet := range output.ResourceRecordSets {
+			// route53 escapes the wildcard of wildcard names
+			name := strings.TrimSuffix(aws.StringValue(recordSet.Name), ".")
+			name = strings.Replace(name, `\052`, "*", 1)
+			if endpoint := endpointForRecordSet(name, recordSet); endpoint != nil {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		if !aws.BoolValue(output.IsTruncated) {
+			return endpoints, nil
+		}
+		input.StartRecordName = output.NextRecordName
+		input.StartRecordType = output.NextRecordType
+		input.StartRecordIdentifier = output.NextRecordIdentifier
+	}
+}
+
+// endpointForRecordSet returns the endpoint publishing the record set, or
+// nil when it can't be published by a DNSRecord. NS record sets are left
+// out, as the NS records of the zone apex are managed by Route53, and so are
+// the record sets routed by CIDR, expanded from sticky endpoints
+func endpointForRecordSet(name string, recordSet *route53.ResourceRecordSet) *v1.Endpoint {
+	if recordSet.AliasTarget != nil || recordSet.CidrRoutingConfig != nil {
+		return nil
+	}
+	switch v1.DNSRecordType(aws.StringValue(recordSet.Type)) {
+	case v1.ARecordType, v1.AAAARecordType, v1.CNAMERecordType, v1.TXTRecordType, v1.MXRecordType, v1.CAARecordType,
+		v1.SRVRecordType, v1.HTTPSRecordType, v1.SVCBRecordType, v1.DSRecordType:
+	default:
+		return nil
+	}
+	endpoint := &v1.Endpoint{
+		DNSName:       name,
+		RecordType:    aws.StringValue(recordSet.Type),
+		SetIdentifier: aws.StringValue(recordSet.SetIdentifier),
+		RecordTTL:     v1.TTL(aws.Int64Value(recordSet.TTL)),
+	}
+	for _, record := range recordSet.ResourceRecords {
+		endpoint.Targets = append(endpoint.Targets, aws.StringValue(record.Value))
+	}
+	if recordSet.Weight != nil {
+		endpoint.SetProviderSpecific(ProviderSpecificWeight, strconv.FormatInt(*recordSet.Weight, 10))
+	}
+	return endpoint
+}
@@ -0,0 +1,142 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+const (
+	// endpointLabelCAA marks the CAA record published for the certificate
+	// authorities of the controller among the raw records of a DNSRecord
+	endpointLabelCAA = "kuadrant.io/caa"
+
+	caaTTL v1.TTL = 300
+)
+
+// CAAResolver looks up the CAA records of a domain
+type CAAResolver interface {
+	LookupCAA(ctx context.Context, domain string) ([]string, error)
+}
+
+// CAAValues returns the values of the CAA record allowing the certificate
+// authorities to issue certificates
+func CAAValues(authorities []string) []string {
+	values := make([]string, 0, len(authorities))
+	for _, authority := range authorities {
+		values = append(values, fmt.Sprintf("0 issue %q", authority))
+	}
+	return values
+}
+
+// SetCAARecord publishes a CAA record allowing the certificate authorities
+// for the host of the record, or removes it when there are none. The CAA
+// records set by users among the raw records take precedence, so none is
+// published alongside them. Returns true when the record changed
+func SetCAARecord(record *v1.DNSRecord, authorities []string) bool {
+	var rawRecords []*v1.Endpoint
+	var current, desired *v1.Endpoint
+	userCAA := false
+	for _, raw := range record.Spec.RawRecords {
+		if raw.Labels[endpointLabelCAA] == "true" {
+			current = raw
+			continue
+		}
+		if raw.RecordType == string(v1.CAARecordType) && (raw.DNSName == "" || raw.DNSName == record.Name) {
+			userCAA = true
+		}
+		rawRecords = append(rawRecords, raw)
+	}
+	if len(authorities) > 0 && !userCAA {
+		desired = &v1.Endpoint{
+			DNSName:    record.Name,
+			Targets:    CAAValues(authorities),
+			RecordType: string(v1.CAARecordType),
+			RecordTTL:  caaTTL,
+			Labels:     v1.Labels{endpointLabelCAA: "true"},
+		}
+		rawRecords = append(rawRecords, desired)
+	}
+	if equality.Semantic.DeepEqual(current, desired) {
+		return false
+	}
+	record.Spec.RawRecords = rawRecords
+	return true
+}
+
+// EnsureCAA publishes a CAA record allowing the certificate authorities of
+// the controller for the host of the record
+func (s *Service) EnsureCAA(ctx context.Context, record *v1.DNSRecord) error {
+	return s.retryOnConflict(ctx, record, func(record *v1.DNSRecord) error {
+		if !SetCAARecord(record, s.config.Get().CertificateAuthorities) {
+			return nil
+		}
+		return s.controlClient.Update(ctx, record, fieldOwner)
+	})
+}
+
+// RecordCAA returns the values of the CAA records published for the host
+// of the record
+func RecordCAA(record *v1.DNSRecord) []string {
+	var values []string
+	for _, endpoint := range record.PublishedEndpoints() {
+		if endpoint.RecordType == string(v1.CAARecordType) && endpoint.DNSName == record.Name {
+			values = append(values, endpoint.Targets...)
+		}
+	}
+	return values
+}
+
+// RelevantCAA returns the CAA records that apply to the host, which are the
+// records of the closest domain of the host, itself included, that has any.
+// Also returns that domain
+func RelevantCAA(ctx context.Context, resolver CAAResolver, host string) ([]string, string, error) {
+	domain := strings.TrimSuffix(host, ".")
+	for domain != "" {
+		values, err := resolver.LookupCAA(ctx, domain)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(values) > 0 {
+			return values, domain, nil
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	return nil, "", nil
+}
+
+// CAAAllows returns whether the CAA record values allow one of the
+// certificate authorities to issue a certificate. Values without an issue
+// property don't restrict issuance
+func CAAAllows(values []string, authorities []string) bool {
+	restricted := false
+	for _, value := range values {
+		tag, issuer, ok := parseCAA(value)
+		if !ok || tag != "issue" {
+			continue
+		}
+		restricted = true
+		for _, authority := range authorities {
+			if strings.EqualFold(issuer, authority) {
+				return true
+			}
+		}
+	}
+	return !restricted
+}
+
+// parseCAA parses a CAA record value, e.g. 0 issue "letsencrypt.org", into
+// its tag and the issuer domain of its value
+func parseCAA(value string) (string, string, bool) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return "", "", false
+	}
+	issuer := strings.Trim(strings.Join(fields[2:], " "), `"`)
+	issuer, _, _ = strings.Cut(issuer, ";")
+	return strings.ToLower(fields[1]), strings.TrimSpace(issuer), true
+}
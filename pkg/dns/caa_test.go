@@ -0,0 +1,59 @@
+package dns
+
+import (
+	"testing"
+)
+
+func TestCAAAllows(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []string
+		authorities []string
+		expect      bool
+	}{
+		{
+			name:        "no CAA records",
+			authorities: []string{"letsencrypt.org"},
+			expect:      true,
+		},
+		{
+			name:        "authority allowed",
+			values:      []string{`0 issue "digicert.com"`, `0 issue "letsencrypt.org"`},
+			authorities: []string{"letsencrypt.org"},
+			expect:      true,
+		},
+		{
+			name:        "authority allowed with parameters",
+			values:      []string{`0 issue "LetsEncrypt.org; validationmethods=dns-01"`},
+			authorities: []string{"letsencrypt.org"},
+			expect:      true,
+		},
+		{
+			name:        "other authority allowed",
+			values:      []string{`0 issue "digicert.com"`},
+			authorities: []string{"letsencrypt.org"},
+			expect:      false,
+		},
+		{
+			name:        "issuance forbidden",
+			values:      []string{`0 issue ";"`},
+			authorities: []string{"letsencrypt.org"},
+			expect:      false,
+		},
+		{
+			name:        "only wildcard issuance restricted",
+			values:      []string{`0 issuewild "digicert.com"`, `0 iodef "mailto:security@example.com"`},
+			authorities: []string{"letsencrypt.org"},
+			expect:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CAAAllows(tt.values, tt.authorities)
+			if got != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package dns
+
+import (
+	"fmt"
+	"time"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// ChangesAllowed returns whether the changes to the records of the zone can
+// be written to the provider at the time. When they can't, it returns the
+// time the next change window of the zone opens, which is zero while the
+// zone is frozen. Windows that aren't valid never open
+func ChangesAllowed(zone *v1.ManagedZone, now time.Time) (bool, time.Time) {
+	if zone.Spec.ChangeFreeze {
+		return false, time.Time{}
+	}
+	if len(zone.Spec.ChangeWindows) == 0 {
+		return true, time.Time{}
+	}
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var next time.Time
+	for _, window := range zone.Spec.ChangeWindows {
+		start, err := minuteOfDay(window.Start)
+		if err != nil {
+			continue
+		}
+		end, err := minuteOfDay(window.End)
+		if err != nil {
+			continue
+		}
+		length := end - start
+		if length <= 0 {
+			length += 24 * time.Hour
+		}
+		// the window opened yesterday may still be open, and the next one
+		// opens within a week
+		for day := -1; day <= 7; day++ {
+			opens := today.AddDate(0, 0, day).Add(start)
+			if !opensOn(window, opens.Weekday()) {
+				continue
+			}
+			if !now.Before(opens) && now.Before(opens.Add(length)) {
+				return true, time.Time{}
+			}
+			if opens.After(now) && (next.IsZero() || opens.Before(next)) {
+				next = opens
+			}
+		}
+	}
+	return false, next
+}
+
+// minuteOfDay parses a time of day formatted as HH:MM
+func minuteOfDay(value string) (time.Duration, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", value, err)
+	}
+	if hours < 0 || hours > 23 || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+func opensOn(window v1.ChangeWindow, weekday time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+	for _, day := range window.Days {
+		if string(day) == weekday.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package dns
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestChangesAllowed(t *testing.T) {
+	// a Friday
+	now := time.Date(2023, time.November, 24, 22, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		spec        v1.ManagedZoneSpec
+		expect      bool
+		expectOpens time.Time
+	}{
+		{
+			name:   "no change windows",
+			expect: true,
+		},
+		{
+			name:   "zone frozen",
+			spec:   v1.ManagedZoneSpec{ChangeFreeze: true, ChangeWindows: []v1.ChangeWindow{{Start: "00:00", End: "00:00"}}},
+			expect: false,
+		},
+		{
+			name:   "window open",
+			spec:   v1.ManagedZoneSpec{ChangeWindows: []v1.ChangeWindow{{Start: "22:00", End: "23:00"}}},
+			expect: true,
+		},
+		{
+			name:        "window of yesterday closed until later today",
+			spec:        v1.ManagedZoneSpec{ChangeWindows: []v1.ChangeWindow{{Days: []v1.Weekday{"Thursday"}, Start: "20:00", End: "23:00"}, {Days: []v1.Weekday{"Friday"}, Start: "23:00", End: "01:00"}}},
+			expect:      false,
+			expectOpens: time.Date(2023, time.November, 24, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "window closed until next week",
+			spec:        v1.ManagedZoneSpec{ChangeWindows: []v1.ChangeWindow{{Days: []v1.Weekday{"Tuesday"}, Start: "09:00", End: "17:00"}}},
+			expect:      false,
+			expectOpens: time.Date(2023, time.November, 28, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "window through midnight open",
+			spec:   v1.ManagedZoneSpec{ChangeWindows: []v1.ChangeWindow{{Days: []v1.Weekday{"Friday"}, Start: "22:00", End: "02:00"}}},
+			expect: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, opens := ChangesAllowed(&v1.ManagedZone{Spec: tt.spec}, now)
+			if allowed != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, allowed)
+			}
+			if !opens.Equal(tt.expectOpens) {
+				t.Errorf("expected '%v' got '%v'", tt.expectOpens, opens)
+			}
+		})
+	}
+}
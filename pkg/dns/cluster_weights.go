@@ -0,0 +1,87 @@
+package dns
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// AnnotationClusterWeights holds the relative weight of the clusters serving
+// a DNSRecord, as a comma separated list of cluster=weight pairs. The share
+// of the traffic of the listed clusters is split between them according to
+// their weight, the other clusters keep their share
+const AnnotationClusterWeights = "kuadrant.io/cluster-weights"
+
+// ClusterWeights returns the cluster weights set on the record. Invalid
+// entries are ignored
+func ClusterWeights(record *v1.DNSRecord) map[string]int {
+	value := metadata.GetAnnotation(record, AnnotationClusterWeights)
+	if value == "" {
+		return nil
+	}
+	weights := map[string]int{}
+	for _, pair := range strings.Split(value, ",") {
+		cluster, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+		weight, err := strconv.Atoi(v)
+		if !found || err != nil || weight < 0 {
+			log.Log.Info("ignoring invalid cluster weight", "record", record.Name, "entry", pair)
+			continue
+		}
+		weights[cluster] = weight
+	}
+	return weights
+}
+
+// SetClusterWeights sets the cluster weights of the record and updates the
+// weights of its endpoints accordingly. Returns true when the record changed
+func SetClusterWeights(record *v1.DNSRecord, weights map[string]int) bool {
+	current := record.DeepCopy()
+	pairs := make([]string, 0, len(weights))
+	for cluster, weight := range weights {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", cluster, weight))
+	}
+	sort.Strings(pairs)
+	metadata.AddAnnotation(record, AnnotationClusterWeights, strings.Join(pairs, ","))
+	setEndpointWeights(record.Spec.Endpoints, weights)
+	return !equality.Semantic.DeepEqual(current.Annotations, record.Annotations) ||
+		!endpointsEqual(current.Spec.Endpoints, record.Spec.Endpoints)
+}
+
+// RemoveClusterWeights removes the weights of the clusters from the cluster
+// weights of the record and updates the weights of its endpoints
+// accordingly, so the clusters get their default share of the traffic back.
+// Returns true when the record changed
+func RemoveClusterWeights(record *v1.DNSRecord, clusters ...string) bool {
+	current := record.DeepCopy()
+	weights := ClusterWeights(record)
+	for _, cluster := range clusters {
+		delete(weights, cluster)
+	}
+	if len(weights) == 0 {
+		metadata.RemoveAnnotation(record, AnnotationClusterWeights)
+		setEndpointWeights(record.Spec.Endpoints, nil)
+	} else {
+		SetClusterWeights(record, weights)
+	}
+	return !equality.Semantic.DeepEqual(current.Annotations, record.Annotations) ||
+		!endpointsEqual(current.Spec.Endpoints, record.Spec.Endpoints)
+}
+
+// ClusterAddresses returns the addresses published for the cluster in the
+// record of the host
+func ClusterAddresses(record *v1.DNSRecord, cluster string) []string {
+	var addresses []string
+	for _, endpoint := range record.Spec.Endpoints {
+		if endpoint.DNSName == record.Name && EndpointCluster(endpoint) == cluster {
+			addresses = append(addresses, endpoint.Targets...)
+		}
+	}
+	return addresses
+}
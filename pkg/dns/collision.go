@@ -0,0 +1,160 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// AnnotationHostCollisions is set on traffic objects rejected from hosts
+// managed for another traffic object, with the hosts separated by commas
+const AnnotationHostCollisions = "kuadrant.io/host-collisions"
+
+// HostCollisionError is returned when a traffic object requests a host
+// managed for another traffic object or claimed by a HostClaim it doesn't
+// reference
+type HostCollisionError struct {
+	Host  string
+	Owner string
+}
+
+func (e *HostCollisionError) Error() string {
+	return fmt.Sprintf("host %s is owned by %s", e.Host, e.Owner)
+}
+
+// collides returns true when the ManagedHost references a traffic object of
+// another kind, namespace or name. The traffic objects of the same kind,
+// namespace and name in each cluster share their hosts
+func collides(managedHost *v1.ManagedHost, t traffic.Interface) bool {
+	ref := managedHost.Spec.TrafficRef
+	return ref.Kind != t.GetKind() || ref.Namespace != t.GetNamespace() || ref.Name != t.GetName()
+}
+
+// admittedRecords returns the records the traffic object publishes its
+// endpoints to. With the Reject host collision policy, the records of hosts
+// managed for another traffic object are left out and their hosts recorded
+// on the traffic object. The endpoints it published to them before, under
+// another policy, are withdrawn
+func (s *Service) admittedRecords(ctx context.Context, t traffic.Interface, records []*v1.DNSRecord) ([]*v1.DNSRecord, error) {
+	if s.config.Get().HostCollision != v1.HostCollisionReject {
+		metadata.RemoveAnnotation(t, AnnotationHostCollisions)
+		return records, nil
+	}
+	admitted := []*v1.DNSRecord{}
+	rejected := []string{}
+	for _, record := range records {
+		managedHost := &v1.ManagedHost{}
+		if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: record.Namespace, Name: record.Name}, managedHost); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return nil, err
+			}
+			admitted = append(admitted, record)
+			continue
+		}
+		if collides(managedHost, t) {
+			logger(ctx).Info("host managed for another traffic object, rejecting", "host", record.Name, "owner", managedHost.Spec.TrafficRef)
+			rejected = append(rejected, record.Name)
+			if err := s.withdrawRejected(ctx, t, record); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		admitted = append(admitted, record)
+	}
+	if len(rejected) == 0 {
+		metadata.RemoveAnnotation(t, AnnotationHostCollisions)
+	} else {
+		metadata.AddAnnotation(t, AnnotationHostCollisions, strings.Join(rejected, ","))
+	}
+	return admitted, nil
+}
+
+// withdrawRejected removes the endpoints the traffic object published to the
+// record of a host it's rejected from. Only the endpoints labelled with the
+// traffic object as owner are removed, the owner of the host may publish the
+// same addresses
+func (s *Service) withdrawRejected(ctx context.Context, t traffic.Interface, record *v1.DNSRecord) error {
+	targets, err := t.GetDNSTargets()
+	if err != nil || len(targets) == 0 {
+		return err
+	}
+	owner := endpointOwner(targets[0].Cluster, t)
+	return s.retryOnConflict(ctx, record, func(record *v1.DNSRecord) error {
+		endpoints := []*v1.Endpoint{}
+		for _, endpoint := range record.Spec.Endpoints {
+			if endpoint.Labels[endpointLabelOwner] != owner {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		if len(endpoints) == len(record.Spec.Endpoints) {
+			return nil
+		}
+		logger(ctx).Info("withdrawing endpoints from host managed for another traffic object", "host", record.Name, "owner", owner)
+		record.Spec.Endpoints = endpoints
+		return s.controlClient.Update(ctx, record, fieldOwner)
+	})
+}
+
+// ValidateHostCollisions checks the hosts requested by the traffic object
+// against the hosts managed and claimed already. With the Deny admission host
+// collision policy, a HostCollisionError is returned for the first host owned
+// by another object, and with the Warn policy a warning is returned for each
+func (s *Service) ValidateHostCollisions(ctx context.Context, t traffic.Interface) ([]string, error) {
+	policy := s.config.Get().AdmissionHostCollision
+	if policy != v1.AdmissionHostCollisionWarn && policy != v1.AdmissionHostCollisionDeny {
+		return nil, nil
+	}
+	collisions, err := s.hostCollisions(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	warnings := []string{}
+	for _, collision := range collisions {
+		if policy == v1.AdmissionHostCollisionDeny {
+			return nil, collision
+		}
+		warnings = append(warnings, collision.Error())
+	}
+	return warnings, nil
+}
+
+// hostCollisions returns the hosts of the traffic object managed for another
+// traffic object, or claimed by a HostClaim it doesn't reference
+func (s *Service) hostCollisions(ctx context.Context, t traffic.Interface) ([]*HostCollisionError, error) {
+	claims := traffic.HostClaims(t)
+	collisions := []*HostCollisionError{}
+	for _, host := range t.GetHosts() {
+		managedHost := &v1.ManagedHost{}
+		err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: host}, managedHost)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil && collides(managedHost, t) {
+			ref := managedHost.Spec.TrafficRef
+			collisions = append(collisions, &HostCollisionError{Host: host, Owner: fmt.Sprintf("%s %s/%s", ref.Kind, ref.Namespace, ref.Name)})
+			continue
+		}
+
+		record := &v1.DNSRecord{}
+		if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: host}, record); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		claim := ClaimOf(record)
+		namespace, name, _ := strings.Cut(claim, "/")
+		if claim != "" && (namespace != t.GetNamespace() || !slice.ContainsString(claims, name)) {
+			collisions = append(collisions, &HostCollisionError{Host: host, Owner: "HostClaim " + claim})
+		}
+	}
+	return collisions, nil
+}
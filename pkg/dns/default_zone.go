@@ -0,0 +1,160 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// AnnotationDefaultZone marks a public ManagedZone of the controller
+// namespace as the zone the managed hosts of traffic objects are assigned
+// from. Set to "true" for the traffic objects of every namespace, or to a
+// comma separated list of the namespaces of the traffic objects
+const AnnotationDefaultZone = "kuadrant.io/default-zone"
+
+// DefaultNamespaces returns the namespaces of the traffic objects the zone
+// is marked as the default zone of, and whether it's marked as the default
+// zone of every namespace
+func DefaultNamespaces(zone *v1.ManagedZone) ([]string, bool) {
+	value := strings.TrimSpace(metadata.GetAnnotation(zone, AnnotationDefaultZone))
+	if value == "" || zone.Spec.Visibility == v1.ZoneVisibilityPrivate {
+		return nil, false
+	}
+	if value == "true" {
+		return nil, true
+	}
+	namespaces := []string{}
+	for _, namespace := range strings.Split(value, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces, false
+}
+
+// DefaultFor returns whether the zone is the default zone of the traffic
+// objects of the namespace, and whether it's specifically their default
+// rather than the default of every namespace
+func DefaultFor(zone *v1.ManagedZone, namespace string) (bool, bool) {
+	namespaces, all := DefaultNamespaces(zone)
+	if slice.ContainsString(namespaces, namespace) {
+		return true, true
+	}
+	return all, false
+}
+
+// defaultZone returns the ManagedZone the managed hosts of the traffic
+// objects of the namespace are assigned from, or nil when no zone is marked
+// as their default. A zone marked for the namespace is preferred over a
+// zone marked for every namespace. When several zones are marked alike, the
+// first by name is used so hosts are assigned predictably
+func (s *Service) defaultZone(ctx context.Context, namespace string) (*v1.ManagedZone, error) {
+	zones := &v1.ManagedZoneList{}
+	if err := s.controlClient.List(ctx, zones, client.InNamespace(s.defaultCtrlNS)); err != nil {
+		return nil, err
+	}
+	sort.Slice(zones.Items, func(i, j int) bool {
+		return zones.Items[i].Name < zones.Items[j].Name
+	})
+	var found *v1.ManagedZone
+	for i := range zones.Items {
+		zone := &zones.Items[i]
+		isDefault, specific := DefaultFor(zone, namespace)
+		if !isDefault {
+			continue
+		}
+		if specific {
+			return zone, nil
+		}
+		if found == nil {
+			found = zone
+		}
+	}
+	return found, nil
+}
+
+// ZoneSelectionError is returned when the ManagedZone selected by the
+// kuadrant.io/managed-zone annotation of a traffic object can't be used
+type ZoneSelectionError struct {
+	Zone   string
+	Reason string
+}
+
+func (e *ZoneSelectionError) Error() string {
+	return fmt.Sprintf("invalid %s annotation: ManagedZone %s %s", traffic.AnnotationManagedZone, e.Zone, e.Reason)
+}
+
+// ValidateManagedZone returns a ZoneSelectionError when the traffic object
+// selects a ManagedZone that doesn't exist or doesn't have its visibility.
+// In the namespace-as-tenant mode, a public traffic object can only select
+// the root zone of the tenants, and a TenantDomainError is returned for its
+// hosts in the root zone outside of the subdomain of its namespace
+func (s *Service) ValidateManagedZone(ctx context.Context, t traffic.Interface) error {
+	selected, err := s.selectedZone(ctx, t)
+	if err != nil || traffic.Private(t) {
+		return err
+	}
+	tenantZone, err := s.TenantZone(ctx)
+	if err != nil || tenantZone == nil {
+		return err
+	}
+	if selected != nil && selected.Name != tenantZone.Name {
+		return &ZoneSelectionError{Zone: selected.Name, Reason: fmt.Sprintf("is not the root zone %s of the tenants", tenantZone.Name)}
+	}
+	domain := TenantDomain(t.GetNamespace(), tenantZone.Spec.DomainName)
+	for _, host := range t.GetHosts() {
+		if InTenantDomain(host, tenantZone.Spec.DomainName) && !InTenantDomain(host, domain) {
+			return &TenantDomainError{Host: host, Domain: domain}
+		}
+	}
+	return nil
+}
+
+// selectedZone returns the ManagedZone selected by the traffic object, or
+// nil when it doesn't select one. A ZoneSelectionError is returned when the
+// zone can't be selected by the namespace of the traffic object
+func (s *Service) selectedZone(ctx context.Context, t traffic.Interface) (*v1.ManagedZone, error) {
+	name := traffic.ManagedZone(t)
+	if name == "" {
+		return nil, nil
+	}
+	zone := &v1.ManagedZone{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: name}, zone); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, &ZoneSelectionError{Zone: name, Reason: fmt.Sprintf("not found in namespace %s", s.defaultCtrlNS)}
+		}
+		return nil, err
+	}
+	private := zone.Spec.Visibility == v1.ZoneVisibilityPrivate
+	if private != traffic.Private(t) {
+		visibility := zone.Spec.Visibility
+		if visibility == "" {
+			visibility = v1.ZoneVisibilityPublic
+		}
+		return nil, &ZoneSelectionError{Zone: name, Reason: fmt.Sprintf("is %s, unlike the traffic object", visibility)}
+	}
+	if !SelectableBy(zone, t.GetNamespace()) {
+		return nil, &ZoneSelectionError{Zone: name, Reason: fmt.Sprintf("can't be selected by the traffic objects of namespace %s", t.GetNamespace())}
+	}
+	return zone, nil
+}
+
+// SelectableBy returns true when the traffic objects of the namespace can
+// select the zone, as it's one of its allowed namespaces or, when it has
+// none, as it's the default zone of the namespace
+func SelectableBy(zone *v1.ManagedZone, namespace string) bool {
+	if len(zone.Spec.AllowedNamespaces) == 0 {
+		isDefault, _ := DefaultFor(zone, namespace)
+		return isDefault
+	}
+	return slice.ContainsString(zone.Spec.AllowedNamespaces, "*") || slice.ContainsString(zone.Spec.AllowedNamespaces, namespace)
+}
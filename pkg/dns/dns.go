@@ -17,21 +17,35 @@ limitations under the License.
 package dns
 
 import (
+	"context"
+
 	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
 )
 
 // Provider knows how to manage DNS zones only as pertains to routing.
 type Provider interface {
 	// Ensure will create or update record.
-	Ensure(record *v1.DNSRecord, zone v1.DNSZone) error
+	Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error
 
 	// Delete will delete record.
-	Delete(record *v1.DNSRecord, zone v1.DNSZone) error
+	Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error
+}
+
+// Verifier is implemented by providers that can detect out of band changes
+// to the records they published
+type Verifier interface {
+	// Verify returns the endpoints of the record that don't match the records
+	// published in the zone.
+	Verify(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) ([]*v1.Endpoint, error)
 }
 
 var _ Provider = &FakeProvider{}
 
 type FakeProvider struct{}
 
-func (_ *FakeProvider) Ensure(record *v1.DNSRecord, zone v1.DNSZone) error { return nil }
-func (_ *FakeProvider) Delete(record *v1.DNSRecord, zone v1.DNSZone) error { return nil }
+func (_ *FakeProvider) Ensure(_ context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return nil
+}
+func (_ *FakeProvider) Delete(_ context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	return nil
+}
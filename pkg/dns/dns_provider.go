@@ -19,6 +19,8 @@ package dns
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
+
 	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
 )
 
@@ -44,3 +46,26 @@ func newAWSDNSProvider() (Provider, error) {
 
 	return dnsProvider, nil
 }
+
+// DNSProviderFromSecret creates a provider using the credentials held in the
+// secret instead of the credentials of the controller
+func DNSProviderFromSecret(dnsProviderName string, secret *corev1.Secret) (Provider, error) {
+	switch dnsProviderName {
+	case "aws":
+		config := dnsAWS.Config{
+			AccessKeyID:     string(secret.Data["AWS_ACCESS_KEY_ID"]),
+			SecretAccessKey: string(secret.Data["AWS_SECRET_ACCESS_KEY"]),
+			Region:          string(secret.Data["AWS_REGION"]),
+		}
+		if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+			return nil, fmt.Errorf("secret %s/%s is missing AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY", secret.Namespace, secret.Name)
+		}
+		provider, err := dnsAWS.NewProvider(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS DNS manager: %v", err)
+		}
+		return provider, nil
+	default:
+		return &FakeProvider{}, nil
+	}
+}
@@ -0,0 +1,55 @@
+package dns
+
+import (
+	"context"
+	"strings"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// dsRecordTTL is the TTL of the DS records published for signed sub-zones
+const dsRecordTTL v1.TTL = 3600
+
+// DNSSECReporter is implemented by providers that can report the DNSSEC
+// signing state of their zones
+type DNSSECReporter interface {
+	// DNSSEC returns the signing state of the zone
+	DNSSEC(ctx context.Context, zone v1.DNSZone) (*v1.ZoneDNSSECStatus, error)
+}
+
+// DSRecordName returns the name of the DNSRecord publishing the DS records
+// of the zone in its parent zone
+func DSRecordName(zone *v1.ManagedZone) string {
+	return zone.Spec.DomainName + "-ds"
+}
+
+// ParentZone returns the zone of the closest parent domain of the zone, or
+// nil when none of the zones is a parent of the zone
+func ParentZone(zone *v1.ManagedZone, zones []v1.ManagedZone) *v1.ManagedZone {
+	var parent *v1.ManagedZone
+	for i := range zones {
+		candidate := &zones[i]
+		if !strings.HasSuffix(zone.Spec.DomainName, "."+candidate.Spec.DomainName) {
+			continue
+		}
+		if parent == nil || len(candidate.Spec.DomainName) > len(parent.Spec.DomainName) {
+			parent = candidate
+		}
+	}
+	return parent
+}
+
+// DSEndpoint returns the endpoint publishing the DS records of the signed
+// zone, or nil when the zone isn't signing or has no active key
+func DSEndpoint(zone *v1.ManagedZone) *v1.Endpoint {
+	dnssec := zone.Status.DNSSEC
+	if dnssec == nil || dnssec.State != v1.DNSSECStateSigning || len(dnssec.DSRecords) == 0 {
+		return nil
+	}
+	return &v1.Endpoint{
+		DNSName:    zone.Spec.DomainName,
+		Targets:    append(v1.Targets{}, dnssec.DSRecords...),
+		RecordType: string(v1.DSRecordType),
+		RecordTTL:  dsRecordTTL,
+	}
+}
@@ -0,0 +1,97 @@
+package dns
+
+import (
+	"testing"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestParentZone(t *testing.T) {
+	zone := func(name, domain string) v1.ManagedZone {
+		return v1.ManagedZone{Spec: v1.ManagedZoneSpec{ID: name, DomainName: domain}}
+	}
+	zones := []v1.ManagedZone{
+		zone("root", "example.com"),
+		zone("apps", "apps.example.com"),
+		zone("other", "otherexample.com"),
+	}
+	tests := []struct {
+		name   string
+		domain string
+		expect string
+	}{
+		{
+			name:   "closest parent zone",
+			domain: "eu.apps.example.com",
+			expect: "apps",
+		},
+		{
+			name:   "zone is not its own parent",
+			domain: "example.com",
+		},
+		{
+			name:   "domain suffix is not a parent",
+			domain: "example.org",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subZone := zone("sub", tt.domain)
+			got := ""
+			if parent := ParentZone(&subZone, zones); parent != nil {
+				got = parent.Spec.ID
+			}
+			if got != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestDSEndpoint(t *testing.T) {
+	tests := []struct {
+		name   string
+		dnssec *v1.ZoneDNSSECStatus
+		expect []string
+	}{
+		{
+			name: "signing zone",
+			dnssec: &v1.ZoneDNSSECStatus{
+				State:     v1.DNSSECStateSigning,
+				DSRecords: []string{"12345 13 2 ABCDEF"},
+			},
+			expect: []string{"12345 13 2 ABCDEF"},
+		},
+		{
+			name: "zone deleting its signing",
+			dnssec: &v1.ZoneDNSSECStatus{
+				State:     v1.DNSSECStateDeleting,
+				DSRecords: []string{"12345 13 2 ABCDEF"},
+			},
+		},
+		{
+			name:   "signing zone without active key",
+			dnssec: &v1.ZoneDNSSECStatus{State: v1.DNSSECStateSigning},
+		},
+		{
+			name: "unknown signing state",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := &v1.ManagedZone{Spec: v1.ManagedZoneSpec{DomainName: "apps.example.com"}, Status: v1.ManagedZoneStatus{DNSSEC: tt.dnssec}}
+			var got []string
+			if endpoint := DSEndpoint(zone); endpoint != nil {
+				got = endpoint.Targets
+				if endpoint.DNSName != "apps.example.com" || endpoint.RecordType != string(v1.DSRecordType) {
+					t.Errorf("expected '%v' got '%v'", "apps.example.com DS", endpoint.DNSName+" "+endpoint.RecordType)
+				}
+			}
+			if len(got) != len(tt.expect) || (len(got) > 0 && got[0] != tt.expect[0]) {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateHost returns an error when the host isn't a valid RFC 1123
+// hostname, or when it's a public suffix or the apex of a registrable domain,
+// which can't be served by the records we publish. A leading `*.` label is
+// allowed for wildcard hosts
+func ValidateHost(host string) error {
+	name := strings.TrimPrefix(host, "*.")
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid host %s: %s", host, strings.Join(errs, ", "))
+	}
+	for _, label := range strings.Split(name, ".") {
+		if errs := validation.IsDNS1123Label(label); len(errs) > 0 {
+			return fmt.Errorf("invalid host %s: %s", host, strings.Join(errs, ", "))
+		}
+	}
+
+	suffix, _ := publicsuffix.PublicSuffix(name)
+	if name == suffix {
+		return fmt.Errorf("invalid host %s: %s is a public suffix", host, name)
+	}
+	apex, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return fmt.Errorf("invalid host %s: %v", host, err)
+	}
+	if name == apex && name == host {
+		return fmt.Errorf("invalid host %s: the apex of domain %s can't be served, use a subdomain", host, apex)
+	}
+	return nil
+}
+
+// ValidateHosts returns an error for the first host that isn't valid. Empty
+// hosts, of rules matching any host, are ignored
+func ValidateHosts(hosts []string) error {
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+		if err := ValidateHost(host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
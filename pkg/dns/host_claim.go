@@ -0,0 +1,173 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// AnnotationHostClaim is set on the DNSRecord of a claimed host to the
+// namespace/name of its HostClaim
+const AnnotationHostClaim = "kuadrant.io/host-claim"
+
+// HostTakenErr is returned when the host of a HostClaim has a DNSRecord
+// already, for another claim or a traffic object
+var HostTakenErr = fmt.Errorf("host taken")
+
+// ClaimKey returns the namespace/name of the HostClaim
+func ClaimKey(claim *v1.HostClaim) string {
+	return claim.Namespace + "/" + claim.Name
+}
+
+// ClaimOf returns the namespace/name of the HostClaim the record of a host
+// is claimed by, or an empty string when the host isn't claimed
+func ClaimOf(record *v1.DNSRecord) string {
+	return metadata.GetAnnotation(record, AnnotationHostClaim)
+}
+
+// ZoneForHost returns the public ManagedZone of the controller namespace
+// with the longest domain the host is a subdomain of, or nil when none is
+func (s *Service) ZoneForHost(ctx context.Context, host string) (*v1.ManagedZone, error) {
+	zones := &v1.ManagedZoneList{}
+	if err := s.controlClient.List(ctx, zones, client.InNamespace(s.defaultCtrlNS)); err != nil {
+		return nil, err
+	}
+	var found *v1.ManagedZone
+	for i := range zones.Items {
+		zone := &zones.Items[i]
+		if zone.Spec.Visibility == v1.ZoneVisibilityPrivate || !strings.HasSuffix(host, "."+zone.Spec.DomainName) {
+			continue
+		}
+		if found == nil || len(zone.Spec.DomainName) > len(found.Spec.DomainName) {
+			found = zone
+		}
+	}
+	return found, nil
+}
+
+// ClaimHost reserves the host of the HostClaim by creating its DNSRecord,
+// published to the zone. HostTakenErr is returned when the host already has
+// a record not claimed by the HostClaim, a ReservedHostError when the host
+// is reserved in the zone, releasing it, and a HostCoolingDownError when the
+// host was released by another namespace within the host reuse cooldown. The
+// records of the hosts the
+// claim held before are released
+func (s *Service) ClaimHost(ctx context.Context, claim *v1.HostClaim, zone *v1.ManagedZone) error {
+	if reservedErr := ReservedHost(zone, claim.Spec.Host); reservedErr != nil {
+		if err := s.ReleaseHosts(ctx, claim, ""); err != nil {
+			return err
+		}
+		return reservedErr
+	}
+	if err := s.checkHostCooldown(ctx, claim.Spec.Host, claim.Namespace); err != nil {
+		return err
+	}
+	if err := s.ReleaseHosts(ctx, claim, claim.Spec.Host); err != nil {
+		return err
+	}
+	zoneRef := &v1.ManagedZoneReference{Name: zone.Name}
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        claim.Spec.Host,
+			Namespace:   s.defaultCtrlNS,
+			Annotations: map[string]string{AnnotationHostClaim: ClaimKey(claim)},
+		},
+		Spec: v1.DNSRecordSpec{
+			ManagedZoneRef: zoneRef,
+		},
+	}
+	err := s.controlClient.Create(ctx, record, fieldOwner)
+	if err == nil || !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	if err := s.controlClient.Get(ctx, client.ObjectKeyFromObject(record), record); err != nil {
+		return err
+	}
+	if ClaimOf(record) != ClaimKey(claim) {
+		return HostTakenErr
+	}
+	if record.Spec.ManagedZoneRef != nil && record.Spec.ManagedZoneRef.Name == zone.Name {
+		return nil
+	}
+	record.Spec.ManagedZoneRef = zoneRef
+	return s.controlClient.Update(ctx, record, fieldOwner)
+}
+
+// ReleaseHosts deletes the DNSRecords claimed by the HostClaim, except the
+// record of the host kept
+func (s *Service) ReleaseHosts(ctx context.Context, claim *v1.HostClaim, keep string) error {
+	records := &v1.DNSRecordList{}
+	if err := s.controlClient.List(ctx, records, client.InNamespace(s.defaultCtrlNS)); err != nil {
+		return err
+	}
+	for i := range records.Items {
+		record := &records.Items[i]
+		if record.Name == keep || ClaimOf(record) != ClaimKey(claim) {
+			continue
+		}
+		logger(ctx).Info("releasing claimed host", "host", record.Name, "claim", ClaimKey(claim))
+		if err := s.controlClient.Delete(ctx, record); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimedRecords returns the records the traffic object is published on
+// once the HostClaims are accounted for: the records of claimed hosts are
+// left out unless the traffic object references their claim, and the
+// records of the claims it references are added
+func (s *Service) claimedRecords(ctx context.Context, t traffic.Interface, records []*v1.DNSRecord) ([]*v1.DNSRecord, error) {
+	claims := traffic.HostClaims(t)
+	referenced := func(record *v1.DNSRecord) bool {
+		namespace, name, _ := strings.Cut(ClaimOf(record), "/")
+		return namespace == t.GetNamespace() && slice.ContainsString(claims, name)
+	}
+	result := []*v1.DNSRecord{}
+	hosts := []string{}
+	for _, record := range records {
+		if ClaimOf(record) != "" && !referenced(record) {
+			logger(ctx).Info("host claimed by a HostClaim the traffic object doesn't reference, skipping", "host", record.Name, "claim", ClaimOf(record))
+			continue
+		}
+		result = append(result, record)
+		hosts = append(hosts, record.Name)
+	}
+
+	for _, name := range claims {
+		claim := &v1.HostClaim{}
+		if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: t.GetNamespace(), Name: name}, claim); err != nil {
+			if k8serrors.IsNotFound(err) {
+				logger(ctx).Info("referenced HostClaim not found", "claim", name, "namespace", t.GetNamespace())
+				continue
+			}
+			return nil, err
+		}
+		if !meta.IsStatusConditionTrue(claim.Status.Conditions, v1.HostClaimClaimedConditionType) || slice.ContainsString(hosts, claim.Spec.Host) {
+			continue
+		}
+		record := &v1.DNSRecord{}
+		if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: claim.Spec.Host}, record); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if ClaimOf(record) != ClaimKey(claim) {
+			continue
+		}
+		result = append(result, record)
+		hosts = append(hosts, record.Name)
+	}
+	return result, nil
+}
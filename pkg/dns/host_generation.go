@@ -0,0 +1,212 @@
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lithammer/shortuuid/v4"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// sequentialHostPrefix is the first label of the hosts generated by the
+// Sequential strategy, before their number
+const sequentialHostPrefix = "host-"
+
+var (
+	adjectives = []string{
+		"amber", "ancient", "autumn", "bold", "brave", "bright", "calm", "clever",
+		"cool", "crimson", "crisp", "dawn", "deep", "eager", "early", "fancy",
+		"fast", "fierce", "gentle", "golden", "grand", "green", "happy", "hidden",
+		"holy", "icy", "jolly", "keen", "late", "lively", "lucky", "mellow",
+		"misty", "modest", "noble", "odd", "plain", "polished", "proud", "purple",
+		"quiet", "rapid", "red", "rough", "royal", "rustic", "shy", "silent",
+		"silver", "simple", "sleek", "small", "snowy", "solid", "spring", "steady",
+		"still", "sunny", "swift", "tidy", "vast", "warm", "wild", "young",
+	}
+	nouns = []string{
+		"badger", "bay", "bird", "breeze", "brook", "butterfly", "cloud", "comet",
+		"cosmos", "creek", "dew", "dream", "dust", "falcon", "feather", "field",
+		"fire", "flower", "fog", "forest", "frog", "glade", "grass", "harbor",
+		"haze", "heron", "hill", "lake", "leaf", "meadow", "moon", "morning",
+		"moss", "mountain", "night", "oak", "ocean", "otter", "owl", "paper",
+		"pine", "pond", "rain", "reef", "river", "sea", "shadow", "sky",
+		"smoke", "snow", "sound", "star", "stone", "sun", "surf", "thunder",
+		"tree", "valley", "violet", "water", "wave", "willow", "wind", "wood",
+	}
+)
+
+// ValidateHostGeneration returns an error when the host generation strategy
+// is unknown, or when the template of the Template strategy doesn't expand
+// the namespace, as the hosts of traffic objects of the same name in each
+// namespace would be the same
+func ValidateHostGeneration(generation v1.HostGeneration) error {
+	switch generation.Strategy {
+	case "", v1.HostGenerationShortUUID, v1.HostGenerationHash, v1.HostGenerationWords, v1.HostGenerationSequential:
+		return nil
+	case v1.HostGenerationTemplate:
+		if generation.Template == "" {
+			return fmt.Errorf("the %s host generation strategy requires a template", generation.Strategy)
+		}
+		if !strings.Contains(generation.Template, "{ns}") {
+			return fmt.Errorf("the template %s of the %s host generation strategy must expand {ns}", generation.Template, generation.Strategy)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown host generation strategy %s", generation.Strategy)
+}
+
+// hostGeneration returns the host generation of the zone, or the one of the
+// controller when the zone doesn't configure one or isn't a ManagedZone
+func (s *Service) hostGeneration(zone *v1.ManagedZone) v1.HostGeneration {
+	if zone != nil && zone.Spec.HostGeneration != nil {
+		return *zone.Spec.HostGeneration
+	}
+	return s.config.Get().HostGeneration
+}
+
+// generateHost returns the managed host generated for the traffic object in
+// the domain with the host generation of the zone. The traffic objects of the
+// same namespace and name in each cluster are generated the same host, so
+// they share it. A ReservedHostError is returned when the host generated is
+// reserved in the zone, and a HostCollisionError when it's owned by another
+// namespace
+func (s *Service) generateHost(ctx context.Context, t traffic.Interface, zone *v1.ManagedZone, domain string) (string, error) {
+	generation := s.hostGeneration(zone)
+	if err := ValidateHostGeneration(generation); err != nil {
+		return "", err
+	}
+	domain = strings.ToLower(domain)
+	var host string
+	var err error
+	switch generation.Strategy {
+	case v1.HostGenerationHash:
+		sum := sha256.Sum256([]byte(t.GetNamespace() + "/" + t.GetName()))
+		host = fmt.Sprintf("%x.%s", sum[:5], domain)
+	case v1.HostGenerationWords:
+		host = fmt.Sprintf("%s.%s", words(t.GetNamespace()+"/"+t.GetName()), domain)
+	case v1.HostGenerationSequential:
+		host, err = s.sequentialHost(ctx, t, domain)
+	case v1.HostGenerationTemplate:
+		host, err = templateHost(generation.Template, t, domain)
+	default:
+		host = strings.ToLower(fmt.Sprintf("%s.%s", shortuuid.NewWithNamespace(t.GetNamespace()+t.GetName()), domain))
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := ReservedHost(zone, host); err != nil {
+		return "", err
+	}
+	if err := s.checkGeneratedHostOwner(ctx, host, t); err != nil {
+		return "", err
+	}
+	return host, nil
+}
+
+// checkGeneratedHostOwner returns a HostCollisionError when the host
+// generated for the traffic object is claimed by or managed for a traffic
+// object of another namespace. Generated hosts are never shared across
+// namespaces, whatever the host collision policy
+func (s *Service) checkGeneratedHostOwner(ctx context.Context, host string, t traffic.Interface) error {
+	record := &v1.DNSRecord{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: host}, record); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+		record = &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Namespace: s.defaultCtrlNS, Name: host}}
+	}
+	owner, err := HostOwner(ctx, s.controlClient, record)
+	if err != nil {
+		return err
+	}
+	if owner != "" && owner != t.GetNamespace() {
+		return &HostCollisionError{Host: host, Owner: "namespace " + owner}
+	}
+	return nil
+}
+
+// words returns an adjective, a noun and a short hash derived from the seed
+func words(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return fmt.Sprintf("%s-%s-%x", adjectives[int(sum[0])%len(adjectives)], nouns[int(sum[1])%len(nouns)], sum[2:4])
+}
+
+// templateHost expands the template for the traffic object. The domain is
+// appended when the template doesn't end with it. The dots of the name are
+// replaced, so the name doesn't add labels to the host
+func templateHost(template string, t traffic.Interface, domain string) (string, error) {
+	if !strings.HasSuffix(template, "{zone}") {
+		template += ".{zone}"
+	}
+	label := strings.NewReplacer(".", "-")
+	host := strings.ToLower(strings.NewReplacer(
+		"{name}", label.Replace(t.GetName()),
+		"{ns}", label.Replace(t.GetNamespace()),
+		"{kind}", t.GetKind(),
+		"{zone}", domain,
+	).Replace(template))
+	if !strings.HasSuffix(host, "."+domain) {
+		return "", fmt.Errorf("host %s generated from template %s isn't a subdomain of %s", host, template, domain)
+	}
+	return host, nil
+}
+
+// sequentialHost returns the host of the domain already generated for a
+// traffic object of the same kind, namespace and name, or the host numbered
+// after the last host generated in the domain, including the hosts released
+// within the host reuse cooldown. Hosts generated at the same
+// time for different traffic objects may be the same, in which case the
+// traffic objects of the namespace not owning it are generated another host
+// once it's registered
+func (s *Service) sequentialHost(ctx context.Context, t traffic.Interface, domain string) (string, error) {
+	number := func(host string) (int, bool) {
+		label, rest, _ := strings.Cut(host, ".")
+		if rest != domain || !strings.HasPrefix(label, sequentialHostPrefix) {
+			return 0, false
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(label, sequentialHostPrefix))
+		return n, err == nil
+	}
+
+	managedHosts := &v1.ManagedHostList{}
+	if err := s.controlClient.List(ctx, managedHosts, client.InNamespace(s.defaultCtrlNS)); err != nil {
+		return "", err
+	}
+	for i := range managedHosts.Items {
+		managedHost := &managedHosts.Items[i]
+		if _, ok := number(managedHost.Spec.Host); ok && !collides(managedHost, t) {
+			return managedHost.Spec.Host, nil
+		}
+	}
+
+	records := &v1.DNSRecordList{}
+	if err := s.controlClient.List(ctx, records, client.InNamespace(s.defaultCtrlNS)); err != nil {
+		return "", err
+	}
+	history, err := s.hostHistory(ctx)
+	if err != nil {
+		return "", err
+	}
+	hosts := []string{}
+	for _, record := range records.Items {
+		hosts = append(hosts, record.Name)
+	}
+	for host := range history {
+		hosts = append(hosts, host)
+	}
+	next := 1
+	for _, host := range hosts {
+		if n, ok := number(host); ok && n >= next {
+			next = n + 1
+		}
+	}
+	return fmt.Sprintf("%s%d.%s", sequentialHostPrefix, next, domain), nil
+}
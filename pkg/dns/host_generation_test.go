@@ -0,0 +1,134 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func TestService_generateHost(t *testing.T) {
+	ingress := func(name string) traffic.Interface {
+		return traffic.NewIngress(&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"}})
+	}
+	zone := func(generation *v1.HostGeneration) *v1.ManagedZone {
+		return &v1.ManagedZone{Spec: v1.ManagedZoneSpec{DomainName: "apps.example.com", HostGeneration: generation}}
+	}
+
+	cases := []struct {
+		name      string
+		config    v1.HostGeneration
+		zone      *v1.ManagedZone
+		ingress   string
+		expected  string
+		collision bool
+		invalid   bool
+	}{
+		{
+			name:     "hash of the name",
+			config:   v1.HostGeneration{Strategy: v1.HostGenerationHash},
+			zone:     zone(nil),
+			ingress:  "web",
+			expected: "a6d6889313.apps.example.com",
+		},
+		{
+			name:     "words",
+			config:   v1.HostGeneration{Strategy: v1.HostGenerationWords},
+			zone:     zone(nil),
+			ingress:  "web",
+			expected: words("team-a/web") + ".apps.example.com",
+		},
+		{
+			name:     "template of the controller",
+			config:   v1.HostGeneration{Strategy: v1.HostGenerationTemplate, Template: "{name}-{ns}.{zone}"},
+			zone:     zone(nil),
+			ingress:  "Api",
+			expected: "api-team-a.apps.example.com",
+		},
+		{
+			name:     "template of the zone preferred",
+			config:   v1.HostGeneration{Strategy: v1.HostGenerationHash},
+			zone:     zone(&v1.HostGeneration{Strategy: v1.HostGenerationTemplate, Template: "{name}.{ns}"}),
+			ingress:  "web",
+			expected: "web.team-a.apps.example.com",
+		},
+		{
+			name:     "template with the dots of the name replaced",
+			config:   v1.HostGeneration{Strategy: v1.HostGenerationTemplate, Template: "{name}-{ns}.{zone}"},
+			zone:     zone(nil),
+			ingress:  "web.v2",
+			expected: "web-v2-team-a.apps.example.com",
+		},
+		{
+			name:      "template host owned by another namespace",
+			config:    v1.HostGeneration{Strategy: v1.HostGenerationTemplate, Template: "{name}-{ns}"},
+			zone:      zone(nil),
+			ingress:   "web",
+			collision: true,
+		},
+		{
+			name:    "template without the namespace",
+			config:  v1.HostGeneration{Strategy: v1.HostGenerationTemplate, Template: "{name}"},
+			zone:    zone(nil),
+			ingress: "web",
+			invalid: true,
+		},
+		{
+			name:     "sequential after the last host of the zone",
+			config:   v1.HostGeneration{Strategy: v1.HostGenerationSequential},
+			zone:     zone(nil),
+			ingress:  "api",
+			expected: "host-3.apps.example.com",
+		},
+		{
+			name:     "sequential host of the traffic object reused",
+			config:   v1.HostGeneration{Strategy: v1.HostGenerationSequential},
+			zone:     zone(nil),
+			ingress:  "web",
+			expected: "host-2.apps.example.com",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := newTestService(config.Config{HostGeneration: tc.config},
+				&v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "host-1.apps.example.com", Namespace: "argocd"}},
+				&v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "host-2.apps.example.com", Namespace: "argocd"}},
+				&v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "host-9.other.example.com", Namespace: "argocd"}},
+				&v1.ManagedHost{
+					ObjectMeta: metav1.ObjectMeta{Name: "host-2.apps.example.com", Namespace: "argocd"},
+					Spec: v1.ManagedHostSpec{
+						Host:       "host-2.apps.example.com",
+						TrafficRef: v1.TrafficReference{Kind: "Ingress", Namespace: "team-a", Name: "web"},
+					},
+				},
+				&v1.ManagedHost{
+					ObjectMeta: metav1.ObjectMeta{Name: "web-team-a.apps.example.com", Namespace: "argocd"},
+					Spec: v1.ManagedHostSpec{
+						Host:       "web-team-a.apps.example.com",
+						TrafficRef: v1.TrafficReference{Kind: "Ingress", Namespace: "a", Name: "web-team"},
+					},
+				},
+			)
+			host, err := service.generateHost(context.Background(), ingress(tc.ingress), tc.zone, tc.zone.Spec.DomainName)
+			var collisionErr *HostCollisionError
+			if collision := errors.As(err, &collisionErr); collision != tc.collision {
+				t.Fatalf("expected '%v' got '%v'", tc.collision, err)
+			}
+			if tc.collision {
+				return
+			}
+			if invalid := err != nil; invalid != tc.invalid {
+				t.Fatalf("expected '%v' got '%v'", tc.invalid, err)
+			}
+			if host != tc.expected {
+				t.Errorf("expected '%v' got '%v'", tc.expected, host)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// HostHistoryConfigMap is the name of the ConfigMap of the controller
+// namespace holding the hosts released within the host reuse cooldown, by
+// host
+const HostHistoryConfigMap = "mctc-host-history"
+
+// HostRelease is when a host was released, and the namespace of the traffic
+// object or HostClaim it was released by
+type HostRelease struct {
+	Owner      string      `json:"owner"`
+	ReleasedAt metav1.Time `json:"releasedAt"`
+}
+
+// HostCoolingDownError is returned for a host released by another namespace
+// within the host reuse cooldown
+type HostCoolingDownError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *HostCoolingDownError) Error() string {
+	return fmt.Sprintf("host %s was released recently and can't be reused by another namespace before %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// HostOwner returns the namespace of the HostClaim or traffic object the host
+// of the record is claimed by or managed for, or an empty string when it has
+// none
+func HostOwner(ctx context.Context, c client.Client, record *v1.DNSRecord) (string, error) {
+	if claim := ClaimOf(record); claim != "" {
+		namespace, _, _ := strings.Cut(claim, "/")
+		return namespace, nil
+	}
+	managedHost := &v1.ManagedHost{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: record.Namespace, Name: record.Name}, managedHost); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	return managedHost.Spec.TrafficRef.Namespace, nil
+}
+
+// RecordHostRelease adds the host of the record to the host history when it
+// had an owner, pruning the hosts released before the cooldown
+func RecordHostRelease(ctx context.Context, c client.Client, record *v1.DNSRecord, cooldown time.Duration) error {
+	if cooldown <= 0 {
+		return nil
+	}
+	owner, err := HostOwner(ctx, c, record)
+	if err != nil || owner == "" {
+		return err
+	}
+	history := &corev1.ConfigMap{}
+	err = c.Get(ctx, client.ObjectKey{Namespace: record.Namespace, Name: HostHistoryConfigMap}, history)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	create := k8serrors.IsNotFound(err)
+	if create {
+		history = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: record.Namespace, Name: HostHistoryConfigMap}}
+	}
+	if history.Data == nil {
+		history.Data = map[string]string{}
+	}
+	now := time.Now()
+	for host, value := range history.Data {
+		release := HostRelease{}
+		if err := json.Unmarshal([]byte(value), &release); err != nil || now.Sub(release.ReleasedAt.Time) > cooldown {
+			delete(history.Data, host)
+		}
+	}
+	value, err := json.Marshal(HostRelease{Owner: owner, ReleasedAt: metav1.NewTime(now)})
+	if err != nil {
+		return err
+	}
+	history.Data[record.Name] = string(value)
+	logger(ctx).Info("host released, cooling down before reuse by another namespace", "host", record.Name, "owner", owner, "cooldown", cooldown)
+	if create {
+		return c.Create(ctx, history, fieldOwner)
+	}
+	return c.Update(ctx, history, fieldOwner)
+}
+
+// hostHistory returns the hosts released within the host reuse cooldown
+func (s *Service) hostHistory(ctx context.Context) (map[string]HostRelease, error) {
+	cooldown := s.config.Get().HostReuseCooldown
+	if cooldown <= 0 {
+		return nil, nil
+	}
+	history := &corev1.ConfigMap{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: HostHistoryConfigMap}, history); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	releases := map[string]HostRelease{}
+	for host, value := range history.Data {
+		release := HostRelease{}
+		if err := json.Unmarshal([]byte(value), &release); err != nil {
+			logger(ctx).Error(err, "invalid host release in the host history", "host", host)
+			continue
+		}
+		if time.Since(release.ReleasedAt.Time) <= cooldown {
+			releases[host] = release
+		}
+	}
+	return releases, nil
+}
+
+// checkHostCooldown returns a HostCoolingDownError when the host was released
+// by another namespace than the owner within the host reuse cooldown
+func (s *Service) checkHostCooldown(ctx context.Context, host, owner string) error {
+	history, err := s.hostHistory(ctx)
+	if err != nil {
+		return err
+	}
+	release, ok := history[host]
+	if !ok || release.Owner == owner {
+		return nil
+	}
+	return &HostCoolingDownError{Host: host, Until: release.ReleasedAt.Add(s.config.Get().HostReuseCooldown)}
+}
@@ -0,0 +1,105 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+)
+
+func TestService_hostCooldown(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v1.ManagedHost{
+			ObjectMeta: metav1.ObjectMeta{Name: "managed.example.com", Namespace: "argocd"},
+			Spec: v1.ManagedHostSpec{
+				Host:       "managed.example.com",
+				TrafficRef: v1.TrafficReference{Kind: "Ingress", Namespace: "team-a", Name: "test"},
+			},
+		},
+	).Build()
+	cooldown := time.Hour
+	records := []*v1.DNSRecord{
+		{ObjectMeta: metav1.ObjectMeta{Name: "managed.example.com", Namespace: "argocd"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "claimed.example.com", Namespace: "argocd", Annotations: map[string]string{AnnotationHostClaim: "team-b/claim"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pooled.example.com", Namespace: "argocd"}},
+	}
+	for _, record := range records {
+		if err := RecordHostRelease(ctx, c, record, cooldown); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	history := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: HostHistoryConfigMap}, history); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(history.Data) != 2 {
+		t.Errorf("expected '%v' got '%v'", 2, history.Data)
+	}
+
+	cases := []struct {
+		name        string
+		cooldown    time.Duration
+		host        string
+		owner       string
+		coolingDown bool
+	}{
+		{
+			name:        "host released by another namespace",
+			cooldown:    cooldown,
+			host:        "managed.example.com",
+			owner:       "team-b",
+			coolingDown: true,
+		},
+		{
+			name:        "host claimed by another namespace",
+			cooldown:    cooldown,
+			host:        "claimed.example.com",
+			owner:       "team-a",
+			coolingDown: true,
+		},
+		{
+			name:     "host released by the same namespace",
+			cooldown: cooldown,
+			host:     "managed.example.com",
+			owner:    "team-a",
+		},
+		{
+			name:     "host released without an owner",
+			cooldown: cooldown,
+			host:     "pooled.example.com",
+			owner:    "team-b",
+		},
+		{
+			name:     "host released before the cooldown",
+			cooldown: time.Nanosecond,
+			host:     "managed.example.com",
+			owner:    "team-b",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := NewService(c, nil, "argocd", config.NewStore(config.Config{HostReuseCooldown: tc.cooldown}))
+			err := service.checkHostCooldown(ctx, tc.host, tc.owner)
+			var coolingDownErr *HostCoolingDownError
+			if coolingDown := errors.As(err, &coolingDownErr); coolingDown != tc.coolingDown {
+				t.Errorf("expected '%v' got '%v'", tc.coolingDown, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lithammer/shortuuid/v4"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+const (
+	// LabelHostPool is set on the DNSRecords of the hosts provisioned for a
+	// HostPool to the name of the pool, until they're assigned
+	LabelHostPool = "kuadrant.io/host-pool"
+	// LabelHostPoolAvailable set to "true" marks the pooled hosts with their
+	// certificate issued, ready to be assigned
+	LabelHostPoolAvailable = "kuadrant.io/host-pool-available"
+)
+
+// PoolNamespaceErr is returned for the HostPools outside the controller
+// namespace, as the DNSRecords of their hosts are owned by the pool
+var PoolNamespaceErr = fmt.Errorf("host pools must be created in the controller namespace")
+
+// PooledHosts returns the DNSRecords of the hosts of the HostPool not
+// assigned yet
+func (s *Service) PooledHosts(ctx context.Context, pool *v1.HostPool) ([]*v1.DNSRecord, error) {
+	records := &v1.DNSRecordList{}
+	if err := s.controlClient.List(ctx, records, client.InNamespace(s.defaultCtrlNS), client.MatchingLabels{LabelHostPool: pool.Name}); err != nil {
+		return nil, err
+	}
+	pooled := []*v1.DNSRecord{}
+	for i := range records.Items {
+		pooled = append(pooled, &records.Items[i])
+	}
+	return pooled, nil
+}
+
+// ProvisionHost creates the DNSRecord of a new host of the HostPool in the
+// zone, owned by the pool so the hosts not assigned are removed with it
+func (s *Service) ProvisionHost(ctx context.Context, pool *v1.HostPool, zone *v1.ManagedZone) (*v1.DNSRecord, error) {
+	if pool.Namespace != s.defaultCtrlNS {
+		return nil, PoolNamespaceErr
+	}
+	hostKey := shortuuid.New()
+	host := strings.ToLower(fmt.Sprintf("%s.%s", hostKey, zone.Spec.DomainName))
+	if err := ValidateHost(host); err != nil {
+		return nil, err
+	}
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      host,
+			Namespace: s.defaultCtrlNS,
+			Labels:    map[string]string{labelRecordID: hostKey, LabelHostPool: pool.Name},
+		},
+		Spec: v1.DNSRecordSpec{
+			ManagedZoneRef: &v1.ManagedZoneReference{Name: zone.Name},
+		},
+	}
+	if err := controllerutil.SetOwnerReference(pool, record, scheme.Scheme); err != nil {
+		return nil, err
+	}
+	if err := s.controlClient.Create(ctx, record, fieldOwner); err != nil {
+		return nil, err
+	}
+	logger(ctx).Info("provisioned pooled host", "host", host, "pool", pool.Name)
+	return record, nil
+}
+
+// takePooledHost assigns an available host of the HostPools of the zone,
+// removing it from its pool. It returns nil when the zone has no available
+// host
+func (s *Service) takePooledHost(ctx context.Context, zoneRef *v1.ManagedZoneReference) (*v1.DNSRecord, error) {
+	if zoneRef == nil {
+		return nil, nil
+	}
+	records := &v1.DNSRecordList{}
+	if err := s.controlClient.List(ctx, records, client.InNamespace(s.defaultCtrlNS), client.MatchingLabels{LabelHostPoolAvailable: "true"}); err != nil {
+		return nil, err
+	}
+	sort.Slice(records.Items, func(i, j int) bool {
+		return records.Items[i].CreationTimestamp.Before(&records.Items[j].CreationTimestamp)
+	})
+	for i := range records.Items {
+		record := &records.Items[i]
+		if record.Spec.ManagedZoneRef == nil || record.Spec.ManagedZoneRef.Name != zoneRef.Name {
+			continue
+		}
+		pool := record.Labels[LabelHostPool]
+		delete(record.Labels, LabelHostPool)
+		delete(record.Labels, LabelHostPoolAvailable)
+		record.OwnerReferences = nil
+		// the update fails on conflict when the host was assigned
+		// concurrently, so each host is assigned once
+		if err := s.controlClient.Update(ctx, record, fieldOwner); err != nil {
+			if k8serrors.IsConflict(err) || k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		logger(ctx).Info("assigned pooled host", "host", record.Name, "pool", pool)
+		return record, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,51 @@
+package dns
+
+import "testing"
+
+func TestValidateHost(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Host  string
+		Valid bool
+	}{
+		{Name: "subdomain", Host: "app.example.co.uk", Valid: true},
+		{Name: "wildcard subdomain", Host: "*.example.co.uk", Valid: true},
+		{Name: "managed host", Host: "2p4ow7glbzf5yz4qjvnoxe.hcpapps.net", Valid: true},
+		{Name: "apex", Host: "example.co.uk", Valid: false},
+		{Name: "public suffix", Host: "co.uk", Valid: false},
+		{Name: "wildcard public suffix", Host: "*.co.uk", Valid: false},
+		{Name: "label over 63 characters", Host: "a234567890123456789012345678901234567890123456789012345678901234.example.com", Valid: false},
+		{Name: "invalid characters", Host: "app_1.example.com", Valid: false},
+		{Name: "upper case", Host: "App.example.com", Valid: false},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			err := ValidateHost(testCase.Host)
+			if valid := err == nil; valid != testCase.Valid {
+				t.Fatalf("expected '%v' got '%v' (%v)", testCase.Valid, valid, err)
+			}
+		})
+	}
+}
+
+func TestValidateHosts(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Hosts []string
+		Valid bool
+	}{
+		{Name: "no hosts", Valid: true},
+		{Name: "rule without host", Hosts: []string{"", "app.example.com"}, Valid: true},
+		{Name: "invalid host", Hosts: []string{"app.example.com", "example.com"}, Valid: false},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			err := ValidateHosts(testCase.Hosts)
+			if valid := err == nil; valid != testCase.Valid {
+				t.Fatalf("expected '%v' got '%v' (%v)", testCase.Valid, valid, err)
+			}
+		})
+	}
+}
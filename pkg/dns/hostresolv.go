@@ -76,6 +76,38 @@ func (hr *DefaultHostResolver) LookupIPAddr(ctx context.Context, host string) ([
 	return nil, errors.New("no records found for host")
 }
 
+// LookupCAA returns the CAA records of the domain, formatted as record values
+// e.g. 0 issue "letsencrypt.org"
+func (hr *DefaultHostResolver) LookupCAA(ctx context.Context, domain string) ([]string, error) {
+	cfg, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range cfg.Servers {
+		m := dns.Msg{}
+		m.SetQuestion(dns.Fqdn(domain), dns.TypeCAA)
+
+		r, _, err := hr.Client.ExchangeContext(ctx, &m, fmt.Sprintf("%s:53", server))
+		if err != nil {
+			return nil, err
+		}
+		if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+			continue
+		}
+
+		var values []string
+		for _, answer := range r.Answer {
+			if caa, ok := answer.(*dns.CAA); ok {
+				values = append(values, fmt.Sprintf("%d %s %q", caa.Flag, caa.Tag, caa.Value))
+			}
+		}
+		return values, nil
+	}
+
+	return nil, errors.New("no DNS server answered the CAA lookup")
+}
+
 type SafeHostResolver struct {
 	HostResolver
 
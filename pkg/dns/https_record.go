@@ -0,0 +1,62 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// svcParamKeys are the service parameters the HTTPS records of traffic
+// objects can set. The port is set by the controller
+var svcParamKeys = map[string]bool{
+	"alpn":            true,
+	"no-default-alpn": true,
+	"ipv4hint":        true,
+	"ipv6hint":        true,
+	"ech":             true,
+}
+
+// httpsRecordParams returns the service parameters of the HTTPS records of
+// the traffic object, and whether it publishes HTTPS records
+func httpsRecordParams(t traffic.Interface) (string, bool) {
+	return traffic.HTTPSRecordParams(t)
+}
+
+// validateSvcParams returns an error when the service parameters, in
+// presentation format, set a parameter not supported
+func validateSvcParams(params string) error {
+	for _, param := range strings.Fields(params) {
+		key, _, _ := strings.Cut(param, "=")
+		if !svcParamKeys[key] {
+			return fmt.Errorf("unsupported HTTPS record parameter %s", key)
+		}
+	}
+	return nil
+}
+
+// httpsEndpoint returns the HTTPS endpoint of the host publishing the
+// service parameters of the cluster. The clusters exposing HTTPS on another
+// port are published with their cluster hostname as target and the port
+func httpsEndpoint(host, cluster string, port int32, params string, ttl v1.TTL, labels map[string]string) *v1.Endpoint {
+	value := "1 ."
+	if port != clusterSecret.HTTPSPort {
+		value = fmt.Sprintf("1 %s.", clusterHostname(cluster, host))
+	}
+	if params != "" {
+		value += " " + params
+	}
+	if port != clusterSecret.HTTPSPort {
+		value += fmt.Sprintf(" port=%d", port)
+	}
+	return &v1.Endpoint{
+		DNSName:       host,
+		Targets:       []string{value},
+		RecordType:    string(v1.HTTPSRecordType),
+		SetIdentifier: clusterLabel(cluster),
+		RecordTTL:     ttl,
+		Labels:        labels,
+	}
+}
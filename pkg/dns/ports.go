@@ -0,0 +1,65 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+)
+
+// srvPrefix prefixes the hosts to name the SRV records of their HTTPS
+// service
+const srvPrefix = "_https._tcp."
+
+// clusterHTTPSPort returns the port the cluster exposes the HTTPS port of
+// the traffic objects on, according to the port mappings declared on its
+// cluster secret. Invalid mappings are ignored
+func (s *Service) clusterHTTPSPort(ctx context.Context, cluster string) (int32, error) {
+	if cluster == "" {
+		return clusterSecret.HTTPSPort, nil
+	}
+	secret := &corev1.Secret{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: cluster}, secret); err != nil {
+		return clusterSecret.HTTPSPort, client.IgnoreNotFound(err)
+	}
+	mappings, err := clusterSecret.PortMappings(secret)
+	if err != nil {
+		logger(ctx).Error(err, "ignoring invalid port mappings", "cluster", cluster)
+		return clusterSecret.HTTPSPort, nil
+	}
+	return clusterSecret.MappedPort(mappings, clusterSecret.HTTPSPort), nil
+}
+
+// srvEndpoint returns the SRV endpoint of the host publishing the port and
+// target the cluster serves it on
+func srvEndpoint(host, cluster string, port int32, target string, ttl v1.TTL, labels map[string]string) *v1.Endpoint {
+	return &v1.Endpoint{
+		DNSName:       srvPrefix + host,
+		Targets:       []string{fmt.Sprintf("0 1 %d %s", port, target)},
+		RecordType:    string(v1.SRVRecordType),
+		SetIdentifier: clusterLabel(cluster),
+		RecordTTL:     ttl,
+		Labels:        labels,
+	}
+}
+
+// remapped returns true when one of the SRV endpoints publishes a port
+// other than the HTTPS port
+func remapped(endpoints []*v1.Endpoint) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.RecordType != string(v1.SRVRecordType) {
+			continue
+		}
+		for _, target := range endpoint.Targets {
+			if fields := strings.Fields(target); len(fields) == 4 && fields[2] != fmt.Sprint(clusterSecret.HTTPSPort) {
+				return true
+			}
+		}
+	}
+	return false
+}
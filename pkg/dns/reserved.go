@@ -0,0 +1,81 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// ReservedHostError is returned for a host matching a reserved name of its
+// zone
+type ReservedHostError struct {
+	Host    string
+	Zone    string
+	Pattern string
+}
+
+func (e *ReservedHostError) Error() string {
+	return fmt.Sprintf("host %s is reserved by %s in zone %s", e.Host, e.Pattern, e.Zone)
+}
+
+// ValidateReservedHostPattern returns an error when the reserved name isn't a
+// valid pattern
+func ValidateReservedHostPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid reserved host %s: %w", pattern, err)
+	}
+	return nil
+}
+
+// ReservedHost returns a ReservedHostError when the host matches a reserved
+// name of the zone
+func ReservedHost(zone *v1.ManagedZone, host string) error {
+	if zone == nil {
+		return nil
+	}
+	name := strings.ToLower(host)
+	if !strings.HasSuffix(name, "."+zone.Spec.DomainName) {
+		return nil
+	}
+	name = strings.TrimSuffix(name, "."+zone.Spec.DomainName)
+	for _, pattern := range zone.Spec.ReservedHosts {
+		if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+			return &ReservedHostError{Host: host, Zone: zone.Name, Pattern: pattern}
+		}
+	}
+	return nil
+}
+
+// ValidateReservedHosts returns a ReservedHostError for the first host of the
+// traffic object matching a reserved name of its zone. The hosts already
+// managed for the traffic object are kept
+func (s *Service) ValidateReservedHosts(ctx context.Context, t traffic.Interface) error {
+	for _, host := range t.GetHosts() {
+		zone, err := s.ZoneForHost(ctx, host)
+		if err != nil {
+			return err
+		}
+		reservedErr := ReservedHost(zone, host)
+		if reservedErr == nil {
+			continue
+		}
+		managedHost := &v1.ManagedHost{}
+		if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: host}, managedHost); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return err
+			}
+			return reservedErr
+		}
+		if collides(managedHost, t) {
+			return reservedErr
+		}
+	}
+	return nil
+}
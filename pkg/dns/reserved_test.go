@@ -0,0 +1,66 @@
+package dns
+
+import (
+	"testing"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestReservedHost(t *testing.T) {
+	zone := &v1.ManagedZone{Spec: v1.ManagedZoneSpec{
+		DomainName:    "apps.example.com",
+		ReservedHosts: []string{"api", "www", "admin", "*.internal"},
+	}}
+
+	cases := []struct {
+		name     string
+		zone     *v1.ManagedZone
+		host     string
+		reserved bool
+	}{
+		{
+			name:     "reserved name",
+			zone:     zone,
+			host:     "api.apps.example.com",
+			reserved: true,
+		},
+		{
+			name:     "reserved name of another case",
+			zone:     zone,
+			host:     "WWW.apps.example.com",
+			reserved: true,
+		},
+		{
+			name:     "reserved pattern",
+			zone:     zone,
+			host:     "db.eu.internal.apps.example.com",
+			reserved: true,
+		},
+		{
+			name: "name not reserved",
+			zone: zone,
+			host: "api-v2.apps.example.com",
+		},
+		{
+			name: "subdomain of a reserved name",
+			zone: zone,
+			host: "a.api.apps.example.com",
+		},
+		{
+			name: "host of another zone",
+			zone: zone,
+			host: "api.example.com",
+		},
+		{
+			name: "no zone",
+			host: "api.apps.example.com",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if reserved := ReservedHost(tc.zone, tc.host) != nil; reserved != tc.reserved {
+				t.Errorf("expected '%v' got '%v'", tc.reserved, reserved)
+			}
+		})
+	}
+}
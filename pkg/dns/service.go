@@ -3,26 +3,109 @@ package dns
 import (
 	"context"
 	"fmt"
-	"os"
+	"net"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
 	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+	"github.com/go-logr/logr"
 	"github.com/lithammer/shortuuid/v4"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
 	labelRecordID = "kuadrant.io/record-id"
+
+	// endpointLabelOwner identifies the traffic object an endpoint was
+	// published for
+	endpointLabelOwner = "kuadrant.io/owner"
+	// endpointLabelWeight holds the relative weight of an endpoint
+	endpointLabelWeight = "kuadrant.io/weight"
+	// endpointLabelDrained set to "true" gives the endpoint a weight of 0
+	// while its cluster is in maintenance, keeping its relative weight
+	endpointLabelDrained = "kuadrant.io/drained"
+	// endpointLabelSticky set to "true" pins the resolvers to the endpoints
+	// of the record set of the endpoint, see setStickyLocations
+	endpointLabelSticky = "kuadrant.io/sticky"
+
+	defaultEndpointWeight = 1
+
+	// spreadTTL and stickyTTL are the TTLs of the endpoints published for
+	// the spread and sticky DNS strategies
+	spreadTTL v1.TTL = 60
+	stickyTTL v1.TTL = 300
+
+	// MaintenanceLeadTime is how long before a maintenance window the
+	// cluster is drained, so the answers cached by resolvers, published with
+	// at most the sticky TTL, expire by the time the window starts
+	MaintenanceLeadTime = time.Duration(stickyTTL) * time.Second
 )
 
+// fieldOwner is the field manager of the changes to DNSRecords and
+// ManagedHosts
+var fieldOwner = client.FieldOwner(traffic.FieldManager)
+
 var AlreadyAssignedErr = fmt.Errorf("managed host already assigned")
 
+// ClusterEvacuatedErr is returned when the endpoints of a traffic object are
+// not published because its cluster is evacuated
+var ClusterEvacuatedErr = fmt.Errorf("cluster evacuated")
+
+// NoPrivateZoneErr is returned when a private traffic object can't be
+// assigned a managed host as no private zone is configured
+var NoPrivateZoneErr = fmt.Errorf("no private zone configured for private traffic objects")
+
+// NoPrivateAddressesErr is returned when the records of a traffic object in
+// private zones get no endpoints, as it has no mesh targets nor private
+// addresses
+var NoPrivateAddressesErr = fmt.Errorf("no mesh targets nor private addresses for private zones")
+
+// ClusterTaintedErr is returned when the endpoints of a traffic object are
+// withdrawn because its cluster has a NoExecute taint it doesn't tolerate
+var ClusterTaintedErr = fmt.Errorf("cluster tainted")
+
+// NoClusterRegionErr is returned when the endpoints of a traffic object
+// using latency routing can't be published as its cluster has no region
+var NoClusterRegionErr = fmt.Errorf("no region declared for the cluster, required for latency routing")
+
+// LatencyDisabledErr is returned when the endpoints of a traffic object
+// using latency routing aren't published as the LatencyDNS feature is
+// disabled
+var LatencyDisabledErr = fmt.Errorf("latency routing requires the %s feature gate", features.LatencyDNS)
+
+// RoutingConflictError is returned when the endpoints of a traffic object
+// aren't published as the host is already published by other traffic objects
+// with another routing policy. Providers can't mix latency and weighted
+// records of the same name
+type RoutingConflictError struct {
+	Host    string
+	Latency bool
+}
+
+func (e *RoutingConflictError) Error() string {
+	if e.Latency {
+		return fmt.Sprintf("host %s is published with weighted routing by other traffic objects, it can't be routed by latency", e.Host)
+	}
+	return fmt.Sprintf("host %s is published with latency routing by other traffic objects, it can't be weighted", e.Host)
+}
+
 type Service struct {
 	controlClient client.Client
 	// this is temporary setting the tenant ns in the control plane.
@@ -30,32 +113,84 @@ type Service struct {
 	defaultCtrlNS string
 
 	hostResolver HostResolver
+
+	// config holds the default zone, and whether a `<cluster>.<host>`
+	// hostname is published for each cluster alongside the managed host
+	config *config.Store
+}
+
+func NewService(controlClient client.Client, hostResolv HostResolver, defaultCtrlNS string, store *config.Store) *Service {
+	return &Service{controlClient: controlClient, defaultCtrlNS: defaultCtrlNS, hostResolver: hostResolv, config: store}
 }
 
-func NewService(controlClient client.Client, hostResolv HostResolver, defaultCtrlNS string) *Service {
-	return &Service{controlClient: controlClient, defaultCtrlNS: defaultCtrlNS, hostResolver: hostResolv}
+// address is a resolved address of a traffic object
+type address struct {
+	IP     string
+	Weight int
 }
 
-func (s *Service) resolveIPS(ctx context.Context, t traffic.Interface) ([]string, error) {
-	activeDNSTargetIPs := []string{}
+// resolveAddresses resolves the DNS targets of the traffic object to IPs.
+// The IPs of a host target get the weight of the target
+func (s *Service) resolveAddresses(ctx context.Context, t traffic.Interface) ([]address, string, error) {
+	addresses := []address{}
 	targets, err := t.GetDNSTargets()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	cluster := ""
 	for _, target := range targets {
+		cluster = target.Cluster
 		if target.TargetType == v1.TargetTypeIP {
-			activeDNSTargetIPs = append(activeDNSTargetIPs, target.Value)
+			addresses = append(addresses, address{IP: target.Value, Weight: target.Weight})
 			continue
 		}
 		addr, err := s.hostResolver.LookupIPAddr(ctx, target.Value)
 		if err != nil {
-			return activeDNSTargetIPs, fmt.Errorf("DNSLookup failed for host %s : %s", target.Value, err)
+			return addresses, cluster, fmt.Errorf("DNSLookup failed for host %s : %s", target.Value, err)
 		}
 		for _, add := range addr {
-			activeDNSTargetIPs = append(activeDNSTargetIPs, add.IP.String())
+			addresses = append(addresses, address{IP: add.IP.String(), Weight: target.Weight})
+		}
+	}
+	return addresses, cluster, nil
+}
+
+// privateAddresses returns the private addresses, RFC 1918 and RFC 4193, of
+// the load balancers or ingresses of a traffic object
+func privateAddresses(addresses []address) []address {
+	private := []address{}
+	for _, addr := range addresses {
+		if ip := net.ParseIP(addr.IP); ip != nil && ip.IsPrivate() {
+			private = append(private, addr)
+		}
+	}
+	return private
+}
+
+// endpointOwner identifies the traffic object in its cluster that an
+// endpoint was published for
+func endpointOwner(cluster string, t traffic.Interface) string {
+	return fmt.Sprintf("%s/%s", cluster, t.GetCacheKey())
+}
+
+// EndpointOwnerLabels returns the labels identifying the endpoints published
+// for the traffic object in the cluster
+func EndpointOwnerLabels(cluster string, t traffic.Interface) map[string]string {
+	return map[string]string{endpointLabelOwner: endpointOwner(cluster, t)}
+}
+
+// isOwnedBy returns true when the endpoint was published for the owner.
+// Endpoints published before owners were recorded are matched by address
+func isOwnedBy(endpoint *v1.Endpoint, owner string, addresses []address) bool {
+	if o, ok := endpoint.Labels[endpointLabelOwner]; ok {
+		return o == owner
+	}
+	for _, addr := range addresses {
+		if endpoint.SetIdentifier == addr.IP {
+			return true
 		}
 	}
-	return activeDNSTargetIPs, nil
+	return false
 }
 
 func (s *Service) GetDNSRecords(ctx context.Context, traffic traffic.Interface) ([]*v1.DNSRecord, error) {
@@ -74,7 +209,7 @@ func (s *Service) GetDNSRecords(ctx context.Context, traffic traffic.Interface)
 		}
 		if err := s.controlClient.Get(ctx, client.ObjectKeyFromObject(record), record); err != nil {
 			if k8serrors.IsNotFound(err) {
-				log.Log.V(10).Info("no dnsrecord found for host ", "host", record.Name)
+				logger(ctx).V(10).Info("no dnsrecord found for host ", "host", record.Name)
 				continue
 			}
 			return nil, err
@@ -84,130 +219,572 @@ func (s *Service) GetDNSRecords(ctx context.Context, traffic traffic.Interface)
 	return records, nil
 }
 
-func (s *Service) AddEndPoints(ctx context.Context, traffic traffic.Interface) error {
-	ips, err := s.resolveIPS(ctx, traffic)
+// AddEndPoints publishes an endpoint for each address of the traffic object
+// in its managed hosts records, replacing the endpoints previously published
+// for it so addresses that disappeared from its status are pruned. The
+// records in private zones get an endpoint for each of the mesh targets
+// instead, the cluster set IPs of its exported backend services, or when it
+// has none for each of the private addresses only, the ones of an internal
+// load balancer or ingress of the traffic object reachable from the
+// connected clusters. NoPrivateAddressesErr is returned once the other
+// records are published when it has neither. While the
+// cluster of the traffic object is evacuated, no endpoint is published and
+// ClusterEvacuatedErr is returned, the ClusterEvacuation withdraws the
+// endpoints already published. ClusterTaintedErr is returned while
+// its cluster has a NoExecute taint it doesn't tolerate. With latency routing the
+// endpoints are published for the region of the cluster instead of weighted
+func (s *Service) AddEndPoints(ctx context.Context, traffic traffic.Interface, meshTargets []v1.Target) error {
+	addresses, cluster, err := s.resolveAddresses(ctx, traffic)
 	if err != nil {
 		return err
 	}
+	privateAddresses := privateAddresses(addresses)
+	// the mesh targets are the addresses of the backend services, the ports
+	// of the cluster don't apply to them
+	meshed := len(meshTargets) > 0
+	if meshed {
+		privateAddresses = []address{}
+		for _, target := range meshTargets {
+			privateAddresses = append(privateAddresses, address{IP: target.Value, Weight: target.Weight})
+		}
+	}
+	unaddressed := false
+	evacuated, err := s.clusterEvacuated(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if evacuated {
+		return ClusterEvacuatedErr
+	}
+	tolerated, err := s.clusterTolerated(ctx, cluster, traffic)
+	if err != nil {
+		return err
+	}
+	if !tolerated {
+		if err := s.WithdrawEndpoints(ctx, traffic); err != nil {
+			return err
+		}
+		return ClusterTaintedErr
+	}
+	drained, err := s.clusterInMaintenance(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	region := ""
+	if latencyRouted(traffic) {
+		if !s.config.Get().Enabled(features.LatencyDNS) {
+			return LatencyDisabledErr
+		}
+		region, err = s.clusterRegion(ctx, cluster)
+		if err != nil {
+			return err
+		}
+		if region == "" {
+			return NoClusterRegionErr
+		}
+	}
+	port, err := s.clusterHTTPSPort(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	owner := endpointOwner(cluster, traffic)
+	ttl := endpointTTL(traffic)
+	endpointLabels := func(weight int) map[string]string {
+		labels := map[string]string{
+			endpointLabelOwner:  owner,
+			endpointLabelWeight: strconv.Itoa(weight),
+		}
+		if drained {
+			labels[endpointLabelDrained] = "true"
+		}
+		if stickyRouted(traffic) {
+			labels[endpointLabelSticky] = "true"
+		}
+		return labels
+	}
 
 	records, err := s.GetDNSRecords(ctx, traffic)
 	if err != nil {
 		return err
 	}
+	records, err = s.claimedRecords(ctx, traffic, records)
+	if err != nil {
+		return err
+	}
+	records, err = s.admittedRecords(ctx, traffic, records)
+	if err != nil {
+		return err
+	}
 	// for each managed host update dns. A managed host will have a DNSRecord in the control plane
+	wildcards := map[string]bool{}
 	for _, r := range records {
 		host := r.Name
-		// record found update
-		// check if endpoint already exists in the DNSRecord
-		endpoints := []string{}
-		for _, addr := range ips {
-			endpointFound := false
+		zone, err := WildcardZone(ctx, s.controlClient, r)
+		if err != nil {
+			return err
+		}
+		if zone != nil {
+			// the hosts of wildcard zones are published by the wildcard
+			// record of the zone, once for all of them
+			if wildcards[zone.Name] {
+				continue
+			}
+			wildcards[zone.Name] = true
+			zoneAddresses := addresses
+			if zone.Spec.Visibility == v1.ZoneVisibilityPrivate {
+				zoneAddresses = privateAddresses
+				unaddressed = unaddressed || len(zoneAddresses) == 0
+			}
+			if err := s.addWildcardEndpoints(ctx, zone, cluster, zoneAddresses, ttl, drained); err != nil {
+				return err
+			}
+			continue
+		}
+		err = s.retryOnConflict(ctx, r, func(r *v1.DNSRecord) error {
+			recordAddresses := addresses
+			recordPort := port
+			private, err := s.inPrivateZone(ctx, r)
+			if err != nil {
+				return err
+			}
+			if private {
+				recordAddresses = privateAddresses
+				unaddressed = unaddressed || len(recordAddresses) == 0
+				if meshed {
+					recordPort = clusterSecret.HTTPSPort
+				}
+			}
+			current := r.Spec.DeepCopy().Endpoints
+			endpoints := []*v1.Endpoint{}
 			for _, endpoint := range r.Spec.Endpoints {
-				if endpoint.DNSName == host && endpoint.SetIdentifier == addr {
-					log.Log.V(3).Info("address ", addr, "already exists in record for host ", host)
-					endpointFound = true
+				if !isOwnedBy(endpoint, owner, recordAddresses) {
+					endpoints = append(endpoints, endpoint)
+				}
+			}
+			if err := checkRoutingConsistency(host, endpoints, latencyRouted(traffic)); err != nil {
+				return err
+			}
+			for _, addr := range recordAddresses {
+				// endpoints routed by latency can't be weighted down, so
+				// they're withdrawn while their cluster is drained
+				if region != "" && drained {
 					continue
 				}
+				endpoint := &v1.Endpoint{
+					DNSName:       host,
+					Targets:       []string{addr.IP},
+					RecordType:    "A",
+					SetIdentifier: addr.IP,
+					RecordTTL:     ttl,
+					Labels:        endpointLabels(addr.Weight),
+				}
+				if region != "" {
+					endpoint.SetProviderSpecific(aws.ProviderSpecificRegion, region)
+				}
+				// clients connecting to the host expect the HTTPS port, the
+				// clusters exposing it on another port are only reached
+				// through the SRV and HTTPS records of the host
+				if recordPort == clusterSecret.HTTPSPort {
+					endpoints = append(endpoints, endpoint)
+				}
+				if (s.config.Get().ClusterHostnames || recordPort != clusterSecret.HTTPSPort) && cluster != "" {
+					endpoints = append(endpoints, &v1.Endpoint{
+						DNSName:       clusterHostname(cluster, host),
+						Targets:       []string{addr.IP},
+						RecordType:    "A",
+						SetIdentifier: fmt.Sprintf("%s-%s", clusterLabel(cluster), addr.IP),
+						RecordTTL:     ttl,
+						Labels:        endpointLabels(addr.Weight),
+					})
+				}
+			}
+			weight := 0
+			for _, addr := range recordAddresses {
+				weight += addr.Weight
+			}
+			published := cluster != "" && len(recordAddresses) > 0 && !(region != "" && drained)
+			// once a cluster of the host exposes it on another port, every
+			// cluster publishes the port and target it serves the host on
+			// as SRV records, as clients resolving them ignore the others
+			if published && (recordPort != clusterSecret.HTTPSPort || remapped(endpoints)) {
+				target := host
+				if recordPort != clusterSecret.HTTPSPort {
+					target = clusterHostname(cluster, host)
+				}
+				endpoint := srvEndpoint(host, cluster, recordPort, target, ttl, endpointLabels(weight))
+				if region != "" {
+					endpoint.SetProviderSpecific(aws.ProviderSpecificRegion, region)
+				}
+				endpoints = append(endpoints, endpoint)
 			}
-			if !endpointFound {
-				endpoints = append(endpoints, addr)
+			if params, ok := httpsRecordParams(traffic); ok && published {
+				if err := validateSvcParams(params); err != nil {
+					logger(ctx).Error(err, "not publishing invalid HTTPS record", "host", host)
+				} else {
+					endpoint := httpsEndpoint(host, cluster, recordPort, params, ttl, endpointLabels(weight))
+					if region != "" {
+						endpoint.SetProviderSpecific(aws.ProviderSpecificRegion, region)
+					}
+					endpoints = append(endpoints, endpoint)
+				}
 			}
+			setEndpointWeights(endpoints, ClusterWeights(r))
+			if endpointsEqual(current, endpoints) {
+				logger(ctx).V(3).Info("endpoints unchanged, skipping update", "host", host)
+				return nil
+			}
+			r.Spec.Endpoints = endpoints
+			return s.controlClient.Update(ctx, r, fieldOwner)
+		})
+		if err != nil {
+			return err
 		}
-		if len(r.Spec.Endpoints) == 0 {
-			// they are all new endpoints
-			endpoints = ips
-		}
-		for _, ep := range endpoints {
-			endpoint := &v1.Endpoint{
-				DNSName:       host,
-				Targets:       []string{ep},
-				RecordType:    "A",
-				SetIdentifier: ep,
-				RecordTTL:     60,
+	}
+	if unaddressed {
+		return NoPrivateAddressesErr
+	}
+	return nil
+}
+
+// retryOnConflict applies the change to the record, reapplying it to the
+// latest version of the record when its write conflicts with another. The
+// clusters of a host each only change the endpoints they own, so their
+// concurrent changes are merged rather than failing each other's reconcile
+func (s *Service) retryOnConflict(ctx context.Context, record *v1.DNSRecord, change func(record *v1.DNSRecord) error) error {
+	attempt := 0
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if attempt > 0 {
+			logger(ctx).V(3).Info("record changed concurrently, merging with the latest version", "host", record.Name, "attempt", attempt)
+			if err := s.controlClient.Get(ctx, client.ObjectKeyFromObject(record), record); err != nil {
+				return err
 			}
+		}
+		attempt++
+		return change(record)
+	})
+}
+
+// inPrivateZone returns true when the record is published in a private
+// ManagedZone
+func (s *Service) inPrivateZone(ctx context.Context, record *v1.DNSRecord) (bool, error) {
+	if record.Spec.ManagedZoneRef == nil {
+		return false, nil
+	}
+	zone := &v1.ManagedZone{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: record.Namespace, Name: record.Spec.ManagedZoneRef.Name}, zone); err != nil {
+		return false, err
+	}
+	return zone.Spec.Visibility == v1.ZoneVisibilityPrivate, nil
+}
 
-			r.Spec.Endpoints = append(r.Spec.Endpoints, endpoint)
+// clusterEvacuated returns true when a ClusterEvacuation exists for the
+// cluster
+func (s *Service) clusterEvacuated(ctx context.Context, cluster string) (bool, error) {
+	if cluster == "" {
+		return false, nil
+	}
+	evacuations := &v1.ClusterEvacuationList{}
+	if err := s.controlClient.List(ctx, evacuations, client.InNamespace(s.defaultCtrlNS)); err != nil {
+		return false, err
+	}
+	for _, evacuation := range evacuations.Items {
+		if evacuation.Spec.Cluster == cluster {
+			return true, nil
 		}
-		totalIPs := 0
-		for _, e := range r.Spec.Endpoints {
-			totalIPs += len(e.Targets)
+	}
+	return false, nil
+}
+
+// clusterInMaintenance returns true when the cluster is drained for one of
+// the maintenance windows declared on its cluster secret
+func (s *Service) clusterInMaintenance(ctx context.Context, cluster string) (bool, error) {
+	if cluster == "" {
+		return false, nil
+	}
+	secret := &corev1.Secret{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: cluster}, secret); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	windows, err := clusterSecret.MaintenanceWindows(secret)
+	if err != nil {
+		logger(ctx).Error(err, "ignoring invalid maintenance windows", "cluster", cluster)
+		return false, nil
+	}
+	drained, _ := clusterSecret.InMaintenance(windows, time.Now(), MaintenanceLeadTime)
+	return drained, nil
+}
+
+// clusterTolerated returns true when the traffic object tolerates every
+// NoExecute taint of its cluster. The other taints only exclude the cluster
+// from placements, so they don't withdraw live endpoints. Clusters with invalid taints are tolerated, as are
+// traffic objects with invalid tolerations, so a typo doesn't withdraw them
+func (s *Service) clusterTolerated(ctx context.Context, cluster string, t traffic.Interface) (bool, error) {
+	if cluster == "" {
+		return true, nil
+	}
+	secret := &corev1.Secret{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: cluster}, secret); err != nil {
+		return true, client.IgnoreNotFound(err)
+	}
+	taints, err := clusterSecret.Taints(secret)
+	if err != nil {
+		logger(ctx).Error(err, "ignoring invalid taints", "cluster", cluster)
+		return true, nil
+	}
+	tolerations, err := clusterSecret.ParseTolerations(metadata.GetAnnotation(t, traffic.AnnotationTolerations))
+	if err != nil {
+		logger(ctx).Error(err, "ignoring invalid tolerations", "traffic", t.GetCacheKey())
+		return true, nil
+	}
+	return clusterSecret.Tolerates(tolerations, clusterSecret.NoExecute(taints)), nil
+}
+
+// clusterRegion returns the region declared on the cluster secret of the
+// cluster
+func (s *Service) clusterRegion(ctx context.Context, cluster string) (string, error) {
+	if cluster == "" {
+		return "", nil
+	}
+	secret := &corev1.Secret{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: cluster}, secret); err != nil {
+		return "", client.IgnoreNotFound(err)
+	}
+	return clusterSecret.Region(secret), nil
+}
+
+// latencyRouted returns true when the endpoints of the traffic object are
+// routed by latency
+func latencyRouted(t traffic.Interface) bool {
+	return traffic.DNSStrategy(t) == traffic.DNSStrategyLatency
+}
+
+// checkRoutingConsistency returns a RoutingConflictError when the endpoints
+// of the host published by other traffic objects aren't routed the same way,
+// by latency or by weight
+func checkRoutingConsistency(host string, others []*v1.Endpoint, latency bool) error {
+	for _, endpoint := range others {
+		if endpoint.DNSName != host {
+			continue
 		}
-		for _, e := range r.Spec.Endpoints {
-			e.SetProviderSpecific(aws.ProviderSpecificWeight, awsEndpointWeight(totalIPs))
+		if _, ok := endpoint.GetProviderSpecificProperty(aws.ProviderSpecificRegion); ok != latency {
+			return &RoutingConflictError{Host: host, Latency: latency}
 		}
-
-		return s.controlClient.Update(ctx, r, &client.UpdateOptions{})
 	}
 	return nil
 }
 
+// stickyRouted returns true when the traffic object pins resolvers to the
+// same endpoints
+func stickyRouted(t traffic.Interface) bool {
+	return traffic.DNSStrategy(t) == traffic.DNSStrategySticky
+}
+
+// endpointTTL returns the TTL of the endpoints published for the traffic
+// object according to its DNS strategy
+func endpointTTL(t traffic.Interface) v1.TTL {
+	if stickyRouted(t) {
+		return stickyTTL
+	}
+	return spreadTTL
+}
+
+// RemoveEndpoints removes the endpoints published for the traffic object from
+// its managed hosts records, deleting the records left without endpoints
 func (s *Service) RemoveEndpoints(ctx context.Context, t traffic.Interface) error {
+	return s.removeEndpoints(ctx, t, true)
+}
+
+// WithdrawEndpoints removes the endpoints published for the traffic object
+// in its cluster, keeping the DNSRecords of the managed hosts even when no
+// endpoints are left, so the endpoints can be added back later
+func (s *Service) WithdrawEndpoints(ctx context.Context, t traffic.Interface) error {
+	return s.removeEndpoints(ctx, t, false)
+}
+
+func (s *Service) removeEndpoints(ctx context.Context, t traffic.Interface, deleteEmpty bool) error {
 	records, err := s.GetDNSRecords(ctx, t)
 	if err != nil {
 		return err
 	}
-	ips, err := s.resolveIPS(ctx, t)
+	addresses, cluster, err := s.resolveAddresses(ctx, t)
 	if err != nil {
 		return err
 	}
-	newEndpoints := []*v1.Endpoint{}
+	owner := endpointOwner(cluster, t)
 	for _, record := range records {
-		log.Log.V(10).Info("removing ip from record ", "host ", record.Name)
-		for _, endpoint := range record.Spec.Endpoints {
-			for _, addr := range ips {
-				if endpoint.SetIdentifier != addr {
+		logger(ctx).V(10).Info("removing ip from record ", "host ", record.Name)
+		// the records of the hosts of wildcard zones have no endpoints, the
+		// endpoints of the wildcard record are shared by the hosts of the
+		// zone and left in place
+		zone, err := WildcardZone(ctx, s.controlClient, record)
+		if err != nil {
+			return err
+		}
+		wildcard := zone != nil
+		err = s.retryOnConflict(ctx, record, func(record *v1.DNSRecord) error {
+			newEndpoints := []*v1.Endpoint{}
+			for _, endpoint := range record.Spec.Endpoints {
+				if !isOwnedBy(endpoint, owner, addresses) {
 					newEndpoints = append(newEndpoints, endpoint)
 				}
 			}
-		}
-		record.Spec.Endpoints = newEndpoints
-		if len(record.Spec.Endpoints) == 0 {
-			// TODO should it be deleted at this point if there are no endpoints all ingresses are gone? If not where do we want to make this decision.
-			//record.Spec = v1.DNSRecordSpec{}
-			if err := s.controlClient.Delete(ctx, record); err != nil {
-				return err
+			if len(newEndpoints) == len(record.Spec.Endpoints) && !(wildcard && len(newEndpoints) == 0 && deleteEmpty) {
+				return nil
 			}
-			return nil
-		}
-		if err := s.controlClient.Update(ctx, record, &client.UpdateOptions{}); err != nil {
+			record.Spec.Endpoints = newEndpoints
+			// the records of claimed hosts are kept for their claim
+			if len(record.Spec.Endpoints) == 0 && deleteEmpty && ClaimOf(record) == "" {
+				// TODO should it be deleted at this point if there are no endpoints all ingresses are gone? If not where do we want to make this decision.
+				//record.Spec = v1.DNSRecordSpec{}
+				// the record is only deleted as long as no other cluster
+				// added endpoints since it was read
+				return s.controlClient.Delete(ctx, record, client.Preconditions{ResourceVersion: &record.ResourceVersion})
+			}
+			setEndpointWeights(record.Spec.Endpoints, ClusterWeights(record))
+			return s.controlClient.Update(ctx, record, fieldOwner)
+		})
+		if err := client.IgnoreNotFound(err); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// EnsureManagedHost will ensure there is at least one managed host for rthe traffic object and return those host and dnsrecords
+// EnsureManagedHost will ensure there is at least one managed host for rthe traffic object and return those host and dnsrecords.
+// A host available in a HostPool of the zone is assigned before generating one
+// with the host generation of the zone.
+// Hosts rejected as managed for another traffic object are left out, as are
+// claimed hosts whose HostClaim it doesn't reference, and the hosts of the
+// claims it references are added. In the namespace-as-tenant mode, the hosts
+// of public traffic objects are generated in the subdomain of their
+// namespace, hosts outside of it are left out, and TenantQuotaExceededErr is
+// returned when the namespace can't be assigned another host. A
+// HostCoolingDownError is returned when the host generated was released by
+// another namespace within the host reuse cooldown
 func (s *Service) EnsureManagedHost(ctx context.Context, t traffic.Interface) ([]string, []*v1.DNSRecord, error) {
 	dnsRecords, err := s.GetDNSRecords(ctx, t)
 	var managedHosts []string
 	if err != nil {
 		return managedHosts, nil, err
 	}
+	dnsRecords, err = s.claimedRecords(ctx, t, dnsRecords)
+	if err != nil {
+		return managedHosts, nil, err
+	}
+	dnsRecords, err = s.admittedRecords(ctx, t, dnsRecords)
+	if err != nil {
+		return managedHosts, nil, err
+	}
+	// the hosts of private traffic objects are assigned from the private
+	// zone, whatever their namespace
+	var tenantZone *v1.ManagedZone
+	if !traffic.Private(t) {
+		if tenantZone, err = s.TenantZone(ctx); err != nil {
+			return managedHosts, nil, err
+		}
+	}
+	dnsRecords = s.tenantRecords(ctx, t, tenantZone, dnsRecords)
 
 	if len(dnsRecords) != 0 {
 		for _, r := range dnsRecords {
 			managedHosts = append(managedHosts, r.Name)
+			if err := s.ensureManagedHostResource(ctx, t, r); err != nil {
+				return managedHosts, dnsRecords, err
+			}
 		}
 		return managedHosts, dnsRecords, AlreadyAssignedErr
 	}
-	log.Log.Info("no managed host found generating one")
+	logger(ctx).Info("no managed host found generating one")
 	hostKey := shortuuid.NewWithNamespace(t.GetNamespace() + t.GetName())
-	zones := getManagedZones()
-	var chosenZone zone
 	var managedHost string
-	for _, z := range zones {
-		if z.Default {
-			managedHost = strings.ToLower(fmt.Sprintf("%s.%s", hostKey, z.RootDomain))
-			chosenZone = z
-			break
+	var zoneRef *v1.ManagedZoneReference
+	selectedZone, err := s.selectedZone(ctx, t)
+	if err != nil {
+		return managedHosts, dnsRecords, err
+	}
+	if tenantZone != nil {
+		if selectedZone != nil && selectedZone.Name != tenantZone.Name {
+			return managedHosts, dnsRecords, &ZoneSelectionError{Zone: selectedZone.Name, Reason: fmt.Sprintf("is not the root zone %s of the tenants", tenantZone.Name)}
+		}
+		if managedHost, err = s.generateHost(ctx, t, tenantZone, TenantDomain(t.GetNamespace(), tenantZone.Spec.DomainName)); err != nil {
+			return managedHosts, dnsRecords, err
+		}
+		zoneRef = &v1.ManagedZoneReference{Name: tenantZone.Name}
+	} else if selectedZone != nil {
+		if managedHost, err = s.generateHost(ctx, t, selectedZone, selectedZone.Spec.DomainName); err != nil {
+			return managedHosts, dnsRecords, err
+		}
+		zoneRef = &v1.ManagedZoneReference{Name: selectedZone.Name}
+	} else if traffic.Private(t) {
+		privateZone, err := s.privateZone(ctx)
+		if err != nil {
+			return managedHosts, dnsRecords, err
+		}
+		if managedHost, err = s.generateHost(ctx, t, privateZone, privateZone.Spec.DomainName); err != nil {
+			return managedHosts, dnsRecords, err
+		}
+		zoneRef = &v1.ManagedZoneReference{Name: privateZone.Name}
+	} else {
+		defaultZone, err := s.defaultZone(ctx, t.GetNamespace())
+		if err != nil {
+			return managedHosts, dnsRecords, err
+		}
+		if defaultZone != nil {
+			if managedHost, err = s.generateHost(ctx, t, defaultZone, defaultZone.Spec.DomainName); err != nil {
+				return managedHosts, dnsRecords, err
+			}
+			zoneRef = &v1.ManagedZoneReference{Name: defaultZone.Name}
+		} else {
+			zones := s.getManagedZones()
+			var chosenZone zone
+			for _, z := range zones {
+				if z.Default {
+					if managedHost, err = s.generateHost(ctx, t, nil, z.RootDomain); err != nil {
+						return managedHosts, dnsRecords, err
+					}
+					chosenZone = z
+					break
+				}
+			}
+			if chosenZone.ID == "" {
+				return managedHosts, dnsRecords, fmt.Errorf("no zone available to use: annotate a ManagedZone of namespace %s with %s or configure a default zone", s.defaultCtrlNS, AnnotationDefaultZone)
+			}
 		}
 	}
-	if chosenZone.ID == "" {
-		return managedHosts, dnsRecords, fmt.Errorf("no zone available to use")
+	if tenantZone != nil {
+		if err := s.CheckTenantQuota(ctx, t.GetNamespace(), managedHost); err != nil {
+			return managedHosts, dnsRecords, err
+		}
 	}
-	record, err := s.RegisterHost(ctx, managedHost, hostKey, chosenZone.DNSZone)
-	if err != nil {
-		log.Log.Error(err, "failed to register host ")
+	// the pooled hosts aren't in the subdomains of the tenants
+	var record *v1.DNSRecord
+	if tenantZone == nil {
+		record, err = s.takePooledHost(ctx, zoneRef)
+		if err != nil {
+			return managedHosts, dnsRecords, err
+		}
+	}
+	if record != nil {
+		managedHost = record.Name
+	} else {
+		if err := ValidateHost(managedHost); err != nil {
+			return managedHosts, dnsRecords, err
+		}
+		if err := s.checkHostCooldown(ctx, managedHost, t.GetNamespace()); err != nil {
+			return managedHosts, dnsRecords, err
+		}
+		record, err = s.registerHost(ctx, managedHost, hostKey, zoneRef)
+		if err != nil {
+			logger(ctx).Error(err, "failed to register host ")
+			return managedHosts, dnsRecords, err
+		}
+	}
+	if err := s.ensureManagedHostResource(ctx, t, record); err != nil {
+		return managedHosts, dnsRecords, err
+	}
+	// the same host may have been generated for a traffic object of another
+	// namespace at the same time
+	if err := s.checkGeneratedHostOwner(ctx, managedHost, t); err != nil {
 		return managedHosts, dnsRecords, err
 	}
 	managedHosts = append(managedHosts, managedHost)
@@ -215,16 +792,226 @@ func (s *Service) EnsureManagedHost(ctx context.Context, t traffic.Interface) ([
 	return managedHosts, dnsRecords, nil
 }
 
+// privateZone returns the private ManagedZone the managed hosts of private
+// traffic objects are assigned from
+func (s *Service) privateZone(ctx context.Context) (*v1.ManagedZone, error) {
+	name := s.config.Get().PrivateZone
+	if name == "" {
+		return nil, NoPrivateZoneErr
+	}
+	zone := &v1.ManagedZone{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: name}, zone); err != nil {
+		return nil, err
+	}
+	if zone.Spec.Visibility != v1.ZoneVisibilityPrivate {
+		return nil, fmt.Errorf("zone %s of private traffic objects is not private", name)
+	}
+	return zone, nil
+}
+
+// ensureManagedHostResource creates or updates the ManagedHost showing the
+// lifecycle state of the host. It's owned by the DNSRecord of the host, so
+// it's removed along with it. The host is reassigned to the traffic object
+// once the one it was assigned to publishes no endpoints to the record
+func (s *Service) ensureManagedHostResource(ctx context.Context, t traffic.Interface, record *v1.DNSRecord) error {
+	trafficRef := v1.TrafficReference{
+		Kind:      t.GetKind(),
+		Namespace: t.GetNamespace(),
+		Name:      t.GetName(),
+	}
+	managedHost := &v1.ManagedHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      record.Name,
+			Namespace: record.Namespace,
+		},
+		Spec: v1.ManagedHostSpec{
+			Host:       record.Name,
+			TrafficRef: trafficRef,
+		},
+	}
+	if err := controllerutil.SetOwnerReference(record, managedHost, scheme.Scheme); err != nil {
+		return err
+	}
+	err := s.controlClient.Create(ctx, managedHost, fieldOwner)
+	if err == nil || !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if err := s.controlClient.Get(ctx, client.ObjectKeyFromObject(managedHost), managedHost); err != nil {
+			return err
+		}
+		previous := managedHost.DeepCopy()
+		managedHost.Spec.Host = record.Name
+		if !trafficRefPublishes(managedHost.Spec.TrafficRef, record) {
+			managedHost.Spec.TrafficRef = trafficRef
+		}
+		if err := controllerutil.SetOwnerReference(record, managedHost, scheme.Scheme); err != nil {
+			return err
+		}
+		if equality.Semantic.DeepEqual(previous.Spec, managedHost.Spec) && equality.Semantic.DeepEqual(previous.OwnerReferences, managedHost.OwnerReferences) {
+			return nil
+		}
+		logger(ctx).V(3).Info("updating managed host", "host", record.Name, "trafficRef", managedHost.Spec.TrafficRef)
+		return s.controlClient.Update(ctx, managedHost, fieldOwner)
+	})
+}
+
+// trafficRefPublishes returns true when the record has endpoints published
+// for the referenced traffic object in any cluster
+func trafficRefPublishes(ref v1.TrafficReference, record *v1.DNSRecord) bool {
+	for _, owner := range EndpointOwners(record) {
+		if owner.Namespace == ref.Namespace && owner.Name == ref.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// EndpointClusters returns the clusters the endpoints of the record were
+// published for
+func EndpointClusters(record *v1.DNSRecord) []string {
+	clusters := map[string]struct{}{}
+	for _, endpoint := range record.Spec.Endpoints {
+		if cluster := EndpointCluster(endpoint); cluster != "" {
+			clusters[cluster] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(clusters))
+	for cluster := range clusters {
+		result = append(result, cluster)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// HealthyClusters returns the clusters the record has endpoints for that
+// aren't drained
+func HealthyClusters(record *v1.DNSRecord) []string {
+	clusters := []string{}
+	for _, cluster := range EndpointClusters(record) {
+		for _, endpoint := range record.Spec.Endpoints {
+			if EndpointCluster(endpoint) == cluster && endpoint.Labels[endpointLabelDrained] != "true" {
+				clusters = append(clusters, cluster)
+				break
+			}
+		}
+	}
+	return clusters
+}
+
+// EndpointOwner is a traffic object in a cluster endpoints are published for
+type EndpointOwner struct {
+	Cluster   string
+	Namespace string
+	Name      string
+}
+
+// EndpointOwners returns the traffic objects the endpoints of the record
+// are published for
+func EndpointOwners(record *v1.DNSRecord) []EndpointOwner {
+	seen := map[string]struct{}{}
+	owners := []EndpointOwner{}
+	for _, endpoint := range record.Spec.Endpoints {
+		owner, ok := endpoint.Labels[endpointLabelOwner]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[owner]; ok {
+			continue
+		}
+		seen[owner] = struct{}{}
+		parts := strings.SplitN(owner, "/", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		owners = append(owners, EndpointOwner{Cluster: parts[0], Namespace: parts[1], Name: parts[2]})
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		return fmt.Sprint(owners[i]) < fmt.Sprint(owners[j])
+	})
+	return owners
+}
+
+// RemoveClusterEndpoints removes the endpoints published for the cluster
+// from the record, including the endpoints of its cluster hostname. The
+// endpoints are kept when no other cluster serves the record, so its host
+// doesn't stop resolving. Returns true when endpoints were removed
+func RemoveClusterEndpoints(record *v1.DNSRecord, cluster string) bool {
+	endpoints := []*v1.Endpoint{}
+	for _, endpoint := range record.Spec.Endpoints {
+		if EndpointCluster(endpoint) != cluster {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == len(record.Spec.Endpoints) || len(endpoints) == 0 {
+		return false
+	}
+	setEndpointWeights(endpoints, ClusterWeights(record))
+	record.Spec.Endpoints = endpoints
+	return true
+}
+
+// DrainClusterEndpoints drains the endpoints published for the cluster in the
+// record, or restores their weight when drained is false. Returns true when
+// the record changed
+func DrainClusterEndpoints(record *v1.DNSRecord, cluster string, drained bool) bool {
+	changed := false
+	for _, endpoint := range record.Spec.Endpoints {
+		if EndpointCluster(endpoint) != cluster || (endpoint.Labels[endpointLabelDrained] == "true") == drained {
+			continue
+		}
+		if drained {
+			endpoint.Labels[endpointLabelDrained] = "true"
+		} else {
+			delete(endpoint.Labels, endpointLabelDrained)
+		}
+		changed = true
+	}
+	if changed {
+		setEndpointWeights(record.Spec.Endpoints, ClusterWeights(record))
+	}
+	return changed
+}
+
+// Drained returns true when the endpoint was published for a drained
+// cluster
+func Drained(endpoint *v1.Endpoint) bool {
+	return endpoint.Labels[endpointLabelDrained] == "true"
+}
+
+// EndpointCluster returns the cluster the endpoint was published for, or an
+// empty string when it isn't known
+func EndpointCluster(endpoint *v1.Endpoint) string {
+	owner, ok := endpoint.Labels[endpointLabelOwner]
+	if !ok {
+		return ""
+	}
+	if cluster, _, found := strings.Cut(owner, "/"); found {
+		return cluster
+	}
+	return ""
+}
+
 func (s *Service) RegisterHost(ctx context.Context, h string, id string, zone v1.DNSZone) (*v1.DNSRecord, error) {
+	return s.registerHost(ctx, h, id, nil)
+}
+
+// registerHost creates the DNSRecord of the host, published to the
+// ManagedZone when one is referenced
+func (s *Service) registerHost(ctx context.Context, h string, id string, zoneRef *v1.ManagedZoneReference) (*v1.DNSRecord, error) {
 	dnsRecord := v1.DNSRecord{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      h,
 			Namespace: s.defaultCtrlNS,
 			Labels:    map[string]string{labelRecordID: id},
 		},
+		Spec: v1.DNSRecordSpec{
+			ManagedZoneRef: zoneRef,
+		},
 	}
 
-	err := s.controlClient.Create(ctx, &dnsRecord, &client.CreateOptions{})
+	err := s.controlClient.Create(ctx, &dnsRecord, fieldOwner)
 	if err != nil && !k8serrors.IsAlreadyExists(err) {
 		return nil, err
 	}
@@ -246,27 +1033,131 @@ type zone struct {
 }
 
 // this is temporary and will be replaced in the future by CRD resources
-func getManagedZones() []zone {
+func (s *Service) getManagedZones() []zone {
+	current := s.config.Get()
 	return []zone{{
-		DNSZone:    v1.DNSZone{ID: os.Getenv("AWS_DNS_PUBLIC_ZONE_ID")},
-		RootDomain: os.Getenv("ZONE_ROOT_DOMAIN"),
+		DNSZone:    v1.DNSZone{ID: current.DefaultZoneID},
+		RootDomain: current.DefaultZoneRootDomain,
 		Default:    true,
 	}}
 }
 
-// awsEndpointWeight returns the weight Value for a single AWS record in a set of records where the traffic is split
-// evenly between a number of clusters/ingresses, each splitting traffic evenly to a number of IPs (numIPs)
+// endpointsEqual returns true when both sets hold the same endpoints,
+// regardless of their order
+func endpointsEqual(a, b []*v1.Endpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byID := func(endpoints []*v1.Endpoint) []*v1.Endpoint {
+		sorted := append([]*v1.Endpoint{}, endpoints...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].SetID() < sorted[j].SetID()
+		})
+		return sorted
+	}
+	return equality.Semantic.DeepEqual(byID(a), byID(b))
+}
+
+// clusterLabel returns the cluster name as a single DNS label
+func clusterLabel(cluster string) string {
+	return strings.ReplaceAll(strings.ToLower(cluster), ".", "-")
+}
+
+// clusterHostname returns the hostname resolving to the addresses of the
+// cluster only
+func clusterHostname(cluster, host string) string {
+	return fmt.Sprintf("%s.%s", clusterLabel(cluster), host)
+}
+
+// setEndpointWeights sets the AWS weight of each endpoint in a set of records
+// where the traffic to each hostname is split, for each record type, between
+// a number of clusters/ingresses, each splitting traffic between a number of IPs
+// according to the relative weight of the IPs (the endpointLabelWeight label,
+// 1 when not set).
+//
+// The share of the traffic of the clusters given a weight in clusterWeights
+// is split between them according to those weights instead. Endpoints
+// routed by latency are answered by region and aren't weighted
+func setEndpointWeights(endpoints []*v1.Endpoint, clusterWeights map[string]int) {
+	weighted := []*v1.Endpoint{}
+	for _, e := range endpoints {
+		if _, ok := e.GetProviderSpecificProperty(aws.ProviderSpecificRegion); !ok {
+			weighted = append(weighted, e)
+		}
+	}
+	endpoints = weighted
+	weights := make([]int, len(endpoints))
+	clusters := make([]string, len(endpoints))
+	totals := map[string]int{}
+	clusterTotals := map[string]int{}
+	for i, e := range endpoints {
+		weights[i] = defaultEndpointWeight
+		if w, err := strconv.Atoi(e.Labels[endpointLabelWeight]); err == nil {
+			weights[i] = w
+		}
+		if e.Labels[endpointLabelDrained] == "true" {
+			weights[i] = 0
+		}
+		clusters[i] = EndpointCluster(e)
+		totals[recordSet(e)] += weights[i]
+		clusterTotals[recordSet(e)+"/"+clusters[i]] += weights[i]
+	}
+
+	// pools are the share of the weighted clusters of each record set, split
+	// between them according to their weight
+	pools := map[string]int{}
+	poolWeights := map[string]int{}
+	pooled := map[string]bool{}
+	for i, e := range endpoints {
+		key := recordSet(e) + "/" + clusters[i]
+		clusterWeight, ok := clusterWeights[clusters[i]]
+		if !ok || pooled[key] {
+			continue
+		}
+		pooled[key] = true
+		pools[recordSet(e)] += clusterTotals[key]
+		poolWeights[recordSet(e)] += clusterWeight
+	}
+
+	for i, e := range endpoints {
+		weight, total := weights[i], totals[recordSet(e)]
+		if clusterWeight, ok := clusterWeights[clusters[i]]; ok && poolWeights[recordSet(e)] > 0 {
+			weight = pools[recordSet(e)] * clusterWeight * weights[i]
+			total = totals[recordSet(e)] * poolWeights[recordSet(e)] * clusterTotals[recordSet(e)+"/"+clusters[i]]
+		}
+		e.SetProviderSpecific(aws.ProviderSpecificWeight, awsEndpointWeight(weight, total))
+	}
+	setStickyLocations(endpoints)
+}
+
+// recordSet identifies the set of weighted records of the endpoint, by its
+// name and type
+func recordSet(endpoint *v1.Endpoint) string {
+	return endpoint.DNSName + "/" + endpoint.RecordType
+}
+
+// awsEndpointWeight returns the weight Value for a single AWS record with the
+// given relative weight in a set of records with a total relative weight
 //
-// Divides the number of IPs by a known weight allowance for a cluster/ingress, note that this means:
-// * Will always return 1 after a certain number of ips is reached, 60 in the current case (maxWeight / 2)
-// * Will return values that don't add up to the total maxWeight when the number of ingresses is not divisible by numIPs
+// Scales the relative weight to a known weight allowance, note that this means:
+// * Will always return 1 for a non zero weight once the share of the record is small enough, 1/120th in the current case
+// * Will return values that don't add up to the total maxWeight when the total weight is not divisible
 //
 // The aws weight value must be an integer between 0 and 255.
 // https://docs.aws.amazon.com/Route53/latest/DeveloperGuide/resource-record-sets-values-weighted.html#rrsets-values-weighted-weight
-func awsEndpointWeight(numIPs int) string {
+func awsEndpointWeight(weight, total int) string {
 	maxWeight := 120
-	if numIPs > maxWeight {
-		numIPs = maxWeight
+	if weight <= 0 || total <= 0 {
+		return "0"
+	}
+	value := maxWeight * weight / total
+	if value < 1 {
+		value = 1
 	}
-	return strconv.Itoa(maxWeight / numIPs)
+	return strconv.Itoa(value)
+}
+
+// logger returns the logger of the context, logging for the DNS subsystem
+func logger(ctx context.Context) logr.Logger {
+	return log.FromContext(ctx).WithName(string(logging.DNS))
 }
@@ -0,0 +1,1117 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func Test_endpointsEqual(t *testing.T) {
+	endpoint := func(ip, weight string) *v1.Endpoint {
+		return &v1.Endpoint{
+			DNSName:          "test.example.com",
+			Targets:          []string{ip},
+			RecordType:       "A",
+			SetIdentifier:    ip,
+			RecordTTL:        60,
+			ProviderSpecific: v1.ProviderSpecific{{Name: "aws/weight", Value: weight}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		a      []*v1.Endpoint
+		b      []*v1.Endpoint
+		expect bool
+	}{
+		{
+			name:   "same endpoints in a different order",
+			a:      []*v1.Endpoint{endpoint("1.1.1.1", "60"), endpoint("2.2.2.2", "60")},
+			b:      []*v1.Endpoint{endpoint("2.2.2.2", "60"), endpoint("1.1.1.1", "60")},
+			expect: true,
+		},
+		{
+			name:   "endpoint added",
+			a:      []*v1.Endpoint{endpoint("1.1.1.1", "120")},
+			b:      []*v1.Endpoint{endpoint("1.1.1.1", "60"), endpoint("2.2.2.2", "60")},
+			expect: false,
+		},
+		{
+			name:   "weight changed",
+			a:      []*v1.Endpoint{endpoint("1.1.1.1", "60"), endpoint("2.2.2.2", "60")},
+			b:      []*v1.Endpoint{endpoint("1.1.1.1", "80"), endpoint("2.2.2.2", "40")},
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := endpointsEqual(tt.a, tt.b)
+			if got != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
+
+func Test_setEndpointWeights(t *testing.T) {
+	endpoint := func(cluster, ip string) *v1.Endpoint {
+		return &v1.Endpoint{
+			DNSName:       "test.example.com",
+			Targets:       []string{ip},
+			RecordType:    "A",
+			SetIdentifier: ip,
+			Labels:        map[string]string{endpointLabelOwner: cluster + "/default/test"},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		endpoints      []*v1.Endpoint
+		clusterWeights map[string]int
+		expect         []string
+	}{
+		{
+			name:      "traffic split by address without cluster weights",
+			endpoints: []*v1.Endpoint{endpoint("a", "1.1.1.1"), endpoint("a", "2.2.2.2"), endpoint("b", "3.3.3.3")},
+			expect:    []string{"40", "40", "40"},
+		},
+		{
+			name:           "traffic split by cluster weight",
+			endpoints:      []*v1.Endpoint{endpoint("a", "1.1.1.1"), endpoint("a", "2.2.2.2"), endpoint("b", "3.3.3.3")},
+			clusterWeights: map[string]int{"a": 75, "b": 25},
+			expect:         []string{"45", "45", "30"},
+		},
+		{
+			name:           "clusters without a weight keep their share",
+			endpoints:      []*v1.Endpoint{endpoint("a", "1.1.1.1"), endpoint("b", "2.2.2.2"), endpoint("c", "3.3.3.3")},
+			clusterWeights: map[string]int{"a": 0, "b": 100},
+			expect:         []string{"0", "80", "40"},
+		},
+		{
+			name:           "cluster weights ignored when no weighted cluster has a weight",
+			endpoints:      []*v1.Endpoint{endpoint("a", "1.1.1.1"), endpoint("c", "2.2.2.2")},
+			clusterWeights: map[string]int{"a": 0, "b": 100},
+			expect:         []string{"60", "60"},
+		},
+		{
+			name: "endpoints routed by latency are not weighted",
+			endpoints: []*v1.Endpoint{
+				endpoint("a", "1.1.1.1"),
+				endpoint("b", "2.2.2.2").WithProviderSpecific("aws/region", "eu-west-1"),
+			},
+			expect: []string{"120", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEndpointWeights(tt.endpoints, tt.clusterWeights)
+			for i, e := range tt.endpoints {
+				weight, _ := e.GetProviderSpecificProperty("aws/weight")
+				if weight.Value != tt.expect[i] {
+					t.Errorf("expected weight '%v' for %s got '%v'", tt.expect[i], e.SetIdentifier, weight.Value)
+				}
+			}
+		})
+	}
+}
+
+// staleClient returns the stale record on the first read of the record, as
+// a lagging cache would
+type staleClient struct {
+	client.Client
+	stale *v1.DNSRecord
+}
+
+func (c *staleClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if record, ok := obj.(*v1.DNSRecord); ok && c.stale != nil && key.Name == c.stale.Name {
+		c.stale.DeepCopyInto(record)
+		c.stale = nil
+		return nil
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+// newTestService returns the service over a fake control plane client
+// holding the objects, and the client
+func newTestService(cfg config.Config, objs ...client.Object) (*Service, client.Client) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	return NewService(c, nil, "argocd", config.NewStore(cfg)), c
+}
+
+func TestService_concurrentEndpoints(t *testing.T) {
+	ctx := context.Background()
+	ingress := func(cluster, ip string) traffic.Interface {
+		return traffic.NewIngressForCluster(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "test.example.com"}}},
+			Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+				Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: ip}},
+			}},
+		}, cluster)
+	}
+	clusters := func(c client.Client) []string {
+		record := &v1.DNSRecord{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "test.example.com"}, record); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil
+			}
+			t.Fatalf("unexpected error %v", err)
+		}
+		return EndpointClusters(record)
+	}
+
+	service, c := newTestService(config.Config{}, &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: "test.example.com", Namespace: "argocd"},
+	})
+	stale := &v1.DNSRecord{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "test.example.com"}, stale); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := service.AddEndPoints(ctx, ingress("cluster-b", "2.2.2.2"), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	staleB := &v1.DNSRecord{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "test.example.com"}, staleB); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// cluster-a adds its endpoints to a read of the record missing the
+	// endpoints of cluster-b
+	service = NewService(&staleClient{Client: c, stale: stale}, nil, "argocd", service.config)
+	if err := service.AddEndPoints(ctx, ingress("cluster-a", "1.1.1.1"), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := clusters(c); len(got) != 2 {
+		t.Fatalf("expected '%v' got '%v'", []string{"cluster-a", "cluster-b"}, got)
+	}
+
+	// cluster-b removes its endpoints from a read of the record missing the
+	// endpoints of cluster-a, which would leave the record empty and delete
+	// it
+	service = NewService(&staleClient{Client: c, stale: staleB}, nil, "argocd", service.config)
+	if err := service.RemoveEndpoints(ctx, ingress("cluster-b", "2.2.2.2")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := clusters(c); len(got) != 1 || got[0] != "cluster-a" {
+		t.Errorf("expected '%v' got '%v'", []string{"cluster-a"}, got)
+	}
+}
+
+func TestService_hostCollision(t *testing.T) {
+	ctx := context.Background()
+	ingress := func(namespace, ip string) traffic.Interface {
+		return traffic.NewIngressForCluster(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "test.example.com"}}},
+			Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+				Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: ip}},
+			}},
+		}, "cluster-a")
+	}
+
+	cases := []struct {
+		name       string
+		policy     v1.HostCollisionPolicy
+		published  []*v1.Endpoint
+		owners     int
+		collisions string
+	}{
+		{
+			name:   "merge publishes the endpoints of both traffic objects",
+			policy: v1.HostCollisionMerge,
+			owners: 2,
+		},
+		{
+			name:       "reject leaves the host to the first traffic object",
+			policy:     v1.HostCollisionReject,
+			owners:     1,
+			collisions: "test.example.com",
+		},
+		{
+			name:   "reject withdraws the endpoints published under another policy",
+			policy: v1.HostCollisionReject,
+			published: []*v1.Endpoint{{
+				DNSName:       "test.example.com",
+				Targets:       v1.Targets{"2.2.2.2"},
+				RecordType:    "A",
+				SetIdentifier: "2.2.2.2",
+				Labels:        map[string]string{endpointLabelOwner: "cluster-a/team-b/test"},
+			}},
+			owners:     1,
+			collisions: "test.example.com",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, c := newTestService(config.Config{HostCollision: tc.policy},
+				&v1.DNSRecord{
+					ObjectMeta: metav1.ObjectMeta{Name: "test.example.com", Namespace: "argocd"},
+					Spec:       v1.DNSRecordSpec{Endpoints: tc.published},
+				},
+				&v1.ManagedHost{
+					ObjectMeta: metav1.ObjectMeta{Name: "test.example.com", Namespace: "argocd"},
+					Spec: v1.ManagedHostSpec{
+						Host:       "test.example.com",
+						TrafficRef: v1.TrafficReference{Kind: "Ingress", Namespace: "team-a", Name: "test"},
+					},
+				},
+			)
+
+			first, second := ingress("team-a", "1.1.1.1"), ingress("team-b", "2.2.2.2")
+			for _, i := range []traffic.Interface{first, second} {
+				if err := service.AddEndPoints(ctx, i, nil); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			record := &v1.DNSRecord{}
+			if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "test.example.com"}, record); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if owners := EndpointOwners(record); len(owners) != tc.owners {
+				t.Errorf("expected '%v' got '%v'", tc.owners, owners)
+			}
+			if collisions := second.GetAnnotations()[AnnotationHostCollisions]; collisions != tc.collisions {
+				t.Errorf("expected '%v' got '%v'", tc.collisions, collisions)
+			}
+		})
+	}
+}
+
+func TestService_validateHostCollisions(t *testing.T) {
+	ingress := func(namespace, host string) traffic.Interface {
+		return traffic.NewIngress(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: host}}},
+		})
+	}
+
+	cases := []struct {
+		name     string
+		policy   v1.AdmissionHostCollisionPolicy
+		ingress  traffic.Interface
+		warnings int
+		denied   bool
+	}{
+		{
+			name:    "allow admits the host of another traffic object",
+			policy:  v1.AdmissionHostCollisionAllow,
+			ingress: ingress("team-b", "managed.example.com"),
+		},
+		{
+			name:     "warn admits the host of another traffic object with a warning",
+			policy:   v1.AdmissionHostCollisionWarn,
+			ingress:  ingress("team-b", "managed.example.com"),
+			warnings: 1,
+		},
+		{
+			name:    "deny rejects the host of another traffic object",
+			policy:  v1.AdmissionHostCollisionDeny,
+			ingress: ingress("team-b", "managed.example.com"),
+			denied:  true,
+		},
+		{
+			name:    "deny admits the host of the traffic object",
+			policy:  v1.AdmissionHostCollisionDeny,
+			ingress: ingress("team-a", "managed.example.com"),
+		},
+		{
+			name:    "deny rejects a host claimed by another namespace",
+			policy:  v1.AdmissionHostCollisionDeny,
+			ingress: ingress("team-a", "claimed.example.com"),
+			denied:  true,
+		},
+		{
+			name:    "deny admits a host not managed yet",
+			policy:  v1.AdmissionHostCollisionDeny,
+			ingress: ingress("team-b", "new.example.com"),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := newTestService(config.Config{AdmissionHostCollision: tc.policy},
+				&v1.ManagedHost{
+					ObjectMeta: metav1.ObjectMeta{Name: "managed.example.com", Namespace: "argocd"},
+					Spec: v1.ManagedHostSpec{
+						Host:       "managed.example.com",
+						TrafficRef: v1.TrafficReference{Kind: "Ingress", Namespace: "team-a", Name: "test"},
+					},
+				},
+				&v1.DNSRecord{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "claimed.example.com",
+						Namespace:   "argocd",
+						Annotations: map[string]string{AnnotationHostClaim: "team-b/claim"},
+					},
+				},
+			)
+			warnings, err := service.ValidateHostCollisions(context.Background(), tc.ingress)
+			var collisionErr *HostCollisionError
+			if denied := errors.As(err, &collisionErr); denied != tc.denied {
+				t.Errorf("expected '%v' got '%v'", tc.denied, err)
+			}
+			if len(warnings) != tc.warnings {
+				t.Errorf("expected '%v' got '%v'", tc.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestService_defaultZone(t *testing.T) {
+	zone := func(name, defaultFor string, visibility v1.ZoneVisibility) *v1.ManagedZone {
+		return &v1.ManagedZone{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "argocd",
+				Annotations: map[string]string{AnnotationDefaultZone: defaultFor},
+			},
+			Spec: v1.ManagedZoneSpec{ID: name, DomainName: name + ".example.com", Visibility: visibility},
+		}
+	}
+
+	cases := []struct {
+		name      string
+		zones     []client.Object
+		namespace string
+		expected  string
+	}{
+		{
+			name:      "no default zone",
+			zones:     []client.Object{zone("a", "", v1.ZoneVisibilityPublic)},
+			namespace: "team-a",
+		},
+		{
+			name:      "default zone of every namespace",
+			zones:     []client.Object{zone("a", "", v1.ZoneVisibilityPublic), zone("b", "true", v1.ZoneVisibilityPublic)},
+			namespace: "team-a",
+			expected:  "b",
+		},
+		{
+			name:      "default zone of the namespace preferred",
+			zones:     []client.Object{zone("a", "true", v1.ZoneVisibilityPublic), zone("b", "team-b, team-a", v1.ZoneVisibilityPublic)},
+			namespace: "team-a",
+			expected:  "b",
+		},
+		{
+			name:      "default zone of another namespace",
+			zones:     []client.Object{zone("a", "team-b", v1.ZoneVisibilityPublic)},
+			namespace: "team-a",
+		},
+		{
+			name:      "private zones are never the default",
+			zones:     []client.Object{zone("a", "true", v1.ZoneVisibilityPrivate)},
+			namespace: "team-a",
+		},
+		{
+			name:      "first zone by name",
+			zones:     []client.Object{zone("c", "true", v1.ZoneVisibilityPublic), zone("b", "true", v1.ZoneVisibilityPublic)},
+			namespace: "team-a",
+			expected:  "b",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := newTestService(config.Config{}, tc.zones...)
+			zone, err := service.defaultZone(context.Background(), tc.namespace)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			got := ""
+			if zone != nil {
+				got = zone.Name
+			}
+			if got != tc.expected {
+				t.Errorf("expected '%v' got '%v'", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_selectedZone(t *testing.T) {
+	zones := []client.Object{
+		&v1.ManagedZone{
+			ObjectMeta: metav1.ObjectMeta{Name: "public", Namespace: "argocd"},
+			Spec:       v1.ManagedZoneSpec{ID: "Z1", DomainName: "public.example.com", AllowedNamespaces: []string{"team-a", "default"}},
+		},
+		&v1.ManagedZone{
+			ObjectMeta: metav1.ObjectMeta{Name: "private", Namespace: "argocd"},
+			Spec:       v1.ManagedZoneSpec{ID: "Z2", DomainName: "private.example.com", Visibility: v1.ZoneVisibilityPrivate, AllowedNamespaces: []string{"*"}},
+		},
+		&v1.ManagedZone{
+			ObjectMeta: metav1.ObjectMeta{Name: "restricted", Namespace: "argocd"},
+			Spec:       v1.ManagedZoneSpec{ID: "Z3", DomainName: "restricted.example.com", AllowedNamespaces: []string{"team-a"}},
+		},
+		&v1.ManagedZone{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "argocd", Annotations: map[string]string{AnnotationDefaultZone: "default"}},
+			Spec:       v1.ManagedZoneSpec{ID: "Z4", DomainName: "default.example.com"},
+		},
+		&v1.ManagedZone{
+			ObjectMeta: metav1.ObjectMeta{Name: "unlisted", Namespace: "argocd"},
+			Spec:       v1.ManagedZoneSpec{ID: "Z5", DomainName: "unlisted.example.com"},
+		},
+	}
+	ingress := func(annotations map[string]string) traffic.Interface {
+		return traffic.NewIngressForCluster(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: annotations},
+		}, "cluster-a")
+	}
+
+	cases := []struct {
+		name     string
+		ingress  traffic.Interface
+		expected string
+		invalid  bool
+	}{
+		{
+			name:    "no zone selected",
+			ingress: ingress(nil),
+		},
+		{
+			name:     "public zone selected",
+			ingress:  ingress(map[string]string{traffic.AnnotationManagedZone: "public"}),
+			expected: "public",
+		},
+		{
+			name: "private zone selected by a private traffic object",
+			ingress: ingress(map[string]string{
+				traffic.AnnotationManagedZone: "private",
+				traffic.AnnotationVisibility:  traffic.VisibilityPrivate,
+			}),
+			expected: "private",
+		},
+		{
+			name:    "private zone selected by a public traffic object",
+			ingress: ingress(map[string]string{traffic.AnnotationManagedZone: "private"}),
+			invalid: true,
+		},
+		{
+			name:    "missing zone",
+			ingress: ingress(map[string]string{traffic.AnnotationManagedZone: "missing"}),
+			invalid: true,
+		},
+		{
+			name:    "zone not allowing the namespace",
+			ingress: ingress(map[string]string{traffic.AnnotationManagedZone: "restricted"}),
+			invalid: true,
+		},
+		{
+			name:     "default zone of the namespace without allowed namespaces",
+			ingress:  ingress(map[string]string{traffic.AnnotationManagedZone: "default"}),
+			expected: "default",
+		},
+		{
+			name:    "zone without allowed namespaces",
+			ingress: ingress(map[string]string{traffic.AnnotationManagedZone: "unlisted"}),
+			invalid: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := newTestService(config.Config{}, zones...)
+			zone, err := service.selectedZone(context.Background(), tc.ingress)
+			if _, ok := err.(*ZoneSelectionError); ok != tc.invalid {
+				t.Fatalf("expected invalid '%v' got '%v'", tc.invalid, err)
+			}
+			got := ""
+			if zone != nil {
+				got = zone.Name
+			}
+			if got != tc.expected {
+				t.Errorf("expected '%v' got '%v'", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_claimHost(t *testing.T) {
+	ctx := context.Background()
+	claim := func(name, host string) *v1.HostClaim {
+		return &v1.HostClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+			Spec:       v1.HostClaimSpec{Host: host},
+		}
+	}
+	zone := &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{Name: "zone", Namespace: "argocd"},
+		Spec:       v1.ManagedZoneSpec{ID: "zone", DomainName: "example.com"},
+	}
+	service, c := newTestService(config.Config{}, zone, &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: "taken.example.com", Namespace: "argocd"},
+	})
+
+	found, err := service.ZoneForHost(ctx, "shop.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if found == nil || found.Name != "zone" {
+		t.Fatalf("expected '%v' got '%v'", "zone", found)
+	}
+
+	cases := []struct {
+		name     string
+		claim    *v1.HostClaim
+		expected error
+	}{
+		{
+			name:  "host claimed",
+			claim: claim("shop", "shop.example.com"),
+		},
+		{
+			name:  "host claimed again",
+			claim: claim("shop", "shop.example.com"),
+		},
+		{
+			name:     "host claimed by another claim",
+			claim:    claim("other", "shop.example.com"),
+			expected: HostTakenErr,
+		},
+		{
+			name:     "host managed for a traffic object",
+			claim:    claim("taken", "taken.example.com"),
+			expected: HostTakenErr,
+		},
+		{
+			name:  "host of the claim changed",
+			claim: claim("shop", "store.example.com"),
+		},
+	}
+	for _, tc := range cases {
+		if err := service.ClaimHost(ctx, tc.claim, zone); err != tc.expected {
+			t.Errorf("%s: expected '%v' got '%v'", tc.name, tc.expected, err)
+		}
+	}
+
+	records := &v1.DNSRecordList{}
+	if err := c.List(ctx, records); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	claimed := []string{}
+	for _, record := range records.Items {
+		if ClaimOf(&record) != "" {
+			claimed = append(claimed, record.Name+"="+ClaimOf(&record))
+		}
+	}
+	if len(claimed) != 1 || claimed[0] != "store.example.com=team-a/shop" {
+		t.Errorf("expected '%v' got '%v'", []string{"store.example.com=team-a/shop"}, claimed)
+	}
+}
+
+func TestService_ensureManagedHostResource(t *testing.T) {
+	ctx := context.Background()
+	// the owner references are set from the global scheme, as main does
+	utilruntime.Must(v1.AddToScheme(clientgoscheme.Scheme))
+
+	ingress := func(name string) traffic.Interface {
+		return traffic.NewIngressForCluster(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+		}, "cluster-a")
+	}
+	record := func(owners ...string) *v1.DNSRecord {
+		record := &v1.DNSRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop.example.com", Namespace: "argocd", UID: "record"},
+		}
+		for _, owner := range owners {
+			record.Spec.Endpoints = append(record.Spec.Endpoints, &v1.Endpoint{
+				DNSName:    "shop.example.com",
+				Targets:    []string{"1.1.1.1"},
+				RecordType: "A",
+				Labels:     map[string]string{endpointLabelOwner: "cluster-a/team-a/" + owner},
+			})
+		}
+		return record
+	}
+	managedHost := func(owner string) *v1.ManagedHost {
+		return &v1.ManagedHost{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop.example.com", Namespace: "argocd"},
+			Spec: v1.ManagedHostSpec{
+				Host:       "shop.example.com",
+				TrafficRef: v1.TrafficReference{Kind: "Ingress", Namespace: "team-a", Name: owner},
+			},
+		}
+	}
+
+	cases := []struct {
+		name          string
+		existing      *v1.ManagedHost
+		record        *v1.DNSRecord
+		traffic       traffic.Interface
+		expectTraffic string
+	}{
+		{
+			name:          "managed host created",
+			record:        record(),
+			traffic:       ingress("shop"),
+			expectTraffic: "shop",
+		},
+		{
+			name:          "managed host kept for the traffic object publishing to the record",
+			existing:      managedHost("shop"),
+			record:        record("shop"),
+			traffic:       ingress("store"),
+			expectTraffic: "shop",
+		},
+		{
+			name:          "managed host reassigned once its traffic object publishes no endpoints",
+			existing:      managedHost("shop"),
+			record:        record("store"),
+			traffic:       ingress("store"),
+			expectTraffic: "store",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			objs := []client.Object{tc.record}
+			if tc.existing != nil {
+				objs = append(objs, tc.existing)
+			}
+			service, c := newTestService(config.Config{}, objs...)
+
+			if err := service.ensureManagedHostResource(ctx, tc.traffic, tc.record); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			got := &v1.ManagedHost{}
+			if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "shop.example.com"}, got); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got.Spec.TrafficRef.Name != tc.expectTraffic {
+				t.Errorf("expected '%v' got '%v'", tc.expectTraffic, got.Spec.TrafficRef.Name)
+			}
+			if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].UID != tc.record.UID {
+				t.Errorf("expected owner '%v' got '%v'", tc.record.UID, got.OwnerReferences)
+			}
+		})
+	}
+}
+
+func TestService_takePooledHost(t *testing.T) {
+	ctx := context.Background()
+	pooled := func(host, zone string, available bool) *v1.DNSRecord {
+		record := &v1.DNSRecord{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      host,
+				Namespace: "argocd",
+				Labels:    map[string]string{LabelHostPool: "pool"},
+			},
+			Spec: v1.DNSRecordSpec{ManagedZoneRef: &v1.ManagedZoneReference{Name: zone}},
+		}
+		if available {
+			record.Labels[LabelHostPoolAvailable] = "true"
+		}
+		return record
+	}
+
+	cases := []struct {
+		name     string
+		records  []client.Object
+		zoneRef  *v1.ManagedZoneReference
+		expected string
+	}{
+		{
+			name:     "available host of the zone assigned",
+			records:  []client.Object{pooled("a.example.com", "zone", false), pooled("b.example.com", "zone", true)},
+			zoneRef:  &v1.ManagedZoneReference{Name: "zone"},
+			expected: "b.example.com",
+		},
+		{
+			name:    "no available host in the zone",
+			records: []client.Object{pooled("a.other.com", "other", true), pooled("b.example.com", "zone", false)},
+			zoneRef: &v1.ManagedZoneReference{Name: "zone"},
+		},
+		{
+			name:    "no zone",
+			records: []client.Object{pooled("a.example.com", "zone", true)},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := newTestService(config.Config{}, tc.records...)
+			record, err := service.takePooledHost(ctx, tc.zoneRef)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			got := ""
+			if record != nil {
+				got = record.Name
+				if _, ok := record.Labels[LabelHostPool]; ok {
+					t.Errorf("expected host %s removed from its pool", got)
+				}
+			}
+			if got != tc.expected {
+				t.Errorf("expected '%v' got '%v'", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_wildcardZone(t *testing.T) {
+	ctx := context.Background()
+	ingress := func(namespace, host string) traffic.Interface {
+		return traffic.NewIngressForCluster(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: host}}},
+			Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+				Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: "1.1.1.1"}},
+			}},
+		}, "cluster-a")
+	}
+	record := func(host string) *v1.DNSRecord {
+		return &v1.DNSRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: host, Namespace: "argocd"},
+			Spec:       v1.DNSRecordSpec{ManagedZoneRef: &v1.ManagedZoneReference{Name: "zone"}},
+		}
+	}
+	zone := &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{Name: "zone", Namespace: "argocd"},
+		Spec:       v1.ManagedZoneSpec{ID: "zone", DomainName: "example.com", Wildcard: true},
+	}
+	service, c := newTestService(config.Config{}, zone, record("a.example.com"), record("b.example.com"))
+
+	a, b := ingress("team-a", "a.example.com"), ingress("team-b", "b.example.com")
+	for _, i := range []traffic.Interface{a, b} {
+		if err := service.AddEndPoints(ctx, i, nil); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	wildcard := &v1.DNSRecord{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "wildcard.example.com"}, wildcard); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(wildcard.Spec.Endpoints) != 1 || wildcard.Spec.Endpoints[0].DNSName != "*.example.com" {
+		t.Errorf("expected '%v' got '%v'", "*.example.com", wildcard.Spec.Endpoints)
+	}
+	host, certificateRecord, err := service.CertificateHost(ctx, record("a.example.com"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if host != "*.example.com" || certificateRecord.Name != "wildcard.example.com" {
+		t.Errorf("expected '%v' got '%v'", "*.example.com", host)
+	}
+
+	// the record of the host is removed with the traffic object, the
+	// endpoints of the wildcard record are kept for the other hosts
+	if err := service.RemoveEndpoints(ctx, a); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "a.example.com"}, &v1.DNSRecord{}); !k8serrors.IsNotFound(err) {
+		t.Errorf("expected '%v' got '%v'", "not found", err)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "wildcard.example.com"}, wildcard); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(wildcard.Spec.Endpoints) != 1 {
+		t.Errorf("expected '%v' got '%v'", 1, len(wildcard.Spec.Endpoints))
+	}
+}
+
+func TestService_portMappings(t *testing.T) {
+	ctx := context.Background()
+	ingress := func(cluster, ip string) traffic.Interface {
+		return traffic.NewIngressForCluster(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "test.example.com"}}},
+			Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+				Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: ip}},
+			}},
+		}, cluster)
+	}
+	service, c := newTestService(config.Config{},
+		&v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "test.example.com", Namespace: "argocd"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:        "cluster-b",
+			Namespace:   "argocd",
+			Annotations: map[string]string{clusterSecret.AnnotationPortMappings: "443=30443"},
+		}},
+	)
+	for _, i := range []traffic.Interface{ingress("cluster-a", "1.1.1.1"), ingress("cluster-b", "2.2.2.2"), ingress("cluster-a", "1.1.1.1")} {
+		if err := service.AddEndPoints(ctx, i, nil); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	record := &v1.DNSRecord{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "test.example.com"}, record); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	got := map[string][]string{}
+	for _, endpoint := range record.Spec.Endpoints {
+		key := endpoint.RecordType + " " + endpoint.DNSName
+		got[key] = append(got[key], endpoint.Targets...)
+	}
+	sort.Strings(got["SRV _https._tcp.test.example.com"])
+	expected := map[string][]string{
+		// cluster-b isn't reachable on the HTTPS port of the host
+		"A test.example.com":           {"1.1.1.1"},
+		"A cluster-b.test.example.com": {"2.2.2.2"},
+		"SRV _https._tcp.test.example.com": {
+			"0 1 30443 cluster-b.test.example.com",
+			"0 1 443 test.example.com",
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected '%v' got '%v'", expected, got)
+	}
+}
+
+func TestService_httpsRecord(t *testing.T) {
+	ctx := context.Background()
+	ingress := func(cluster, ip, params string) traffic.Interface {
+		return traffic.NewIngressForCluster(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "default",
+				Annotations: map[string]string{traffic.AnnotationHTTPSRecord: params},
+			},
+			Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "test.example.com"}}},
+			Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+				Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: ip}},
+			}},
+		}, cluster)
+	}
+	cases := []struct {
+		Name     string
+		Params   string
+		Expected []string
+	}{
+		{
+			Name:   "test HTTPS records published with their parameters",
+			Params: "alpn=h2,http/1.1",
+			Expected: []string{
+				"1 . alpn=h2,http/1.1",
+				"1 cluster-b.test.example.com. alpn=h2,http/1.1 port=30443",
+			},
+		},
+		{
+			Name:     "test HTTPS records not published with invalid parameters",
+			Params:   "port=8443",
+			Expected: nil,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			service, c := newTestService(config.Config{},
+				&v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "test.example.com", Namespace: "argocd"}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster-b",
+					Namespace:   "argocd",
+					Annotations: map[string]string{clusterSecret.AnnotationPortMappings: "443=30443"},
+				}},
+			)
+			for _, i := range []traffic.Interface{ingress("cluster-a", "1.1.1.1", tc.Params), ingress("cluster-b", "2.2.2.2", tc.Params)} {
+				if err := service.AddEndPoints(ctx, i, nil); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			record := &v1.DNSRecord{}
+			if err := c.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "test.example.com"}, record); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var got []string
+			for _, endpoint := range record.Spec.Endpoints {
+				if endpoint.RecordType == string(v1.HTTPSRecordType) {
+					got = append(got, endpoint.Targets...)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.Expected) {
+				t.Errorf("expected '%v' got '%v'", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestService_tenantQuota(t *testing.T) {
+	managedHost := func(host, namespace string) client.Object {
+		return &v1.ManagedHost{
+			ObjectMeta: metav1.ObjectMeta{Name: host, Namespace: "argocd"},
+			Spec: v1.ManagedHostSpec{
+				Host:       host,
+				TrafficRef: v1.TrafficReference{Kind: "Ingress", Namespace: namespace, Name: "test"},
+			},
+		}
+	}
+	claimed := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "b.team-a.apps.example.com",
+			Namespace:   "argocd",
+			Annotations: map[string]string{AnnotationHostClaim: "team-a/b"},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		maxHosts int
+		host     string
+		expected error
+	}{
+		{
+			name:     "no quota",
+			host:     "c.team-a.apps.example.com",
+			maxHosts: 0,
+		},
+		{
+			name:     "under quota",
+			host:     "c.team-a.apps.example.com",
+			maxHosts: 3,
+		},
+		{
+			name:     "quota exceeded",
+			host:     "c.team-a.apps.example.com",
+			maxHosts: 2,
+			expected: TenantQuotaExceededErr,
+		},
+		{
+			name:     "host of the tenant already",
+			host:     "b.team-a.apps.example.com",
+			maxHosts: 2,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := newTestService(config.Config{TenantRootZone: "apps", TenantMaxHosts: tc.maxHosts},
+				managedHost("a.team-a.apps.example.com", "team-a"),
+				managedHost("a.team-b.apps.example.com", "team-b"),
+				claimed,
+			)
+			err := service.CheckTenantQuota(context.Background(), "team-a", tc.host)
+			if err != tc.expected {
+				t.Errorf("expected '%v' got '%v'", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestPrivateAddresses(t *testing.T) {
+	addresses := []address{
+		{IP: "10.0.0.1", Weight: 1},
+		{IP: "172.16.0.1", Weight: 1},
+		{IP: "192.168.1.1", Weight: 1},
+		{IP: "fd00::1", Weight: 1},
+		{IP: "1.1.1.1", Weight: 1},
+		{IP: "2001:db8::1", Weight: 1},
+	}
+	private := privateAddresses(addresses)
+	expected := []string{"10.0.0.1", "172.16.0.1", "192.168.1.1", "fd00::1"}
+	if len(private) != len(expected) {
+		t.Fatalf("expected '%v' got '%v'", expected, private)
+	}
+	for i, addr := range private {
+		if addr.IP != expected[i] {
+			t.Errorf("expected '%v' got '%v'", expected[i], addr.IP)
+		}
+	}
+}
+
+func TestService_latencyRouting(t *testing.T) {
+	ctx := context.Background()
+	ingress := func(cluster, ip string, latency bool) traffic.Interface {
+		annotations := map[string]string{}
+		if latency {
+			annotations[traffic.AnnotationDNSStrategy] = traffic.DNSStrategyLatency
+		}
+		return traffic.NewIngressForCluster(&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: annotations},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "test.example.com"}}},
+			Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+				Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: ip}},
+			}},
+		}, cluster)
+	}
+	cluster := func(name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "argocd",
+			Annotations: map[string]string{clusterSecret.AnnotationRegion: "eu-west-1"},
+		}}
+	}
+
+	cases := []struct {
+		name      string
+		gate      bool
+		published traffic.Interface
+		traffic   traffic.Interface
+		expectErr func(error) bool
+	}{
+		{
+			name:    "latency routing enabled",
+			gate:    true,
+			traffic: ingress("cluster-a", "1.1.1.1", true),
+		},
+		{
+			name:    "latency routing disabled",
+			traffic: ingress("cluster-a", "1.1.1.1", true),
+			expectErr: func(err error) bool {
+				return err == LatencyDisabledErr
+			},
+		},
+		{
+			name:      "latency routing of a host with latency endpoints",
+			gate:      true,
+			published: ingress("cluster-b", "2.2.2.2", true),
+			traffic:   ingress("cluster-a", "1.1.1.1", true),
+		},
+		{
+			name:      "latency routing of a host with weighted endpoints",
+			gate:      true,
+			published: ingress("cluster-b", "2.2.2.2", false),
+			traffic:   ingress("cluster-a", "1.1.1.1", true),
+			expectErr: func(err error) bool {
+				var conflictErr *RoutingConflictError
+				return errors.As(err, &conflictErr) && conflictErr.Latency
+			},
+		},
+		{
+			name:      "weighted routing of a host with latency endpoints",
+			gate:      true,
+			published: ingress("cluster-b", "2.2.2.2", true),
+			traffic:   ingress("cluster-a", "1.1.1.1", false),
+			expectErr: func(err error) bool {
+				var conflictErr *RoutingConflictError
+				return errors.As(err, &conflictErr) && !conflictErr.Latency
+			},
+		},
+		{
+			name:      "latency routing of a host previously weighted by the same cluster",
+			gate:      true,
+			published: ingress("cluster-a", "1.1.1.1", false),
+			traffic:   ingress("cluster-a", "1.1.1.1", true),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, c := newTestService(config.Config{FeatureGates: map[string]bool{"LatencyDNS": tc.gate}},
+				&v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "test.example.com", Namespace: "argocd"}},
+				cluster("cluster-a"),
+				cluster("cluster-b"),
+			)
+			if tc.published != nil {
+				if err := NewService(c, nil, "argocd", config.NewStore(config.Config{FeatureGates: map[string]bool{"LatencyDNS": true}})).AddEndPoints(ctx, tc.published, nil); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			err := service.AddEndPoints(ctx, tc.traffic, nil)
+			if tc.expectErr == nil && err != nil || tc.expectErr != nil && !tc.expectErr(err) {
+				t.Errorf("unexpected error '%v'", err)
+			}
+		})
+	}
+}
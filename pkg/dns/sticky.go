@@ -0,0 +1,78 @@
+package dns
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+)
+
+// setStickyLocations pins each resolver location of the sticky strategy to
+// a single endpoint of the record sets of sticky hosts, so a resolver keeps
+// being answered with the same address while the endpoints of the host
+// don't change. Each location is pinned by weighted rendezvous hashing, so
+// an endpoint added or removed only moves the locations it wins or loses,
+// and the endpoints weighted down to 0 only answer when all of them are. A
+// record set is sticky as soon as one of its endpoints is, as the record
+// sets of a name can't mix routing policies
+func setStickyLocations(endpoints []*v1.Endpoint) {
+	sticky := map[string]bool{}
+	for _, e := range endpoints {
+		if e.Labels[endpointLabelSticky] == "true" {
+			sticky[recordSet(e)] = true
+		}
+	}
+	sets := map[string][]*v1.Endpoint{}
+	for _, e := range endpoints {
+		if !sticky[recordSet(e)] {
+			e.DeleteProviderSpecific(aws.ProviderSpecificCIDRLocations)
+			continue
+		}
+		sets[recordSet(e)] = append(sets[recordSet(e)], e)
+	}
+	for _, set := range sets {
+		locations := map[*v1.Endpoint][]string{}
+		for _, location := range aws.StickyLocations() {
+			winner := rendezvous(location, set)
+			locations[winner] = append(locations[winner], location)
+		}
+		for _, e := range set {
+			e.SetProviderSpecific(aws.ProviderSpecificCIDRLocations, strings.Join(locations[e], ","))
+		}
+	}
+}
+
+// rendezvous returns the endpoint with the highest weighted score for the
+// location
+func rendezvous(location string, endpoints []*v1.Endpoint) *v1.Endpoint {
+	weights := make([]float64, len(endpoints))
+	total := 0.0
+	for i, e := range endpoints {
+		if value, ok := e.GetProviderSpecific(aws.ProviderSpecificWeight); ok {
+			weights[i], _ = strconv.ParseFloat(value, 64)
+		}
+		total += weights[i]
+	}
+	var winner *v1.Endpoint
+	best := math.Inf(-1)
+	for i, e := range endpoints {
+		weight := weights[i]
+		if total == 0 {
+			weight = 1
+		}
+		if weight <= 0 {
+			continue
+		}
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(location + "/" + e.SetID()))
+		// a uniform value in (0, 1) for the location and endpoint
+		u := (float64(h.Sum64()>>11) + 0.5) / (1 << 53)
+		if score := -weight / math.Log(u); score > best {
+			best, winner = score, e
+		}
+	}
+	return winner
+}
@@ -0,0 +1,109 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+)
+
+func Test_setStickyLocations(t *testing.T) {
+	endpoint := func(cluster, ip string, labels ...string) *v1.Endpoint {
+		e := &v1.Endpoint{
+			DNSName:       "test.example.com",
+			Targets:       []string{ip},
+			RecordType:    "A",
+			SetIdentifier: ip,
+			Labels:        map[string]string{endpointLabelOwner: cluster + "/default/test"},
+		}
+		for _, label := range labels {
+			e.Labels[label] = "true"
+		}
+		return e
+	}
+	// locations returns the endpoint of each location pinned to one
+	locations := func(t *testing.T, endpoints []*v1.Endpoint) map[string]string {
+		pinned := map[string]string{}
+		for _, e := range endpoints {
+			value, _ := e.GetProviderSpecific(aws.ProviderSpecificCIDRLocations)
+			if value == "" {
+				continue
+			}
+			for _, location := range strings.Split(value, ",") {
+				if other, ok := pinned[location]; ok {
+					t.Errorf("expected location %s pinned to one endpoint got '%v' and '%v'", location, other, e.SetIdentifier)
+				}
+				pinned[location] = e.SetIdentifier
+			}
+		}
+		return pinned
+	}
+
+	tests := []struct {
+		name         string
+		endpoints    []*v1.Endpoint
+		expectPinned map[string]bool
+	}{
+		{
+			name:         "spread record sets are not pinned",
+			endpoints:    []*v1.Endpoint{endpoint("a", "1.1.1.1"), endpoint("b", "2.2.2.2")},
+			expectPinned: map[string]bool{},
+		},
+		{
+			name:         "every location is pinned to an endpoint",
+			endpoints:    []*v1.Endpoint{endpoint("a", "1.1.1.1", endpointLabelSticky), endpoint("b", "2.2.2.2", endpointLabelSticky)},
+			expectPinned: map[string]bool{"1.1.1.1": true, "2.2.2.2": true},
+		},
+		{
+			name:         "record set is sticky as soon as one endpoint is",
+			endpoints:    []*v1.Endpoint{endpoint("a", "1.1.1.1", endpointLabelSticky), endpoint("b", "2.2.2.2")},
+			expectPinned: map[string]bool{"1.1.1.1": true, "2.2.2.2": true},
+		},
+		{
+			name:         "drained endpoints are not pinned",
+			endpoints:    []*v1.Endpoint{endpoint("a", "1.1.1.1", endpointLabelSticky), endpoint("b", "2.2.2.2", endpointLabelSticky, endpointLabelDrained)},
+			expectPinned: map[string]bool{"1.1.1.1": true},
+		},
+		{
+			name:         "drained endpoints are pinned when all are",
+			endpoints:    []*v1.Endpoint{endpoint("a", "1.1.1.1", endpointLabelSticky, endpointLabelDrained), endpoint("b", "2.2.2.2", endpointLabelSticky, endpointLabelDrained)},
+			expectPinned: map[string]bool{"1.1.1.1": true, "2.2.2.2": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEndpointWeights(tt.endpoints, nil)
+			pinned := locations(t, tt.endpoints)
+			if len(tt.expectPinned) > 0 && len(pinned) != len(aws.StickyLocations()) {
+				t.Errorf("expected '%v' locations pinned got '%v'", len(aws.StickyLocations()), len(pinned))
+			}
+			got := map[string]bool{}
+			for _, setIdentifier := range pinned {
+				got[setIdentifier] = true
+			}
+			if len(got) != len(tt.expectPinned) {
+				t.Errorf("expected '%v' got '%v'", tt.expectPinned, got)
+			}
+			for setIdentifier := range tt.expectPinned {
+				if !got[setIdentifier] {
+					t.Errorf("expected '%v' got '%v'", tt.expectPinned, got)
+				}
+			}
+		})
+	}
+
+	t.Run("locations only move to an added endpoint", func(t *testing.T) {
+		endpoints := []*v1.Endpoint{endpoint("a", "1.1.1.1", endpointLabelSticky), endpoint("b", "2.2.2.2", endpointLabelSticky)}
+		setEndpointWeights(endpoints, nil)
+		before := locations(t, endpoints)
+		endpoints = append(endpoints, endpoint("c", "3.3.3.3", endpointLabelSticky))
+		setEndpointWeights(endpoints, nil)
+		for location, setIdentifier := range locations(t, endpoints) {
+			if setIdentifier != before[location] && setIdentifier != "3.3.3.3" {
+				t.Errorf("expected location %s to stay on '%v' got '%v'", location, before[location], setIdentifier)
+			}
+		}
+	})
+}
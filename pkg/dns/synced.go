@@ -0,0 +1,65 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// AnnotationSyncedSecrets records on a ManagedHost the resource version of
+// the TLS secret of the host last synced to each cluster, as a comma
+// separated list of cluster=resourceVersion pairs
+const AnnotationSyncedSecrets = "kuadrant.io/synced-secrets"
+
+// SyncedSecrets returns the resource version of the TLS secret last synced
+// to each cluster
+func SyncedSecrets(managedHost *v1.ManagedHost) map[string]string {
+	synced := map[string]string{}
+	value := metadata.GetAnnotation(managedHost, AnnotationSyncedSecrets)
+	if value == "" {
+		return synced
+	}
+	for _, pair := range strings.Split(value, ",") {
+		if cluster, version, found := strings.Cut(strings.TrimSpace(pair), "="); found {
+			synced[cluster] = version
+		}
+	}
+	return synced
+}
+
+// RecordSynced records on the ManagedHost of the host the resource version
+// of its TLS secret synced to the cluster of the traffic object
+func (s *Service) RecordSynced(ctx context.Context, t traffic.Interface, host, version string) error {
+	targets, err := t.GetDNSTargets()
+	if err != nil || len(targets) == 0 || targets[0].Cluster == "" {
+		return err
+	}
+	cluster := targets[0].Cluster
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		managedHost := &v1.ManagedHost{}
+		if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: host}, managedHost); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		synced := SyncedSecrets(managedHost)
+		if synced[cluster] == version {
+			return nil
+		}
+		synced[cluster] = version
+		pairs := make([]string, 0, len(synced))
+		for c, v := range synced {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", c, v))
+		}
+		sort.Strings(pairs)
+		patch := client.MergeFromWithOptions(managedHost.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		metadata.AddAnnotation(managedHost, AnnotationSyncedSecrets, strings.Join(pairs, ","))
+		return s.controlClient.Patch(ctx, managedHost, patch, fieldOwner)
+	})
+}
@@ -0,0 +1,143 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// AnnotationOutsideTenant is set on traffic objects to the comma separated
+// hosts rejected as outside of the subdomain of their namespace, in the
+// namespace-as-tenant mode
+const AnnotationOutsideTenant = "kuadrant.io/hosts-outside-tenant"
+
+// TenantQuotaExceededErr is returned when the namespace of a traffic object
+// or HostClaim has as many hosts as it can be assigned
+var TenantQuotaExceededErr = fmt.Errorf("tenant host quota exceeded")
+
+// TenantDomainError is returned for a host of the root zone of the tenants
+// outside of the subdomain of the namespace of its traffic object
+type TenantDomainError struct {
+	Host   string
+	Domain string
+}
+
+func (e *TenantDomainError) Error() string {
+	return fmt.Sprintf("host %s is outside of the domain %s of the namespace", e.Host, e.Domain)
+}
+
+// TenantDomain returns the subdomain of the namespace in the root domain
+func TenantDomain(namespace, rootDomain string) string {
+	return strings.ToLower(namespace + "." + rootDomain)
+}
+
+// InTenantDomain returns true when the host is a subdomain of the tenant
+// domain
+func InTenantDomain(host, tenantDomain string) bool {
+	return strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(tenantDomain))
+}
+
+// TenantZone returns the root ManagedZone of the namespace-as-tenant mode,
+// or nil when the mode is disabled
+func (s *Service) TenantZone(ctx context.Context) (*v1.ManagedZone, error) {
+	name := s.config.Get().TenantRootZone
+	if name == "" {
+		return nil, nil
+	}
+	zone := &v1.ManagedZone{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: name}, zone); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("root zone %s of the tenants not found in namespace %s: %w", name, s.defaultCtrlNS, err)
+		}
+		return nil, err
+	}
+	if zone.Spec.Visibility == v1.ZoneVisibilityPrivate {
+		return nil, fmt.Errorf("root zone %s of the tenants is not public", name)
+	}
+	return zone, nil
+}
+
+// TenantHosts returns the hosts assigned to the traffic objects of the
+// namespace, and claimed by its HostClaims
+func (s *Service) TenantHosts(ctx context.Context, namespace string) ([]string, error) {
+	hosts := map[string]struct{}{}
+	managedHosts := &v1.ManagedHostList{}
+	if err := s.controlClient.List(ctx, managedHosts, client.InNamespace(s.defaultCtrlNS)); err != nil {
+		return nil, err
+	}
+	for _, managedHost := range managedHosts.Items {
+		if managedHost.Spec.TrafficRef.Namespace == namespace {
+			hosts[managedHost.Spec.Host] = struct{}{}
+		}
+	}
+	records := &v1.DNSRecordList{}
+	if err := s.controlClient.List(ctx, records, client.InNamespace(s.defaultCtrlNS)); err != nil {
+		return nil, err
+	}
+	for _, record := range records.Items {
+		if claimNamespace, _, found := strings.Cut(ClaimOf(&record), "/"); found && claimNamespace == namespace {
+			hosts[record.Name] = struct{}{}
+		}
+	}
+	result := []string{}
+	for host := range hosts {
+		result = append(result, host)
+	}
+	return result, nil
+}
+
+// CheckTenantQuota returns TenantQuotaExceededErr when the namespace can't
+// be assigned another host than the ones it has
+func (s *Service) CheckTenantQuota(ctx context.Context, namespace, host string) error {
+	maxHosts := s.config.Get().TenantMaxHosts
+	if maxHosts <= 0 {
+		return nil
+	}
+	hosts, err := s.TenantHosts(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		if h == host {
+			return nil
+		}
+	}
+	if len(hosts) >= maxHosts {
+		return TenantQuotaExceededErr
+	}
+	return nil
+}
+
+// tenantRecords returns the records of the traffic object within the
+// subdomain of its namespace, flagging the traffic object with the hosts
+// rejected
+func (s *Service) tenantRecords(ctx context.Context, t traffic.Interface, tenantZone *v1.ManagedZone, records []*v1.DNSRecord) []*v1.DNSRecord {
+	if tenantZone == nil {
+		metadata.RemoveAnnotation(t, AnnotationOutsideTenant)
+		return records
+	}
+	domain := TenantDomain(t.GetNamespace(), tenantZone.Spec.DomainName)
+	admitted := []*v1.DNSRecord{}
+	rejected := []string{}
+	for _, record := range records {
+		if !InTenantDomain(record.Name, domain) {
+			logger(ctx).Info("host outside of the tenant domain of the namespace, rejecting", "host", record.Name, "domain", domain)
+			rejected = append(rejected, record.Name)
+			continue
+		}
+		admitted = append(admitted, record)
+	}
+	if len(rejected) == 0 {
+		metadata.RemoveAnnotation(t, AnnotationOutsideTenant)
+	} else {
+		metadata.AddAnnotation(t, AnnotationOutsideTenant, strings.Join(rejected, ","))
+	}
+	return admitted
+}
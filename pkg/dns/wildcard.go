@@ -0,0 +1,131 @@
+package dns
+
+import (
+	"context"
+	"strconv"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// WildcardHost returns the wildcard host serving the managed hosts of the
+// zone
+func WildcardHost(zone *v1.ManagedZone) string {
+	return "*." + zone.Spec.DomainName
+}
+
+// WildcardRecordName returns the name of the DNSRecord and of the
+// certificate of the wildcard host of the zone, as the wildcard label isn't
+// valid in names
+func WildcardRecordName(zone *v1.ManagedZone) string {
+	return "wildcard." + zone.Spec.DomainName
+}
+
+// WildcardZone returns the zone of the record when it serves its managed
+// hosts with a wildcard record and certificate, or nil
+func WildcardZone(ctx context.Context, c client.Client, record *v1.DNSRecord) (*v1.ManagedZone, error) {
+	if record.Spec.ManagedZoneRef == nil {
+		return nil, nil
+	}
+	zone := &v1.ManagedZone{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: record.Namespace, Name: record.Spec.ManagedZoneRef.Name}, zone); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if !zone.Spec.Wildcard {
+		return nil, nil
+	}
+	return zone, nil
+}
+
+// CertificateHost returns the host the certificate of the host of the
+// record is issued for, and the record owning the certificate: the wildcard
+// host and record of the zone of the record when it's a wildcard zone, or
+// the host and record themselves
+func (s *Service) CertificateHost(ctx context.Context, record *v1.DNSRecord) (string, *v1.DNSRecord, error) {
+	zone, err := WildcardZone(ctx, s.controlClient, record)
+	if err != nil || zone == nil {
+		return record.Name, record, err
+	}
+	wildcard, err := s.wildcardRecord(ctx, zone)
+	if err != nil {
+		return "", nil, err
+	}
+	return WildcardHost(zone), wildcard, nil
+}
+
+// wildcardRecord returns the DNSRecord of the wildcard host of the zone,
+// creating it when missing
+func (s *Service) wildcardRecord(ctx context.Context, zone *v1.ManagedZone) (*v1.DNSRecord, error) {
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      WildcardRecordName(zone),
+			Namespace: s.defaultCtrlNS,
+		},
+		Spec: v1.DNSRecordSpec{
+			ManagedZoneRef: &v1.ManagedZoneReference{Name: zone.Name},
+		},
+	}
+	err := s.controlClient.Get(ctx, client.ObjectKeyFromObject(record), record)
+	if err == nil || !k8serrors.IsNotFound(err) {
+		return record, err
+	}
+	logger(ctx).Info("creating wildcard record of zone", "zone", zone.Name, "host", WildcardHost(zone))
+	if err := s.controlClient.Create(ctx, record, fieldOwner); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+		return record, s.controlClient.Get(ctx, client.ObjectKeyFromObject(record), record)
+	}
+	return record, nil
+}
+
+// wildcardOwner identifies the endpoints published in wildcard records for
+// the load balancers of the cluster, shared by its traffic objects
+func wildcardOwner(cluster string) string {
+	return cluster + "/*"
+}
+
+// addWildcardEndpoints publishes the addresses of the cluster in the
+// wildcard record of the zone, unless they are already
+func (s *Service) addWildcardEndpoints(ctx context.Context, zone *v1.ManagedZone, cluster string, addresses []address, ttl v1.TTL, drained bool) error {
+	record, err := s.wildcardRecord(ctx, zone)
+	if err != nil {
+		return err
+	}
+	return s.retryOnConflict(ctx, record, func(record *v1.DNSRecord) error {
+		published := map[string]bool{}
+		for _, endpoint := range record.Spec.Endpoints {
+			published[endpoint.SetIdentifier] = true
+		}
+		changed := false
+		for _, addr := range addresses {
+			if published[addr.IP] {
+				continue
+			}
+			labels := v1.Labels{
+				endpointLabelOwner:  wildcardOwner(cluster),
+				endpointLabelWeight: strconv.Itoa(addr.Weight),
+			}
+			if drained {
+				labels[endpointLabelDrained] = "true"
+			}
+			record.Spec.Endpoints = append(record.Spec.Endpoints, &v1.Endpoint{
+				DNSName:       WildcardHost(zone),
+				Targets:       []string{addr.IP},
+				RecordType:    "A",
+				SetIdentifier: addr.IP,
+				RecordTTL:     ttl,
+				Labels:        labels,
+			})
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+		setEndpointWeights(record.Spec.Endpoints, ClusterWeights(record))
+		return s.controlClient.Update(ctx, record, fieldOwner)
+	})
+}
@@ -0,0 +1,201 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+)
+
+var CredentialsNotAllowedErr = fmt.Errorf("provider credentials are not allowed in the namespace of the zone")
+
+// DefaultCredentialsNotAllowedErr is returned for the zones and records
+// outside of the controller namespace that don't reference their own
+// provider credentials
+var DefaultCredentialsNotAllowedErr = fmt.Errorf("the provider credentials of the controller are only used in the controller namespace")
+
+// ZoneProviders returns the provider managing the records of a ManagedZone.
+// Zones referencing their own credentials get a provider built from the
+// credentials secret, as long as their namespace is allowed. Other zones
+// use the default provider of the controller, as long as they're in the
+// controller namespace
+type ZoneProviders struct {
+	client          client.Client
+	namespace       string
+	providerName    string
+	defaultProvider Provider
+	// config holds the namespaces allowed to reference credentials
+	config *config.Store
+
+	// providers caches the providers built from credentials secrets, keyed
+	// by secret and provider name, along with the resource version they were
+	// built from
+	providers sync.Map
+
+	// Wrap, when set, wraps the providers built from credentials secrets
+	Wrap func(Provider) Provider
+}
+
+type providerKey struct {
+	secret       client.ObjectKey
+	providerName string
+}
+
+type cachedProvider struct {
+	resourceVersion string
+	provider        Provider
+}
+
+func NewZoneProviders(client client.Client, namespace, providerName string, defaultProvider Provider, store *config.Store) *ZoneProviders {
+	return &ZoneProviders{
+		client:          client,
+		namespace:       namespace,
+		providerName:    providerName,
+		defaultProvider: defaultProvider,
+		config:          store,
+	}
+}
+
+// CredentialsAllowed returns true when zones in the namespace can reference
+// their own provider credentials
+func (z *ZoneProviders) CredentialsAllowed(namespace string) bool {
+	for _, ns := range z.config.Get().ZoneCredentialsNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultCredentialsAllowed returns true when the zones and records of the
+// namespace can be managed with the default provider of the controller
+func (z *ZoneProviders) DefaultCredentialsAllowed(namespace string) bool {
+	return namespace == z.namespace
+}
+
+// ProviderFor returns the provider for the zone
+func (z *ZoneProviders) ProviderFor(ctx context.Context, zone *v1.ManagedZone) (Provider, error) {
+	if zone.Spec.ProviderCredentialsRef == nil {
+		if !z.DefaultCredentialsAllowed(zone.Namespace) {
+			return nil, DefaultCredentialsNotAllowedErr
+		}
+		return z.defaultProvider, nil
+	}
+	return z.providerFromCredentials(ctx, zone.Namespace, z.providerName, zone.Spec.ProviderCredentialsRef)
+}
+
+// SecondaryProviderFor returns the provider for the secondary zone of the
+// zone
+func (z *ZoneProviders) SecondaryProviderFor(ctx context.Context, zone *v1.ManagedZone, secondary v1.SecondaryZone) (Provider, error) {
+	providerName := secondary.Provider
+	if providerName == "" {
+		providerName = z.providerName
+	}
+	if secondary.ProviderCredentialsRef == nil {
+		if providerName != z.providerName {
+			return nil, fmt.Errorf("the %s hosted zone %s requires provider credentials", providerName, secondary.ID)
+		}
+		if !z.DefaultCredentialsAllowed(zone.Namespace) {
+			return nil, DefaultCredentialsNotAllowedErr
+		}
+		return z.defaultProvider, nil
+	}
+	return z.providerFromCredentials(ctx, zone.Namespace, providerName, secondary.ProviderCredentialsRef)
+}
+
+// ProvidersFor returns the zones the records of the zone are published to,
+// its hosted zone followed by its secondary zones, and the provider
+// publishing them, which publishes the records of each zone with its own
+// provider
+func (z *ZoneProviders) ProvidersFor(ctx context.Context, zone *v1.ManagedZone) ([]v1.DNSZone, Provider, error) {
+	provider, err := z.ProviderFor(ctx, zone)
+	if err != nil || len(zone.Spec.SecondaryZones) == 0 {
+		return []v1.DNSZone{zone.DNSZone()}, provider, err
+	}
+	providers := zonesProvider{zone.Spec.ID: provider}
+	for _, secondary := range zone.Spec.SecondaryZones {
+		provider, err := z.SecondaryProviderFor(ctx, zone, secondary)
+		if err != nil {
+			return nil, nil, fmt.Errorf("secondary zone %s: %w", secondary.ID, err)
+		}
+		providers[secondary.ID] = provider
+	}
+	return zone.DNSZones(), providers, nil
+}
+
+// providerFromCredentials returns the provider built from the credentials
+// secret of the namespace, cached until the secret changes
+func (z *ZoneProviders) providerFromCredentials(ctx context.Context, namespace, providerName string, ref *v1.ProviderCredentialsReference) (Provider, error) {
+	if !z.CredentialsAllowed(namespace) {
+		return nil, CredentialsNotAllowedErr
+	}
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: namespace, Name: ref.Name}
+	if err := z.client.Get(ctx, key, secret); err != nil {
+		return nil, err
+	}
+
+	cacheKey := providerKey{secret: key, providerName: providerName}
+	if cached, ok := z.providers.Load(cacheKey); ok && cached.(*cachedProvider).resourceVersion == secret.ResourceVersion {
+		return cached.(*cachedProvider).provider, nil
+	}
+	provider, err := DNSProviderFromSecret(providerName, secret)
+	if err != nil {
+		return nil, err
+	}
+	if z.Wrap != nil {
+		provider = z.Wrap(provider)
+	}
+	z.providers.Store(cacheKey, &cachedProvider{resourceVersion: secret.ResourceVersion, provider: provider})
+	return provider, nil
+}
+
+// zonesProvider publishes the records of each zone, keyed by ID, with the
+// provider of the zone
+type zonesProvider map[string]Provider
+
+var (
+	_ Verifier   = zonesProvider{}
+	_ ZoneLister = zonesProvider{}
+)
+
+func (p zonesProvider) Ensure(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	provider, ok := p[zone.ID]
+	if !ok {
+		return fmt.Errorf("zone %s is not a zone of the record", zone.ID)
+	}
+	return provider.Ensure(ctx, record, zone)
+}
+
+// Delete deletes the record from the zone. The zones removed from the
+// secondary zones are skipped, as their provider is no longer known
+func (p zonesProvider) Delete(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	provider, ok := p[zone.ID]
+	if !ok {
+		logger(ctx).Info("not deleting record from zone removed from the secondary zones", "record", record.Name, "zone", zone.ID)
+		return nil
+	}
+	return provider.Delete(ctx, record, zone)
+}
+
+func (p zonesProvider) Verify(ctx context.Context, record *v1.DNSRecord, zone v1.DNSZone) ([]*v1.Endpoint, error) {
+	verifier, ok := p[zone.ID].(Verifier)
+	if !ok {
+		return nil, nil
+	}
+	return verifier.Verify(ctx, record, zone)
+}
+
+func (p zonesProvider) ZoneRecords(ctx context.Context, zone v1.DNSZone) ([]*v1.Endpoint, error) {
+	lister, ok := p[zone.ID].(ZoneLister)
+	if !ok {
+		return nil, fmt.Errorf("the provider of zone %s can't list its records", zone.ID)
+	}
+	return lister.ZoneRecords(ctx, zone)
+}
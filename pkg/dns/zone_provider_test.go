@@ -0,0 +1,67 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+)
+
+func TestZoneProviders_ProviderFor(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+
+	zone := func(namespace string, credentials string) *v1.ManagedZone {
+		zone := &v1.ManagedZone{
+			ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: namespace},
+			Spec:       v1.ManagedZoneSpec{ID: "Z1", DomainName: "example.com"},
+		}
+		if credentials != "" {
+			zone.Spec.ProviderCredentialsRef = &v1.ProviderCredentialsReference{Name: credentials}
+		}
+		return zone
+	}
+
+	cases := []struct {
+		name        string
+		zone        *v1.ManagedZone
+		expectedErr error
+	}{
+		{
+			name: "default credentials in the controller namespace",
+			zone: zone("argocd", ""),
+		},
+		{
+			name:        "default credentials in another namespace",
+			zone:        zone("tenant-a", ""),
+			expectedErr: DefaultCredentialsNotAllowedErr,
+		},
+		{
+			name:        "credentials in a namespace not allowed",
+			zone:        zone("tenant-b", "aws-credentials"),
+			expectedErr: CredentialsNotAllowedErr,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).Build()
+			providers := NewZoneProviders(c, "argocd", "aws", nil, config.NewStore(config.Config{ZoneCredentialsNamespaces: []string{"tenant-a"}}))
+			if _, err := providers.ProviderFor(context.Background(), tc.zone); !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected '%v' got '%v'", tc.expectedErr, err)
+			}
+			secondary := v1.SecondaryZone{ID: "Z2"}
+			if _, err := providers.SecondaryProviderFor(context.Background(), tc.zone, secondary); tc.zone.Spec.ProviderCredentialsRef == nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected '%v' got '%v'", tc.expectedErr, err)
+			}
+		})
+	}
+}
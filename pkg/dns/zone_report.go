@@ -0,0 +1,130 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	dnsAWS "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+)
+
+var _ ZoneLister = &dnsAWS.Provider{}
+
+// ZoneLister is implemented by providers that can list all the records
+// published in a zone, so they can be compared against the DNSRecords of
+// the zone
+type ZoneLister interface {
+	// ZoneRecords returns the records published in the zone, skipping the
+	// records DNSRecords can't publish
+	ZoneRecords(ctx context.Context, zone v1.DNSZone) ([]*v1.Endpoint, error)
+}
+
+// CompareRecords compares the records published in the hosted zone against
+// the records the DNSRecords of the zone publish, and returns the records
+// published that no DNSRecord publishes, the records of the DNSRecords that
+// aren't published, and the records published with other targets or TTL.
+// The TTL of a record is only compared when its DNSRecord sets one, as the
+// provider picks it otherwise
+func CompareRecords(zoneID string, published []*v1.Endpoint, records []v1.DNSRecord) v1.HostedZoneReport {
+	report := v1.HostedZoneReport{ID: zoneID}
+
+	type expectedRecord struct {
+		record   string
+		endpoint *v1.Endpoint
+	}
+	expected := map[string]expectedRecord{}
+	for _, record := range records {
+		for _, endpoint := range record.PublishedEndpoints() {
+			key := driftKey(endpoint)
+			if _, ok := expected[key]; !ok {
+				expected[key] = expectedRecord{record: record.Name, endpoint: endpoint}
+			}
+		}
+	}
+
+	found := map[string]bool{}
+	for _, endpoint := range published {
+		key := driftKey(endpoint)
+		want, ok := expected[key]
+		if !ok {
+			report.Extra = append(report.Extra, drift(endpoint, "", nil, endpoint))
+			continue
+		}
+		found[key] = true
+		if !valuesMatch(want.endpoint, endpoint) {
+			report.Mismatched = append(report.Mismatched, drift(endpoint, want.record, want.endpoint, endpoint))
+		}
+	}
+	for key, want := range expected {
+		if !found[key] {
+			report.Missing = append(report.Missing, drift(want.endpoint, want.record, want.endpoint, nil))
+		}
+	}
+
+	for _, drifts := range [][]v1.RecordDrift{report.Extra, report.Missing, report.Mismatched} {
+		sortDrift(drifts)
+	}
+	return report
+}
+
+// driftKey identifies a record within a zone
+func driftKey(endpoint *v1.Endpoint) string {
+	return fmt.Sprintf("%s/%s/%s", strings.ToLower(strings.TrimSuffix(endpoint.DNSName, ".")), endpoint.RecordType, endpoint.SetIdentifier)
+}
+
+func drift(endpoint *v1.Endpoint, record string, expected, published *v1.Endpoint) v1.RecordDrift {
+	d := v1.RecordDrift{
+		DNSName:       endpoint.DNSName,
+		RecordType:    endpoint.RecordType,
+		SetIdentifier: endpoint.SetIdentifier,
+		DNSRecord:     record,
+	}
+	if expected != nil {
+		d.Expected = &v1.RecordValues{Targets: expected.Targets, TTL: expected.RecordTTL}
+	}
+	if published != nil {
+		d.Published = &v1.RecordValues{Targets: published.Targets, TTL: published.RecordTTL}
+	}
+	return d
+}
+
+func valuesMatch(expected, published *v1.Endpoint) bool {
+	if expected.RecordTTL != 0 && expected.RecordTTL != published.RecordTTL {
+		return false
+	}
+	return reflect.DeepEqual(normalizedTargets(expected), normalizedTargets(published))
+}
+
+// normalizedTargets returns the sorted targets of the endpoint, unquoting
+// TXT values and removing the trailing dot of names, as providers return
+// them in their own format
+func normalizedTargets(endpoint *v1.Endpoint) []string {
+	targets := make([]string, 0, len(endpoint.Targets))
+	for _, target := range endpoint.Targets {
+		if endpoint.RecordType == string(v1.TXTRecordType) {
+			if len(target) > 1 && strings.HasPrefix(target, `"`) && strings.HasSuffix(target, `"`) {
+				target = strings.ReplaceAll(target[1:len(target)-1], `\"`, `"`)
+			}
+		} else {
+			target = strings.TrimSuffix(target, ".")
+		}
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+func sortDrift(drifts []v1.RecordDrift) {
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].DNSName != drifts[j].DNSName {
+			return drifts[i].DNSName < drifts[j].DNSName
+		}
+		if drifts[i].RecordType != drifts[j].RecordType {
+			return drifts[i].RecordType < drifts[j].RecordType
+		}
+		return drifts[i].SetIdentifier < drifts[j].SetIdentifier
+	})
+}
@@ -0,0 +1,75 @@
+package dns
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestCompareRecords(t *testing.T) {
+	record := v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: "app.example.com"},
+		Spec: v1.DNSRecordSpec{
+			TTL: 60,
+			Endpoints: []*v1.Endpoint{
+				{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"1.1.1.1", "2.2.2.2"}},
+				{DNSName: "www.example.com", RecordType: "CNAME", Targets: v1.Targets{"app.example.com"}},
+			},
+			RawRecords: []*v1.Endpoint{
+				{RecordType: "TXT", Targets: v1.Targets{`owner="mctc"`}},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		published  []*v1.Endpoint
+		extra      []string
+		missing    []string
+		mismatched []string
+	}{
+		{
+			name: "records in sync",
+			published: []*v1.Endpoint{
+				{DNSName: "APP.example.com", RecordType: "A", Targets: v1.Targets{"2.2.2.2", "1.1.1.1"}, RecordTTL: 60},
+				{DNSName: "www.example.com", RecordType: "CNAME", Targets: v1.Targets{"app.example.com."}, RecordTTL: 60},
+				{DNSName: "app.example.com", RecordType: "TXT", Targets: v1.Targets{`"owner=\"mctc\""`}, RecordTTL: 60},
+			},
+		},
+		{
+			name: "records drifted",
+			published: []*v1.Endpoint{
+				{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"1.1.1.1"}, RecordTTL: 60},
+				{DNSName: "www.example.com", RecordType: "CNAME", Targets: v1.Targets{"app.example.com"}, RecordTTL: 300},
+				{DNSName: "manual.example.com", RecordType: "A", Targets: v1.Targets{"3.3.3.3"}, RecordTTL: 300},
+			},
+			extra:      []string{"manual.example.com/A"},
+			missing:    []string{"app.example.com/TXT"},
+			mismatched: []string{"app.example.com/A", "www.example.com/CNAME"},
+		},
+	}
+
+	names := func(drifts []v1.RecordDrift) []string {
+		var result []string
+		for _, drift := range drifts {
+			result = append(result, drift.DNSName+"/"+drift.RecordType)
+		}
+		return result
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := CompareRecords("zone", tt.published, []v1.DNSRecord{record})
+			if got := names(report.Extra); !reflect.DeepEqual(got, tt.extra) {
+				t.Errorf("expected '%v' got '%v'", tt.extra, got)
+			}
+			if got := names(report.Missing); !reflect.DeepEqual(got, tt.missing) {
+				t.Errorf("expected '%v' got '%v'", tt.missing, got)
+			}
+			if got := names(report.Mismatched); !reflect.DeepEqual(got, tt.mismatched) {
+				t.Errorf("expected '%v' got '%v'", tt.mismatched, got)
+			}
+		})
+	}
+}
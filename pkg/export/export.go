@@ -0,0 +1,250 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterScoped is the directory of the manifests of cluster scoped objects
+const clusterScoped = "_cluster"
+
+// Writer writes the manifests of the objects the controller would apply to
+// a directory instead, laid out as <namespace>/<kind>-<name>.yaml. The
+// manifests of the control plane and of each workload cluster are written
+// to their own directories. At most limit manifests are written across the
+// directories, as the objects exported are never created, so controllers
+// reconciling them would otherwise keep generating new ones
+type Writer struct {
+	dir       string
+	scheme    *runtime.Scheme
+	manifests *manifests
+}
+
+// ErrLimitReached is returned when writing a new manifest would exceed the
+// limit of the writer
+var ErrLimitReached = errors.New("export limit reached")
+
+// manifests are the paths of the manifests written, shared by the writers of
+// each directory
+type manifests struct {
+	mutex sync.Mutex
+	limit int
+	paths map[string]struct{}
+}
+
+// NewWriter returns a writer writing at most limit manifests to the
+// directory. A limit of 0 doesn't limit the manifests written
+func NewWriter(dir string, scheme *runtime.Scheme, limit int) *Writer {
+	return &Writer{dir: dir, scheme: scheme, manifests: &manifests{limit: limit, paths: map[string]struct{}{}}}
+}
+
+// ForControlPlane returns a writer writing to the directory of the control
+// plane
+func (w *Writer) ForControlPlane() *Writer {
+	return &Writer{dir: filepath.Join(w.dir, "control-plane"), scheme: w.scheme, manifests: w.manifests}
+}
+
+// ForCluster returns a writer writing to the directory of the workload
+// cluster
+func (w *Writer) ForCluster(name string) *Writer {
+	return &Writer{dir: filepath.Join(w.dir, "clusters", name), scheme: w.scheme, manifests: w.manifests}
+}
+
+// Write writes the manifest of the object, replacing any previous manifest
+// of the object. Server populated metadata is left out. ErrLimitReached is
+// returned when the object has no manifest yet and the limit is reached
+func (w *Writer) Write(obj client.Object) error {
+	path, err := w.path(obj)
+	if err != nil {
+		return err
+	}
+	w.manifests.mutex.Lock()
+	defer w.manifests.mutex.Unlock()
+	if _, ok := w.manifests.paths[path]; !ok && w.manifests.limit > 0 && len(w.manifests.paths) >= w.manifests.limit {
+		return fmt.Errorf("%w, not exporting %s: %d manifests written", ErrLimitReached, path, len(w.manifests.paths))
+	}
+	gvk, err := apiutil.GVKForObject(obj, w.scheme)
+	if err != nil {
+		return err
+	}
+	manifest := obj.DeepCopyObject().(client.Object)
+	manifest.GetObjectKind().SetGroupVersionKind(gvk)
+	manifest.SetResourceVersion("")
+	manifest.SetUID("")
+	manifest.SetGeneration(0)
+	manifest.SetCreationTimestamp(metav1.Time{})
+	manifest.SetManagedFields(nil)
+	out, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	log.Log.V(3).Info("exporting manifest", "path", path)
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return err
+	}
+	w.manifests.paths[path] = struct{}{}
+	return nil
+}
+
+// Remove removes the manifest of the object
+func (w *Writer) Remove(obj client.Object) error {
+	path, err := w.path(obj)
+	if err != nil {
+		return err
+	}
+	w.manifests.mutex.Lock()
+	defer w.manifests.mutex.Unlock()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(w.manifests.paths, path)
+	return nil
+}
+
+// read reads the manifest of the object into the object, returning false
+// when the object has no manifest
+func (w *Writer) read(key client.ObjectKey, obj client.Object) (bool, error) {
+	named := obj.DeepCopyObject().(client.Object)
+	named.SetNamespace(key.Namespace)
+	named.SetName(key.Name)
+	path, err := w.path(named)
+	if err != nil {
+		return false, err
+	}
+	w.manifests.mutex.Lock()
+	defer w.manifests.mutex.Unlock()
+	if _, ok := w.manifests.paths[path]; !ok {
+		return false, nil
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	value := reflect.ValueOf(obj).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	return true, yaml.Unmarshal(out, obj)
+}
+
+func (w *Writer) path(obj client.Object) (string, error) {
+	gvk, err := apiutil.GVKForObject(obj, w.scheme)
+	if err != nil {
+		return "", err
+	}
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = clusterScoped
+	}
+	if obj.GetName() == "" {
+		return "", fmt.Errorf("can't export %s without a name", gvk.Kind)
+	}
+	return filepath.Join(w.dir, namespace, fmt.Sprintf("%s-%s.yaml", strings.ToLower(gvk.Kind), obj.GetName())), nil
+}
+
+// Client returns a client reading through the client and writing the
+// manifests of the objects it's asked to create, update or patch, status
+// included. Deleted objects have their manifest removed
+func (w *Writer) Client(c client.Client) client.Client {
+	return &exportClient{Client: c, writer: w}
+}
+
+// NewClient creates the client of a manager with the default client of
+// controller-runtime, exporting its writes. Every controller of the manager
+// exports its writes instead of applying them
+func (w *Writer) NewClient(cache cache.Cache, config *rest.Config, options client.Options, uncachedObjects ...client.Object) (client.Client, error) {
+	c, err := cluster.DefaultNewClient(cache, config, options, uncachedObjects...)
+	if err != nil {
+		return nil, err
+	}
+	return w.Client(c), nil
+}
+
+type exportClient struct {
+	client.Client
+	writer *Writer
+}
+
+// Get reads the object as the writes exported would have left it: the
+// manifest exported of the object, with the server populated metadata of
+// the object read through when it exists
+func (c *exportClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	err := c.Client.Get(ctx, key, obj, opts...)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	server := obj.DeepCopyObject().(client.Object)
+	exported, readErr := c.writer.read(key, obj)
+	if readErr != nil {
+		return readErr
+	}
+	if !exported {
+		return err
+	}
+	if err == nil {
+		obj.SetResourceVersion(server.GetResourceVersion())
+		obj.SetUID(server.GetUID())
+		obj.SetGeneration(server.GetGeneration())
+		obj.SetCreationTimestamp(server.GetCreationTimestamp())
+	}
+	return nil
+}
+
+func (c *exportClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	return c.writer.Write(obj)
+}
+
+func (c *exportClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	return c.writer.Write(obj)
+}
+
+func (c *exportClient) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	return c.writer.Write(obj)
+}
+
+func (c *exportClient) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	return c.writer.Remove(obj)
+}
+
+func (c *exportClient) DeleteAllOf(_ context.Context, obj client.Object, _ ...client.DeleteAllOfOption) error {
+	log.Log.Info("skipping export of collection deletion", "kind", fmt.Sprintf("%T", obj))
+	return nil
+}
+
+func (c *exportClient) Status() client.SubResourceWriter {
+	return &exportStatusWriter{writer: c.writer}
+}
+
+type exportStatusWriter struct {
+	writer *Writer
+}
+
+func (s *exportStatusWriter) Create(_ context.Context, obj client.Object, _ client.Object, _ ...client.SubResourceCreateOption) error {
+	return s.writer.Write(obj)
+}
+
+func (s *exportStatusWriter) Update(_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+	return s.writer.Write(obj)
+}
+
+func (s *exportStatusWriter) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+	return s.writer.Write(obj)
+}
@@ -0,0 +1,188 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		obj        client.Object
+		expectPath string
+	}{
+		{
+			name: "namespaced object",
+			obj: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name:            "app.example.com",
+				Namespace:       "argocd",
+				ResourceVersion: "42",
+			}},
+			expectPath: "control-plane/argocd/secret-app.example.com.yaml",
+		},
+		{
+			name:       "cluster scoped object",
+			obj:        &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "argocd"}},
+			expectPath: "control-plane/_cluster/namespace-argocd.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := NewWriter(dir, scheme.Scheme, 0).ForControlPlane()
+			if err := w.Write(tt.obj); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			path := filepath.Join(dir, tt.expectPath)
+			out, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("expected manifest at '%s': %v", tt.expectPath, err)
+			}
+			if !strings.Contains(string(out), "kind: ") || strings.Contains(string(out), "resourceVersion") {
+				t.Errorf("expected manifest with kind and without resourceVersion got '%s'", out)
+			}
+			if err := w.Remove(tt.obj); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("expected manifest removed got '%v'", err)
+			}
+		})
+	}
+}
+
+func TestWriter_limit(t *testing.T) {
+	secret := func(name string) client.Object {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "argocd"}}
+	}
+	tests := []struct {
+		name        string
+		written     []client.Object
+		removed     []client.Object
+		obj         client.Object
+		expectLimit bool
+	}{
+		{
+			name:    "below the limit",
+			written: []client.Object{secret("a")},
+			obj:     secret("b"),
+		},
+		{
+			name:        "new manifest at the limit",
+			written:     []client.Object{secret("a"), secret("b")},
+			obj:         secret("c"),
+			expectLimit: true,
+		},
+		{
+			name:    "manifest already written at the limit",
+			written: []client.Object{secret("a"), secret("b")},
+			obj:     secret("b"),
+		},
+		{
+			name:    "new manifest after a removal",
+			written: []client.Object{secret("a"), secret("b")},
+			removed: []client.Object{secret("a")},
+			obj:     secret("c"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWriter(t.TempDir(), scheme.Scheme, 2)
+			// the limit is shared by the directories of the writer
+			for i, obj := range tt.written {
+				dir := w.ForControlPlane()
+				if i%2 == 1 {
+					dir = w.ForCluster("cluster-1")
+				}
+				if err := dir.Write(obj); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			for _, obj := range tt.removed {
+				if err := w.ForControlPlane().Remove(obj); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			err := w.ForCluster("cluster-1").Write(tt.obj)
+			if limit := errors.Is(err, ErrLimitReached); limit != tt.expectLimit {
+				t.Errorf("expected '%v' got '%v'", tt.expectLimit, err)
+			}
+		})
+	}
+}
+
+func TestClient_get(t *testing.T) {
+	exported := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"},
+		Data:       map[string][]byte{"tls.crt": []byte("exported")},
+	}
+	tests := []struct {
+		name          string
+		existing      []client.Object
+		export        bool
+		expectData    string
+		expectVersion string
+		expectErr     bool
+	}{
+		{
+			name:     "not exported",
+			existing: []client.Object{&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"}, Data: map[string][]byte{"tls.crt": []byte("served")}}},
+			// read through
+			expectData:    "served",
+			expectVersion: "999",
+		},
+		{
+			name:       "exported",
+			export:     true,
+			expectData: "exported",
+		},
+		{
+			name:          "exported over an existing object",
+			existing:      []client.Object{&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"}, Data: map[string][]byte{"tls.crt": []byte("served"), "tls.key": []byte("served")}}},
+			export:        true,
+			expectData:    "exported",
+			expectVersion: "999",
+		},
+		{
+			name:      "neither exported nor existing",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewWriter(t.TempDir(), scheme.Scheme, 0).ForCluster("cluster-1").Client(fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tt.existing...).Build())
+			if tt.export {
+				if err := c.Patch(context.TODO(), exported.DeepCopy(), client.Apply); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			secret := &corev1.Secret{}
+			err := c.Get(context.TODO(), client.ObjectKeyFromObject(exported), secret)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error '%v' got '%v'", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if string(secret.Data["tls.crt"]) != tt.expectData || len(secret.Data) != 1 {
+				t.Errorf("expected '%v' got '%v'", tt.expectData, secret.Data)
+			}
+			if secret.ResourceVersion != tt.expectVersion {
+				t.Errorf("expected '%v' got '%v'", tt.expectVersion, secret.ResourceVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kuadrantv1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+)
+
+// InjectedErr is wrapped by the errors of injected faults
+var InjectedErr = errors.New("injected fault")
+
+// Faults are the faults injected into the calls to providers
+type Faults struct {
+	// ErrorRate is the fraction of calls failing, between 0 and 1
+	ErrorRate float64
+	// Latency is the maximum delay added to each call. The delay of a call
+	// is picked at random up to it
+	Latency time.Duration
+	// PartialFailureRate is the fraction of DNS record updates publishing
+	// only some of the endpoints of the record before failing
+	PartialFailureRate float64
+}
+
+// Validate returns an error when a rate isn't between 0 and 1 or the latency
+// is negative
+func (f Faults) Validate() error {
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("fault error rate %v is not between 0 and 1", f.ErrorRate)
+	}
+	if f.PartialFailureRate < 0 || f.PartialFailureRate > 1 {
+		return fmt.Errorf("fault partial failure rate %v is not between 0 and 1", f.PartialFailureRate)
+	}
+	if f.Latency < 0 {
+		return fmt.Errorf("fault latency %v is negative", f.Latency)
+	}
+	return nil
+}
+
+// Injector injects faults into the calls of the providers it wraps while
+// the FaultInjection feature is enabled, so the feature can be switched on
+// and off with the ControllerConfig during a soak test
+type Injector struct {
+	Faults Faults
+	config *config.Store
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func NewInjector(faults Faults, store *config.Store, seed int64) *Injector {
+	return &Injector{Faults: faults, config: store, rand: rand.New(rand.NewSource(seed))}
+}
+
+func (i *Injector) enabled() bool {
+	return i.config.Get().Enabled(features.FaultInjection)
+}
+
+// roll returns true with the probability given
+func (i *Injector) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Float64() < probability
+}
+
+func (i *Injector) delay() time.Duration {
+	if i.Faults.Latency <= 0 {
+		return 0
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return time.Duration(i.rand.Int63n(int64(i.Faults.Latency)))
+}
+
+// inject delays the call and fails it at the configured rates
+func (i *Injector) inject(ctx context.Context, call string) error {
+	if !i.enabled() {
+		return nil
+	}
+	if delay := i.delay(); delay > 0 {
+		log.FromContext(ctx).V(1).Info("injecting latency", "call", call, "delay", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if i.roll(i.Faults.ErrorRate) {
+		log.FromContext(ctx).V(1).Info("injecting error", "call", call)
+		return fmt.Errorf("%s: %w", call, InjectedErr)
+	}
+	return nil
+}
+
+// partial returns true when the call should only partially succeed
+func (i *Injector) partial() bool {
+	return i.enabled() && i.roll(i.Faults.PartialFailureRate)
+}
+
+// DNSProvider wraps the provider with the injector. Providers that can
+// verify their records keep doing so, with faults injected, as do the
+// providers that can also report the DNSSEC state of their zones and list
+// their records
+func (i *Injector) DNSProvider(provider dns.Provider) dns.Provider {
+	p := &dnsProvider{Provider: provider, injector: i}
+	if verifier, ok := provider.(dns.Verifier); ok {
+		v := &dnsVerifier{dnsProvider: p, verifier: verifier}
+		if reporter, ok := provider.(dns.DNSSECReporter); ok {
+			r := &dnsSECReporter{dnsVerifier: v, reporter: reporter}
+			if lister, ok := provider.(dns.ZoneLister); ok {
+				return &dnsZoneLister{dnsSECReporter: r, lister: lister}
+			}
+			return r
+		}
+		return v
+	}
+	return p
+}
+
+// CertificateService wraps the certificate service with the injector
+func (i *Injector) CertificateService(certificates traffic.CertificateService) traffic.CertificateService {
+	return &certificateService{CertificateService: certificates, injector: i}
+}
+
+type dnsProvider struct {
+	dns.Provider
+	injector *Injector
+}
+
+// Ensure fails before calling the provider, or after publishing only the
+// first half of the endpoints of the record on a partial failure
+func (p *dnsProvider) Ensure(ctx context.Context, record *kuadrantv1.DNSRecord, zone kuadrantv1.DNSZone) error {
+	if err := p.injector.inject(ctx, "ensure record "+record.Name); err != nil {
+		return err
+	}
+	if len(record.Spec.Endpoints) < 2 || !p.injector.partial() {
+		return p.Provider.Ensure(ctx, record, zone)
+	}
+	partial := record.DeepCopy()
+	partial.Spec.Endpoints = partial.Spec.Endpoints[:len(partial.Spec.Endpoints)/2]
+	log.FromContext(ctx).V(1).Info("injecting partial failure", "record", record.Name, "published", len(partial.Spec.Endpoints), "endpoints", len(record.Spec.Endpoints))
+	if err := p.Provider.Ensure(ctx, partial, zone); err != nil {
+		return err
+	}
+	return fmt.Errorf("ensure record %s: published %d of %d endpoints: %w", record.Name, len(partial.Spec.Endpoints), len(record.Spec.Endpoints), InjectedErr)
+}
+
+func (p *dnsProvider) Delete(ctx context.Context, record *kuadrantv1.DNSRecord, zone kuadrantv1.DNSZone) error {
+	if err := p.injector.inject(ctx, "delete record "+record.Name); err != nil {
+		return err
+	}
+	return p.Provider.Delete(ctx, record, zone)
+}
+
+type dnsVerifier struct {
+	*dnsProvider
+	verifier dns.Verifier
+}
+
+func (p *dnsVerifier) Verify(ctx context.Context, record *kuadrantv1.DNSRecord, zone kuadrantv1.DNSZone) ([]*kuadrantv1.Endpoint, error) {
+	if err := p.injector.inject(ctx, "verify record "+record.Name); err != nil {
+		return nil, err
+	}
+	return p.verifier.Verify(ctx, record, zone)
+}
+
+type dnsSECReporter struct {
+	*dnsVerifier
+	reporter dns.DNSSECReporter
+}
+
+func (p *dnsSECReporter) DNSSEC(ctx context.Context, zone kuadrantv1.DNSZone) (*kuadrantv1.ZoneDNSSECStatus, error) {
+	if err := p.injector.inject(ctx, "get DNSSEC of zone "+zone.ID); err != nil {
+		return nil, err
+	}
+	return p.reporter.DNSSEC(ctx, zone)
+}
+
+type dnsZoneLister struct {
+	*dnsSECReporter
+	lister dns.ZoneLister
+}
+
+func (p *dnsZoneLister) ZoneRecords(ctx context.Context, zone kuadrantv1.DNSZone) ([]*kuadrantv1.Endpoint, error) {
+	if err := p.injector.inject(ctx, "list records of zone "+zone.ID); err != nil {
+		return nil, err
+	}
+	return p.lister.ZoneRecords(ctx, zone)
+}
+
+type certificateService struct {
+	traffic.CertificateService
+	injector *Injector
+}
+
+func (s *certificateService) EnsureCertificate(ctx context.Context, host string, owner metav1.Object) error {
+	if err := s.injector.inject(ctx, "ensure certificate "+host); err != nil {
+		return err
+	}
+	return s.CertificateService.EnsureCertificate(ctx, host, owner)
+}
+
+func (s *certificateService) GetCertificateSecret(ctx context.Context, host string) (*v1.Secret, error) {
+	if err := s.injector.inject(ctx, "get certificate secret "+host); err != nil {
+		return nil, err
+	}
+	return s.CertificateService.GetCertificateSecret(ctx, host)
+}
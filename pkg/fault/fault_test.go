@@ -0,0 +1,110 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+)
+
+type recordingProvider struct {
+	ensured []*v1.DNSRecord
+}
+
+func (p *recordingProvider) Ensure(_ context.Context, record *v1.DNSRecord, _ v1.DNSZone) error {
+	p.ensured = append(p.ensured, record)
+	return nil
+}
+
+func (p *recordingProvider) Delete(_ context.Context, _ *v1.DNSRecord, _ v1.DNSZone) error {
+	return nil
+}
+
+func testRecord() *v1.DNSRecord {
+	return &v1.DNSRecord{Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+		{DNSName: "test.example.com", SetIdentifier: "a"},
+		{DNSName: "test.example.com", SetIdentifier: "b"},
+		{DNSName: "test.example.com", SetIdentifier: "c"},
+		{DNSName: "test.example.com", SetIdentifier: "d"},
+	}}}
+}
+
+func TestDNSProvider_Ensure(t *testing.T) {
+	cases := []struct {
+		Name              string
+		Enabled           bool
+		Faults            Faults
+		ExpectErr         bool
+		ExpectedEndpoints int
+	}{
+		{
+			Name:              "feature disabled",
+			Faults:            Faults{ErrorRate: 1, PartialFailureRate: 1},
+			ExpectedEndpoints: 4,
+		},
+		{
+			Name:              "no faults",
+			Enabled:           true,
+			ExpectedEndpoints: 4,
+		},
+		{
+			Name:      "error",
+			Enabled:   true,
+			Faults:    Faults{ErrorRate: 1},
+			ExpectErr: true,
+		},
+		{
+			Name:              "partial failure",
+			Enabled:           true,
+			Faults:            Faults{PartialFailureRate: 1},
+			ExpectErr:         true,
+			ExpectedEndpoints: 2,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			store := config.NewStore(config.Config{FeatureGates: map[string]bool{string(features.FaultInjection): tc.Enabled}})
+			inner := &recordingProvider{}
+			provider := NewInjector(tc.Faults, store, 1).DNSProvider(inner)
+
+			err := provider.Ensure(context.Background(), testRecord(), v1.DNSZone{})
+			if tc.ExpectErr != (err != nil) {
+				t.Fatalf("expected error '%v' got '%v'", tc.ExpectErr, err)
+			}
+			if err != nil && !errors.Is(err, InjectedErr) {
+				t.Errorf("expected '%v' got '%v'", InjectedErr, err)
+			}
+			published := 0
+			if len(inner.ensured) > 0 {
+				published = len(inner.ensured[0].Spec.Endpoints)
+			}
+			if published != tc.ExpectedEndpoints {
+				t.Errorf("expected '%v' got '%v'", tc.ExpectedEndpoints, published)
+			}
+		})
+	}
+}
+
+func TestFaults_Validate(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Faults Faults
+		Error  bool
+	}{
+		{Name: "no faults", Faults: Faults{}},
+		{Name: "valid rates", Faults: Faults{ErrorRate: 0.1, PartialFailureRate: 1}},
+		{Name: "error rate above 1", Faults: Faults{ErrorRate: 1.5}, Error: true},
+		{Name: "negative partial failure rate", Faults: Faults{PartialFailureRate: -0.1}, Error: true},
+		{Name: "negative latency", Faults: Faults{Latency: -1}, Error: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if err := tc.Faults.Validate(); tc.Error != (err != nil) {
+				t.Errorf("expected error '%v' got '%v'", tc.Error, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature is the name of a feature that can be enabled or disabled with the
+// --feature-gates flag or the featureGates of the ControllerConfig
+type Feature string
+
+const (
+	// GeoDNS publishes records with geolocation routing
+	GeoDNS Feature = "GeoDNS"
+	// LatencyDNS publishes records with latency routing, for the traffic
+	// objects using the latency DNS strategy
+	LatencyDNS Feature = "LatencyDNS"
+	// BackendPlacement only publishes the endpoints of a cluster while the
+	// backend services of its traffic object exist in the cluster, so
+	// clusters without backends don't black-hole traffic
+	BackendPlacement Feature = "BackendPlacement"
+	// HTTP01Challenges answers the ACME HTTP-01 challenges of managed hosts
+	// from every cluster they resolve to, publishing the endpoints of a host
+	// before its certificate is issued
+	HTTP01Challenges Feature = "HTTP01Challenges"
+	// FaultInjection injects the faults configured by the --fault-* flags
+	// into the calls to the DNS provider and the certificate service, to
+	// exercise the resilience of the reconcile loops in soak tests
+	FaultInjection Feature = "FaultInjection"
+	// ReconcileDiff logs the changes the controller is about to write to
+	// the traffic objects of the workload clusters, to debug the controller
+	// fighting other actors mutating them
+	ReconcileDiff Feature = "ReconcileDiff"
+)
+
+type PreRelease string
+
+const (
+	Alpha PreRelease = "ALPHA"
+	Beta  PreRelease = "BETA"
+	GA    PreRelease = ""
+)
+
+// FeatureSpec is the default state and maturity of a feature
+type FeatureSpec struct {
+	Default    bool
+	PreRelease PreRelease
+}
+
+// Known are the features that can be gated. Experimental features are
+// disabled by default so they can ship dark and be enabled per install
+var Known = map[Feature]FeatureSpec{
+	GeoDNS:           {Default: false, PreRelease: Alpha},
+	LatencyDNS:       {Default: false, PreRelease: Alpha},
+	BackendPlacement: {Default: false, PreRelease: Alpha},
+	HTTP01Challenges: {Default: false, PreRelease: Alpha},
+	FaultInjection:   {Default: false, PreRelease: Alpha},
+	ReconcileDiff:    {Default: false, PreRelease: Alpha},
+}
+
+// Gates holds the features enabled or disabled explicitly. It implements
+// flag.Value to be parsed from a `Feature=true,Other=false` list
+type Gates map[string]bool
+
+func (g Gates) String() string {
+	var pairs []string
+	for name, enabled := range g {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (g Gates) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, v, found := strings.Cut(pair, "=")
+		if !found {
+			return fmt.Errorf("missing value for feature gate %s", name)
+		}
+		if _, ok := Known[Feature(name)]; !ok {
+			return fmt.Errorf("unknown feature gate %s", name)
+		}
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid value %s for feature gate %s: %w", v, name, err)
+		}
+		g[name] = enabled
+	}
+	return nil
+}
+
+// Enabled returns whether the feature is enabled, falling back to its
+// default when it isn't set explicitly
+func (g Gates) Enabled(feature Feature) bool {
+	if enabled, ok := g[string(feature)]; ok {
+		return enabled
+	}
+	return Known[feature].Default
+}
+
+// Usage describes the known features for the flag usage
+func Usage() string {
+	var features []string
+	for feature, spec := range Known {
+		features = append(features, fmt.Sprintf("%s=true|false (%s - default=%t)", feature, spec.PreRelease, spec.Default))
+	}
+	sort.Strings(features)
+	return strings.Join(features, ", ")
+}
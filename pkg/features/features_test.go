@@ -0,0 +1,57 @@
+package features
+
+import "testing"
+
+func TestGates_Set(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Value    string
+		Expected Gates
+		Error    bool
+	}{
+		{
+			Name:     "enables known feature",
+			Value:    "GeoDNS=true",
+			Expected: Gates{"GeoDNS": true},
+		},
+		{
+			Name:     "ignores empty entries",
+			Value:    " GeoDNS=false, ",
+			Expected: Gates{"GeoDNS": false},
+		},
+		{
+			Name:  "rejects unknown feature",
+			Value: "Unknown=true",
+			Error: true,
+		},
+		{
+			Name:  "rejects missing value",
+			Value: "GeoDNS",
+			Error: true,
+		},
+		{
+			Name:  "rejects invalid value",
+			Value: "GeoDNS=maybe",
+			Error: true,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			gates := Gates{}
+			err := gates.Set(testCase.Value)
+			if testCase.Error {
+				if err == nil {
+					t.Fatalf("expected error got '%v'", gates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+			if gates.String() != testCase.Expected.String() {
+				t.Fatalf("expected '%v' got '%v'", testCase.Expected, gates)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+)
+
+// EgressPath is the path the egress addresses of the fleet are served at
+const EgressPath = "/api/v1/egress"
+
+// Egress lists the addresses the traffic of the fleet egresses from, for
+// the dependencies of the fleet to allow-list
+type Egress struct {
+	// IPs are the sorted egress addresses of all the clusters
+	IPs      []string        `json:"ips"`
+	Clusters []ClusterEgress `json:"clusters"`
+}
+
+type ClusterEgress struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips"`
+	// Declared is true when the addresses are declared on the cluster
+	// secret rather than detected
+	Declared bool `json:"declared"`
+	// Error is why the addresses of the cluster are unknown
+	Error string `json:"error,omitempty"`
+}
+
+// serveEgress serves the egress addresses of the fleet as JSON, or as a
+// list of one address per line with format=text
+func (s *Server) serveEgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	egress, err := s.Egress(r.Context())
+	if err != nil {
+		log.Log.Error(err, "failed to list fleet egress addresses")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain")
+		for _, ip := range egress.IPs {
+			if _, err := w.Write([]byte(ip + "\n")); err != nil {
+				log.Log.Error(err, "failed to write fleet egress addresses")
+				return
+			}
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(egress); err != nil {
+		log.Log.Error(err, "failed to write fleet egress addresses")
+	}
+}
+
+// Egress aggregates the egress addresses of the clusters of the fleet
+func (s *Server) Egress(ctx context.Context) (*Egress, error) {
+	secrets := &corev1.SecretList{}
+	if err := s.Client.List(ctx, secrets, client.InNamespace(s.Namespace), client.MatchingLabels{secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE}); err != nil {
+		return nil, err
+	}
+	egress := &Egress{IPs: []string{}, Clusters: []ClusterEgress{}}
+	unique := map[string]struct{}{}
+	for i := range secrets.Items {
+		cluster := &secrets.Items[i]
+		clusterEgress := ClusterEgress{
+			Name:     cluster.Name,
+			IPs:      []string{},
+			Declared: metadata.GetAnnotation(cluster, clusterSecret.AnnotationEgressIPs) != "",
+		}
+		ips, err := clusterSecret.EgressIPs(cluster)
+		if err != nil {
+			clusterEgress.Error = err.Error()
+		} else {
+			clusterEgress.IPs = ips
+		}
+		for _, ip := range clusterEgress.IPs {
+			unique[ip] = struct{}{}
+		}
+		egress.Clusters = append(egress.Clusters, clusterEgress)
+	}
+	for ip := range unique {
+		egress.IPs = append(egress.IPs, ip)
+	}
+	sort.Strings(egress.IPs)
+	sort.Slice(egress.Clusters, func(i, j int) bool { return egress.Clusters[i].Name < egress.Clusters[j].Name })
+	return egress, nil
+}
+
+// EgressDetector periodically detects the egress addresses of each cluster
+// from the external addresses of its nodes, and records them on its cluster
+// secret, so the egress addresses served follow the fleet as it changes.
+// Clusters whose traffic is NATed declare their addresses instead
+type EgressDetector struct {
+	Client client.Client
+	// Namespace is the controller namespace holding the cluster secrets
+	Namespace string
+	Interval  time.Duration
+}
+
+func (d *EgressDetector) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info("Starting egress address detector", "interval", d.Interval)
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := d.detectAll(ctx); err != nil {
+				log.FromContext(ctx).Error(err, "failed to detect egress addresses")
+			}
+		}
+	}
+}
+
+func (d *EgressDetector) detectAll(ctx context.Context) error {
+	secrets := &corev1.SecretList{}
+	if err := d.Client.List(ctx, secrets, client.InNamespace(d.Namespace), client.MatchingLabels{secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE}); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		cluster := &secrets.Items[i]
+		if err := d.detect(ctx, cluster); err != nil {
+			log.FromContext(ctx).V(3).Info("failed to detect egress addresses of cluster", "cluster", cluster.Name, "error", err.Error())
+		}
+	}
+	return nil
+}
+
+// detect records the external addresses of the nodes of the cluster on its
+// secret when they changed
+func (d *EgressDetector) detect(ctx context.Context, cluster *corev1.Secret) error {
+	workloadClient, err := clusterSecret.ClientFromSecret(d.Client, cluster, client.Options{})
+	if err != nil {
+		return err
+	}
+	nodes := &corev1.NodeList{}
+	if err := workloadClient.List(ctx, nodes); err != nil {
+		return err
+	}
+	detected := strings.Join(clusterSecret.NodeEgressIPs(nodes.Items), ",")
+	if metadata.GetAnnotation(cluster, clusterSecret.AnnotationDetectedEgressIPs) == detected {
+		return nil
+	}
+	patch := client.MergeFrom(cluster.DeepCopy())
+	metadata.AddAnnotation(cluster, clusterSecret.AnnotationDetectedEgressIPs, detected)
+	log.FromContext(ctx).Info("Detected egress addresses of cluster", "cluster", cluster.Name, "ips", detected)
+	return d.Client.Patch(ctx, cluster, patch)
+}
@@ -0,0 +1,231 @@
+package fleet
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/httpserver"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+// SummaryPath is the path the fleet summary is served at
+const SummaryPath = "/api/v1/summary"
+
+// defaultExpiryDays is how many days ahead certificates are reported as
+// expiring, unless the request sets expiringWithinDays
+const defaultExpiryDays = 30
+
+// Summary aggregates the state of the fleet
+type Summary struct {
+	Hosts        HostSummary        `json:"hosts"`
+	Clusters     ClusterSummary     `json:"clusters"`
+	Certificates CertificateSummary `json:"certificates"`
+	Records      RecordSummary      `json:"records"`
+}
+
+type HostSummary struct {
+	// Managed is the number of managed hosts
+	Managed int `json:"managed"`
+	// Ready is the number of managed hosts published with their
+	// certificate issued
+	Ready int `json:"ready"`
+}
+
+type ClusterSummary struct {
+	// Total is the number of cluster secrets
+	Total int `json:"total"`
+	// Healthy is the number of clusters neither drained for maintenance
+	// nor evacuated
+	Healthy int `json:"healthy"`
+}
+
+type CertificateSummary struct {
+	Total int `json:"total"`
+	// ExpiringWithinDays is the number of days ahead certificates are
+	// counted as expiring
+	ExpiringWithinDays int `json:"expiringWithinDays"`
+	// Expiring is the number of certificates expiring within that many days
+	Expiring int `json:"expiring"`
+}
+
+type RecordSummary struct {
+	Total int `json:"total"`
+	// OutOfSync is the number of DNSRecords not published to all their
+	// zones as they're specified
+	OutOfSync int `json:"outOfSync"`
+}
+
+// Server serves a read-only summary of the fleet for dashboards, aggregated
+// from the resources in the controller namespace, and the egress addresses
+// of the fleet. Without a token it listens on localhost only, so it's
+// reached with a port-forward or from a sidecar
+type Server struct {
+	Client client.Client
+	// Namespace is the controller namespace, holding the cluster secrets,
+	// ManagedHosts, DNSRecords and Certificates
+	Namespace string
+	Port      int
+	// Token is the bearer token clients authenticate with. When set, the
+	// server listens on every interface
+	Token string
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	addr := fmt.Sprintf("127.0.0.1:%d", s.Port)
+	if s.Token != "" {
+		addr = fmt.Sprintf(":%d", s.Port)
+	}
+	log.FromContext(ctx).Info("Starting fleet summary server at " + addr)
+	mux := http.NewServeMux()
+	mux.Handle(SummaryPath, s)
+	mux.HandleFunc(EgressPath, s.serveEgress)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	return httpserver.Serve(ctx, server)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	days := defaultExpiryDays
+	if value := r.URL.Query().Get("expiringWithinDays"); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil || days < 0 {
+			http.Error(w, "expiringWithinDays must be a non-negative number of days", http.StatusBadRequest)
+			return
+		}
+	}
+	summary, err := s.Summarize(r.Context(), days)
+	if err != nil {
+		log.Log.Error(err, "failed to summarize fleet")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		log.Log.Error(err, "failed to write fleet summary")
+	}
+}
+
+// authorized returns true when the request carries the token, or no token
+// is configured as the server only listens on localhost
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// Summarize aggregates the resources in the controller namespace, counting
+// the certificates expiring within the given number of days
+func (s *Server) Summarize(ctx context.Context, expiringWithinDays int) (*Summary, error) {
+	inNamespace := client.InNamespace(s.Namespace)
+	managedHosts := &v1.ManagedHostList{}
+	if err := s.Client.List(ctx, managedHosts, inNamespace); err != nil {
+		return nil, err
+	}
+	secrets := &corev1.SecretList{}
+	if err := s.Client.List(ctx, secrets, inNamespace, client.MatchingLabels{secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE}); err != nil {
+		return nil, err
+	}
+	evacuations := &v1.ClusterEvacuationList{}
+	if err := s.Client.List(ctx, evacuations, inNamespace); err != nil {
+		return nil, err
+	}
+	certificates := &certman.CertificateList{}
+	if err := s.Client.List(ctx, certificates, inNamespace); err != nil {
+		return nil, err
+	}
+	records := &v1.DNSRecordList{}
+	if err := s.Client.List(ctx, records, inNamespace); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	summary := &Summary{}
+	summary.Hosts.Managed = len(managedHosts.Items)
+	for _, managedHost := range managedHosts.Items {
+		if meta.IsStatusConditionTrue(managedHost.Status.Conditions, v1.ManagedHostReadyConditionType) {
+			summary.Hosts.Ready++
+		}
+	}
+
+	evacuated := map[string]struct{}{}
+	for _, evacuation := range evacuations.Items {
+		evacuated[evacuation.Spec.Cluster] = struct{}{}
+	}
+	summary.Clusters.Total = len(secrets.Items)
+	for i := range secrets.Items {
+		if _, ok := evacuated[secrets.Items[i].Name]; !ok && !inMaintenance(&secrets.Items[i], now) {
+			summary.Clusters.Healthy++
+		}
+	}
+
+	summary.Certificates.Total = len(certificates.Items)
+	summary.Certificates.ExpiringWithinDays = expiringWithinDays
+	expiryCutoff := now.Add(time.Duration(expiringWithinDays) * 24 * time.Hour)
+	for _, certificate := range certificates.Items {
+		if notAfter := certificate.Status.NotAfter; notAfter != nil && notAfter.Time.Before(expiryCutoff) {
+			summary.Certificates.Expiring++
+		}
+	}
+
+	summary.Records.Total = len(records.Items)
+	for i := range records.Items {
+		if OutOfSync(&records.Items[i]) {
+			summary.Records.OutOfSync++
+		}
+	}
+	return summary, nil
+}
+
+// inMaintenance returns whether the cluster is drained for one of its
+// maintenance windows. Clusters with invalid windows aren't drained
+func inMaintenance(cluster *corev1.Secret, now time.Time) bool {
+	windows, err := clusterSecret.MaintenanceWindows(cluster)
+	if err != nil {
+		return false
+	}
+	drained, _ := clusterSecret.InMaintenance(windows, now, dns.MaintenanceLeadTime)
+	return drained
+}
+
+// OutOfSync returns whether the record isn't published to all its zones as
+// it's specified: its generation isn't observed yet, it isn't published to
+// any zone or publishing to one of them failed
+func OutOfSync(record *v1.DNSRecord) bool {
+	if record.Status.ObservedGeneration != record.Generation || len(record.Status.Zones) == 0 {
+		return true
+	}
+	for _, zone := range record.Status.Zones {
+		for _, condition := range zone.Conditions {
+			if condition.Type == v1.DNSRecordFailedConditionType && condition.Status != string(metav1.ConditionFalse) {
+				return true
+			}
+		}
+	}
+	return false
+}
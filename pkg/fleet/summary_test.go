@@ -0,0 +1,103 @@
+package fleet
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestOutOfSync(t *testing.T) {
+	published := v1.DNSZoneStatus{
+		DNSZone:    v1.DNSZone{ID: "zone"},
+		Conditions: []v1.DNSZoneCondition{{Type: v1.DNSRecordFailedConditionType, Status: string(metav1.ConditionFalse)}},
+	}
+	failed := v1.DNSZoneStatus{
+		DNSZone:    v1.DNSZone{ID: "zone"},
+		Conditions: []v1.DNSZoneCondition{{Type: v1.DNSRecordFailedConditionType, Status: string(metav1.ConditionTrue)}},
+	}
+	tests := []struct {
+		name       string
+		generation int64
+		status     v1.DNSRecordStatus
+		expect     bool
+	}{
+		{
+			name:       "published",
+			generation: 2,
+			status:     v1.DNSRecordStatus{ObservedGeneration: 2, Zones: []v1.DNSZoneStatus{published}},
+			expect:     false,
+		},
+		{
+			name:       "generation not observed",
+			generation: 3,
+			status:     v1.DNSRecordStatus{ObservedGeneration: 2, Zones: []v1.DNSZoneStatus{published}},
+			expect:     true,
+		},
+		{
+			name:       "not published to any zone",
+			generation: 1,
+			status:     v1.DNSRecordStatus{ObservedGeneration: 1},
+			expect:     true,
+		},
+		{
+			name:       "publishing failed",
+			generation: 1,
+			status:     v1.DNSRecordStatus{ObservedGeneration: 1, Zones: []v1.DNSZoneStatus{published, failed}},
+			expect:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Generation: tt.generation}, Status: tt.status}
+			if got := OutOfSync(record); got != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestServer_authorized(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		expect        bool
+	}{
+		{
+			name:   "no token on localhost",
+			expect: true,
+		},
+		{
+			name:          "token matches",
+			token:         "secret",
+			authorization: "Bearer secret",
+			expect:        true,
+		},
+		{
+			name:          "token doesn't match",
+			token:         "secret",
+			authorization: "Bearer other",
+		},
+		{
+			name:  "token missing",
+			token: "secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", SummaryPath, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			got := (&Server{Token: tt.token}).authorized(r)
+			if got != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package hostapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/httpserver"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+// HostsPath is the path the managed hosts are listed at, and looked up at
+// HostsPath/<host>
+const HostsPath = "/api/v1/hosts"
+
+// Host is the state of a managed host, for tooling outside the clusters
+type Host struct {
+	Host    string              `json:"host"`
+	Traffic v1.TrafficReference `json:"traffic"`
+	// Ready is true once the host is published with its certificate issued
+	Ready bool `json:"ready"`
+	// Clusters are the clusters serving the host, with the targets the host
+	// resolves to in each
+	Clusters []Cluster `json:"clusters"`
+	// Targets are the targets the host resolves to across all clusters
+	Targets     []string     `json:"targets"`
+	Certificate *Certificate `json:"certificate,omitempty"`
+}
+
+type Cluster struct {
+	Name    string   `json:"name"`
+	Targets []string `json:"targets"`
+	// Drained is true while the cluster is drained of the traffic of the
+	// host
+	Drained bool `json:"drained"`
+}
+
+type Certificate struct {
+	Name     string     `json:"name"`
+	Ready    bool       `json:"ready"`
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+	// RenewalTime is when cert-manager renews the certificate
+	RenewalTime *time.Time `json:"renewalTime,omitempty"`
+}
+
+// Server serves a read-only, token authenticated HTTP API looking up the
+// clusters, targets and certificate of the managed hosts, so tooling
+// outside the clusters can integrate without access to the API server
+type Server struct {
+	Client client.Client
+	// Namespace is the controller namespace, holding the ManagedHosts,
+	// DNSRecords and Certificates
+	Namespace string
+	Port      int
+	// Token is the bearer token clients authenticate with
+	Token string
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info(fmt.Sprintf("Starting host API at :%d", s.Port))
+	mux := http.NewServeMux()
+	mux.Handle(HostsPath, s)
+	mux.Handle(HostsPath+"/", s)
+	server := &http.Server{Addr: fmt.Sprintf(":%d", s.Port), Handler: mux}
+
+	return httpserver.Serve(ctx, server)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body interface{}
+	var err error
+	host := strings.Trim(strings.TrimPrefix(r.URL.Path, HostsPath), "/")
+	if host == "" {
+		body, err = s.Hosts(r.Context())
+	} else {
+		var found *Host
+		found, err = s.Lookup(r.Context(), host)
+		if err == nil && found == nil {
+			http.Error(w, fmt.Sprintf("host %s is not managed", host), http.StatusNotFound)
+			return
+		}
+		body = found
+	}
+	if err != nil {
+		log.Log.Error(err, "failed to look up managed hosts", "host", host)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Log.Error(err, "failed to write managed hosts", "host", host)
+	}
+}
+
+// Hosts returns the sorted names of the managed hosts
+func (s *Server) Hosts(ctx context.Context) ([]string, error) {
+	managedHosts := &v1.ManagedHostList{}
+	if err := s.Client.List(ctx, managedHosts, client.InNamespace(s.Namespace)); err != nil {
+		return nil, err
+	}
+	hosts := make([]string, 0, len(managedHosts.Items))
+	for _, managedHost := range managedHosts.Items {
+		hosts = append(hosts, managedHost.Spec.Host)
+	}
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+// Lookup returns the state of the managed host, or nil when the host isn't
+// managed
+func (s *Server) Lookup(ctx context.Context, host string) (*Host, error) {
+	managedHosts := &v1.ManagedHostList{}
+	if err := s.Client.List(ctx, managedHosts, client.InNamespace(s.Namespace)); err != nil {
+		return nil, err
+	}
+	var managedHost *v1.ManagedHost
+	for i := range managedHosts.Items {
+		if strings.EqualFold(managedHosts.Items[i].Spec.Host, host) {
+			managedHost = &managedHosts.Items[i]
+			break
+		}
+	}
+	if managedHost == nil {
+		return nil, nil
+	}
+
+	result := &Host{
+		Host:     managedHost.Spec.Host,
+		Traffic:  managedHost.Spec.TrafficRef,
+		Ready:    meta.IsStatusConditionTrue(managedHost.Status.Conditions, v1.ManagedHostReadyConditionType),
+		Clusters: []Cluster{},
+		Targets:  []string{},
+	}
+	if managedHost.Status.DNSRecord != "" {
+		record := &v1.DNSRecord{}
+		err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: managedHost.Status.DNSRecord}, record)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err == nil {
+			result.Clusters, result.Targets = HostTargets(record, managedHost.Spec.Host)
+		}
+	}
+	if managedHost.Status.Certificate != "" {
+		certificate := &certman.Certificate{}
+		err := s.Client.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: managedHost.Status.Certificate}, certificate)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err == nil {
+			result.Certificate = certificateOf(certificate)
+		}
+	}
+	return result, nil
+}
+
+// HostTargets returns the clusters the record publishes the host for, with
+// the targets of each, and the targets of the host across all clusters
+func HostTargets(record *v1.DNSRecord, host string) ([]Cluster, []string) {
+	byCluster := map[string]*Cluster{}
+	all := map[string]struct{}{}
+	for _, endpoint := range record.Spec.Endpoints {
+		if !strings.EqualFold(endpoint.DNSName, host) {
+			continue
+		}
+		for _, target := range endpoint.Targets {
+			all[target] = struct{}{}
+		}
+		name := dns.EndpointCluster(endpoint)
+		if name == "" {
+			continue
+		}
+		cluster, ok := byCluster[name]
+		if !ok {
+			cluster = &Cluster{Name: name, Targets: []string{}, Drained: true}
+			byCluster[name] = cluster
+		}
+		cluster.Targets = append(cluster.Targets, endpoint.Targets...)
+		cluster.Drained = cluster.Drained && dns.Drained(endpoint)
+	}
+
+	clusters := make([]Cluster, 0, len(byCluster))
+	for _, cluster := range byCluster {
+		sort.Strings(cluster.Targets)
+		clusters = append(clusters, *cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+	targets := make([]string, 0, len(all))
+	for target := range all {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return clusters, targets
+}
+
+func certificateOf(certificate *certman.Certificate) *Certificate {
+	result := &Certificate{Name: certificate.Name}
+	for _, condition := range certificate.Status.Conditions {
+		if condition.Type == certman.CertificateConditionReady {
+			result.Ready = condition.Status == cmmeta.ConditionTrue
+		}
+	}
+	if notAfter := certificate.Status.NotAfter; notAfter != nil {
+		result.NotAfter = &notAfter.Time
+	}
+	if renewalTime := certificate.Status.RenewalTime; renewalTime != nil {
+		result.RenewalTime = &renewalTime.Time
+	}
+	return result
+}
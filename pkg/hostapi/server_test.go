@@ -0,0 +1,78 @@
+package hostapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestHostTargets(t *testing.T) {
+	record := &v1.DNSRecord{
+		Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+			{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"2.2.2.2"}, Labels: v1.Labels{"kuadrant.io/owner": "east/default/app"}},
+			{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"1.1.1.1"}, Labels: v1.Labels{"kuadrant.io/owner": "east/default/app"}},
+			{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"3.3.3.3"}, Labels: v1.Labels{"kuadrant.io/owner": "west/default/app", "kuadrant.io/drained": "true"}},
+			{DNSName: "east.app.example.com", RecordType: "A", Targets: v1.Targets{"1.1.1.1"}, Labels: v1.Labels{"kuadrant.io/owner": "east/default/app"}},
+		}},
+	}
+
+	clusters, targets := HostTargets(record, "app.example.com")
+	expectClusters := []Cluster{
+		{Name: "east", Targets: []string{"1.1.1.1", "2.2.2.2"}},
+		{Name: "west", Targets: []string{"3.3.3.3"}, Drained: true},
+	}
+	if !reflect.DeepEqual(clusters, expectClusters) {
+		t.Errorf("expected '%v' got '%v'", expectClusters, clusters)
+	}
+	expectTargets := []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}
+	if !reflect.DeepEqual(targets, expectTargets) {
+		t.Errorf("expected '%v' got '%v'", expectTargets, targets)
+	}
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v1.ManagedHost{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "mctc"},
+			Spec:       v1.ManagedHostSpec{Host: "app.example.com"},
+		},
+	).Build()
+	server := &Server{Client: c, Namespace: "mctc", Token: "secret"}
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		expect int
+	}{
+		{name: "unauthenticated", path: HostsPath + "/app.example.com", expect: http.StatusUnauthorized},
+		{name: "wrong token", path: HostsPath + "/app.example.com", token: "guess", expect: http.StatusUnauthorized},
+		{name: "list hosts", path: HostsPath, token: "secret", expect: http.StatusOK},
+		{name: "managed host", path: HostsPath + "/APP.example.com", token: "secret", expect: http.StatusOK},
+		{name: "unmanaged host", path: HostsPath + "/other.example.com", token: "secret", expect: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, request)
+			if recorder.Code != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, recorder.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package localdns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns/aws"
+)
+
+const (
+	// ConfigMapName is the ConfigMap synced to each workload cluster with
+	// the addresses of the managed hosts
+	ConfigMapName = "mctc-managed-hosts"
+	// HostsKey is the key of the ConfigMap holding the addresses of the
+	// managed hosts in the hosts file format of the CoreDNS hosts plugin
+	HostsKey = "hosts"
+)
+
+// Syncer periodically syncs the addresses the managed hosts of the fleet
+// resolve to into a ConfigMap of each workload cluster, so the cluster DNS
+// can answer them locally. In-cluster clients then resolve the managed hosts
+// without leaving the cluster, and keep resolving them through an outage of
+// the public DNS. The ConfigMap is served by mounting it into CoreDNS and
+// adding a server block for the domains of the managed zones, e.g.
+//
+//	example.com:53 {
+//	    hosts /etc/coredns/managed/hosts {
+//	        ttl 60
+//	        reload 15s
+//	        fallthrough
+//	    }
+//	    forward . /etc/resolv.conf
+//	}
+type Syncer struct {
+	Client client.Client
+	// Namespace is the controller namespace holding the cluster secrets
+	Namespace string
+	// TargetNamespace is the namespace of the ConfigMap in each workload
+	// cluster
+	TargetNamespace string
+	Interval        time.Duration
+}
+
+func (s *Syncer) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info("Starting local DNS syncer", "interval", s.Interval, "namespace", s.TargetNamespace)
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.syncAll(ctx); err != nil {
+				log.FromContext(ctx).Error(err, "failed to sync managed hosts to local DNS")
+			}
+		}
+	}
+}
+
+func (s *Syncer) syncAll(ctx context.Context) error {
+	records := &v1.DNSRecordList{}
+	if err := s.Client.List(ctx, records, client.InNamespace(s.Namespace)); err != nil {
+		return err
+	}
+	hosts := Hosts(records.Items, s.Namespace)
+
+	secrets := &corev1.SecretList{}
+	if err := s.Client.List(ctx, secrets, client.InNamespace(s.Namespace), client.MatchingLabels{secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE}); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		cluster := &secrets.Items[i]
+		if err := s.sync(ctx, cluster, hosts); err != nil {
+			log.FromContext(ctx).Error(err, "failed to sync managed hosts to local DNS of cluster", "cluster", cluster.Name)
+		}
+	}
+	return nil
+}
+
+// sync writes the hosts file to the ConfigMap of the cluster
+func (s *Syncer) sync(ctx context.Context, cluster *corev1.Secret, hosts string) error {
+	workloadClient, err := clusterSecret.ClientFromSecret(s.Client, cluster, client.Options{})
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: s.TargetNamespace, Name: ConfigMapName}}
+	result, err := controllerutil.CreateOrUpdate(ctx, workloadClient, configMap, func() error {
+		configMap.Data = map[string]string{HostsKey: hosts}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		log.FromContext(ctx).V(3).Info("synced managed hosts to local DNS", "cluster", cluster.Name, "result", result)
+	}
+	return nil
+}
+
+// Hosts returns the addresses of the managed hosts published by the records
+// of the controller namespace in the hosts file format. Only the endpoints
+// published for the traffic objects of the clusters are included, not the
+// endpoints set on the records by their users. As the hosts file can't
+// weight them, the addresses of drained clusters and of endpoints weighted
+// 0 are left out unless every address of a host is, as the provider then
+// answers with all of them. Hosts published as CNAMEs and wildcard hosts,
+// which the hosts file can't hold, aren't included, and are resolved by the
+// cluster DNS as usual
+func Hosts(records []v1.DNSRecord, namespace string) string {
+	type addresses struct {
+		serving map[string]struct{}
+		idle    map[string]struct{}
+	}
+	byHost := map[string]*addresses{}
+	for i := range records {
+		record := &records[i]
+		if record.DeletionTimestamp != nil || record.Namespace != namespace {
+			continue
+		}
+		for _, endpoint := range record.PublishedEndpoints() {
+			if endpoint.RecordType != string(v1.ARecordType) || strings.HasPrefix(endpoint.DNSName, "*") || dns.EndpointCluster(endpoint) == "" {
+				continue
+			}
+			host := strings.ToLower(strings.TrimSuffix(endpoint.DNSName, "."))
+			if byHost[host] == nil {
+				byHost[host] = &addresses{serving: map[string]struct{}{}, idle: map[string]struct{}{}}
+			}
+			set := byHost[host].serving
+			if dns.Drained(endpoint) || zeroWeighted(endpoint) {
+				set = byHost[host].idle
+			}
+			for _, target := range endpoint.Targets {
+				set[target] = struct{}{}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(byHost))
+	for host := range byHost {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+
+	lines := []string{"# managed hosts of the fleet, synced by the multi-cluster traffic controller"}
+	for _, host := range names {
+		set := byHost[host].serving
+		if len(set) == 0 {
+			set = byHost[host].idle
+		}
+		ips := make([]string, 0, len(set))
+		for ip := range set {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		for _, ip := range ips {
+			lines = append(lines, fmt.Sprintf("%s %s", ip, host))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// zeroWeighted returns true when the endpoint is weighted so it's not
+// answered while other endpoints of its host are
+func zeroWeighted(endpoint *v1.Endpoint) bool {
+	weight, ok := endpoint.GetProviderSpecificProperty(aws.ProviderSpecificWeight)
+	return ok && weight.Value == "0"
+}
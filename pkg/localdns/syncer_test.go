@@ -0,0 +1,108 @@
+package localdns
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestHosts(t *testing.T) {
+	owner := func(cluster string) v1.Labels {
+		return v1.Labels{"kuadrant.io/owner": cluster + "/ingress/default/app"}
+	}
+	drained := func(cluster string) v1.Labels {
+		labels := owner(cluster)
+		labels["kuadrant.io/drained"] = "true"
+		return labels
+	}
+	weight := func(value string) v1.ProviderSpecific {
+		return v1.ProviderSpecific{{Name: "aws/weight", Value: value}}
+	}
+	header := "# managed hosts of the fleet, synced by the multi-cluster traffic controller\n"
+	tests := []struct {
+		name    string
+		records []v1.DNSRecord
+		expect  string
+	}{
+		{
+			name:   "no records",
+			expect: header,
+		},
+		{
+			name: "drained clusters left out",
+			records: []v1.DNSRecord{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"},
+					Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+						{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"2.2.2.2"}, Labels: owner("cluster-b")},
+						{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"1.1.1.1"}, Labels: owner("cluster-a")},
+						{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"3.3.3.3"}, Labels: drained("cluster-c")},
+						{DNSName: "*.app.example.com", RecordType: "A", Targets: v1.Targets{"1.1.1.1"}, Labels: owner("cluster-a")},
+						{DNSName: "www.example.com", RecordType: "CNAME", Targets: v1.Targets{"app.example.com"}, Labels: owner("cluster-a")},
+					}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "api.example.com", Namespace: "argocd"},
+					Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+						{DNSName: "api.example.com", RecordType: "A", Targets: v1.Targets{"4.4.4.4"}, Labels: drained("cluster-a")},
+					}},
+				},
+			},
+			expect: header +
+				"4.4.4.4 api.example.com\n" +
+				"1.1.1.1 app.example.com\n" +
+				"2.2.2.2 app.example.com\n",
+		},
+		{
+			name: "endpoints weighted 0 left out",
+			records: []v1.DNSRecord{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"},
+					Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+						{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"1.1.1.1"}, Labels: owner("cluster-a"), ProviderSpecific: weight("120")},
+						{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"2.2.2.2"}, Labels: owner("cluster-b"), ProviderSpecific: weight("0")},
+					}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "api.example.com", Namespace: "argocd"},
+					Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+						{DNSName: "api.example.com", RecordType: "A", Targets: v1.Targets{"3.3.3.3"}, Labels: owner("cluster-a"), ProviderSpecific: weight("0")},
+					}},
+				},
+			},
+			expect: header +
+				"3.3.3.3 api.example.com\n" +
+				"1.1.1.1 app.example.com\n",
+		},
+		{
+			name: "records of other namespaces and unmanaged endpoints left out",
+			records: []v1.DNSRecord{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"},
+					Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+						{DNSName: "app.example.com", RecordType: "A", Targets: v1.Targets{"1.1.1.1"}, Labels: owner("cluster-a")},
+						{DNSName: "bank.example.com", RecordType: "A", Targets: v1.Targets{"6.6.6.6"}},
+					}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "bank.example.com", Namespace: "team-a"},
+					Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+						{DNSName: "bank.example.com", RecordType: "A", Targets: v1.Targets{"6.6.6.6"}, Labels: owner("cluster-a")},
+					}},
+				},
+			},
+			expect: header +
+				"1.1.1.1 app.example.com\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Hosts(tt.records, "argocd"); got != tt.expect {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
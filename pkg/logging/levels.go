@@ -0,0 +1,204 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Subsystem is the name of a part of the controller whose log verbosity can
+// be set on its own with the --log-levels flag or the logLevels of the
+// ControllerConfig
+type Subsystem string
+
+const (
+	// DNS logs the DNSRecords and ManagedZones published to the DNS
+	// providers and the calls to the providers
+	DNS Subsystem = "dns"
+	// TLS logs the certificates of managed hosts and their ACME challenges
+	TLS Subsystem = "tls"
+	// Syncer logs the traffic objects synced from the workload clusters
+	Syncer Subsystem = "syncer"
+	// Placement logs the shifting of traffic between clusters, by rollouts
+	// and evacuations
+	Placement Subsystem = "placement"
+)
+
+// Known are the subsystems whose verbosity can be set
+var Known = []Subsystem{DNS, TLS, Syncer, Placement}
+
+// MaxVerbosity is the verbosity the underlying logger is configured with,
+// so the verbosity of each subsystem can be raised up to it at runtime
+const MaxVerbosity = 127
+
+// IntoContext returns a context whose logger logs for the subsystem
+func IntoContext(ctx context.Context, subsystem Subsystem) context.Context {
+	return log.IntoContext(ctx, log.FromContext(ctx).WithName(string(subsystem)))
+}
+
+// Levels holds the log verbosity of each subsystem, parsed from a
+// `subsystem=verbosity` list. It implements flag.Value
+type Levels map[string]int
+
+func (l Levels) String() string {
+	var pairs []string
+	for name, verbosity := range l {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, verbosity))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l Levels) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, v, found := strings.Cut(pair, "=")
+		if !found {
+			return fmt.Errorf("missing verbosity for log subsystem %s", name)
+		}
+		if !IsKnown(name) {
+			return fmt.Errorf("unknown log subsystem %s", name)
+		}
+		verbosity, err := strconv.Atoi(v)
+		if err != nil || verbosity < 0 || verbosity > MaxVerbosity {
+			return fmt.Errorf("invalid verbosity %s for log subsystem %s, expected 0 to %d", v, name, MaxVerbosity)
+		}
+		l[name] = verbosity
+	}
+	return nil
+}
+
+// IsKnown returns whether the name is a known subsystem
+func IsKnown(name string) bool {
+	for _, subsystem := range Known {
+		if string(subsystem) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Usage describes the known subsystems for the flag usage
+func Usage() string {
+	names := make([]string, 0, len(Known))
+	for _, subsystem := range Known {
+		names = append(names, string(subsystem))
+	}
+	return strings.Join(names, ", ")
+}
+
+// Filter filters the logs of a logger by the verbosity of the subsystem
+// they're logged for, which is the last subsystem the logger was named
+// after, or by the default verbosity. The verbosities can be changed while
+// logging
+type Filter struct {
+	mu               sync.RWMutex
+	defaultVerbosity int
+	levels           Levels
+}
+
+func NewFilter(defaultVerbosity int, levels Levels) *Filter {
+	f := &Filter{defaultVerbosity: defaultVerbosity}
+	f.Set(levels)
+	return f
+}
+
+// Set replaces the verbosity of the subsystems. Subsystems that aren't set
+// log with the default verbosity
+func (f *Filter) Set(levels Levels) {
+	copied := Levels{}
+	for name, verbosity := range levels {
+		copied[name] = verbosity
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.levels = copied
+}
+
+func (f *Filter) verbosity(subsystem string) int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if verbosity, ok := f.levels[subsystem]; ok {
+		return verbosity
+	}
+	return f.defaultVerbosity
+}
+
+// Logger returns the logger filtering the logs of the logger
+func (f *Filter) Logger(logger logr.Logger) logr.Logger {
+	return logr.New(&filterSink{sink: logger.GetSink(), filter: f})
+}
+
+type filterSink struct {
+	sink      logr.LogSink
+	filter    *Filter
+	subsystem string
+}
+
+var _ logr.CallDepthLogSink = &filterSink{}
+
+func (s *filterSink) Init(info logr.RuntimeInfo) {
+	// account for the filter in the call stack of the logs
+	info.CallDepth++
+	s.sink.Init(info)
+}
+
+func (s *filterSink) Enabled(level int) bool {
+	return level <= s.filter.verbosity(s.subsystem) && s.sink.Enabled(level)
+}
+
+func (s *filterSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(level, msg, keysAndValues...)
+}
+
+func (s *filterSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(err, msg, keysAndValues...)
+}
+
+func (s *filterSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &filterSink{sink: s.sink.WithValues(keysAndValues...), filter: s.filter, subsystem: s.subsystem}
+}
+
+func (s *filterSink) WithName(name string) logr.LogSink {
+	subsystem := s.subsystem
+	if IsKnown(name) {
+		subsystem = name
+	}
+	return &filterSink{sink: s.sink.WithName(name), filter: s.filter, subsystem: subsystem}
+}
+
+func (s *filterSink) WithCallDepth(depth int) logr.LogSink {
+	sink := s.sink
+	if withCallDepth, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = withCallDepth.WithCallDepth(depth)
+	}
+	return &filterSink{sink: sink, filter: s.filter, subsystem: s.subsystem}
+}
+
+// Verbosity returns the verbosity of the logs the zap level enables, which
+// is -1 when even informational logs are disabled. A nil level enables the
+// default level of zap loggers in development mode or not
+func Verbosity(level zapcore.LevelEnabler, development bool) int {
+	if level == nil {
+		if development {
+			return 1
+		}
+		return 0
+	}
+	for verbosity := MaxVerbosity; verbosity >= 0; verbosity-- {
+		if level.Enabled(zapcore.Level(-verbosity)) {
+			return verbosity
+		}
+	}
+	return -1
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+)
+
+func TestLevels_Set(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Value    string
+		Expected Levels
+		Error    bool
+	}{
+		{
+			Name:     "sets known subsystems",
+			Value:    "dns=4, syncer=0,",
+			Expected: Levels{"dns": 4, "syncer": 0},
+		},
+		{
+			Name:  "rejects unknown subsystem",
+			Value: "unknown=1",
+			Error: true,
+		},
+		{
+			Name:  "rejects missing verbosity",
+			Value: "dns",
+			Error: true,
+		},
+		{
+			Name:  "rejects negative verbosity",
+			Value: "dns=-1",
+			Error: true,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			levels := Levels{}
+			err := levels.Set(testCase.Value)
+			if testCase.Error {
+				if err == nil {
+					t.Fatalf("expected error got '%v'", levels)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+			if levels.String() != testCase.Expected.String() {
+				t.Fatalf("expected '%v' got '%v'", testCase.Expected, levels)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var logged []string
+	base := funcr.New(func(prefix, args string) {
+		logged = append(logged, prefix)
+	}, funcr.Options{Verbosity: MaxVerbosity})
+	filter := NewFilter(1, Levels{"dns": 4})
+	logger := filter.Logger(base)
+
+	logger.V(2).Info("default verbosity")
+	logger.WithName("dns").V(4).Info("dns verbosity")
+	logger.WithName("dns").WithName("aws-route53").V(4).Info("dns verbosity")
+	logger.WithName("dns").WithName("syncer").V(4).Info("syncer verbosity")
+
+	filter.Set(Levels{"syncer": 4})
+	logger.WithName("syncer").V(4).Info("syncer verbosity")
+
+	expected := []string{"dns", "dns/aws-route53", "syncer"}
+	if len(logged) != len(expected) {
+		t.Fatalf("expected '%v' got '%v'", expected, logged)
+	}
+	for i := range expected {
+		if logged[i] != expected[i] {
+			t.Errorf("expected '%v' got '%v'", expected, logged)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package migrate
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// certManagerAnnotationPrefix is the prefix of the annotations cert-manager
+// records on the secrets of the certificates it issues
+const certManagerAnnotationPrefix = "cert-manager.io/"
+
+// Importer plans the resources importing the DNS records and certificates of
+// the Ingresses of a cluster into the controller. The records already
+// published are adopted and the TLS secrets copied, so the hosts keep
+// resolving and serving TLS while the controller takes over. The
+// certificates issued by cert-manager keep their issuer, and the others are
+// imported as they are, without being renewed by the controller
+type Importer struct {
+	// WorkloadClient reads the Ingresses and their TLS secrets
+	WorkloadClient client.Client
+	// Provider lists the zones of the DNS provider and their records
+	Provider dns.Lister
+	// Cluster is the name of the cluster secret of the workload cluster
+	Cluster string
+	// Namespace is the controller namespace the resources are created in
+	Namespace string
+}
+
+// Plan returns the ManagedZones, DNSRecords, TLS secrets and Certificates
+// importing the hosts of the Ingresses. Hosts outside the zones of the
+// provider are skipped, as are Ingresses that can't be adopted
+func (i *Importer) Plan(ctx context.Context) ([]client.Object, error) {
+	zones, err := i.Provider.Zones()
+	if err != nil {
+		return nil, err
+	}
+	ingresses := &networkingv1.IngressList{}
+	if err := i.WorkloadClient.List(ctx, ingresses); err != nil {
+		return nil, err
+	}
+	sort.Slice(ingresses.Items, func(a, b int) bool {
+		return ingresses.Items[a].Namespace+"/"+ingresses.Items[a].Name < ingresses.Items[b].Namespace+"/"+ingresses.Items[b].Name
+	})
+
+	managedZones := map[string]*v1.ManagedZone{}
+	var records, secrets, certificates []client.Object
+	adopted := map[string]struct{}{}
+	for n := range ingresses.Items {
+		ingress := &ingresses.Items[n]
+		t := traffic.NewIngressForCluster(ingress, i.Cluster)
+		if metadata.IsPaused(t) || traffic.DNSDisabled(t) {
+			log.FromContext(ctx).Info("skipping ingress excluded from traffic management", "ingress", t.GetCacheKey())
+			continue
+		}
+		if !uniformRules(ingress) {
+			log.FromContext(ctx).Info("skipping ingress with different rules for each host, adopting would copy the rules between hosts", "ingress", t.GetCacheKey())
+			continue
+		}
+		for _, host := range t.GetHosts() {
+			if _, ok := adopted[host]; ok || host == "" || strings.HasPrefix(host, "*.") {
+				continue
+			}
+			domain, zone, ok := ZoneForHost(host, zones)
+			if !ok {
+				log.FromContext(ctx).Info("skipping host outside the zones of the provider", "ingress", t.GetCacheKey(), "host", host)
+				continue
+			}
+			managedZone, ok := managedZones[domain]
+			if !ok {
+				managedZone = i.managedZone(domain, zone)
+				managedZones[domain] = managedZone
+			}
+			published, err := i.Provider.Records(zone, host)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, dns.AdoptedRecord(host, i.Namespace, managedZone, published, i.Cluster, t))
+			adopted[host] = struct{}{}
+
+			secret, certificate, err := i.tlsSecret(ctx, ingress, host)
+			if err != nil {
+				return nil, err
+			}
+			if secret == nil {
+				continue
+			}
+			secrets = append(secrets, secret)
+			if certificate != nil {
+				certificates = append(certificates, certificate)
+			}
+		}
+	}
+
+	objects := []client.Object{}
+	domains := make([]string, 0, len(managedZones))
+	for domain := range managedZones {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		objects = append(objects, managedZones[domain])
+	}
+	objects = append(objects, records...)
+	objects = append(objects, secrets...)
+	return append(objects, certificates...), nil
+}
+
+func (i *Importer) managedZone(domain string, zone v1.DNSZone) *v1.ManagedZone {
+	return &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      domain,
+			Namespace: i.Namespace,
+		},
+		Spec: v1.ManagedZoneSpec{
+			ID:          zone.ID,
+			DomainName:  domain,
+			Description: "imported from the DNS provider",
+		},
+	}
+}
+
+// tlsSecret returns a copy of the TLS secret of the Ingress for the host,
+// named after the host as the controller expects, or nil when the host has
+// no TLS secret. When the secret is issued by a cert-manager Certificate, a
+// copy of the Certificate is returned too, with the same issuer and
+// specification, and the secret keeps the annotations cert-manager recorded
+// on it, so cert-manager doesn't issue the certificate again. Otherwise the
+// secret is marked as imported, so the controller doesn't issue a
+// certificate replacing it
+func (i *Importer) tlsSecret(ctx context.Context, ingress *networkingv1.Ingress, host string) (*corev1.Secret, *certman.Certificate, error) {
+	for _, t := range ingress.Spec.TLS {
+		if t.SecretName == "" || !slice.ContainsString(t.Hosts, host) {
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := i.WorkloadClient.Get(ctx, client.ObjectKey{Namespace: ingress.Namespace, Name: t.SecretName}, secret); err != nil {
+			if k8serrors.IsNotFound(err) {
+				log.FromContext(ctx).Info("skipping missing TLS secret", "ingress", ingress.Namespace+"/"+ingress.Name, "secret", t.SecretName)
+				continue
+			}
+			return nil, nil, err
+		}
+		imported := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        tls.CertificateName(host),
+				Namespace:   i.Namespace,
+				Annotations: map[string]string{},
+			},
+			Type: secret.Type,
+			Data: secret.Data,
+		}
+		for key, value := range secret.Annotations {
+			if strings.HasPrefix(key, certManagerAnnotationPrefix) {
+				imported.Annotations[key] = value
+			}
+		}
+		source, err := i.certificate(ctx, ingress.Namespace, t.SecretName)
+		if err != nil {
+			return nil, nil, err
+		}
+		if source == nil {
+			log.FromContext(ctx).Info("importing certificate not issued by cert-manager, it won't be renewed by the controller", "ingress", ingress.Namespace+"/"+ingress.Name, "secret", t.SecretName)
+			imported.Annotations[tls.AnnotationImportedCertificate] = "true"
+			return imported, nil, nil
+		}
+		if source.Spec.IssuerRef.Kind != "" && source.Spec.IssuerRef.Kind != "ClusterIssuer" {
+			log.FromContext(ctx).Info("certificate issued by a namespaced issuer, it must be created in the controller namespace", "certificate", source.Namespace+"/"+source.Name, "issuer", source.Spec.IssuerRef.Name)
+		}
+		certificate := &certman.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      tls.CertificateName(host),
+				Namespace: i.Namespace,
+			},
+			Spec: *source.Spec.DeepCopy(),
+		}
+		certificate.Spec.SecretName = imported.Name
+		return imported, certificate, nil
+	}
+	return nil, nil, nil
+}
+
+// certificate returns the cert-manager Certificate of the namespace issuing
+// the secret, or nil when there is none or cert-manager isn't installed
+func (i *Importer) certificate(ctx context.Context, namespace, secretName string) (*certman.Certificate, error) {
+	certificates := &certman.CertificateList{}
+	if err := i.WorkloadClient.List(ctx, certificates, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for n := range certificates.Items {
+		if certificates.Items[n].Spec.SecretName == secretName {
+			return &certificates.Items[n], nil
+		}
+	}
+	return nil, nil
+}
+
+// Apply creates the planned resources in the control plane. Existing
+// resources are left untouched. The Certificates are owned by the DNSRecord
+// of their host, as the Certificates created by the controller are
+func Apply(ctx context.Context, c client.Client, scheme *runtime.Scheme, objects []client.Object) error {
+	for _, obj := range objects {
+		if cert, ok := obj.(*certman.Certificate); ok {
+			record := &v1.DNSRecord{}
+			if err := c.Get(ctx, client.ObjectKey{Namespace: cert.Namespace, Name: cert.Name}, record); err != nil {
+				return err
+			}
+			if err := controllerutil.SetOwnerReference(record, cert, scheme); err != nil {
+				return err
+			}
+		}
+		if err := c.Create(ctx, obj); err != nil {
+			if k8serrors.IsAlreadyExists(err) {
+				log.FromContext(ctx).Info("resource already exists, skipping", "name", obj.GetName(), "namespace", obj.GetNamespace())
+				continue
+			}
+			return err
+		}
+		log.FromContext(ctx).Info("resource created", "name", obj.GetName(), "namespace", obj.GetNamespace())
+	}
+	return nil
+}
+
+// ZoneForHost returns the domain and zone the host belongs to, which is the
+// zone with the longest domain the host is a subdomain of
+func ZoneForHost(host string, zones map[string]v1.DNSZone) (string, v1.DNSZone, bool) {
+	host = strings.ToLower(host)
+	found := ""
+	for domain := range zones {
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > len(found) {
+			found = domain
+		}
+	}
+	if found == "" {
+		return "", v1.DNSZone{}, false
+	}
+	return found, zones[found], true
+}
+
+// uniformRules returns true when every rule of the Ingress routes the same
+// paths, so the rules of any host can be copied to the managed hosts
+func uniformRules(ingress *networkingv1.Ingress) bool {
+	for _, rule := range ingress.Spec.Rules {
+		if !equality.Semantic.DeepEqual(rule.HTTP, ingress.Spec.Rules[0].HTTP) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+)
+
+func TestZoneForHost(t *testing.T) {
+	zones := map[string]v1.DNSZone{
+		"example.com":     {ID: "Z1"},
+		"dev.example.com": {ID: "Z2"},
+	}
+	tests := []struct {
+		name         string
+		host         string
+		expectDomain string
+		expectFound  bool
+	}{
+		{
+			name:         "host in zone",
+			host:         "app.example.com",
+			expectDomain: "example.com",
+			expectFound:  true,
+		},
+		{
+			name:         "host in delegated subzone",
+			host:         "App.Dev.example.com",
+			expectDomain: "dev.example.com",
+			expectFound:  true,
+		},
+		{
+			name:        "host sharing a suffix with a zone",
+			host:        "app.notexample.com",
+			expectFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, zone, found := ZoneForHost(tt.host, zones)
+			if found != tt.expectFound || domain != tt.expectDomain {
+				t.Errorf("expected '%v' '%v' got '%v' '%v'", tt.expectDomain, tt.expectFound, domain, found)
+			}
+			if found && zone.ID != zones[domain].ID {
+				t.Errorf("expected zone '%v' got '%v'", zones[domain], zone)
+			}
+		})
+	}
+}
+
+func TestImporter_tlsSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(certman.AddToScheme(scheme))
+	workloadClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "issued", Namespace: "app", Annotations: map[string]string{"cert-manager.io/issuer-name": "letsencrypt"}},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{"tls.crt": []byte("issued")},
+		},
+		&certman.Certificate{
+			ObjectMeta: metav1.ObjectMeta{Name: "issued", Namespace: "app"},
+			Spec: certman.CertificateSpec{
+				SecretName: "issued",
+				DNSNames:   []string{"issued.example.com"},
+				IssuerRef:  cmmeta.ObjectReference{Kind: "ClusterIssuer", Name: "letsencrypt"},
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bought", Namespace: "app"},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{"tls.crt": []byte("bought")},
+		},
+	).Build()
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "app"},
+		Spec: networkingv1.IngressSpec{TLS: []networkingv1.IngressTLS{
+			{Hosts: []string{"issued.example.com"}, SecretName: "issued"},
+			{Hosts: []string{"bought.example.com"}, SecretName: "bought"},
+		}},
+	}
+	importer := &Importer{WorkloadClient: workloadClient, Namespace: "argocd"}
+
+	tests := []struct {
+		name           string
+		host           string
+		expectData     string
+		expectIssuer   string
+		expectImported bool
+	}{
+		{
+			name:         "certificate issued by cert-manager",
+			host:         "issued.example.com",
+			expectData:   "issued",
+			expectIssuer: "letsencrypt",
+		},
+		{
+			name:           "certificate not issued by cert-manager",
+			host:           "bought.example.com",
+			expectData:     "bought",
+			expectImported: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, certificate, err := importer.tlsSecret(context.Background(), ingress, tt.host)
+			if err != nil {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+			if secret.Name != tt.host || string(secret.Data["tls.crt"]) != tt.expectData {
+				t.Errorf("expected '%v' got '%v'", tt.expectData, secret)
+			}
+			if imported := tls.Imported(secret); imported != tt.expectImported {
+				t.Errorf("expected '%v' got '%v'", tt.expectImported, imported)
+			}
+			issuer := ""
+			if certificate != nil {
+				issuer = certificate.Spec.IssuerRef.Name
+				if certificate.Spec.SecretName != secret.Name {
+					t.Errorf("expected '%v' got '%v'", secret.Name, certificate.Spec.SecretName)
+				}
+				if secret.Annotations["cert-manager.io/issuer-name"] != issuer {
+					t.Errorf("expected '%v' got '%v'", issuer, secret.Annotations)
+				}
+			}
+			if issuer != tt.expectIssuer {
+				t.Errorf("expected '%v' got '%v'", tt.expectIssuer, issuer)
+			}
+		})
+	}
+}
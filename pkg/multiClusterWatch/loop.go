@@ -0,0 +1,145 @@
+package multiClusterWatch
+
+import (
+	"sync"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+const (
+	// loopWindow is the window the reverts of the writes to an ingress are
+	// counted in
+	loopWindow = 2 * time.Minute
+	// loopThreshold is the number of reverts within the window detected as
+	// a reconcile loop with another controller
+	loopThreshold = 3
+	// loopMinBackoff and loopMaxBackoff bound the pause of the writes to an
+	// ingress in a reconcile loop, doubled on each loop detected
+	loopMinBackoff = time.Minute
+	loopMaxBackoff = 30 * time.Minute
+)
+
+// loopDetector detects the reconcile loops between the controller and
+// another controller mutating the same ingresses: a write of the controller
+// reverted by another actor, then written again, is a revert. Once an
+// ingress is reverted loopThreshold times within loopWindow the writes to it
+// are paused, backing off while the loop persists
+type loopDetector struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	history map[string]*writeHistory
+}
+
+type writeHistory struct {
+	written     *networkingv1.Ingress
+	reverts     []time.Time
+	pausedUntil time.Time
+	backoff     time.Duration
+}
+
+func newLoopDetector() *loopDetector {
+	return &loopDetector{now: time.Now, history: map[string]*writeHistory{}}
+}
+
+// paused returns the time left before the writes to the ingress resume
+func (d *loopDetector) paused(key string) (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, ok := d.history[key]
+	if !ok {
+		return 0, false
+	}
+	left := h.pausedUntil.Sub(d.now())
+	return left, left > 0
+}
+
+// observe is called before writing the target state of the ingress over its
+// current state. It returns the pause of the writes to the ingress when
+// writing would be the revert completing a reconcile loop
+func (d *loopDetector) observe(key string, current, target *networkingv1.Ingress) (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, ok := d.history[key]
+	if !ok || h.written == nil {
+		return 0, false
+	}
+	now := d.now()
+	if managedEqual(current, h.written) || !managedEqual(target, h.written) {
+		// the last write stuck or the target changed since, no loop
+		h.reverts = nil
+		if now.Sub(h.pausedUntil) > loopMaxBackoff {
+			h.backoff = 0
+		}
+		return 0, false
+	}
+
+	reverts := []time.Time{}
+	for _, t := range h.reverts {
+		if now.Sub(t) < loopWindow {
+			reverts = append(reverts, t)
+		}
+	}
+	h.reverts = append(reverts, now)
+	if len(h.reverts) < loopThreshold {
+		return 0, false
+	}
+
+	h.backoff *= 2
+	if h.backoff < loopMinBackoff {
+		h.backoff = loopMinBackoff
+	}
+	if h.backoff > loopMaxBackoff {
+		h.backoff = loopMaxBackoff
+	}
+	h.reverts = nil
+	h.pausedUntil = now.Add(h.backoff)
+	return h.backoff, true
+}
+
+// written records the state written to the ingress
+func (d *loopDetector) written(key string, ingress *networkingv1.Ingress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h, ok := d.history[key]
+	if !ok {
+		h = &writeHistory{}
+		d.history[key] = h
+	}
+	h.written = ingress
+}
+
+// forget drops the history of a deleted ingress
+func (d *loopDetector) forget(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.history, key)
+}
+
+// managedEqual returns true when the parts of the ingresses the controller
+// writes are equal
+func managedEqual(a, b *networkingv1.Ingress) bool {
+	return equality.Semantic.DeepEqual(a.Labels, b.Labels) &&
+		equality.Semantic.DeepEqual(a.Annotations, b.Annotations) &&
+		equality.Semantic.DeepEqual(a.Spec, b.Spec)
+}
+
+// conflictingManager returns the field manager that updated the ingress
+// last, other than the controller
+func conflictingManager(ingress *networkingv1.Ingress) string {
+	manager := "unknown"
+	var last time.Time
+	for _, entry := range ingress.ManagedFields {
+		if entry.Manager == traffic.FieldManager || entry.Time == nil {
+			continue
+		}
+		if entry.Time.Time.After(last) {
+			manager = entry.Manager
+			last = entry.Time.Time
+		}
+	}
+	return manager
+}
@@ -0,0 +1,110 @@
+package multiClusterWatch
+
+import (
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func ingressWithHost(host string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: host}},
+		},
+	}
+}
+
+func TestLoopDetector(t *testing.T) {
+	ours := ingressWithHost("test.hcpapps.net")
+	theirs := ingressWithHost("test.example.com")
+	other := ingressWithHost("other.hcpapps.net")
+
+	tests := []struct {
+		name string
+		// writes are the current state of the ingress before each write of
+		// the target state
+		writes      []*networkingv1.Ingress
+		target      *networkingv1.Ingress
+		interval    time.Duration
+		expectLoop  bool
+		expectPause time.Duration
+	}{
+		{
+			name:     "writes stick",
+			writes:   []*networkingv1.Ingress{theirs, ours, ours, ours, ours},
+			target:   ours,
+			interval: time.Second,
+		},
+		{
+			name:        "writes reverted",
+			writes:      []*networkingv1.Ingress{theirs, theirs, theirs, theirs},
+			target:      ours,
+			interval:    time.Second,
+			expectLoop:  true,
+			expectPause: loopMinBackoff,
+		},
+		{
+			name:     "writes reverted slowly",
+			writes:   []*networkingv1.Ingress{theirs, theirs, theirs, theirs},
+			target:   ours,
+			interval: loopWindow,
+		},
+		{
+			name:     "target changing",
+			writes:   []*networkingv1.Ingress{theirs, theirs, theirs, theirs},
+			target:   nil,
+			interval: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			detector := newLoopDetector()
+			detector.now = func() time.Time { return now }
+			loop := false
+			var pause time.Duration
+			for i, current := range tt.writes {
+				target := tt.target
+				if target == nil {
+					target = []*networkingv1.Ingress{ours, other}[i%2]
+				}
+				if pause, loop = detector.observe("test/ingress", current, target); loop {
+					break
+				}
+				detector.written("test/ingress", target)
+				now = now.Add(tt.interval)
+			}
+			if loop != tt.expectLoop {
+				t.Errorf("expected '%v' got '%v'", tt.expectLoop, loop)
+			}
+			if pause != tt.expectPause {
+				t.Errorf("expected '%v' got '%v'", tt.expectPause, pause)
+			}
+			if _, paused := detector.paused("test/ingress"); paused != tt.expectLoop {
+				t.Errorf("expected '%v' got '%v'", tt.expectLoop, paused)
+			}
+		})
+	}
+}
+
+func TestConflictingManager(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Minute))
+	later := metav1.NewTime(time.Now())
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "other-controller", Time: &earlier},
+				{Manager: traffic.FieldManager, Time: &later},
+				{Manager: "gateway-controller", Time: &later},
+			},
+		},
+	}
+	if got := conflictingManager(ingress); got != "gateway-controller" {
+		t.Errorf("expected '%v' got '%v'", "gateway-controller", got)
+	}
+}
@@ -5,26 +5,35 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	runtimeUtil "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
 	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
-	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
-	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/export"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/features"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/policy"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
 )
 
@@ -32,33 +41,45 @@ const (
 	RESYNC_PERIOD = 30 * time.Minute
 )
 
-type ResourceHandlerFactory func(c *rest.Config, controlClient client.Client) (ResourceHandler, error)
+type ResourceHandlerFactory func(cluster string, c *rest.Config, controlClient client.Client) (ResourceHandler, error)
 
 type ResourceHandler interface {
 	Handle(context.Context, runtime.Object) (ctrl.Result, error)
 }
 
-func NewTrafficHandlerFactory(dnsService *dns.Service, tlsService *tls.Service) ResourceHandlerFactory {
-	return func(config *rest.Config, controlClient client.Client) (ResourceHandler, error) {
+// NewTrafficHandlerFactory returns the factory of the traffic controllers of
+// the workload clusters. When the exporter is set, the objects the traffic
+// controllers would apply to the workload clusters are exported instead
+func NewTrafficHandlerFactory(dnsService trafficController.HostService, tlsService trafficController.CertificateService, store *config.Store, policies policy.Evaluator, companions trafficController.CompanionRenderer, exporter *export.Writer) ResourceHandlerFactory {
+	return func(cluster string, config *rest.Config, controlClient client.Client) (ResourceHandler, error) {
 		c, err := client.New(config, client.Options{})
 		if err != nil {
 			return nil, err
 		}
+		if exporter != nil {
+			c = exporter.ForCluster(cluster).Client(c)
+		}
 		trafficHandler := &trafficController.Reconciler{
 			WorkloadClient: c,
 			Hosts:          dnsService,
 			Certificates:   tlsService,
+			Config:         store,
+			Policies:       policies,
+			Companions:     companions,
+			Cluster:        cluster,
 		}
 		return trafficHandler, nil
 	}
 }
 
 type Interface interface {
-	WatchCluster(config *rest.Config) (Watcher, error)
+	WatchCluster(name string, config *rest.Config) (Watcher, error)
 }
 
 type Watcher interface {
 	Start(context.Context) error
+	// Resync enqueues every traffic object of the cluster
+	Resync()
 }
 
 type WatchController struct {
@@ -66,6 +87,12 @@ type WatchController struct {
 	InformerContext context.Context
 	Manager         manager.Manager
 	HandlerFactory  ResourceHandlerFactory
+	// Exporter is optional. When set, the traffic objects are exported
+	// instead of written back to the workload clusters
+	Exporter *export.Writer
+	// Config is optional. It enables logging the changes written back to
+	// the traffic objects with the ReconcileDiff feature gate
+	Config *config.Store
 }
 
 type ClusterWatcher struct {
@@ -74,9 +101,14 @@ type ClusterWatcher struct {
 	Handler     ResourceHandler
 	Queue       workqueue.RateLimitingInterface
 	indexer     cache.Indexer
+	exporter    *export.Writer
+	config      *config.Store
+	loops       *loopDetector
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
 }
 
-func (w *WatchController) WatchCluster(config *rest.Config) (Watcher, error) {
+func (w *WatchController) WatchCluster(name string, config *rest.Config) (Watcher, error) {
 	if w.watchers == nil {
 		w.watchers = map[string]Watcher{}
 	}
@@ -85,7 +117,7 @@ func (w *WatchController) WatchCluster(config *rest.Config) (Watcher, error) {
 		return w.watchers[config.Host], nil
 	}
 
-	watcher, err := NewClusterWatcher(w.Manager, config, w.HandlerFactory)
+	watcher, err := NewClusterWatcher(w.Manager, name, config, w.HandlerFactory, w.Exporter, w.Config)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +153,8 @@ func (w *ClusterWatcher) WatchIngress(sharedInformer informers.SharedInformerFac
 func (w *ClusterWatcher) Start(ctx context.Context) error {
 	defer runtimeUtil.HandleCrash()
 	defer w.Queue.ShutDown()
+	w.broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: w.client.CoreV1().Events("")})
+	defer w.broadcaster.Shutdown()
 	informerFactory := informers.NewSharedInformerFactory(w.client, RESYNC_PERIOD)
 
 	if err := w.WatchIngress(informerFactory); err != nil {
@@ -129,10 +163,10 @@ func (w *ClusterWatcher) Start(ctx context.Context) error {
 	informerFactory.Start(ctx.Done())
 	informerFactory.WaitForCacheSync(ctx.Done())
 
-	log.Log.Info("started watcher events", "cluster watcher", w.ClusterName)
+	log.FromContext(ctx).Info("started watcher events", "cluster watcher", w.ClusterName)
 	go wait.UntilWithContext(ctx, w.startWorker, time.Second)
 	<-ctx.Done()
-	log.Log.Info("closing watch", "cluster", w.ClusterName)
+	log.FromContext(ctx).Info("closing watch", "cluster", w.ClusterName)
 	return nil
 }
 
@@ -150,6 +184,15 @@ func (w *ClusterWatcher) Enqueue(obj interface{}) {
 	w.Queue.Add(key)
 }
 
+func (w *ClusterWatcher) Resync() {
+	if w.indexer == nil {
+		return
+	}
+	for _, key := range w.indexer.ListKeys() {
+		w.Queue.Add(key)
+	}
+}
+
 func (w *ClusterWatcher) EnqueueAfter(obj interface{}, dur time.Duration) {
 	key, err := cache.MetaNamespaceKeyFunc(obj)
 	if err != nil {
@@ -170,29 +213,75 @@ func (w *ClusterWatcher) process(ctx context.Context, key string) error {
 
 	if !exists {
 		// The Ingress has been deleted, so we remove any Ingress to Service tracking.
+		w.loops.forget(key)
 		return nil
 	}
 
 	currentState := object.(*networkingv1.Ingress)
 	targetState := currentState.DeepCopy()
-	targetStateReadWriter := traffic.NewIngress(targetState)
+	targetStateReadWriter := traffic.NewIngressForCluster(targetState, w.ClusterName)
 	res, err := w.Handler.Handle(ctx, targetStateReadWriter)
 	if err != nil {
 		return err
 	}
 	if !equality.Semantic.DeepEqual(currentState, targetState) {
+		if w.config != nil && w.config.Get().Enabled(features.ReconcileDiff) {
+			log.FromContext(ctx).Info("writing back changes to ingress", changes(currentState, targetState)...)
+		}
+		if paused, ok := w.loops.paused(key); ok {
+			log.FromContext(ctx).Info("writes to ingress paused by a reconcile loop", "resume", paused)
+			w.EnqueueAfter(currentState, paused)
+			return nil
+		}
+		if backoff, loop := w.loops.observe(key, currentState, targetState); loop {
+			manager := conflictingManager(currentState)
+			log.FromContext(ctx).Info("pausing writes to ingress reverted by another controller", "fieldManager", manager, "backoff", backoff)
+			w.recorder.Eventf(currentState, corev1.EventTypeWarning, "ReconcileLoop", "Changes to the ingress are reverted by %s, pausing writes for %s", manager, backoff)
+			w.EnqueueAfter(currentState, backoff)
+			return nil
+		}
 		//write back to cluster
-		if _, err := w.client.NetworkingV1().Ingresses(targetState.Namespace).Update(ctx, targetState, metav1.UpdateOptions{}); err != nil {
+		if err := w.writeBack(ctx, targetState); err != nil {
 			return err
 		}
+		if w.exporter == nil {
+			w.loops.written(key, targetState)
+		}
 	}
 	if res.Requeue {
-		log.Log.V(10).Info("requeuing object after ", "duration", res.RequeueAfter)
+		log.FromContext(ctx).V(10).Info("requeuing object after ", "duration", res.RequeueAfter)
 		w.EnqueueAfter(currentState, res.RequeueAfter)
 	}
 	return nil
 }
 
+// writeBack updates the ingress in the cluster, or exports it when the
+// watcher has an exporter
+func (w *ClusterWatcher) writeBack(ctx context.Context, ingress *networkingv1.Ingress) error {
+	if w.exporter != nil {
+		return w.exporter.Write(ingress)
+	}
+	_, err := w.client.NetworkingV1().Ingresses(ingress.Namespace).Update(ctx, ingress, metav1.UpdateOptions{FieldManager: traffic.FieldManager})
+	return err
+}
+
+// changes returns the differences between the current and target state of
+// the ingress as key/value pairs, one for each part of the ingress changed
+func changes(current, target *networkingv1.Ingress) []interface{} {
+	var keysAndValues []interface{}
+	add := func(key string, x, y interface{}) {
+		if diff := cmp.Diff(x, y); diff != "" {
+			keysAndValues = append(keysAndValues, key, diff)
+		}
+	}
+	add("labels", current.Labels, target.Labels)
+	add("annotations", current.Annotations, target.Annotations)
+	add("finalizers", current.Finalizers, target.Finalizers)
+	add("spec", current.Spec, target.Spec)
+	add("status", current.Status, target.Status)
+	return keysAndValues
+}
+
 func (w *ClusterWatcher) processNextWorkItem(ctx context.Context) bool {
 	// Wait until there is a new item in the working queue
 	k, quit := w.Queue.Get()
@@ -205,6 +294,9 @@ func (w *ClusterWatcher) processNextWorkItem(ctx context.Context) bool {
 	// to unblock other workers.
 	defer w.Queue.Done(key)
 
+	// correlate the logs of processing the key with a reconcileID, as
+	// controllers do
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithName(string(logging.Syncer)).WithValues("cluster", w.ClusterName, "ingress", key, "reconcileID", uuid.NewUUID()))
 	err := w.process(ctx, key)
 
 	// Reconcile worked, nothing else to do for this work-queue item
@@ -215,7 +307,7 @@ func (w *ClusterWatcher) processNextWorkItem(ctx context.Context) bool {
 	// Re-enqueue up to 5 times
 	n := w.Queue.NumRequeues(key)
 	if n < 5 {
-		log.Log.Error(err, "Re-queuing after reconciliation error", "key", key, "retries", n)
+		log.FromContext(ctx).Error(err, "Re-queuing after reconciliation error", "key", key, "retries", n)
 		w.Queue.AddRateLimited(key)
 		return true
 	}
@@ -223,13 +315,13 @@ func (w *ClusterWatcher) processNextWorkItem(ctx context.Context) bool {
 	// Give up and report error elsewhere.
 	w.Queue.Forget(key)
 	runtimeUtil.HandleError(err)
-	log.Log.Error(err, "Dropping key after max failed retries", "key", key, "retries", n)
+	log.FromContext(ctx).Error(err, "Dropping key after max failed retries", "key", key, "retries", n)
 
 	return true
 }
 
-func NewClusterWatcher(mgr manager.Manager, config *rest.Config, handlerFactory ResourceHandlerFactory) (Watcher, error) {
-	controllerName := fmt.Sprintf("%s/%s", config.ServerName, "ingress")
+func NewClusterWatcher(mgr manager.Manager, name string, config *rest.Config, handlerFactory ResourceHandlerFactory, exporter *export.Writer, store *config.Store) (Watcher, error) {
+	controllerName := fmt.Sprintf("%s/%s", name, "ingress")
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
 	log.Log.Info("creating new cluster watcher", "host", config.Host)
 	watcherClient, err := kubernetes.NewForConfig(config)
@@ -237,11 +329,24 @@ func NewClusterWatcher(mgr manager.Manager, config *rest.Config, handlerFactory
 		return nil, err
 	}
 
-	handler, err := handlerFactory(config, mgr.GetClient())
+	handler, err := handlerFactory(name, config, mgr.GetClient())
 	if err != nil {
 		return nil, err
 	}
-	watcher := &ClusterWatcher{client: watcherClient, ClusterName: config.Host, Handler: handler, Queue: queue}
+	broadcaster := record.NewBroadcaster()
+	watcher := &ClusterWatcher{
+		client:      watcherClient,
+		ClusterName: name,
+		Handler:     handler,
+		Queue:       queue,
+		config:      store,
+		loops:       newLoopDetector(),
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "multi-cluster-traffic-controller"}),
+	}
+	if exporter != nil {
+		watcher.exporter = exporter.ForCluster(name)
+	}
 	err = mgr.Add(watcher)
 	if err != nil {
 		log.Log.Error(err, "error Adding cluster watcher the Manager")
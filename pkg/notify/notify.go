@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// Event is a significant transition of the traffic state notified
+type Event string
+
+const (
+	// HostUnserved is notified when no healthy cluster serves a managed host
+	// any more
+	HostUnserved Event = "HostUnserved"
+	// ClusterEvacuated is notified once the traffic of a cluster is
+	// evacuated
+	ClusterEvacuated Event = "ClusterEvacuated"
+	// CertificateFailed is notified when the issuance of the certificate of
+	// a managed host fails
+	CertificateFailed Event = "CertificateFailed"
+	// DNSDrift is notified when the records of a zone drift from its
+	// DNSRecords, as reported by its ZoneReport
+	DNSDrift Event = "DNSDrift"
+)
+
+var events = []Event{HostUnserved, ClusterEvacuated, CertificateFailed, DNSDrift}
+
+const (
+	// EndpointWebhook endpoints are posted the notification as JSON
+	EndpointWebhook = "webhook"
+	// EndpointSlack endpoints are Slack incoming webhooks, posted the
+	// message of the notification
+	EndpointSlack = "slack"
+)
+
+// queueSize is the number of notifications waiting to be sent before new
+// ones are dropped, so a slow endpoint doesn't block the informers
+const queueSize = 100
+
+// Notification is sent to the endpoints subscribed to its event
+type Notification struct {
+	Event Event `json:"event"`
+	// Object is the kind/namespace/name of the object that transitioned
+	Object  string    `json:"object"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Endpoint is a webhook notifications are posted to
+type Endpoint struct {
+	URL string `json:"url"`
+	// Type is EndpointWebhook (default) or EndpointSlack
+	Type string `json:"type,omitempty"`
+	// Events are the events notified to the endpoint. Every event when
+	// empty
+	Events []Event `json:"events,omitempty"`
+}
+
+func (e Endpoint) subscribed(event Event) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range e.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadEndpoints reads the YAML list of endpoints of the file
+func LoadEndpoints(path string) ([]Endpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []Endpoint{}
+	if err := yaml.UnmarshalStrict(data, &endpoints); err != nil {
+		return nil, fmt.Errorf("invalid notification endpoints %s: %w", path, err)
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.URL == "" {
+			return nil, fmt.Errorf("invalid notification endpoints %s: url is required", path)
+		}
+		switch endpoint.Type {
+		case "", EndpointWebhook, EndpointSlack:
+		default:
+			return nil, fmt.Errorf("invalid notification endpoints %s: unknown type %s", path, endpoint.Type)
+		}
+		for _, event := range endpoint.Events {
+			if !known(event) {
+				return nil, fmt.Errorf("invalid notification endpoints %s: unknown event %s, events are %v", path, event, events)
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+func known(event Event) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier notifies the endpoints of the transitions of the ManagedHosts,
+// ClusterEvacuations and ZoneReports, complementing the alerts on the
+// metrics of the controller. Notifications are sent in order, and dropped
+// when an endpoint fails, as the transitions are also reported in the
+// status of the objects
+type Notifier struct {
+	Cache      cache.Informers
+	Endpoints  []Endpoint
+	HTTPClient *http.Client
+
+	queue chan Notification
+}
+
+func (n *Notifier) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info("Starting notifier", "endpoints", len(n.Endpoints))
+	n.queue = make(chan Notification, queueSize)
+	if n.HTTPClient == nil {
+		n.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	for _, obj := range []client.Object{&v1.ManagedHost{}, &v1.ClusterEvacuation{}, &v1.ZoneReport{}} {
+		informer, err := n.Cache.GetInformer(ctx, obj)
+		if err != nil {
+			return err
+		}
+		if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				old, ok := oldObj.(client.Object)
+				if !ok {
+					return
+				}
+				updated, ok := newObj.(client.Object)
+				if !ok {
+					return
+				}
+				for _, notification := range Transitions(old, updated) {
+					n.enqueue(ctx, notification)
+				}
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-n.queue:
+			n.send(ctx, notification)
+		}
+	}
+}
+
+func (n *Notifier) enqueue(ctx context.Context, notification Notification) {
+	select {
+	case n.queue <- notification:
+	default:
+		log.FromContext(ctx).Info("Dropping notification, too many notifications queued", "event", notification.Event, "object", notification.Object)
+	}
+}
+
+// send posts the notification to the endpoints subscribed to its event
+func (n *Notifier) send(ctx context.Context, notification Notification) {
+	for _, endpoint := range n.Endpoints {
+		if !endpoint.subscribed(notification.Event) {
+			continue
+		}
+		if err := n.post(ctx, endpoint, notification); err != nil {
+			log.FromContext(ctx).Error(err, "failed to send notification", "event", notification.Event, "object", notification.Object)
+		}
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, endpoint Endpoint, notification Notification) error {
+	var payload interface{} = notification
+	if endpoint.Type == EndpointSlack {
+		payload = map[string]string{"text": fmt.Sprintf("*%s* %s: %s", notification.Event, notification.Object, notification.Message)}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.HTTPClient.Do(req)
+	if err != nil {
+		// the URL of the endpoint may hold a secret, such as the URLs of
+		// Slack incoming webhooks
+		if urlErr, ok := err.(*url.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint answered %s", resp.Status)
+	}
+	return nil
+}
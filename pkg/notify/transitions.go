@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// Transitions returns the notifications of the transitions of the object
+// between its old and updated states
+func Transitions(old, updated client.Object) []Notification {
+	now := time.Now()
+	switch updated := updated.(type) {
+	case *v1.ManagedHost:
+		old, ok := old.(*v1.ManagedHost)
+		if !ok {
+			return nil
+		}
+		return hostTransitions(old, updated, now)
+	case *v1.ClusterEvacuation:
+		old, ok := old.(*v1.ClusterEvacuation)
+		if !ok {
+			return nil
+		}
+		if became(old.Status.Conditions, updated.Status.Conditions, v1.ClusterEvacuationEvacuatedConditionType, metav1.ConditionTrue) {
+			return []Notification{{
+				Event:   ClusterEvacuated,
+				Object:  objectName("ClusterEvacuation", updated),
+				Message: fmt.Sprintf("The traffic of cluster %s is evacuated", updated.Spec.Cluster),
+				Time:    now,
+			}}
+		}
+	case *v1.ZoneReport:
+		old, ok := old.(*v1.ZoneReport)
+		if !ok {
+			return nil
+		}
+		if drift(old.Status) == 0 && drift(updated.Status) > 0 {
+			return []Notification{{
+				Event:  DNSDrift,
+				Object: objectName("ZoneReport", updated),
+				Message: fmt.Sprintf("The records of zone %s drifted from its DNSRecords: %d extra, %d missing and %d mismatched records",
+					updated.Spec.ManagedZoneRef.Name, updated.Status.Extra, updated.Status.Missing, updated.Status.Mismatched),
+				Time: now,
+			}}
+		}
+	}
+	return nil
+}
+
+func hostTransitions(old, updated *v1.ManagedHost, now time.Time) []Notification {
+	notifications := []Notification{}
+	if meta.IsStatusConditionTrue(old.Status.Conditions, v1.ManagedHostPlacementSatisfiedConditionType) &&
+		meta.IsStatusConditionFalse(updated.Status.Conditions, v1.ManagedHostPlacementSatisfiedConditionType) {
+		notifications = append(notifications, Notification{
+			Event:   HostUnserved,
+			Object:  objectName("ManagedHost", updated),
+			Message: fmt.Sprintf("No healthy cluster serves host %s: %s", updated.Spec.Host, conditionMessage(updated.Status.Conditions, v1.ManagedHostPlacementSatisfiedConditionType)),
+			Time:    now,
+		})
+	}
+	if updated.Status.IssuanceFailures > old.Status.IssuanceFailures {
+		notifications = append(notifications, Notification{
+			Event:   CertificateFailed,
+			Object:  objectName("ManagedHost", updated),
+			Message: fmt.Sprintf("The certificate of host %s failed to be issued: %s", updated.Spec.Host, conditionMessage(updated.Status.Conditions, v1.ManagedHostCertificateReadyConditionType)),
+			Time:    now,
+		})
+	}
+	return notifications
+}
+
+// became returns true when the condition changed to the status
+func became(old, updated []metav1.Condition, conditionType string, status metav1.ConditionStatus) bool {
+	return !meta.IsStatusConditionPresentAndEqual(old, conditionType, status) &&
+		meta.IsStatusConditionPresentAndEqual(updated, conditionType, status)
+}
+
+func conditionMessage(conditions []metav1.Condition, conditionType string) string {
+	if condition := meta.FindStatusCondition(conditions, conditionType); condition != nil {
+		return condition.Message
+	}
+	return ""
+}
+
+func drift(status v1.ZoneReportStatus) int {
+	return status.Extra + status.Missing + status.Mismatched
+}
+
+func objectName(kind string, obj client.Object) string {
+	return fmt.Sprintf("%s/%s/%s", kind, obj.GetNamespace(), obj.GetName())
+}
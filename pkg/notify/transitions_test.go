@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestTransitions(t *testing.T) {
+	condition := func(conditionType string, status metav1.ConditionStatus) []metav1.Condition {
+		return []metav1.Condition{{Type: conditionType, Status: status}}
+	}
+	tests := []struct {
+		name    string
+		old     client.Object
+		updated client.Object
+		expect  []Event
+	}{
+		{
+			name:    "host unserved",
+			old:     &v1.ManagedHost{Status: v1.ManagedHostStatus{Conditions: condition(v1.ManagedHostPlacementSatisfiedConditionType, metav1.ConditionTrue)}},
+			updated: &v1.ManagedHost{Status: v1.ManagedHostStatus{Conditions: condition(v1.ManagedHostPlacementSatisfiedConditionType, metav1.ConditionFalse)}},
+			expect:  []Event{HostUnserved},
+		},
+		{
+			name:    "host never served",
+			old:     &v1.ManagedHost{},
+			updated: &v1.ManagedHost{Status: v1.ManagedHostStatus{Conditions: condition(v1.ManagedHostPlacementSatisfiedConditionType, metav1.ConditionFalse)}},
+		},
+		{
+			name:    "certificate failed",
+			old:     &v1.ManagedHost{Status: v1.ManagedHostStatus{IssuanceFailures: 1}},
+			updated: &v1.ManagedHost{Status: v1.ManagedHostStatus{IssuanceFailures: 2}},
+			expect:  []Event{CertificateFailed},
+		},
+		{
+			name:    "cluster evacuated",
+			old:     &v1.ClusterEvacuation{Status: v1.ClusterEvacuationStatus{Conditions: condition(v1.ClusterEvacuationEvacuatedConditionType, metav1.ConditionFalse)}},
+			updated: &v1.ClusterEvacuation{Status: v1.ClusterEvacuationStatus{Conditions: condition(v1.ClusterEvacuationEvacuatedConditionType, metav1.ConditionTrue)}},
+			expect:  []Event{ClusterEvacuated},
+		},
+		{
+			name:    "drift detected",
+			old:     &v1.ZoneReport{},
+			updated: &v1.ZoneReport{Status: v1.ZoneReportStatus{Missing: 1}},
+			expect:  []Event{DNSDrift},
+		},
+		{
+			name:    "drift already reported",
+			old:     &v1.ZoneReport{Status: v1.ZoneReportStatus{Missing: 1}},
+			updated: &v1.ZoneReport{Status: v1.ZoneReportStatus{Missing: 1, Extra: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Event
+			for _, notification := range Transitions(tt.old, tt.updated) {
+				got = append(got, notification.Event)
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package placement
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+)
+
+const (
+	// LabelCostTier is set on cluster secrets to declare the cost tier of
+	// the cluster, a non negative integer where lower tiers are cheaper.
+	// Clusters without a valid tier are ranked after every tier
+	LabelCostTier = "kuadrant.io/cost-tier"
+	// LabelRegion is set on cluster secrets to declare the region of the
+	// cluster
+	LabelRegion = "topology.kubernetes.io/region"
+	// LabelZone is set on cluster secrets to declare the availability zone
+	// of the cluster
+	LabelZone = "topology.kubernetes.io/zone"
+)
+
+// Cluster is a candidate cluster of a placement, identified by the name of
+// its cluster secret and described by the labels of the secret
+type Cluster struct {
+	Name   string
+	Labels map[string]string
+}
+
+// Scorer ranks the candidate clusters of a placement. Traffic objects are
+// placed on the first clusters of the ranking, so scorers plug in the
+// strategy deciding where they land
+type Scorer interface {
+	Rank(clusters []Cluster) []Cluster
+}
+
+// Select returns the first count clusters ranked by the scorer, or every
+// cluster when count is 0
+func Select(scorer Scorer, clusters []Cluster, count int) []Cluster {
+	ranked := scorer.Rank(clusters)
+	if count > 0 && count < len(ranked) {
+		ranked = ranked[:count]
+	}
+	return ranked
+}
+
+// Keep returns a scorer ranking the clusters already placed on first, in the
+// order the scorer ranks them, so placements don't move while their
+// clusters remain candidates
+func Keep(scorer Scorer, placed []string) Scorer {
+	return &keepScorer{scorer: scorer, placed: placed}
+}
+
+type keepScorer struct {
+	scorer Scorer
+	placed []string
+}
+
+func (k *keepScorer) Rank(clusters []Cluster) []Cluster {
+	ranked := k.scorer.Rank(clusters)
+	kept := make([]Cluster, 0, len(ranked))
+	others := []Cluster{}
+	for _, cluster := range ranked {
+		if slice.ContainsString(k.placed, cluster.Name) {
+			kept = append(kept, cluster)
+		} else {
+			others = append(others, cluster)
+		}
+	}
+	return append(kept, others...)
+}
+
+// CostZoneScorer ranks the cheapest clusters first, by their cost tier.
+// Clusters of the same tier are spread across regions, and across the
+// availability zones of each region, taking a cluster from each in turn
+type CostZoneScorer struct{}
+
+func (CostZoneScorer) Rank(clusters []Cluster) []Cluster {
+	sorted := append([]Cluster{}, clusters...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if a, b := costTier(sorted[i]), costTier(sorted[j]); a != b {
+			return a < b
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	ranked := make([]Cluster, 0, len(sorted))
+	for start := 0; start < len(sorted); {
+		end := start
+		for end < len(sorted) && costTier(sorted[end]) == costTier(sorted[start]) {
+			end++
+		}
+		ranked = append(ranked, spread(sorted[start:end], LabelRegion, LabelZone)...)
+		start = end
+	}
+	return ranked
+}
+
+// spread orders the clusters so consecutive clusters have different values
+// of the first label while every value has clusters left, and so on for the
+// clusters of each value with the remaining labels
+func spread(clusters []Cluster, labels ...string) []Cluster {
+	if len(labels) == 0 {
+		return clusters
+	}
+	values := []string{}
+	byValue := map[string][]Cluster{}
+	for _, cluster := range clusters {
+		value := cluster.Labels[labels[0]]
+		if _, ok := byValue[value]; !ok {
+			values = append(values, value)
+		}
+		byValue[value] = append(byValue[value], cluster)
+	}
+	for _, value := range values {
+		byValue[value] = spread(byValue[value], labels[1:]...)
+	}
+	ordered := make([]Cluster, 0, len(clusters))
+	for len(ordered) < len(clusters) {
+		for _, value := range values {
+			if len(byValue[value]) == 0 {
+				continue
+			}
+			ordered = append(ordered, byValue[value][0])
+			byValue[value] = byValue[value][1:]
+		}
+	}
+	return ordered
+}
+
+// costTier returns the cost tier of the cluster, ranking clusters without a
+// valid tier last
+func costTier(cluster Cluster) int {
+	tier, err := strconv.Atoi(cluster.Labels[LabelCostTier])
+	if err != nil || tier < 0 {
+		return math.MaxInt
+	}
+	return tier
+}
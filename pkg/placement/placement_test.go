@@ -0,0 +1,73 @@
+package placement
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCostZoneScorer_Rank(t *testing.T) {
+	cluster := func(name, tier, zone string) Cluster {
+		region, _, _ := strings.Cut(zone, "-")
+		labels := map[string]string{LabelRegion: region, LabelZone: zone}
+		if tier != "" {
+			labels[LabelCostTier] = tier
+		}
+		return Cluster{Name: name, Labels: labels}
+	}
+
+	tests := []struct {
+		name     string
+		clusters []Cluster
+		count    int
+		expect   []string
+	}{
+		{
+			name:     "cheapest tier first",
+			clusters: []Cluster{cluster("a", "2", "z1"), cluster("b", "1", "z1"), cluster("c", "", "z1")},
+			count:    2,
+			expect:   []string{"b", "a"},
+		},
+		{
+			name:     "clusters of a tier spread across zones",
+			clusters: []Cluster{cluster("a", "1", "z1"), cluster("b", "1", "z1"), cluster("c", "1", "z2"), cluster("d", "1", "z3")},
+			count:    3,
+			expect:   []string{"a", "c", "d"},
+		},
+		{
+			name:     "clusters of a tier spread across regions first",
+			clusters: []Cluster{cluster("a", "1", "r1-z1"), cluster("b", "1", "r1-z2"), cluster("c", "1", "r2-z1"), cluster("d", "1", "r2-z1")},
+			count:    3,
+			expect:   []string{"a", "c", "b"},
+		},
+		{
+			name:     "every cluster without a count",
+			clusters: []Cluster{cluster("a", "1", "z1"), cluster("b", "2", "z1")},
+			expect:   []string{"a", "b"},
+		},
+		{
+			name:     "cheaper clusters preferred over zone spread",
+			clusters: []Cluster{cluster("a", "1", "z1"), cluster("b", "1", "z1"), cluster("c", "2", "z2")},
+			count:    2,
+			expect:   []string{"a", "b"},
+		},
+		{
+			name:     "fewer candidates than requested",
+			clusters: []Cluster{cluster("a", "1", "z1")},
+			count:    3,
+			expect:   []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, c := range Select(CostZoneScorer{}, tt.clusters, tt.count) {
+				got = append(got, c.Name)
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+// AnnotationViolations is set on traffic objects violating policy rules,
+// with the violations separated by semicolons
+const AnnotationViolations = "kuadrant.io/policy-violations"
+
+// Input is what policies are evaluated against
+type Input struct {
+	Object traffic.Interface
+	// Clusters are the clusters serving each host of the object, the
+	// cluster of the object included. Nil at admission, where the cluster
+	// of the object isn't known
+	Clusters map[string][]string
+}
+
+// Violation is a policy rule a traffic object doesn't meet
+type Violation struct {
+	Policy  string
+	Rule    string
+	Action  v1.PolicyAction
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s/%s: %s", v.Policy, v.Rule, v.Message)
+}
+
+// Evaluator evaluates policies against traffic objects at admission and
+// reconcile time. Policy engines plug in by implementing it
+type Evaluator interface {
+	Evaluate(ctx context.Context, input Input) ([]Violation, error)
+}
+
+// Evaluators evaluates each of the evaluators, returning all violations
+type Evaluators []Evaluator
+
+func (e Evaluators) Evaluate(ctx context.Context, input Input) ([]Violation, error) {
+	violations := []Violation{}
+	for _, evaluator := range e {
+		v, err := evaluator.Evaluate(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, v...)
+	}
+	return violations, nil
+}
+
+// Denied returns the violations of rules denying the traffic object
+func Denied(violations []Violation) []Violation {
+	denied := []Violation{}
+	for _, v := range violations {
+		if v.Action != v1.PolicyActionWarn {
+			denied = append(denied, v)
+		}
+	}
+	return denied
+}
+
+// Introduced returns the violations that aren't violations of the previous
+// version of the traffic object, so objects already admitted aren't denied
+// for violating rules added since
+func Introduced(violations, previous []Violation) []Violation {
+	introduced := []Violation{}
+	for _, v := range violations {
+		found := false
+		for _, p := range previous {
+			if v == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			introduced = append(introduced, v)
+		}
+	}
+	return introduced
+}
+
+// Messages returns the violations as strings
+func Messages(violations []Violation) []string {
+	messages := make([]string, 0, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.String())
+	}
+	return messages
+}
+
+// Flag records the violations on the traffic object, or clears them when
+// there are none
+func Flag(t traffic.Interface, violations []Violation) {
+	if len(violations) == 0 {
+		metadata.RemoveAnnotation(t, AnnotationViolations)
+		return
+	}
+	metadata.AddAnnotation(t, AnnotationViolations, strings.Join(Messages(violations), "; "))
+}
+
+//+kubebuilder:rbac:groups=kuadrant.io,resources=trafficpolicies,verbs=get;list;watch
+
+// RuleEvaluator evaluates the rules of the TrafficPolicies in the controller
+// namespace
+type RuleEvaluator struct {
+	client    client.Client
+	namespace string
+}
+
+func NewRuleEvaluator(c client.Client, namespace string) *RuleEvaluator {
+	return &RuleEvaluator{client: c, namespace: namespace}
+}
+
+func (e *RuleEvaluator) Evaluate(ctx context.Context, input Input) ([]Violation, error) {
+	policies := &v1.TrafficPolicyList{}
+	if err := e.client.List(ctx, policies, client.InNamespace(e.namespace)); err != nil {
+		return nil, err
+	}
+	violations := []Violation{}
+	for _, policy := range policies.Items {
+		for _, rule := range policy.Spec.Rules {
+			for _, message := range EvaluateRule(rule, input) {
+				action := rule.Action
+				if action == "" {
+					action = v1.PolicyActionDeny
+				}
+				violations = append(violations, Violation{Policy: policy.Name, Rule: rule.Name, Action: action, Message: message})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// EvaluateRule returns a message for each constraint of the rule the input
+// doesn't meet
+func EvaluateRule(rule v1.PolicyRule, input Input) []string {
+	t := input.Object
+	if len(rule.Namespaces) > 0 && !slice.ContainsString(rule.Namespaces, t.GetNamespace()) {
+		return nil
+	}
+	hosts := userHosts(t)
+
+	messages := []string{}
+	if len(rule.HostSuffixes) > 0 {
+		for _, host := range hosts {
+			if !inDomains(host, rule.HostSuffixes) {
+				messages = append(messages, fmt.Sprintf("host %s doesn't end with any of %s", host, strings.Join(rule.HostSuffixes, ", ")))
+			}
+		}
+	}
+	if rule.MaxHosts != nil && len(hosts) > *rule.MaxHosts {
+		messages = append(messages, fmt.Sprintf("%d hosts exceed the maximum of %d", len(hosts), *rule.MaxHosts))
+	}
+	if rule.MaxClusters != nil {
+		for _, host := range t.GetHosts() {
+			if clusters := input.Clusters[host]; len(clusters) > *rule.MaxClusters {
+				messages = append(messages, fmt.Sprintf("host %s is served by %d clusters, exceeding the maximum of %d", host, len(clusters), *rule.MaxClusters))
+			}
+		}
+	}
+	return messages
+}
+
+// userHosts returns the hosts of the traffic object that aren't managed
+// hosts
+func userHosts(t traffic.Interface) []string {
+	managed := strings.Split(metadata.GetAnnotation(t, traffic.AnnotationManagedHosts), ",")
+	hosts := []string{}
+	for _, host := range t.GetHosts() {
+		if host != "" && !slice.ContainsString(managed, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// inDomains returns true when the host is within any of the domains,
+// matching whole labels: .corp.example.com matches the subdomains of
+// corp.example.com, corp.example.com also matches the domain itself, and
+// neither matches evilcorp.example.com
+func inDomains(host string, domains []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		if strings.HasSuffix(host, "."+strings.TrimPrefix(domain, ".")) ||
+			(!strings.HasPrefix(domain, ".") && host == domain) {
+			return true
+		}
+	}
+	return false
+}
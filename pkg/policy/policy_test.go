@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func testIngress(namespace string, hosts ...string) traffic.Interface {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   namespace,
+			Annotations: map[string]string{traffic.AnnotationManagedHosts: "abc.mctc.example.com"},
+		},
+	}
+	for _, host := range append(hosts, "abc.mctc.example.com") {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: host})
+	}
+	return traffic.NewIngress(ingress)
+}
+
+func TestEvaluateRule(t *testing.T) {
+	one := 1
+	tests := []struct {
+		name   string
+		rule   v1.PolicyRule
+		input  Input
+		expect []string
+	}{
+		{
+			name:   "host suffixes met, managed hosts ignored",
+			rule:   v1.PolicyRule{HostSuffixes: []string{".corp.example.com"}},
+			input:  Input{Object: testIngress("default", "app.corp.example.com")},
+			expect: []string{},
+		},
+		{
+			name:   "host suffixes violated",
+			rule:   v1.PolicyRule{HostSuffixes: []string{".corp.example.com"}},
+			input:  Input{Object: testIngress("default", "app.example.com")},
+			expect: []string{"host app.example.com doesn't end with any of .corp.example.com"},
+		},
+		{
+			name:   "host suffixes matched on whole labels",
+			rule:   v1.PolicyRule{HostSuffixes: []string{"corp.example.com"}},
+			input:  Input{Object: testIngress("default", "evilcorp.example.com", "corp.example.com", "*.App.Corp.example.com")},
+			expect: []string{"host evilcorp.example.com doesn't end with any of corp.example.com"},
+		},
+		{
+			name:   "host suffixes with a leading dot only matching subdomains",
+			rule:   v1.PolicyRule{HostSuffixes: []string{".corp.example.com"}},
+			input:  Input{Object: testIngress("default", "corp.example.com")},
+			expect: []string{"host corp.example.com doesn't end with any of .corp.example.com"},
+		},
+		{
+			name:   "namespace not selected",
+			rule:   v1.PolicyRule{Namespaces: []string{"prod"}, HostSuffixes: []string{".corp.example.com"}},
+			input:  Input{Object: testIngress("default", "app.example.com")},
+			expect: nil,
+		},
+		{
+			name:   "max hosts exceeded",
+			rule:   v1.PolicyRule{MaxHosts: &one},
+			input:  Input{Object: testIngress("default", "a.example.com", "b.example.com")},
+			expect: []string{"2 hosts exceed the maximum of 1"},
+		},
+		{
+			name:   "max clusters not checked at admission",
+			rule:   v1.PolicyRule{MaxClusters: &one},
+			input:  Input{Object: testIngress("default")},
+			expect: []string{},
+		},
+		{
+			name: "max clusters exceeded",
+			rule: v1.PolicyRule{MaxClusters: &one},
+			input: Input{
+				Object:   testIngress("default"),
+				Clusters: map[string][]string{"abc.mctc.example.com": {"cluster-1", "cluster-2"}},
+			},
+			expect: []string{"host abc.mctc.example.com is served by 2 clusters, exceeding the maximum of 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateRule(tt.rule, tt.input)
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestIntroduced(t *testing.T) {
+	violation := func(message string) Violation {
+		return Violation{Policy: "corp", Rule: "hosts", Action: v1.PolicyActionDeny, Message: message}
+	}
+	tests := []struct {
+		name       string
+		violations []Violation
+		previous   []Violation
+		expect     []Violation
+	}{
+		{
+			name:       "created",
+			violations: []Violation{violation("a")},
+			expect:     []Violation{violation("a")},
+		},
+		{
+			name:       "violation already present",
+			violations: []Violation{violation("a")},
+			previous:   []Violation{violation("a")},
+			expect:     []Violation{},
+		},
+		{
+			name:       "violation introduced",
+			violations: []Violation{violation("a"), violation("b")},
+			previous:   []Violation{violation("a")},
+			expect:     []Violation{violation("b")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Introduced(tt.violations, tt.previous); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var AuditPendingErr = errors.New("permissions audit has not completed")
+
+// Permission is a verb on a resource the controller needs. Mandatory
+// permissions are required for the core functionality, the rest are only
+// needed by optional features
+type Permission struct {
+	Group       string
+	Resource    string
+	Subresource string
+	Verb        string
+	Mandatory   bool
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource = resource + "/" + p.Subresource
+	}
+	if p.Group != "" {
+		resource = resource + "." + p.Group
+	}
+	return fmt.Sprintf("%s %s", p.Verb, resource)
+}
+
+func permissions(group, resource, subresource string, mandatory bool, verbs ...string) []Permission {
+	result := make([]Permission, 0, len(verbs))
+	for _, verb := range verbs {
+		result = append(result, Permission{Group: group, Resource: resource, Subresource: subresource, Verb: verb, Mandatory: mandatory})
+	}
+	return result
+}
+
+// ControllerPermissions are the permissions used by the controller in the
+// control plane cluster
+var ControllerPermissions = concat(
+	permissions("", "secrets", "", true, "get", "list", "watch"),
+	// refreshing scoped cluster tokens
+	permissions("", "secrets", "", false, "update"),
+	// recording the detected egress addresses of clusters
+	permissions("", "secrets", "", false, "patch"),
+	// templates of the companion objects of the ingress classes
+	permissions("", "configmaps", "", false, "get", "list", "watch"),
+	// HTTP-01 challenges
+	permissions("acme.cert-manager.io", "challenges", "", false, "get", "list", "watch", "update"),
+	permissions("acme.cert-manager.io", "challenges", "finalizers", false, "update"),
+	permissions("cert-manager.io", "certificates", "", true, "create", "get"),
+	permissions("kuadrant.io", "clusterevacuations", "", true, "get", "list", "watch"),
+	permissions("kuadrant.io", "clusterevacuations", "status", true, "update"),
+	permissions("kuadrant.io", "controllerconfigs", "", true, "get", "list", "watch"),
+	permissions("kuadrant.io", "controllerconfigs", "status", true, "update"),
+	permissions("kuadrant.io", "dnsrecords", "", true, "get", "list", "watch", "create", "update", "delete"),
+	permissions("kuadrant.io", "dnsrecords", "status", true, "update"),
+	permissions("kuadrant.io", "dnsrecords", "finalizers", true, "update"),
+	permissions("kuadrant.io", "hostclaims", "", true, "get", "list", "watch", "update", "delete"),
+	permissions("kuadrant.io", "hostclaims", "status", true, "update"),
+	permissions("kuadrant.io", "hostclaims", "finalizers", true, "update"),
+	permissions("kuadrant.io", "hostpools", "", true, "get", "list", "watch"),
+	permissions("kuadrant.io", "hostpools", "status", true, "update"),
+	permissions("kuadrant.io", "managedhosts", "", true, "get", "list", "watch", "create"),
+	permissions("kuadrant.io", "managedhosts", "status", true, "update"),
+	permissions("kuadrant.io", "managedzones", "", true, "get", "list", "watch", "update"),
+	permissions("kuadrant.io", "managedzones", "finalizers", true, "update"),
+	permissions("kuadrant.io", "managedzones", "status", true, "update"),
+	permissions("kuadrant.io", "trafficpolicies", "", true, "get", "list", "watch"),
+	permissions("kuadrant.io", "trafficrollouts", "", true, "get", "list", "watch"),
+	permissions("kuadrant.io", "trafficrollouts", "status", true, "update"),
+	permissions("kuadrant.io", "zonereports", "", true, "get", "list", "watch", "create", "update"),
+	permissions("kuadrant.io", "zonereports", "status", true, "update"),
+	permissions("networking.k8s.io", "ingresses", "", true, "get"),
+	// DNS drift events
+	permissions("", "events", "", false, "create", "patch"),
+	// leader election, and the heartbeat watched by the standby control plane
+	permissions("coordination.k8s.io", "leases", "", false, "get", "create", "update"),
+	// handing the zones and records over between control planes
+	permissions("kuadrant.io", "managedzones", "", false, "patch"),
+	permissions("kuadrant.io", "dnsrecords", "", false, "patch"),
+)
+
+// WorkloadPermissions are the permissions used by the controller in each
+// workload cluster
+var WorkloadPermissions = concat(
+	permissions("networking.k8s.io", "ingresses", "", true, "get", "list", "watch", "update"),
+	permissions("", "secrets", "", true, "get", "create", "patch"),
+	// backend placement
+	permissions("", "services", "", false, "get"),
+	// scraping the request metrics of the data plane
+	permissions("", "services", "proxy", false, "get"),
+	// restricting the backends of private traffic objects to the fleet
+	permissions("networking.k8s.io", "networkpolicies", "", false, "get", "create", "patch", "delete"),
+	permissions("admissionregistration.k8s.io", "mutatingwebhookconfigurations", "", true, "get", "create", "update"),
+	permissions("admissionregistration.k8s.io", "validatingwebhookconfigurations", "", false, "get", "create", "update"),
+	// companion objects of the ingress classes, such as the Service,
+	// Deployment and HorizontalPodAutoscaler of self-hosted data planes
+	permissions("", "services", "", false, "patch", "delete"),
+	permissions("apps", "deployments", "", false, "get", "patch", "delete"),
+	permissions("autoscaling", "horizontalpodautoscalers", "", false, "get", "patch", "delete"),
+	// HTTP-01 challenge solvers
+	permissions("", "pods", "", false, "create", "delete"),
+	permissions("", "services", "", false, "create", "delete"),
+	permissions("networking.k8s.io", "ingresses", "", false, "create", "delete"),
+	// requesting scoped cluster tokens
+	permissions("", "serviceaccounts", "token", false, "create"),
+	// Multi-Cluster Services API
+	permissions("multicluster.x-k8s.io", "serviceexports", "", false, "get"),
+	permissions("multicluster.x-k8s.io", "serviceimports", "", false, "get", "create"),
+	// detecting the egress addresses of the cluster
+	permissions("", "nodes", "", false, "list"),
+	// detecting the Gateway API version of the cluster
+	permissions("apiextensions.k8s.io", "customresourcedefinitions", "", false, "get"),
+	// addresses of the managed hosts served by the cluster DNS
+	permissions("", "configmaps", "", false, "get", "create", "update"),
+	// reconcile loop events
+	permissions("", "events", "", false, "create", "patch"),
+)
+
+func concat(permissions ...[]Permission) []Permission {
+	result := []Permission{}
+	for _, p := range permissions {
+		result = append(result, p...)
+	}
+	return result
+}
+
+// Report is the result of auditing a set of permissions
+type Report struct {
+	// Missing are the permissions that were denied
+	Missing []Permission
+	// Excess are rules granted in the audited namespace that none of the
+	// permissions need
+	Excess []string
+}
+
+// MissingMandatory returns the mandatory permissions that were denied
+func (r *Report) MissingMandatory() []Permission {
+	missing := []Permission{}
+	for _, p := range r.Missing {
+		if p.Mandatory {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// Audit checks each permission with a SelfSubjectAccessReview, and compares
+// the rules granted in the namespace against the permissions
+func Audit(ctx context.Context, config *rest.Config, namespace string, permissions []Permission) (*Report, error) {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	for _, p := range permissions {
+		review, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   namespace,
+					Group:       p.Group,
+					Resource:    p.Resource,
+					Subresource: p.Subresource,
+					Verb:        p.Verb,
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review permission %s: %v", p, err)
+		}
+		if !review.Status.Allowed {
+			report.Missing = append(report.Missing, p)
+		}
+	}
+
+	// rules can only be reviewed within a namespace
+	if namespace == "" {
+		return report, nil
+	}
+	rules, err := kubeClient.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to review granted rules: %v", err)
+	}
+	report.Excess = excessRules(rules.Status.ResourceRules, permissions)
+
+	return report, nil
+}
+
+// excessRules returns the granted verb/resource combinations that aren't
+// needed by any of the permissions. Rules granted to every authenticated
+// user, such as the self reviews themselves, are ignored
+func excessRules(rules []authorizationv1.ResourceRule, permissions []Permission) []string {
+	needed := map[string]struct{}{}
+	for _, p := range permissions {
+		needed[p.String()] = struct{}{}
+	}
+
+	excess := []string{}
+	for _, rule := range rules {
+		for _, group := range rule.APIGroups {
+			if group == "authorization.k8s.io" || group == "authentication.k8s.io" {
+				continue
+			}
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					resourceParts := strings.SplitN(resource, "/", 2)
+					p := Permission{Group: group, Resource: resourceParts[0], Verb: verb}
+					if len(resourceParts) == 2 {
+						p.Subresource = resourceParts[1]
+					}
+					if _, ok := needed[p.String()]; !ok {
+						excess = append(excess, p.String())
+					}
+				}
+			}
+		}
+	}
+	return excess
+}
+
+// Auditor audits the controller permissions on startup, logs and exports
+// the result and fails readiness when mandatory permissions are missing
+type Auditor struct {
+	config      *rest.Config
+	namespace   string
+	permissions []Permission
+
+	mu     sync.RWMutex
+	report *Report
+}
+
+var _ manager.Runnable = &Auditor{}
+var _ manager.LeaderElectionRunnable = &Auditor{}
+
+func NewAuditor(config *rest.Config, namespace string, permissions []Permission) *Auditor {
+	return &Auditor{config: config, namespace: namespace, permissions: permissions}
+}
+
+func (a *Auditor) Start(ctx context.Context) error {
+	report, err := Audit(ctx, a.config, a.namespace, a.permissions)
+	if err != nil {
+		return err
+	}
+	LogReport(report, "control plane")
+	observeReport(report, "control plane")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.report = report
+	return nil
+}
+
+// NeedLeaderElection is false so every replica reports its own readiness
+func (a *Auditor) NeedLeaderElection() bool {
+	return false
+}
+
+// Checker is a readiness check failing until the audit has completed, and
+// while mandatory permissions are missing
+func (a *Auditor) Checker(_ *http.Request) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.report == nil {
+		return AuditPendingErr
+	}
+	if missing := a.report.MissingMandatory(); len(missing) > 0 {
+		return fmt.Errorf("missing mandatory permissions: %v", missing)
+	}
+	return nil
+}
+
+// LogReport logs the missing and excess permissions of the report
+func LogReport(report *Report, cluster string) {
+	for _, p := range report.Missing {
+		log.Log.Info("missing permission", "cluster", cluster, "permission", p.String(), "mandatory", p.Mandatory)
+	}
+	for _, p := range report.Excess {
+		log.Log.Info("excess permission", "cluster", cluster, "permission", p)
+	}
+	if len(report.Missing) == 0 {
+		log.Log.Info("all required permissions granted", "cluster", cluster)
+	}
+}
+
+// AuditCluster audits the permissions needed in a workload cluster, logging
+// and exporting the result
+func AuditCluster(ctx context.Context, config *rest.Config, cluster string) (*Report, error) {
+	report, err := Audit(ctx, config, "", WorkloadPermissions)
+	if err != nil {
+		return nil, err
+	}
+	LogReport(report, cluster)
+	observeReport(report, cluster)
+	return report, nil
+}
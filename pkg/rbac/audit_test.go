@@ -0,0 +1,53 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+func Test_excessRules(t *testing.T) {
+	needed := concat(
+		permissions("", "secrets", "", true, "get", "list"),
+		permissions("kuadrant.io", "dnsrecords", "status", true, "update"),
+	)
+
+	tests := []struct {
+		name   string
+		rules  []authorizationv1.ResourceRule
+		expect []string
+	}{
+		{
+			name: "only needed rules granted",
+			rules: []authorizationv1.ResourceRule{
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}},
+				{APIGroups: []string{"kuadrant.io"}, Resources: []string{"dnsrecords/status"}, Verbs: []string{"update"}},
+			},
+			expect: []string{},
+		},
+		{
+			name: "extra verbs and resources granted",
+			rules: []authorizationv1.ResourceRule{
+				{APIGroups: []string{""}, Resources: []string{"secrets", "configmaps"}, Verbs: []string{"get", "delete"}},
+			},
+			expect: []string{"delete secrets", "get configmaps", "delete configmaps"},
+		},
+		{
+			name: "self review rules ignored",
+			rules: []authorizationv1.ResourceRule{
+				{APIGroups: []string{"authorization.k8s.io"}, Resources: []string{"selfsubjectaccessreviews"}, Verbs: []string{"create"}},
+			},
+			expect: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := excessRules(tt.rules, needed)
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected '%v' got '%v'", tt.expect, got)
+			}
+		})
+	}
+}
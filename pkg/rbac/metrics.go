@@ -0,0 +1,43 @@
+package rbac
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// missingPermissions is a prometheus metric which is set to 1 for each
+	// permission found missing by the last audit of a cluster.
+	missingPermissions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_rbac_missing_permission",
+			Help: "MCTC permission missing from the last RBAC audit",
+		},
+		[]string{"cluster", "permission", "mandatory"},
+	)
+
+	// excessPermissions is a prometheus metric which holds the number of
+	// granted permissions that aren't needed, found by the last audit of a
+	// cluster.
+	excessPermissions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mctc_rbac_excess_permissions",
+			Help: "MCTC number of excess permissions from the last RBAC audit",
+		},
+		[]string{"cluster"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(missingPermissions, excessPermissions)
+}
+
+func observeReport(report *Report, cluster string) {
+	missingPermissions.DeletePartialMatch(prometheus.Labels{"cluster": cluster})
+	for _, p := range report.Missing {
+		missingPermissions.WithLabelValues(cluster, p.String(), strconv.FormatBool(p.Mandatory)).Set(1)
+	}
+	excessPermissions.WithLabelValues(cluster).Set(float64(len(report.Excess)))
+}
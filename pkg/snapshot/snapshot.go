@@ -0,0 +1,220 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// Version is the version of the snapshot format
+const Version = "kuadrant.io/snapshot/v1"
+
+// Kinds are the kinds of the desired state of the controller, in the order
+// they're restored, so owners are restored before the objects they own.
+// The ManagedHosts hold the placements of the traffic objects, as the
+// clusters serving their hosts. ZoneReports are left out, as they're
+// regenerated, as are the Secrets of the clusters and provider credentials,
+// which are restored from where they're managed
+var Kinds = []string{
+	"ControllerConfig",
+	"ManagedZone",
+	"HostPool",
+	"HostClaim",
+	"DNSRecord",
+	"ManagedHost",
+	"TrafficPolicy",
+	"TrafficRollout",
+	"ClusterEvacuation",
+}
+
+// Snapshot is the desired state of the controller, restored on a new
+// control plane cluster to recover the controller without re-deriving its
+// state. The status of each object is kept, as it records where the records
+// are published and where the traffic is placed
+type Snapshot struct {
+	Version   string                      `json:"version"`
+	CreatedAt metav1.Time                 `json:"createdAt"`
+	Objects   []unstructured.Unstructured `json:"objects"`
+}
+
+// Take returns the snapshot of the objects of the namespace, or of all the
+// namespaces when empty
+func Take(ctx context.Context, c client.Client, namespace string) (*Snapshot, error) {
+	snapshot := &Snapshot{Version: Version, CreatedAt: metav1.Now(), Objects: []unstructured.Unstructured{}}
+	for _, kind := range Kinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(v1.GroupVersion.WithKind(kind + "List"))
+		if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+		for _, obj := range list.Items {
+			if obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			snapshot.Objects = append(snapshot.Objects, strip(obj))
+		}
+	}
+	return snapshot, nil
+}
+
+// strip removes the metadata assigned by the API server, the finalizers the
+// controllers add back, and the handover of the objects, so the control
+// plane they're restored on manages them
+func strip(obj unstructured.Unstructured) unstructured.Unstructured {
+	obj = *obj.DeepCopy()
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		delete(annotations, metadata.AnnotationHandedOverTo)
+		obj.SetAnnotations(annotations)
+	}
+	obj.SetUID("")
+	obj.SetResourceVersion("")
+	obj.SetGeneration(0)
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetManagedFields(nil)
+	obj.SetSelfLink("")
+	obj.SetFinalizers(nil)
+	return obj
+}
+
+// Restore creates the objects of the snapshot and restores their status.
+// Objects that already exist are left as they are, so a restore can be
+// resumed. The owner references between the objects are remapped to the
+// objects restored, and the references to other owners dropped
+func Restore(ctx context.Context, c client.Client, snapshot *Snapshot) error {
+	if snapshot.Version != Version {
+		return fmt.Errorf("unsupported snapshot version %q, expected %q", snapshot.Version, Version)
+	}
+	uids := map[string]types.UID{}
+	for _, kind := range Kinds {
+		for i := range snapshot.Objects {
+			snapshotted := &snapshot.Objects[i]
+			if snapshotted.GetKind() != kind {
+				continue
+			}
+			obj := snapshotted.DeepCopy()
+			status, hasStatus := obj.Object["status"]
+			delete(obj.Object, "status")
+			obj.SetOwnerReferences(remapOwners(obj.GetNamespace(), obj.GetOwnerReferences(), uids))
+
+			if err := c.Create(ctx, obj); err != nil {
+				if !k8serrors.IsAlreadyExists(err) {
+					return fmt.Errorf("failed to restore %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+				}
+				log.FromContext(ctx).Info("object already exists, skipping", "kind", kind, "name", obj.GetName(), "namespace", obj.GetNamespace())
+				existing := &unstructured.Unstructured{}
+				existing.SetGroupVersionKind(obj.GroupVersionKind())
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+					return err
+				}
+				uids[ownerKey(kind, obj.GetNamespace(), obj.GetName())] = existing.GetUID()
+				continue
+			}
+			uids[ownerKey(kind, obj.GetNamespace(), obj.GetName())] = obj.GetUID()
+			log.FromContext(ctx).Info("object restored", "kind", kind, "name", obj.GetName(), "namespace", obj.GetNamespace())
+
+			if !hasStatus {
+				continue
+			}
+			obj.Object["status"] = status
+			if err := c.Status().Update(ctx, obj); err != nil {
+				return fmt.Errorf("failed to restore status of %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Handover hands the ManagedZones and DNSRecords of the namespace over to
+// the control plane named hub, once their snapshot is restored on it. Both
+// control planes can then run side by side: the controller stops writing
+// the records handed over, which the new control plane adopts, and releases
+// them on deletion without deleting the records served
+func Handover(ctx context.Context, c client.Client, namespace, hub string) error {
+	objects, err := zonesAndRecords(ctx, c, namespace)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if metadata.HandedOverTo(obj) == hub {
+			continue
+		}
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		metadata.AddAnnotation(obj, metadata.AnnotationHandedOverTo, hub)
+		if err := c.Patch(ctx, obj, patch); err != nil {
+			return fmt.Errorf("failed to hand over %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+		log.FromContext(ctx).Info("object handed over", "name", obj.GetName(), "namespace", obj.GetNamespace(), "handedOverTo", hub)
+	}
+	return nil
+}
+
+// Release takes back the ManagedZones and DNSRecords of the namespace
+// handed over to the control plane named hub
+func Release(ctx context.Context, c client.Client, namespace, hub string) error {
+	objects, err := zonesAndRecords(ctx, c, namespace)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if metadata.HandedOverTo(obj) != hub {
+			continue
+		}
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		metadata.RemoveAnnotation(obj, metadata.AnnotationHandedOverTo)
+		if err := c.Patch(ctx, obj, patch); err != nil {
+			return fmt.Errorf("failed to take back %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+		log.FromContext(ctx).Info("object taken back", "name", obj.GetName(), "namespace", obj.GetNamespace(), "handedOverTo", hub)
+	}
+	return nil
+}
+
+func zonesAndRecords(ctx context.Context, c client.Client, namespace string) ([]client.Object, error) {
+	zones := &v1.ManagedZoneList{}
+	if err := c.List(ctx, zones, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	records := &v1.DNSRecordList{}
+	if err := c.List(ctx, records, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	var objects []client.Object
+	for i := range zones.Items {
+		objects = append(objects, &zones.Items[i])
+	}
+	for i := range records.Items {
+		objects = append(objects, &records.Items[i])
+	}
+	return objects, nil
+}
+
+func remapOwners(namespace string, owners []metav1.OwnerReference, uids map[string]types.UID) []metav1.OwnerReference {
+	remapped := []metav1.OwnerReference{}
+	for _, owner := range owners {
+		gv, err := schema.ParseGroupVersion(owner.APIVersion)
+		if err != nil || gv.Group != v1.GroupVersion.Group {
+			continue
+		}
+		uid, ok := uids[ownerKey(owner.Kind, namespace, owner.Name)]
+		if !ok {
+			continue
+		}
+		owner.UID = uid
+		remapped = append(remapped, owner)
+	}
+	return remapped
+}
+
+func ownerKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
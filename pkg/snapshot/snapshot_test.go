@@ -0,0 +1,129 @@
+package snapshot
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+func TestTakeRestore(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	zone := &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{Name: "example.com", Namespace: "mctc", UID: "zone-uid", Finalizers: []string{"kuadrant.io/dns"}},
+		Status: v1.ManagedZoneStatus{
+			ObservedGeneration: 1,
+			Conditions:         []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "ProviderSuccess", LastTransitionTime: metav1.Now()}},
+		},
+	}
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "mctc", OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: v1.GroupVersion.String(), Kind: "ManagedZone", Name: "example.com", UID: "zone-uid"},
+			{APIVersion: "v1", Kind: "Secret", Name: "credentials", UID: "secret-uid"},
+		}},
+		Status: v1.DNSRecordStatus{ObservedGeneration: 1},
+	}
+	source := fake.NewClientBuilder().WithScheme(scheme).WithObjects(zone, record).Build()
+
+	snapshot, err := Take(context.TODO(), source, "mctc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Objects) != 2 {
+		t.Fatalf("expected '%v' got '%v'", 2, len(snapshot.Objects))
+	}
+	if uid := snapshot.Objects[0].GetUID(); uid != "" {
+		t.Errorf("expected '%v' got '%v'", "", uid)
+	}
+
+	target := fake.NewClientBuilder().WithScheme(scheme).Build()
+	if err := Restore(context.TODO(), target, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	// restoring again skips the objects restored
+	if err := Restore(context.TODO(), target, snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	restoredZone := &v1.ManagedZone{}
+	if err := target.Get(context.TODO(), client.ObjectKeyFromObject(zone), restoredZone); err != nil {
+		t.Fatal(err)
+	}
+	if len(restoredZone.Finalizers) != 0 {
+		t.Errorf("expected '%v' got '%v'", 0, len(restoredZone.Finalizers))
+	}
+	if len(restoredZone.Status.Conditions) != 1 {
+		t.Errorf("expected '%v' got '%v'", 1, len(restoredZone.Status.Conditions))
+	}
+
+	restoredRecord := &v1.DNSRecord{}
+	if err := target.Get(context.TODO(), client.ObjectKeyFromObject(record), restoredRecord); err != nil {
+		t.Fatal(err)
+	}
+	if restoredRecord.Status.ObservedGeneration != 1 {
+		t.Errorf("expected '%v' got '%v'", 1, restoredRecord.Status.ObservedGeneration)
+	}
+	owners := restoredRecord.GetOwnerReferences()
+	if len(owners) != 1 || owners[0].UID != restoredZone.UID {
+		t.Errorf("expected '%v' got '%v'", restoredZone.UID, owners)
+	}
+
+	snapshot.Version = "kuadrant.io/snapshot/v0"
+	if err := Restore(context.TODO(), target, snapshot); err == nil {
+		t.Errorf("expected '%v' got '%v'", "unsupported snapshot version", err)
+	}
+}
+
+func TestHandover(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	zone := &v1.ManagedZone{ObjectMeta: metav1.ObjectMeta{Name: "example.com", Namespace: "mctc"}}
+	record := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "mctc"}}
+	other := &v1.DNSRecord{ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "other"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(zone, record, other).Build()
+
+	if err := Handover(context.TODO(), c, "mctc", "hub-2"); err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range []client.Object{&v1.ManagedZone{}, &v1.DNSRecord{}} {
+		key := client.ObjectKeyFromObject(zone)
+		if _, ok := obj.(*v1.DNSRecord); ok {
+			key = client.ObjectKeyFromObject(record)
+		}
+		if err := c.Get(context.TODO(), key, obj); err != nil {
+			t.Fatal(err)
+		}
+		if hub := metadata.HandedOverTo(obj); hub != "hub-2" {
+			t.Errorf("expected '%v' got '%v'", "hub-2", hub)
+		}
+	}
+	if err := c.Get(context.TODO(), client.ObjectKeyFromObject(other), other); err != nil {
+		t.Fatal(err)
+	}
+	if hub := metadata.HandedOverTo(other); hub != "" {
+		t.Errorf("expected '%v' got '%v'", "", hub)
+	}
+
+	// the objects restored from a snapshot of the objects handed over are
+	// managed by the control plane they're restored on
+	snapshot, err := Take(context.TODO(), c, "mctc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range snapshot.Objects {
+		if hub := metadata.HandedOverTo(&obj); hub != "" {
+			t.Errorf("expected '%v' got '%v'", "", hub)
+		}
+	}
+}
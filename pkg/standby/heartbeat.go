@@ -0,0 +1,75 @@
+package standby
+
+import (
+	"context"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// HeartbeatLease is the name of the Lease the active control plane renews
+// in the controller namespace, watched by its standby
+const HeartbeatLease = "mctc-heartbeat"
+
+// Heartbeat renews the heartbeat Lease of the active control plane every
+// Interval. The standby takes over once the Lease isn't renewed for
+// LeaseDuration
+type Heartbeat struct {
+	Client        client.Client
+	Namespace     string
+	Identity      string
+	Interval      time.Duration
+	LeaseDuration time.Duration
+}
+
+func (h *Heartbeat) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info("Starting heartbeat", "interval", h.Interval, "leaseDuration", h.LeaseDuration, "identity", h.Identity)
+	if err := h.renew(ctx); err != nil {
+		log.FromContext(ctx).Error(err, "failed to renew heartbeat lease")
+	}
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := h.renew(ctx); err != nil {
+				log.FromContext(ctx).Error(err, "failed to renew heartbeat lease")
+			}
+		}
+	}
+}
+
+func (h *Heartbeat) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(h.LeaseDuration.Seconds())
+	lease := &coordinationv1.Lease{}
+	err := h.Client.Get(ctx, client.ObjectKey{Namespace: h.Namespace, Name: HeartbeatLease}, lease)
+	if k8serrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: HeartbeatLease, Namespace: h.Namespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &h.Identity,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		return h.Client.Create(ctx, lease)
+	}
+	if err != nil {
+		return err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != h.Identity {
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.HolderIdentity = &h.Identity
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+	return h.Client.Update(ctx, lease)
+}
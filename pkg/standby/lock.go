@@ -0,0 +1,43 @@
+package standby
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// FencedErr is returned by the leader election lock of a standby control
+// plane until it takes over
+var FencedErr = errors.New("the standby control plane didn't take over")
+
+// Lock is the leader election lock of a standby control plane. It can't be
+// acquired until the standby takes over, so the controllers and runnables
+// of the manager needing leader election, which write to the control plane,
+// the workload clusters and the DNS providers, only start once the standby
+// is active
+type Lock struct {
+	resourcelock.Interface
+	Standby *Standby
+}
+
+func (l *Lock) Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	if !l.Standby.Active() {
+		return nil, nil, FencedErr
+	}
+	return l.Interface.Get(ctx)
+}
+
+func (l *Lock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	if !l.Standby.Active() {
+		return FencedErr
+	}
+	return l.Interface.Create(ctx, ler)
+}
+
+func (l *Lock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	if !l.Standby.Active() {
+		return FencedErr
+	}
+	return l.Interface.Update(ctx, ler)
+}
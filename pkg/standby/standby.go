@@ -0,0 +1,234 @@
+package standby
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/snapshot"
+)
+
+// fenceTimeout is how long the standby tries to hand the zones and records
+// of the active control plane over to itself when taking over
+const fenceTimeout = 10 * time.Second
+
+var VerificationPendingErr = errors.New("the standby didn't verify the DNS providers and clusters yet")
+
+// Standby runs a control plane as the standby of the active control plane,
+// restored from its snapshot. While the heartbeat Lease of the active
+// control plane is renewed, the ManagedZones and DNSRecords of the standby
+// are kept handed over to it, so the standby doesn't write their records,
+// and the standby verifies it can reach the DNS providers of its zones and
+// its clusters. Once the Lease isn't renewed for its duration, as observed
+// by the standby, the standby takes over: it hands the zones and records of
+// the active control plane over to itself, resumes writing its own records,
+// and renews its own heartbeat Lease. The standby doesn't take over while it
+// can't hand the zones and records of the active control plane over, unless
+// forced to
+type Standby struct {
+	Client client.Client
+	// ActiveClient is the client of the active control plane
+	ActiveClient  client.Client
+	Namespace     string
+	Identity      string
+	Interval      time.Duration
+	ZoneProviders *dns.ZoneProviders
+	// Heartbeat is renewed by the standby once it took over
+	Heartbeat *Heartbeat
+	// ForceTakeover lets the standby take over when the zones and records of
+	// the active control plane can't be handed over to it, once an operator
+	// made sure the active control plane is down. Otherwise both control
+	// planes would write the records of the zones
+	ForceTakeover bool
+
+	mu        sync.RWMutex
+	verifyErr error
+	active    bool
+
+	// holder, duration and renewTime are the holder, duration and renew
+	// time of the heartbeat Lease last observed, at observedAt according to
+	// the clock of the standby, as the clocks of the control planes may
+	// differ, and the active control plane may not be reachable any more
+	holder     string
+	duration   time.Duration
+	renewTime  time.Time
+	observedAt time.Time
+}
+
+func (s *Standby) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info("Starting standby", "interval", s.Interval, "identity", s.Identity)
+	s.observedAt = time.Now()
+	s.mu.Lock()
+	s.verifyErr = VerificationPendingErr
+	s.mu.Unlock()
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for !s.Active() {
+		s.step(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+	return s.Heartbeat.Start(ctx)
+}
+
+// NeedLeaderElection returns false, as the standby holds the leader election
+// lock of the manager until it takes over, see Lock
+func (s *Standby) NeedLeaderElection() bool {
+	return false
+}
+
+// Active returns true once the standby took over
+func (s *Standby) Active() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// Checker is a readiness check failing while the standby can't reach the
+// DNS providers of its zones or its clusters
+func (s *Standby) Checker(_ *http.Request) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.active {
+		return nil
+	}
+	return s.verifyErr
+}
+
+// step observes the heartbeat Lease of the active control plane, and takes
+// over once it expired
+func (s *Standby) step(ctx context.Context, now time.Time) {
+	lease := &coordinationv1.Lease{}
+	err := s.ActiveClient.Get(ctx, client.ObjectKey{Namespace: s.Namespace, Name: HeartbeatLease}, lease)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to get the heartbeat lease of the active control plane")
+	} else {
+		s.observe(lease, now)
+	}
+
+	if s.expired(now) {
+		log.FromContext(ctx).Info("Heartbeat lease of the active control plane expired, taking over", "active", s.holder, "renewTime", s.renewTime, "observedAt", s.observedAt)
+		if err := s.takeOver(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "failed to take over")
+			return
+		}
+		s.mu.Lock()
+		s.active = true
+		s.mu.Unlock()
+		log.FromContext(ctx).Info("Took over from the active control plane", "active", s.holder)
+		return
+	}
+
+	if s.holder != "" {
+		if err := snapshot.Handover(ctx, s.Client, s.Namespace, s.holder); err != nil {
+			log.FromContext(ctx).Error(err, "failed to hand the zones and records over to the active control plane")
+		}
+	}
+	verifyErr := s.verify(ctx)
+	if verifyErr != nil {
+		log.FromContext(ctx).Error(verifyErr, "standby verification failed")
+	}
+	s.mu.Lock()
+	s.verifyErr = verifyErr
+	s.mu.Unlock()
+}
+
+// observe records when the renewal of the Lease was last observed
+func (s *Standby) observe(lease *coordinationv1.Lease, now time.Time) {
+	if lease.Spec.HolderIdentity != nil {
+		s.holder = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.LeaseDurationSeconds != nil {
+		s.duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	if lease.Spec.RenewTime != nil && !lease.Spec.RenewTime.Time.Equal(s.renewTime) {
+		s.renewTime = lease.Spec.RenewTime.Time
+		s.observedAt = now
+	}
+}
+
+// expired returns true once the Lease wasn't observed renewed for its
+// duration. The standby doesn't take over before it observed the Lease of
+// an active control plane
+func (s *Standby) expired(now time.Time) bool {
+	if s.holder == "" || s.duration <= 0 {
+		return false
+	}
+	return now.Sub(s.observedAt) > s.duration
+}
+
+// takeOver hands the zones and records of the active control plane over to
+// the standby, so it doesn't write them if it comes back, and releases the
+// zones and records of the standby. It fails when the active control plane
+// can't be fenced, as it may still be writing the records behind a network
+// partition, unless the takeover is forced
+func (s *Standby) takeOver(ctx context.Context) error {
+	fenceCtx, cancel := context.WithTimeout(ctx, fenceTimeout)
+	defer cancel()
+	if err := snapshot.Handover(fenceCtx, s.ActiveClient, s.Namespace, s.Identity); err != nil {
+		if !s.ForceTakeover {
+			return fmt.Errorf("failed to fence the active control plane %s, not taking over: %w", s.holder, err)
+		}
+		log.FromContext(ctx).Error(err, "failed to hand the zones and records of the active control plane over, taking over as forced, it must not resume", "active", s.holder)
+	}
+
+	return snapshot.Release(ctx, s.Client, s.Namespace, s.holder)
+}
+
+// verify checks the DNS providers of the zones and the clusters can be
+// reached
+func (s *Standby) verify(ctx context.Context) error {
+	var errs []error
+
+	zones := &v1.ManagedZoneList{}
+	if err := s.Client.List(ctx, zones, client.InNamespace(s.Namespace)); err != nil {
+		return err
+	}
+	for i := range zones.Items {
+		zone := &zones.Items[i]
+		_, provider, err := s.ZoneProviders.ProvidersFor(ctx, zone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("zone %s: %w", zone.Name, err))
+			continue
+		}
+		if lister, ok := provider.(dns.Lister); ok {
+			if _, err := lister.Zones(); err != nil {
+				errs = append(errs, fmt.Errorf("zone %s: %w", zone.Name, err))
+			}
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := s.Client.List(ctx, secrets, client.InNamespace(s.Namespace), client.MatchingLabels{secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE}); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		clusterSecretItem := &secrets.Items[i]
+		workloadClient, err := clusterSecret.ClientFromSecret(s.Client, clusterSecretItem, client.Options{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", clusterSecretItem.Name, err))
+			continue
+		}
+		if err := workloadClient.List(ctx, &networkingv1.IngressList{}, client.Limit(1)); err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", clusterSecretItem.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,139 @@
+package standby
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/test"
+)
+
+func TestStandby_step(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	zone := func() *v1.ManagedZone {
+		return &v1.ManagedZone{ObjectMeta: metav1.ObjectMeta{Name: "example.com", Namespace: "mctc"}, Spec: v1.ManagedZoneSpec{ID: "zone", DomainName: "example.com"}}
+	}
+	start := time.Now()
+	holder, seconds, renewTime := "hub-1", int32(60), metav1.NewMicroTime(start)
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: HeartbeatLease, Namespace: "mctc"},
+		Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder, LeaseDurationSeconds: &seconds, RenewTime: &renewTime},
+	}
+	active := fake.NewClientBuilder().WithScheme(scheme).WithObjects(zone(), lease).Build()
+	local := fake.NewClientBuilder().WithScheme(scheme).WithObjects(zone()).Build()
+
+	provider := test.NewDNSProvider()
+	provider.AddZone("example.com", "zone")
+	standby := &Standby{
+		Client:        local,
+		ActiveClient:  active,
+		Namespace:     "mctc",
+		Identity:      "hub-2",
+		ZoneProviders: dns.NewZoneProviders(local, "mctc", "aws", provider, nil),
+		observedAt:    start,
+	}
+
+	handedOverTo := func(c client.Client) string {
+		z := &v1.ManagedZone{}
+		if err := c.Get(context.TODO(), client.ObjectKeyFromObject(zone()), z); err != nil {
+			t.Fatal(err)
+		}
+		return metadata.HandedOverTo(z)
+	}
+
+	// while the lease is renewed, the zones of the standby are handed over
+	// to the active control plane
+	standby.step(context.TODO(), start.Add(30*time.Second))
+	if standby.Active() {
+		t.Errorf("expected '%v' got '%v'", false, standby.Active())
+	}
+	if hub := handedOverTo(local); hub != "hub-1" {
+		t.Errorf("expected '%v' got '%v'", "hub-1", hub)
+	}
+	if err := standby.Checker(nil); err != nil {
+		t.Errorf("expected '%v' got '%v'", nil, err)
+	}
+
+	// the lease isn't renewed past its duration
+	standby.step(context.TODO(), start.Add(100*time.Second))
+	if !standby.Active() {
+		t.Errorf("expected '%v' got '%v'", true, standby.Active())
+	}
+	if hub := handedOverTo(local); hub != "" {
+		t.Errorf("expected '%v' got '%v'", "", hub)
+	}
+	if hub := handedOverTo(active); hub != "hub-2" {
+		t.Errorf("expected '%v' got '%v'", "hub-2", hub)
+	}
+}
+
+func TestStandby_takeOver(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	zone := &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{Name: "example.com", Namespace: "mctc", Annotations: map[string]string{metadata.AnnotationHandedOverTo: "hub-1"}},
+		Spec:       v1.ManagedZoneSpec{ID: "zone", DomainName: "example.com"},
+	}
+
+	cases := []struct {
+		name          string
+		forceTakeover bool
+		takenOver     bool
+	}{
+		{
+			name: "active control plane not fenced",
+		},
+		{
+			name:          "takeover forced",
+			forceTakeover: true,
+			takenOver:     true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			local := fake.NewClientBuilder().WithScheme(scheme).WithObjects(zone.DeepCopy()).Build()
+			// the active control plane can't be reached
+			unreachable := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+			standby := &Standby{
+				Client:        local,
+				ActiveClient:  unreachable,
+				Namespace:     "mctc",
+				Identity:      "hub-2",
+				ForceTakeover: tc.forceTakeover,
+				holder:        "hub-1",
+			}
+			err := standby.takeOver(context.TODO())
+			if takenOver := err == nil; takenOver != tc.takenOver {
+				t.Errorf("expected '%v' got '%v'", tc.takenOver, err)
+			}
+			z := &v1.ManagedZone{}
+			if err := local.Get(context.TODO(), client.ObjectKeyFromObject(zone), z); err != nil {
+				t.Fatal(err)
+			}
+			if released := metadata.HandedOverTo(z) == ""; released != tc.takenOver {
+				t.Errorf("expected '%v' got '%v'", tc.takenOver, released)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+)
+
+var _ traffic.CertificateService = &CertificateService{}
+
+// CertificateService is an in-memory certificate service. Certificates are
+// issued as soon as they're ensured, unless Pending is set, so tests can
+// hold hosts waiting for their certificate
+type CertificateService struct {
+	mu sync.Mutex
+	// Namespace is the namespace of the certificate secrets
+	Namespace string
+	// Pending keeps certificates from being issued
+	Pending bool
+	// Failed reports the issuance of pending certificates as failed
+	Failed bool
+	// host -> owner of the certificate
+	certificates map[string]metav1.Object
+}
+
+func NewCertificateService(namespace string) *CertificateService {
+	return &CertificateService{Namespace: namespace, certificates: map[string]metav1.Object{}}
+}
+
+func (s *CertificateService) EnsureCertificate(_ context.Context, host string, owner metav1.Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certificates[host] = owner
+	return nil
+}
+
+// GetCertificateSecret returns a TLS secret for the host once its
+// certificate is ensured and issued, or a not found error
+func (s *CertificateService) GetCertificateSecret(_ context.Context, host string) (*corev1.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.certificates[host]; !ok || s.Pending {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, host)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      host,
+			Namespace: s.Namespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("certificate of " + host),
+			corev1.TLSPrivateKeyKey: []byte("key of " + host),
+		},
+	}, nil
+}
+
+// RecordContentHash records the content hash of the secret on the secret
+func (s *CertificateService) RecordContentHash(_ context.Context, secret *corev1.Secret) (string, error) {
+	hash := tls.ContentHash(secret)
+	metadata.AddAnnotation(secret, tls.AnnotationContentHash, hash)
+	return hash, nil
+}
+
+// IssuanceFailed returns true when the certificate of the host is pending
+// and Failed is set
+func (s *CertificateService) IssuanceFailed(_ context.Context, host string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.certificates[host]
+	return ok && s.Pending && s.Failed, nil
+}
+
+// Ensured returns true when a certificate was ensured for the host
+func (s *CertificateService) Ensured(host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.certificates[host]
+	return ok
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"sync"
+
+	certmanv1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/clusterSecret"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/secret"
+	trafficController "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/multiClusterWatch"
+)
+
+// Scheme returns a scheme with the types of the control plane and workload
+// clusters registered
+func Scheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+	utilruntime.Must(certmanv1.AddToScheme(scheme))
+	return scheme
+}
+
+// Clusters holds a fake client for each workload cluster, created on first
+// use, standing in for the clients built from the cluster secrets
+type Clusters struct {
+	mu      sync.Mutex
+	scheme  *runtime.Scheme
+	clients map[string]client.Client
+}
+
+func NewClusters(scheme *runtime.Scheme) *Clusters {
+	return &Clusters{scheme: scheme, clients: map[string]client.Client{}}
+}
+
+// Client returns the client of the workload cluster
+func (c *Clusters) Client(cluster string) client.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.clients[cluster]; !ok {
+		c.clients[cluster] = &applyClient{Client: fake.NewClientBuilder().WithScheme(c.scheme).Build()}
+	}
+	return c.clients[cluster]
+}
+
+// applyClient creates the objects server-side applied while they don't
+// exist, which the fake client doesn't support
+type applyClient struct {
+	client.Client
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch != client.Apply {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object)); k8serrors.IsNotFound(err) {
+		return c.Client.Create(ctx, obj)
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// HandlerFactory returns the factory of the traffic controllers of the
+// workload clusters, reading and writing through the fake clients of the
+// clusters with the hosts and certificates services given
+func (c *Clusters) HandlerFactory(hosts trafficController.HostService, certificates trafficController.CertificateService, store *config.Store) multiClusterWatch.ResourceHandlerFactory {
+	return func(cluster string, _ *rest.Config, _ client.Client) (multiClusterWatch.ResourceHandler, error) {
+		return c.Handler(cluster, hosts, certificates, store), nil
+	}
+}
+
+// Handler returns the traffic controller of the workload cluster
+func (c *Clusters) Handler(cluster string, hosts trafficController.HostService, certificates trafficController.CertificateService, store *config.Store) *trafficController.Reconciler {
+	return &trafficController.Reconciler{
+		WorkloadClient: c.Client(cluster),
+		Hosts:          hosts,
+		Certificates:   certificates,
+		Config:         store,
+	}
+}
+
+// ClusterSecret returns the ArgoCD cluster secret of the workload cluster
+// served at the address
+func ClusterSecret(name, namespace, server string) *corev1.Secret {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{secret.CLUSTER__SECRET_LABEL: secret.ARGO_CLUSTER_LABEL_VALUE},
+		},
+		Data: map[string][]byte{
+			"name":   []byte(name),
+			"server": []byte(server),
+		},
+	}
+	utilruntime.Must(clusterSecret.SetConfig(s, &clusterSecret.ArgoClusterConfig{
+		BearerToken: "token",
+		TlsClientConfig: clusterSecret.TLSClientConfig{
+			Insecure: true,
+		},
+	}))
+	return s
+}
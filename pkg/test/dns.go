@@ -0,0 +1,156 @@
+// Package test is a harness for testing the controllers without cloud
+// credentials. It provides an in-memory DNS provider, the host service of
+// the controller over a fake control plane client, a fake certificate
+// service, and fake workload cluster clients, to be used with envtest or
+// the controller-runtime fake client. Tests of the packages the harness
+// depends on import it from an external _test package.
+package test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+var (
+	_ dns.Provider     = &DNSProvider{}
+	_ dns.Verifier     = &DNSProvider{}
+	_ dns.Lister       = &DNSProvider{}
+	_ dns.ZoneLister   = &DNSProvider{}
+	_ dns.HostResolver = StaticResolver{}
+)
+
+// DNSProvider is an in-memory DNS provider. The endpoints of each record
+// published are kept by zone, so tests can assert what would be published
+type DNSProvider struct {
+	mu sync.Mutex
+	// domain -> zone
+	zones map[string]v1.DNSZone
+	// zone ID -> record name -> endpoints
+	records map[string]map[string][]*v1.Endpoint
+	// Err is returned by Ensure and Delete when set, to test provider
+	// failures
+	Err error
+}
+
+func NewDNSProvider() *DNSProvider {
+	return &DNSProvider{zones: map[string]v1.DNSZone{}, records: map[string]map[string][]*v1.Endpoint{}}
+}
+
+// AddZone adds a hosted zone for the domain
+func (p *DNSProvider) AddZone(domain, id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.zones[domain] = v1.DNSZone{ID: id}
+}
+
+func (p *DNSProvider) Ensure(_ context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	if p.Err != nil {
+		return p.Err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.records[zone.ID]; !ok {
+		p.records[zone.ID] = map[string][]*v1.Endpoint{}
+	}
+	p.records[zone.ID][record.Name] = copyEndpoints(record.PublishedEndpoints())
+	return nil
+}
+
+func (p *DNSProvider) Delete(_ context.Context, record *v1.DNSRecord, zone v1.DNSZone) error {
+	if p.Err != nil {
+		return p.Err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.records[zone.ID], record.Name)
+	return nil
+}
+
+// Verify returns the endpoints of the record that differ from the endpoints
+// published
+func (p *DNSProvider) Verify(_ context.Context, record *v1.DNSRecord, zone v1.DNSZone) ([]*v1.Endpoint, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	published := map[string]*v1.Endpoint{}
+	for _, endpoint := range p.records[zone.ID][record.Name] {
+		published[endpoint.SetID()] = endpoint
+	}
+	var drifted []*v1.Endpoint
+	for _, endpoint := range record.PublishedEndpoints() {
+		if !reflect.DeepEqual(published[endpoint.SetID()], endpoint) {
+			drifted = append(drifted, endpoint)
+		}
+	}
+	return drifted, nil
+}
+
+func (p *DNSProvider) Zones() (map[string]v1.DNSZone, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	zones := make(map[string]v1.DNSZone, len(p.zones))
+	for domain, zone := range p.zones {
+		zones[domain] = zone
+	}
+	return zones, nil
+}
+
+func (p *DNSProvider) Records(zone v1.DNSZone, name string) ([]*v1.Endpoint, error) {
+	return p.Published(zone.ID, name), nil
+}
+
+func (p *DNSProvider) ZoneRecords(_ context.Context, zone v1.DNSZone) ([]*v1.Endpoint, error) {
+	var endpoints []*v1.Endpoint
+	for _, name := range p.PublishedNames(zone.ID) {
+		endpoints = append(endpoints, p.Published(zone.ID, name)...)
+	}
+	return endpoints, nil
+}
+
+// Published returns the endpoints published for the record in the zone
+func (p *DNSProvider) Published(zoneID, name string) []*v1.Endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return copyEndpoints(p.records[zoneID][name])
+}
+
+// PublishedNames returns the names of the records published in the zone
+func (p *DNSProvider) PublishedNames(zoneID string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.records[zoneID]))
+	for name := range p.records[zoneID] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func copyEndpoints(endpoints []*v1.Endpoint) []*v1.Endpoint {
+	copied := make([]*v1.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		copied = append(copied, endpoint.DeepCopy())
+	}
+	return copied
+}
+
+// StaticResolver resolves hostnames to fixed addresses, standing in for the
+// DNS lookups of the load balancer hostnames of traffic objects
+type StaticResolver map[string][]string
+
+func (r StaticResolver) LookupIPAddr(_ context.Context, host string) ([]dns.HostAddress, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, dns.NoSuchHost
+	}
+	addresses := make([]dns.HostAddress, 0, len(ips))
+	for _, ip := range ips {
+		addresses = append(addresses, dns.HostAddress{Host: host, IP: net.ParseIP(ip)})
+	}
+	return addresses, nil
+}
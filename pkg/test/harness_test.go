@@ -0,0 +1,528 @@
+package test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/traffic"
+)
+
+func testIngress(ip string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: "test.example.com"}},
+		},
+		Status: networkingv1.IngressStatus{
+			LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+				Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: ip}},
+			},
+		},
+	}
+}
+
+func TestTrafficAcrossClusters(t *testing.T) {
+	ctx := context.Background()
+	store := config.NewStore(config.Config{CertificateAuthorities: []string{"letsencrypt.org"}})
+	hosts := NewHostService(Scheme(), "mctc.example.com", "argocd", store)
+	certificates := NewCertificateService("argocd")
+	clusters := NewClusters(Scheme())
+
+	ips := map[string]string{"cluster-a": "1.1.1.1", "cluster-b": "2.2.2.2"}
+	ingresses := map[string]traffic.Interface{}
+	for _, cluster := range []string{"cluster-a", "cluster-b"} {
+		factory := clusters.HandlerFactory(hosts, certificates, store)
+		handler, err := factory(cluster, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		// the second reconcile applies the copied TLS secret again
+		ingress := traffic.NewIngressForCluster(testIngress(ips[cluster]), cluster)
+		for i := 0; i < 2; i++ {
+			if _, err := handler.Handle(ctx, ingress); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+		}
+		ingresses[cluster] = ingress
+	}
+
+	host, ok := hosts.ManagedHost("default", "test")
+	if !ok {
+		t.Fatalf("expected a managed host to be assigned")
+	}
+	if !certificates.Ensured(host) {
+		t.Errorf("expected a certificate to be ensured for '%v'", host)
+	}
+	if !hosts.CAAEnsured(host) {
+		t.Errorf("expected CAA records to be ensured for '%v'", host)
+	}
+	if clusters := hosts.Clusters(host); len(clusters) != 2 {
+		t.Errorf("expected '%v' got '%v'", 2, len(clusters))
+	}
+	for cluster := range ips {
+		secret := &corev1.Secret{}
+		if err := clusters.Client(cluster).Get(ctx, client.ObjectKey{Namespace: "default", Name: host}, secret); err != nil {
+			t.Errorf("expected the TLS secret to be copied to %s: %v", cluster, err)
+		}
+	}
+
+	// withdrawing a cluster keeps the endpoints of the other
+	if err := hosts.WithdrawEndpoints(ctx, ingresses["cluster-a"]); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if clusters := hosts.Clusters(host); len(clusters) != 1 || clusters[0] != "cluster-b" {
+		t.Errorf("expected '%v' got '%v'", []string{"cluster-b"}, clusters)
+	}
+}
+
+func TestCertificatePending(t *testing.T) {
+	ctx := context.Background()
+	store := config.NewStore(config.Config{})
+	hosts := NewHostService(Scheme(), "mctc.example.com", "argocd", store)
+	certificates := NewCertificateService("argocd")
+	certificates.Pending = true
+	clusters := NewClusters(Scheme())
+
+	handler := clusters.Handler("cluster-a", hosts, certificates, store)
+	result, err := handler.Handle(ctx, traffic.NewIngressForCluster(testIngress("1.1.1.1"), "cluster-a"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !result.Requeue {
+		t.Errorf("expected a requeue while the certificate is pending")
+	}
+	host, _ := hosts.ManagedHost("default", "test")
+	if clusters := hosts.Clusters(host); len(clusters) != 0 {
+		t.Errorf("expected no endpoints before the certificate is issued, got '%v'", clusters)
+	}
+}
+
+func TestCertificateIssuanceFailed(t *testing.T) {
+	ctx := context.Background()
+	store := config.NewStore(config.Config{})
+	hosts := NewHostService(Scheme(), "mctc.example.com", "argocd", store)
+	certificates := NewCertificateService("argocd")
+	certificates.Pending = true
+	certificates.Failed = true
+	clusters := NewClusters(Scheme())
+
+	handler := clusters.Handler("cluster-a", hosts, certificates, store)
+	result, err := handler.Handle(ctx, traffic.NewIngressForCluster(testIngress("1.1.1.1"), "cluster-a"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.RequeueAfter != tls.IssuanceRetryInterval {
+		t.Errorf("expected '%v' got '%v'", tls.IssuanceRetryInterval, result.RequeueAfter)
+	}
+}
+
+func TestPausedTrafficDeleted(t *testing.T) {
+	ctx := context.Background()
+	store := config.NewStore(config.Config{})
+	hosts := NewHostService(Scheme(), "mctc.example.com", "argocd", store)
+	clusters := NewClusters(Scheme())
+	handler := clusters.Handler("cluster-a", hosts, NewCertificateService("argocd"), store)
+	ingress := testIngress("1.1.1.1")
+	if _, err := handler.Handle(ctx, traffic.NewIngressForCluster(ingress, "cluster-a")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	host, _ := hosts.ManagedHost("default", "test")
+	if clusters := hosts.Clusters(host); len(clusters) != 1 {
+		t.Fatalf("expected '%v' got '%v'", 1, len(clusters))
+	}
+
+	// a paused ingress still has its endpoints removed when deleted
+	ingress.Annotations = map[string]string{metadata.AnnotationPaused: "true"}
+	ingress.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	ingress.Finalizers = []string{"kuadrant.io/traffic-management"}
+	deleted := traffic.NewIngressForCluster(ingress, "cluster-a")
+	if _, err := handler.Handle(ctx, deleted); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if clusters := hosts.Clusters(host); len(clusters) != 0 {
+		t.Errorf("expected no endpoints got '%v'", clusters)
+	}
+	if finalizers := deleted.GetFinalizers(); len(finalizers) != 0 {
+		t.Errorf("expected the finalizer removed got '%v'", finalizers)
+	}
+}
+
+func TestDNSDisabled(t *testing.T) {
+	ctx := context.Background()
+	store := config.NewStore(config.Config{})
+	hosts := NewHostService(Scheme(), "mctc.example.com", "argocd", store)
+	certificates := NewCertificateService("argocd")
+	clusters := NewClusters(Scheme())
+	handler := clusters.Handler("cluster-a", hosts, certificates, store)
+	ingress := testIngress("1.1.1.1")
+	if _, err := handler.Handle(ctx, traffic.NewIngressForCluster(ingress.DeepCopy(), "cluster-a")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	host, _ := hosts.ManagedHost("default", "test")
+	if clusters := hosts.Clusters(host); len(clusters) != 1 {
+		t.Fatalf("expected '%v' got '%v'", 1, len(clusters))
+	}
+
+	// disabling DNS withdraws the endpoints already published, and keeps
+	// the certificate managed
+	ingress.Annotations = map[string]string{traffic.AnnotationDNS: traffic.AnnotationValueDisabled}
+	if _, err := handler.Handle(ctx, traffic.NewIngressForCluster(ingress.DeepCopy(), "cluster-a")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if clusters := hosts.Clusters(host); len(clusters) != 0 {
+		t.Errorf("expected no endpoints got '%v'", clusters)
+	}
+	if !certificates.Ensured(host) {
+		t.Errorf("expected a certificate to be ensured for '%v'", host)
+	}
+}
+
+func TestPrivateTrafficFleetPolicy(t *testing.T) {
+	backendIngress := func(name, port string, private bool) *networkingv1.Ingress {
+		ingress := testIngress("1.1.1.1")
+		ingress.Name = name
+		ingress.Spec.Rules[0].HTTP = &networkingv1.HTTPIngressRuleValue{Paths: []networkingv1.HTTPIngressPath{{
+			Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+				Name: "api",
+				Port: networkingv1.ServiceBackendPort{Name: port},
+			}},
+		}}}
+		if private {
+			ingress.Annotations = map[string]string{traffic.AnnotationVisibility: traffic.VisibilityPrivate}
+		}
+		return ingress
+	}
+
+	tests := []struct {
+		name      string
+		ingresses []*networkingv1.Ingress
+		// deleted is deleted after the ingresses are reconciled
+		deleted *networkingv1.Ingress
+		// expectRestricted are the target ports restricted to the fleet,
+		// none when the policy isn't applied
+		expectRestricted []string
+		expectOpen       []string
+		expectOwners     string
+	}{
+		{
+			name:             "private ingress",
+			ingresses:        []*networkingv1.Ingress{backendIngress("internal", "grpc", true)},
+			expectRestricted: []string{"9090"},
+			expectOpen:       []string{"8080"},
+			expectOwners:     "Ingress/default/internal",
+		},
+		{
+			name:      "public ingress sharing the port",
+			ingresses: []*networkingv1.Ingress{backendIngress("internal", "grpc", true), backendIngress("public", "grpc", false)},
+		},
+		{
+			name:             "public ingress routing to another port",
+			ingresses:        []*networkingv1.Ingress{backendIngress("internal", "grpc", true), backendIngress("public", "http", false)},
+			expectRestricted: []string{"9090"},
+			expectOpen:       []string{"8080"},
+			expectOwners:     "Ingress/default/internal",
+		},
+		{
+			name:             "private ingresses sharing the service",
+			ingresses:        []*networkingv1.Ingress{backendIngress("internal", "grpc", true), backendIngress("other", "http", true)},
+			expectRestricted: []string{"8080", "9090"},
+			expectOwners:     "Ingress/default/internal,Ingress/default/other",
+		},
+		{
+			name:             "private ingress sharing the service deleted",
+			ingresses:        []*networkingv1.Ingress{backendIngress("internal", "grpc", true), backendIngress("other", "http", true)},
+			deleted:          backendIngress("other", "http", true),
+			expectRestricted: []string{"9090"},
+			expectOpen:       []string{"8080"},
+			expectOwners:     "Ingress/default/internal",
+		},
+		{
+			name:      "ingress not private anymore",
+			ingresses: []*networkingv1.Ingress{backendIngress("internal", "grpc", true), backendIngress("internal", "grpc", false)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clusters := NewClusters(Scheme())
+			workload := clusters.Client("cluster-a")
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+				Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "api"}, ClusterIP: "10.96.0.10", Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+					{Name: "grpc", Port: 90, TargetPort: intstr.FromInt(9090)},
+				}},
+			}
+			if err := workload.Create(ctx, service); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			store := config.NewStore(config.Config{FleetCIDRs: []string{"10.244.0.0/16"}, PrivateZone: PrivateZone})
+			handler := clusters.Handler("cluster-a", NewHostService(Scheme(), "mctc.example.com", "argocd", store), NewCertificateService("argocd"), store)
+			reconcile := func(ingress *networkingv1.Ingress) {
+				existing := &networkingv1.Ingress{}
+				if err := workload.Get(ctx, client.ObjectKeyFromObject(ingress), existing); err == nil {
+					ingress.ResourceVersion = existing.ResourceVersion
+					if err := workload.Update(ctx, ingress); err != nil {
+						t.Fatalf("unexpected error %v", err)
+					}
+				} else if err := workload.Create(ctx, ingress); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if _, err := handler.Handle(ctx, traffic.NewIngressForCluster(ingress.DeepCopy(), "cluster-a")); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			for _, ingress := range tt.ingresses {
+				reconcile(ingress.DeepCopy())
+			}
+			if tt.deleted != nil {
+				// the deleted ingress is still listed until its finalizer
+				// is removed
+				tt.deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				tt.deleted.Finalizers = []string{"kuadrant.io/traffic-management"}
+				if _, err := handler.Handle(ctx, traffic.NewIngressForCluster(tt.deleted, "cluster-a")); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+
+			policy := &networkingv1.NetworkPolicy{}
+			err := workload.Get(ctx, client.ObjectKey{Namespace: "default", Name: "kuadrant-fleet-api"}, policy)
+			if len(tt.expectRestricted) == 0 {
+				if !k8serrors.IsNotFound(err) {
+					t.Errorf("expected no fleet policy got '%v'", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the fleet policy to be applied: %v", err)
+			}
+			ports := func(rule networkingv1.NetworkPolicyIngressRule) []string {
+				ports := []string{}
+				for _, port := range rule.Ports {
+					ports = append(ports, port.Port.String())
+				}
+				sort.Strings(ports)
+				return ports
+			}
+			if got := ports(policy.Spec.Ingress[0]); !reflect.DeepEqual(got, tt.expectRestricted) {
+				t.Errorf("expected '%v' got '%v'", tt.expectRestricted, got)
+			}
+			if got := policy.Spec.Ingress[0].From[0].IPBlock.CIDR; got != "10.244.0.0/16" {
+				t.Errorf("expected '%v' got '%v'", "10.244.0.0/16", got)
+			}
+			if len(tt.expectOpen) > 0 {
+				if len(policy.Spec.Ingress) != 2 || len(policy.Spec.Ingress[1].From) != 0 || !reflect.DeepEqual(ports(policy.Spec.Ingress[1]), tt.expectOpen) {
+					t.Errorf("expected '%v' open got '%v'", tt.expectOpen, policy.Spec.Ingress)
+				}
+			} else if len(policy.Spec.Ingress) != 1 {
+				t.Errorf("expected no open ports got '%v'", policy.Spec.Ingress)
+			}
+			if got := policy.Annotations["kuadrant.io/traffic-owners"]; got != tt.expectOwners {
+				t.Errorf("expected '%v' got '%v'", tt.expectOwners, got)
+			}
+			if got := policy.Spec.PodSelector.MatchLabels["app"]; got != "api" {
+				t.Errorf("expected '%v' got '%v'", "api", got)
+			}
+		})
+	}
+}
+
+func TestDNSProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := NewDNSProvider()
+	provider.AddZone("example.com", "zone")
+	zone := v1.DNSZone{ID: "zone"}
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: "test.example.com"},
+		Spec: v1.DNSRecordSpec{Endpoints: []*v1.Endpoint{
+			{DNSName: "test.example.com", Targets: v1.Targets{"1.1.1.1"}, RecordType: "A", SetIdentifier: "1.1.1.1"},
+		}},
+	}
+
+	drifted, err := provider.Verify(ctx, record, zone)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(drifted) != 1 {
+		t.Errorf("expected '%v' got '%v'", 1, len(drifted))
+	}
+	if err := provider.Ensure(ctx, record, zone); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	drifted, err = provider.Verify(ctx, record, zone)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(drifted) != 0 {
+		t.Errorf("expected '%v' got '%v'", 0, len(drifted))
+	}
+	if names := provider.PublishedNames("zone"); len(names) != 1 || names[0] != "test.example.com" {
+		t.Errorf("expected '%v' got '%v'", []string{"test.example.com"}, names)
+	}
+	if err := provider.Delete(ctx, record, zone); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if published := provider.Published("zone", "test.example.com"); len(published) != 0 {
+		t.Errorf("expected '%v' got '%v'", 0, len(published))
+	}
+}
+
+func TestPrivateTrafficMeshTargets(t *testing.T) {
+	mcs := func(kind string, ips ...string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "multicluster.x-k8s.io", Version: "v1alpha1", Kind: kind})
+		obj.SetNamespace("default")
+		obj.SetName("api")
+		if len(ips) > 0 {
+			utilruntime.Must(unstructured.SetNestedStringSlice(obj.Object, ips, "spec", "ips"))
+		}
+		return obj
+	}
+
+	tests := []struct {
+		name string
+		// ip is the address of the load balancer of the ingress
+		ip string
+		// exported are the MCS objects of the backend service
+		exported []client.Object
+		// expectTargets are the targets published in the private zone
+		expectTargets []string
+		expectImport  bool
+		expectFlagged bool
+	}{
+		{
+			name:          "cluster set IPs of the exported backend preferred",
+			ip:            "10.0.0.5",
+			exported:      []client.Object{mcs("ServiceExport"), mcs("ServiceImport", "242.1.0.1")},
+			expectTargets: []string{"242.1.0.1"},
+			expectImport:  true,
+		},
+		{
+			name:          "ServiceImport created for the exported backend",
+			ip:            "10.0.0.5",
+			exported:      []client.Object{mcs("ServiceExport")},
+			expectTargets: []string{"10.0.0.5"},
+			expectImport:  true,
+		},
+		{
+			name:          "private address of the load balancer",
+			ip:            "10.0.0.5",
+			expectTargets: []string{"10.0.0.5"},
+		},
+		{
+			name:          "no private address",
+			ip:            "1.1.1.1",
+			expectTargets: []string{},
+			expectFlagged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clusters := NewClusters(Scheme())
+			workload := clusters.Client("cluster-a")
+			objects := append([]client.Object{&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+				Spec: corev1.ServiceSpec{ClusterIP: "10.96.0.10", Ports: []corev1.ServicePort{
+					{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP},
+				}},
+			}}, tt.exported...)
+			for _, obj := range objects {
+				if err := workload.Create(ctx, obj); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			store := config.NewStore(config.Config{PrivateZone: PrivateZone})
+			hosts := NewHostService(Scheme(), "mctc.example.com", "argocd", store)
+			handler := clusters.Handler("cluster-a", hosts, NewCertificateService("argocd"), store)
+			ingress := testIngress(tt.ip)
+			ingress.Annotations = map[string]string{traffic.AnnotationVisibility: traffic.VisibilityPrivate}
+			ingress.Spec.Rules[0].HTTP = &networkingv1.HTTPIngressRuleValue{Paths: []networkingv1.HTTPIngressPath{{
+				Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+					Name: "api",
+					Port: networkingv1.ServiceBackendPort{Name: "https"},
+				}},
+			}}}
+			if _, err := handler.Handle(ctx, traffic.NewIngressForCluster(ingress, "cluster-a")); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			host, ok := hosts.ManagedHost("default", "test")
+			if !ok {
+				t.Fatalf("expected a managed host to be assigned")
+			}
+			targets := []string{}
+			for _, endpoint := range hosts.Record(host).Spec.Endpoints {
+				if endpoint.DNSName == host && endpoint.RecordType == "A" {
+					targets = append(targets, endpoint.Targets...)
+				}
+			}
+			if !reflect.DeepEqual(targets, tt.expectTargets) {
+				t.Errorf("expected '%v' got '%v'", tt.expectTargets, targets)
+			}
+			serviceImport := mcs("ServiceImport")
+			err := workload.Get(ctx, client.ObjectKeyFromObject(serviceImport), serviceImport)
+			if imported := err == nil; imported != tt.expectImport {
+				t.Errorf("expected ServiceImport '%v' got '%v'", tt.expectImport, err)
+			}
+			if _, flagged := ingress.Annotations[traffic.AnnotationNoPrivateAddresses]; flagged != tt.expectFlagged {
+				t.Errorf("expected flagged '%v' got '%v'", tt.expectFlagged, ingress.Annotations)
+			}
+		})
+	}
+}
+
+func TestWildcardZoneTLS(t *testing.T) {
+	ctx := context.Background()
+	store := config.NewStore(config.Config{})
+	hosts := NewHostService(Scheme(), "mctc.example.com", "argocd", store)
+	zone := &v1.ManagedZone{}
+	if err := hosts.Client.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "default"}, zone); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	zone.Spec.Wildcard = true
+	if err := hosts.Client.Update(ctx, zone); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	certificates := NewCertificateService("argocd")
+	clusters := NewClusters(Scheme())
+	handler := clusters.Handler("cluster-a", hosts, certificates, store)
+	ingress := testIngress("1.1.1.1")
+	if _, err := handler.Handle(ctx, traffic.NewIngressForCluster(ingress, "cluster-a")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	host, _ := hosts.ManagedHost("default", "test")
+	if !certificates.Ensured("*.mctc.example.com") || certificates.Ensured(host) {
+		t.Errorf("expected the wildcard certificate of the zone to be ensured for '%v'", host)
+	}
+	// the TLS configuration points at the copy of the wildcard secret
+	// named after the managed host
+	secret := &corev1.Secret{}
+	if err := clusters.Client("cluster-a").Get(ctx, client.ObjectKey{Namespace: "default", Name: host}, secret); err != nil {
+		t.Errorf("expected the TLS secret to be copied: %v", err)
+	}
+	expected := []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: host}}
+	if !reflect.DeepEqual(ingress.Spec.TLS, expected) {
+		t.Errorf("expected '%v' got '%v'", expected, ingress.Spec.TLS)
+	}
+}
@@ -0,0 +1,120 @@
+package test
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/controllers/traffic"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+var _ traffic.HostService = &HostService{}
+
+// PrivateZone is the name of the private ManagedZone of the host service,
+// for the private zone of the config
+const PrivateZone = "private"
+
+// HostService is the host service of the controller, backed by a fake
+// control plane client holding a ManagedZone of the domain marked as the
+// default zone and a private ManagedZone named PrivateZone, so the hosts and
+// endpoints are managed exactly as the controller manages them. The DNSRecords aren't published, the DNSRecord
+// controller publishes them, so tests assert on the DNSRecords of the hosts
+type HostService struct {
+	*dns.Service
+	// Client is the fake control plane client
+	Client client.Client
+	// Namespace is the controller namespace, holding the DNSRecords and
+	// ManagedHosts
+	Namespace string
+}
+
+func NewHostService(scheme *runtime.Scheme, domain, namespace string, store *config.Store) *HostService {
+	// the owner references of the ManagedHosts are set from the global
+	// scheme, as main does
+	utilruntime.Must(v1.AddToScheme(clientgoscheme.Scheme))
+	zone := &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "default",
+			Namespace:   namespace,
+			Annotations: map[string]string{dns.AnnotationDefaultZone: "true"},
+		},
+		Spec: v1.ManagedZoneSpec{ID: "default", DomainName: domain},
+	}
+	private := &v1.ManagedZone{
+		ObjectMeta: metav1.ObjectMeta{Name: PrivateZone, Namespace: namespace},
+		Spec:       v1.ManagedZoneSpec{ID: PrivateZone, DomainName: "internal." + domain, Visibility: v1.ZoneVisibilityPrivate},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(zone, private).Build()
+	return &HostService{
+		Service:   dns.NewService(c, nil, namespace, store),
+		Client:    c,
+		Namespace: namespace,
+	}
+}
+
+// Record returns the DNSRecord of the managed host, or nil when the host
+// isn't assigned or its record was deleted
+func (s *HostService) Record(host string) *v1.DNSRecord {
+	record := &v1.DNSRecord{}
+	if err := s.Client.Get(context.Background(), client.ObjectKey{Namespace: s.Namespace, Name: host}, record); err != nil {
+		return nil
+	}
+	return record
+}
+
+// Clusters returns the clusters the record of the managed host has
+// endpoints for, none once its record is deleted
+func (s *HostService) Clusters(host string) []string {
+	record := s.Record(host)
+	if record == nil {
+		return []string{}
+	}
+	return dns.EndpointClusters(record)
+}
+
+// ManagedHost returns the managed host assigned to the traffic object
+func (s *HostService) ManagedHost(namespace, name string) (string, bool) {
+	managedHosts := &v1.ManagedHostList{}
+	if err := s.Client.List(context.Background(), managedHosts, client.InNamespace(s.Namespace)); err != nil {
+		return "", false
+	}
+	for _, managedHost := range managedHosts.Items {
+		ref := managedHost.Spec.TrafficRef
+		if ref.Namespace == namespace && ref.Name == name {
+			return managedHost.Spec.Host, true
+		}
+	}
+	return "", false
+}
+
+// CAAEnsured returns true when a CAA record is published for the host
+func (s *HostService) CAAEnsured(host string) bool {
+	record := s.Record(host)
+	if record == nil {
+		return false
+	}
+	for _, raw := range record.Spec.RawRecords {
+		if raw.RecordType == string(v1.CAARecordType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Synced returns the version of the TLS secret of the host synced to the
+// cluster
+func (s *HostService) Synced(host, cluster string) string {
+	managedHost := &v1.ManagedHost{}
+	if err := s.Client.Get(context.Background(), client.ObjectKey{Namespace: s.Namespace, Name: host}, managedHost); err != nil {
+		return ""
+	}
+	return dns.SyncedSecrets(managedHost)[cluster]
+}
@@ -0,0 +1,474 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/logging"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/tls"
+)
+
+const (
+	// AccountSecretName is the secret of the controller namespace holding
+	// the key of the ACME account
+	AccountSecretName = "mctc-acme-account"
+	// LabelIssuedBy set to IssuerName marks the TLS secrets of the
+	// certificates issued by the built-in ACME client
+	LabelIssuedBy = "kuadrant.io/issued-by"
+	IssuerName    = "builtin-acme"
+	// endpointLabelChallenge marks the TXT records answering DNS-01
+	// challenges among the raw records of a DNSRecord
+	endpointLabelChallenge = "kuadrant.io/acme-challenge"
+
+	// renewBefore is how long before their expiry the certificates are
+	// renewed, as cert-manager renews the certificates of the controller
+	renewBefore = 15 * 24 * time.Hour
+	// issuanceTimeout bounds the issuance of a certificate
+	issuanceTimeout = 15 * time.Minute
+	// cleanupTimeout bounds the removal of the challenge records once an
+	// issuance ends, including when it's cancelled by the shutdown
+	cleanupTimeout = 30 * time.Second
+	// issuanceWorkers is the number of certificates issued concurrently
+	issuanceWorkers = 5
+)
+
+// pollInterval is how often the DNSRecord of the challenges is checked
+// while waiting for it to be published
+var pollInterval = 5 * time.Second
+
+// PropagationDelay is how long the TXT record of a challenge is given to
+// propagate once published, before the challenge is validated
+var PropagationDelay = time.Minute
+
+// Service issues the certificates of the managed hosts with the built-in
+// ACME client, answering DNS-01 challenges with TXT records published in
+// the DNSRecord of the host. It replaces the cert-manager certificates of
+// the tls.Service when cert-manager can't be installed: the certificates
+// are stored in secrets named as the secrets of cert-manager, and renewed
+// when they're ensured close to their expiry. The certificates are issued
+// by the workers of the service, a manager Runnable stopped with the
+// manager
+type Service struct {
+	controlClient client.Client
+	defaultCtrlNS string
+	directoryURL  string
+	email         string
+
+	accountMu sync.Mutex
+	acme      *acme.Client
+
+	queue workqueue.Interface
+
+	mu      sync.Mutex
+	issuing map[string]bool
+	failed  map[string]time.Time
+}
+
+// issuance is the issuance of the certificate of a host, answering its
+// challenges in a DNSRecord
+type issuance struct {
+	host      string
+	recordKey client.ObjectKey
+}
+
+func NewService(controlClient client.Client, defaultCtrlNS, directoryURL, email string) *Service {
+	return &Service{
+		controlClient: controlClient,
+		defaultCtrlNS: defaultCtrlNS,
+		directoryURL:  directoryURL,
+		email:         email,
+		queue:         workqueue.New(),
+		issuing:       map[string]bool{},
+		failed:        map[string]time.Time{},
+	}
+}
+
+// Start issues the certificates queued by EnsureCertificate until the
+// context is done, cancelling the issuances in progress
+func (s *Service) Start(ctx context.Context) error {
+	ctx = logging.IntoContext(ctx, logging.TLS)
+	go func() {
+		<-ctx.Done()
+		s.queue.ShutDown()
+	}()
+	wg := sync.WaitGroup{}
+	for i := 0; i < issuanceWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s.processNext(ctx) {
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *Service) processNext(ctx context.Context) bool {
+	item, shutdown := s.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer s.queue.Done(item)
+	s.issue(ctx, item.(issuance))
+	return true
+}
+
+// EnsureCertificate queues the issuance of the certificate of the host
+// unless it's issued and not due for renewal, or its last issuance failed
+// less than tls.IssuanceRetryInterval ago. The owner must be the DNSRecord
+// the challenges of the host are answered in, and owns the secret of the
+// certificate
+func (s *Service) EnsureCertificate(ctx context.Context, host string, owner metav1.Object) error {
+	record, ok := owner.(*v1.DNSRecord)
+	if !ok {
+		return fmt.Errorf("the certificates issued by the built-in ACME client must be owned by the DNSRecord of the host")
+	}
+	secret, err := s.GetCertificateSecret(ctx, host)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if secret != nil && (tls.Imported(secret) || !dueForRenewal(secret)) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.issuing[host] {
+		return nil
+	}
+	if failed, ok := s.failed[host]; ok && time.Since(failed) < tls.IssuanceRetryInterval {
+		return nil
+	}
+	s.issuing[host] = true
+	s.queue.Add(issuance{host: host, recordKey: client.ObjectKeyFromObject(record)})
+	return nil
+}
+
+func (s *Service) GetCertificateSecret(ctx context.Context, host string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: tls.CertificateName(host)}, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// RecordContentHash records the content hash of the TLS secret of a host on
+// the secret, see tls.RecordContentHash
+func (s *Service) RecordContentHash(ctx context.Context, secret *corev1.Secret) (string, error) {
+	return tls.RecordContentHash(ctx, s.controlClient, secret)
+}
+
+// IssuanceFailed returns true when the last issuance of the certificate of
+// the host failed
+func (s *Service) IssuanceFailed(_ context.Context, host string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, failed := s.failed[host]
+	return failed, nil
+}
+
+func (s *Service) issue(ctx context.Context, i issuance) {
+	logger := log.FromContext(ctx).WithValues("host", i.host)
+	logger.Info("issuing certificate with the built-in ACME client")
+
+	issueCtx, cancel := context.WithTimeout(ctx, issuanceTimeout)
+	err := s.issueCertificate(issueCtx, i.host, i.recordKey)
+	cancel()
+	// the challenge records are removed even when the issuance was
+	// cancelled by the shutdown, so they're not left published
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	if cleanupErr := s.setChallenges(cleanupCtx, i.recordKey, nil); cleanupErr != nil {
+		logger.Error(cleanupErr, "failed to remove the ACME challenge records")
+	}
+	cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.issuing, i.host)
+	if err != nil {
+		if ctx.Err() != nil {
+			logger.Info("certificate issuance cancelled")
+			return
+		}
+		logger.Error(err, "certificate issuance failed", "retryAfter", tls.IssuanceRetryInterval)
+		s.failed[i.host] = time.Now()
+		return
+	}
+	logger.Info("certificate issued")
+	delete(s.failed, i.host)
+}
+
+func (s *Service) issueCertificate(ctx context.Context, host string, recordKey client.ObjectKey) error {
+	c, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	order, err := c.AuthorizeOrder(ctx, acme.DomainIDs(host))
+	if err != nil {
+		return err
+	}
+
+	challenges := map[string]string{}
+	accepted := []*acme.Challenge{}
+	for _, url := range order.AuthzURLs {
+		authz, err := c.GetAuthorization(ctx, url)
+		if err != nil {
+			return err
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+		challenge, ok := dns01(authz)
+		if !ok {
+			return fmt.Errorf("no DNS-01 challenge offered for %s", authz.Identifier.Value)
+		}
+		value, err := c.DNS01ChallengeRecord(challenge.Token)
+		if err != nil {
+			return err
+		}
+		challenges["_acme-challenge."+authz.Identifier.Value] = value
+		accepted = append(accepted, challenge)
+	}
+	if len(accepted) > 0 {
+		if err := s.setChallenges(ctx, recordKey, challenges); err != nil {
+			return err
+		}
+		if err := s.waitPublished(ctx, recordKey); err != nil {
+			return err
+		}
+		if err := sleep(ctx, PropagationDelay); err != nil {
+			return err
+		}
+		for _, challenge := range accepted {
+			if _, err := c.Accept(ctx, challenge); err != nil {
+				return err
+			}
+		}
+		for _, url := range order.AuthzURLs {
+			if _, err := c.WaitAuthorization(ctx, url); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := c.WaitOrder(ctx, order.URI); err != nil {
+		return err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	// the host isn't set as the common name, limited to 64 characters
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		DNSNames: []string{host},
+	}, key)
+	if err != nil {
+		return err
+	}
+	der, _, err := c.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return err
+	}
+	chain := []byte{}
+	for _, certificate := range der {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})...)
+	}
+	return s.storeCertificate(ctx, host, recordKey, chain, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+}
+
+// client returns the ACME client registered with the account key of the
+// controller namespace, generating the key when it doesn't exist
+func (s *Service) client(ctx context.Context) (*acme.Client, error) {
+	s.accountMu.Lock()
+	defer s.accountMu.Unlock()
+	if s.acme != nil {
+		return s.acme, nil
+	}
+	key, err := s.accountKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := &acme.Client{DirectoryURL: s.directoryURL, Key: key}
+	account := &acme.Account{}
+	if s.email != "" {
+		account.Contact = []string{"mailto:" + s.email}
+	}
+	if _, err := c.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, err
+	}
+	s.acme = c
+	return c, nil
+}
+
+func (s *Service) accountKey(ctx context.Context) (*ecdsa.PrivateKey, error) {
+	secret := &corev1.Secret{}
+	err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: AccountSecretName}, secret)
+	if err == nil {
+		block, _ := pem.Decode(secret.Data[corev1.TLSPrivateKeyKey])
+		if block == nil {
+			return nil, fmt.Errorf("secret %s holds no ACME account key", AccountSecretName)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: AccountSecretName, Namespace: s.defaultCtrlNS},
+		Data: map[string][]byte{
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		},
+	}
+	if err := s.controlClient.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// setChallenges replaces the TXT records answering the challenges in the
+// raw records of the DNSRecord, removing them when there are none
+func (s *Service) setChallenges(ctx context.Context, recordKey client.ObjectKey, challenges map[string]string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		record := &v1.DNSRecord{}
+		if err := s.controlClient.Get(ctx, recordKey, record); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		raw := []*v1.Endpoint{}
+		removed := false
+		for _, endpoint := range record.Spec.RawRecords {
+			if endpoint.Labels[endpointLabelChallenge] == "true" {
+				removed = true
+				continue
+			}
+			raw = append(raw, endpoint)
+		}
+		if !removed && len(challenges) == 0 {
+			return nil
+		}
+		for name, value := range challenges {
+			raw = append(raw, &v1.Endpoint{
+				DNSName:    name,
+				Targets:    []string{value},
+				RecordType: string(v1.TXTRecordType),
+				RecordTTL:  60,
+				Labels:     v1.Labels{endpointLabelChallenge: "true"},
+			})
+		}
+		record.Spec.RawRecords = raw
+		return s.controlClient.Update(ctx, record)
+	})
+}
+
+// waitPublished waits for the DNSRecord to be published to its zones
+func (s *Service) waitPublished(ctx context.Context, recordKey client.ObjectKey) error {
+	for {
+		record := &v1.DNSRecord{}
+		if err := s.controlClient.Get(ctx, recordKey, record); err != nil {
+			return err
+		}
+		if record.Status.ObservedGeneration == record.Generation && len(record.Status.Zones) > 0 {
+			for _, zone := range record.Status.Zones {
+				for _, condition := range zone.Conditions {
+					if condition.Type == v1.DNSRecordFailedConditionType && condition.Status == string(metav1.ConditionTrue) {
+						return fmt.Errorf("publishing the ACME challenges to zone %s failed: %s", zone.DNSZone.ID, condition.Message)
+					}
+				}
+			}
+			return nil
+		}
+		if err := sleep(ctx, pollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Service) storeCertificate(ctx context.Context, host string, recordKey client.ObjectKey, chain, key []byte) error {
+	record := &v1.DNSRecord{}
+	if err := s.controlClient.Get(ctx, recordKey, record); err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tls.CertificateName(host),
+			Namespace: s.defaultCtrlNS,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, s.controlClient, secret, func() error {
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[LabelIssuedBy] = IssuerName
+		secret.Type = corev1.SecretTypeTLS
+		secret.Data = map[string][]byte{
+			corev1.TLSCertKey:       chain,
+			corev1.TLSPrivateKeyKey: key,
+		}
+		return controllerutil.SetOwnerReference(record, secret, s.controlClient.Scheme())
+	})
+	return err
+}
+
+// dns01 returns the DNS-01 challenge of the authorization
+func dns01(authz *acme.Authorization) (*acme.Challenge, bool) {
+	for _, challenge := range authz.Challenges {
+		if challenge.Type == "dns-01" {
+			return challenge, true
+		}
+	}
+	return nil, false
+}
+
+// dueForRenewal returns true when the certificate of the secret expires
+// within renewBefore, or can't be read
+func dueForRenewal(secret *corev1.Secret) bool {
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return true
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	return time.Until(certificate.NotAfter) < renewBefore
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,420 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+)
+
+// fakeDirectory is an ACME server validating the DNS-01 challenge of its
+// single order against the TXT records of the DNSRecord of the host, and
+// issuing the certificate of the order from a test CA once finalized
+type fakeDirectory struct {
+	t    *testing.T
+	url  string
+	host string
+	// records is the client of the DNSRecord and of the account key
+	records client.Client
+
+	mu        sync.Mutex
+	orders    int
+	accepted  bool
+	validated bool
+	finalized bool
+	chain     []byte
+}
+
+func (f *fakeDirectory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Replay-Nonce", "nonce")
+	if r.URL.Path == "/directory" {
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   f.url + "/nonce",
+			"newAccount": f.url + "/account",
+			"newOrder":   f.url + "/order",
+		})
+		return
+	}
+	if r.URL.Path == "/nonce" {
+		return
+	}
+	payload := f.payload(r)
+
+	authzStatus, orderStatus := acme.StatusPending, acme.StatusPending
+	switch {
+	case f.accepted && !f.validated:
+		authzStatus, orderStatus = acme.StatusInvalid, acme.StatusInvalid
+	case f.finalized:
+		authzStatus, orderStatus = acme.StatusValid, acme.StatusValid
+	case f.validated:
+		authzStatus, orderStatus = acme.StatusValid, acme.StatusReady
+	}
+	order := map[string]interface{}{
+		"status":         orderStatus,
+		"identifiers":    []map[string]string{{"type": "dns", "value": f.host}},
+		"authorizations": []string{f.url + "/authz/1"},
+		"finalize":       f.url + "/finalize/1",
+	}
+	switch r.URL.Path {
+	case "/account":
+		w.Header().Set("Location", f.url+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"status": acme.StatusValid})
+	case "/order":
+		f.orders++
+		w.Header().Set("Location", f.url+"/order/1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+	case "/order/1":
+		if f.finalized {
+			order["certificate"] = f.url + "/cert/1"
+		}
+		json.NewEncoder(w).Encode(order)
+	case "/authz/1":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     authzStatus,
+			"identifier": map[string]string{"type": "dns", "value": f.host},
+			"challenges": []map[string]string{{"type": "dns-01", "url": f.url + "/challenge/1", "token": "token", "status": authzStatus}},
+		})
+	case "/challenge/1":
+		f.accepted = true
+		f.validated = f.validate(r.Context())
+		json.NewEncoder(w).Encode(map[string]string{"type": "dns-01", "url": f.url + "/challenge/1", "token": "token", "status": acme.StatusProcessing})
+	case "/finalize/1":
+		csr := struct {
+			CSR string `json:"csr"`
+		}{}
+		if err := json.Unmarshal(payload, &csr); err != nil {
+			f.t.Errorf("unexpected error %v", err)
+		}
+		der, err := base64.RawURLEncoding.DecodeString(csr.CSR)
+		if err != nil {
+			f.t.Errorf("unexpected error %v", err)
+		}
+		f.chain = f.sign(der)
+		f.finalized = true
+		order["status"] = acme.StatusValid
+		order["certificate"] = f.url + "/cert/1"
+		w.Header().Set("Location", f.url+"/order/1")
+		json.NewEncoder(w).Encode(order)
+	case "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(f.chain)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// payload returns the payload of the JWS of the request
+func (f *fakeDirectory) payload(r *http.Request) []byte {
+	jws := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		f.t.Errorf("unexpected error %v", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(jws["payload"])
+	if err != nil {
+		f.t.Errorf("unexpected error %v", err)
+	}
+	return payload
+}
+
+// validate returns true when the DNSRecord of the host publishes the TXT
+// record answering the challenge for the account key
+func (f *fakeDirectory) validate(ctx context.Context) bool {
+	secret := &corev1.Secret{}
+	if err := f.records.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: AccountSecretName}, secret); err != nil {
+		f.t.Errorf("unexpected error %v", err)
+		return false
+	}
+	block, _ := pem.Decode(secret.Data[corev1.TLSPrivateKeyKey])
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		f.t.Errorf("unexpected error %v", err)
+		return false
+	}
+	expected, err := (&acme.Client{Key: key}).DNS01ChallengeRecord("token")
+	if err != nil {
+		f.t.Errorf("unexpected error %v", err)
+		return false
+	}
+	record := &v1.DNSRecord{}
+	if err := f.records.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: f.host}, record); err != nil {
+		f.t.Errorf("unexpected error %v", err)
+		return false
+	}
+	for _, endpoint := range record.Spec.RawRecords {
+		if endpoint.DNSName == "_acme-challenge."+f.host && endpoint.RecordType == string(v1.TXTRecordType) && endpoint.Targets[0] == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// sign returns the PEM encoded certificate of the request signed by a test
+// CA, followed by the certificate of the CA
+func (f *fakeDirectory) sign(der []byte) []byte {
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		f.t.Errorf("unexpected error %v", err)
+		return nil
+	}
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		f.t.Errorf("unexpected error %v", err)
+		return nil
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake ACME CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(90 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		f.t.Errorf("unexpected error %v", err)
+		return nil
+	}
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, csr.PublicKey, caKey)
+	if err != nil {
+		f.t.Errorf("unexpected error %v", err)
+		return nil
+	}
+	return append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+}
+
+func TestService(t *testing.T) {
+	pollInterval = time.Millisecond
+	PropagationDelay = 0
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+	host := "test.example.com"
+
+	cases := []struct {
+		name string
+		// answer answers the challenges in the DNSRecord of the host
+		answer       bool
+		expectIssued bool
+		expectFailed bool
+	}{
+		{
+			name:         "certificate issued once the challenge is validated",
+			answer:       true,
+			expectIssued: true,
+		},
+		{
+			name:         "issuance failed when the challenge isn't validated",
+			expectFailed: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			record := &v1.DNSRecord{
+				ObjectMeta: metav1.ObjectMeta{Name: host, Namespace: "argocd"},
+				Status:     v1.DNSRecordStatus{Zones: []v1.DNSZoneStatus{{DNSZone: v1.DNSZone{ID: "zone"}}}},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(record).Build()
+			directory := &fakeDirectory{t: t, host: host, records: c}
+			if !tc.answer {
+				directory.records = &unansweredClient{Client: c}
+			}
+			server := httptest.NewServer(directory)
+			defer server.Close()
+			directory.url = server.URL
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s := NewService(c, "argocd", server.URL+"/directory", "admin@example.com")
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				if err := s.Start(ctx); err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+			}()
+			if err := s.EnsureCertificate(ctx, host, record); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			waitIssuance(t, s, host)
+
+			secret, err := s.GetCertificateSecret(ctx, host)
+			if issued := err == nil; issued != tc.expectIssued {
+				t.Fatalf("expected '%v' got '%v'", tc.expectIssued, err)
+			}
+			if tc.expectIssued {
+				if secret.Labels[LabelIssuedBy] != IssuerName {
+					t.Errorf("expected '%v' got '%v'", IssuerName, secret.Labels[LabelIssuedBy])
+				}
+				block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+				certificate, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if len(certificate.DNSNames) != 1 || certificate.DNSNames[0] != host {
+					t.Errorf("expected '%v' got '%v'", []string{host}, certificate.DNSNames)
+				}
+				if dueForRenewal(secret) {
+					t.Errorf("expected the certificate not to be due for renewal")
+				}
+			}
+			failed, err := s.IssuanceFailed(ctx, host)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if failed != tc.expectFailed {
+				t.Errorf("expected '%v' got '%v'", tc.expectFailed, failed)
+			}
+			current := &v1.DNSRecord{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(record), current); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(current.Spec.RawRecords) != 0 {
+				t.Errorf("expected the challenge records removed got '%v'", current.Spec.RawRecords)
+			}
+
+			// the certificate isn't issued again while it's valid, or while
+			// its last issuance failed
+			if err := s.EnsureCertificate(ctx, host, record); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			directory.mu.Lock()
+			orders := directory.orders
+			directory.mu.Unlock()
+			if s.queue.Len() != 0 || orders != 1 {
+				t.Errorf("expected the certificate not to be issued again")
+			}
+
+			cancel()
+			select {
+			case <-stopped:
+			case <-time.After(10 * time.Second):
+				t.Fatalf("the service didn't stop")
+			}
+		})
+	}
+}
+
+func TestService_shutdown(t *testing.T) {
+	pollInterval = time.Millisecond
+	PropagationDelay = time.Hour
+	defer func() {
+		PropagationDelay = 0
+	}()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1.AddToScheme(scheme))
+	host := "test.example.com"
+	record := &v1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: host, Namespace: "argocd"},
+		Status:     v1.DNSRecordStatus{Zones: []v1.DNSZoneStatus{{DNSZone: v1.DNSZone{ID: "zone"}}}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(record).Build()
+	directory := &fakeDirectory{t: t, host: host, records: c}
+	server := httptest.NewServer(directory)
+	defer server.Close()
+	directory.url = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewService(c, "argocd", server.URL+"/directory", "")
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := s.Start(ctx); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	}()
+	if err := s.EnsureCertificate(ctx, host, record); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// the issuance waits for the challenge records to propagate
+	current := &v1.DNSRecord{}
+	for i := 0; len(current.Spec.RawRecords) == 0; i++ {
+		if i == 1000 {
+			t.Fatalf("the challenge records weren't published")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := c.Get(ctx, client.ObjectKeyFromObject(record), current); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the issuance wasn't cancelled by the shutdown")
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(record), current); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(current.Spec.RawRecords) != 0 {
+		t.Errorf("expected the challenge records removed got '%v'", current.Spec.RawRecords)
+	}
+	if failed, _ := s.IssuanceFailed(context.Background(), host); failed {
+		t.Errorf("expected the cancelled issuance not to be failed")
+	}
+}
+
+// unansweredClient reads the DNSRecords without their raw records, as if
+// the challenges weren't published
+type unansweredClient struct {
+	client.Client
+}
+
+func (c *unansweredClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.Client.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+	if record, ok := obj.(*v1.DNSRecord); ok {
+		record.Spec.RawRecords = nil
+	}
+	return nil
+}
+
+// waitIssuance waits for the issuance of the certificate of the host to end
+func waitIssuance(t *testing.T, s *Service, host string) {
+	for i := 0; i < 1000; i++ {
+		s.mu.Lock()
+		issuing := s.issuing[host]
+		s.mu.Unlock()
+		if !issuing {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the certificate of %s wasn't issued", host)
+}
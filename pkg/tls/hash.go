@@ -0,0 +1,61 @@
+package tls
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+)
+
+// AnnotationContentHash records on the TLS secrets of the control plane the
+// hash of their content, and on their copies in the workload clusters the
+// hash recorded on the secret they were copied from
+const AnnotationContentHash = "kuadrant.io/content-hash"
+
+// ContentHashMismatchErr is returned when the copy of a TLS secret doesn't
+// hold the certificate and key of the secret it was copied from
+var ContentHashMismatchErr = fmt.Errorf("tls secret content doesn't match its content hash")
+
+// ContentHash returns the hash of the certificate and key of the TLS
+// secret. Other keys, such as the ca.crt added by cert-manager, aren't
+// hashed as they're not served
+func ContentHash(secret *v1.Secret) string {
+	hash := sha256.New()
+	for _, key := range []string{v1.TLSCertKey, v1.TLSPrivateKeyKey} {
+		// the length prefix keeps the boundary between the keys unambiguous
+		fmt.Fprintf(hash, "%s:%d:", key, len(secret.Data[key]))
+		hash.Write(secret.Data[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// VerifyContentHash returns ContentHashMismatchErr when the secret doesn't
+// hold the content of the given hash
+func VerifyContentHash(secret *v1.Secret, hash string) error {
+	if actual := ContentHash(secret); actual != hash {
+		return fmt.Errorf("%w: secret %s/%s has hash %s, expected %s", ContentHashMismatchErr, secret.Namespace, secret.Name, actual, hash)
+	}
+	return nil
+}
+
+// RecordContentHash records the content hash of the TLS secret of the
+// control plane on the secret, when it isn't recorded yet or the secret was
+// renewed since, and returns it. The secret isn't recorded when it changed
+// since it was read, so the hash recorded is always the one of its content
+func RecordContentHash(ctx context.Context, c client.Client, secret *v1.Secret) (string, error) {
+	hash := ContentHash(secret)
+	if metadata.GetAnnotation(secret, AnnotationContentHash) == hash {
+		return hash, nil
+	}
+	patch := client.MergeFromWithOptions(secret.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	metadata.AddAnnotation(secret, AnnotationContentHash, hash)
+	if err := c.Patch(ctx, secret, patch); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
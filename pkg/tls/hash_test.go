@@ -0,0 +1,125 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestVerifyContentHash(t *testing.T) {
+	secret := &v1.Secret{Data: map[string][]byte{v1.TLSCertKey: []byte("cert"), v1.TLSPrivateKeyKey: []byte("key")}}
+	hash := ContentHash(secret)
+
+	cases := []struct {
+		Name   string
+		Data   map[string][]byte
+		Verify bool
+	}{
+		{
+			Name:   "test same content verified",
+			Data:   map[string][]byte{v1.TLSCertKey: []byte("cert"), v1.TLSPrivateKeyKey: []byte("key")},
+			Verify: true,
+		},
+		{
+			Name:   "test keys not served ignored",
+			Data:   map[string][]byte{v1.TLSCertKey: []byte("cert"), v1.TLSPrivateKeyKey: []byte("key"), "ca.crt": []byte("ca")},
+			Verify: true,
+		},
+		{
+			Name:   "test other key not verified",
+			Data:   map[string][]byte{v1.TLSCertKey: []byte("cert"), v1.TLSPrivateKeyKey: []byte("other")},
+			Verify: false,
+		},
+		{
+			Name:   "test content moved between keys not verified",
+			Data:   map[string][]byte{v1.TLSCertKey: []byte("certk"), v1.TLSPrivateKeyKey: []byte("ey")},
+			Verify: false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := VerifyContentHash(&v1.Secret{Data: tc.Data}, hash)
+			if tc.Verify && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if !tc.Verify && !errors.Is(err, ContentHashMismatchErr) {
+				t.Errorf("expected '%v' got '%v'", ContentHashMismatchErr, err)
+			}
+		})
+	}
+}
+
+func TestRecordContentHash(t *testing.T) {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app.example.com", Namespace: "argocd"},
+		Data:       map[string][]byte{v1.TLSCertKey: []byte("cert"), v1.TLSPrivateKeyKey: []byte("key")},
+	}
+	hash := ContentHash(secret)
+
+	cases := []struct {
+		Name      string
+		Recorded  string
+		Renewed   bool
+		Stale     bool
+		ExpectErr bool
+	}{
+		{
+			Name: "test hash recorded",
+		},
+		{
+			Name:     "test hash already recorded",
+			Recorded: hash,
+		},
+		{
+			Name:     "test hash of renewed secret recorded",
+			Recorded: "previous",
+		},
+		{
+			Name:      "test secret changed since read not recorded",
+			Stale:     true,
+			ExpectErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			existing := secret.DeepCopy()
+			if tc.Recorded != "" {
+				existing.Annotations = map[string]string{AnnotationContentHash: tc.Recorded}
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build()
+			read := &v1.Secret{}
+			if err := c.Get(context.TODO(), client.ObjectKeyFromObject(secret), read); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tc.Stale {
+				renewed := read.DeepCopy()
+				renewed.Data[v1.TLSCertKey] = []byte("renewed")
+				if err := c.Update(context.TODO(), renewed); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			recorded, err := RecordContentHash(context.TODO(), c, read)
+			if (err != nil) != tc.ExpectErr {
+				t.Fatalf("expected error '%v' got '%v'", tc.ExpectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if recorded != hash {
+				t.Errorf("expected '%v' got '%v'", hash, recorded)
+			}
+			if err := c.Get(context.TODO(), client.ObjectKeyFromObject(secret), read); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if read.Annotations[AnnotationContentHash] != hash {
+				t.Errorf("expected '%v' got '%v'", hash, read.Annotations[AnnotationContentHash])
+			}
+		})
+	}
+}
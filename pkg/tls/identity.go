@@ -0,0 +1,188 @@
+package tls
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// LabelClusterIdentity is set on the identity certificate and its secret
+	// with the name of the cluster secret the identity was minted for
+	LabelClusterIdentity = "kuadrant.io/cluster-identity"
+	// IdentityCAName is the name of the CA certificate, its secret and the
+	// issuer minting the cluster identities, in the controller namespace
+	IdentityCAName = "cluster-identity-ca"
+
+	clusterIdentityPrefix = "cluster-identity-"
+)
+
+// IdentityService mints per-cluster client certificates from the hub CA. Each
+// certificate carries a SPIFFE-style URI SAN identifying the cluster, and is
+// renewed by cert-manager well before it expires.
+//
+// The trust model is the following. The hub CA is a root certificate issued
+// by the bootstrap issuer, usually the self-signed glbc-ca, so the root is
+// self-signed. Only the root is self-signed: the identities are issued by a
+// CA issuer signing with the key of the root, so every identity chains to
+// the same root. The workload clusters trust the hub by configuring the
+// certificate of the root, from the tls.crt of the IdentityCAName secret, as
+// a client CA of their API server. Kubernetes authenticates the identity as
+// the user named by its common name, the name of the cluster secret, so the
+// workload clusters grant that user the permissions of the controller. A
+// cluster accepting the root accepts the identity of any cluster, so the
+// user of each identity should only be bound in its own cluster. The key of
+// the root is kept when the root is renewed, so the root configured in the
+// workload clusters keeps verifying the identities until it expires. It has
+// to be replaced by the renewed root before then
+type IdentityService struct {
+	controlClient client.Client
+	defaultCtrlNS string
+	// rootIssuer is the ClusterIssuer of the root of the hub CA
+	rootIssuer  string
+	trustDomain string
+}
+
+func NewIdentityService(controlClient client.Client, defaultCtrlNS, rootIssuer, trustDomain string) *IdentityService {
+	return &IdentityService{controlClient: controlClient, defaultCtrlNS: defaultCtrlNS, rootIssuer: rootIssuer, trustDomain: trustDomain}
+}
+
+// SPIFFEID returns the identity URI of a cluster within the trust domain
+func SPIFFEID(trustDomain, cluster string) string {
+	return fmt.Sprintf("spiffe://%s/cluster/%s", trustDomain, cluster)
+}
+
+// EnsureClusterIdentity creates the identity certificate of the cluster,
+// issued by the hub CA. The hub CA is created first when it doesn't exist
+// yet. An AlreadyExists error is returned when the identity exists
+func (s *IdentityService) EnsureClusterIdentity(ctx context.Context, cluster string, owner metav1.Object) error {
+	if err := s.ensureCA(ctx); err != nil {
+		return err
+	}
+	cert := s.identityCertificate(cluster)
+	if err := controllerutil.SetOwnerReference(owner, cert, scheme.Scheme); err != nil {
+		return err
+	}
+	if err := s.controlClient.Create(ctx, cert, &client.CreateOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *IdentityService) GetClusterIdentitySecret(ctx context.Context, cluster string) (*v1.Secret, error) {
+	identitySecret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      clusterIdentityPrefix + cluster,
+		Namespace: s.defaultCtrlNS,
+	}}
+	if err := s.controlClient.Get(ctx, client.ObjectKeyFromObject(identitySecret), identitySecret); err != nil {
+		return nil, err
+	}
+	return identitySecret, nil
+}
+
+// ensureCA creates the root certificate of the hub CA and the issuer signing
+// the identities with it
+func (s *IdentityService) ensureCA(ctx context.Context) error {
+	root := &certman.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IdentityCAName,
+			Namespace: s.defaultCtrlNS,
+		},
+		Spec: certman.CertificateSpec{
+			SecretName: IdentityCAName,
+			CommonName: fmt.Sprintf("%s cluster identity CA", s.trustDomain),
+			IsCA:       true,
+			Duration: &metav1.Duration{
+				Duration: time.Hour * 24 * 365,
+			},
+			// leaves a month to replace the root configured in the
+			// workload clusters
+			RenewBefore: &metav1.Duration{
+				Duration: time.Hour * 24 * 30,
+			},
+			PrivateKey: &certman.CertificatePrivateKey{
+				Algorithm:      certman.ECDSAKeyAlgorithm,
+				Size:           256,
+				RotationPolicy: certman.RotationPolicyNever,
+			},
+			Usages: []certman.KeyUsage{
+				certman.UsageCertSign,
+				certman.UsageCRLSign,
+			},
+			IssuerRef: cmmeta.ObjectReference{
+				Group: "cert-manager.io",
+				Kind:  "ClusterIssuer",
+				Name:  s.rootIssuer,
+			},
+		},
+	}
+	if err := s.controlClient.Create(ctx, root); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	issuer := &certman.Issuer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IdentityCAName,
+			Namespace: s.defaultCtrlNS,
+		},
+		Spec: certman.IssuerSpec{
+			IssuerConfig: certman.IssuerConfig{
+				CA: &certman.CAIssuer{SecretName: IdentityCAName},
+			},
+		},
+	}
+	if err := s.controlClient.Create(ctx, issuer); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *IdentityService) identityCertificate(cluster string) *certman.Certificate {
+	labels := map[string]string{LabelClusterIdentity: cluster}
+	return &certman.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterIdentityPrefix + cluster,
+			Namespace: s.defaultCtrlNS,
+			Labels:    labels,
+		},
+		Spec: certman.CertificateSpec{
+			SecretName: clusterIdentityPrefix + cluster,
+			SecretTemplate: &certman.CertificateSecretTemplate{
+				Labels: labels,
+			},
+			CommonName: cluster,
+			URIs:       []string{SPIFFEID(s.trustDomain, cluster)},
+			// identities are short lived so a leaked key is only useful for a
+			// limited time. cert-manager takes care of the rotation
+			Duration: &metav1.Duration{
+				Duration: time.Hour * 24,
+			},
+			RenewBefore: &metav1.Duration{
+				Duration: time.Hour * 8,
+			},
+			PrivateKey: &certman.CertificatePrivateKey{
+				Algorithm:      certman.ECDSAKeyAlgorithm,
+				Size:           256,
+				RotationPolicy: certman.RotationPolicyAlways,
+			},
+			Usages: []certman.KeyUsage{
+				certman.UsageDigitalSignature,
+				certman.UsageKeyEncipherment,
+				certman.UsageClientAuth,
+			},
+			IssuerRef: cmmeta.ObjectReference{
+				Group: "cert-manager.io",
+				Kind:  "Issuer",
+				Name:  IdentityCAName,
+			},
+		},
+	}
+}
@@ -0,0 +1,97 @@
+package tls
+
+import (
+	"context"
+	"testing"
+
+	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIdentityService_EnsureClusterIdentity(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(certman.AddToScheme(scheme))
+	owner := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: "argocd", UID: "uid"}}
+	existingRoot := &certman.Certificate{
+		ObjectMeta: metav1.ObjectMeta{Name: IdentityCAName, Namespace: "argocd"},
+		Spec:       certman.CertificateSpec{SecretName: IdentityCAName, IsCA: true, CommonName: "existing"},
+	}
+
+	cases := []struct {
+		Name              string
+		Existing          []client.Object
+		ExpectExists      bool
+		ExpectRootCommon  string
+		ExpectIdentityURI string
+	}{
+		{
+			Name:              "test hub CA created with the first identity",
+			ExpectRootCommon:  "kuadrant.io cluster identity CA",
+			ExpectIdentityURI: "spiffe://kuadrant.io/cluster/cluster-1",
+		},
+		{
+			Name:              "test existing hub CA kept",
+			Existing:          []client.Object{existingRoot},
+			ExpectRootCommon:  "existing",
+			ExpectIdentityURI: "spiffe://kuadrant.io/cluster/cluster-1",
+		},
+		{
+			Name:             "test existing identity not issued again",
+			Existing:         []client.Object{existingRoot, (&IdentityService{defaultCtrlNS: "argocd", trustDomain: "other"}).identityCertificate("cluster-1")},
+			ExpectExists:     true,
+			ExpectRootCommon: "existing",
+			// rotated by cert-manager rather than recreated
+			ExpectIdentityURI: "spiffe://other/cluster/cluster-1",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.Existing...).Build()
+			s := NewIdentityService(c, "argocd", "glbc-ca", "kuadrant.io")
+			err := s.EnsureClusterIdentity(context.TODO(), "cluster-1", owner)
+			if tc.ExpectExists != k8serrors.IsAlreadyExists(err) || (!tc.ExpectExists && err != nil) {
+				t.Fatalf("expected already exists '%v' got '%v'", tc.ExpectExists, err)
+			}
+
+			root := &certman.Certificate{}
+			if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "argocd", Name: IdentityCAName}, root); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !root.Spec.IsCA || root.Spec.CommonName != tc.ExpectRootCommon {
+				t.Errorf("expected CA '%v' got '%v'", tc.ExpectRootCommon, root.Spec.CommonName)
+			}
+			if tc.Existing == nil && (root.Spec.IssuerRef.Kind != "ClusterIssuer" || root.Spec.IssuerRef.Name != "glbc-ca") {
+				t.Errorf("expected root issued by 'glbc-ca' got '%v'", root.Spec.IssuerRef)
+			}
+			issuer := &certman.Issuer{}
+			if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "argocd", Name: IdentityCAName}, issuer); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if issuer.Spec.CA == nil || issuer.Spec.CA.SecretName != IdentityCAName {
+				t.Errorf("expected CA issuer signing with '%v' got '%v'", IdentityCAName, issuer.Spec.CA)
+			}
+
+			identity := &certman.Certificate{}
+			if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "argocd", Name: "cluster-identity-cluster-1"}, identity); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if identity.Spec.IssuerRef.Kind != "Issuer" || identity.Spec.IssuerRef.Name != IdentityCAName {
+				t.Errorf("expected identity issued by '%v' got '%v'", IdentityCAName, identity.Spec.IssuerRef)
+			}
+			if len(identity.Spec.URIs) != 1 || identity.Spec.URIs[0] != tc.ExpectIdentityURI {
+				t.Errorf("expected '%v' got '%v'", tc.ExpectIdentityURI, identity.Spec.URIs)
+			}
+			if identity.Spec.CommonName != "cluster-1" || identity.Spec.PrivateKey.RotationPolicy != certman.RotationPolicyAlways {
+				t.Errorf("expected identity of 'cluster-1' rotating its key got '%v' '%v'", identity.Spec.CommonName, identity.Spec.PrivateKey.RotationPolicy)
+			}
+		})
+	}
+}
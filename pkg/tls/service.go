@@ -2,8 +2,10 @@ package tls
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/config"
 	certman "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1"
 	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
 	v1 "k8s.io/api/core/v1"
@@ -16,6 +18,15 @@ import (
 const (
 	TlsIssuerAnnotation = "kuadrant.dev/tls-issuer"
 	certFinalizer       = "kuadrant.dev/certificates-cleanup"
+	// IssuanceRetryInterval is how often a certificate cert-manager failed
+	// to issue is checked again. cert-manager backs off for an hour after a
+	// failed issuance, so checking more often only adds load
+	IssuanceRetryInterval = 10 * time.Minute
+	// AnnotationImportedCertificate is set on the TLS secrets imported from
+	// an existing cluster whose certificate wasn't issued by cert-manager.
+	// No certificate is issued for their host, so the imported certificate
+	// keeps being served until the secret is replaced
+	AnnotationImportedCertificate = "kuadrant.io/imported-certificate"
 )
 
 type Service struct {
@@ -23,15 +34,25 @@ type Service struct {
 	// this is temporary setting the tenant ns in the control plane.
 	// will be removed when we have auth that can map to a given ctrl plane ns
 	defaultCtrlNS string
-	defaultIssuer string
+	// config holds the issuer of the certificates
+	config *config.Store
 }
 
-func NewService(controlClient client.Client, defaultCtrlNS, defaultIssuer string) *Service {
-	return &Service{controlClient: controlClient, defaultCtrlNS: defaultCtrlNS, defaultIssuer: defaultIssuer}
+func NewService(controlClient client.Client, defaultCtrlNS string, store *config.Store) *Service {
+	return &Service{controlClient: controlClient, defaultCtrlNS: defaultCtrlNS, config: store}
 }
 
+// EnsureCertificate creates the certificate of the host issued by the
+// configured issuer, unless its TLS secret was imported
 func (s *Service) EnsureCertificate(ctx context.Context, host string, owner metav1.Object) error {
-	cert := s.certificate(host, s.defaultIssuer, s.defaultCtrlNS)
+	secret, err := s.GetCertificateSecret(ctx, host)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if secret != nil && Imported(secret) {
+		return nil
+	}
+	cert := Certificate(host, s.config.Get().CertificateIssuer, s.defaultCtrlNS)
 	if err := controllerutil.SetOwnerReference(owner, cert, scheme.Scheme); err != nil {
 		return err
 	}
@@ -41,10 +62,16 @@ func (s *Service) EnsureCertificate(ctx context.Context, host string, owner meta
 	return nil
 }
 
+// Imported returns true when the certificate of the TLS secret was imported
+// from an existing cluster and isn't issued by the controller
+func Imported(secret *v1.Secret) bool {
+	return secret.Annotations[AnnotationImportedCertificate] == "true"
+}
+
 func (s *Service) GetCertificateSecret(ctx context.Context, host string) (*v1.Secret, error) {
 	//the secret is expected to be named after the host
 	tlsSecret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{
-		Name:      host,
+		Name:      CertificateName(host),
 		Namespace: s.defaultCtrlNS,
 	}}
 	if err := s.controlClient.Get(ctx, client.ObjectKeyFromObject(tlsSecret), tlsSecret); err != nil {
@@ -53,19 +80,47 @@ func (s *Service) GetCertificateSecret(ctx context.Context, host string) (*v1.Se
 	return tlsSecret, nil
 }
 
-func (s *Service) certificate(host, issuer, controlNS string) *certman.Certificate {
+// RecordContentHash records the content hash of the TLS secret of a host on
+// the secret, see RecordContentHash
+func (s *Service) RecordContentHash(ctx context.Context, secret *v1.Secret) (string, error) {
+	return RecordContentHash(ctx, s.controlClient, secret)
+}
+
+// IssuanceFailed returns true when the last attempt of cert-manager to issue
+// the certificate of the host failed
+func (s *Service) IssuanceFailed(ctx context.Context, host string) (bool, error) {
+	cert := &certman.Certificate{}
+	if err := s.controlClient.Get(ctx, client.ObjectKey{Namespace: s.defaultCtrlNS, Name: CertificateName(host)}, cert); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return cert.Status.LastFailureTime != nil, nil
+}
+
+// CertificateName returns the name of the certificate of the host and of its
+// secret: the host, with the wildcard label of wildcard hosts replaced as
+// it's not valid in names
+func CertificateName(host string) string {
+	if strings.HasPrefix(host, "*.") {
+		return "wildcard." + strings.TrimPrefix(host, "*.")
+	}
+	return host
+}
+
+// Certificate returns the certificate of the host issued by the issuer, with
+// its secret named after the host
+func Certificate(host, issuer, controlNS string) *certman.Certificate {
 	// this will be created in the control plane
 	annotations := map[string]string{TlsIssuerAnnotation: issuer}
 	labels := map[string]string{}
 	return &certman.Certificate{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        host,
+			Name:        CertificateName(host),
 			Namespace:   controlNS,
 			Labels:      labels,
 			Annotations: annotations,
 		},
 		Spec: certman.CertificateSpec{
-			SecretName: host,
+			SecretName: CertificateName(host),
 			SecretTemplate: &certman.CertificateSecretTemplate{
 				Labels:      labels,
 				Annotations: annotations,
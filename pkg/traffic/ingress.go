@@ -2,6 +2,7 @@ package traffic
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	admissionv1 "k8s.io/api/admissionregistration/v1"
@@ -14,20 +15,41 @@ import (
 	"k8s.io/utils/strings/slices"
 
 	internalctrl "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/controller"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
 	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/slice"
 	kuadrantv1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
 )
 
 const (
 	AnnotationManagedHosts = "kuadrant.io/managed-hosts"
+	// AnnotationAddressWeights configures the relative weight of the load
+	// balancer addresses of the traffic object, as a comma separated list of
+	// address=weight pairs. Addresses not listed get a weight of 1
+	AnnotationAddressWeights = "kuadrant.io/address-weights"
+
+	defaultAddressWeight = 1
+
+	// annotationSSLRedirect makes ingress-nginx redirect plain HTTP requests
+	// to HTTPS
+	annotationSSLRedirect = "nginx.ingress.kubernetes.io/ssl-redirect"
+	// annotationIngressClass is the legacy annotation selecting the ingress
+	// class of an Ingress
+	annotationIngressClass = "kubernetes.io/ingress.class"
 )
 
 func NewIngress(i *networkingv1.Ingress) Interface {
 	return &Ingress{Ingress: i}
 }
 
+// NewIngressForCluster returns an Ingress whose DNS targets are associated
+// with the cluster it came from
+func NewIngressForCluster(i *networkingv1.Ingress, cluster string) Interface {
+	return &Ingress{Ingress: i, Cluster: cluster}
+}
+
 type Ingress struct {
 	*networkingv1.Ingress
+	Cluster string
 }
 
 func (a *Ingress) GetKind() string {
@@ -45,6 +67,28 @@ func (a *Ingress) GetHosts() []string {
 	return hosts
 }
 
+// GetBackendServices returns the names of the services the ingress routes
+// traffic to, in the namespace of the ingress
+func (a *Ingress) GetBackendServices() []string {
+	var services []string
+	addBackend := func(backend *networkingv1.IngressBackend) {
+		if backend == nil || backend.Service == nil || slices.Contains(services, backend.Service.Name) {
+			return
+		}
+		services = append(services, backend.Service.Name)
+	}
+	addBackend(a.Spec.DefaultBackend)
+	for _, rule := range a.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			addBackend(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+	return services
+}
+
 func (a *Ingress) AddManagedHost(h string) error {
 	// rules to add to the spec
 	additionalRules := []networkingv1.IngressRule{}
@@ -146,10 +190,31 @@ func (a *Ingress) RemoveTLS(hosts []string) {
 	}
 }
 
+// AddHTTPSRedirect redirects plain HTTP requests to HTTPS, unless the
+// redirect was already configured on the Ingress
+func (a *Ingress) AddHTTPSRedirect() {
+	if a.Annotations == nil {
+		a.Annotations = map[string]string{}
+	}
+	if _, ok := a.Annotations[annotationSSLRedirect]; ok {
+		return
+	}
+	a.Annotations[annotationSSLRedirect] = "true"
+}
+
 func (a *Ingress) GetSpec() interface{} {
 	return a.Spec
 }
 
+// GetClassName returns the ingress class of the Ingress, set by its
+// ingressClassName or the legacy kubernetes.io/ingress.class annotation
+func (a *Ingress) GetClassName() string {
+	if a.Spec.IngressClassName != nil {
+		return *a.Spec.IngressClassName
+	}
+	return metadata.GetAnnotation(a, annotationIngressClass)
+}
+
 func (a *Ingress) GetNamespaceName() types.NamespacedName {
 	return types.NamespacedName{
 		Namespace: a.Namespace,
@@ -170,10 +235,14 @@ func (a *Ingress) String() string {
 func (a *Ingress) GetDNSTargets() ([]kuadrantv1.Target, error) {
 	status := a.Status
 
+	weights, err := addressWeights(a.Annotations[AnnotationAddressWeights])
+	if err != nil {
+		return nil, err
+	}
+
 	dnsTargets := []kuadrantv1.Target{}
 	for _, lb := range status.LoadBalancer.Ingress {
-		dnsTarget := kuadrantv1.Target{}
-		//dnsTarget.Cluster = cluster.String()
+		dnsTarget := kuadrantv1.Target{Cluster: a.Cluster}
 		if lb.IP != "" {
 			dnsTarget.TargetType = kuadrantv1.TargetTypeIP
 			dnsTarget.Value = lb.IP
@@ -183,12 +252,36 @@ func (a *Ingress) GetDNSTargets() ([]kuadrantv1.Target, error) {
 			dnsTarget.Value = lb.Hostname
 
 		}
+		dnsTarget.Weight = defaultAddressWeight
+		if weight, ok := weights[dnsTarget.Value]; ok {
+			dnsTarget.Weight = weight
+		}
 		dnsTargets = append(dnsTargets, dnsTarget)
 	}
 
 	return dnsTargets, nil
 }
 
+// addressWeights parses the value of the AnnotationAddressWeights annotation
+func addressWeights(value string) (map[string]int, error) {
+	weights := map[string]int{}
+	if value == "" {
+		return weights, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid %s annotation entry %q, expected address=weight", AnnotationAddressWeights, pair)
+		}
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for address %s in %s annotation", parts[1], parts[0], AnnotationAddressWeights)
+		}
+		weights[parts[0]] = weight
+	}
+	return weights, nil
+}
+
 func (a *Ingress) GetWebhookConfigurations(host string, caBundle []byte) ([]*admissionv1.ValidatingWebhookConfiguration, []*admissionv1.MutatingWebhookConfiguration) {
 	var matchPolicy admissionv1.MatchPolicyType = admissionv1.Exact
 	var scope admissionv1.ScopeType = admissionv1.AllScopes
@@ -2,7 +2,9 @@ package traffic
 
 import (
 	"context"
+	"strings"
 
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
 	kuadrantv1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
 	admissionv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -12,6 +14,83 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 )
 
+const (
+	// AnnotationDNS set to AnnotationValueDisabled opts the traffic object out
+	// of DNS management. The endpoints it already published are withdrawn
+	AnnotationDNS = "kuadrant.io/dns"
+	// AnnotationTLS set to AnnotationValueDisabled opts the traffic object out
+	// of TLS management
+	AnnotationTLS = "kuadrant.io/tls"
+
+	AnnotationValueDisabled = "disabled"
+
+	// AnnotationDNSStrategy selects how clients are spread across the
+	// clusters serving the traffic object: DNSStrategySpread (default),
+	// DNSStrategySticky or DNSStrategyLatency
+	AnnotationDNSStrategy = "kuadrant.io/dns-strategy"
+	// DNSStrategySpread publishes short lived answers drawn by weight for
+	// each query, so answers are shuffled across the clusters and clients
+	// move between them as weights change
+	DNSStrategySpread = "spread"
+	// DNSStrategySticky publishes long lived answers, and pins each
+	// resolver to the same address of the host by the block of the IPv4
+	// space its address is in, so resolvers keep sending clients to the
+	// same cluster, minimising mid-session switches for stateful apps. A
+	// host is sticky as soon as one of its traffic objects is
+	DNSStrategySticky = "sticky"
+	// DNSStrategyLatency answers resolvers with the clusters of the region
+	// with the lowest latency to them, measured by the DNS provider, instead
+	// of spreading them by weight. Every cluster serving the host must declare
+	// its region, and every traffic object of the host must use it
+	DNSStrategyLatency = "latency"
+
+	// AnnotationVisibility set to VisibilityPrivate exposes the traffic
+	// object within the fleet only. Its managed host is assigned from the
+	// private zone, resolving to the cluster set IPs of its backend services
+	// exported with a ServiceExport, or to the private addresses of its load
+	// balancers or ingresses when none is, and its backend services only
+	// accept traffic from the fleet. It must be set before the managed host
+	// is assigned
+	AnnotationVisibility = "kuadrant.io/visibility"
+	VisibilityPrivate    = "private"
+	// AnnotationNoPrivateAddresses is set by the controller on the traffic
+	// objects whose records in private zones have no endpoints, as they have
+	// neither exported backend services nor private addresses
+	AnnotationNoPrivateAddresses = "kuadrant.io/no-private-addresses"
+
+	// AnnotationManagedZone selects by name the ManagedZone of the controller
+	// namespace the managed host of the traffic object is assigned from,
+	// instead of its default zone. The zone must have the visibility of the
+	// traffic object and allow its namespace. It must be set before the
+	// managed host is assigned
+	AnnotationManagedZone = "kuadrant.io/managed-zone"
+
+	// AnnotationTolerations lists the cluster taints the traffic object
+	// tolerates, as comma separated key, key=value, key:effect or
+	// key=value:effect entries. Its endpoints are withdrawn while its cluster
+	// has a NoExecute taint it doesn't tolerate
+	AnnotationTolerations = "kuadrant.io/tolerations"
+
+	// AnnotationHostClaims lists by name the HostClaims of the namespace of
+	// the traffic object whose hosts it's served on, separated by commas.
+	// The hosts claimed are added to the traffic object like its managed
+	// hosts, and only the traffic objects referencing a claim are published
+	// on its host
+	AnnotationHostClaims = "kuadrant.io/host-claims"
+
+	// AnnotationHTTPSRecord publishes an HTTPS record for the managed hosts
+	// of the traffic object in each cluster, with the service parameters it
+	// holds in presentation format, e.g. alpn=h2,http/1.1. Set it to an
+	// empty value to publish the record without parameters. The port
+	// parameter is set by the controller for the clusters exposing HTTPS on
+	// another port
+	AnnotationHTTPSRecord = "kuadrant.io/https-record"
+
+	// FieldManager is the field manager of the changes the controller makes
+	// to the objects of the workload clusters and the control plane
+	FieldManager = "kuadrant-traffic-controller"
+)
+
 type CreateOrUpdateTraffic func(ctx context.Context, i Interface) error
 type DeleteTraffic func(ctx context.Context, i Interface) error
 
@@ -21,18 +100,76 @@ type Interface interface {
 	AddManagedHost(h string) error
 	GetKind() string
 	GetHosts() []string
+	GetBackendServices() []string
 	GetCacheKey() string
+	GetClassName() string
 	GetNamespaceName() types.NamespacedName
 	AddTLS(host string, secret *corev1.Secret)
 	HasTLS() bool
 	GetTLS() []TLSConfig
 	RemoveTLS(host []string)
+	AddHTTPSRedirect()
 	GetSpec() interface{}
 	GetDNSTargets() ([]kuadrantv1.Target, error)
 	GetWebhookConfigurations(host string, caBundle []byte) ([]*admissionv1.ValidatingWebhookConfiguration, []*admissionv1.MutatingWebhookConfiguration)
 	ExposesOwnController() bool
 }
 
+// DNSDisabled returns true when the traffic object opted out of DNS
+// management
+func DNSDisabled(t Interface) bool {
+	return metadata.GetAnnotation(t, AnnotationDNS) == AnnotationValueDisabled
+}
+
+// DNSStrategy returns the DNS strategy of the traffic object, defaulting to
+// DNSStrategySpread when it isn't set or isn't valid
+func DNSStrategy(t Interface) string {
+	switch strategy := metadata.GetAnnotation(t, AnnotationDNSStrategy); strategy {
+	case DNSStrategySticky, DNSStrategyLatency:
+		return strategy
+	}
+	return DNSStrategySpread
+}
+
+// Private returns true when the traffic object is exposed within the fleet
+// only
+func Private(t Interface) bool {
+	return metadata.GetAnnotation(t, AnnotationVisibility) == VisibilityPrivate
+}
+
+// ManagedZone returns the name of the ManagedZone selected for the managed
+// host of the traffic object, or an empty string when none is
+func ManagedZone(t Interface) string {
+	return metadata.GetAnnotation(t, AnnotationManagedZone)
+}
+
+// HostClaims returns the names of the HostClaims referenced by the traffic
+// object
+func HostClaims(t Interface) []string {
+	claims := []string{}
+	for _, name := range strings.Split(metadata.GetAnnotation(t, AnnotationHostClaims), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			claims = append(claims, name)
+		}
+	}
+	return claims
+}
+
+// TLSDisabled returns true when the traffic object opted out of TLS
+// management
+func TLSDisabled(t Interface) bool {
+	return metadata.GetAnnotation(t, AnnotationTLS) == AnnotationValueDisabled
+}
+
+// HTTPSRecordParams returns the service parameters of the HTTPS records of
+// the traffic object, and whether it publishes HTTPS records
+func HTTPSRecordParams(t Interface) (string, bool) {
+	if !metadata.HasAnnotation(t, AnnotationHTTPSRecord) {
+		return "", false
+	}
+	return strings.TrimSpace(metadata.GetAnnotation(t, AnnotationHTTPSRecord)), true
+}
+
 type TLSConfig struct {
 	Hosts      []string
 	SecretName string
@@ -0,0 +1,172 @@
+package zonereport
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/_internal/metadata"
+	v1 "github.com/Kuadrant/multi-cluster-traffic-controller/pkg/apis/v1"
+	"github.com/Kuadrant/multi-cluster-traffic-controller/pkg/dns"
+)
+
+// maxDrift is the number of records of each kind of drift listed in the
+// report of a hosted zone, so the report of a zone that drifted a lot still
+// fits in an object
+const maxDrift = 100
+
+// Reporter periodically compares the records published in the hosted zones
+// of each ManagedZone against the DNSRecords of the zone, and reports the
+// records published out of band, the records missing and the records
+// changed in the ZoneReport of the zone. The drift is only reported unless
+// Repair is set, in which case the DNSRecords of the missing and changed
+// records are published again while the zone accepts changes, and isn't
+// handed over to another control plane
+type Reporter struct {
+	Client        client.Client
+	Scheme        *runtime.Scheme
+	ZoneProviders *dns.ZoneProviders
+	Interval      time.Duration
+	Repair        bool
+}
+
+func (r *Reporter) Start(ctx context.Context) error {
+	log.FromContext(ctx).Info("Starting zone reporter", "interval", r.Interval, "repair", r.Repair)
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.reportAll(ctx); err != nil {
+				log.FromContext(ctx).Error(err, "failed to report zones")
+			}
+		}
+	}
+}
+
+func (r *Reporter) reportAll(ctx context.Context) error {
+	zones := &v1.ManagedZoneList{}
+	if err := r.Client.List(ctx, zones); err != nil {
+		return err
+	}
+	for i := range zones.Items {
+		zone := &zones.Items[i]
+		if zone.DeletionTimestamp != nil {
+			continue
+		}
+		if err := r.report(ctx, zone); err != nil {
+			log.FromContext(ctx).Error(err, "failed to report zone", "zone", zone.Name, "namespace", zone.Namespace)
+		}
+	}
+	return nil
+}
+
+// report compares the records of each hosted zone of the zone and writes
+// the ZoneReport of the zone
+func (r *Reporter) report(ctx context.Context, zone *v1.ManagedZone) error {
+	records, err := r.zoneRecords(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	status := v1.ZoneReportStatus{GeneratedAt: &metav1.Time{Time: time.Now()}}
+	hostedZones, provider, err := r.ZoneProviders.ProvidersFor(ctx, zone)
+	if err != nil {
+		status.Zones = []v1.HostedZoneReport{{ID: zone.Spec.ID, Error: err.Error()}}
+	}
+	repair := r.Repair
+	if allowed, _ := dns.ChangesAllowed(zone, time.Now()); !allowed || metadata.HandedOverTo(zone) != "" {
+		repair = false
+	}
+	for _, hostedZone := range hostedZones {
+		report := r.compare(ctx, provider, hostedZone, records)
+		if repair && report.Error == "" {
+			report.Repaired = r.repair(ctx, provider, hostedZone, records, report)
+		}
+		status.Extra += len(report.Extra)
+		status.Missing += len(report.Missing)
+		status.Mismatched += len(report.Mismatched)
+		report.Extra = truncate(report.Extra)
+		report.Missing = truncate(report.Missing)
+		report.Mismatched = truncate(report.Mismatched)
+		status.Zones = append(status.Zones, report)
+	}
+
+	zoneReport := &v1.ZoneReport{ObjectMeta: metav1.ObjectMeta{Namespace: zone.Namespace, Name: zone.Name}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, zoneReport, func() error {
+		zoneReport.Spec.ManagedZoneRef = v1.ManagedZoneReference{Name: zone.Name}
+		return controllerutil.SetControllerReference(zone, zoneReport, r.Scheme)
+	}); err != nil {
+		return err
+	}
+	zoneReport.Status = status
+	return r.Client.Status().Update(ctx, zoneReport)
+}
+
+// zoneRecords returns the DNSRecords publishing to the zone
+func (r *Reporter) zoneRecords(ctx context.Context, zone *v1.ManagedZone) ([]v1.DNSRecord, error) {
+	list := &v1.DNSRecordList{}
+	if err := r.Client.List(ctx, list, client.InNamespace(zone.Namespace)); err != nil {
+		return nil, err
+	}
+	records := []v1.DNSRecord{}
+	for _, record := range list.Items {
+		if record.Spec.ManagedZoneRef != nil && record.Spec.ManagedZoneRef.Name == zone.Name {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	return records, nil
+}
+
+func (r *Reporter) compare(ctx context.Context, provider dns.Provider, hostedZone v1.DNSZone, records []v1.DNSRecord) v1.HostedZoneReport {
+	lister, ok := provider.(dns.ZoneLister)
+	if !ok {
+		return v1.HostedZoneReport{ID: hostedZone.ID, Error: "the provider of the zone can't list its records"}
+	}
+	published, err := lister.ZoneRecords(ctx, hostedZone)
+	if err != nil {
+		return v1.HostedZoneReport{ID: hostedZone.ID, Error: err.Error()}
+	}
+	return dns.CompareRecords(hostedZone.ID, published, records)
+}
+
+// repair publishes the DNSRecords of the missing and mismatched records of
+// the report to the hosted zone again, returning the names of the ones
+// published. The records published out of band are left, as they may be
+// managed by other tools
+func (r *Reporter) repair(ctx context.Context, provider dns.Provider, hostedZone v1.DNSZone, records []v1.DNSRecord, report v1.HostedZoneReport) []string {
+	drifted := map[string]bool{}
+	for _, drift := range append(append([]v1.RecordDrift{}, report.Missing...), report.Mismatched...) {
+		drifted[drift.DNSRecord] = true
+	}
+	var repaired []string
+	for i := range records {
+		record := &records[i]
+		if !drifted[record.Name] || record.DeletionTimestamp != nil {
+			continue
+		}
+		log.FromContext(ctx).Info("Repairing records of DNSRecord", "record", record.Name, "namespace", record.Namespace, "zone", hostedZone.ID)
+		if err := provider.Ensure(ctx, record, hostedZone); err != nil {
+			log.FromContext(ctx).Error(err, "failed to repair records of DNSRecord", "record", record.Name, "namespace", record.Namespace, "zone", hostedZone.ID)
+			continue
+		}
+		repaired = append(repaired, record.Name)
+	}
+	return repaired
+}
+
+func truncate(drifts []v1.RecordDrift) []v1.RecordDrift {
+	if len(drifts) > maxDrift {
+		return drifts[:maxDrift]
+	}
+	return drifts
+}